@@ -2,16 +2,36 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
 
 	"mmmeld/internal/audio"
+	"mmmeld/internal/batch"
 	"mmmeld/internal/config"
+	"mmmeld/internal/events"
+	"mmmeld/internal/ffmpeg"
 	"mmmeld/internal/fileutil"
+	"mmmeld/internal/genai"
 	"mmmeld/internal/image"
+	"mmmeld/internal/logx"
+	"mmmeld/internal/preflight"
+	"mmmeld/internal/resume"
+	"mmmeld/internal/tts"
 	"mmmeld/internal/video"
 )
 
@@ -21,53 +41,506 @@ func main() {
 	// Setup logging
 	config.SetupLogging()
 
+	if len(os.Args) > 1 && os.Args[1] == "cache" {
+		if err := runCacheCommand(os.Args[2:]); err != nil {
+			log.Fatalf("Cache error: %v", err)
+		}
+		return
+	}
+
 	// Create and load configuration
 	cfg := config.New()
-	if err := cfg.LoadFromFlags(); err != nil {
-		log.Fatalf("Configuration error: %v", err)
+	err := cfg.LoadFromFlags()
+	// Flags are parsed before validation, so --json also covers config errors
+	if cfg.JSON {
+		events.Enable(os.Stdout)
+	}
+	if err != nil {
+		fatal("config", "Configuration error", err)
+	}
+
+	if cfg.Manifest != "" {
+		os.Exit(runBatch(cfg))
+	}
+
+	if cfg.Estimate {
+		estimate := tts.EstimateSpeech(cfg.Text, audio.SpeechOptionsFromConfig(cfg), cfg.TTSPrice)
+		if cfg.JSON {
+			events.Emit("estimate", events.Fields{
+				"provider":     estimate.Provider,
+				"model":        estimate.Model,
+				"chunks":       estimate.Chunks,
+				"characters":   estimate.Characters,
+				"price_per_1k": estimate.PricePer1K,
+				"cost":         estimate.Cost,
+			})
+			return
+		}
+		if err := tts.PrintEstimate(os.Stdout, estimate); err != nil {
+			log.Fatalf("Failed to print estimate: %v", err)
+		}
+		return
 	}
 
 	// Set API keys in environment
 	cfg.SetAPIKeys()
 
-	// Create cleanup manager
+	// Create cleanup manager. A failed run keeps its files for debugging and
+	// for --resume.
 	cleanup := fileutil.NewCleanupManager()
-	defer func() {
+	cleanup.KeepOnFailure(true)
+	runCleanup := func() {
 		if cfg.Cleanup {
 			if err := cleanup.Cleanup(); err != nil {
-				log.Printf("Cleanup error: %v", err)
+				logx.Warnf("Cleanup failed: %v", err)
 			}
 		}
-	}()
+	}
+	defer runCleanup()
+
+	// A --temp-dir this run creates, such as a batch job's, is removed whole
+	// once the run succeeds
+	if cfg.TempDir != "" && !fileutil.FileExists(config.TempAssetsFolder) {
+		cleanup.AddDir(config.TempAssetsFolder)
+	}
 
 	// Ensure temp folder exists
 	if err := fileutil.EnsureTempFolder(); err != nil {
-		log.Fatalf("Failed to create temp folder: %v", err)
+		fatal("setup", "Failed to create temp folder", err)
 	}
 
 	// Process inputs based on configuration
 	if err := processInputs(cfg, cleanup); err != nil {
-		log.Fatalf("Processing error: %v", err)
+		cleanup.Fail()
+		runCleanup() // fatal exits without running deferred calls
+		category := "internal"
+		var stage *stageError
+		if errors.As(err, &stage) {
+			category = stage.stage
+		}
+		fatal(category, "Processing error", err)
+	}
+}
+
+// runSummary records everything that went into a rendered video. It is
+// written to --summary and is the --json summary event.
+type runSummary struct {
+	Output            string                `json:"output"`
+	ElapsedSeconds    float64               `json:"elapsed_seconds"`
+	Audio             *audioSummary         `json:"audio,omitempty"`
+	BGMusic           *bgMusicSummary       `json:"bg_music,omitempty"`
+	AudioBrief        *genai.AudioBrief     `json:"audio_brief,omitempty"` // When audio analysis wrote an image prompt
+	Media             []mediaSummary        `json:"media"`
+	Render            *video.RenderSettings `json:"render"`
+	Stages            []stageTiming         `json:"stages"`
+	ValidationWarning string                `json:"validation_warning,omitempty"`
+}
+
+type audioSummary struct {
+	Input           string  `json:"input"` // --audio as given
+	Path            string  `json:"path"`
+	Title           string  `json:"title,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+}
+
+type bgMusicSummary struct {
+	Input  string  `json:"input"`
+	Path   string  `json:"path"`
+	Volume float64 `json:"volume"`
+}
+
+type mediaSummary struct {
+	Path        string                `json:"path"`
+	Source      string                `json:"source"` // local, downloaded, or generated
+	Entry       string                `json:"entry,omitempty"`
+	IsVideo     bool                  `json:"is_video,omitempty"`
+	Prompt      string                `json:"prompt,omitempty"`
+	AspectCheck string                `json:"aspect_check,omitempty"`
+	TextOverlay bool                  `json:"text_overlay,omitempty"`
+	Attempts    *image.AttemptsReport `json:"attempts,omitempty"` // Generation attempts with validation scores
+}
+
+// buildRunSummary gathers the inputs, settings, and stage times of a render
+func buildRunSummary(cfg *config.Config, outputPath string, audioSource *audio.AudioSource, mediaInputs []image.MediaInput, bgMusicPath string, settings *video.RenderSettings, timings stageTimings) *runSummary {
+	summary := &runSummary{Output: outputPath, Render: settings, Stages: timings}
+
+	if audioSource != nil {
+		duration := audioSource.Duration
+		if duration <= 0 {
+			if d, err := video.GetMediaDuration(audioSource.Path); err == nil {
+				duration = d
+			}
+		}
+		summary.Audio = &audioSummary{
+			Input:           fileutil.RedactURL(cfg.Audio),
+			Path:            audioSource.Path,
+			Title:           audioSource.Title,
+			DurationSeconds: roundSeconds(duration),
+		}
+	}
+	if bgMusicPath != "" {
+		summary.BGMusic = &bgMusicSummary{Input: fileutil.RedactURL(cfg.BGMusic), Path: bgMusicPath, Volume: cfg.BGMusicVolume}
+	}
+
+	for _, mi := range mediaInputs {
+		source := mi.Source
+		if source == "" {
+			// Chosen interactively or recorded by an older run manifest
+			source = image.SourceLocal
+			if mi.IsGenerated {
+				source = image.SourceGenerated
+			}
+		}
+		if mi.Brief != nil && summary.AudioBrief == nil {
+			summary.AudioBrief = mi.Brief
+		}
+		summary.Media = append(summary.Media, mediaSummary{
+			Path:        mi.Path,
+			Source:      source,
+			Entry:       mi.Entry,
+			IsVideo:     mi.IsVideo,
+			Prompt:      mi.Prompt,
+			AspectCheck: mi.AspectCheck,
+			TextOverlay: mi.TextOverlay,
+			Attempts:    mi.Attempts,
+		})
+	}
+	return summary
+}
+
+// defaultSummaryPath puts the summary next to the video: out.mp4 -> out.mmmeld.json
+func defaultSummaryPath(outputPath string) string {
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".mmmeld.json"
+}
+
+// writeRunSummary saves summary as indented JSON
+func writeRunSummary(path string, summary *runSummary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode run summary: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write run summary: %w", err)
+	}
+	return nil
+}
+
+// fields flattens the summary into the members of a --json summary event
+func (s *runSummary) fields() events.Fields {
+	var fields events.Fields
+	data, _ := json.Marshal(s)
+	json.Unmarshal(data, &fields)
+	return fields
+}
+
+// stageError is a failure tagged with the stage it happened in, which --json
+// reports as the error's category
+type stageError struct {
+	stage string
+	err   error
+}
+
+func (e *stageError) Error() string { return e.err.Error() }
+func (e *stageError) Unwrap() error { return e.err }
+
+// Exit codes. Scripts can tell a run that was missing an input, which never
+// started any work, from one that failed partway.
+const (
+	exitFailure      = 1
+	exitMissingInput = 2
+)
+
+// successMessage starts the line naming the rendered video, which batch runs
+// read to report where each job's video went
+const successMessage = "Video generated successfully: "
+
+// fatal logs err and exits. With --json, an error event with category is the
+// last line on stdout.
+func fatal(category, context string, err error) {
+	events.Emit("error", events.Fields{"category": category, "message": err.Error()})
+	log.Printf("%s: %v", context, err)
+	if category == "missing_input" {
+		os.Exit(exitMissingInput)
 	}
+	os.Exit(exitFailure)
+}
+
+// stageTiming is the wall time of one stage of a run
+type stageTiming struct {
+	Stage   string  `json:"stage"`
+	Seconds float64 `json:"seconds"`
+	Reused  bool    `json:"reused"` // Skipped by --resume
+}
+
+// stageTimings collects the timing of each finished stage
+type stageTimings []stageTiming
+
+// begin emits stage_start for --json and returns a function that records the
+// stage's time and emits the matching stage_end
+func (t *stageTimings) begin(stage string) func(reused bool) {
+	start := time.Now()
+	events.Emit("stage_start", events.Fields{"stage": stage})
+	return func(reused bool) {
+		timing := stageTiming{Stage: stage, Seconds: roundSeconds(time.Since(start).Seconds()), Reused: reused}
+		*t = append(*t, timing)
+		events.Emit("stage_end", events.Fields{"stage": stage, "reused": reused, "seconds": timing.Seconds})
+	}
+}
+
+// roundSeconds keeps durations in events to milliseconds
+func roundSeconds(seconds float64) float64 {
+	return float64(int64(seconds*1000+0.5)) / 1000
+}
+
+// batchJob is the outcome of one --manifest job
+type batchJob struct {
+	job     batch.Job
+	output  string // Where the video went, which --auto-number may have changed
+	err     error
+	detail  string // Last line the job logged, which explains a failure
+	elapsed time.Duration
+}
+
+// batchExecutable, batchStdout, and batchStderr are variables so tests can run
+// batch jobs with a fake mmmeld and read what they print
+var (
+	batchExecutable           = os.Executable
+	batchStdout     io.Writer = os.Stdout
+	batchStderr     io.Writer = os.Stderr
+)
+
+// runBatch renders every --manifest job, at most cfg.Parallel at a time, and
+// returns the exit code: non-zero if any job failed. Each job is its own
+// mmmeld process with its own temp folder, so a failing job neither stops
+// the batch nor touches another job's files.
+func runBatch(cfg *config.Config) int {
+	manifest, err := batch.Load(cfg.Manifest)
+	if err != nil {
+		fatal("config", "Manifest error", err)
+	}
+	exe, err := batchExecutable()
+	if err != nil {
+		fatal("setup", "Failed to locate the mmmeld executable", err)
+	}
+	common := batch.CommonArgs(os.Args[1:])
+	batchFolder := filepath.Join(config.TempAssetsFolder, "batch")
+
+	jobs := make([]batchJob, len(manifest.Jobs))
+	sem := make(chan struct{}, cfg.Parallel)
+	var output sync.Mutex
+	var wg sync.WaitGroup
+	for i, job := range manifest.Jobs {
+		wg.Add(1)
+		go func(i int, job batch.Job) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			// Flags on the command line override the manifest's defaults, and
+			// the job's own settings override both
+			name := fileutil.SanitizeFilename(job.Name)
+			tempDir := filepath.Join(batchFolder, fmt.Sprintf("%02d_%s", i+1, name))
+			args := append(append(append([]string{}, manifest.Defaults...), common...), job.Args...)
+			args = append(args, "--temp-dir", tempDir, "--non-interactive")
+			if cfg.Summary != "" {
+				args = append(args, "--summary", batch.SummaryPath(cfg.Summary, name))
+			}
+
+			logx.Infof("[%d/%d] %s...", i+1, len(jobs), job.Name)
+			start := time.Now()
+			prefix := "[" + job.Name + "] "
+			stderr := &prefixWriter{w: batchStderr, mu: &output, prefix: prefix}
+			stdout := &prefixWriter{w: batchStdout, mu: &output, prefix: prefix}
+			cmd := exec.Command(exe, args...)
+			cmd.Stdout = stdout
+			cmd.Stderr = stderr
+			err := cmd.Run()
+			stdout.Flush()
+			stderr.Flush()
+
+			result := batchJob{job: job, output: stdout.output, err: err, detail: logPrefix.ReplaceAllString(stderr.last, ""), elapsed: time.Since(start)}
+			if result.output == "" {
+				result.output = job.Output
+			}
+			if err != nil {
+				logx.Warnf("[%d/%d] %s failed: %v", i+1, len(jobs), job.Name, err)
+			} else {
+				// A job removes the folder it creates; one resumed from an
+				// earlier failure leaves it behind, emptied
+				os.Remove(tempDir)
+				logx.Infof("[%d/%d] %s done: %s", i+1, len(jobs), job.Name, result.output)
+			}
+			jobs[i] = result
+		}(i, job)
+	}
+	wg.Wait()
+	os.Remove(batchFolder) // Fails, leaving it, while a job's files remain
+
+	failed := 0
+	for _, j := range jobs {
+		if j.err != nil {
+			failed++
+		}
+	}
+
+	fmt.Fprintf(batchStdout, "\nBatch summary: %d succeeded, %d failed\n", len(jobs)-failed, failed)
+	w := tabwriter.NewWriter(batchStdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "JOB\tSTATUS\tTIME\tOUTPUT")
+	for _, j := range jobs {
+		status, detail := "ok", j.output
+		if j.err != nil {
+			status, detail = "failed", j.detail
+			var exitErr *exec.ExitError
+			if errors.As(j.err, &exitErr) && exitErr.ExitCode() == exitMissingInput {
+				status = "missing-input"
+			}
+			if detail == "" {
+				detail = j.err.Error()
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", j.job.Name, status, j.elapsed.Round(time.Second), detail)
+	}
+	w.Flush()
+
+	if failed > 0 {
+		return exitFailure
+	}
+	return 0
+}
+
+// logPrefix matches the date, time, and (with --verbose) source location the
+// standard logger puts before each message
+var logPrefix = regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} (\S+\.go:\d+: )?`)
+
+// prefixWriter writes a batch job's output a line at a time, each line marked
+// with the job, so parallel jobs stay readable. It remembers the last line,
+// which for a failed job is the error, and the video named by successMessage.
+type prefixWriter struct {
+	w      io.Writer
+	mu     *sync.Mutex // Shared by every job writing to the terminal
+	prefix string
+	buf    []byte
+	last   string
+	output string
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		p.writeLine(string(p.buf[:i]))
+		p.buf = p.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush writes a final line that did not end in a newline
+func (p *prefixWriter) Flush() {
+	if len(p.buf) > 0 {
+		p.writeLine(string(p.buf))
+		p.buf = nil
+	}
+}
+
+func (p *prefixWriter) writeLine(line string) {
+	line = strings.TrimRight(line, "\r")
+	if strings.TrimSpace(line) != "" {
+		p.last = line
+	}
+	if path, ok := strings.CutPrefix(line, successMessage); ok {
+		p.output = path
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintln(p.w, p.prefix+line)
+}
+
+// runCacheCommand handles "mmmeld cache prune", which bounds the generated
+// image cache by size and, optionally, age
+func runCacheCommand(args []string) error {
+	if len(args) == 0 || args[0] != "prune" {
+		return fmt.Errorf("usage: mmmeld cache prune [--max-size MB] [--max-age DAYS]")
+	}
+
+	fs := flag.NewFlagSet("mmmeld cache prune", flag.ContinueOnError)
+	maxSizeMB := fs.Int64("max-size", config.DefaultImageCacheMaxSize>>20, "Keep at most this many MB of cached images")
+	maxAgeDays := fs.Int("max-age", 0, "Also remove cached images unused for this many days (0 = no age limit)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *maxSizeMB < 0 || *maxAgeDays < 0 {
+		return fmt.Errorf("--max-size and --max-age must not be negative")
+	}
+
+	removed, freed, err := image.PruneImageCache("", *maxSizeMB<<20, time.Duration(*maxAgeDays)*24*time.Hour)
+	if err != nil {
+		return fmt.Errorf("failed to prune image cache: %w", err)
+	}
+	fmt.Printf("Removed %d cached image(s), freed %.1f MB\n", removed, float64(freed)/(1<<20))
+	return nil
 }
 
 func processInputs(cfg *config.Config, cleanup *fileutil.CleanupManager) error {
 	var audioSource *audio.AudioSource
 	var err error
+	started := time.Now()
+	var timings stageTimings
+
+	// Without a terminal to prompt on, fail before any work instead of
+	// blocking on stdin
+	if cfg.NonInteractive {
+		if missing := cfg.MissingInputs(); len(missing) > 0 {
+			return &stageError{"missing_input", fmt.Errorf("missing required input in non-interactive mode: %s (or use --autofill)", strings.Join(missing, "; "))}
+		}
+	}
+
+	run, err := openRunManifest(cfg)
+	if err != nil {
+		return &stageError{"setup", err}
+	}
+
+	if !cfg.SkipPreflight {
+		completed := make(map[string]bool)
+		if cfg.Resume {
+			for name := range run.Stages {
+				completed[name] = true
+			}
+		}
+		report := preflight.Check(cfg, preflight.Options{Completed: completed})
+		for _, warning := range report.Warnings {
+			logx.Warnf("%s", warning)
+		}
+		if err := report.Err(); err != nil {
+			return &stageError{"preflight", err}
+		}
+	}
 
 	// Handle audio processing
 	if cfg.Audio != "" {
-		log.Println("Processing audio input...")
-		audioSource, err = audio.GetAudioSource(cfg, cleanup)
-		if err != nil {
-			return fmt.Errorf("failed to process audio: %w", err)
+		endStage := timings.begin("audio")
+		audioInputs := stageInputs(cfg, audioStageKeys, nil)
+		if reuseStage(cfg, run, "audio", audioInputs, &audioSource) {
+			registerReused(cleanup, audioSource.Path)
+			logx.Infof("Reusing audio from the previous run: %s (title: %s)", audioSource.Path, audioSource.Title)
+			endStage(true)
+		} else {
+			logx.Infof("Processing audio input...")
+			audioSource, err = getAudioSource(cfg, cleanup)
+			if err != nil {
+				return &stageError{"audio", fmt.Errorf("failed to process audio: %w", err)}
+			}
+			logx.Infof("Audio processed: %s (title: %s)", audioSource.Path, audioSource.Title)
+			completeStage(run, "audio", audioInputs, []string{audioSource.Path}, audioSource)
+			endStage(false)
 		}
-		log.Printf("Audio processed: %s (title: %s)", audioSource.Path, audioSource.Title)
 	} else if !cfg.AutoFill {
 		// Interactive mode for audio
 		audioSource, err = getAudioInteractive(cfg, cleanup)
 		if err != nil {
-			return fmt.Errorf("interactive audio input failed: %w", err)
+			return &stageError{"audio", fmt.Errorf("interactive audio input failed: %w", err)}
 		}
 	}
 
@@ -80,39 +553,75 @@ func processInputs(cfg *config.Config, cleanup *fileutil.CleanupManager) error {
 		title = audioSource.Title
 		description = audioSource.Description
 	}
+	if cfg.Title != "" {
+		title = cfg.Title
+	}
 	if cfg.Image != "" || cfg.AutoFill {
-		log.Println("Processing image/video inputs...")
-		// Pass audio path for potential audio analysis
+		endStage := timings.begin("images")
+		imageInputs := stageInputs(cfg, imageStageKeys, map[string]string{"audio output": run.Digest("audio")})
+		if reuseStage(cfg, run, "images", imageInputs, &mediaInputs) {
+			for _, mi := range mediaInputs {
+				registerReused(cleanup, mi.Path)
+			}
+			logx.Infof("Reusing %d image/video input(s) from the previous run", len(mediaInputs))
+			endStage(true)
+		} else {
+			logx.Infof("Processing image/video inputs...")
+			// Pass audio path for potential audio analysis
+			audioPath := ""
+			if audioSource != nil {
+				audioPath = audioSource.Path
+			}
+			// Ctrl-C cancels generation and validation requests
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			mediaInputs, err = image.GetImageInputsWithAudio(ctx, cfg, title, description, audioPath, cleanup)
+			stop()
+			if err != nil {
+				return &stageError{"images", fmt.Errorf("failed to process images: %w", err)}
+			}
+			paths := make([]string, len(mediaInputs))
+			for i, mi := range mediaInputs {
+				paths[i] = mi.Path
+			}
+			completeStage(run, "images", imageInputs, paths, mediaInputs)
+			endStage(false)
+		}
+	} else {
+		// Interactive mode for images
 		audioPath := ""
 		if audioSource != nil {
 			audioPath = audioSource.Path
 		}
-		mediaInputs, err = image.GetImageInputsWithAudio(cfg, title, description, audioPath, cleanup)
-		if err != nil {
-			return fmt.Errorf("failed to process images: %w", err)
-		}
-	} else {
-		// Interactive mode for images
-		mediaInputs, err = getImagesInteractive(cfg, cleanup, title, description)
+		mediaInputs, err = getImagesInteractive(cfg, cleanup, title, description, audioPath)
 		if err != nil {
-			return fmt.Errorf("interactive image input failed: %w", err)
+			return &stageError{"images", fmt.Errorf("interactive image input failed: %w", err)}
 		}
 	}
 
 	// Ensure we have at least some media input
 	if len(mediaInputs) == 0 {
-		return fmt.Errorf("no image or video inputs provided")
+		return &stageError{"images", fmt.Errorf("no image or video inputs provided")}
 	}
 
 	// Handle background music
 	var bgMusicPath string
 	if cfg.BGMusic != "" {
-		log.Println("Processing background music...")
-		bgMusicPath, err = audio.GetBackgroundMusic(cfg.BGMusic, cleanup)
-		if err != nil {
-			return fmt.Errorf("failed to process background music: %w", err)
+		endStage := timings.begin("bg_music")
+		bgInputs := stageInputs(cfg, bgMusicStageKeys, nil)
+		if reuseStage(cfg, run, "bg_music", bgInputs, &bgMusicPath) {
+			registerReused(cleanup, bgMusicPath)
+			logx.Infof("Reusing background music from the previous run: %s", bgMusicPath)
+			endStage(true)
+		} else {
+			logx.Infof("Processing background music...")
+			bgMusicPath, err = audio.GetBackgroundMusic(cfg.BGMusic, cfg.ForceYtDlp, fileutil.YtDlpOptionsFromConfig(cfg), cleanup)
+			if err != nil {
+				return &stageError{"bg_music", fmt.Errorf("failed to process background music: %w", err)}
+			}
+			logx.Infof("Background music processed: %s", bgMusicPath)
+			completeStage(run, "bg_music", bgInputs, []string{bgMusicPath}, bgMusicPath)
+			endStage(false)
 		}
-		log.Printf("Background music processed: %s", bgMusicPath)
 	}
 
 	// Determine output path
@@ -122,17 +631,34 @@ func processInputs(cfg *config.Config, cleanup *fileutil.CleanupManager) error {
 		if audioSource != nil {
 			audioPath = audioSource.Path
 		}
-		outputPath = fileutil.GetDefaultOutputPath(audioPath)
+		// Generated speech lives in temp_assets under a provider name; its title is more useful
+		outputTitle := ""
+		if cfg.Audio == "generate" {
+			outputTitle = title
+		}
+		outputPath = fileutil.GetDefaultOutputPath(audioPath, outputTitle, cfg.OutputDir)
+	}
+
+	// Default names are only known now, and another run may have written an
+	// explicit --output since the config was checked
+	planned := outputPath
+	outputPath, err = fileutil.ResolveOutputPath(planned, cfg.Overwrite, cfg.AutoNumber)
+	if err != nil {
+		return &stageError{"render", err}
+	}
+	if outputPath != planned {
+		logx.Infof("%s exists; writing %s instead", planned, outputPath)
 	}
 
 	// Ensure output directory exists
 	outputDir := filepath.Dir(outputPath)
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+		return &stageError{"render", fmt.Errorf("failed to create output directory: %w", err)}
 	}
 
 	// Generate video
-	log.Println("Generating video...")
+	endRender := timings.begin("render")
+	logx.Infof("Generating video...")
 	audioPath := ""
 	if audioSource != nil {
 		audioPath = audioSource.Path
@@ -146,10 +672,14 @@ func processInputs(cfg *config.Config, cleanup *fileutil.CleanupManager) error {
 		BGMusicVolume: cfg.BGMusicVolume,
 		AudioMargins:  cfg.AudioMargins,
 		TempFolder:    config.TempAssetsFolder,
+
+		MaxSourceDimension: cfg.MaxSourceDimension,
+		Background:         cfg.Background,
 	}
 
-	if err := video.GenerateVideo(params); err != nil {
-		return fmt.Errorf("failed to generate video: %w", err)
+	settings, err := video.GenerateVideo(params)
+	if err != nil {
+		return &stageError{"render", fmt.Errorf("failed to generate video: %w", err)}
 	}
 
 	if cfg.Cleanup {
@@ -161,19 +691,141 @@ func processInputs(cfg *config.Config, cleanup *fileutil.CleanupManager) error {
 	}
 
 	// Validate the output
+	var validationWarning string
 	expectedDuration, err := video.CalculateTotalDuration(audioPath, mediaInputs, cfg.AudioMargins)
 	if err != nil {
-		log.Printf("Warning: Could not calculate expected duration for validation: %v", err)
+		logx.Warnf("Could not calculate expected duration for validation: %v", err)
 	} else {
 		if err := video.ValidateVideo(outputPath, expectedDuration, audioPath != "" || bgMusicPath != ""); err != nil {
-			log.Printf("Warning: Video validation failed: %v", err)
+			logx.Warnf("Video validation failed: %v", err)
+			validationWarning = err.Error()
 		}
 	}
+	endRender(false)
 
-	fmt.Printf("Video generated successfully: %s\n", outputPath)
+	if err := run.Remove(); err != nil {
+		logx.Warnf("%v", err)
+	}
+
+	summary := buildRunSummary(cfg, outputPath, audioSource, mediaInputs, bgMusicPath, settings, timings)
+	summary.ValidationWarning = validationWarning
+	summary.ElapsedSeconds = roundSeconds(time.Since(started).Seconds())
+	summaryPath := cfg.Summary
+	if summaryPath == "" {
+		summaryPath = defaultSummaryPath(outputPath)
+	}
+	if err := writeRunSummary(summaryPath, summary); err != nil {
+		logx.Warnf("%v", err)
+	} else {
+		logx.Debugf("Run summary written to %s", summaryPath)
+	}
+
+	if cfg.JSON {
+		events.Emit("summary", summary.fields())
+		return nil
+	}
+
+	fmt.Printf("%s%s\n", successMessage, outputPath)
+	if audioSource != nil && audioSource.Duration > 0 {
+		fmt.Printf("Narration duration: %s\n", time.Duration(audioSource.Duration*float64(time.Second)).Round(time.Second/10))
+	}
+	for i, mi := range mediaInputs {
+		if mi.TextOverlay {
+			fmt.Printf("Note: caption on image %d was overlaid with ffmpeg drawtext, not generated\n", i+1)
+		}
+	}
 	return nil
 }
 
+// Settings, by config JSON name, that each resumable stage depends on. A
+// change to any of them makes --resume redo the stage.
+var (
+	audioStageKeys = []string{
+		"audio", "title", "text", "voice_id", "tts_provider", "tts_command", "tts_chunk_size",
+		"tts_model", "tts_speed", "speech_rate", "voices", "dialogue_pause", "lexicon",
+		"elevenlabs_model", "stability", "similarity", "style_exaggeration", "no_speaker_boost",
+		"azure_region", "force_ytdlp", "no_llm_title", "playlist_mode", "playlist_items",
+	}
+	imageStageKeys = []string{
+		"image", "image_description", "image_provider", "title", "auto_fill",
+		"analyze_audio", "audio_notes", "analyze_window", "image_caption", "image_subcaption",
+		"image_avoid", "language", "aspect_ratio", "image_style", "style_type", "style_preset",
+		"negative_prompt", "seed", "replicate_model", "image_quality", "sd_endpoint", "sd_steps",
+		"sd_sampler", "upscale", "fit", "focus", "strict_aspect", "caption_fallback", "caption_font",
+		"caption_font_size", "caption_color", "casing_policy", "reviewer", "review_model",
+		"validation_model", "adaptive_retry", "reference_image", "image_weight", "force_ytdlp",
+		"playlist_mode", "playlist_items",
+	}
+	bgMusicStageKeys = []string{"bg_music", "force_ytdlp"}
+)
+
+// openRunManifest loads the previous run's manifest for --resume, or starts a
+// fresh one that replaces it as stages complete
+func openRunManifest(cfg *config.Config) (*resume.Manifest, error) {
+	if !cfg.Resume {
+		return resume.New(config.TempAssetsFolder), nil
+	}
+	run, err := resume.Load(config.TempAssetsFolder)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resume: %w", err)
+	}
+	if len(run.Stages) == 0 {
+		logx.Infof("No previous run found in %s; running every stage", run.Path())
+	}
+	return run, nil
+}
+
+// stageInputs picks keys out of cfg's JSON form, plus any extra values such
+// as the digest of a stage this one builds on
+func stageInputs(cfg *config.Config, keys []string, extra map[string]string) map[string]string {
+	var all map[string]json.RawMessage
+	if data, err := json.Marshal(cfg); err == nil {
+		json.Unmarshal(data, &all)
+	}
+
+	inputs := make(map[string]string, len(keys)+len(extra))
+	for _, k := range keys {
+		inputs[k] = string(all[k])
+	}
+	for k, v := range extra {
+		inputs[k] = v
+	}
+	return inputs
+}
+
+// reuseStage reports whether --resume can skip stage, decoding its recorded
+// result into result. It logs why a recorded stage has to run again.
+func reuseStage(cfg *config.Config, run *resume.Manifest, stage string, inputs map[string]string, result any) bool {
+	if !cfg.Resume {
+		return false
+	}
+	ok, reason, err := run.Check(stage, inputs, result)
+	if err != nil {
+		logx.Warnf("Redoing %s stage: %v", stage, err)
+		return false
+	}
+	if !ok && run.Stages[stage] != nil {
+		logx.Infof("Redoing %s stage: %s", stage, reason)
+	}
+	return ok
+}
+
+// completeStage records a finished stage. A manifest that cannot be written
+// only costs the ability to resume, so it is not fatal.
+func completeStage(run *resume.Manifest, stage string, inputs map[string]string, files []string, result any) {
+	if err := run.Complete(stage, inputs, files, result); err != nil {
+		logx.Warnf("Could not record %s stage for --resume: %v", stage, err)
+	}
+}
+
+// registerReused hands temp assets from the previous run to the cleanup
+// manager; files outside the temp folder are the user's own inputs
+func registerReused(cleanup *fileutil.CleanupManager, path string) {
+	if fileutil.InTempFolder(path) {
+		cleanup.Add(path)
+	}
+}
+
 // Interactive mode functions
 
 // readLine reads a full line from stdin after printing the prompt.
@@ -204,8 +856,15 @@ func readMultiline(prompt string) string {
 	return strings.Join(lines, "\n")
 }
 
+// getAudioSource runs audio.GetAudioSource with Ctrl-C cancelling its ffmpeg work
+func getAudioSource(cfg *config.Config, cleanup *fileutil.CleanupManager) (*audio.AudioSource, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return audio.GetAudioSource(ctx, cfg, cleanup)
+}
+
 func getAudioInteractive(cfg *config.Config, cleanup *fileutil.CleanupManager) (*audio.AudioSource, error) {
-	input := readLine("Enter audio source (file path, YouTube URL, or 'generate' for TTS): ")
+	input := readLine("Enter audio source (file path, YouTube URL, audio URL, or 'generate' for TTS): ")
 	if input == "" {
 		return nil, nil // No audio
 	}
@@ -221,17 +880,54 @@ func getAudioInteractive(cfg *config.Config, cleanup *fileutil.CleanupManager) (
 		}
 		cfg.Text = text
 
-		voiceID := readLine(fmt.Sprintf("Enter voice ID (default: %s): ", cfg.VoiceID))
-		if voiceID != "" {
-			cfg.VoiceID = voiceID
+		for {
+			voiceID := readLine(fmt.Sprintf("Enter voice ID, or 'list' to see available voices (default: %s): ", cfg.VoiceID))
+			if strings.ToLower(voiceID) == "list" {
+				voices, err := tts.ListVoices(cfg.TTSProvider)
+				if err != nil {
+					fmt.Printf("Could not list voices: %v\n", err)
+					continue
+				}
+				tts.PrintVoices(os.Stdout, voices)
+				continue
+			}
+			if voiceID != "" {
+				cfg.VoiceID = voiceID
+			}
+			break
+		}
+
+		audioSource, err := getAudioSource(cfg, cleanup)
+		if err != nil {
+			return nil, err
 		}
+		previewNarration(audioSource.Path)
+		return audioSource, nil
+	}
+
+	return getAudioSource(cfg, cleanup)
+}
+
+// previewNarration offers to play generated speech before rendering starts
+func previewNarration(path string) {
+	answer := readLine("Preview narration before rendering? (y/N): ")
+	if strings.ToLower(answer) != "y" && strings.ToLower(answer) != "yes" {
+		return
 	}
 
-	return audio.GetAudioSource(cfg, cleanup)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := ffmpeg.PlayAudio(ctx, path); err != nil && ctx.Err() == nil {
+		fmt.Printf("Could not play narration: %v\n", err)
+	}
 }
 
-func getImagesInteractive(cfg *config.Config, cleanup *fileutil.CleanupManager, title, description string) ([]image.MediaInput, error) {
+// getImagesInteractive prompts for image/video sources. With --analyze-audio,
+// generated images without a description are prompted from audioPath, which
+// is analyzed once however many images are entered.
+func getImagesInteractive(cfg *config.Config, cleanup *fileutil.CleanupManager, title, description, audioPath string) ([]image.MediaInput, error) {
 	var results []image.MediaInput
+	analysis := image.NewAudioAnalysis(audioPath)
 
 	fmt.Println("Enter image/video sources (press Enter on empty line to finish):")
 	first := true
@@ -261,7 +957,9 @@ func getImagesInteractive(cfg *config.Config, cleanup *fileutil.CleanupManager,
 			cfg.ImageDescription = ""
 		}
 
-		items, err := image.GetImageInputs(cfg, title, description, cleanup)
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		items, err := image.GetImageInputsWithAnalysis(ctx, cfg, title, description, analysis, cleanup)
+		stop()
 		if err != nil {
 			return nil, err
 		}
@@ -282,7 +980,9 @@ func getImagesInteractive(cfg *config.Config, cleanup *fileutil.CleanupManager,
 		prevDesc := cfg.ImageDescription
 		cfg.Image = "generate"
 		cfg.ImageDescription = "A visually engaging background image"
-		items, err := image.GetImageInputs(cfg, title, description, cleanup)
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		items, err := image.GetImageInputs(ctx, cfg, title, description, cleanup)
+		stop()
 		cfg.Image = prevImage
 		cfg.ImageDescription = prevDesc
 		if err != nil {
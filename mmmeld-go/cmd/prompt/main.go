@@ -1,20 +1,34 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"text/tabwriter"
 	"time"
 
 	"mmmeld/internal/config"
+	"mmmeld/internal/ffmpeg"
 	"mmmeld/internal/fileutil"
 	"mmmeld/internal/genai"
+	"mmmeld/internal/httpx"
 	"mmmeld/internal/image"
+	"mmmeld/internal/logx"
+	"mmmeld/internal/probe"
 )
 
 type OutputFormat string
@@ -29,36 +43,72 @@ func main() {
 	config.SetupLogging()
 
 	// Parse command line arguments
-	audioFile := flag.String("file", "", "Path to the audio file (mp3, wav, aac, etc.)")
+	audioFile := flag.String("file", "", "Path to the audio file (mp3, wav, aac, etc.) or video (mp4, mov, mkv, etc.; its audio track is analyzed)")
 	audioFileShort := flag.String("f", "", "Path to the audio file (shorthand)")
 	title := flag.String("title", "", "Title of the track")
 	titleShort := flag.String("t", "", "Title of the track (shorthand)")
 	notes := flag.String("notes", "", "Context notes (genre, mood, themes, lyrics)")
 	notesShort := flag.String("n", "", "Context notes (shorthand)")
-	style := flag.String("style", "auto", "Preferred visual style: auto, photorealistic, artistic, abstract, cinematic")
-	styleShort := flag.String("s", "auto", "Preferred visual style (shorthand)")
+	style := flag.String("style", "", "Preferred visual style: auto (default), photorealistic, artistic, abstract, cinematic")
+	styleShort := flag.String("s", "", "Preferred visual style (shorthand)")
+	listStylesFlag := flag.Bool("list-styles", false, "List the supported --style and --aspect-ratio values and exit")
+	lenient := flag.Bool("lenient", false, "Warn and use auto/16:9 for an unrecognized --style or --aspect-ratio instead of failing")
 	model := flag.String("model", genai.DefaultModel, "Gemini model to use")
 	save := flag.Bool("save", false, "Save prompt to a text file alongside the audio")
+	output := flag.String("output", "", "Save the prompt (and with --verify, the accepted image and its validation report) to this file or directory instead; implies --save")
+	outputShort := flag.String("o", "", "Output file or directory (shorthand)")
 	jsonOutput := flag.Bool("json", false, "Output in JSON format")
+	interactive := flag.Bool("interactive", false, "After the prompt is printed, accept, regenerate, edit it with an instruction, or verify it, reusing the creative brief (needs --llm gemini)")
+	copyPrompt := flag.Bool("copy", false, "Copy the final prompt to the clipboard (with --quiet, nothing is printed)")
 	quiet := flag.Bool("quiet", false, "Suppress progress messages")
 	quietShort := flag.Bool("q", false, "Suppress progress messages (shorthand)")
 	debug := flag.Bool("debug", false, "Show raw audio analysis from Gemini (for debugging)")
 	debugShort := flag.Bool("d", false, "Show raw audio analysis (shorthand)")
-	verify := flag.Bool("verify", false, "Generate image with Ideogram and verify with Gemini")
+	verify := flag.Bool("verify", false, "Generate image with the image provider and verify with Gemini")
+	var imageProvider string
+	flag.StringVar(&imageProvider, "image-provider", string(config.ImageProviderIdeogram), "Image provider for --verify (ideogram, dalle, gpt-image, imagen, stability, replicate, local-sd)")
+	flag.StringVar(&imageProvider, "provider", string(config.ImageProviderIdeogram), "Image provider for --verify (same as --image-provider)")
+	sdEndpoint := flag.String("sd-endpoint", config.DefaultSDEndpoint, "Local Stable Diffusion (A1111 API) server for --provider local-sd")
 	verifyShort := flag.Bool("v", false, "Generate and verify image (shorthand)")
 	caption := flag.String("caption", "", "Caption/title text to render on the image")
 	captionShort := flag.String("c", "", "Caption text (shorthand)")
 	subcaption := flag.String("subcaption", "", "Subcaption/subtitle text to render on the image")
 	subcaptionShort := flag.String("sc", "", "Subcaption text (shorthand)")
+	casingPolicy := flag.String("casing-policy", string(genai.CasingStrict), "Caption casing validation for --verify: strict (exact, ALL CAPS, or lowercase), any (ignore casing), or exact")
+	reviewer := flag.String("reviewer", genai.ReviewerOpenAI, "Second-opinion reviewer and Gemini fallback: openai, anthropic, ollama, or none")
+	reviewModel := flag.String("review-model", "", "Reviewer model (default: $MMMELD_REVIEW_MODEL, else "+genai.DefaultReviewModel+" for openai or "+genai.DefaultAnthropicReviewModel+" for anthropic; $OLLAMA_MODEL, else "+genai.DefaultOllamaModel+", for ollama)")
+	validationModel := flag.String("validation-model", "", "Gemini model for --verify validation (default: "+strings.TrimPrefix(genai.DefaultValidationModel, "models/")+", retried with "+strings.TrimPrefix(genai.DefaultModel, "models/")+" on error)")
+	llm := flag.String("llm", "", "Prompt-writing model: gemini (audio analysis) or ollama (local, title and notes only); default gemini, or ollama when neither GEMINI_API_KEY nor OPENAI_API_KEY is set")
+	llmModel := flag.String("llm-model", "", "Ollama model for --llm ollama (default: $OLLAMA_MODEL, else "+genai.DefaultOllamaModel+")")
+	geminiRetries := flag.Int("gemini-retries", genai.DefaultQuotaRetries, "Retries with backoff when Gemini reports a quota error, before falling back to the reviewer")
+	noFallback := flag.Bool("no-openai-fallback", false, "Fail instead of falling back to the --reviewer model when Gemini stays over quota")
+	batch := flag.String("batch", "", "Generate a prompt for every audio or video file in this directory (or matching this glob)")
+	watch := flag.String("watch", "", "Watch this directory and generate a prompt for each new audio or video file once it stops growing (Ctrl-C finishes the current file, then exits)")
+	concurrency := flag.Int("concurrency", defaultBatchConcurrency, "Files processed at once in --batch mode")
+	briefTemp := flag.Float64("brief-temp", genai.DefaultBriefTemperature, "Gemini temperature (0-2) for the audio brief pass; lower is more consistent")
+	promptTemp := flag.Float64("prompt-temp", genai.DefaultPromptTemperature, "Gemini temperature (0-2) for the image prompt pass; higher explores more")
+	language := flag.String("language", "", "Language of the caption/subcaption text, e.g. pt-BR; scene descriptions stay English and validation accepts accents")
+	avoid := flag.String("avoid", "", "Comma-separated imagery to avoid, merged into the brief's avoid list (e.g. \"neon city,vinyl record close-up\")")
+	analyzeWindow := flag.String("analyze-window", "", "Analyze only this part of the audio, e.g. 0:60-4:00 (default: whole file; long or large files are compressed before upload)")
+	noAudioAnalysis := flag.Bool("no-audio-analysis", false, "Skip the audio upload and analysis; Gemini writes the prompt from the title, notes, and caption only")
+	noCache := flag.Bool("no-cache", false, "Do not read or write the prompt cache (~/.cache/mmmeld/prompts)")
+	refresh := flag.Bool("refresh", false, "Regenerate even if the prompt cache has this request, replacing the cached prompt")
+	lyrics := flag.Bool("lyrics", false, "Also transcribe the lyrics (instrumental sections are summarized) and use them for the brief's lyric themes")
+	timeout := flag.Duration("timeout", 0, "Give up after this long (e.g. 5m), deleting any uploaded audio; covers analysis, review, and validation (0 = no limit)")
+	retries := flag.Int("retries", defaultVerifyRetries, "With --verify, regenerate up to this many times when the image fails validation, keeping the best attempt")
+	adaptiveRetry := flag.Bool("adaptive-retry", true, "With --verify, add validation feedback to retry prompts (--adaptive-retry=false resends the same prompt)")
+	proxy := flag.String("proxy", "", "Proxy URL for Gemini, reviewer, and --verify image requests, e.g. http://proxy:3128 (default: HTTPS_PROXY, HTTP_PROXY, and NO_PROXY)")
+	caCert := flag.String("ca-cert", "", "PEM CA bundle to trust on top of the system roots, e.g. for a TLS-inspecting proxy")
 	var aspectRatioVal string
-	flag.StringVar(&aspectRatioVal, "aspect-ratio", "16:9", "Aspect ratio for generated image (16:9, 9:16, 1:1, etc.)")
+	flag.StringVar(&aspectRatioVal, "aspect-ratio", "16:9", "Aspect ratio for generated image (16:9, 9:16, 1:1, etc.; see --list-styles)")
 	flag.StringVar(&aspectRatioVal, "ar", "16:9", "Aspect ratio (shorthand)")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Audio to Image Prompt Generator\n\n")
 		fmt.Fprintf(os.Stderr, "Analyzes audio files using Google Gemini to generate detailed image prompts\n")
-		fmt.Fprintf(os.Stderr, "optimized for AI image generators like Ideogram.\n\n")
-		fmt.Fprintf(os.Stderr, "Usage: %s [options] [audio_file]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "optimized for AI image generators like Ideogram. Video files (mp4, mov,\n")
+		fmt.Fprintf(os.Stderr, "mkv, ...) work too: their audio track is extracted with ffmpeg.\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] [audio_or_video_file]\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
@@ -66,62 +116,290 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  %s -f remix.wav -t \"Energy Burst\" -n \"Upbeat electronic dance track\"\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -f audio.mp3 -t \"Peaceful Morning\" -s artistic --save\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s song.mp3 --json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s song.mp3 -t \"Midnight Drive\" --copy -q\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s song.mp3 -t \"Midnight Drive\" --interactive --save\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s live-set.mp4 -n \"Concert recording\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s song.mp3 --verify --provider stability\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s song.mp3 --verify --provider local-sd --sd-endpoint http://gpu-box:7860\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s /mnt/share/song.mp3 --verify -c \"Midnight Drive\" -o ~/covers/\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --batch album/ -n \"Indie folk album\" --concurrency 2\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s 'album/*.mp3' --json > prompts.json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --watch ~/Music/exports/ -n \"Late-night mixes\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s song.mp3 -t \"Midnight Drive\" -n \"Slow synthwave\" --llm ollama\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "\nEnvironment Variables:\n")
-		fmt.Fprintf(os.Stderr, "  GEMINI_API_KEY    Required. Your Google Gemini API key.\n")
+		fmt.Fprintf(os.Stderr, "  GEMINI_API_KEY    Your Google Gemini API key (not needed with --llm ollama).\n")
+		fmt.Fprintf(os.Stderr, "  OLLAMA_HOST       Ollama server for --llm/--reviewer ollama (default: localhost:11434).\n")
+		fmt.Fprintf(os.Stderr, "  OLLAMA_MODEL      Default Ollama model.\n")
+		fmt.Fprintf(os.Stderr, "  IDEOGRAM_API_KEY, OPENAI_API_KEY, STABILITY_API_KEY, REPLICATE_API_TOKEN\n")
+		fmt.Fprintf(os.Stderr, "                    Needed by --verify for the chosen --provider (checked before\n")
+		fmt.Fprintf(os.Stderr, "                    the prompt is generated; imagen uses GEMINI_API_KEY).\n")
+		fmt.Fprintf(os.Stderr, "\nExit Codes:\n")
+		fmt.Fprintf(os.Stderr, "  0  Success (with --verify, the image passed validation)\n")
+		fmt.Fprintf(os.Stderr, "  1  Invalid arguments, or an output file could not be written\n")
+		fmt.Fprintf(os.Stderr, "  2  Prompt generation failed (in --batch mode, for every file)\n")
+		fmt.Fprintf(os.Stderr, "  3  --verify image generation failed\n")
+		fmt.Fprintf(os.Stderr, "  4  --verify validation failed, or the image did not match the prompt or had blockers\n")
+		fmt.Fprintf(os.Stderr, "  With --json, failed and --verify runs end with {\"status\", \"exit_code\", ...} on stdout.\n")
 	}
 
 	flag.Parse()
 
-	// Handle positional argument for audio file
+	if *listStylesFlag {
+		listStyles(os.Stdout)
+		return
+	}
+
+	if err := httpx.Configure(*proxy, *caCert); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Handle positional argument for audio file; a glob pattern means batch mode
 	audioPath := coalesce(*audioFile, *audioFileShort)
 	if audioPath == "" && flag.NArg() > 0 {
 		audioPath = flag.Arg(0)
 	}
-
-	if audioPath == "" {
-		fmt.Fprintln(os.Stderr, "Error: Please provide an audio file using -file or as a positional argument")
-		flag.Usage()
-		os.Exit(1)
+	batchPattern := *batch
+	if batchPattern == "" && isGlobPattern(audioPath) {
+		batchPattern, audioPath = audioPath, ""
 	}
 
-	// Expand path (handle ~)
-	audioPath = expandPath(audioPath)
+	watchDir := expandPath(*watch)
+	var batchFiles []string
+	if watchDir != "" {
+		if info, err := os.Stat(watchDir); err != nil || !info.IsDir() {
+			fmt.Fprintf(os.Stderr, "Error: --watch needs an existing directory, got '%s'\n", watchDir)
+			os.Exit(1)
+		}
+		if batchPattern != "" || audioPath != "" || *verify || *verifyShort {
+			fmt.Fprintln(os.Stderr, "Error: --watch cannot be combined with an audio file, --batch, or --verify")
+			os.Exit(1)
+		}
+		if coalesce(*title, *titleShort) != "" {
+			fmt.Fprintln(os.Stderr, "Warning: --title is ignored in watch mode; each title comes from its file name")
+		}
+		if *copyPrompt {
+			fmt.Fprintln(os.Stderr, "Warning: --copy is ignored in watch mode")
+		}
+		if out := coalesce(*output, *outputShort); out != "" && !isOutputDir(expandPath(out)) {
+			fmt.Fprintln(os.Stderr, "Error: --output must be a directory in watch mode")
+			os.Exit(1)
+		}
+	} else if batchPattern != "" {
+		var err error
+		batchFiles, err = batchAudioFiles(expandPath(batchPattern))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *verify || *verifyShort {
+			fmt.Fprintln(os.Stderr, "Error: --verify cannot be combined with --batch")
+			os.Exit(1)
+		}
+		if *concurrency < 1 {
+			fmt.Fprintln(os.Stderr, "Error: --concurrency must be at least 1")
+			os.Exit(1)
+		}
+		if coalesce(*title, *titleShort) != "" {
+			fmt.Fprintln(os.Stderr, "Warning: --title is ignored in batch mode; each title comes from its file name")
+		}
+		if *copyPrompt {
+			fmt.Fprintln(os.Stderr, "Warning: --copy is ignored in batch mode")
+		}
+		if out := coalesce(*output, *outputShort); out != "" && !isOutputDir(expandPath(out)) {
+			fmt.Fprintln(os.Stderr, "Error: --output must be a directory in batch mode")
+			os.Exit(1)
+		}
+	} else {
+		if audioPath == "" {
+			fmt.Fprintln(os.Stderr, "Error: Please provide an audio file using -file or as a positional argument")
+			flag.Usage()
+			os.Exit(1)
+		}
 
-	// Validate file exists
-	if _, err := os.Stat(audioPath); os.IsNotExist(err) {
-		fmt.Fprintf(os.Stderr, "Error: Audio file '%s' not found.\n", audioPath)
-		os.Exit(1)
-	}
+		// Expand path (handle ~)
+		audioPath = expandPath(audioPath)
 
-	// Validate it's an audio file
-	if !genai.IsAudioFile(audioPath) {
-		fmt.Fprintf(os.Stderr, "Warning: '%s' may not be a recognized audio format.\n", audioPath)
+		// Validate file exists
+		if _, err := os.Stat(audioPath); os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Error: Audio file '%s' not found.\n", audioPath)
+			os.Exit(1)
+		}
+
+		// Validate it's an audio file, or a video whose audio track is used
+		if !genai.IsAudioFile(audioPath) && !ffmpeg.IsVideoInput(audioPath) {
+			fmt.Fprintf(os.Stderr, "Warning: '%s' may not be a recognized audio format.\n", audioPath)
+		}
 	}
 
 	// Coalesce options
 	titleVal := coalesce(*title, *titleShort)
 	notesVal := coalesce(*notes, *notesShort)
-	styleVal := coalesce(*style, *styleShort)
+	styleVal := coalesce(*style, *styleShort, string(genai.StyleAuto))
 	quietVal := *quiet || *quietShort || *jsonOutput
 	debugVal := *debug || *debugShort
+	// Shared packages such as image log through logx
+	if quietVal {
+		logx.SetLevel(logx.LevelWarn)
+	} else if debugVal {
+		logx.SetLevel(logx.LevelDebug)
+	}
 	verifyVal := *verify || *verifyShort
 	captionVal := coalesce(*caption, *captionShort)
 	subcaptionVal := coalesce(*subcaption, *subcaptionShort)
+	outputVal := expandPath(coalesce(*output, *outputShort))
 	// aspectRatioVal is already set via StringVar
 
-	// Map style string to StylePreference
-	stylePreference := mapStylePreference(styleVal)
+	// Typos fail here, before any API call, unless --lenient
+	stylePreference, err := parseStylePreference(styleVal)
+	if err != nil {
+		if !*lenient {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %v; using %s (--lenient)\n", err, genai.StyleAuto)
+		stylePreference = genai.StyleAuto
+	}
+	aspectRatio, err := config.ParseAspectRatio(aspectRatioVal)
+	if err != nil {
+		if !*lenient {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %v; using %s (--lenient)\n", err, config.AspectRatio16x9)
+		aspectRatio = config.AspectRatio16x9
+	}
+
+	provider := config.ImageProvider(imageProvider)
+	switch provider {
+	case config.ImageProviderIdeogram, config.ImageProviderDALLE, config.ImageProviderGPTImage, config.ImageProviderImagen, config.ImageProviderStability, config.ImageProviderReplicate, config.ImageProviderLocalSD:
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid image provider '%s' (must be ideogram, dalle, gpt-image, imagen, stability, replicate, or local-sd)\n", provider)
+		os.Exit(1)
+	}
+	// Fail before the prompt is generated rather than after
+	if verifyVal {
+		if env := provider.KeyEnv(); env != "" && os.Getenv(env) == "" {
+			fmt.Fprintf(os.Stderr, "Error: --verify with provider %s needs %s\n", provider, env)
+			os.Exit(1)
+		}
+	}
 
-	// Create context
-	ctx := context.Background()
+	casing := genai.CasingPolicy(*casingPolicy)
+	switch casing {
+	case genai.CasingStrict, genai.CasingAny, genai.CasingExact:
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid casing policy '%s' (must be strict, any, or exact)\n", casing)
+		os.Exit(1)
+	}
 
-	// Create client
-	client, err := genai.NewClient(ctx)
-	if err != nil {
-		outputError(err, *jsonOutput)
+	if *retries < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --retries cannot be negative")
+		os.Exit(1)
+	}
+
+	switch *reviewer {
+	case genai.ReviewerOpenAI, genai.ReviewerAnthropic, genai.ReviewerOllama, genai.ReviewerNone:
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid reviewer '%s' (must be openai, anthropic, ollama, or none)\n", *reviewer)
 		os.Exit(1)
 	}
 
+	// With no cloud keys at all, the only option left is a local model
+	llmVal := *llm
+	if llmVal == "" {
+		llmVal = llmGemini
+		if os.Getenv("GEMINI_API_KEY") == "" && os.Getenv("OPENAI_API_KEY") == "" {
+			fmt.Fprintln(os.Stderr, "Warning: neither GEMINI_API_KEY nor OPENAI_API_KEY is set; using a local Ollama model (--llm ollama)")
+			llmVal = llmOllama
+		}
+	}
+	switch llmVal {
+	case llmGemini:
+		if *noAudioAnalysis && *lyrics {
+			fmt.Fprintln(os.Stderr, "Warning: --lyrics needs audio analysis and is ignored with --no-audio-analysis")
+		}
+	case llmOllama:
+		if *lyrics {
+			fmt.Fprintln(os.Stderr, "Warning: --lyrics needs Gemini and is ignored with --llm ollama")
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid --llm '%s' (must be gemini or ollama)\n", llmVal)
+		os.Exit(1)
+	}
+	if *interactive {
+		switch {
+		case batchFiles != nil || watchDir != "":
+			fmt.Fprintln(os.Stderr, "Error: --interactive cannot be combined with --batch or --watch")
+			os.Exit(1)
+		case *jsonOutput:
+			fmt.Fprintln(os.Stderr, "Error: --interactive cannot be combined with --json")
+			os.Exit(1)
+		case llmVal != llmGemini:
+			fmt.Fprintln(os.Stderr, "Error: --interactive revises the prompt with Gemini and needs --llm gemini")
+			os.Exit(1)
+		case *noAudioAnalysis:
+			fmt.Fprintln(os.Stderr, "Error: --interactive revises the prompt from the audio brief and cannot be combined with --no-audio-analysis")
+			os.Exit(1)
+		}
+	}
+
+	for name, temp := range map[string]float64{"--brief-temp": *briefTemp, "--prompt-temp": *promptTemp} {
+		if temp < 0 || temp > 2 {
+			fmt.Fprintf(os.Stderr, "Error: %s must be between 0 and 2\n", name)
+			os.Exit(1)
+		}
+	}
+
+	if *timeout < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --timeout cannot be negative\n")
+		os.Exit(1)
+	}
+
+	if *geminiRetries < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --gemini-retries cannot be negative\n")
+		os.Exit(1)
+	}
+
+	var window *probe.AudioWindow
+	if *analyzeWindow != "" {
+		var err error
+		if window, err = probe.ParseAudioWindow(*analyzeWindow); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Create context: Ctrl-C and --timeout cancel the run, and Close still
+	// deletes the uploaded audio. Watch mode applies both per file instead.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *timeout > 0 && watchDir == "" {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	// Create client; the local path needs no Gemini client
+	generate := genai.GenerateMetadataPrompt
+	var revise reviseFunc
+	closeClient := func() {}
+	if llmVal == llmGemini {
+		client, err := genai.NewClient(ctx)
+		if err != nil {
+			outputError(&stageError{exitPromptFailed, err}, *jsonOutput)
+			os.Exit(exitPromptFailed)
+		}
+		generate = client.GenerateImagePrompt
+		revise = client.RevisePrompt
+		closeClient = func() {
+			if err := client.Close(); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}
+	}
+	defer closeClient()
+
 	// Generate the prompt
 	opts := genai.PromptOptions{
 		Title:           titleVal,
@@ -132,33 +410,764 @@ func main() {
 		Model:           *model,
 		Quiet:           quietVal,
 		Debug:           debugVal,
+		CasingPolicy:    casing,
+		Reviewer:        *reviewer,
+		ReviewModel:     *reviewModel,
+		Retries:         *geminiRetries,
+		NoFallback:      *noFallback,
+		Transcribe:      *lyrics,
+		Avoid:           genai.ParseAvoidList(*avoid),
+		Language:        *language,
+		SkipAudio:       *noAudioAnalysis,
+		AnalyzeWindow:   window,
+		NoCache:         *noCache,
+		Refresh:         *refresh,
+
+		BriefTemperature:  float32Ptr(*briefTemp),
+		PromptTemperature: float32Ptr(*promptTemp),
+	}
+	if llmVal == llmOllama {
+		// The local model both writes and reviews the prompt
+		opts.Reviewer = genai.ReviewerOllama
+		opts.ReviewModel = *llmModel
+	}
+
+	if watchDir != "" {
+		code := runWatch(ctx, stop, generate, watchDir, opts, *timeout, *jsonOutput, outputVal)
+		closeClient()
+		os.Exit(code)
+	}
+
+	if batchFiles != nil {
+		code := runBatch(ctx, generate, batchFiles, opts, *concurrency, *jsonOutput, outputVal)
+		closeClient()
+		os.Exit(code)
 	}
 
-	result, err := client.GenerateImagePrompt(audioPath, opts)
+	result, err := generate(ctx, audioPath, opts)
 	if err != nil {
-		outputError(err, *jsonOutput)
-		os.Exit(1)
+		outputError(&stageError{exitPromptFailed, err}, *jsonOutput)
+		closeClient()
+		os.Exit(exitPromptFailed)
 	}
 
-	// Output the result
+	// Output the result; --copy --quiet leaves the clipboard as the only output
 	if *jsonOutput {
 		outputJSON(result)
-	} else {
+	} else if !(*copyPrompt && quietVal) {
 		outputText(result)
+		if !quietVal && result.Usage != nil {
+			fmt.Println(result.Usage.Summary())
+		}
+	}
+
+	outputs := resolveOutputPaths(outputVal, audioPath)
+	saveVal := *save || outputVal != ""
+	// With --json, stdout carries only the JSON, so the human-readable
+	// verification report goes to stderr
+	verifyOut := io.Writer(os.Stdout)
+	if *jsonOutput {
+		verifyOut = os.Stderr
+	}
+	verifyPrompt := func(prompt string, outputs outputPaths) (string, error) {
+		return verifyImageGeneration(ctx, prompt, titleVal, captionVal, subcaptionVal, aspectRatio, provider, genai.ValidationOptions{
+			Casing:      opts.CasingPolicy,
+			Reviewer:    opts.Reviewer,
+			ReviewModel: opts.ReviewModel,
+			Retries:     opts.Retries,
+			Model:       *validationModel,
+			NoFallback:  opts.NoFallback,
+			Language:    opts.Language,
+		}, opts.Avoid, *sdEndpoint, *retries, *adaptiveRetry, debugVal, quietVal, verifyOut, outputs)
+	}
+
+	// Interactive refinement reuses the brief; the saved file is written
+	// first and each revision appended to it. A fallback prompt has no brief
+	// to revise from.
+	interactiveVal := *interactive
+	if interactiveVal && result.Brief == nil {
+		fmt.Fprintln(os.Stderr, "Warning: --interactive skipped: the prompt was written without an audio brief, so there is nothing to revise")
+		interactiveVal = false
+	}
+	if interactiveVal {
+		if saveVal {
+			if err := savePromptToFile(result, outputs.prompt); err != nil {
+				fmt.Fprintf(os.Stderr, "Error saving prompt: %v\n", err)
+				closeClient()
+				os.Exit(exitUsage)
+			}
+		}
+		onRevision := func(n int, label, prompt string) {
+			if !saveVal {
+				return
+			}
+			if err := appendRevision(outputs.prompt, n, label, prompt); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}
+		prompt, n, err := runInteractive(ctx, os.Stdin, os.Stdout, result, opts, revise, func(prompt string) {
+			if _, err := verifyPrompt(prompt, outputPaths{}); err != nil {
+				log.Printf("Verify: %v", err)
+			}
+		}, onRevision)
+		if err != nil {
+			log.Printf("Error: %v", err)
+			closeClient()
+			os.Exit(exitPromptFailed)
+		}
+		result.Prompt = prompt
+		if !quietVal && n > 1 && result.Usage != nil {
+			fmt.Printf("\nSession total, %s\n", result.Usage.Summary())
+		}
+		if saveVal {
+			if err := appendRevision(outputs.prompt, n, "", ""); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+			if !quietVal {
+				fmt.Printf("\nPrompt and revisions saved to: %s\n", outputs.prompt)
+			}
+		}
+	}
+
+	if *copyPrompt {
+		if method, err := copyToClipboard(result.Prompt); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: prompt not copied: %v\n", err)
+		} else if !quietVal {
+			fmt.Printf("✓ Prompt copied to clipboard (%s)\n", method)
+		}
 	}
 
 	// If verify mode, generate image and validate it
+	var imagePath string
+	var verifyErr error
 	if verifyVal {
-		verifyImageGeneration(result.Prompt, titleVal, captionVal, subcaptionVal, aspectRatioVal, quietVal)
+		imagePath, verifyErr = verifyPrompt(result.Prompt, outputs)
 	}
 
 	// Save to file if requested
-	if *save {
-		outputPath := savePromptToFile(result)
+	if saveVal && !interactiveVal {
+		if err := savePromptToFile(result, outputs.prompt); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving prompt: %v\n", err)
+			closeClient()
+			os.Exit(exitUsage)
+		}
 		if !quietVal {
-			fmt.Printf("\nPrompt saved to: %s\n", outputPath)
+			fmt.Printf("\nPrompt saved to: %s\n", outputs.prompt)
 		}
 	}
+
+	if verifyVal {
+		if *jsonOutput {
+			outputStatus(verifyErr, imagePath, provider)
+		} else if verifyErr != nil {
+			log.Printf("Error: %v", verifyErr)
+		}
+		// os.Exit skips the deferred close, so delete the upload first
+		closeClient()
+		os.Exit(exitCode(verifyErr))
+	}
+}
+
+// isGlobPattern reports whether path is a batch glob rather than a file:
+// it has glob characters and no file by that name exists, so "Song [Live].mp3"
+// is still analyzed on its own
+func isGlobPattern(path string) bool {
+	if !strings.ContainsAny(path, "*?[") {
+		return false
+	}
+	_, err := os.Stat(expandPath(path))
+	return err != nil
+}
+
+// Exit codes, so scripts can tell which stage failed
+const (
+	exitOK               = 0
+	exitUsage            = 1 // invalid arguments, or an output file could not be written
+	exitPromptFailed     = 2
+	exitImageFailed      = 3
+	exitValidationFailed = 4 // the check failed or the image did not pass it
+)
+
+// exitStatus names each exit code in --json status objects
+var exitStatus = map[int]string{
+	exitOK:               "ok",
+	exitUsage:            "error",
+	exitPromptFailed:     "prompt_failed",
+	exitImageFailed:      "image_failed",
+	exitValidationFailed: "validation_failed",
+}
+
+// stageError is a failure carrying the exit code of the stage it happened in
+type stageError struct {
+	code int
+	err  error
+}
+
+func (e *stageError) Error() string { return e.err.Error() }
+func (e *stageError) Unwrap() error { return e.err }
+
+// exitCode returns the exit code for a run's error
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var stage *stageError
+	if errors.As(err, &stage) {
+		return stage.code
+	}
+	return exitUsage
+}
+
+// outputStatus prints the --json object that ends a failed or --verify run
+func outputStatus(err error, imagePath string, provider config.ImageProvider) {
+	code := exitCode(err)
+	output := map[string]interface{}{
+		"status":    exitStatus[code],
+		"exit_code": code,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	}
+	if err != nil {
+		output["error"] = err.Error()
+	}
+	if imagePath != "" {
+		output["image"] = imagePath
+	}
+	if provider != "" {
+		output["provider"] = string(provider)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(output)
+}
+
+// clipboardCommands returns the clipboard tools to try, in order, for an OS;
+// wayland prefers wl-copy, and clip.exe covers WSL
+func clipboardCommands(goos string, wayland bool) [][]string {
+	switch goos {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"clip.exe"}}
+	}
+	commands := [][]string{
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+		{"clip.exe"},
+	}
+	if wayland {
+		commands = append([][]string{{"wl-copy"}}, commands...)
+	}
+	return commands
+}
+
+// copyToClipboard puts text on the system clipboard and returns the method
+// used. Without a clipboard tool, the text is sent to the terminal as an
+// OSC 52 escape sequence, which most modern terminals (including over SSH)
+// copy to the clipboard.
+func copyToClipboard(text string) (string, error) {
+	for _, cmd := range clipboardCommands(runtime.GOOS, os.Getenv("WAYLAND_DISPLAY") != "") {
+		if _, err := exec.LookPath(cmd[0]); err != nil {
+			continue
+		}
+		execCmd := exec.Command(cmd[0], cmd[1:]...)
+		execCmd.Stdin = strings.NewReader(text)
+		if err := execCmd.Run(); err != nil {
+			return "", fmt.Errorf("%s failed: %w", cmd[0], err)
+		}
+		return cmd[0], nil
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return "", fmt.Errorf("no clipboard tool found (install pbcopy, wl-copy, xclip, or xsel) and no terminal for OSC 52")
+	}
+	defer tty.Close()
+	if _, err := fmt.Fprint(tty, osc52(text)); err != nil {
+		return "", fmt.Errorf("failed to write OSC 52 sequence: %w", err)
+	}
+	return "terminal OSC 52", nil
+}
+
+// osc52 is the terminal escape sequence that sets the clipboard to text
+func osc52(text string) string {
+	return "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+}
+
+// reviseFunc rewrites a prompt from its brief; see genai.Client.RevisePrompt
+type reviseFunc func(ctx context.Context, brief *genai.AudioBrief, previous, instruction string, opts genai.PromptOptions) (string, *genai.Usage, error)
+
+// runInteractive asks whether to accept, regenerate, edit, or verify the
+// prompt until it is accepted or the input ends, and returns the accepted
+// prompt and its revision number (the original is 1). Regenerations and
+// edits rerun pass 2 on result's brief, and their token usage is added to
+// result.Usage; onRevision gets each new prompt.
+func runInteractive(ctx context.Context, in io.Reader, out io.Writer, result *genai.PromptResult, opts genai.PromptOptions, revise reviseFunc, verify func(prompt string), onRevision func(n int, label, prompt string)) (string, int, error) {
+	// Read on the side so Ctrl-C is noticed while waiting for input
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	read := func(question string) (string, bool, error) {
+		fmt.Fprint(out, question)
+		select {
+		case <-ctx.Done():
+			return "", false, ctx.Err()
+		case line, ok := <-lines:
+			if !ok {
+				fmt.Fprintln(out)
+			}
+			return strings.TrimSpace(line), ok, nil
+		}
+	}
+
+	prompt, n := result.Prompt, 1
+	for {
+		answer, ok, err := read("\n(a)ccept, (r)egenerate, (e)dit, (v)erify? ")
+		if err != nil {
+			return prompt, n, err
+		}
+		if !ok {
+			return prompt, n, nil
+		}
+
+		var instruction, label string
+		switch strings.ToLower(answer) {
+		case "a", "accept":
+			return prompt, n, nil
+		case "v", "verify":
+			verify(prompt)
+			continue
+		case "r", "regenerate":
+			label = "regenerated"
+		case "e", "edit":
+			instruction, ok, err = read("Instruction (e.g. same idea but at night): ")
+			if err != nil {
+				return prompt, n, err
+			}
+			if !ok {
+				return prompt, n, nil
+			}
+			if instruction == "" {
+				fmt.Fprintln(out, "No instruction given.")
+				continue
+			}
+			label = "edit: " + instruction
+		default:
+			fmt.Fprintln(out, "Please answer a, r, e, or v.")
+			continue
+		}
+
+		revised, usage, err := revise(ctx, result.Brief, prompt, instruction, opts)
+		if usage != nil {
+			if result.Usage == nil {
+				result.Usage = &genai.Usage{}
+			}
+			result.Usage.Merge(usage)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return prompt, n, ctx.Err()
+			}
+			fmt.Fprintf(out, "Revision failed: %v\n", err)
+			continue
+		}
+		prompt = revised
+		n++
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, strings.Repeat("=", 60))
+		fmt.Fprintf(out, "REVISION %d (%s)\n", n, label)
+		fmt.Fprintln(out, strings.Repeat("=", 60))
+		fmt.Fprintln(out, prompt)
+		fmt.Fprintln(out, strings.Repeat("=", 60))
+		if !opts.Quiet && usage != nil {
+			fmt.Fprintln(out, usage.Summary())
+		}
+		onRevision(n, label, prompt)
+	}
+}
+
+// appendRevision adds a numbered revision to a saved prompt file; an empty
+// prompt records which revision was accepted
+func appendRevision(path string, n int, label, prompt string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to append revision: %w", err)
+	}
+	defer f.Close()
+	if prompt == "" {
+		_, err = fmt.Fprintf(f, "\n%s\nAccepted revision %d\n", strings.Repeat("-", 50), n)
+	} else {
+		_, err = fmt.Fprintf(f, "\n%s\nRevision %d (%s)\n%s\n", strings.Repeat("-", 50), n, label, prompt)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to append revision: %w", err)
+	}
+	return nil
+}
+
+// defaultVerifyRetries gives --verify three attempts in all
+const defaultVerifyRetries = 2
+
+// defaultBatchConcurrency keeps batch runs under typical Gemini rate limits
+const defaultBatchConcurrency = 2
+
+// --llm values
+const (
+	llmGemini = "gemini"
+	llmOllama = "ollama"
+)
+
+// generateFunc writes the prompt for one audio file: Gemini audio analysis,
+// or the metadata-only path for --llm ollama
+type generateFunc func(ctx context.Context, audioPath string, opts genai.PromptOptions) (*genai.PromptResult, error)
+
+// batchAudioFiles lists the audio and video files in a directory, or matching a glob
+// pattern, in name order
+func batchAudioFiles(pattern string) ([]string, error) {
+	var candidates []string
+	if info, err := os.Stat(pattern); err == nil && info.IsDir() {
+		entries, err := os.ReadDir(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read batch directory: %w", err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				candidates = append(candidates, filepath.Join(pattern, entry.Name()))
+			}
+		}
+	} else {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid batch pattern %q: %w", pattern, err)
+		}
+		candidates = matches
+	}
+
+	var files []string
+	for _, path := range candidates {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() && isPromptInput(path) {
+			files = append(files, path)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no audio or video files found for %q", pattern)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// isPromptInput reports whether a file can be given to the prompt pipeline
+func isPromptInput(path string) bool {
+	return genai.IsAudioFile(path) || ffmpeg.IsVideoInput(path)
+}
+
+// batchItem is the outcome of one file in a batch run
+type batchItem struct {
+	path    string
+	result  *genai.PromptResult
+	saved   string
+	err     error
+	elapsed time.Duration
+}
+
+// runBatch generates a prompt for each file, at most concurrency at a time,
+// and returns the exit code: non-zero only if every file failed. Text mode
+// saves a prompt file per track, next to it or in the output directory; JSON
+// mode prints one array.
+func runBatch(ctx context.Context, generate generateFunc, files []string, opts genai.PromptOptions, concurrency int, jsonOutput bool, output string) int {
+	// Progress dots from parallel uploads would interleave
+	opts.Quiet = true
+	opts.Title = ""
+
+	items := make([]batchItem, len(files))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, path := range files {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			log.Printf("[%d/%d] %s...", i+1, len(files), filepath.Base(path))
+			start := time.Now()
+			result, err := generate(ctx, path, opts)
+			item := batchItem{path: path, result: result, err: err, elapsed: time.Since(start)}
+			if err != nil {
+				log.Printf("[%d/%d] %s failed: %v", i+1, len(files), filepath.Base(path), err)
+			} else if !jsonOutput {
+				saved := resolveOutputPaths(output, path).prompt
+				if err := savePromptToFile(result, saved); err != nil {
+					log.Printf("[%d/%d] %s: %v", i+1, len(files), filepath.Base(path), err)
+					item.err = err
+				} else {
+					item.saved = saved
+				}
+			}
+			items[i] = item
+		}(i, path)
+	}
+	wg.Wait()
+
+	failed := 0
+	usage := &genai.Usage{}
+	for _, item := range items {
+		if item.err != nil {
+			failed++
+			continue
+		}
+		usage.Merge(item.result.Usage)
+	}
+
+	summary := os.Stdout
+	if jsonOutput {
+		output := make([]map[string]interface{}, 0, len(items))
+		for _, item := range items {
+			if item.err != nil {
+				output = append(output, map[string]interface{}{
+					"audio_file": item.path,
+					"error":      item.err.Error(),
+				})
+				continue
+			}
+			output = append(output, resultJSON(item.result))
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(output)
+		summary = os.Stderr
+	}
+
+	fmt.Fprintf(summary, "\nBatch summary: %d succeeded, %d failed\n", len(items)-failed, failed)
+	w := tabwriter.NewWriter(summary, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tSTATUS\tTIME\tDETAIL")
+	for _, item := range items {
+		status, detail := "ok", item.saved
+		if item.err != nil {
+			status, detail = "failed", strings.SplitN(item.err.Error(), "\n", 2)[0]
+		} else if item.result.Fallback {
+			status = "fallback"
+		} else if item.result.AudioSkipped {
+			status = "no-audio"
+		} else if item.result.Cached {
+			status = "cached"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", filepath.Base(item.path), status, item.elapsed.Round(time.Second), detail)
+	}
+	w.Flush()
+	fmt.Fprintln(summary, usage.Summary())
+
+	if failed == len(items) {
+		return exitPromptFailed
+	}
+	return exitOK
+}
+
+// watchPollInterval is how often --watch rescans its directory; a file is
+// ready once its size and modification time hold for one interval
+const watchPollInterval = 3 * time.Second
+
+// watchStateFile, kept in the watched directory, lists the files already
+// processed so a restarted watch skips them
+const watchStateFile = ".mmmeld-prompt-watch.json"
+
+// watchMaxAttempts is how often --watch tries a file that keeps failing
+// before leaving it until it changes
+const watchMaxAttempts = 3
+
+// fileSnapshot is a file's size and modification time at one poll
+type fileSnapshot struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// watchEntry records one processed file; a file that changes afterwards is
+// processed again, and a failed one is retried up to watchMaxAttempts times
+type watchEntry struct {
+	fileSnapshot
+	Saved    string `json:"saved,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Attempts int    `json:"attempts,omitempty"` // Failed tries of this version of the file
+}
+
+// settled reports whether the file in snap needs no further processing: it
+// is unchanged since this entry and either succeeded or ran out of attempts
+func (e watchEntry) settled(snap fileSnapshot) bool {
+	if e.Size != snap.Size || !e.ModTime.Equal(snap.ModTime) {
+		return false
+	}
+	return e.Error == "" || e.Attempts >= watchMaxAttempts
+}
+
+// watchState is the --watch state file
+type watchState struct {
+	path  string
+	Files map[string]watchEntry `json:"files"`
+}
+
+// loadWatchState reads dir's state file; a missing file is an empty state
+func loadWatchState(dir string) (*watchState, error) {
+	state := &watchState{path: filepath.Join(dir, watchStateFile), Files: map[string]watchEntry{}}
+	data, err := os.ReadFile(state.path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watch state: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse watch state %s: %w", state.path, err)
+	}
+	if state.Files == nil {
+		state.Files = map[string]watchEntry{}
+	}
+	return state, nil
+}
+
+// record marks a file processed and rewrites the state file
+func (s *watchState) record(name string, entry watchEntry) error {
+	s.Files[name] = entry
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode watch state: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write watch state: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// pollWatchDir returns the unprocessed (or failed, with attempts left) audio
+// and video files in dir whose snapshot matches the previous poll's, in name
+// order, and updates seen
+func pollWatchDir(dir string, seen map[string]fileSnapshot, state *watchState) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	var ready []string
+	current := map[string]bool{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !isPromptInput(name) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		snap := fileSnapshot{Size: info.Size(), ModTime: info.ModTime()}
+		current[name] = true
+		if done, ok := state.Files[name]; ok && done.settled(snap) {
+			continue
+		}
+		if prev, ok := seen[name]; ok && prev.Size == snap.Size && prev.ModTime.Equal(snap.ModTime) && snap.Size > 0 {
+			ready = append(ready, filepath.Join(dir, name))
+		}
+		seen[name] = snap
+	}
+	for name := range seen {
+		if !current[name] {
+			delete(seen, name)
+		}
+	}
+	return ready, nil
+}
+
+// runWatch generates a prompt for each new file in dir until ctx is
+// cancelled by Ctrl-C, which lets the in-flight file finish; a second Ctrl-C
+// aborts it. timeout bounds each file. Text mode saves a prompt file per
+// track; JSON mode prints one object per line. It returns the exit code.
+func runWatch(ctx context.Context, releaseSignal func(), generate generateFunc, dir string, opts genai.PromptOptions, timeout time.Duration, jsonOutput bool, output string) int {
+	opts.Title = ""
+	state, err := loadWatchState(dir)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return exitUsage
+	}
+
+	// Files are finished on their own context so Ctrl-C does not cut them off
+	go func() {
+		<-ctx.Done()
+		log.Printf("Interrupted; finishing the current file (Ctrl-C again to abort)")
+		releaseSignal()
+	}()
+
+	log.Printf("Watching %s for new audio and video files (Ctrl-C to stop)...", dir)
+	seen := map[string]fileSnapshot{}
+	for {
+		ready, err := pollWatchDir(dir, seen, state)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		for _, path := range ready {
+			if ctx.Err() != nil {
+				break
+			}
+			name := filepath.Base(path)
+			snap := seen[name]
+			log.Printf("%s: generating prompt...", name)
+
+			fileCtx, cancel := context.Background(), func() {}
+			if timeout > 0 {
+				fileCtx, cancel = context.WithTimeout(fileCtx, timeout)
+			}
+			start := time.Now()
+			result, err := generate(fileCtx, path, opts)
+			cancel()
+
+			entry := watchEntry{fileSnapshot: snap}
+			if prev, ok := state.Files[name]; ok && prev.Error != "" && prev.Size == snap.Size && prev.ModTime.Equal(snap.ModTime) {
+				entry.Attempts = prev.Attempts
+			}
+			if err == nil && jsonOutput {
+				json.NewEncoder(os.Stdout).Encode(resultJSON(result))
+			} else if err == nil {
+				saved := resolveOutputPaths(output, path).prompt
+				if err = savePromptToFile(result, saved); err == nil {
+					entry.Saved = saved
+				}
+			}
+			elapsed := time.Since(start).Round(time.Second)
+			if err != nil {
+				entry.Error = err.Error()
+				entry.Attempts++
+				if entry.Attempts < watchMaxAttempts {
+					log.Printf("%s failed (attempt %d/%d, will retry): %v", name, entry.Attempts, watchMaxAttempts, err)
+				} else {
+					log.Printf("%s failed (attempt %d/%d, skipped until it changes): %v", name, entry.Attempts, watchMaxAttempts, err)
+				}
+				// Forget the snapshot so the file is retried once it is
+				// seen stable again on a later poll
+				delete(seen, name)
+			} else if entry.Saved != "" {
+				log.Printf("%s: done in %s, saved to %s", name, elapsed, entry.Saved)
+			} else {
+				log.Printf("%s: done in %s", name, elapsed)
+			}
+			if err := state.record(name, entry); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("Stopped watching %s", dir)
+			return exitOK
+		case <-time.After(watchPollInterval):
+		}
+	}
+}
+
+func float32Ptr(v float64) *float32 {
+	f := float32(v)
+	return &f
 }
 
 func coalesce(values ...string) string {
@@ -180,18 +1189,40 @@ func expandPath(path string) string {
 	return path
 }
 
-func mapStylePreference(style string) genai.StylePreference {
-	switch strings.ToLower(style) {
-	case "photorealistic":
-		return genai.StylePhotorealistic
-	case "artistic":
-		return genai.StyleArtistic
-	case "abstract":
-		return genai.StyleAbstract
-	case "cinematic":
-		return genai.StyleCinematic
-	default:
-		return genai.StyleAuto
+// stylePreferences are the accepted --style values, default first
+var stylePreferences = []genai.StylePreference{genai.StyleAuto, genai.StylePhotorealistic, genai.StyleArtistic, genai.StyleAbstract, genai.StyleCinematic}
+
+// parseStylePreference maps --style to a StylePreference; unknown styles are
+// an error listing the accepted ones
+func parseStylePreference(style string) (genai.StylePreference, error) {
+	v := genai.StylePreference(strings.ToLower(strings.TrimSpace(style)))
+	names := make([]string, len(stylePreferences))
+	for i, s := range stylePreferences {
+		if v == s {
+			return s, nil
+		}
+		names[i] = string(s)
+	}
+	return "", fmt.Errorf("invalid style %q (must be one of %s)", style, strings.Join(names, ", "))
+}
+
+// listStyles prints the accepted --style and --aspect-ratio values
+func listStyles(w io.Writer) {
+	fmt.Fprintln(w, "Styles (--style, -s):")
+	for i, s := range stylePreferences {
+		if i == 0 {
+			fmt.Fprintf(w, "  %s (default)\n", s)
+		} else {
+			fmt.Fprintf(w, "  %s\n", s)
+		}
+	}
+	fmt.Fprintln(w, "\nAspect ratios (--aspect-ratio, -ar; W:H or WxH, or square for 1:1):")
+	for i, ar := range config.AspectRatios {
+		if i == 0 {
+			fmt.Fprintf(w, "  %s (default)\n", ar)
+		} else {
+			fmt.Fprintf(w, "  %s\n", ar)
+		}
 	}
 }
 
@@ -202,40 +1233,162 @@ func outputText(result *genai.PromptResult) {
 	fmt.Println(strings.Repeat("=", 60))
 	fmt.Println(result.Prompt)
 	fmt.Println(strings.Repeat("=", 60))
+	if result.Lyrics != "" {
+		fmt.Println("LYRICS")
+		fmt.Println(strings.Repeat("=", 60))
+		fmt.Println(result.Lyrics)
+		fmt.Println(strings.Repeat("=", 60))
+	}
+	if result.Fallback {
+		fmt.Println("Note: this prompt was written without audio analysis (Gemini unavailable or --llm ollama).")
+	} else if result.AudioSkipped {
+		fmt.Println("Note: no audio analysis was performed (--no-audio-analysis); the prompt is based on the title and notes only.")
+	}
+	if result.Cached {
+		fmt.Printf("Note: cached prompt from %s (--refresh to regenerate).\n", result.Timestamp.Format("2006-01-02 15:04"))
+	}
 }
 
 func outputJSON(result *genai.PromptResult) {
-	output := map[string]interface{}{
-		"title":      result.Title,
-		"audio_file": result.AudioFile,
-		"style":      string(result.Style),
-		"prompt":     result.Prompt,
-		"timestamp":  result.Timestamp.Format("2006-01-02 15:04:05"),
-	}
-
 	encoder := json.NewEncoder(os.Stdout)
 	encoder.SetIndent("", "  ")
-	encoder.Encode(output)
+	encoder.Encode(resultJSON(result))
+}
+
+// resultJSON is the --json representation of a prompt result
+func resultJSON(result *genai.PromptResult) map[string]interface{} {
+	output := map[string]interface{}{
+		"title":          result.Title,
+		"audio_file":     result.AudioFile,
+		"style":          string(result.Style),
+		"prompt":         result.Prompt,
+		"timestamp":      result.Timestamp.Format("2006-01-02 15:04:05"),
+		"fallback":       result.Fallback,
+		"audio_analyzed": !result.Fallback && !result.AudioSkipped,
+		"cached":         result.Cached,
+		"brief":          result.Brief,
+	}
+	if result.Lyrics != "" {
+		output["lyrics"] = result.Lyrics
+	}
+	if result.Usage != nil {
+		input, out := result.Usage.Totals()
+		cost, _ := result.Usage.EstimatedCost()
+		output["usage"] = map[string]interface{}{
+			"calls":              result.Usage.Calls,
+			"input_tokens":       input,
+			"output_tokens":      out,
+			"estimated_cost_usd": cost,
+		}
+	}
+	return output
 }
 
 func outputError(err error, jsonFormat bool) {
 	if jsonFormat {
-		output := map[string]interface{}{
-			"error":     err.Error(),
-			"timestamp": time.Now().Format("2006-01-02 15:04:05"),
-		}
-		encoder := json.NewEncoder(os.Stderr)
-		encoder.SetIndent("", "  ")
-		encoder.Encode(output)
+		outputStatus(err, "", "")
 	} else {
 		log.Printf("Error: %v", err)
 	}
 }
 
-func savePromptToFile(result *genai.PromptResult) string {
-	baseName := strings.TrimSuffix(result.AudioFile, filepath.Ext(result.AudioFile))
-	outputPath := baseName + "_ideogram_prompt.txt"
+// formatBrief renders the creative brief as "Field: value" lines
+func formatBrief(b *genai.AudioBrief) string {
+	var sb strings.Builder
+	sb.WriteString("Creative brief\n")
+	fmt.Fprintf(&sb, "Genre: %s\n", b.Genre)
+	fmt.Fprintf(&sb, "BPM: %d\n", b.BPM)
+	fmt.Fprintf(&sb, "Energy: %d/10\n", b.Energy)
+	fmt.Fprintf(&sb, "Mood: %s\n", strings.Join(b.MoodAdjectives, ", "))
+	fmt.Fprintf(&sb, "Instruments: %s\n", strings.Join(b.ProminentInstruments, ", "))
+	fmt.Fprintf(&sb, "Visual nouns: %s\n", strings.Join(b.VisualNouns, ", "))
+	fmt.Fprintf(&sb, "Textures: %s\n", strings.Join(b.Textures, ", "))
+	fmt.Fprintf(&sb, "Palette: %s\n", strings.Join(b.PaletteColors, ", "))
+	fmt.Fprintf(&sb, "Central metaphor: %s\n", b.CentralMetaphor)
+	fmt.Fprintf(&sb, "Avoid: %s\n", strings.Join(b.Avoid, ", "))
+	if b.LyricThemes != "" {
+		fmt.Fprintf(&sb, "Lyric themes: %s\n", b.LyricThemes)
+	}
+	return sb.String()
+}
+
+// outputPaths are where a run's files are saved. image has no extension; the
+// generated image's own is added. Empty image and report paths mean the
+// verify image stays in temp_assets.
+type outputPaths struct {
+	prompt string
+	image  string
+	report string
+}
+
+// resolveOutputPaths places a track's outputs. Without --output the prompt
+// goes next to the audio. An --output directory (existing, ending in a path
+// separator, or without an extension) gets <track>_ideogram_prompt.txt,
+// <track>_image.<ext> and <track>_validation.txt; an --output file is the
+// prompt path, and the image and report take its name as their prefix.
+func resolveOutputPaths(output, audioPath string) outputPaths {
+	track := strings.TrimSuffix(filepath.Base(audioPath), filepath.Ext(audioPath))
+	if output == "" {
+		return outputPaths{prompt: strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + "_ideogram_prompt.txt"}
+	}
+	if isOutputDir(output) {
+		stem := filepath.Join(output, track)
+		return outputPaths{prompt: stem + "_ideogram_prompt.txt", image: stem + "_image", report: stem + "_validation.txt"}
+	}
+	stem := strings.TrimSuffix(output, filepath.Ext(output))
+	return outputPaths{prompt: output, image: stem + "_image", report: stem + "_validation.txt"}
+}
+
+// isOutputDir reports whether --output names a directory
+func isOutputDir(output string) bool {
+	if strings.HasSuffix(output, "/") || strings.HasSuffix(output, string(os.PathSeparator)) {
+		return true
+	}
+	if info, err := os.Stat(output); err == nil {
+		return info.IsDir()
+	}
+	return filepath.Ext(output) == ""
+}
+
+// writeOutputFile writes data to path, creating its directory if needed
+func writeOutputFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
 
+// moveOutputImage moves the accepted verify image out of temp_assets to
+// dest plus the image's extension
+func moveOutputImage(src, dest string) (string, error) {
+	dest += filepath.Ext(src)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := fileutil.CopyFile(src, dest); err != nil {
+		return "", fmt.Errorf("failed to copy image to %s: %w", dest, err)
+	}
+	os.Remove(src)
+	return dest, nil
+}
+
+// saveValidationReport writes the verify attempts and validation results
+func saveValidationReport(path, prompt, imagePath string, provider config.ImageProvider, report *image.AttemptsReport, validation *genai.PromptValidationResult, hasText bool) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Image: %s\nProvider: %s\nValidated: %s\n%s\n%s\n", filepath.Base(imagePath), provider, time.Now().Format("2006-01-02 15:04:05"), strings.Repeat("-", 50), prompt)
+	if report != nil && len(report.Attempts) > 0 {
+		fmt.Fprintf(&sb, "\nGeneration attempts (outcome: %s):\n", report.Outcome)
+		image.PrintAttemptsReport(&sb, report)
+	}
+	writeValidationReport(&sb, validation, provider, hasText)
+	return writeOutputFile(path, []byte(sb.String()))
+}
+
+// savePromptToFile writes the prompt, brief and lyrics to path
+func savePromptToFile(result *genai.PromptResult, path string) error {
 	content := fmt.Sprintf("Title: %s\nAudio: %s\nGenerated: %s\n%s\n%s",
 		result.Title,
 		filepath.Base(result.AudioFile),
@@ -243,17 +1396,24 @@ func savePromptToFile(result *genai.PromptResult) string {
 		strings.Repeat("-", 50),
 		result.Prompt,
 	)
+	if result.Brief != nil {
+		content += "\n" + strings.Repeat("-", 50) + "\n" + formatBrief(result.Brief)
+	} else if result.Fallback || result.AudioSkipped {
+		content += "\n" + strings.Repeat("-", 50) + "\nNo audio analysis: written from the title and notes only\n"
+	}
+	if result.Lyrics != "" {
+		content += "\n" + strings.Repeat("-", 50) + "\nLyrics\n" + result.Lyrics + "\n"
+	}
 
-	os.WriteFile(outputPath, []byte(content), 0644)
-	return outputPath
+	return writeOutputFile(path, []byte(content))
 }
 
-func verifyImageGeneration(prompt, title, caption, subcaption, aspectRatioStr string, quiet bool) {
+func verifyImageGeneration(ctx context.Context, prompt, title, caption, subcaption string, ar config.AspectRatio, provider config.ImageProvider, vopts genai.ValidationOptions, avoid []string, sdEndpoint string, retries int, adaptiveRetry, debug, quiet bool, out io.Writer, outputs outputPaths) (string, error) {
 	if !quiet {
-		fmt.Println()
-		fmt.Println(strings.Repeat("=", 60))
-		fmt.Println("VERIFICATION MODE: Generating and validating image...")
-		fmt.Println(strings.Repeat("=", 60))
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, strings.Repeat("=", 60))
+		fmt.Fprintf(out, "VERIFICATION MODE: Generating with %s and validating image...\n", provider)
+		fmt.Fprintln(out, strings.Repeat("=", 60))
 	}
 
 	// Create cleanup manager
@@ -262,107 +1422,164 @@ func verifyImageGeneration(prompt, title, caption, subcaption, aspectRatioStr st
 
 	// Ensure temp folder exists
 	if err := fileutil.EnsureTempFolder(); err != nil {
-		log.Printf("Error creating temp folder: %v", err)
-		return
+		return "", fmt.Errorf("error creating temp folder: %w", err)
 	}
 
-	// Parse aspect ratio
-	ar := parseAspectRatioString(aspectRatioStr)
-
 	// Build image generation options
 	opts := image.ImageGenOptions{
-		Description:  prompt,
-		Title:        title,
-		Caption:      caption,
-		Subcaption:   subcaption,
-		AspectRatio:  ar,
-		Provider:     config.ImageProviderIdeogram,
-		MaxRetries:   3,
-		ValidateText: caption != "" || subcaption != "",
-	}
-
-	// Generate and validate the image
-	result, err := image.GenerateAndValidateImage(opts, cleanup)
+		Description:     prompt,
+		Title:           title,
+		Caption:         caption,
+		Subcaption:      subcaption,
+		AspectRatio:     ar,
+		Provider:        provider,
+		SDEndpoint:      sdEndpoint,
+		MaxRetries:      retries + 1,
+		ValidateText:    caption != "" || subcaption != "",
+		ValidatePrompt:  true,
+		CasingPolicy:    vopts.Casing,
+		Reviewer:        vopts.Reviewer,
+		ReviewModel:     vopts.ReviewModel,
+		Retries:         vopts.Retries,
+		ValidationModel: vopts.Model,
+		NoFallback:      vopts.NoFallback,
+		Language:        vopts.Language,
+
+		AdaptiveRetry: adaptiveRetry,
+		Debug:         debug,
+		Context:       ctx,
+
+		// Providers with negative prompts get the avoid list there too
+		NegativePrompt: strings.Join(avoid, ", "),
+	}
+
+	// Generate images until one passes text and prompt-intent validation,
+	// then summarize every attempt
+	result, report, err := image.GenerateAndValidateImage(opts, cleanup)
+	defer printAttemptsSummary(out, report)
 	if err != nil {
-		log.Printf("Image generation failed: %v", err)
-		return
+		return "", &stageError{exitImageFailed, fmt.Errorf("image generation failed: %w", err)}
 	}
 
-	if !quiet {
-		fmt.Printf("\n✓ Image generated: %s\n", result.Path)
+	// A cached image was accepted by an earlier run; check it once more
+	validation := report.PromptValidation
+	if report.Outcome == "cached" {
+		if !quiet {
+			fmt.Fprintln(out, "\nValidating cached image matches prompt intent...")
+		}
+		if validation, err = genai.ValidateImageAgainstPrompt(ctx, result.Path, prompt, caption, subcaption, vopts); err != nil {
+			return result.Path, &stageError{exitValidationFailed, fmt.Errorf("validation failed: %w", err)}
+		}
+	}
+	if validation == nil {
+		return result.Path, &stageError{exitValidationFailed, fmt.Errorf("validation failed: the accepted image was never checked against the prompt (the check errored, or was skipped after the text check failed)")}
 	}
 
-	// Now validate the image against the prompt using Gemini
-	if !quiet {
-		fmt.Println("\nValidating image matches prompt intent...")
+	// Output validation results
+	writeValidationReport(out, validation, provider, caption != "" || subcaption != "")
+
+	imagePath := result.Path
+	if outputs.image != "" {
+		if imagePath, err = moveOutputImage(result.Path, outputs.image); err != nil {
+			return result.Path, fmt.Errorf("error saving image: %w", err)
+		}
+		if err := saveValidationReport(outputs.report, prompt, imagePath, provider, report, validation, caption != "" || subcaption != ""); err != nil {
+			return imagePath, fmt.Errorf("error saving validation report: %w", err)
+		}
+		if !quiet {
+			fmt.Fprintf(out, "\nValidation report saved to: %s\n", outputs.report)
+		}
+	}
+	if err := validationFailure(validation); err != nil {
+		fmt.Fprintf(out, "\n✗ Best image, which did not pass validation: %s\n", imagePath)
+		return imagePath, &stageError{exitValidationFailed, err}
 	}
+	fmt.Fprintf(out, "\n✓ Accepted image: %s\n", imagePath)
+	return imagePath, nil
+}
 
-	validation, err := genai.ValidateImageAgainstPrompt(result.Path, prompt, caption, subcaption)
-	if err != nil {
-		log.Printf("Validation failed: %v", err)
+// printAttemptsSummary lists every generation attempt, marking the one kept
+func printAttemptsSummary(w io.Writer, report *image.AttemptsReport) {
+	if report == nil || len(report.Attempts) == 0 {
 		return
 	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Generation attempts (outcome: %s):\n", report.Outcome)
+	image.PrintAttemptsReport(w, report)
+}
 
-	// Output validation results
-	fmt.Println()
-	fmt.Println(strings.Repeat("=", 60))
-	fmt.Println("VALIDATION RESULTS")
-	fmt.Println(strings.Repeat("=", 60))
+// validationFailure explains why a validated image did not pass, or returns
+// nil: a mismatch with the prompt or any blocker fails it, warnings do not
+func validationFailure(validation *genai.PromptValidationResult) error {
+	if blockers := validation.IssuesWithSeverity(genai.SeverityBlocker); len(blockers) > 0 {
+		return fmt.Errorf("image failed validation: %s", strings.Join(blockers, "; "))
+	}
+	if !validation.PromptMatch {
+		return fmt.Errorf("image failed validation: does not match the prompt intent")
+	}
+	return nil
+}
+
+// writeValidationReport prints the prompt validation results for an image
+// from provider; hasText adds the caption rendering and casing checks
+func writeValidationReport(w io.Writer, validation *genai.PromptValidationResult, provider config.ImageProvider, hasText bool) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, strings.Repeat("=", 60))
+	fmt.Fprintln(w, "VALIDATION RESULTS")
+	fmt.Fprintln(w, strings.Repeat("=", 60))
+	fmt.Fprintf(w, "Provider: %s\n", provider)
 
 	if validation.PromptMatch {
-		fmt.Println("✓ Image matches prompt intent")
+		fmt.Fprintln(w, "✓ Image matches prompt intent")
 	} else {
-		fmt.Println("✗ Image does NOT match prompt intent")
+		fmt.Fprintln(w, "✗ Image does NOT match prompt intent")
 	}
 
-	if caption != "" || subcaption != "" {
+	if hasText {
 		if validation.TextRendered {
-			fmt.Println("✓ Text rendered correctly")
+			fmt.Fprintln(w, "✓ Text rendered correctly")
 		} else {
-			fmt.Println("✗ Text rendering issues detected")
+			fmt.Fprintln(w, "✗ Text rendering issues detected")
 		}
 
 		if validation.CasingCorrect {
-			fmt.Println("✓ Text casing is appropriate")
+			fmt.Fprintln(w, "✓ Text casing is appropriate")
 		} else {
-			fmt.Printf("⚠ Text casing may differ from expected (style-appropriate: %v)\n", validation.CasingAppropriate)
+			fmt.Fprintf(w, "⚠ Text casing may differ from expected (style-appropriate: %v)\n", validation.CasingAppropriate)
 		}
 	}
 
-	if len(validation.Issues) > 0 {
-		fmt.Println("\nIssues found:")
-		for _, issue := range validation.Issues {
-			fmt.Printf("  - %s\n", issue)
+	if validation.Score > 0 {
+		fmt.Fprintf(w, "Score: %.1f/10\n", validation.Score)
+	}
+	if len(validation.InstrumentsSeen) > 0 {
+		fmt.Fprintf(w, "Instruments seen: %s\n", strings.Join(validation.InstrumentsSeen, ", "))
+	}
+
+	for _, group := range []struct {
+		heading  string
+		severity genai.Severity
+	}{
+		{"Blockers (regenerate):", genai.SeverityBlocker},
+		{"Warnings:", genai.SeverityWarning},
+		{"Info:", genai.SeverityInfo},
+	} {
+		issues := validation.IssuesWithSeverity(group.severity)
+		if len(issues) == 0 {
+			continue
+		}
+		fmt.Fprintln(w, "\n"+group.heading)
+		for _, issue := range issues {
+			fmt.Fprintf(w, "  - %s\n", issue)
 		}
 	}
 
 	if len(validation.Suggestions) > 0 {
-		fmt.Println("\nSuggestions:")
+		fmt.Fprintln(w, "\nSuggestions:")
 		for _, suggestion := range validation.Suggestions {
-			fmt.Printf("  • %s\n", suggestion)
+			fmt.Fprintf(w, "  • %s\n", suggestion)
 		}
 	}
 
-	fmt.Println(strings.Repeat("=", 60))
-}
-
-func parseAspectRatioString(s string) config.AspectRatio {
-	switch strings.ToLower(strings.TrimSpace(s)) {
-	case "16:9", "16x9":
-		return config.AspectRatio16x9
-	case "9:16", "9x16":
-		return config.AspectRatio9x16
-	case "1:1", "1x1", "square":
-		return config.AspectRatio1x1
-	case "4:3", "4x3":
-		return config.AspectRatio4x3
-	case "3:4", "3x4":
-		return config.AspectRatio3x4
-	case "3:2", "3x2":
-		return config.AspectRatio3x2
-	case "2:3", "2x3":
-		return config.AspectRatio2x3
-	default:
-		return config.AspectRatio16x9
-	}
+	fmt.Fprintln(w, strings.Repeat("=", 60))
 }
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"mmmeld/internal/genai"
+)
+
+func TestBatchAudioFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"02-b.mp3", "01-a.wav", "cover.png", "notes.txt", "03-c.MP3", "04-live.mp4"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "stems.mp3"), 0755); err != nil {
+		t.Fatalf("Failed to create test dir: %v", err)
+	}
+	empty := t.TempDir()
+
+	tests := []struct {
+		name        string
+		pattern     string
+		expected    []string
+		expectError bool
+	}{
+		{"directory", dir, []string{"01-a.wav", "02-b.mp3", "03-c.MP3", "04-live.mp4"}, false},
+		{"glob", filepath.Join(dir, "*.mp3"), []string{"02-b.mp3"}, false},
+		{"glob skips non-media", filepath.Join(dir, "*"), []string{"01-a.wav", "02-b.mp3", "03-c.MP3", "04-live.mp4"}, false},
+		{"no matches", filepath.Join(dir, "*.flac"), nil, true},
+		{"empty directory", empty, nil, true},
+		{"bad pattern", filepath.Join(dir, "[.mp3"), nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := batchAudioFiles(tt.pattern)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("batchAudioFiles(%q) = %v, expected error", tt.pattern, files)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("batchAudioFiles(%q) unexpected error: %v", tt.pattern, err)
+			}
+			var names []string
+			for _, f := range files {
+				names = append(names, filepath.Base(f))
+			}
+			if !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("batchAudioFiles(%q) = %v, expected %v", tt.pattern, names, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIsGlobPattern(t *testing.T) {
+	dir := t.TempDir()
+	live := filepath.Join(dir, "Song [Live].mp3")
+	if err := os.WriteFile(live, []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{live, false},
+		{filepath.Join(dir, "song.mp3"), false},
+		{filepath.Join(dir, "*.mp3"), true},
+		{filepath.Join(dir, "Song [Studio].mp3"), true},
+	}
+	for _, tt := range tests {
+		if got := isGlobPattern(tt.path); got != tt.expected {
+			t.Errorf("isGlobPattern(%q) = %v, expected %v", filepath.Base(tt.path), got, tt.expected)
+		}
+	}
+}
+
+func TestResolveOutputPaths(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "covers.v2")
+	if err := os.Mkdir(existing, 0755); err != nil {
+		t.Fatalf("Failed to create test dir: %v", err)
+	}
+
+	tests := []struct {
+		output   string
+		audio    string
+		expected outputPaths
+	}{
+		{"", "/share/album/song.mp3", outputPaths{prompt: "/share/album/song_ideogram_prompt.txt"}},
+		{"out", "/share/song.mp3", outputPaths{prompt: "out/song_ideogram_prompt.txt", image: "out/song_image", report: "out/song_validation.txt"}},
+		{"out.d/", "/share/song.mp3", outputPaths{prompt: "out.d/song_ideogram_prompt.txt", image: "out.d/song_image", report: "out.d/song_validation.txt"}},
+		{existing, "song.wav", outputPaths{
+			prompt: filepath.Join(existing, "song_ideogram_prompt.txt"),
+			image:  filepath.Join(existing, "song_image"),
+			report: filepath.Join(existing, "song_validation.txt"),
+		}},
+		{"covers/drive.txt", "/share/song.mp3", outputPaths{prompt: "covers/drive.txt", image: "covers/drive_image", report: "covers/drive_validation.txt"}},
+	}
+
+	for _, test := range tests {
+		if result := resolveOutputPaths(test.output, test.audio); result != test.expected {
+			t.Errorf("resolveOutputPaths(%q, %q) = %+v, expected %+v", test.output, test.audio, result, test.expected)
+		}
+	}
+}
+
+func TestSavePromptToFile(t *testing.T) {
+	dir := t.TempDir()
+	result := &genai.PromptResult{AudioFile: "song.mp3", Prompt: "A neon skyline at dusk"}
+
+	path := filepath.Join(dir, "nested", "song.txt")
+	if err := savePromptToFile(result, path); err != nil {
+		t.Fatalf("savePromptToFile(%q) unexpected error: %v", path, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(data), result.Prompt) {
+		t.Errorf("savePromptToFile(%q) wrote %q, %v, expected the prompt", path, data, err)
+	}
+
+	// A file where the directory should be makes the save fail
+	blocked := filepath.Join(dir, "nested", "song.txt", "prompt.txt")
+	if err := savePromptToFile(result, blocked); err == nil {
+		t.Errorf("savePromptToFile(%q) expected error", blocked)
+	}
+}
+
+func TestClipboardCommands(t *testing.T) {
+	tests := []struct {
+		goos     string
+		wayland  bool
+		expected []string
+	}{
+		{"darwin", false, []string{"pbcopy"}},
+		{"windows", false, []string{"clip.exe"}},
+		{"linux", false, []string{"xclip", "xsel", "clip.exe"}},
+		{"linux", true, []string{"wl-copy", "xclip", "xsel", "clip.exe"}},
+		{"freebsd", false, []string{"xclip", "xsel", "clip.exe"}},
+	}
+
+	for _, test := range tests {
+		var result []string
+		for _, cmd := range clipboardCommands(test.goos, test.wayland) {
+			result = append(result, cmd[0])
+		}
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("clipboardCommands(%q, %v) = %v, expected %v", test.goos, test.wayland, result, test.expected)
+		}
+	}
+}
+
+func TestOSC52(t *testing.T) {
+	if result, expected := osc52("hi"), "\x1b]52;c;aGk=\a"; result != expected {
+		t.Errorf("osc52(%q) = %q, expected %q", "hi", result, expected)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{nil, exitOK},
+		{errors.New("disk full"), exitUsage},
+		{&stageError{exitImageFailed, errors.New("quota")}, exitImageFailed},
+		{fmt.Errorf("verify: %w", &stageError{exitValidationFailed, errors.New("blurry")}), exitValidationFailed},
+	}
+
+	for _, test := range tests {
+		if result := exitCode(test.err); result != test.expected {
+			t.Errorf("exitCode(%v) = %d, expected %d", test.err, result, test.expected)
+		}
+	}
+}
+
+func TestValidationFailure(t *testing.T) {
+	tests := []struct {
+		name       string
+		validation genai.PromptValidationResult
+		fails      bool
+	}{
+		{"match", genai.PromptValidationResult{PromptMatch: true}, false},
+		{"warnings only", genai.PromptValidationResult{PromptMatch: true, Issues: []genai.ValidationIssue{{Severity: genai.SeverityWarning, Message: "muted colors"}}}, false},
+		{"blocker", genai.PromptValidationResult{PromptMatch: true, Issues: []genai.ValidationIssue{{Severity: genai.SeverityBlocker, Message: "caption misspelled"}}}, true},
+		{"mismatch", genai.PromptValidationResult{}, true},
+	}
+
+	for _, test := range tests {
+		if err := validationFailure(&test.validation); (err != nil) != test.fails {
+			t.Errorf("validationFailure(%s) = %v, expected failure %v", test.name, err, test.fails)
+		}
+	}
+}
+
+func TestParseStylePreference(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    genai.StylePreference
+		expectError bool
+	}{
+		{"auto", genai.StyleAuto, false},
+		{"Cinematic", genai.StyleCinematic, false},
+		{" abstract ", genai.StyleAbstract, false},
+		{"cinemtic", "", true},
+		{"", "", true},
+	}
+
+	for _, test := range tests {
+		result, err := parseStylePreference(test.input)
+		if test.expectError {
+			if err == nil {
+				t.Errorf("parseStylePreference(%q) expected error, got %q", test.input, result)
+			}
+			continue
+		}
+		if err != nil || result != test.expected {
+			t.Errorf("parseStylePreference(%q) = %q, %v, expected %q", test.input, result, err, test.expected)
+		}
+	}
+}
+
+func TestPollWatchDir(t *testing.T) {
+	dir := t.TempDir()
+	mix := filepath.Join(dir, "mix.mp3")
+	if err := os.WriteFile(mix, []byte("partial"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	state, err := loadWatchState(dir)
+	if err != nil {
+		t.Fatalf("loadWatchState(%q) unexpected error: %v", dir, err)
+	}
+	seen := map[string]fileSnapshot{}
+
+	poll := func(step string, expected []string) {
+		t.Helper()
+		ready, err := pollWatchDir(dir, seen, state)
+		if err != nil {
+			t.Fatalf("pollWatchDir(%s) unexpected error: %v", step, err)
+		}
+		if !reflect.DeepEqual(ready, expected) {
+			t.Errorf("pollWatchDir(%s) = %v, expected %v", step, ready, expected)
+		}
+	}
+
+	poll("first sighting", nil)
+	if err := os.WriteFile(mix, []byte("partial export"), 0644); err != nil {
+		t.Fatalf("Failed to grow test file: %v", err)
+	}
+	poll("still growing", nil)
+	poll("stable", []string{mix})
+
+	info, _ := os.Stat(mix)
+	if err := state.record("mix.mp3", watchEntry{fileSnapshot: fileSnapshot{Size: info.Size(), ModTime: info.ModTime()}, Saved: "mix_ideogram_prompt.txt"}); err != nil {
+		t.Fatalf("record unexpected error: %v", err)
+	}
+	poll("processed", nil)
+
+	// A restarted watch reads the state file and skips the processed file
+	state, err = loadWatchState(dir)
+	if err != nil || state.Files["mix.mp3"].Saved != "mix_ideogram_prompt.txt" {
+		t.Fatalf("loadWatchState(%q) = %+v, %v, expected the recorded file", dir, state, err)
+	}
+	seen = map[string]fileSnapshot{}
+	poll("restart", nil)
+	poll("restart stable", nil)
+}
+
+func TestPollWatchDirRetriesFailures(t *testing.T) {
+	dir := t.TempDir()
+	mix := filepath.Join(dir, "mix.mp3")
+	if err := os.WriteFile(mix, []byte("export"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	state, err := loadWatchState(dir)
+	if err != nil {
+		t.Fatalf("loadWatchState(%q) unexpected error: %v", dir, err)
+	}
+	info, _ := os.Stat(mix)
+	snap := fileSnapshot{Size: info.Size(), ModTime: info.ModTime()}
+
+	for attempt := 1; attempt <= watchMaxAttempts; attempt++ {
+		seen := map[string]fileSnapshot{}
+		pollWatchDir(dir, seen, state)
+		ready, err := pollWatchDir(dir, seen, state)
+		if err != nil {
+			t.Fatalf("pollWatchDir unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(ready, []string{mix}) {
+			t.Fatalf("pollWatchDir before attempt %d = %v, expected the failed file again", attempt, ready)
+		}
+		if err := state.record("mix.mp3", watchEntry{fileSnapshot: snap, Error: "quota exceeded", Attempts: attempt}); err != nil {
+			t.Fatalf("record unexpected error: %v", err)
+		}
+	}
+
+	// Out of attempts: skipped until the file changes
+	seen := map[string]fileSnapshot{}
+	pollWatchDir(dir, seen, state)
+	if ready, _ := pollWatchDir(dir, seen, state); ready != nil {
+		t.Errorf("pollWatchDir after %d failures = %v, expected nothing", watchMaxAttempts, ready)
+	}
+	if err := os.WriteFile(mix, []byte("new export"), 0644); err != nil {
+		t.Fatalf("Failed to update test file: %v", err)
+	}
+	pollWatchDir(dir, seen, state)
+	if ready, _ := pollWatchDir(dir, seen, state); !reflect.DeepEqual(ready, []string{mix}) {
+		t.Errorf("pollWatchDir after a change = %v, expected the file again", ready)
+	}
+}
+
+func TestRunInteractive(t *testing.T) {
+	brief := &genai.AudioBrief{Genre: "synthwave"}
+	result := &genai.PromptResult{Prompt: "A rooftop at dusk.", Brief: brief}
+	var instructions []string
+	revise := func(ctx context.Context, b *genai.AudioBrief, previous, instruction string, opts genai.PromptOptions) (string, *genai.Usage, error) {
+		if b != brief {
+			t.Errorf("revise got brief %v, expected the result's brief", b)
+		}
+		instructions = append(instructions, instruction)
+		usage := &genai.Usage{Calls: []genai.CallUsage{{Model: "gemini-2.5-flash", InputTokens: 100, OutputTokens: 10}}}
+		return fmt.Sprintf("%s [%d]", previous, len(instructions)), usage, nil
+	}
+	var verified []string
+	var revisions []string
+
+	tests := []struct {
+		name         string
+		input        string
+		expected     string
+		revision     int
+		instructions []string
+		verified     []string
+	}{
+		{"accept", "a\n", "A rooftop at dusk.", 1, nil, nil},
+		{"edit then regenerate", "x\ne\nsame idea but at night\nr\nv\naccept\n", "A rooftop at dusk. [1] [2]", 3, []string{"same idea but at night", ""}, []string{"A rooftop at dusk. [1] [2]"}},
+		{"end of input accepts", "e\nat night\n", "A rooftop at dusk. [1]", 2, []string{"at night"}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			instructions, verified, revisions = nil, nil, nil
+			result.Usage = nil
+			var out strings.Builder
+			prompt, n, err := runInteractive(context.Background(), strings.NewReader(test.input), &out, result, genai.PromptOptions{Quiet: true}, revise,
+				func(prompt string) { verified = append(verified, prompt) },
+				func(n int, label, prompt string) { revisions = append(revisions, fmt.Sprintf("%d %s", n, label)) })
+			if err != nil || prompt != test.expected || n != test.revision {
+				t.Errorf("runInteractive(%q) = %q, %d, %v, expected %q, %d", test.input, prompt, n, err, test.expected, test.revision)
+			}
+			if !reflect.DeepEqual(instructions, test.instructions) {
+				t.Errorf("runInteractive(%q) instructions = %q, expected %q", test.input, instructions, test.instructions)
+			}
+			if !reflect.DeepEqual(verified, test.verified) {
+				t.Errorf("runInteractive(%q) verified = %q, expected %q", test.input, verified, test.verified)
+			}
+			if len(revisions) != test.revision-1 {
+				t.Errorf("runInteractive(%q) revisions = %q, expected %d", test.input, revisions, test.revision-1)
+			}
+			if input, _ := result.Usage.Totals(); input != 100*(test.revision-1) {
+				t.Errorf("runInteractive(%q) usage = %d input tokens, expected %d", test.input, input, 100*(test.revision-1))
+			}
+		})
+	}
+}
+
+func TestAppendRevision(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "song_ideogram_prompt.txt")
+	if err := savePromptToFile(&genai.PromptResult{AudioFile: "song.mp3", Prompt: "A rooftop at dusk."}, path); err != nil {
+		t.Fatalf("savePromptToFile unexpected error: %v", err)
+	}
+	if err := appendRevision(path, 2, "edit: at night", "A rooftop at night."); err != nil {
+		t.Fatalf("appendRevision unexpected error: %v", err)
+	}
+	if err := appendRevision(path, 2, "", ""); err != nil {
+		t.Fatalf("appendRevision unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	for _, want := range []string{"A rooftop at dusk.", "Revision 2 (edit: at night)\nA rooftop at night.", "Accepted revision 2"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("appendRevision wrote %q, missing %q", data, want)
+		}
+	}
+}
@@ -1,17 +1,23 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"mmmeld/internal/config"
+	"mmmeld/internal/ffmpeg"
 	"mmmeld/internal/fileutil"
+	"mmmeld/internal/httpx"
+	"mmmeld/internal/logx"
 	"mmmeld/internal/tts"
 )
 
@@ -22,6 +28,30 @@ type TTSConfig struct {
 	VoiceID     string
 	Output      string
 	DefaultFile string
+	Stdin       bool
+	Command     string
+	ChunkSize   int
+	Model       string
+	Speed       float64
+	Rate        float64
+	Format      string
+	Encoding    string
+	SampleRate  int
+	MaxAttempts int
+	NoCache     bool
+	Voices      map[string]string
+	Pause       float64
+	Lexicon     map[string]string
+	Timestamps  string
+	ElevenLabs  tts.ElevenLabsSettings
+	NoLLMTitle  bool
+	Play        bool
+	Estimate    bool
+	Price       float64
+	ListVoices  bool
+	JSON        bool
+	Proxy       string
+	CACert      string
 }
 
 func main() {
@@ -34,6 +64,18 @@ func main() {
 		log.Fatalf("Argument parsing error: %v", err)
 	}
 
+	provider, err := parseProvider(cfg.Provider)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.ListVoices {
+		if err := listVoices(provider, cfg.JSON); err != nil {
+			log.Fatalf("Failed to list voices: %v", err)
+		}
+		return
+	}
+
 	tempDirExisted := fileutil.FileExists(config.TempAssetsFolder)
 
 	// Get text input
@@ -46,17 +88,34 @@ func main() {
 		log.Fatal("No text provided for speech generation")
 	}
 
-	// Validate provider
-	var provider config.TTSProvider
-	switch cfg.Provider {
-	case "elevenlabs":
-		provider = config.ProviderElevenLabs
-	case "openai":
-		provider = config.ProviderOpenAI
-	case "deepgram":
-		provider = config.ProviderDeepgram
-	default:
-		log.Fatalf("Invalid TTS provider: %s. Must be one of: elevenlabs, openai, deepgram", cfg.Provider)
+	opts := tts.SpeechOptions{
+		Provider:    provider,
+		VoiceID:     cfg.VoiceID,
+		Command:     cfg.Command,
+		ChunkSize:   cfg.ChunkSize,
+		Model:       cfg.Model,
+		Speed:       cfg.Speed,
+		Rate:        cfg.Rate,
+		Format:      cfg.Format,
+		MaxAttempts: cfg.MaxAttempts,
+		NoCache:     cfg.NoCache,
+
+		DeepgramEncoding:   cfg.Encoding,
+		DeepgramSampleRate: cfg.SampleRate,
+
+		Voices:        cfg.Voices,
+		DialoguePause: cfg.Pause,
+		Lexicon:       cfg.Lexicon,
+		Timestamps:    cfg.Timestamps != "",
+		ElevenLabs:    &cfg.ElevenLabs,
+		NoLLMTitle:    cfg.NoLLMTitle,
+	}
+
+	if cfg.Estimate {
+		if err := tts.PrintEstimate(os.Stdout, tts.EstimateSpeech(text, opts, cfg.Price)); err != nil {
+			log.Fatalf("Failed to print estimate: %v", err)
+		}
+		return
 	}
 
 	if cfg.Output == "" {
@@ -66,9 +125,9 @@ func main() {
 			if sanitized == "" {
 				sanitized = "tts_output"
 			}
-			cfg.Output = fmt.Sprintf("%s.mp3", sanitized)
+			cfg.Output = fmt.Sprintf("%s.%s", sanitized, outputExt(cfg.Format))
 		} else {
-			cfg.Output = fmt.Sprintf("tts_output_%d.mp3", time.Now().Unix())
+			cfg.Output = fmt.Sprintf("tts_output_%d.%s", time.Now().Unix(), outputExt(cfg.Format))
 		}
 	}
 
@@ -76,26 +135,97 @@ func main() {
 	cleanup := fileutil.NewCleanupManager()
 	defer func() {
 		if err := cleanup.Cleanup(); err != nil {
-			log.Printf("Cleanup error: %v", err)
+			logx.Warnf("Cleanup error: %v", err)
 		}
 		if !tempDirExisted {
 			if err := fileutil.RemoveTempFolderIfEmpty(); err != nil {
-				log.Printf("Temp folder cleanup error: %v", err)
+				logx.Warnf("Temp folder cleanup error: %v", err)
 			}
 		}
 	}()
 
 	// Generate speech
-	log.Printf("Generating speech using %s provider with voice %s", provider, cfg.VoiceID)
-	result, err := tts.GenerateSpeech(text, cfg.VoiceID, provider, cleanup, cfg.Output)
+	logx.Infof("Generating speech using %s provider with voice %s", provider, cfg.VoiceID)
+	result, err := tts.GenerateSpeechWithOptions(text, opts, cleanup, cfg.Output)
 	if err != nil {
 		log.Fatalf("Speech generation failed: %v", err)
 	}
 
 	fmt.Printf("Generated speech saved to: %s\n", result.AudioPath)
+	if result.Duration > 0 {
+		fmt.Printf("Duration: %s (%d chunk(s))\n", time.Duration(result.Duration*float64(time.Second)).Round(time.Second/10), len(result.Chunks))
+	}
+
+	if cfg.Timestamps != "" {
+		if err := writeSegments(cfg.Timestamps, result.Segments); err != nil {
+			log.Fatalf("Failed to write timestamps: %v", err)
+		}
+		fmt.Printf("Timestamps saved to: %s\n", cfg.Timestamps)
+	}
 	if result.Title != "" {
 		fmt.Printf("Title: %s\n", result.Title)
 	}
+
+	if cfg.Play {
+		// Ctrl+C stops playback without skipping cleanup
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := ffmpeg.PlayAudio(ctx, result.AudioPath); err != nil && ctx.Err() == nil {
+			logx.Warnf("Playback failed: %v", err)
+		}
+	}
+}
+
+// outputExt returns the file extension for the requested format
+func outputExt(format string) string {
+	if format == "" {
+		return "mp3"
+	}
+	return format
+}
+
+func parseProvider(name string) (config.TTSProvider, error) {
+	switch name {
+	case "elevenlabs":
+		return config.ProviderElevenLabs, nil
+	case "openai":
+		return config.ProviderOpenAI, nil
+	case "deepgram":
+		return config.ProviderDeepgram, nil
+	case "azure":
+		return config.ProviderAzure, nil
+	case "local":
+		return config.ProviderLocal, nil
+	default:
+		return "", fmt.Errorf("invalid TTS provider: %s, must be one of: elevenlabs, openai, deepgram, azure, local", name)
+	}
+}
+
+// writeSegments saves sentence timings as a JSON sidecar
+func writeSegments(path string, segments []tts.Segment) error {
+	if segments == nil {
+		segments = []tts.Segment{}
+	}
+	data, err := json.MarshalIndent(segments, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// listVoices prints the provider's voices as a table or JSON array
+func listVoices(provider config.TTSProvider, asJSON bool) error {
+	voices, err := tts.ListVoices(provider)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(voices)
+	}
+	return tts.PrintVoices(os.Stdout, voices)
 }
 
 func parseArgs() (*TTSConfig, error) {
@@ -107,23 +237,72 @@ func parseArgs() (*TTSConfig, error) {
 	flag.StringVar(&cfg.TextFile, "textfile", "", "File containing text to convert to speech")
 	flag.StringVar(&cfg.TextFile, "tf", "", "File containing text to convert to speech")
 
-	flag.StringVar(&cfg.Provider, "provider", "", "TTS provider (elevenlabs, openai, deepgram)")
-	flag.StringVar(&cfg.Provider, "p", "", "TTS provider (elevenlabs, openai, deepgram)")
+	flag.StringVar(&cfg.Provider, "provider", "", "TTS provider (elevenlabs, openai, deepgram, azure, local)")
+	flag.StringVar(&cfg.Provider, "p", "", "TTS provider (elevenlabs, openai, deepgram, azure, local)")
 
 	flag.StringVar(&cfg.VoiceID, "voiceid", "", "Voice ID for the TTS provider")
 	flag.StringVar(&cfg.VoiceID, "v", "", "Voice ID for the TTS provider")
 
+	flag.BoolVar(&cfg.Stdin, "stdin", false, "Read text from standard input (implied when input is piped)")
+
 	flag.StringVar(&cfg.Output, "output", "", "Output filename or file path")
 	flag.StringVar(&cfg.Output, "o", "", "Output filename or file path")
 
+	flag.StringVar(&cfg.Command, "tts-command", os.Getenv("MMMELD_TTS_COMMAND"), "Command template for the local provider; {out} is the output file, {voice} the voice ID, text is sent on stdin")
+	flag.StringVar(&cfg.Model, "tts-model", "", "TTS model (OpenAI: tts-1, tts-1-hd, gpt-4o-mini-tts; default: tts-1)")
+	flag.Float64Var(&cfg.Speed, "tts-speed", 0, "OpenAI speaking speed (0.25 to 4.0, default: 1.0)")
+	flag.Float64Var(&cfg.Rate, "speech-rate", 0, "Speaking rate for any provider, e.g. 1.15 (0.25 to 4.0); uses the provider's native control when available, ffmpeg atempo otherwise")
+	flag.IntVar(&cfg.ChunkSize, "tts-chunk-size", tts.MaxChunkSize, "Max characters per TTS request (0 disables chunking for the local provider)")
+
+	flag.IntVar(&cfg.MaxAttempts, "tts-max-attempts", config.DefaultTTSMaxAttempts, "Attempts per request on 408/429/5xx or network errors (exponential backoff, honors Retry-After)")
+	var voices string
+	flag.StringVar(&voices, "voices", "", "Speaker voices for dialogue scripts, e.g. 'ALICE=voice1,BOB=voice2' (lines start with 'ALICE:')")
+	flag.Float64Var(&cfg.Pause, "dialogue-pause", 0, "Seconds of silence between dialogue turns")
+	var lexicon string
+	flag.StringVar(&lexicon, "lexicon", "", "JSON file mapping words to replacement spellings, or \"ipa:...\" phonemes (Azure)")
+	var noSpeakerBoost bool
+	flag.StringVar(&cfg.ElevenLabs.ModelID, "elevenlabs-model", config.ElevenLabsModelID, "ElevenLabs model ID (e.g. eleven_v3, eleven_multilingual_v2, eleven_turbo_v2_5)")
+	flag.Float64Var(&cfg.ElevenLabs.Stability, "stability", config.DefaultElevenLabsStability, "ElevenLabs voice stability (0.0 to 1.0)")
+	flag.Float64Var(&cfg.ElevenLabs.SimilarityBoost, "similarity", config.DefaultElevenLabsSimilarity, "ElevenLabs similarity boost (0.0 to 1.0)")
+	flag.Float64Var(&cfg.ElevenLabs.Style, "style-exaggeration", config.DefaultElevenLabsStyle, "ElevenLabs style exaggeration (0.0 to 1.0)")
+	flag.BoolVar(&noSpeakerBoost, "no-speaker-boost", false, "Disable ElevenLabs speaker boost")
+	flag.BoolVar(&cfg.NoLLMTitle, "no-llm-title", false, "Title the speech from its first sentence instead of asking Gemini/OpenAI")
+	flag.StringVar(&cfg.Timestamps, "timestamps", "", "Write sentence start/end times to this JSON file")
+	flag.BoolVar(&cfg.NoCache, "no-tts-cache", false, "Do not read or write the TTS cache (~/.cache/mmmeld/tts)")
+	flag.StringVar(&cfg.Format, "format", "", "Output format: mp3, wav, ogg, flac (default: from --output extension, else mp3)")
+	flag.StringVar(&cfg.Encoding, "encoding", "", "Deepgram output encoding: "+strings.Join(tts.DeepgramEncodings, ", ")+" (overrides the encoding picked from --format)")
+	flag.IntVar(&cfg.SampleRate, "sample-rate", 0, "Deepgram sample rate in Hz for --encoding, e.g. 8000 for telephony (default: Deepgram's)")
+	flag.BoolVar(&cfg.Estimate, "estimate", false, "Print chunk count, characters, and approximate cost, then exit without calling any API")
+	flag.Float64Var(&cfg.Price, "tts-price", 0, "USD per 1,000 characters for --estimate (default: built-in price for the provider)")
+	flag.BoolVar(&cfg.Play, "play", false, "Play the audio when generation succeeds (ffplay, else the system player)")
+	flag.BoolVar(&cfg.ListVoices, "list-voices", false, "List the provider's voices (ID, name, language, gender) and exit")
+	flag.BoolVar(&cfg.JSON, "json", false, "With --list-voices, print JSON instead of a table")
+	flag.StringVar(&cfg.Proxy, "proxy", "", "Proxy URL for TTS and title requests, e.g. http://proxy:3128 (default: HTTPS_PROXY, HTTP_PROXY, and NO_PROXY)")
+	flag.StringVar(&cfg.CACert, "ca-cert", "", "PEM CA bundle to trust on top of the system roots, e.g. for a TLS-inspecting proxy")
+
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Text to Speech Command Line Tool\n\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  %s --text \"Hello world\" --provider elevenlabs --voiceid %s\n", os.Args[0], config.ElevenLabsVoiceID)
+		fmt.Fprintf(os.Stderr, "  %s --provider elevenlabs --list-voices\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --textfile script.txt --provider elevenlabs --estimate\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --textfile spanish.txt --provider elevenlabs --voiceid %s --elevenlabs-model eleven_multilingual_v2\n", os.Args[0], config.ElevenLabsVoiceID)
+		fmt.Fprintf(os.Stderr, "  %s --textfile story.txt --provider elevenlabs --voiceid %s --stability 0.8 --style-exaggeration 0.3\n", os.Args[0], config.ElevenLabsVoiceID)
+		fmt.Fprintf(os.Stderr, "  %s --textfile input.txt --provider openai --voiceid %s --format wav\n", os.Args[0], config.OpenAIVoiceID)
+		fmt.Fprintf(os.Stderr, "  %s --textfile input.txt --provider elevenlabs --voiceid %s --timestamps input.json\n", os.Args[0], config.ElevenLabsVoiceID)
+		fmt.Fprintf(os.Stderr, "  %s --textfile dialogue.txt --provider openai --voices \"ALICE=nova,BOB=onyx\" --dialogue-pause 0.4\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s --textfile input.txt --provider openai --voiceid %s --output speech.mp3\n", os.Args[0], config.OpenAIVoiceID)
+		fmt.Fprintf(os.Stderr, "  %s --text \"Testing one two\" --provider openai --voiceid %s --play\n", os.Args[0], config.OpenAIVoiceID)
+		fmt.Fprintf(os.Stderr, "  %s --textfile input.txt --provider openai --voiceid %s --tts-model tts-1-hd --tts-speed 1.1\n", os.Args[0], config.OpenAIVoiceID)
 		fmt.Fprintf(os.Stderr, "  %s --provider deepgram --voiceid %s < input.txt\n", os.Args[0], config.DeepgramVoiceID)
+		fmt.Fprintf(os.Stderr, "  %s --provider deepgram --voiceid %s --speech-rate 1.15 < input.txt\n", os.Args[0], config.DeepgramVoiceID)
+		fmt.Fprintf(os.Stderr, "  %s --textfile prompt.txt --provider deepgram --voiceid %s --encoding mulaw --sample-rate 8000\n", os.Args[0], config.DeepgramVoiceID)
+		fmt.Fprintf(os.Stderr, "  echo \"Hello world\" | %s --stdin --provider openai --voiceid %s\n", os.Args[0], config.OpenAIVoiceID)
+		fmt.Fprintf(os.Stderr, "  %s --text \"Hello world\" --provider azure --voiceid %s\n", os.Args[0], config.AzureVoiceID)
+		fmt.Fprintf(os.Stderr, "  %s --textfile sermon.txt --provider azure --voiceid %s --lexicon names.json\n", os.Args[0], config.AzureVoiceID)
+		fmt.Fprintf(os.Stderr, "  %s --textfile notes.txt --provider local --tts-command \"piper --model en_US-amy --output_file {out}\" --tts-chunk-size 0\n", os.Args[0])
 	}
 
 	flag.Parse()
@@ -138,16 +317,109 @@ func parseArgs() (*TTSConfig, error) {
 		return nil, fmt.Errorf("provider is required")
 	}
 
-	if cfg.VoiceID == "" {
+	if err := httpx.Configure(cfg.Proxy, cfg.CACert); err != nil {
+		return nil, err
+	}
+
+	if cfg.ListVoices {
+		return cfg, nil
+	}
+
+	cfg.ElevenLabs.SpeakerBoost = !noSpeakerBoost
+	if cfg.ElevenLabs.ModelID != "" && !config.IsKnownElevenLabsModel(cfg.ElevenLabs.ModelID) {
+		logx.Warnf("unknown ElevenLabs model %q, passing it through (known: %s)", cfg.ElevenLabs.ModelID, strings.Join(config.KnownElevenLabsModels, ", "))
+	}
+	if err := config.ValidateVoiceSettings(cfg.ElevenLabs.Stability, cfg.ElevenLabs.SimilarityBoost, cfg.ElevenLabs.Style); err != nil {
+		return nil, err
+	}
+
+	var err error
+	cfg.Voices, err = config.ParseVoiceMap(voices)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Pause < 0 {
+		return nil, fmt.Errorf("dialogue-pause must be zero or positive")
+	}
+	cfg.Lexicon, err = config.LoadLexicon(lexicon)
+	if err != nil {
+		return nil, err
+	}
+
+	// The local provider's voice usually lives in its command template,
+	// dialogue scripts take theirs from --voices, and estimates need none
+	if cfg.VoiceID == "" && cfg.Provider != string(config.ProviderLocal) && len(cfg.Voices) == 0 && !cfg.Estimate {
 		return nil, fmt.Errorf("voice ID is required")
 	}
 
-	// Must have either text, textfile, or default file
-	if cfg.Text == "" && cfg.TextFile == "" && cfg.DefaultFile == "" {
-		return nil, fmt.Errorf("must provide either --text, --textfile, or a default text file argument")
+	if cfg.ChunkSize < 0 {
+		return nil, fmt.Errorf("tts-chunk-size must be zero or positive")
+	}
+
+	if err := config.ValidateTTSSpeed(cfg.Speed); err != nil {
+		return nil, err
+	}
+
+	if err := config.ValidateSpeechRate(cfg.Rate); err != nil {
+		return nil, err
+	}
+
+	if err := config.ValidateSpeedAndRate(cfg.Speed, cfg.Rate); err != nil {
+		return nil, err
+	}
+
+	if cfg.Price < 0 {
+		return nil, fmt.Errorf("tts-price must be zero or positive")
+	}
+
+	if cfg.MaxAttempts < 1 {
+		return nil, fmt.Errorf("tts-max-attempts must be at least 1")
+	}
+
+	// Infer the format from an explicit output name such as speech.wav
+	if cfg.Format == "" && cfg.Output != "" {
+		if ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(cfg.Output)), "."); tts.ValidateFormat(ext) == nil {
+			cfg.Format = ext
+		}
+	}
+	if err := tts.ValidateFormat(cfg.Format); err != nil {
+		return nil, err
+	}
+
+	if (cfg.Encoding != "" || cfg.SampleRate != 0) && cfg.Provider != string(config.ProviderDeepgram) {
+		return nil, fmt.Errorf("--encoding and --sample-rate are only supported by the deepgram provider")
+	}
+	if err := tts.ValidateDeepgramEncoding(cfg.Encoding, cfg.SampleRate, cfg.Format); err != nil {
+		return nil, err
+	}
+	if cfg.Encoding != "" && cfg.Format == "" {
+		cfg.Format = tts.DeepgramEncodingFormat(cfg.Encoding)
+	}
+
+	if err := validateTextSource(cfg, stdinIsPiped()); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// stdinIsPiped reports whether standard input is a pipe or redirected file
+// rather than a terminal
+func stdinIsPiped() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice == 0
+}
+
+// validateTextSource requires exactly one of --text, --textfile, a positional
+// file, or stdin. Piped input counts as a source even without --stdin.
+func validateTextSource(cfg *TTSConfig, stdinPiped bool) error {
+	if cfg.Stdin && !stdinPiped {
+		return fmt.Errorf("--stdin given but standard input is a terminal; pipe or redirect text into it")
 	}
 
-	// Cannot have multiple text sources
 	textSources := 0
 	if cfg.Text != "" {
 		textSources++
@@ -159,11 +431,21 @@ func parseArgs() (*TTSConfig, error) {
 		textSources++
 	}
 
-	if textSources > 1 {
-		return nil, fmt.Errorf("provide only one text source")
+	if stdinPiped {
+		if textSources > 0 {
+			return fmt.Errorf("text is piped on stdin; do not also pass --text, --textfile, or a file argument")
+		}
+		cfg.Stdin = true
+		return nil
 	}
 
-	return cfg, nil
+	if textSources == 0 {
+		return fmt.Errorf("must provide either --text, --textfile, a default text file argument, or text on stdin")
+	}
+	if textSources > 1 {
+		return fmt.Errorf("provide only one text source")
+	}
+	return nil
 }
 
 func getTextInput(cfg *TTSConfig) (string, string, error) {
@@ -187,6 +469,10 @@ func getTextInput(cfg *TTSConfig) (string, string, error) {
 		return string(content), filename, nil
 	}
 
+	if !cfg.Stdin {
+		return "", "", fmt.Errorf("no text source provided")
+	}
+
 	// Read from stdin
 	content, err := io.ReadAll(os.Stdin)
 	if err != nil {
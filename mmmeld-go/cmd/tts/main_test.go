@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestValidateTextSource(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       TTSConfig
+		piped     bool
+		wantErr   bool
+		wantStdin bool
+	}{
+		{"text", TTSConfig{Text: "hello"}, false, false, false},
+		{"textfile", TTSConfig{TextFile: "in.txt"}, false, false, false},
+		{"positional file", TTSConfig{DefaultFile: "in.txt"}, false, false, false},
+		{"no source", TTSConfig{}, false, true, false},
+		{"text and textfile", TTSConfig{Text: "hello", TextFile: "in.txt"}, false, true, false},
+		{"textfile and positional", TTSConfig{TextFile: "a.txt", DefaultFile: "b.txt"}, false, true, false},
+		{"piped stdin", TTSConfig{}, true, false, true},
+		{"explicit stdin", TTSConfig{Stdin: true}, true, false, true},
+		{"stdin flag on a terminal", TTSConfig{Stdin: true}, false, true, true},
+		{"piped plus text", TTSConfig{Text: "hello"}, true, true, false},
+		{"piped plus textfile", TTSConfig{TextFile: "in.txt"}, true, true, false},
+		{"piped plus positional", TTSConfig{DefaultFile: "in.txt"}, true, true, false},
+		{"stdin flag plus text", TTSConfig{Stdin: true, Text: "hello"}, true, true, true},
+	}
+
+	for _, test := range tests {
+		cfg := test.cfg
+		err := validateTextSource(&cfg, test.piped)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: validateTextSource() error = %v, wantErr %v", test.name, err, test.wantErr)
+			continue
+		}
+		if cfg.Stdin != test.wantStdin {
+			t.Errorf("%s: Stdin = %v, expected %v", test.name, cfg.Stdin, test.wantStdin)
+		}
+	}
+}
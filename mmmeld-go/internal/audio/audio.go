@@ -1,74 +1,299 @@
 package audio
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"os"
 	"os/exec"
 	"path/filepath"
-	"strconv"
 	"strings"
 
 	"mmmeld/internal/config"
+	"mmmeld/internal/ffmpeg"
 	"mmmeld/internal/fileutil"
+	"mmmeld/internal/httpx"
+	"mmmeld/internal/logx"
+	"mmmeld/internal/probe"
 	"mmmeld/internal/tts"
 )
 
+// downloadPlaylistAudio, runFFmpeg and extractAudio are variables so tests
+// can join playlists and extract audio tracks without yt-dlp or ffmpeg
+var (
+	downloadPlaylistAudio = fileutil.DownloadPlaylistAudio
+	runFFmpeg             = ffmpeg.RunCommand
+	extractAudio          = ffmpeg.ExtractAudio
+)
+
 type AudioSource struct {
 	Path        string
 	Title       string
 	Description string
+	Duration    float64 // Seconds; only known up front for generated speech
 }
 
-// GetAudioSource processes audio input based on configuration
-func GetAudioSource(cfg *config.Config, cleanup *fileutil.CleanupManager) (*AudioSource, error) {
+// GetAudioSource processes audio input based on configuration. Cancelling ctx
+// stops extracting the audio track of a video input.
+func GetAudioSource(ctx context.Context, cfg *config.Config, cleanup *fileutil.CleanupManager) (*AudioSource, error) {
+	source, err := getAudioSource(ctx, cfg, cleanup)
+	if err != nil {
+		return nil, err
+	}
+
+	// An explicit --title wins over tags, filenames, and generated titles
+	if cfg.Title != "" {
+		source.Title = cfg.Title
+	}
+	return source, nil
+}
+
+func getAudioSource(ctx context.Context, cfg *config.Config, cleanup *fileutil.CleanupManager) (*AudioSource, error) {
 	switch {
 	case cfg.Audio == "generate":
 		if cfg.Text == "" {
 			return nil, fmt.Errorf("text is required for speech generation")
 		}
-		
-		log.Printf("Generating speech using %s provider", cfg.TTSProvider)
-		result, err := tts.GenerateSpeech(cfg.Text, cfg.VoiceID, cfg.TTSProvider, cleanup, "")
+
+		logx.Infof("Generating speech using %s provider", cfg.TTSProvider)
+		result, err := tts.GenerateSpeechWithOptions(cfg.Text, SpeechOptionsFromConfig(cfg), cleanup, "")
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate speech: %w", err)
 		}
-		
+
 		return &AudioSource{
 			Path:        result.AudioPath,
 			Title:       result.Title,
 			Description: result.Description,
+			Duration:    result.Duration,
 		}, nil
-		
+
 	case fileutil.FileExists(cfg.Audio):
-		title := strings.TrimSuffix(filepath.Base(cfg.Audio), filepath.Ext(cfg.Audio))
+		fallback := strings.TrimSuffix(filepath.Base(cfg.Audio), filepath.Ext(cfg.Audio))
+		title, description := readAudioMetadata(cfg.Audio, fallback)
+		audioPath := cfg.Audio
+		if ffmpeg.IsVideoInput(cfg.Audio) {
+			var err error
+			if audioPath, err = extractVideoAudio(ctx, cfg.Audio, cfg.Output, cleanup); err != nil {
+				return nil, err
+			}
+		}
 		return &AudioSource{
-			Path:        cfg.Audio,
+			Path:        audioPath,
 			Title:       title,
-			Description: "",
+			Description: description,
 		}, nil
-		
-	case fileutil.IsYouTubeURL(cfg.Audio):
-		log.Println("Downloading audio from YouTube...")
-		audioPath, err := fileutil.DownloadYouTubeAudio(cfg.Audio, cleanup)
+
+	case fileutil.IsYtDlpURL(cfg.Audio, cfg.ForceYtDlp) && fileutil.IsPlaylistURL(cfg.Audio):
+		return getPlaylistAudio(cfg, cleanup)
+
+	case fileutil.IsYtDlpURL(cfg.Audio, cfg.ForceYtDlp):
+		logx.Infof("Downloading audio with yt-dlp...")
+		audioPath, err := fileutil.DownloadRemoteAudio(cfg.Audio, fileutil.YtDlpOptionsFromConfig(cfg), cleanup)
 		if err != nil {
-			return nil, fmt.Errorf("failed to download YouTube audio: %w", err)
+			return nil, fmt.Errorf("failed to download remote audio: %w", err)
 		}
-		
-		// Extract title from filename
-		title := strings.TrimSuffix(filepath.Base(audioPath), filepath.Ext(audioPath))
+
+		// yt-dlp embeds the video's metadata as tags; fall back to the filename
+		fallback := fileutil.SanitizeFilename(strings.TrimSuffix(filepath.Base(audioPath), filepath.Ext(audioPath)))
+		title, description := readAudioMetadata(audioPath, fallback)
 		return &AudioSource{
 			Path:        audioPath,
-			Title:       fileutil.SanitizeFilename(title),
-			Description: "",
+			Title:       title,
+			Description: description,
 		}, nil
-		
+
+	case httpx.IsHTTPURL(cfg.Audio):
+		logx.Infof("Downloading audio from URL: %s", cfg.Audio)
+		audioPath, err := downloadAndValidateAudio(cfg.Audio, cleanup)
+		if err != nil {
+			return nil, err
+		}
+
+		fallback := strings.TrimSuffix(filepath.Base(audioPath), filepath.Ext(audioPath))
+		title, description := readAudioMetadata(audioPath, fallback)
+		return &AudioSource{
+			Path:        audioPath,
+			Title:       title,
+			Description: description,
+		}, nil
+
 	default:
 		return nil, fmt.Errorf("invalid audio input: %s", cfg.Audio)
 	}
 }
 
-// GetBackgroundMusic processes background music input
-func GetBackgroundMusic(bgMusicPath string, cleanup *fileutil.CleanupManager) (string, error) {
+// getPlaylistAudio downloads a playlist --audio URL and, with --playlist-mode
+// concat, joins its entries in playlist order. The playlist's title names the
+// video; its description lists the tracks.
+func getPlaylistAudio(cfg *config.Config, cleanup *fileutil.CleanupManager) (*AudioSource, error) {
+	if cfg.PlaylistMode != config.PlaylistModeConcat {
+		return nil, fmt.Errorf("--audio %s is a playlist; pass --playlist-mode concat to join its entries into one track, or link a single video", fileutil.RedactURL(cfg.Audio))
+	}
+
+	logx.Infof("Downloading playlist audio with yt-dlp...")
+	playlist, err := downloadPlaylistAudio(cfg.Audio, fileutil.YtDlpOptionsFromConfig(cfg), cleanup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download remote audio: %w", err)
+	}
+
+	tracks := make([]string, len(playlist.Files))
+	for i, file := range playlist.Files {
+		fallback := fileutil.SanitizeFilename(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)))
+		title, _ := readAudioMetadata(file, fallback)
+		tracks[i] = fmt.Sprintf("%d. %s", i+1, title)
+	}
+	title := playlist.Title
+	if title == "" {
+		title = strings.TrimPrefix(tracks[0], "1. ")
+	}
+
+	audioPath, err := concatenatePlaylist(playlist.Files, cfg.Output, cleanup)
+	if err != nil {
+		return nil, err
+	}
+	return &AudioSource{
+		Path:        audioPath,
+		Title:       title,
+		Description: strings.Join(tracks, "\n"),
+	}, nil
+}
+
+// concatenatePlaylist joins downloaded mp3s into one in the temp folder,
+// named for the planned output. Entries may differ in sample rate, so the
+// result is re-encoded rather than stream-copied.
+func concatenatePlaylist(files []string, plannedOutputPath string, cleanup *fileutil.CleanupManager) (string, error) {
+	if len(files) == 1 {
+		return files[0], nil
+	}
+
+	listFile := fileutil.TempAssetPath(config.TempAssetsFolder, plannedOutputPath, "playlist_list.txt")
+	var list strings.Builder
+	for _, file := range files {
+		absPath, err := filepath.Abs(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve audio path %s: %w", file, err)
+		}
+		fmt.Fprintf(&list, "file '%s'\n", strings.ReplaceAll(absPath, "'", "'\\''"))
+	}
+	if err := os.WriteFile(listFile, []byte(list.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to create concat list: %w", err)
+	}
+	defer os.Remove(listFile)
+
+	outputPath := fileutil.TempAssetPath(config.TempAssetsFolder, plannedOutputPath, "playlist.mp3")
+	cleanup.Add(outputPath)
+	if err := runFFmpeg([]string{"ffmpeg", "-y", "-f", "concat", "-safe", "0", "-i", listFile,
+		"-ar", "44100", "-c:a", "libmp3lame", "-b:a", "192k", outputPath}); err != nil {
+		return "", fmt.Errorf("failed to join playlist audio: %w", err)
+	}
+
+	logx.Infof("Joined %d playlist entries into: %s", len(files), outputPath)
+	return outputPath, nil
+}
+
+// extractVideoAudio copies the audio track of a video into an m4a in the temp
+// folder. The output is registered for cleanup first, so a failed or
+// interrupted run does not leave a partial file behind.
+func extractVideoAudio(ctx context.Context, videoPath, plannedOutputPath string, cleanup *fileutil.CleanupManager) (string, error) {
+	outputPath := fileutil.TempAssetPath(config.TempAssetsFolder, plannedOutputPath, "extracted_audio.m4a")
+	cleanup.Add(outputPath)
+	logx.Infof("Extracting audio track from video: %s", videoPath)
+	if err := extractAudio(ctx, videoPath, outputPath, ffmpeg.ExtractOptions{Bitrate: "192k"}); err != nil {
+		return "", fmt.Errorf("failed to extract audio from %s: %w", videoPath, err)
+	}
+	return outputPath, nil
+}
+
+// downloadAndValidateAudio fetches a direct audio URL and rejects files ffmpeg cannot decode
+func downloadAndValidateAudio(url string, cleanup *fileutil.CleanupManager) (string, error) {
+	audioPath, err := fileutil.DownloadAudio(url, cleanup)
+	if err != nil {
+		return "", fmt.Errorf("failed to download audio: %w", err)
+	}
+
+	if !tts.IsValidAudioFile(audioPath) {
+		cleanup.Remove(audioPath)
+		os.Remove(audioPath)
+		return "", fmt.Errorf("downloaded file is not valid audio: %s", url)
+	}
+
+	return audioPath, nil
+}
+
+// readAudioMetadata reads title/artist/album tags from an audio file. The
+// fallback title is used when the file has no title tag or cannot be probed.
+func readAudioMetadata(path, fallbackTitle string) (string, string) {
+	tags, err := probe.Tags(path)
+	if err != nil {
+		logx.Warnf("Could not read audio tags for %s: %v", path, err)
+		return fallbackTitle, ""
+	}
+	return metadataFromTags(tags, fallbackTitle)
+}
+
+// metadataFromTags builds a title and description from lowercased tag keys
+func metadataFromTags(tags map[string]string, fallbackTitle string) (string, string) {
+	title := tags["title"]
+	if title == "" {
+		title = fallbackTitle
+	}
+
+	artist := tags["artist"]
+	if artist == "" {
+		artist = tags["album_artist"]
+	}
+	album := tags["album"]
+
+	var description string
+	switch {
+	case artist != "" && album != "":
+		description = fmt.Sprintf("%s by %s, from the album %s", title, artist, album)
+	case artist != "":
+		description = fmt.Sprintf("%s by %s", title, artist)
+	case album != "":
+		description = fmt.Sprintf("%s, from the album %s", title, album)
+	}
+
+	return title, description
+}
+
+// SpeechOptionsFromConfig maps the mmmeld configuration onto TTS options
+func SpeechOptionsFromConfig(cfg *config.Config) tts.SpeechOptions {
+	// Already validated by config.LoadFromFlags
+	voices, _ := config.ParseVoiceMap(cfg.Voices)
+	lexicon, _ := config.LoadLexicon(cfg.Lexicon)
+
+	return tts.SpeechOptions{
+		Provider:    cfg.TTSProvider,
+		VoiceID:     cfg.VoiceID,
+		Command:     cfg.TTSCommand,
+		ChunkSize:   cfg.TTSChunkSize,
+		Model:       cfg.TTSModel,
+		Speed:       cfg.TTSSpeed,
+		Rate:        cfg.SpeechRate,
+		MaxAttempts: cfg.TTSMaxAttempts,
+		NoCache:     cfg.NoTTSCache,
+
+		Voices:        voices,
+		DialoguePause: cfg.DialoguePause,
+		Lexicon:       lexicon,
+
+		ElevenLabs: &tts.ElevenLabsSettings{
+			ModelID:         cfg.ElevenLabsModel,
+			Stability:       cfg.Stability,
+			SimilarityBoost: cfg.Similarity,
+			Style:           cfg.StyleExaggeration,
+			SpeakerBoost:    !cfg.NoSpeakerBoost,
+		},
+		NoLLMTitle: cfg.NoLLMTitle,
+		GeminiKey:  cfg.GeminiKey,
+	}
+}
+
+// GetBackgroundMusic processes background music input. When forceYtDlp is set,
+// any http(s) URL is downloaded with yt-dlp, using ytDlp, instead of fetched
+// directly.
+func GetBackgroundMusic(bgMusicPath string, forceYtDlp bool, ytDlp fileutil.YtDlpOptions, cleanup *fileutil.CleanupManager) (string, error) {
 	if bgMusicPath == "" {
 		return "", nil
 	}
@@ -77,31 +302,26 @@ func GetBackgroundMusic(bgMusicPath string, cleanup *fileutil.CleanupManager) (s
 	case fileutil.FileExists(bgMusicPath):
 		return bgMusicPath, nil
 		
-	case fileutil.IsYouTubeURL(bgMusicPath):
-		log.Println("Downloading background music from YouTube...")
-		return fileutil.DownloadYouTubeAudio(bgMusicPath, cleanup)
-		
+	case fileutil.IsYtDlpURL(bgMusicPath, forceYtDlp) && fileutil.IsPlaylistURL(bgMusicPath):
+		return "", fmt.Errorf("background music %s is a playlist; link a single track", fileutil.RedactURL(bgMusicPath))
+
+	case fileutil.IsYtDlpURL(bgMusicPath, forceYtDlp):
+		logx.Infof("Downloading background music with yt-dlp...")
+		return fileutil.DownloadRemoteAudio(bgMusicPath, ytDlp, cleanup)
+
+	case httpx.IsHTTPURL(bgMusicPath):
+		logx.Infof("Downloading background music from URL: %s", bgMusicPath)
+		return downloadAndValidateAudio(bgMusicPath, cleanup)
+
 	default:
 		return "", fmt.Errorf("invalid background music input: %s", bgMusicPath)
 	}
 }
 
-// GetAudioDuration returns the duration of an audio file in seconds using ffmpeg
+// GetAudioDuration returns the duration of an audio file in seconds using ffprobe.
+// Results are cached per file for the rest of the run.
 func GetAudioDuration(filepath string) (float64, error) {
-	cmd := exec.Command("ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "csv=p=0", filepath)
-	output, err := cmd.Output()
-	if err != nil {
-		return 0, fmt.Errorf("failed to get audio duration: %w", err)
-	}
-	
-	durationStr := strings.TrimSpace(string(output))
-	duration, err := strconv.ParseFloat(durationStr, 64)
-	if err != nil {
-		return 0, fmt.Errorf("failed to parse duration: %w", err)
-	}
-	
-	log.Printf("Audio duration for %s: %.3f seconds", filepath, duration)
-	return duration, nil
+	return probe.Duration(filepath)
 }
 
 // ValidateAudioFile checks if a file is a valid audio file using ffmpeg
@@ -134,7 +354,7 @@ func ConvertToFormat(inputPath, outputPath, format string, cleanup *fileutil.Cle
 	}
 	
 	cleanup.Add(outputPath)
-	log.Printf("Converted audio to %s: %s", format, outputPath)
+	logx.Debugf("Converted audio to %s: %s", format, outputPath)
 	
 	return nil
 }
@@ -183,7 +403,7 @@ func ApplyAudioEffects(inputPath, outputPath string, volume float64, fadeInDurat
 	}
 	
 	cleanup.Add(outputPath)
-	log.Printf("Applied audio effects: %s", outputPath)
+	logx.Debugf("Applied audio effects: %s", outputPath)
 	
 	return nil
 }
@@ -238,7 +458,7 @@ func MixAudioFiles(files []string, outputPath string, volumes []float64, cleanup
 	}
 	
 	cleanup.Add(outputPath)
-	log.Printf("Mixed %d audio files: %s", len(files), outputPath)
+	logx.Debugf("Mixed %d audio files: %s", len(files), outputPath)
 	
 	return nil
 }
\ No newline at end of file
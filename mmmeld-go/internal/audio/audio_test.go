@@ -0,0 +1,167 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mmmeld/internal/config"
+	"mmmeld/internal/ffmpeg"
+	"mmmeld/internal/fileutil"
+)
+
+// fakePlaylist replaces yt-dlp with a download of the named entries into the
+// temp folder, and ffmpeg with a recorder that writes its output file
+func fakePlaylist(t *testing.T, entries ...string) (commands *[][]string, lists *[]string) {
+	t.Helper()
+	saved := config.TempAssetsFolder
+	config.TempAssetsFolder = t.TempDir()
+	origDownload, origFFmpeg := downloadPlaylistAudio, runFFmpeg
+	t.Cleanup(func() {
+		config.TempAssetsFolder = saved
+		downloadPlaylistAudio, runFFmpeg = origDownload, origFFmpeg
+	})
+
+	downloadPlaylistAudio = func(url string, opts fileutil.YtDlpOptions, cleanup *fileutil.CleanupManager) (*fileutil.Playlist, error) {
+		playlist := &fileutil.Playlist{Title: "Road Trip"}
+		for _, entry := range entries {
+			path := filepath.Join(config.TempAssetsFolder, entry+".mp3")
+			if err := os.WriteFile(path, []byte("mp3"), 0644); err != nil {
+				return nil, err
+			}
+			playlist.Files = append(playlist.Files, path)
+		}
+		return playlist, nil
+	}
+	commands, lists = &[][]string{}, &[]string{}
+	runFFmpeg = func(cmd []string) error {
+		*commands = append(*commands, cmd)
+		for i, arg := range cmd {
+			if arg == "-i" {
+				list, _ := os.ReadFile(cmd[i+1])
+				*lists = append(*lists, string(list))
+			}
+		}
+		return os.WriteFile(cmd[len(cmd)-1], []byte("joined"), 0644)
+	}
+	return commands, lists
+}
+
+func TestGetPlaylistAudioConcat(t *testing.T) {
+	commands, lists := fakePlaylist(t, "first", "it's second")
+	cfg := &config.Config{
+		Audio:        "https://www.youtube.com/playlist?list=PL123",
+		PlaylistMode: config.PlaylistModeConcat,
+		Output:       "trip.mp4",
+	}
+	cleanup := fileutil.NewCleanupManager()
+
+	source, err := getPlaylistAudio(cfg, cleanup)
+	if err != nil {
+		t.Fatalf("getPlaylistAudio() error: %v", err)
+	}
+	if source.Title != "Road Trip" {
+		t.Errorf("Title = %q, expected the playlist title", source.Title)
+	}
+	if source.Description != "1. first\n2. it's second" {
+		t.Errorf("Description = %q, expected the numbered tracks", source.Description)
+	}
+	if len(*commands) != 1 {
+		t.Fatalf("ran ffmpeg %d times, expected once", len(*commands))
+	}
+	if !strings.HasPrefix(filepath.Base(source.Path), filepath.Base(fileutil.TempAssetPath("", cfg.Output, ""))) ||
+		!strings.HasSuffix(source.Path, "_playlist.mp3") {
+		t.Errorf("Path = %q, expected a temp asset named for %s", source.Path, cfg.Output)
+	}
+	if list := (*lists)[0]; !strings.Contains(list, "first.mp3'\n") || !strings.Contains(list, `it'\''s second.mp3'`) {
+		t.Errorf("concat list = %q, expected both entries in order with quotes escaped", list)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(config.TempAssetsFolder, "*playlist_list.txt")); len(matches) != 0 {
+		t.Errorf("concat list left behind: %v", matches)
+	}
+
+	cleanup.Cleanup()
+	if fileutil.FileExists(source.Path) {
+		t.Error("joined playlist not registered for cleanup")
+	}
+}
+
+func TestGetPlaylistAudioSingleEntry(t *testing.T) {
+	commands, _ := fakePlaylist(t, "only")
+	cfg := &config.Config{Audio: "https://www.youtube.com/playlist?list=PL1", PlaylistMode: config.PlaylistModeConcat}
+
+	source, err := getPlaylistAudio(cfg, fileutil.NewCleanupManager())
+	if err != nil {
+		t.Fatalf("getPlaylistAudio() error: %v", err)
+	}
+	if filepath.Base(source.Path) != "only.mp3" || len(*commands) != 0 {
+		t.Errorf("Path = %q after %d ffmpeg runs, expected the entry itself", source.Path, len(*commands))
+	}
+}
+
+func TestGetPlaylistAudioNeedsConcatMode(t *testing.T) {
+	commands, _ := fakePlaylist(t, "first", "second")
+	cfg := &config.Config{Audio: "https://www.youtube.com/playlist?list=PL1"}
+
+	_, err := getPlaylistAudio(cfg, fileutil.NewCleanupManager())
+	if err == nil || !strings.Contains(err.Error(), "--playlist-mode concat") {
+		t.Errorf("getPlaylistAudio() error = %v, expected a --playlist-mode hint", err)
+	}
+	if len(*commands) != 0 {
+		t.Error("ffmpeg ran without --playlist-mode concat")
+	}
+}
+
+// fakeExtract replaces ffmpeg's audio extraction with one that writes its
+// output file and then returns err
+func fakeExtract(t *testing.T, err error) {
+	t.Helper()
+	saved, orig := config.TempAssetsFolder, extractAudio
+	config.TempAssetsFolder = t.TempDir()
+	t.Cleanup(func() { config.TempAssetsFolder, extractAudio = saved, orig })
+	extractAudio = func(ctx context.Context, inputPath, outputPath string, opts ffmpeg.ExtractOptions) error {
+		if werr := os.WriteFile(outputPath, []byte("m4a"), 0644); werr != nil {
+			return werr
+		}
+		return err
+	}
+}
+
+func TestGetAudioSourceExtractsVideo(t *testing.T) {
+	tests := []struct {
+		name        string
+		extractErr  error
+		expectError bool
+	}{
+		{name: "extracted", extractErr: nil, expectError: false},
+		{name: "ffmpeg failure", extractErr: errors.New("exit status 1"), expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fakeExtract(t, test.extractErr)
+			video := filepath.Join(t.TempDir(), "clip.mp4")
+			if err := os.WriteFile(video, []byte("mp4"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			cfg := &config.Config{Audio: video, Output: "clip_mmmeld.mp4"}
+			cleanup := fileutil.NewCleanupManager()
+
+			source, err := GetAudioSource(context.Background(), cfg, cleanup)
+			if (err != nil) != test.expectError {
+				t.Fatalf("GetAudioSource() error = %v, expectError %v", err, test.expectError)
+			}
+			if err == nil && (source.Path == video || !strings.HasPrefix(filepath.Base(source.Path), filepath.Base(fileutil.TempAssetPath("", cfg.Output, "")))) {
+				t.Errorf("Path = %q, expected a temp asset named for %s", source.Path, cfg.Output)
+			}
+
+			cleanup.Cleanup()
+			if matches, _ := filepath.Glob(filepath.Join(config.TempAssetsFolder, "*extracted_audio.m4a")); len(matches) != 0 {
+				t.Errorf("extracted audio left behind after cleanup: %v", matches)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+// Package batch reads --manifest files, which describe several videos to
+// render in one run. Each job is a set of mmmeld flags layered over the
+// manifest's defaults; the caller runs every job as its own mmmeld process.
+package batch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// reservedFlags are set by the batch runner for every job, so manifests may
+// not set them
+var reservedFlags = map[string]bool{
+	"manifest":        true,
+	"parallel":        true,
+	"temp-dir":        true,
+	"json":            true,
+	"non-interactive": true,
+	"summary":         true, // One path would be overwritten by every job; see SummaryPath
+}
+
+// Manifest is a parsed --manifest file
+type Manifest struct {
+	Defaults []string // Flags every job starts from
+	Jobs     []Job
+}
+
+// Job is one video of a manifest
+type Job struct {
+	Name   string   // The job's "name", or its output file name without extension
+	Output string   // Where the video is written
+	Args   []string // The job's own flags, applied after the defaults
+}
+
+// manifestFile is the YAML layout of a manifest. JSON manifests work too.
+type manifestFile struct {
+	Defaults map[string]any   `yaml:"defaults"`
+	Jobs     []map[string]any `yaml:"jobs"`
+}
+
+// Load reads and parses the manifest at path
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Parse parses a manifest. Keys are mmmeld flag names; underscores may stand
+// in for dashes, so bg_music and bg-music are the same flag. Lists become
+// comma-separated values, as --image expects.
+func Parse(data []byte) (*Manifest, error) {
+	var file manifestFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil {
+		return nil, err
+	}
+	if len(file.Jobs) == 0 {
+		return nil, errors.New("no jobs")
+	}
+
+	m := &Manifest{}
+	var err error
+	if _, ok := file.Defaults["name"]; ok {
+		return nil, errors.New("defaults: name must be set per job")
+	}
+	if m.Defaults, err = flagArgs(file.Defaults); err != nil {
+		return nil, fmt.Errorf("defaults: %w", err)
+	}
+	defaultOutput := lookup(file.Defaults, "output", "o")
+
+	outputs := make(map[string]int)
+	for i, entry := range file.Jobs {
+		var job Job
+		if name, ok := entry["name"]; ok {
+			job.Name, _ = formatValue(name)
+			delete(entry, "name")
+		}
+		if job.Args, err = flagArgs(entry); err != nil {
+			return nil, fmt.Errorf("job %d: %w", i+1, err)
+		}
+
+		job.Output = lookup(entry, "output", "o")
+		if job.Output == "" {
+			job.Output = defaultOutput
+		}
+		// Without an output each job would pick its own name and the summary
+		// could not say where the video went
+		if job.Output == "" {
+			return nil, fmt.Errorf("job %d has no output", i+1)
+		}
+		clean := filepath.Clean(job.Output)
+		if prev, ok := outputs[clean]; ok {
+			return nil, fmt.Errorf("jobs %d and %d both write %s", prev, i+1, job.Output)
+		}
+		outputs[clean] = i + 1
+
+		if job.Name == "" {
+			job.Name = strings.TrimSuffix(filepath.Base(job.Output), filepath.Ext(job.Output))
+		}
+		m.Jobs = append(m.Jobs, job)
+	}
+	return m, nil
+}
+
+// flagArgs converts manifest settings to --flag=value arguments, sorted by
+// flag so runs are repeatable
+func flagArgs(settings map[string]any) ([]string, error) {
+	values := make(map[string]string, len(settings))
+	for key, v := range settings {
+		name := flagName(key)
+		if reservedFlags[name] {
+			return nil, fmt.Errorf("%s is set by the batch runner", key)
+		}
+		value, err := formatValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if _, dup := values[name]; dup {
+			return nil, fmt.Errorf("%s is set twice", name)
+		}
+		values[name] = value
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	args := make([]string, len(names))
+	for i, name := range names {
+		args[i] = "--" + name + "=" + values[name]
+	}
+	return args, nil
+}
+
+// flagName turns a manifest key such as bg_music into the flag bg-music
+func flagName(key string) string {
+	return strings.ReplaceAll(strings.TrimLeft(key, "-"), "_", "-")
+}
+
+// lookup returns the formatted value of the first of names set in settings
+func lookup(settings map[string]any, names ...string) string {
+	for key, v := range settings {
+		for _, name := range names {
+			if flagName(key) == name {
+				value, _ := formatValue(v)
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// formatValue renders a YAML scalar, or a list of them, as a flag value
+func formatValue(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			s, err := formatValue(item)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, ","), nil
+	case nil:
+		return "", errors.New("has no value")
+	default:
+		return "", fmt.Errorf("unsupported value %v", v)
+	}
+}
+
+// CommonArgs returns command-line arguments without --manifest, --parallel,
+// and --summary, leaving the flags that apply to every job
+func CommonArgs(args []string) []string {
+	var common []string
+	for i := 0; i < len(args); i++ {
+		name, _, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") || (name != "manifest" && name != "parallel" && name != "summary") {
+			common = append(common, args[i])
+			continue
+		}
+		if !hasValue {
+			i++ // Skip the value in "--manifest jobs.yaml"
+		}
+	}
+	return common
+}
+
+// SummaryPath derives the run summary path of the job named name from the
+// --summary given for the whole batch: runs.json -> runs_ep01.json
+func SummaryPath(summary, name string) string {
+	ext := filepath.Ext(summary)
+	return strings.TrimSuffix(summary, ext) + "_" + name + ext
+}
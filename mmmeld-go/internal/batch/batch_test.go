@@ -0,0 +1,127 @@
+package batch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	manifest := `
+defaults:
+  image_provider: dalle
+  bg_music: music/weekly.mp3
+  bg-music-volume: 0.15
+jobs:
+  - name: Episode 1
+    audio: ep01.mp3
+    image: [cover1.png, clip.mp4]
+    image_caption: "Episode 1"
+    output: out/ep01.mp4
+  - audio: generate
+    text: Welcome back
+    aspect_ratio: 9:16
+    upscale: true
+    seed: 42
+    output: out/ep02.mp4
+`
+	m, err := Parse([]byte(manifest))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	expectedDefaults := []string{"--bg-music=music/weekly.mp3", "--bg-music-volume=0.15", "--image-provider=dalle"}
+	if !reflect.DeepEqual(m.Defaults, expectedDefaults) {
+		t.Errorf("Parse() defaults = %q, expected %q", m.Defaults, expectedDefaults)
+	}
+
+	expected := []Job{
+		{
+			Name:   "Episode 1",
+			Output: "out/ep01.mp4",
+			Args:   []string{"--audio=ep01.mp3", "--image=cover1.png,clip.mp4", "--image-caption=Episode 1", "--output=out/ep01.mp4"},
+		},
+		{
+			Name:   "ep02",
+			Output: "out/ep02.mp4",
+			Args:   []string{"--aspect-ratio=9:16", "--audio=generate", "--output=out/ep02.mp4", "--seed=42", "--text=Welcome back", "--upscale=true"},
+		},
+	}
+	if !reflect.DeepEqual(m.Jobs, expected) {
+		t.Errorf("Parse() jobs = %+v, expected %+v", m.Jobs, expected)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		expected string
+	}{
+		{"no jobs", "defaults:\n  audio: a.mp3\n", "no jobs"},
+		{"unknown section", "job:\n  - output: a.mp4\n", "field job not found"},
+		{"missing output", "jobs:\n  - audio: a.mp3\n", "job 1 has no output"},
+		{"duplicate output", "jobs:\n  - output: a.mp4\n  - output: ./a.mp4\n", "jobs 1 and 2 both write"},
+		{"reserved flag", "jobs:\n  - output: a.mp4\n    parallel: 2\n", "set by the batch runner"},
+		{"job summary", "jobs:\n  - output: a.mp4\n    summary: a.json\n", "set by the batch runner"},
+		{"same flag twice", "jobs:\n  - output: a.mp4\n    bg_music: a.mp3\n    bg-music: b.mp3\n", "bg-music is set twice"},
+		{"empty value", "jobs:\n  - output: a.mp4\n    image:\n", "image: has no value"},
+		{"nested value", "jobs:\n  - output: a.mp4\n    image: {path: a.png}\n", "unsupported value"},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt.manifest))
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("Parse(%s) error = %v, expected it to contain %q", tt.name, err, tt.expected)
+		}
+	}
+}
+
+func TestParseDefaultOutput(t *testing.T) {
+	// A single job may take its output from the defaults
+	m, err := Parse([]byte("defaults:\n  o: weekly.mp4\njobs:\n  - audio: a.mp3\n"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if m.Jobs[0].Output != "weekly.mp4" || m.Jobs[0].Name != "weekly" {
+		t.Errorf("Parse() job = %+v, expected output weekly.mp4 named weekly", m.Jobs[0])
+	}
+}
+
+func TestCommonArgs(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"--manifest", "jobs.yaml", "--resume"}, []string{"--resume"}},
+		{[]string{"--manifest=jobs.yaml", "-parallel", "3", "--quiet"}, []string{"--quiet"}},
+		{[]string{"--image-provider", "dalle", "--parallel=2", "--manifest", "jobs.yaml"}, []string{"--image-provider", "dalle"}},
+		{[]string{"--text", "manifest"}, []string{"--text", "manifest"}},
+		{[]string{"--summary", "runs.json", "--manifest", "jobs.yaml", "--summary=x.json", "--quiet"}, []string{"--quiet"}},
+	}
+
+	for _, test := range tests {
+		result := CommonArgs(test.args)
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("CommonArgs(%q) = %q, expected %q", test.args, result, test.expected)
+		}
+	}
+}
+
+func TestSummaryPath(t *testing.T) {
+	tests := []struct {
+		summary  string
+		name     string
+		expected string
+	}{
+		{"runs.json", "ep01", "runs_ep01.json"},
+		{"out/summary", "ep02", "out/summary_ep02"},
+		{"out.d/run.v1.json", "intro", "out.d/run.v1_intro.json"},
+	}
+
+	for _, test := range tests {
+		if got := SummaryPath(test.summary, test.name); got != test.expected {
+			t.Errorf("SummaryPath(%q, %q) = %q, expected %q", test.summary, test.name, got, test.expected)
+		}
+	}
+}
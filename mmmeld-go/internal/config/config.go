@@ -1,40 +1,166 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+
+	"mmmeld/internal/httpx"
+	"mmmeld/internal/logx"
+	"mmmeld/internal/probe"
+
+	"golang.org/x/term"
 )
 
+// TempAssetsFolder holds a run's intermediate files. --temp-dir changes it,
+// which batch runs use to give each job its own folder.
+var TempAssetsFolder = "temp_assets"
+
+// DefaultMaxDownloadMB is the default --max-download-size
+const DefaultMaxDownloadMB = 500
+
+// MaxDownloadSize caps each HTTP download of an image or audio file, in
+// bytes. --max-download-size changes it.
+var MaxDownloadSize int64 = DefaultMaxDownloadMB << 20
+
 const (
-	TempAssetsFolder     = "temp_assets"
-	MaxFilenameLength    = 100
-	ElevenLabsVoiceID    = "WWr4C8ld745zI3BiA8n7"
-	ElevenLabsModelID    = "eleven_v3"
-	OpenAIVoiceID        = "onyx"
-	DeepgramVoiceID      = "aura-zeus-en"
-	DefaultBGMusicVolume = 0.2
+	MaxFilenameLength      = 100
+	ElevenLabsVoiceID      = "WWr4C8ld745zI3BiA8n7"
+	ElevenLabsModelID      = "eleven_v3" // Default ElevenLabs model
+	OpenAIVoiceID          = "onyx"
+	OpenAITTSModel         = "tts-1"
+	DeepgramVoiceID        = "aura-zeus-en"
+	AzureVoiceID           = "en-US-JennyNeural"
+	DefaultBGMusicVolume   = 0.2
+	DefaultTTSChunkSize    = 4096
+	DefaultTTSMaxAttempts  = 4
+	DefaultTTSCacheMaxSize = 1 << 30 // Bytes kept in the on-disk TTS cache before eviction
+
+	DefaultImageCacheMaxSize = 512 << 20 // Bytes kept in the on-disk image cache before eviction
+
+	DefaultDownloadCacheTTLDays = 30 // Days a cached yt-dlp download is kept unused
+
+	// ElevenLabs voice_settings defaults
+	DefaultElevenLabsStability  = 0.5
+	DefaultElevenLabsSimilarity = 0.8
+	DefaultElevenLabsStyle      = 0.0
 )
 
+// KnownElevenLabsModels are the ElevenLabs model IDs mmmeld has been used
+// with. Other IDs are passed through with a warning.
+var KnownElevenLabsModels = []string{
+	"eleven_v3",
+	"eleven_multilingual_v2",
+	"eleven_turbo_v2_5",
+	"eleven_flash_v2_5",
+	"eleven_turbo_v2",
+	"eleven_flash_v2",
+	"eleven_monolingual_v1",
+}
+
+// IsKnownElevenLabsModel reports whether id is in KnownElevenLabsModels
+func IsKnownElevenLabsModel(id string) bool {
+	for _, known := range KnownElevenLabsModels {
+		if id == known {
+			return true
+		}
+	}
+	return false
+}
+
 type TTSProvider string
 
 const (
 	ProviderElevenLabs TTSProvider = "elevenlabs"
 	ProviderOpenAI     TTSProvider = "openai"
 	ProviderDeepgram   TTSProvider = "deepgram"
+	ProviderAzure      TTSProvider = "azure"
+	ProviderLocal      TTSProvider = "local" // External command such as Piper
 )
 
+// DefaultVoiceID returns the default voice for a TTS provider
+func DefaultVoiceID(provider TTSProvider) string {
+	switch provider {
+	case ProviderOpenAI:
+		return OpenAIVoiceID
+	case ProviderDeepgram:
+		return DeepgramVoiceID
+	case ProviderAzure:
+		return AzureVoiceID
+	case ProviderLocal:
+		return "" // Voice is baked into the command template unless {voice} is used
+	default:
+		return ElevenLabsVoiceID
+	}
+}
+
 type ImageProvider string
 
 const (
-	ImageProviderDALLE    ImageProvider = "dalle"
-	ImageProviderIdeogram ImageProvider = "ideogram"
+	ImageProviderDALLE     ImageProvider = "dalle"
+	ImageProviderIdeogram  ImageProvider = "ideogram"
+	ImageProviderStability ImageProvider = "stability"
+	ImageProviderReplicate ImageProvider = "replicate"
+	ImageProviderGPTImage  ImageProvider = "gpt-image"
+	ImageProviderImagen    ImageProvider = "imagen"
+	ImageProviderLocalSD   ImageProvider = "local-sd"
+)
+
+// KeyEnv returns the environment variable holding the provider's API key,
+// or "" for providers that need none
+func (p ImageProvider) KeyEnv() string {
+	switch p {
+	case ImageProviderIdeogram:
+		return "IDEOGRAM_API_KEY"
+	case ImageProviderDALLE, ImageProviderGPTImage:
+		return "OPENAI_API_KEY"
+	case ImageProviderImagen:
+		return "GEMINI_API_KEY"
+	case ImageProviderStability:
+		return "STABILITY_API_KEY"
+	case ImageProviderReplicate:
+		return "REPLICATE_API_TOKEN"
+	default:
+		return ""
+	}
+}
+
+// DefaultReplicateModel is the Replicate model used by the replicate image provider
+const DefaultReplicateModel = "black-forest-labs/flux-dev"
+
+// Local Stable Diffusion (A1111 sdapi) defaults for the local-sd image provider
+const (
+	DefaultSDEndpoint = "http://127.0.0.1:7860"
+	DefaultSDSteps    = 30
+	DefaultSDTimeout  = 600 // seconds; local generation on modest GPUs is slow
 )
 
+// CaptionFallbackDrawtext overlays captions with ffmpeg drawtext after text
+// validation fails on every attempt
+const CaptionFallbackDrawtext = "drawtext"
+
+// Playlist modes: what a playlist --audio URL does
+const (
+	PlaylistModeError  = "error"  // Refuse it, before downloading anything
+	PlaylistModeConcat = "concat" // Join its entries, in playlist order, into one track
+)
+
+// DefaultCaptionColor is the drawtext caption fallback's text color
+const DefaultCaptionColor = "white"
+
+// DefaultImageWeight is the Ideogram remix weight given to --reference-image
+const DefaultImageWeight = 50
+
+// MaxStabilitySeed is the largest seed Stability AI accepts (0 = random)
+const MaxStabilitySeed = 4294967294
+
 type AspectRatio string
 
 const (
@@ -47,6 +173,22 @@ const (
 	AspectRatio2x3  AspectRatio = "2:3"  // Portrait photo
 )
 
+// FitMode controls how user-supplied stills are adjusted to the aspect ratio
+type FitMode string
+
+const (
+	FitNone    FitMode = ""        // Leave images as they are
+	FitCrop    FitMode = "crop"    // Crop around the focus point
+	FitPad     FitMode = "pad"     // Letterbox/pillarbox with black bars
+	FitStretch FitMode = "stretch" // Scale non-uniformly
+)
+
+// Focus is a crop focus point as fractions of width and height (0.5,0.5 = center)
+type Focus struct {
+	X float64
+	Y float64
+}
+
 type AudioMargins struct {
 	Start float64
 	End   float64
@@ -54,10 +196,31 @@ type AudioMargins struct {
 
 type Config struct {
 	// Audio options
-	Audio       string      `json:"audio"`
-	Text        string      `json:"text"`
-	VoiceID     string      `json:"voice_id"`
-	TTSProvider TTSProvider `json:"tts_provider"`
+	Audio          string      `json:"audio"`
+	Title          string      `json:"title"` // Overrides titles derived from tags, filenames, or TTS
+	Text           string      `json:"text"`
+	VoiceID        string      `json:"voice_id"`
+	TTSProvider    TTSProvider `json:"tts_provider"`
+	TTSCommand     string      `json:"tts_command"`      // Command template for the local TTS provider
+	TTSChunkSize   int         `json:"tts_chunk_size"`   // Max characters per TTS request (0 = no chunking for local)
+	TTSModel       string      `json:"tts_model"`        // TTS model (OpenAI: tts-1, tts-1-hd, gpt-4o-mini-tts)
+	TTSSpeed       float64     `json:"tts_speed"`        // OpenAI speaking speed (0.25-4.0, 0 = default)
+	SpeechRate     float64     `json:"speech_rate"`      // Speaking rate for any provider (native or ffmpeg atempo)
+	TTSMaxAttempts int         `json:"tts_max_attempts"` // Attempts per TTS chunk on transient API failures
+	NoTTSCache     bool        `json:"no_tts_cache"`     // Always call the TTS provider instead of reusing cached chunks
+	NoImageCache   bool        `json:"no_image_cache"`   // Always call the image provider instead of reusing cached images
+	NoPromptCache  bool        `json:"no_prompt_cache"`  // Always analyze the audio instead of reusing a cached image prompt
+	Voices         string      `json:"voices"`           // Dialogue speaker voices, e.g. "ALICE=voice1,BOB=voice2"
+	DialoguePause  float64     `json:"dialogue_pause"`   // Seconds of silence between dialogue turns
+	Lexicon        string      `json:"lexicon"`          // JSON file of pronunciation substitutions
+	TTSPrice       float64     `json:"tts_price"`        // USD per 1,000 characters for --estimate (0 = built-in table)
+
+	// ElevenLabs model and voice settings
+	ElevenLabsModel   string  `json:"elevenlabs_model"`
+	Stability         float64 `json:"stability"`
+	Similarity        float64 `json:"similarity"`
+	StyleExaggeration float64 `json:"style_exaggeration"`
+	NoSpeakerBoost    bool    `json:"no_speaker_boost"`
 
 	// Image/Video options
 	Image            string        `json:"image"`
@@ -69,13 +232,35 @@ type Config struct {
 	BGMusicVolume float64 `json:"bg_music_volume"`
 
 	// Output options
-	Output       string       `json:"output"`
-	AudioMargins AudioMargins `json:"audio_margins"`
+	Output             string       `json:"output"`
+	OutputDir          string       `json:"output_dir"`  // Folder for the default output name or a relative --output (empty = next to local audio)
+	Overwrite          bool         `json:"overwrite"`   // Replace an existing output file
+	AutoNumber         bool         `json:"auto_number"` // Write name_2.mp4, name_3.mp4, ... instead of replacing an existing output
+	Summary            string       `json:"summary"`     // Run summary JSON path (empty = <output without extension>.mmmeld.json)
+	AudioMargins       AudioMargins `json:"audio_margins"`
+	MaxSourceDimension int          `json:"max_source_dimension"` // Long-edge cap for still inputs (0 = 2x the canvas)
+	Background         string       `json:"background"`           // Canvas fill around mismatched inputs: black, blur, auto, or color:#RRGGBB
 
 	// Behavior flags
 	Cleanup     bool `json:"cleanup"`
 	AutoFill    bool `json:"auto_fill"`
 	ShowPrompts bool `json:"show_prompts"`
+	ForceYtDlp  bool `json:"force_ytdlp"`  // Send any http(s) URL through yt-dlp
+	NoLLMTitle  bool `json:"no_llm_title"` // Title generated speech from its first sentence instead of an LLM
+	Estimate    bool `json:"estimate"`     // Print the TTS chunk/character/cost estimate and exit
+	Resume      bool `json:"resume"`       // Reuse stages a failed run completed, per the temp assets run manifest
+	JSON        bool `json:"json"`         // Write progress and the result as newline-delimited JSON events on stdout
+
+	NonInteractive bool `json:"non_interactive"` // Fail on missing inputs instead of prompting; default when stdin is not a terminal
+	SkipPreflight  bool `json:"skip_preflight"`  // Start without checking API keys and external tools first
+
+	Manifest string `json:"manifest"` // Batch manifest of jobs, each rendered by its own mmmeld process
+	Parallel int    `json:"parallel"` // Batch jobs run at once
+	TempDir  string `json:"temp_dir"` // Overrides TempAssetsFolder (empty = temp_assets)
+
+	LogLevel string `json:"log_level"` // error, warn, info, or debug (empty = info, or debug with MMMELD_DEBUG=1)
+	Quiet    bool   `json:"quiet"`     // Shorthand for --log-level warn
+	Verbose  bool   `json:"verbose"`   // Shorthand for --log-level debug
 
 	// API Keys
 	OpenAIKey     string `json:"-"` // Don't serialize keys
@@ -83,29 +268,118 @@ type Config struct {
 	DeepgramKey   string `json:"-"`
 	GeminiKey     string `json:"-"`
 	IdeogramKey   string `json:"-"`
+	StabilityKey  string `json:"-"`
+	ReplicateKey  string `json:"-"`
+	AzureKey      string `json:"-"`
+	AzureRegion   string `json:"azure_region"`
 
 	// Audio analysis options
 	AnalyzeAudio    bool   `json:"analyze_audio"`    // Use Gemini to analyze audio for image prompt
 	AudioNotes      string `json:"audio_notes"`      // Notes for audio analysis (genre, mood, themes)
+	AnalyzeWindow   string `json:"analyze_window"`   // Analyze only this part of the audio, e.g. 0:60-4:00 (empty = whole file)
 	ImageCaption    string `json:"image_caption"`    // Caption/title text to render on the image
 	ImageSubcaption string `json:"image_subcaption"` // Subcaption/subtitle text to render on the image
+	ImageAvoid      string `json:"image_avoid"`      // Comma-separated terms generated images should avoid
+	Language        string `json:"language"`         // Language of the caption text, e.g. pt-BR (empty = English)
 
 	// Image generation options
 	AspectRatio AspectRatio `json:"aspect_ratio"` // Aspect ratio for generated images
 	ImageStyle  string      `json:"image_style"`  // Style preference for generated images (auto, photorealistic, artistic, abstract, cinematic)
 	StyleType   string      `json:"style_type"`   // Ideogram style type (AUTO, GENERAL, REALISTIC, DESIGN, FICTION)
 	StylePreset string      `json:"style_preset"` // Ideogram style preset (e.g., CINEMATIC, OIL_PAINTING, etc.)
+
+	NegativePrompt string `json:"negative_prompt"` // Stability: what the image should not contain
+	Seed           int64  `json:"seed"`            // Stability/Replicate: generation seed (0 = random)
+	ReplicateModel string `json:"replicate_model"` // Replicate model slug, optionally with :version
+	ImageQuality   string `json:"image_quality"`   // gpt-image-1 quality: low, medium, high (empty = auto)
+
+	SDEndpoint string `json:"sd_endpoint"` // Base URL of the local A1111-compatible Stable Diffusion server
+	SDSteps    int    `json:"sd_steps"`    // Sampling steps for local-sd
+	SDSampler  string `json:"sd_sampler"`  // Sampler name for local-sd (empty = server default)
+	SDTimeout  int    `json:"sd_timeout"`  // Seconds to wait for a local-sd image
+
+	Upscale bool `json:"upscale"` // Upscale the accepted generated image with Ideogram
+
+	Fit   FitMode `json:"fit"`   // Adjust local stills to AspectRatio (crop, pad, stretch)
+	Focus Focus   `json:"focus"` // Crop focus point for Fit == FitCrop
+
+	StrictAspect bool `json:"strict_aspect"` // Reject generated images off the aspect ratio instead of cropping them
+	Lenient      bool `json:"lenient"`       // Warn and use 16:9 for an unknown --aspect-ratio instead of failing
+
+	CaptionFallback string `json:"caption_fallback"`  // "drawtext" to overlay captions when text validation keeps failing
+	CaptionFont     string `json:"caption_font"`      // Font file for the drawtext caption fallback
+	CaptionFontSize int    `json:"caption_font_size"` // Caption font size in pixels (0 = relative to image height)
+	CaptionColor    string `json:"caption_color"`     // Caption text color for the drawtext fallback
+
+	CasingPolicy string `json:"casing_policy"` // Caption casing validation: strict, any, or exact
+	Reviewer     string `json:"reviewer"`      // Model that stands in for Gemini validation: openai, anthropic, ollama, or none
+	ReviewModel  string `json:"review_model"`  // Reviewer model for the validation fallback (empty = $MMMELD_REVIEW_MODEL or default)
+
+	ValidationModel string `json:"validation_model"` // Gemini model that validates generated images (empty = gemini-2.5-flash)
+
+	GeminiRetries    int  `json:"gemini_retries"`     // Gemini quota-error retries before falling back to the reviewer
+	NoOpenAIFallback bool `json:"no_openai_fallback"` // Fail instead of falling back to the reviewer on Gemini quota errors
+
+	BriefTemperature  float64 `json:"brief_temperature"`  // Gemini temperature for the audio brief pass (0-2)
+	PromptTemperature float64 `json:"prompt_temperature"` // Gemini temperature for the image prompt pass (0-2)
+
+	AdaptiveRetry bool `json:"adaptive_retry"` // Feed validation failures into the next attempt's prompt
+	Debug         bool `json:"debug"`          // Log extra diagnostics such as adapted retry prompts
+
+	ImageHeaders http.Header `json:"-"` // Extra headers for http(s) image downloads; may hold credentials
+
+	YtDlpCookies            string   `json:"ytdlp_cookies"`              // Netscape cookies file for yt-dlp downloads
+	YtDlpCookiesFromBrowser string   `json:"ytdlp_cookies_from_browser"` // Browser yt-dlp reads login cookies from, e.g. chrome
+	YtDlpArgs               []string `json:"-"`                          // Extra yt-dlp arguments; may hold credentials
+	YtDlpPath               string   `json:"ytdlp_path"`                 // yt-dlp binary (empty = $MMMELD_YTDLP, else yt-dlp or youtube-dl on PATH)
+
+	MaxDownloadMB int `json:"max_download_mb"` // Size cap for each HTTP image or audio download
+
+	NoDownloadCache      bool `json:"no_download_cache"`       // Always run yt-dlp instead of reusing ~/.cache/mmmeld/yt
+	DownloadCacheTTLDays int  `json:"download_cache_ttl_days"` // Cached downloads unused this many days are pruned (0 = kept)
+
+	Proxy  string `json:"-"`       // Proxy URL for every outbound request and yt-dlp; may hold credentials
+	CACert string `json:"ca_cert"` // PEM bundle trusted on top of the system roots
+
+	PlaylistMode  string `json:"playlist_mode"`  // What a playlist --audio URL does: error or concat
+	PlaylistItems string `json:"playlist_items"` // yt-dlp --playlist-items selection, e.g. 1-5,8
+
+	ReferenceImage string `json:"reference_image"` // Local image whose visual language generated images follow
+	ImageWeight    int    `json:"image_weight"`    // Ideogram remix weight of the reference image (1-100)
 }
 
 func New() *Config {
 	return &Config{
-		VoiceID:       ElevenLabsVoiceID,
-		TTSProvider:   ProviderElevenLabs,
-		ImageProvider: ImageProviderIdeogram, // Default to Ideogram
-		BGMusicVolume: DefaultBGMusicVolume,
-		AudioMargins:  AudioMargins{Start: 0.5, End: 2.0},
-		Cleanup:       true,
-		AspectRatio:   AspectRatio16x9, // Default to YouTube landscape
+		VoiceID:         ElevenLabsVoiceID,
+		TTSProvider:     ProviderElevenLabs,
+		TTSChunkSize:    DefaultTTSChunkSize,
+		TTSMaxAttempts:  DefaultTTSMaxAttempts,
+		ElevenLabsModel: ElevenLabsModelID,
+		Stability:       DefaultElevenLabsStability,
+		Similarity:      DefaultElevenLabsSimilarity,
+		ImageProvider:   ImageProviderIdeogram, // Default to Ideogram
+		BGMusicVolume:   DefaultBGMusicVolume,
+		AudioMargins:    AudioMargins{Start: 0.5, End: 2.0},
+		Cleanup:         true,
+		AspectRatio:     AspectRatio16x9, // Default to YouTube landscape
+		SDEndpoint:      DefaultSDEndpoint,
+		SDSteps:         DefaultSDSteps,
+		SDTimeout:       DefaultSDTimeout,
+		ImageWeight:     DefaultImageWeight,
+		Focus:           Focus{X: 0.5, Y: 0.5},
+		CaptionColor:    DefaultCaptionColor,
+		CasingPolicy:    "strict",
+		Reviewer:        "openai",
+		GeminiRetries:   3,
+		AdaptiveRetry:   true,
+		Parallel:        1,
+		PlaylistMode:    PlaylistModeError,
+		MaxDownloadMB:   DefaultMaxDownloadMB,
+
+		DownloadCacheTTLDays: DefaultDownloadCacheTTLDays,
+
+		BriefTemperature:  0.7,
+		PromptTemperature: 0.8,
 	}
 }
 
@@ -114,13 +388,15 @@ func (c *Config) LoadFromFlags() error {
 	fs := flag.NewFlagSet("mmmeld", flag.ContinueOnError)
 
 	var (
-		ttsProvider = fs.String("tts-provider", string(ProviderElevenLabs), "Text-to-speech provider (elevenlabs, openai, deepgram)")
+		ttsProvider = fs.String("tts-provider", string(ProviderElevenLabs), "Text-to-speech provider (elevenlabs, openai, deepgram, azure, local)")
 		audioMargin = fs.String("audiomargin", "0.5,2.0", "Start and end audio margins in seconds, comma-separated")
 		noCleanup   = fs.Bool("nocleanup", false, "Do not clean up temporary files")
 	)
 
-	fs.StringVar(&c.Audio, "audio", "", "Path to audio file, YouTube URL, or 'generate' for text-to-speech")
-	fs.StringVar(&c.Audio, "a", "", "Path to audio file, YouTube URL, or 'generate' for text-to-speech")
+	fs.StringVar(&c.Audio, "audio", "", "Path to audio file, YouTube or audio URL, or 'generate' for text-to-speech")
+	fs.StringVar(&c.Audio, "a", "", "Path to audio file, YouTube or audio URL, or 'generate' for text-to-speech")
+
+	fs.StringVar(&c.Title, "title", "", "Title for the audio (overrides metadata tags and filename)")
 
 	fs.StringVar(&c.Text, "text", "", "Text for speech generation")
 	fs.StringVar(&c.Text, "t", "", "Text for speech generation")
@@ -128,41 +404,91 @@ func (c *Config) LoadFromFlags() error {
 	fs.StringVar(&c.VoiceID, "voice-id", ElevenLabsVoiceID, "Voice ID for TTS")
 	fs.StringVar(&c.VoiceID, "vid", ElevenLabsVoiceID, "Voice ID for TTS")
 
+	fs.StringVar(&c.TTSCommand, "tts-command", "", "Command template for the local TTS provider, e.g. 'piper --model en_US-amy --output_file {out}' (text is sent on stdin)")
+	fs.IntVar(&c.TTSChunkSize, "tts-chunk-size", DefaultTTSChunkSize, "Max characters per TTS request (0 disables chunking for the local provider)")
+
+	fs.StringVar(&c.TTSModel, "tts-model", "", "TTS model (OpenAI: tts-1, tts-1-hd, gpt-4o-mini-tts; default: tts-1)")
+	fs.Float64Var(&c.TTSSpeed, "tts-speed", 0, "OpenAI TTS speaking speed (0.25 to 4.0, default: 1.0)")
+	fs.IntVar(&c.TTSMaxAttempts, "tts-max-attempts", DefaultTTSMaxAttempts, "Attempts per TTS request on 408/429/5xx or network errors")
+	fs.BoolVar(&c.NoTTSCache, "no-tts-cache", false, "Do not read or write the TTS cache (~/.cache/mmmeld/tts)")
+	fs.BoolVar(&c.NoImageCache, "no-image-cache", false, "Do not read or write the generated image cache (~/.cache/mmmeld/images)")
+	fs.BoolVar(&c.NoPromptCache, "no-prompt-cache", false, "Do not read or write the --analyze-audio prompt cache (~/.cache/mmmeld/prompts)")
+	fs.BoolVar(&c.NoDownloadCache, "no-download-cache", false, "Do not read or write the yt-dlp download cache (~/.cache/mmmeld/yt)")
+	fs.IntVar(&c.DownloadCacheTTLDays, "download-cache-ttl", DefaultDownloadCacheTTLDays, "Prune cached yt-dlp downloads unused for this many days (0 = keep them)")
+	fs.StringVar(&c.Voices, "voices", "", "Speaker voices for dialogue scripts, e.g. 'ALICE=voice1,BOB=voice2' (lines start with 'ALICE:')")
+	fs.Float64Var(&c.DialoguePause, "dialogue-pause", 0, "Seconds of silence between dialogue turns")
+	fs.StringVar(&c.Lexicon, "lexicon", "", "JSON file mapping words to replacement spellings, or \"ipa:...\" phonemes (Azure)")
+	fs.StringVar(&c.ElevenLabsModel, "elevenlabs-model", ElevenLabsModelID, "ElevenLabs model ID (e.g. eleven_v3, eleven_multilingual_v2, eleven_turbo_v2_5)")
+	fs.Float64Var(&c.Stability, "stability", DefaultElevenLabsStability, "ElevenLabs voice stability (0.0 to 1.0)")
+	fs.Float64Var(&c.Similarity, "similarity", DefaultElevenLabsSimilarity, "ElevenLabs similarity boost (0.0 to 1.0)")
+	fs.Float64Var(&c.StyleExaggeration, "style-exaggeration", DefaultElevenLabsStyle, "ElevenLabs style exaggeration (0.0 to 1.0)")
+	fs.BoolVar(&c.NoSpeakerBoost, "no-speaker-boost", false, "Disable ElevenLabs speaker boost")
+	fs.Float64Var(&c.SpeechRate, "speech-rate", 0, "Speaking rate for any TTS provider, e.g. 1.15 (0.25 to 4.0, default: 1.0)")
+
 	fs.StringVar(&c.Image, "image", "", "Path to image/video file(s), URL(s), or 'generate'")
 	fs.StringVar(&c.Image, "i", "", "Path to image/video file(s), URL(s), or 'generate'")
 
 	fs.StringVar(&c.ImageDescription, "image-description", "", "Description for image generation")
 	fs.StringVar(&c.ImageDescription, "img-desc", "", "Description for image generation")
 
-	fs.StringVar(&c.BGMusic, "bg-music", "", "Path to background music file or YouTube URL")
-	fs.StringVar(&c.BGMusic, "bm", "", "Path to background music file or YouTube URL")
+	fs.StringVar(&c.BGMusic, "bg-music", "", "Path to background music file, YouTube URL, or audio URL")
+	fs.StringVar(&c.BGMusic, "bm", "", "Path to background music file, YouTube URL, or audio URL")
 
 	fs.Float64Var(&c.BGMusicVolume, "bg-music-volume", DefaultBGMusicVolume, "Volume of background music (0.0 to 1.0)")
 	fs.Float64Var(&c.BGMusicVolume, "bmv", DefaultBGMusicVolume, "Volume of background music (0.0 to 1.0)")
 
+	fs.StringVar(&c.Background, "background", "black", "Fill around inputs that do not match the canvas: black, blur, auto (edge color of each still), or color:#RRGGBB")
+	fs.IntVar(&c.MaxSourceDimension, "max-source-dimension", 0, "Downscale stills whose long edge exceeds this many pixels before encoding (default: 2x the output canvas)")
+
 	fs.BoolVar(&c.AutoFill, "autofill", false, "Use defaults for all unspecified options")
 	fs.BoolVar(&c.AutoFill, "af", false, "Use defaults for all unspecified options")
+	fs.BoolVar(&c.SkipPreflight, "skip-preflight", false, "Start without first checking that the needed API keys, ffmpeg, ffprobe, and yt-dlp are available")
+	fs.BoolVar(&c.NonInteractive, "non-interactive", false, "Fail with the missing flags instead of prompting on stdin (default when stdin is not a terminal; --non-interactive=false forces prompts)")
 
 	fs.BoolVar(&c.ShowPrompts, "showprompts", false, "Show all prompts")
 	fs.BoolVar(&c.ShowPrompts, "sp", false, "Show all prompts")
 
+	fs.BoolVar(&c.Estimate, "estimate", false, "With --audio generate, print TTS chunks, characters, and approximate cost, then exit without calling any API")
+	fs.Float64Var(&c.TTSPrice, "tts-price", 0, "USD per 1,000 characters used by --estimate (default: built-in price for the provider)")
+	fs.BoolVar(&c.NoLLMTitle, "no-llm-title", false, "Title generated speech from its first sentence instead of asking Gemini/OpenAI")
+
+	fs.BoolVar(&c.ForceYtDlp, "force-ytdlp", false, "Download any http(s) URL with yt-dlp, not just known sites (--image URLs of image files excepted)")
+	fs.StringVar(&c.LogLevel, "log-level", "", "Log detail on stderr: error, warn, info (stage progress and warnings), or debug (commands and raw ffmpeg output)")
+	fs.BoolVar(&c.Quiet, "quiet", false, "Log only warnings and errors (--log-level warn)")
+	fs.BoolVar(&c.Verbose, "verbose", false, "Log commands, raw ffmpeg output, and other diagnostics (--log-level debug)")
+	fs.BoolVar(&c.JSON, "json", false, "Write progress, results, and errors to stdout as newline-delimited JSON events; logs stay on stderr")
+	fs.StringVar(&c.Manifest, "manifest", "", "YAML or JSON file of jobs to render, each a set of flags over the manifest's defaults; see Batch Runs")
+	fs.IntVar(&c.Parallel, "parallel", 1, "With --manifest, render this many jobs at once")
+	fs.StringVar(&c.TempDir, "temp-dir", "", "Folder for intermediate files (default: temp_assets)")
+	fs.BoolVar(&c.Resume, "resume", false, "Reuse audio, images, and background music a failed run already produced, redoing only stages whose settings or files changed")
+
 	fs.StringVar(&c.Output, "output", "", "Path for the output video file")
 	fs.StringVar(&c.Output, "o", "", "Path for the output video file")
+	fs.StringVar(&c.OutputDir, "output-dir", "", "Folder for the output video, keeping the default name (default: next to a local audio file, else the current folder); a relative --output is placed in it")
+	fs.BoolVar(&c.Overwrite, "overwrite", false, "Replace the output file if it already exists")
+	fs.BoolVar(&c.Overwrite, "y", false, "Replace the output file if it already exists")
+	fs.BoolVar(&c.AutoNumber, "auto-number", false, "If the output file exists, write name_2.mp4, name_3.mp4, ... next to it instead")
+	fs.StringVar(&c.Summary, "summary", "", "Path for the JSON run summary of inputs, prompts, scores, encode settings, and stage times (default: <output>.mmmeld.json)")
 
 	fs.StringVar(&c.OpenAIKey, "openai-key", "", "OpenAI API key")
 	fs.StringVar(&c.ElevenLabsKey, "elevenlabs-key", "", "ElevenLabs API key")
 	fs.StringVar(&c.DeepgramKey, "deepgram-key", "", "DeepGram API key")
 	fs.StringVar(&c.GeminiKey, "gemini-key", "", "Google Gemini API key")
 	fs.StringVar(&c.IdeogramKey, "ideogram-key", "", "Ideogram API key")
+	fs.StringVar(&c.AzureKey, "azure-speech-key", "", "Azure Speech subscription key")
+	fs.StringVar(&c.AzureRegion, "azure-speech-region", "", "Azure Speech region (e.g., eastus)")
 
-	var imageProvider = fs.String("image-provider", "ideogram", "Image generation provider (ideogram, dalle)")
+	var imageProvider = fs.String("image-provider", "ideogram", "Image generation provider (ideogram, dalle, gpt-image, imagen, stability, replicate, local-sd)")
 	fs.String("ip", "ideogram", "Image generation provider (shorthand)")
 
 	fs.BoolVar(&c.AnalyzeAudio, "analyze-audio", false, "Use Gemini to analyze audio and generate image prompt")
 	fs.BoolVar(&c.AnalyzeAudio, "aa", false, "Use Gemini to analyze audio and generate image prompt")
+	fs.StringVar(&c.AnalyzeWindow, "analyze-window", "", "With --analyze-audio, analyze only this part of the audio, e.g. 0:60-4:00 (default: whole file; long or large files are compressed before upload)")
 
 	fs.StringVar(&c.AudioNotes, "audio-image-notes", "", "Notes for audio-to-image generation (style, mood, exclusions)")
 	fs.StringVar(&c.AudioNotes, "ain", "", "Notes for audio-to-image generation (style, mood, exclusions)")
+	fs.StringVar(&c.Language, "language", "", "Language of the caption/subcaption text, e.g. pt-BR; prompts stay English and validation accepts accents")
+	fs.StringVar(&c.ImageAvoid, "image-avoid", "", "Comma-separated imagery to avoid, added to the audio brief's avoid list and the negative prompt where supported")
 
 	fs.StringVar(&c.ImageCaption, "image-caption", "", "Caption/title text to render on the generated image")
 	fs.StringVar(&c.ImageCaption, "ic", "", "Caption/title text to render on the generated image")
@@ -179,6 +505,60 @@ func (c *Config) LoadFromFlags() error {
 	fs.StringVar(&c.StylePreset, "style-preset", "", "Ideogram style preset (e.g., CINEMATIC, OIL_PAINTING, DRAMATIC_CINEMA, WATERCOLOR, etc.)")
 	fs.StringVar(&c.StylePreset, "spr", "", "Ideogram style preset (shorthand)")
 
+	fs.StringVar(&c.NegativePrompt, "negative-prompt", "", "What generated images should not contain (stability, local-sd)")
+	fs.Int64Var(&c.Seed, "seed", 0, "Seed for reproducible generated images (stability, replicate, local-sd; 0 = random)")
+	fs.StringVar(&c.ImageQuality, "image-quality", "", "gpt-image-1 quality: low, medium, high (default: auto)")
+	fs.StringVar(&c.ReplicateModel, "replicate-model", DefaultReplicateModel, "Replicate model for --image-provider replicate (owner/name or owner/name:version)")
+	fs.StringVar(&c.SDEndpoint, "sd-endpoint", DefaultSDEndpoint, "Local Stable Diffusion (A1111 API) server for --image-provider local-sd")
+	fs.IntVar(&c.SDSteps, "sd-steps", DefaultSDSteps, "Sampling steps for local-sd")
+	fs.StringVar(&c.SDSampler, "sd-sampler", "", "Sampler name for local-sd (e.g. \"DPM++ 2M\"; default: server default)")
+	fs.IntVar(&c.SDTimeout, "sd-timeout", DefaultSDTimeout, "Seconds to wait for a local-sd image")
+	fs.StringVar(&c.ReferenceImage, "reference-image", "", "Local image whose visual language generated images should follow")
+	fs.IntVar(&c.ImageWeight, "image-weight", DefaultImageWeight, "Ideogram remix weight of --reference-image (1-100)")
+	fs.BoolVar(&c.Upscale, "upscale", false, "Upscale the accepted generated image with Ideogram (requires IDEOGRAM_API_KEY)")
+
+	fit := fs.String("fit", "", "Fit local images to --aspect-ratio: crop, pad, or stretch (default: leave as is)")
+	fs.StringVar(&c.CaptionFallback, "caption-fallback", "", "When caption text validation fails on every attempt: drawtext overlays it with ffmpeg on the best image")
+	fs.StringVar(&c.CaptionFont, "caption-font", "", "Font file for --caption-fallback drawtext (default: ffmpeg's default font)")
+	fs.IntVar(&c.CaptionFontSize, "caption-font-size", 0, "Caption font size in pixels for --caption-fallback drawtext (default: 1/12 of image height)")
+	fs.StringVar(&c.CaptionColor, "caption-color", DefaultCaptionColor, "Caption text color for --caption-fallback drawtext (ffmpeg color, e.g. white, #FFD700)")
+	fs.StringVar(&c.CasingPolicy, "casing-policy", "strict", "Caption casing validation: strict (exact, ALL CAPS, or lowercase), any (ignore casing), or exact")
+	fs.StringVar(&c.Reviewer, "reviewer", "openai", "Second-opinion reviewer and Gemini fallback: openai, anthropic, ollama, or none")
+	fs.StringVar(&c.ReviewModel, "review-model", "", "Reviewer model (default: $MMMELD_REVIEW_MODEL, else gpt-5.2-pro for openai or claude-sonnet-4-5 for anthropic)")
+	fs.StringVar(&c.ValidationModel, "validation-model", "", "Gemini model that validates generated images (default: gemini-2.5-flash, retried with gemini-3-pro-preview on error)")
+	fs.Float64Var(&c.BriefTemperature, "brief-temp", 0.7, "Gemini temperature (0-2) for the --analyze-audio brief pass; lower is more consistent")
+	fs.Float64Var(&c.PromptTemperature, "prompt-temp", 0.8, "Gemini temperature (0-2) for the --analyze-audio prompt pass; higher explores more")
+	fs.IntVar(&c.GeminiRetries, "gemini-retries", 3, "Retries with backoff when Gemini reports a quota error, before falling back to the reviewer")
+	fs.BoolVar(&c.NoOpenAIFallback, "no-openai-fallback", false, "Fail instead of falling back to the --reviewer model when Gemini stays over quota")
+	fs.Func("image-header", "Header for http(s) image downloads as 'Name: Value' (repeatable)", func(v string) error {
+		name, value, err := parseHeader(v)
+		if err != nil {
+			return err
+		}
+		if c.ImageHeaders == nil {
+			c.ImageHeaders = http.Header{}
+		}
+		c.ImageHeaders.Add(name, value)
+		return nil
+	})
+	fs.IntVar(&c.MaxDownloadMB, "max-download-size", DefaultMaxDownloadMB, "Largest HTTP image or audio download, in MB")
+	fs.StringVar(&c.Proxy, "proxy", "", "Proxy URL for API requests, downloads, and yt-dlp, e.g. http://proxy:3128 (default: HTTPS_PROXY, HTTP_PROXY, and NO_PROXY)")
+	fs.StringVar(&c.CACert, "ca-cert", "", "PEM CA bundle to trust on top of the system roots, e.g. for a TLS-inspecting proxy")
+	fs.StringVar(&c.YtDlpPath, "ytdlp-path", "", "yt-dlp binary to run (default: $MMMELD_YTDLP, else yt-dlp, or youtube-dl, on PATH)")
+	fs.StringVar(&c.YtDlpCookies, "ytdlp-cookies", "", "Netscape-format cookies file passed to yt-dlp, for age-restricted or members-only videos")
+	fs.StringVar(&c.YtDlpCookiesFromBrowser, "ytdlp-cookies-from-browser", "", "Browser yt-dlp loads cookies from, e.g. chrome or firefox:profile")
+	fs.Func("ytdlp-arg", "Extra argument passed to yt-dlp as is (repeatable), e.g. --ytdlp-arg=--extractor-args=youtube:player_client=web", func(v string) error {
+		c.YtDlpArgs = append(c.YtDlpArgs, v)
+		return nil
+	})
+	fs.StringVar(&c.PlaylistMode, "playlist-mode", PlaylistModeError, "What a playlist --audio URL does: error (refuse it) or concat (join its entries into one track)")
+	fs.StringVar(&c.PlaylistItems, "playlist-items", "", "Playlist entries to download, in yt-dlp's --playlist-items syntax, e.g. 1-5,8")
+	fs.BoolVar(&c.AdaptiveRetry, "adaptive-retry", true, "Add corrective instructions from a failed image validation to the next attempt's prompt (--adaptive-retry=false resends the same prompt)")
+	fs.BoolVar(&c.Debug, "debug", false, "Log extra diagnostics, such as adapted image retry prompts")
+	fs.BoolVar(&c.StrictAspect, "strict-aspect", false, "Fail generation attempts whose image does not match --aspect-ratio instead of cropping")
+	fs.BoolVar(&c.Lenient, "lenient", false, "Warn and use 16:9 for an unrecognized --aspect-ratio instead of failing")
+	focus := fs.String("focus", "0.5,0.5", "Crop focus point for --fit crop as x,y fractions (0,0 = top left)")
+
 	var aspectRatioStr string
 	fs.StringVar(&aspectRatioStr, "aspect-ratio", "16:9", "Aspect ratio for generated images (16:9, 9:16, 1:1, 4:3, 3:4, 3:2, 2:3)")
 	fs.StringVar(&aspectRatioStr, "ar", "16:9", "Aspect ratio for generated images (shorthand)")
@@ -187,21 +567,100 @@ func (c *Config) LoadFromFlags() error {
 		return fmt.Errorf("failed to parse flags: %w", err)
 	}
 
+	// Set the log level first so the rest of loading logs at it
+	level, err := c.ResolveLogLevel()
+	if err != nil {
+		return err
+	}
+	logx.SetLevel(level)
+
+	if c.TempDir != "" {
+		TempAssetsFolder = c.TempDir
+	}
+	MaxDownloadSize = int64(c.MaxDownloadMB) << 20
+	if err := httpx.Configure(c.Proxy, c.CACert); err != nil {
+		return err
+	}
+
 	// Post-process values
 	c.TTSProvider = TTSProvider(*ttsProvider)
+	if !flagWasSet(fs, "voice-id", "vid") {
+		c.VoiceID = DefaultVoiceID(c.TTSProvider)
+	}
 	c.ImageProvider = ImageProvider(*imageProvider)
 	c.Cleanup = !*noCleanup
-	c.AspectRatio = parseAspectRatio(aspectRatioStr)
+	ar, err := ParseAspectRatio(aspectRatioStr)
+	if err != nil {
+		if !c.Lenient {
+			return err
+		}
+		logx.Warnf("%v; using %s (--lenient)", err, AspectRatio16x9)
+		ar = AspectRatio16x9
+	}
+	c.AspectRatio = ar
+	c.Fit = FitMode(strings.ToLower(strings.TrimSpace(*fit)))
+
+	parsedFocus, err := parseFocus(*focus)
+	if err != nil {
+		return err
+	}
+	c.Focus = parsedFocus
 
 	if err := c.parseAudioMargin(*audioMargin); err != nil {
 		return err
 	}
 
+	if c.OutputDir != "" && c.Output != "" && !filepath.IsAbs(c.Output) {
+		c.Output = filepath.Join(c.OutputDir, c.Output)
+	}
+
 	c.loadAPIKeysFromEnv()
 
+	// Prompting on a pipe or /dev/null would block or read garbage, e.g. in CI
+	if !flagWasSet(fs, "non-interactive") && !stdinIsTerminal() {
+		c.NonInteractive = true
+	}
+
 	return c.validate()
 }
 
+// stdinIsTerminal reports whether stdin is a terminal someone can answer
+// prompts on. /dev/null is a character device too, so the mode bits are not
+// enough; cron, systemd, and docker run without -i all give it as stdin.
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// MissingInputs lists the flags whose absence would make mmmeld prompt for
+// input, with what each accepts. It is empty with --autofill, and with
+// --manifest, whose jobs supply their own inputs.
+func (c *Config) MissingInputs() []string {
+	if c.AutoFill || c.Manifest != "" {
+		return nil
+	}
+	var missing []string
+	if c.Audio == "" {
+		missing = append(missing, "--audio (a file, URL, or 'generate' with --text)")
+	}
+	if c.Image == "" {
+		missing = append(missing, "--image (files, URLs, or 'generate', comma-separated)")
+	}
+	return missing
+}
+
+// flagWasSet reports whether any of the named flags was given on the command line
+func flagWasSet(fs *flag.FlagSet, names ...string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		for _, name := range names {
+			if f.Name == name {
+				set = true
+			}
+		}
+	})
+	return set
+}
+
 func (c *Config) parseAudioMargin(margin string) error {
 	parts := strings.Split(margin, ",")
 	if len(parts) != 2 {
@@ -222,25 +681,98 @@ func (c *Config) parseAudioMargin(margin string) error {
 	return nil
 }
 
-func parseAspectRatio(s string) AspectRatio {
-	switch strings.ToLower(strings.TrimSpace(s)) {
-	case "16:9", "16x9":
-		return AspectRatio16x9
-	case "9:16", "9x16":
-		return AspectRatio9x16
-	case "1:1", "1x1", "square":
-		return AspectRatio1x1
-	case "4:3", "4x3":
-		return AspectRatio4x3
-	case "3:4", "3x4":
-		return AspectRatio3x4
-	case "3:2", "3x2":
-		return AspectRatio3x2
-	case "2:3", "2x3":
-		return AspectRatio2x3
-	default:
-		return AspectRatio16x9 // Default to YouTube landscape
+// validBackground reports whether s is a --background value: empty, black,
+// blur, auto, or color: followed by a six-digit hex color
+func validBackground(s string) bool {
+	switch s {
+	case "", "black", "blur", "auto":
+		return true
+	}
+	hex, ok := strings.CutPrefix(s, "color:")
+	hex = strings.TrimPrefix(hex, "#")
+	if !ok || len(hex) != 6 {
+		return false
+	}
+	_, err := strconv.ParseUint(hex, 16, 32)
+	return err == nil
+}
+
+// validPlaylistItems reports whether s looks like a yt-dlp --playlist-items
+// selection: comma-separated indexes, START-STOP ranges, or START:STOP:STEP
+// slices, where negative indexes count from the end
+func validPlaylistItems(s string) bool {
+	for _, part := range strings.Split(s, ",") {
+		if strings.Trim(part, "-:") == "" || strings.Trim(part, "0123456789-:") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseHeader parses a "Name: Value" header. The value is left out of errors
+// because it is usually a credential.
+func parseHeader(s string) (string, string, error) {
+	name, value, ok := strings.Cut(s, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", errors.New("header must be in format 'Name: Value'")
+	}
+	return name, strings.TrimSpace(value), nil
+}
+
+// parseFocus parses an "x,y" focus point with both values between 0 and 1
+func parseFocus(s string) (Focus, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return Focus{}, errors.New("focus must be in format 'x,y'")
 	}
+
+	x, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return Focus{}, fmt.Errorf("invalid focus x: %w", err)
+	}
+	y, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return Focus{}, fmt.Errorf("invalid focus y: %w", err)
+	}
+	if x < 0 || x > 1 || y < 0 || y > 1 {
+		return Focus{}, errors.New("focus values must be between 0.0 and 1.0")
+	}
+	return Focus{X: x, Y: y}, nil
+}
+
+// Ratio returns the aspect ratio's width and height terms (16:9 -> 16, 9)
+func (ar AspectRatio) Ratio() (int, int) {
+	w, h, ok := strings.Cut(string(ar), ":")
+	if ok {
+		wi, errW := strconv.Atoi(w)
+		hi, errH := strconv.Atoi(h)
+		if errW == nil && errH == nil && wi > 0 && hi > 0 {
+			return wi, hi
+		}
+	}
+	return 16, 9
+}
+
+// AspectRatios lists the supported aspect ratios, default first
+var AspectRatios = []AspectRatio{AspectRatio16x9, AspectRatio9x16, AspectRatio1x1, AspectRatio4x3, AspectRatio3x4, AspectRatio3x2, AspectRatio2x3}
+
+// ParseAspectRatio parses a supported ratio written W:H or WxH, or "square".
+// Unknown ratios are an error listing the supported ones.
+func ParseAspectRatio(s string) (AspectRatio, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	if v == "square" {
+		return AspectRatio1x1, nil
+	}
+	v = strings.Replace(v, "x", ":", 1)
+	names := make([]string, len(AspectRatios))
+	for i, ar := range AspectRatios {
+		if v == string(ar) {
+			return ar, nil
+		}
+		names[i] = string(ar)
+	}
+	return "", fmt.Errorf("invalid aspect ratio %q (must be one of %s)", s, strings.Join(names, ", "))
 }
 
 // IdeogramAspectRatio converts AspectRatio to Ideogram API format
@@ -265,6 +797,69 @@ func (ar AspectRatio) IdeogramAspectRatio() string {
 	}
 }
 
+// OpenAIImageSize maps AspectRatio to the closest gpt-image-1 size
+func (ar AspectRatio) OpenAIImageSize() string {
+	switch ar {
+	case AspectRatio1x1:
+		return "1024x1024"
+	case AspectRatio9x16, AspectRatio3x4, AspectRatio2x3:
+		return "1024x1536"
+	default:
+		return "1536x1024"
+	}
+}
+
+// ImagenAspectRatio converts AspectRatio to one Imagen supports (1:1, 3:4,
+// 4:3, 9:16, 16:9); 3:2 and 2:3 map to 4:3 and 3:4
+func (ar AspectRatio) ImagenAspectRatio() string {
+	switch ar {
+	case AspectRatio16x9, AspectRatio9x16, AspectRatio1x1, AspectRatio4x3, AspectRatio3x4:
+		return string(ar)
+	case AspectRatio3x2:
+		return "4:3"
+	case AspectRatio2x3:
+		return "3:4"
+	default:
+		return "16:9"
+	}
+}
+
+// SDDimensions returns the width and height for local Stable Diffusion,
+// using SDXL's native resolution buckets (all multiples of 64)
+func (ar AspectRatio) SDDimensions() (int, int) {
+	switch ar {
+	case AspectRatio9x16:
+		return 768, 1344
+	case AspectRatio1x1:
+		return 1024, 1024
+	case AspectRatio4x3:
+		return 1152, 896
+	case AspectRatio3x4:
+		return 896, 1152
+	case AspectRatio3x2:
+		return 1216, 832
+	case AspectRatio2x3:
+		return 832, 1216
+	default:
+		return 1344, 768
+	}
+}
+
+// StabilityAspectRatio converts AspectRatio to the Stability AI format. Ratios
+// Stability lacks map to the nearest one it offers (4:3 -> 5:4, 3:4 -> 4:5).
+func (ar AspectRatio) StabilityAspectRatio() string {
+	switch ar {
+	case AspectRatio16x9, AspectRatio9x16, AspectRatio1x1, AspectRatio3x2, AspectRatio2x3:
+		return string(ar)
+	case AspectRatio4x3:
+		return "5:4"
+	case AspectRatio3x4:
+		return "4:5"
+	default:
+		return "16:9"
+	}
+}
+
 func (c *Config) loadAPIKeysFromEnv() {
 	if c.OpenAIKey == "" {
 		c.OpenAIKey = os.Getenv("OPENAI_API_KEY")
@@ -281,23 +876,229 @@ func (c *Config) loadAPIKeysFromEnv() {
 	if c.IdeogramKey == "" {
 		c.IdeogramKey = os.Getenv("IDEOGRAM_API_KEY")
 	}
+	if c.StabilityKey == "" {
+		c.StabilityKey = os.Getenv("STABILITY_API_KEY")
+	}
+	if c.ReplicateKey == "" {
+		c.ReplicateKey = os.Getenv("REPLICATE_API_TOKEN")
+	}
+	if c.AzureKey == "" {
+		c.AzureKey = os.Getenv("AZURE_SPEECH_KEY")
+	}
+	if c.AzureRegion == "" {
+		c.AzureRegion = os.Getenv("AZURE_SPEECH_REGION")
+	}
+	if c.TTSCommand == "" {
+		c.TTSCommand = os.Getenv("MMMELD_TTS_COMMAND")
+	}
+	if c.YtDlpPath == "" {
+		c.YtDlpPath = os.Getenv("MMMELD_YTDLP")
+	}
 }
 
 func (c *Config) validate() error {
 	// Validate TTS provider
 	switch c.TTSProvider {
-	case ProviderElevenLabs, ProviderOpenAI, ProviderDeepgram:
+	case ProviderElevenLabs, ProviderOpenAI, ProviderDeepgram, ProviderAzure, ProviderLocal:
 		// Valid
 	default:
 		return fmt.Errorf("invalid TTS provider: %s", c.TTSProvider)
 	}
 
+	if c.TTSChunkSize < 0 {
+		return errors.New("tts-chunk-size must be zero or positive")
+	}
+
+	if err := ValidateTTSSpeed(c.TTSSpeed); err != nil {
+		return err
+	}
+
+	if err := ValidateSpeechRate(c.SpeechRate); err != nil {
+		return err
+	}
+
+	if err := ValidateSpeedAndRate(c.TTSSpeed, c.SpeechRate); err != nil {
+		return err
+	}
+
+	if c.TTSPrice < 0 {
+		return errors.New("tts-price must be zero or positive")
+	}
+
+	if c.Estimate && c.Audio != "generate" {
+		return errors.New("--estimate requires --audio generate")
+	}
+
+	if c.Resume && len(c.MissingInputs()) > 0 {
+		return errors.New("--resume requires --audio and --image (or --autofill); interactive answers are not recorded")
+	}
+
+	if c.JSON && !c.Estimate && len(c.MissingInputs()) > 0 {
+		return errors.New("--json requires --audio and --image (or --autofill); interactive prompts would mix with the event stream")
+	}
+
+	if c.Parallel < 1 {
+		return errors.New("parallel must be at least 1")
+	}
+
+	if c.Manifest == "" && c.Parallel > 1 {
+		return errors.New("--parallel requires --manifest")
+	}
+
+	if c.Manifest != "" && c.JSON {
+		return errors.New("--manifest cannot be combined with --json")
+	}
+
+	if c.Overwrite && c.AutoNumber {
+		return errors.New("--overwrite and --auto-number cannot be combined")
+	}
+
+	// Fail before any API calls rather than after a render's worth of work
+	if c.Output != "" && !c.Overwrite && !c.AutoNumber && !c.Estimate {
+		if info, err := os.Stat(c.Output); err == nil && !info.IsDir() {
+			return fmt.Errorf("output %s already exists; use --overwrite (-y) to replace it or --auto-number to keep it", c.Output)
+		}
+	}
+
+	if c.Manifest != "" && c.Output != "" {
+		return errors.New("--manifest cannot be combined with --output; set output per job")
+	}
+
+	if c.TTSMaxAttempts < 1 {
+		return errors.New("tts-max-attempts must be at least 1")
+	}
+
+	if _, err := ParseVoiceMap(c.Voices); err != nil {
+		return err
+	}
+
+	if c.DialoguePause < 0 {
+		return errors.New("dialogue-pause must be zero or positive")
+	}
+
+	if _, err := LoadLexicon(c.Lexicon); err != nil {
+		return err
+	}
+
+	if err := ValidateVoiceSettings(c.Stability, c.Similarity, c.StyleExaggeration); err != nil {
+		return err
+	}
+
+	if c.ElevenLabsModel != "" && !IsKnownElevenLabsModel(c.ElevenLabsModel) {
+		logx.Warnf("Unknown ElevenLabs model %q, passing it through (known: %s)", c.ElevenLabsModel, strings.Join(KnownElevenLabsModels, ", "))
+	}
+
 	// Validate Image provider
 	switch c.ImageProvider {
-	case ImageProviderDALLE, ImageProviderIdeogram:
+	case ImageProviderDALLE, ImageProviderGPTImage, ImageProviderIdeogram, ImageProviderImagen, ImageProviderStability, ImageProviderReplicate, ImageProviderLocalSD:
+		// Valid
+	default:
+		return fmt.Errorf("invalid image provider: %s (must be 'dalle', 'gpt-image', 'ideogram', 'imagen', 'stability', 'replicate', or 'local-sd')", c.ImageProvider)
+	}
+
+	switch c.CasingPolicy {
+	case "strict", "any", "exact":
+		// Valid
+	default:
+		return fmt.Errorf("invalid casing policy: %s (must be 'strict', 'any', or 'exact')", c.CasingPolicy)
+	}
+
+	switch c.Reviewer {
+	case "openai", "anthropic", "ollama", "none":
+		// Valid
+	default:
+		return fmt.Errorf("invalid reviewer: %s (must be 'openai', 'anthropic', 'ollama', or 'none')", c.Reviewer)
+	}
+
+	if c.BriefTemperature < 0 || c.BriefTemperature > 2 {
+		return fmt.Errorf("brief temperature must be between 0 and 2: %g", c.BriefTemperature)
+	}
+	if c.PromptTemperature < 0 || c.PromptTemperature > 2 {
+		return fmt.Errorf("prompt temperature must be between 0 and 2: %g", c.PromptTemperature)
+	}
+
+	if c.GeminiRetries < 0 {
+		return fmt.Errorf("gemini retries cannot be negative: %d", c.GeminiRetries)
+	}
+
+	switch c.CaptionFallback {
+	case "", CaptionFallbackDrawtext:
+		// Valid
+	default:
+		return fmt.Errorf("invalid caption fallback: %s (must be 'drawtext')", c.CaptionFallback)
+	}
+	if c.CaptionFont != "" {
+		if _, err := os.Stat(c.CaptionFont); err != nil {
+			return fmt.Errorf("caption font not found: %s", c.CaptionFont)
+		}
+	}
+	if c.CaptionFontSize < 0 {
+		return errors.New("caption font size must not be negative")
+	}
+
+	switch c.Fit {
+	case FitNone, FitCrop, FitPad, FitStretch:
 		// Valid
 	default:
-		return fmt.Errorf("invalid image provider: %s (must be 'dalle' or 'ideogram')", c.ImageProvider)
+		return fmt.Errorf("invalid fit mode: %s (must be 'crop', 'pad', or 'stretch')", c.Fit)
+	}
+
+	if c.ReferenceImage != "" {
+		if _, err := os.Stat(c.ReferenceImage); err != nil {
+			return fmt.Errorf("reference image not found: %s", c.ReferenceImage)
+		}
+	}
+	if c.YtDlpCookies != "" {
+		if _, err := os.Stat(c.YtDlpCookies); err != nil {
+			return fmt.Errorf("yt-dlp cookies file not found: %s", c.YtDlpCookies)
+		}
+	}
+	if c.MaxDownloadMB < 1 {
+		return errors.New("max download size must be at least 1 MB")
+	}
+	if c.DownloadCacheTTLDays < 0 {
+		return errors.New("download cache TTL must not be negative")
+	}
+
+	switch c.PlaylistMode {
+	case PlaylistModeError, PlaylistModeConcat:
+		// Valid
+	default:
+		return fmt.Errorf("invalid playlist mode: %s (must be 'error' or 'concat')", c.PlaylistMode)
+	}
+	if c.PlaylistItems != "" && !validPlaylistItems(c.PlaylistItems) {
+		return fmt.Errorf("invalid playlist items: %s (use indexes and ranges such as 1-5,8)", c.PlaylistItems)
+	}
+	if c.AnalyzeWindow != "" {
+		if _, err := probe.ParseAudioWindow(c.AnalyzeWindow); err != nil {
+			return err
+		}
+	}
+	if c.ImageWeight < 1 || c.ImageWeight > 100 {
+		return errors.New("image weight must be between 1 and 100")
+	}
+
+	if c.ImageProvider == ImageProviderLocalSD {
+		if u, err := url.Parse(c.SDEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid --sd-endpoint %q (expected e.g. %s)", c.SDEndpoint, DefaultSDEndpoint)
+		}
+		if c.SDSteps <= 0 {
+			return errors.New("sd-steps must be positive")
+		}
+		if c.SDTimeout <= 0 {
+			return errors.New("sd-timeout must be positive")
+		}
+	}
+
+	switch c.ImageQuality {
+	case "", "auto", "low", "medium", "high":
+		// Valid
+	default:
+		return fmt.Errorf("invalid image quality: %s (must be 'low', 'medium', or 'high')", c.ImageQuality)
+	}
+
+	if c.Seed < 0 || c.Seed > MaxStabilitySeed {
+		return fmt.Errorf("seed must be between 0 and %d", MaxStabilitySeed)
 	}
 
 	// Validate audio margins
@@ -310,6 +1111,106 @@ func (c *Config) validate() error {
 		return errors.New("background music volume must be between 0.0 and 1.0")
 	}
 
+	if !validBackground(c.Background) {
+		return fmt.Errorf("invalid background: %s (must be 'black', 'blur', 'auto', or 'color:#RRGGBB')", c.Background)
+	}
+
+	if c.MaxSourceDimension < 0 {
+		return errors.New("max source dimension must not be negative")
+	}
+
+	return nil
+}
+
+// ValidateTTSSpeed checks a speed against OpenAI's supported range. Zero means
+// "not set" and is always accepted.
+func ValidateTTSSpeed(speed float64) error {
+	if speed != 0 && (speed < 0.25 || speed > 4.0) {
+		return fmt.Errorf("tts speed must be between 0.25 and 4.0, got %.2f", speed)
+	}
+	return nil
+}
+
+// ParseVoiceMap parses "ALICE=voice1,BOB=voice2" into a speaker -> voice ID
+// map. An empty string yields a nil map.
+func ParseVoiceMap(s string) (map[string]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	voices := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		speaker, voice, ok := strings.Cut(pair, "=")
+		speaker = strings.TrimSpace(speaker)
+		voice = strings.TrimSpace(voice)
+		if !ok || speaker == "" || voice == "" {
+			return nil, fmt.Errorf("invalid voices entry %q (expected SPEAKER=voice)", strings.TrimSpace(pair))
+		}
+		voices[speaker] = voice
+	}
+	return voices, nil
+}
+
+// LoadLexicon reads a pronunciation lexicon: a JSON object mapping source
+// strings to replacement spellings, or to "ipa:<phonemes>" hints. An empty
+// path yields a nil map.
+func LoadLexicon(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lexicon: %w", err)
+	}
+
+	var lexicon map[string]string
+	if err := json.Unmarshal(data, &lexicon); err != nil {
+		return nil, fmt.Errorf("invalid lexicon %s: %w", path, err)
+	}
+	for word, replacement := range lexicon {
+		if strings.TrimSpace(word) == "" {
+			return nil, fmt.Errorf("invalid lexicon %s: empty source string", path)
+		}
+		if strings.HasPrefix(replacement, "ipa:") && strings.TrimSpace(strings.TrimPrefix(replacement, "ipa:")) == "" {
+			return nil, fmt.Errorf("invalid lexicon %s: empty IPA for %q", path, word)
+		}
+	}
+	return lexicon, nil
+}
+
+// ValidateVoiceSettings checks that ElevenLabs voice settings are within 0.0-1.0
+func ValidateVoiceSettings(stability, similarity, style float64) error {
+	for _, setting := range []struct {
+		name  string
+		value float64
+	}{
+		{"stability", stability},
+		{"similarity", similarity},
+		{"style-exaggeration", style},
+	} {
+		if setting.value < 0 || setting.value > 1 {
+			return fmt.Errorf("%s must be between 0.0 and 1.0, got %.2f", setting.name, setting.value)
+		}
+	}
+	return nil
+}
+
+// ValidateSpeechRate checks a provider-independent speaking rate. Zero means
+// "not set" and is always accepted.
+func ValidateSpeechRate(rate float64) error {
+	if rate != 0 && (rate < 0.25 || rate > 4.0) {
+		return fmt.Errorf("speech rate must be between 0.25 and 4.0, got %.2f", rate)
+	}
+	return nil
+}
+
+// ValidateSpeedAndRate rejects setting both the OpenAI speed and the speaking
+// rate: for OpenAI they are the same request field, so one would be ignored
+func ValidateSpeedAndRate(speed, rate float64) error {
+	if speed != 0 && rate != 0 {
+		return errors.New("--tts-speed and --speech-rate both set the speaking speed; pass only one (--speech-rate works with every provider)")
+	}
 	return nil
 }
 
@@ -323,16 +1224,50 @@ func (c *Config) SetAPIKeys() {
 	if c.DeepgramKey != "" {
 		os.Setenv("DEEPGRAM_API_KEY", c.DeepgramKey)
 	}
-	if c.GeminiKey != "" {
-		os.Setenv("GEMINI_API_KEY", c.GeminiKey)
-	}
 	if c.IdeogramKey != "" {
 		os.Setenv("IDEOGRAM_API_KEY", c.IdeogramKey)
 	}
+	if c.StabilityKey != "" {
+		os.Setenv("STABILITY_API_KEY", c.StabilityKey)
+	}
+	if c.ReplicateKey != "" {
+		os.Setenv("REPLICATE_API_TOKEN", c.ReplicateKey)
+	}
+	if c.AzureKey != "" {
+		os.Setenv("AZURE_SPEECH_KEY", c.AzureKey)
+	}
+	if c.AzureRegion != "" {
+		os.Setenv("AZURE_SPEECH_REGION", c.AzureRegion)
+	}
 }
 
+// SetupLogging applies the default log level until flags choose another
 func SetupLogging() {
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	logx.SetLevel(logx.LevelInfo)
+}
+
+// ResolveLogLevel combines --log-level, --quiet, and --verbose. With none of
+// them, MMMELD_DEBUG=1 selects debug.
+func (c *Config) ResolveLogLevel() (logx.Level, error) {
+	set := 0
+	for _, given := range []bool{c.LogLevel != "", c.Quiet, c.Verbose} {
+		if given {
+			set++
+		}
+	}
+	if set > 1 {
+		return logx.LevelInfo, errors.New("use only one of --log-level, --quiet, and --verbose")
+	}
+
+	switch {
+	case c.Quiet:
+		return logx.LevelWarn, nil
+	case c.Verbose:
+		return logx.LevelDebug, nil
+	case c.LogLevel == "" && os.Getenv("MMMELD_DEBUG") == "1":
+		return logx.LevelDebug, nil
+	}
+	return logx.ParseLevel(c.LogLevel)
 }
 
 func ValidateInput(inputType, value string) bool {
@@ -341,11 +1276,12 @@ func ValidateInput(inputType, value string) bool {
 		return strings.ToLower(value) == "generate" ||
 			fileExists(value) ||
 			strings.Contains(value, "youtube.com") ||
-			strings.Contains(value, "youtu.be")
+			strings.Contains(value, "youtu.be") ||
+			httpx.IsHTTPURL(value)
 	case "image":
 		return strings.ToLower(value) == "generate" ||
 			fileExists(value) ||
-			strings.HasPrefix(value, "http")
+			httpx.IsHTTPURL(value)
 	}
 	return false
 }
@@ -2,6 +2,9 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -86,6 +89,69 @@ func TestValidate(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "azure TTS provider",
+			setup: func(c *Config) {
+				c.TTSProvider = ProviderAzure
+			},
+			expectError: false,
+		},
+		{
+			name: "TTS speed in range",
+			setup: func(c *Config) {
+				c.TTSSpeed = 1.5
+			},
+			expectError: false,
+		},
+		{
+			name: "TTS speed too slow",
+			setup: func(c *Config) {
+				c.TTSSpeed = 0.1
+			},
+			expectError: true,
+		},
+		{
+			name: "TTS speed too fast",
+			setup: func(c *Config) {
+				c.TTSSpeed = 4.5
+			},
+			expectError: true,
+		},
+		{
+			name: "speech rate out of range",
+			setup: func(c *Config) {
+				c.SpeechRate = 5
+			},
+			expectError: true,
+		},
+		{
+			name: "TTS speed with speech rate",
+			setup: func(c *Config) {
+				c.TTSSpeed, c.SpeechRate = 1.1, 1.3
+			},
+			expectError: true,
+		},
+		{
+			name: "zero TTS attempts",
+			setup: func(c *Config) {
+				c.TTSMaxAttempts = 0
+			},
+			expectError: true,
+		},
+		{
+			name: "stability out of range",
+			setup: func(c *Config) {
+				c.Stability = 1.5
+			},
+			expectError: true,
+		},
+		{
+			name: "negative style exaggeration",
+			setup: func(c *Config) {
+				c.StyleExaggeration = -0.1
+			},
+			expectError: true,
+		},
 		{
 			name: "negative start margin",
 			setup: func(c *Config) {
@@ -114,6 +180,139 @@ func TestValidate(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "invalid image weight",
+			setup: func(c *Config) {
+				c.ImageWeight = 101
+			},
+			expectError: true,
+		},
+		{
+			name: "missing reference image",
+			setup: func(c *Config) {
+				c.ReferenceImage = "does-not-exist.png"
+			},
+			expectError: true,
+		},
+		{
+			name: "any casing policy",
+			setup: func(c *Config) {
+				c.CasingPolicy = "any"
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid casing policy",
+			setup: func(c *Config) {
+				c.CasingPolicy = "title"
+			},
+			expectError: true,
+		},
+		{
+			name: "anthropic reviewer",
+			setup: func(c *Config) {
+				c.Reviewer = "anthropic"
+			},
+			expectError: false,
+		},
+		{
+			name: "ollama reviewer",
+			setup: func(c *Config) {
+				c.Reviewer = "ollama"
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid reviewer",
+			setup: func(c *Config) {
+				c.Reviewer = "claude"
+			},
+			expectError: true,
+		},
+		{
+			name: "zero brief temperature",
+			setup: func(c *Config) {
+				c.BriefTemperature = 0
+			},
+			expectError: false,
+		},
+		{
+			name: "prompt temperature above 2",
+			setup: func(c *Config) {
+				c.PromptTemperature = 2.5
+			},
+			expectError: true,
+		},
+		{
+			name: "negative gemini retries",
+			setup: func(c *Config) {
+				c.GeminiRetries = -1
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid caption fallback",
+			setup: func(c *Config) {
+				c.CaptionFallback = "burn-in"
+			},
+			expectError: true,
+		},
+		{
+			name: "missing yt-dlp cookies file",
+			setup: func(c *Config) {
+				c.YtDlpCookies = "no-such-cookies.txt"
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid playlist mode",
+			setup: func(c *Config) {
+				c.PlaylistMode = "shuffle"
+			},
+			expectError: true,
+		},
+		{
+			name: "playlist items",
+			setup: func(c *Config) {
+				c.PlaylistMode, c.PlaylistItems = PlaylistModeConcat, "1-3,7,-2::2"
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid playlist items",
+			setup: func(c *Config) {
+				c.PlaylistItems = "first,2"
+			},
+			expectError: true,
+		},
+		{
+			name: "empty playlist item",
+			setup: func(c *Config) {
+				c.PlaylistItems = "1,,3"
+			},
+			expectError: true,
+		},
+		{
+			name: "analysis window",
+			setup: func(c *Config) {
+				c.AnalyzeWindow = "0:60-4:00"
+			},
+			expectError: false,
+		},
+		{
+			name: "reversed analysis window",
+			setup: func(c *Config) {
+				c.AnalyzeWindow = "4:00-1:00"
+			},
+			expectError: true,
+		},
+		{
+			name: "malformed analysis window",
+			setup: func(c *Config) {
+				c.AnalyzeWindow = "the chorus"
+			},
+			expectError: true,
+		},
 	}
 	
 	for _, test := range tests {
@@ -132,6 +331,24 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestDefaultVoiceID(t *testing.T) {
+	tests := []struct {
+		provider TTSProvider
+		expected string
+	}{
+		{ProviderElevenLabs, ElevenLabsVoiceID},
+		{ProviderOpenAI, OpenAIVoiceID},
+		{ProviderDeepgram, DeepgramVoiceID},
+		{ProviderAzure, AzureVoiceID},
+	}
+
+	for _, test := range tests {
+		if result := DefaultVoiceID(test.provider); result != test.expected {
+			t.Errorf("DefaultVoiceID(%s) = %s, expected %s", test.provider, result, test.expected)
+		}
+	}
+}
+
 func TestLoadAPIKeysFromEnv(t *testing.T) {
 	// Save original env vars
 	originalOpenAI := os.Getenv("OPENAI_API_KEY")
@@ -173,10 +390,14 @@ func TestValidateInput(t *testing.T) {
 		{"audio", "generate", true},
 		{"audio", "https://youtube.com/watch?v=test", true},
 		{"audio", "https://youtu.be/test", true},
+		{"audio", "https://example.com/episode.mp3", true},
 		{"audio", "invalid-file.mp3", false}, // File doesn't exist
+		{"audio", "httpfile.mp3", false},     // Not a URL, and doesn't exist
 		{"image", "generate", true},
 		{"image", "http://example.com/image.jpg", true},
 		{"image", "invalid-file.jpg", false}, // File doesn't exist
+		{"image", "HTTPS://example.com/a.jpg", true},
+		{"image", "http-poster.jpg", false}, // Not a URL, and doesn't exist
 		{"unknown", "anything", false},
 	}
 	
@@ -187,4 +408,367 @@ func TestValidateInput(t *testing.T) {
 				test.inputType, test.value, result, test.expected)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestParseVoiceMap(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    map[string]string
+		expectError bool
+	}{
+		{"", nil, false},
+		{"ALICE=nova,BOB=onyx", map[string]string{"ALICE": "nova", "BOB": "onyx"}, false},
+		{" ALICE = nova , BOB=onyx ", map[string]string{"ALICE": "nova", "BOB": "onyx"}, false},
+		{"ALICE", nil, true},
+		{"ALICE=", nil, true},
+		{"=nova", nil, true},
+	}
+
+	for _, test := range tests {
+		result, err := ParseVoiceMap(test.input)
+		if test.expectError {
+			if err == nil {
+				t.Errorf("ParseVoiceMap(%q) expected error", test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseVoiceMap(%q) unexpected error: %v", test.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("ParseVoiceMap(%q) = %v, expected %v", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestIsKnownElevenLabsModel(t *testing.T) {
+	tests := []struct {
+		model    string
+		expected bool
+	}{
+		{ElevenLabsModelID, true},
+		{"eleven_multilingual_v2", true},
+		{"eleven_turbo_v2_5", true},
+		{"eleven_future_v9", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if result := IsKnownElevenLabsModel(test.model); result != test.expected {
+			t.Errorf("IsKnownElevenLabsModel(%q) = %v, expected %v", test.model, result, test.expected)
+		}
+	}
+}
+
+func TestStabilityAspectRatio(t *testing.T) {
+	tests := []struct {
+		ratio    AspectRatio
+		expected string
+	}{
+		{AspectRatio16x9, "16:9"},
+		{AspectRatio9x16, "9:16"},
+		{AspectRatio1x1, "1:1"},
+		{AspectRatio4x3, "5:4"},
+		{AspectRatio3x4, "4:5"},
+		{AspectRatio3x2, "3:2"},
+		{AspectRatio2x3, "2:3"},
+		{AspectRatio(""), "16:9"},
+	}
+
+	for _, test := range tests {
+		if got := test.ratio.StabilityAspectRatio(); got != test.expected {
+			t.Errorf("StabilityAspectRatio(%q) = %q, expected %q", test.ratio, got, test.expected)
+		}
+	}
+}
+
+func TestOpenAIImageSize(t *testing.T) {
+	tests := []struct {
+		ratio    AspectRatio
+		expected string
+	}{
+		{AspectRatio1x1, "1024x1024"},
+		{AspectRatio16x9, "1536x1024"},
+		{AspectRatio4x3, "1536x1024"},
+		{AspectRatio3x2, "1536x1024"},
+		{AspectRatio9x16, "1024x1536"},
+		{AspectRatio3x4, "1024x1536"},
+		{AspectRatio2x3, "1024x1536"},
+	}
+
+	for _, test := range tests {
+		if got := test.ratio.OpenAIImageSize(); got != test.expected {
+			t.Errorf("OpenAIImageSize(%q) = %q, expected %q", test.ratio, got, test.expected)
+		}
+	}
+}
+
+func TestImagenAspectRatio(t *testing.T) {
+	tests := []struct {
+		ratio    AspectRatio
+		expected string
+	}{
+		{AspectRatio16x9, "16:9"},
+		{AspectRatio9x16, "9:16"},
+		{AspectRatio1x1, "1:1"},
+		{AspectRatio4x3, "4:3"},
+		{AspectRatio3x4, "3:4"},
+		{AspectRatio3x2, "4:3"},
+		{AspectRatio2x3, "3:4"},
+	}
+
+	for _, test := range tests {
+		if got := test.ratio.ImagenAspectRatio(); got != test.expected {
+			t.Errorf("ImagenAspectRatio(%q) = %q, expected %q", test.ratio, got, test.expected)
+		}
+	}
+}
+
+func TestSDDimensions(t *testing.T) {
+	tests := []struct {
+		ratio  AspectRatio
+		width  int
+		height int
+	}{
+		{AspectRatio16x9, 1344, 768},
+		{AspectRatio9x16, 768, 1344},
+		{AspectRatio1x1, 1024, 1024},
+		{AspectRatio4x3, 1152, 896},
+		{AspectRatio3x4, 896, 1152},
+		{AspectRatio3x2, 1216, 832},
+		{AspectRatio2x3, 832, 1216},
+	}
+
+	for _, test := range tests {
+		w, h := test.ratio.SDDimensions()
+		if w != test.width || h != test.height {
+			t.Errorf("SDDimensions(%q) = %dx%d, expected %dx%d", test.ratio, w, h, test.width, test.height)
+		}
+		if w%64 != 0 || h%64 != 0 {
+			t.Errorf("SDDimensions(%q) = %dx%d, expected multiples of 64", test.ratio, w, h)
+		}
+	}
+}
+
+func TestParseFocus(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    Focus
+		expectError bool
+	}{
+		{"0.5,0.5", Focus{X: 0.5, Y: 0.5}, false},
+		{"0, 1", Focus{X: 0, Y: 1}, false},
+		{"0.25,0.3", Focus{X: 0.25, Y: 0.3}, false},
+		{"1.5,0.5", Focus{}, true},
+		{"0.5", Focus{}, true},
+		{"a,b", Focus{}, true},
+	}
+
+	for _, test := range tests {
+		result, err := parseFocus(test.input)
+		if test.expectError {
+			if err == nil {
+				t.Errorf("parseFocus(%q) expected error, got %v", test.input, result)
+			}
+			continue
+		}
+		if err != nil || result != test.expected {
+			t.Errorf("parseFocus(%q) = %v, %v, expected %v", test.input, result, err, test.expected)
+		}
+	}
+}
+
+func TestValidBackground(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"black", true},
+		{"blur", true},
+		{"auto", true},
+		{"", true},
+		{"color:#112233", true},
+		{"color:A0b1C2", true},
+		{"color:#12345", false},
+		{"color:#GG0000", false},
+		{"white", false},
+	}
+
+	for _, test := range tests {
+		if result := validBackground(test.input); result != test.expected {
+			t.Errorf("validBackground(%q) = %v, expected %v", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		input       string
+		name        string
+		value       string
+		expectError bool
+	}{
+		{"Authorization: Bearer abc", "Authorization", "Bearer abc", false},
+		{"X-Api-Key:secret", "X-Api-Key", "secret", false},
+		{"X-Token: a:b:c", "X-Token", "a:b:c", false},
+		{"no colon", "", "", true},
+		{": value", "", "", true},
+		{"Bad Name: value", "", "", true},
+	}
+
+	for _, test := range tests {
+		name, value, err := parseHeader(test.input)
+		if test.expectError {
+			if err == nil {
+				t.Errorf("parseHeader(%q) expected error, got %q, %q", test.input, name, value)
+			}
+			continue
+		}
+		if err != nil || name != test.name || value != test.value {
+			t.Errorf("parseHeader(%q) = %q, %q, %v, expected %q, %q", test.input, name, value, err, test.name, test.value)
+		}
+	}
+}
+
+func TestAspectRatioRatio(t *testing.T) {
+	tests := []struct {
+		ratio  AspectRatio
+		width  int
+		height int
+	}{
+		{AspectRatio16x9, 16, 9},
+		{AspectRatio9x16, 9, 16},
+		{AspectRatio3x2, 3, 2},
+		{AspectRatio(""), 16, 9},
+	}
+
+	for _, test := range tests {
+		if w, h := test.ratio.Ratio(); w != test.width || h != test.height {
+			t.Errorf("Ratio(%q) = %d:%d, expected %d:%d", test.ratio, w, h, test.width, test.height)
+		}
+	}
+}
+
+func TestImageProviderKeyEnv(t *testing.T) {
+	tests := []struct {
+		provider ImageProvider
+		expected string
+	}{
+		{ImageProviderIdeogram, "IDEOGRAM_API_KEY"},
+		{ImageProviderDALLE, "OPENAI_API_KEY"},
+		{ImageProviderGPTImage, "OPENAI_API_KEY"},
+		{ImageProviderImagen, "GEMINI_API_KEY"},
+		{ImageProviderStability, "STABILITY_API_KEY"},
+		{ImageProviderReplicate, "REPLICATE_API_TOKEN"},
+		{ImageProviderLocalSD, ""},
+	}
+
+	for _, test := range tests {
+		if got := test.provider.KeyEnv(); got != test.expected {
+			t.Errorf("KeyEnv(%q) = %q, expected %q", test.provider, got, test.expected)
+		}
+	}
+}
+
+func TestParseAspectRatio(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    AspectRatio
+		expectError bool
+	}{
+		{"16:9", AspectRatio16x9, false},
+		{"9x16", AspectRatio9x16, false},
+		{" Square ", AspectRatio1x1, false},
+		{"3X2", AspectRatio3x2, false},
+		{"2:3", AspectRatio2x3, false},
+		{"16x10", "", true},
+		{"21:9", "", true},
+		{"", "", true},
+	}
+
+	for _, test := range tests {
+		result, err := ParseAspectRatio(test.input)
+		if test.expectError {
+			if err == nil {
+				t.Errorf("ParseAspectRatio(%q) expected error, got %q", test.input, result)
+			}
+			continue
+		}
+		if err != nil || result != test.expected {
+			t.Errorf("ParseAspectRatio(%q) = %q, %v, expected %q", test.input, result, err, test.expected)
+		}
+	}
+}
+
+func TestMissingInputs(t *testing.T) {
+	tests := []struct {
+		audio    string
+		image    string
+		autoFill bool
+		expected []string
+	}{
+		{"song.mp3", "cover.png", false, nil},
+		{"", "cover.png", false, []string{"--audio"}},
+		{"song.mp3", "", false, []string{"--image"}},
+		{"", "", false, []string{"--audio", "--image"}},
+		{"", "", true, nil},
+	}
+
+	for _, test := range tests {
+		cfg := &Config{Audio: test.audio, Image: test.image, AutoFill: test.autoFill}
+		missing := cfg.MissingInputs()
+		var flags []string
+		for _, m := range missing {
+			flags = append(flags, strings.Fields(m)[0])
+		}
+		if strings.Join(flags, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("MissingInputs(audio=%q, image=%q, autofill=%v) = %q, expected %q", test.audio, test.image, test.autoFill, missing, test.expected)
+		}
+	}
+}
+
+func TestValidateExistingOutput(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "out.mp4")
+	if err := os.WriteFile(output, []byte("video"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tests := []struct {
+		overwrite  bool
+		autoNumber bool
+		wantErr    bool
+	}{
+		{false, false, true},
+		{true, false, false},
+		{false, true, false},
+		{true, true, true},
+	}
+
+	for _, test := range tests {
+		cfg := New()
+		cfg.Output = output
+		cfg.Overwrite = test.overwrite
+		cfg.AutoNumber = test.autoNumber
+		err := cfg.validate()
+		if (err != nil) != test.wantErr {
+			t.Errorf("validate() with existing output, overwrite=%v, auto-number=%v = %v, expected error: %v", test.overwrite, test.autoNumber, err, test.wantErr)
+		}
+	}
+}
+
+func TestStdinIsTerminal(t *testing.T) {
+	// Cron and docker run without -i give /dev/null, a character device
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	origStdin := os.Stdin
+	os.Stdin = devNull
+	defer func() { os.Stdin = origStdin }()
+
+	if stdinIsTerminal() {
+		t.Errorf("stdinIsTerminal() with %s = true, expected false", os.DevNull)
+	}
+}
@@ -0,0 +1,136 @@
+// Package events writes machine-readable progress for mmmeld --json as
+// newline-delimited JSON objects. Emitting is a no-op until Enable is called,
+// so packages report progress unconditionally and only --json runs see it.
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Fields are the event-specific members of an event object
+type Fields map[string]any
+
+var (
+	mu  sync.Mutex
+	out io.Writer
+	now = time.Now
+)
+
+// Enable sends every later event to w. A nil w disables events again.
+func Enable(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	out = w
+}
+
+// Enabled reports whether events are being written
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return out != nil
+}
+
+// Emit writes one event as a single JSON line. "event" and "time" always come
+// first; fields may not override them.
+func Emit(name string, fields Fields) {
+	mu.Lock()
+	defer mu.Unlock()
+	if out == nil {
+		return
+	}
+
+	head, _ := json.Marshal(struct {
+		Event string `json:"event"`
+		Time  string `json:"time"`
+	}{name, now().UTC().Format(time.RFC3339Nano)})
+
+	rest := make(Fields, len(fields))
+	for k, v := range fields {
+		if k != "event" && k != "time" {
+			rest[k] = v
+		}
+	}
+	if len(rest) > 0 {
+		body, err := json.Marshal(rest)
+		if err != nil {
+			body, _ = json.Marshal(Fields{"marshal_error": err.Error()})
+		}
+		// Splice the two objects: {"event":..,"time":..} + {...} -> {"event":..,"time":..,...}
+		head = append(head[:len(head)-1], ',')
+		head = append(head, body[1:]...)
+	}
+
+	var line bytes.Buffer
+	line.Write(head)
+	line.WriteByte('\n')
+	out.Write(line.Bytes())
+}
+
+// progressStep is how far, in percent, a download advances between events
+const progressStep = 5
+
+// unknownSizeStep is how many bytes a download of unknown size advances
+// between events
+const unknownSizeStep = 1 << 20
+
+// progressReader emits name events as it is read
+type progressReader struct {
+	r       io.Reader
+	total   int64
+	read    int64
+	next    int64
+	emitted int64
+	name    string
+	fields  Fields
+}
+
+// Progress wraps r so reading it emits name events with bytes read, the total
+// (when total > 0), and a percent, every few percent and at EOF. Without
+// Enable, r is returned as is.
+func Progress(r io.Reader, total int64, name string, fields Fields) io.Reader {
+	if !Enabled() {
+		return r
+	}
+	p := &progressReader{r: r, total: total, name: name, fields: fields, emitted: -1}
+	p.next = p.step()
+	return p
+}
+
+func (p *progressReader) step() int64 {
+	if p.total > 0 {
+		return max(p.total*progressStep/100, 1)
+	}
+	return unknownSizeStep
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+	if p.read >= p.next || err == io.EOF {
+		p.emit()
+		for p.next <= p.read {
+			p.next += p.step()
+		}
+	}
+	return n, err
+}
+
+func (p *progressReader) emit() {
+	if p.read == p.emitted {
+		return
+	}
+	p.emitted = p.read
+	fields := Fields{"bytes": p.read}
+	for k, v := range p.fields {
+		fields[k] = v
+	}
+	if p.total > 0 {
+		fields["total"] = p.total
+		fields["percent"] = min(100, p.read*100/p.total)
+	}
+	Emit(p.name, fields)
+}
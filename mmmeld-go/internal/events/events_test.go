@@ -0,0 +1,122 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func capture(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	Enable(&buf)
+	now = func() time.Time { return time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC) }
+	t.Cleanup(func() {
+		Enable(nil)
+		now = time.Now
+	})
+	return &buf
+}
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var events []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var event map[string]any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestEmit(t *testing.T) {
+	tests := []struct {
+		name     string
+		fields   Fields
+		expected string
+	}{
+		{"stage_start", Fields{"stage": "audio"}, `{"event":"stage_start","time":"2025-01-02T03:04:05Z","stage":"audio"}`},
+		{"done", nil, `{"event":"done","time":"2025-01-02T03:04:05Z"}`},
+		{"error", Fields{"event": "spoofed", "category": "render"}, `{"event":"error","time":"2025-01-02T03:04:05Z","category":"render"}`},
+	}
+
+	for _, tt := range tests {
+		buf := capture(t)
+		Emit(tt.name, tt.fields)
+		if got := strings.TrimSuffix(buf.String(), "\n"); got != tt.expected {
+			t.Errorf("Emit(%q, %v) = %s, expected %s", tt.name, tt.fields, got, tt.expected)
+		}
+	}
+}
+
+func TestEmitDisabled(t *testing.T) {
+	Enable(nil)
+	Emit("stage_start", Fields{"stage": "audio"})
+	if Enabled() {
+		t.Errorf("Enabled() = true after Enable(nil)")
+	}
+}
+
+func TestProgress(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int
+		total    int64
+		percents []float64
+	}{
+		{"known size", 100, 100, []float64{5, 10, 15, 20, 25, 30, 35, 40, 45, 50, 55, 60, 65, 70, 75, 80, 85, 90, 95, 100}},
+		{"short body", 50, 100, []float64{5, 10, 15, 20, 25, 30, 35, 40, 45, 50}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := capture(t)
+			r := Progress(bytes.NewReader(make([]byte, tt.size)), tt.total, "download_progress", Fields{"url": "https://example.com/a.mp3"})
+			// Read one byte at a time so every step boundary is crossed
+			one := make([]byte, 1)
+			for {
+				if _, err := r.Read(one); err == io.EOF {
+					break
+				}
+			}
+
+			events := decodeLines(t, buf)
+			if len(events) != len(tt.percents) {
+				t.Fatalf("Progress emitted %d events, expected %d: %s", len(events), len(tt.percents), buf)
+			}
+			for i, event := range events {
+				if event["percent"] != tt.percents[i] {
+					t.Errorf("Event %d percent = %v, expected %v", i, event["percent"], tt.percents[i])
+				}
+				if event["url"] != "https://example.com/a.mp3" {
+					t.Errorf("Event %d url = %v, expected the download URL", i, event["url"])
+				}
+			}
+		})
+	}
+}
+
+func TestProgressUnknownSize(t *testing.T) {
+	buf := capture(t)
+	r := Progress(bytes.NewReader(make([]byte, unknownSizeStep+10)), -1, "download_progress", nil)
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	events := decodeLines(t, buf)
+	last := events[len(events)-1]
+	if last["bytes"] != float64(unknownSizeStep+10) {
+		t.Errorf("Final event bytes = %v, expected %d", last["bytes"], unknownSizeStep+10)
+	}
+	if _, ok := last["percent"]; ok {
+		t.Errorf("Final event has a percent without a known size: %v", last)
+	}
+}
@@ -2,107 +2,222 @@ package ffmpeg
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"fmt"
-	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
+
+	"mmmeld/internal/events"
+	"mmmeld/internal/logx"
 )
 
+// videoExts are the container extensions treated as video
+var videoExts = []string{".mp4", ".avi", ".mov", ".mkv", ".webm", ".wmv", ".flv", ".m4v"}
+
+// IsVideoFile reports whether path has a video container extension
+func IsVideoFile(path string) bool {
+	return slices.Contains(videoExts, strings.ToLower(filepath.Ext(path)))
+}
+
+// IsVideoInput reports whether path is a video container whose audio track
+// must be extracted before use. WebM counts as audio.
+func IsVideoInput(path string) bool {
+	return IsVideoFile(path) && !strings.EqualFold(filepath.Ext(path), ".webm")
+}
+
+// ExtractOptions selects what ExtractAudio copies out of a media file
+type ExtractOptions struct {
+	Start    time.Duration // Where to start (0 = the beginning)
+	Duration time.Duration // How much to extract (0 = to the end)
+	Mono     bool          // Downmix to one channel
+	Bitrate  string        // AAC bitrate, e.g. "64k" (empty = ffmpeg's default)
+}
+
+// ExtractAudio writes the audio track of inputPath, which may be a video, to
+// outputPath as AAC in an m4a container
+func ExtractAudio(ctx context.Context, inputPath, outputPath string, opts ExtractOptions) error {
+	args := []string{"-v", "error"}
+	if opts.Start > 0 {
+		args = append(args, "-ss", fmt.Sprintf("%.3f", opts.Start.Seconds()))
+	}
+	args = append(args, "-i", inputPath)
+	if opts.Duration > 0 {
+		args = append(args, "-t", fmt.Sprintf("%.3f", opts.Duration.Seconds()))
+	}
+	args = append(args, "-vn")
+	if opts.Mono {
+		args = append(args, "-ac", "1")
+	}
+	args = append(args, "-c:a", "aac")
+	if opts.Bitrate != "" {
+		args = append(args, "-b:a", opts.Bitrate)
+	}
+	args = append(args, "-y", outputPath)
+
+	output, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg audio extraction failed: %w\nOutput: %s", err, output)
+	}
+	return nil
+}
+
 // logFFmpeg logs ffmpeg output with clean formatting (no file/line info)
 func logFFmpeg(message string) {
 	fmt.Fprintf(os.Stderr, "%s [ffmpeg] %s\n", time.Now().Format("2006/01/02 15:04:05"), message)
 }
 
-// RunCommand executes an ffmpeg command with real-time progress output
+// RunOptions describes a long-running ffmpeg command for progress reporting
+type RunOptions struct {
+	Step     string  // Name used in progress lines and ffmpeg_progress events, e.g. "final_render"
+	Duration float64 // Seconds of output expected; enables percent progress
+}
+
+// progressLogStep is how far, in percent, an encode advances between progress
+// lines at the default log level
+const progressLogStep = 25
+
+// stderrTailLines is how much of ffmpeg's output is kept for error messages
+const stderrTailLines = 20
+
+// RunCommand executes an ffmpeg command, echoing its output only at the debug
+// log level. See Run.
 func RunCommand(cmd []string) error {
-	log.Printf("Running ffmpeg: %s", strings.Join(cmd, " "))
-	
-	execCmd := exec.Command(cmd[0], cmd[1:]...)
-	
-	// Create pipes for stdout and stderr
+	return Run(cmd, RunOptions{})
+}
+
+// Run executes an ffmpeg command. At the debug log level every line ffmpeg
+// writes is echoed; otherwise its output is only kept for the error message.
+// With opts.Duration, progress is logged every few percent and sent as
+// ffmpeg_progress events for --json.
+func Run(cmd []string, opts RunOptions) error {
+	logx.Debugf("Running ffmpeg: %s", strings.Join(cmd, " "))
+	verbose := logx.Enabled(logx.LevelDebug)
+
+	var global []string
+	if opts.Duration > 0 {
+		global = append(global, "-progress", "pipe:1")
+	}
+	if !verbose {
+		global = append(global, "-nostats")
+	}
+	execCmd := exec.Command(cmd[0], append(global, cmd[1:]...)...)
+
 	stdout, err := execCmd.StdoutPipe()
 	if err != nil {
 		return fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
-	
 	stderr, err := execCmd.StderrPipe()
 	if err != nil {
 		return fmt.Errorf("failed to create stderr pipe: %w", err)
 	}
-	
-	// Start the command
 	if err := execCmd.Start(); err != nil {
 		return fmt.Errorf("failed to start ffmpeg: %w", err)
 	}
-	
-	// Create a channel to signal when output reading is done
-	done := make(chan bool, 2)
-	
-	// Read stdout in a goroutine
+
+	// ffmpeg rewrites its stats line with \r, so split on either line ending
+	var tail []string
+	done := make(chan struct{})
 	go func() {
-		scanner := bufio.NewScanner(stdout)
+		defer close(done)
+		scanner := bufio.NewScanner(stderr)
+		scanner.Split(scanLines)
 		for scanner.Scan() {
 			line := scanner.Text()
-			logFFmpeg(fmt.Sprintf("stdout: %s", line))
-		}
-		done <- true
-	}()
-	
-	// Read stderr in a goroutine (ffmpeg outputs progress here)
-	go func() {
-		reader := bufio.NewReader(stderr)
-		buffer := make([]byte, 4096)
-		lineBuffer := make([]byte, 0, 4096)
-		
-		for {
-			n, err := reader.Read(buffer)
-			if n > 0 {
-				for i := 0; i < n; i++ {
-					if buffer[i] == '\r' {
-						// Handle \r (carriage return) - used for progress updates
-						if len(lineBuffer) > 0 {
-							line := string(lineBuffer)
-							logFFmpeg(line)
-							lineBuffer = lineBuffer[:0]
-						}
-					} else if buffer[i] == '\n' {
-						// Handle \n (newline) - creates a new line
-						if len(lineBuffer) > 0 {
-							line := string(lineBuffer)
-							logFFmpeg(line)
-							lineBuffer = lineBuffer[:0]
-						}
-					} else {
-						lineBuffer = append(lineBuffer, buffer[i])
-					}
-				}
+			if verbose {
+				logFFmpeg(line)
 			}
-			if err != nil {
-				if len(lineBuffer) > 0 {
-					line := string(lineBuffer)
-					log.Printf("[ffmpeg] %s", line)
-				}
-				break
+			tail = append(tail, line)
+			if len(tail) > stderrTailLines {
+				tail = tail[1:]
 			}
 		}
-		done <- true
 	}()
-	
-	// Wait for both output readers to finish
-	<-done
+
+	progress := &progressReporter{opts: opts, last: -1, nextLog: progressLogStep}
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if seconds, ok := parseProgressTime(line); ok {
+			progress.update(seconds)
+		} else if verbose && opts.Duration <= 0 {
+			logFFmpeg(fmt.Sprintf("stdout: %s", line))
+		}
+	}
 	<-done
-	
-	// Wait for the command to complete
+
 	if err := execCmd.Wait(); err != nil {
-		return fmt.Errorf("ffmpeg failed: %w", err)
+		return fmt.Errorf("ffmpeg failed: %w\nOutput: %s", err, strings.Join(tail, "\n"))
 	}
-	
-	log.Println("ffmpeg command completed successfully")
+	if opts.Duration > 0 {
+		progress.update(opts.Duration)
+	}
+
+	logx.Debugf("ffmpeg command completed successfully")
 	return nil
 }
 
+// progressReporter turns ffmpeg -progress positions into percent events and
+// occasional log lines
+type progressReporter struct {
+	opts    RunOptions
+	last    int
+	nextLog int
+}
+
+func (p *progressReporter) update(seconds float64) {
+	if p.opts.Duration <= 0 {
+		return
+	}
+	percent := int(min(100, seconds*100/p.opts.Duration))
+	if percent <= p.last {
+		return
+	}
+	p.last = percent
+	events.Emit("ffmpeg_progress", events.Fields{"step": p.opts.Step, "percent": percent})
+	if percent >= p.nextLog {
+		logx.Infof("%s: %d%%", strings.ReplaceAll(p.opts.Step, "_", " "), percent)
+		for p.nextLog <= percent {
+			p.nextLog += progressLogStep
+		}
+	}
+}
+
+// parseProgressTime reads the encoded position, in seconds, from an
+// "out_time_us=" line of ffmpeg -progress output
+func parseProgressTime(line string) (float64, bool) {
+	value, ok := strings.CutPrefix(line, "out_time_us=")
+	if !ok {
+		return 0, false
+	}
+	us, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || us < 0 {
+		return 0, false
+	}
+	return float64(us) / 1e6, true
+}
+
+// scanLines is bufio.ScanLines that also ends lines at \r, dropping empty ones
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	start := 0
+	for start < len(data) && (data[start] == '\r' || data[start] == '\n') {
+		start++
+	}
+	if i := bytes.IndexAny(data[start:], "\r\n"); i >= 0 {
+		return start + i + 1, data[start : start+i], nil
+	}
+	if atEOF && start < len(data) {
+		return len(data), data[start:], nil
+	}
+	return start, nil, nil
+}
+
 // RunCommandQuiet executes an ffmpeg command without progress output (for validation checks)
 func RunCommandQuiet(cmd []string) error {
 	execCmd := exec.Command(cmd[0], cmd[1:]...)
@@ -116,4 +231,42 @@ func RunCommandQuiet(cmd []string) error {
 func RunCommandWithOutput(cmd []string) ([]byte, error) {
 	execCmd := exec.Command(cmd[0], cmd[1:]...)
 	return execCmd.CombinedOutput()
-}
\ No newline at end of file
+}
+
+// PlayAudio plays an audio file, blocking until playback ends or ctx is
+// cancelled. ffplay is used when available; otherwise the platform's default
+// player or opener, which on Linux and Windows may return before playback ends.
+func PlayAudio(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("cannot play %s: %w", path, err)
+	}
+
+	cmd := []string{"ffplay", "-nodisp", "-autoexit", "-loglevel", "error", path}
+	if _, err := exec.LookPath("ffplay"); err != nil {
+		cmd = openerCommand(path)
+		logx.Infof("ffplay not found, playing with %s", cmd[0])
+	}
+
+	execCmd := exec.CommandContext(ctx, cmd[0], cmd[1:]...)
+	execCmd.Stdout = os.Stdout
+	execCmd.Stderr = os.Stderr
+	if err := execCmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("playback with %s failed: %w", cmd[0], err)
+	}
+	return nil
+}
+
+// openerCommand returns the platform's default way to play a file
+func openerCommand(path string) []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"afplay", path}
+	case "windows":
+		return []string{"cmd", "/c", "start", "/wait", "", path}
+	default:
+		return []string{"xdg-open", path}
+	}
+}
@@ -0,0 +1,71 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestParseProgressTime(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected float64
+		ok       bool
+	}{
+		{"out_time_us=12500000", 12.5, true},
+		{"out_time_us=0", 0, true},
+		{"out_time_us=N/A", 0, false},
+		{"out_time=00:00:12.500000", 0, false},
+		{"progress=end", 0, false},
+	}
+
+	for _, test := range tests {
+		seconds, ok := parseProgressTime(test.line)
+		if seconds != test.expected || ok != test.ok {
+			t.Errorf("parseProgressTime(%q) = %v, %v, expected %v, %v", test.line, seconds, ok, test.expected, test.ok)
+		}
+	}
+}
+
+func TestScanLines(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"a\nb\n", []string{"a", "b"}},
+		{"frame=1\rframe=2\rframe=3\nError\n", []string{"frame=1", "frame=2", "frame=3", "Error"}},
+		{"\r\n\nlast", []string{"last"}},
+		{"", nil},
+	}
+
+	for _, test := range tests {
+		scanner := bufio.NewScanner(strings.NewReader(test.input))
+		scanner.Split(scanLines)
+		var lines []string
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		if strings.Join(lines, "|") != strings.Join(test.expected, "|") {
+			t.Errorf("scanLines(%q) = %q, expected %q", test.input, lines, test.expected)
+		}
+	}
+}
+
+func TestIsVideoInput(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"clip.mp4", true},
+		{"Clip.MOV", true},
+		{"song.webm", false},
+		{"song.m4a", false},
+		{"song.mp3", false},
+	}
+
+	for _, test := range tests {
+		if got := IsVideoInput(test.path); got != test.expected {
+			t.Errorf("IsVideoInput(%q) = %v, expected %v", test.path, got, test.expected)
+		}
+	}
+}
@@ -1,21 +1,37 @@
 package fileutil
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"mime"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"mmmeld/internal/config"
+	"mmmeld/internal/events"
+	"mmmeld/internal/httpx"
+	"mmmeld/internal/logx"
 )
 
 var tempAssetRunNonce = func() string {
@@ -23,9 +39,14 @@ var tempAssetRunNonce = func() string {
 	return hex.EncodeToString(sum[:])[:8]
 }()
 
-// CleanupManager handles temporary file cleanup
+// CleanupManager tracks temporary files and folders to delete at exit. It is
+// safe for concurrent use.
 type CleanupManager struct {
-	files []string
+	mu            sync.Mutex
+	files         []string
+	dirs          []string
+	keepOnFailure bool
+	failed        bool
 }
 
 func NewCleanupManager() *CleanupManager {
@@ -34,30 +55,88 @@ func NewCleanupManager() *CleanupManager {
 	}
 }
 
-func (cm *CleanupManager) Add(filepath string) {
-	cm.files = append(cm.files, filepath)
+// Add registers a file for cleanup; adding it again changes nothing
+func (cm *CleanupManager) Add(path string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.files = appendUnique(cm.files, path)
 }
 
-// Remove removes a file from the cleanup list (used to preserve files we want to keep)
-func (cm *CleanupManager) Remove(filepath string) {
-	for i, f := range cm.files {
-		if f == filepath {
-			cm.files = append(cm.files[:i], cm.files[i+1:]...)
-			return
-		}
+// AddDir registers a folder, removed with everything in it after the files.
+// Folders go in reverse order of adding, so nested ones go first.
+func (cm *CleanupManager) AddDir(dir string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.dirs = appendUnique(cm.dirs, dir)
+}
+
+func appendUnique(paths []string, path string) []string {
+	path = filepath.Clean(path)
+	if slices.Contains(paths, path) {
+		return paths
 	}
+	return append(paths, path)
+}
+
+// Remove removes a file or folder from the cleanup list (used to preserve files we want to keep)
+func (cm *CleanupManager) Remove(path string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	path = filepath.Clean(path)
+	cm.files = slices.DeleteFunc(cm.files, func(f string) bool { return f == path })
+	cm.dirs = slices.DeleteFunc(cm.dirs, func(d string) bool { return d == path })
+}
+
+// KeepOnFailure makes Cleanup keep everything once Fail has been called, so a
+// failed run's intermediate files stay for debugging (and --resume). A run
+// that never fails is cleaned up fully either way.
+func (cm *CleanupManager) KeepOnFailure(keep bool) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.keepOnFailure = keep
 }
 
+// Fail marks the run as failed
+func (cm *CleanupManager) Fail() {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.failed = true
+}
+
+// Cleanup deletes the registered files, then the folders. Entries it removes
+// (or finds already gone) are forgotten, so calling it again only retries the
+// ones that failed.
 func (cm *CleanupManager) Cleanup() error {
-	var errors []string
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if cm.failed && cm.keepOnFailure {
+		if n := len(cm.files) + len(cm.dirs); n > 0 {
+			logx.Infof("Keeping %d temporary file(s) of the failed run in %s", n, config.TempAssetsFolder)
+		}
+		return nil
+	}
+
+	var errs []string
+	remaining := make([]string, 0)
 	for _, file := range cm.files {
 		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
-			errors = append(errors, fmt.Sprintf("failed to remove %s: %v", file, err))
+			errs = append(errs, fmt.Sprintf("failed to remove %s: %v", file, err))
+			remaining = append(remaining, file)
+		}
+	}
+	cm.files = remaining
+
+	var remainingDirs []string
+	for i := len(cm.dirs) - 1; i >= 0; i-- {
+		if err := os.RemoveAll(cm.dirs[i]); err != nil {
+			errs = append(errs, fmt.Sprintf("failed to remove %s: %v", cm.dirs[i], err))
+			remainingDirs = append([]string{cm.dirs[i]}, remainingDirs...)
 		}
 	}
+	cm.dirs = remainingDirs
 
-	if len(errors) > 0 {
-		return fmt.Errorf("cleanup errors: %s", strings.Join(errors, "; "))
+	if len(errs) > 0 {
+		return fmt.Errorf("cleanup errors: %s", strings.Join(errs, "; "))
 	}
 	return nil
 }
@@ -113,7 +192,19 @@ func TempAssetPath(tempFolder, plannedOutputPath, filename string) string {
 	return filepath.Join(tempFolder, fmt.Sprintf("%s_%s_%s", prefix, tempAssetRunNonce, filename))
 }
 
-// SanitizeFilename cleans a filename for safe filesystem use
+// maxFilenameBytes keeps sanitized names, plus the run prefixes and
+// _mmmeld.mp4 suffixes added to them, under the 255-byte limit of common file
+// systems. MaxFilenameLength counts characters; for CJK and emoji titles this
+// is the tighter limit.
+const maxFilenameBytes = 200
+
+// windowsReserved matches the device names Windows refuses as file names,
+// with or without an extension
+var windowsReserved = regexp.MustCompile(`(?i)^(CON|PRN|AUX|NUL|COM[1-9]|LPT[1-9])$`)
+
+// SanitizeFilename cleans a filename for safe filesystem use. Long names are
+// shortened between characters, keeping emoji and accented letters whole and
+// any short extension such as .mp3.
 func SanitizeFilename(filename string) string {
 	// Remove or replace invalid characters
 	reg := regexp.MustCompile(`[<>:"/\\|?*]`)
@@ -130,23 +221,99 @@ func SanitizeFilename(filename string) string {
 	// Trim whitespace and dots
 	sanitized = strings.Trim(sanitized, " .")
 
-	// Limit length
-	if len(sanitized) > config.MaxFilenameLength {
-		sanitized = sanitized[:config.MaxFilenameLength]
+	// Limit length, keeping the extension
+	stem, ext := sanitized, filepath.Ext(sanitized)
+	if isShortExtension(ext) {
+		stem = strings.TrimSuffix(sanitized, ext)
+	} else {
+		ext = ""
 	}
+	stem = truncateName(stem, config.MaxFilenameLength-utf8.RuneCountInString(ext), maxFilenameBytes-len(ext))
+	stem = strings.TrimRight(stem, " .")
+	if windowsReserved.MatchString(stem) {
+		stem += "_"
+	}
+	sanitized = stem + ext
 
 	// Ensure it's not empty
-	if sanitized == "" {
+	if stem == "" {
 		sanitized = "unnamed"
 	}
 
 	return sanitized
 }
 
-// GetDefaultOutputPath generates a default output filename based on audio source
-func GetDefaultOutputPath(audioPath string) string {
+// isShortExtension reports whether ext, from filepath.Ext, looks like a file
+// extension rather than the end of a title such as "Mr. Smith"
+func isShortExtension(ext string) bool {
+	if len(ext) < 2 || len(ext) > 10 {
+		return false
+	}
+	for _, r := range ext[1:] {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// truncateName shortens s to at most maxRunes characters and maxBytes bytes.
+// The cut moves back to the start of the character it would split: a letter
+// with its combining marks, an emoji with its modifiers or zero-width-joined
+// parts, or a flag's pair of regional indicators.
+func truncateName(s string, maxRunes, maxBytes int) string {
+	runes := []rune(s)
+	cut, size := 0, 0
+	for cut < len(runes) && cut < maxRunes && size+utf8.RuneLen(runes[cut]) <= maxBytes {
+		size += utf8.RuneLen(runes[cut])
+		cut++
+	}
+	if cut == len(runes) {
+		return s
+	}
+	for cut > 0 && (extendsCharacter(runes[cut]) || runes[cut-1] == zeroWidthJoiner || splitsFlag(runes, cut)) {
+		cut--
+	}
+	return string(runes[:cut])
+}
+
+const zeroWidthJoiner = '\u200D'
+
+// extendsCharacter reports whether r continues the character before it
+func extendsCharacter(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+		r == zeroWidthJoiner ||
+		(r >= 0xFE00 && r <= 0xFE0F) || // Variation selectors
+		(r >= 0x1F3FB && r <= 0x1F3FF) || // Skin tone modifiers
+		(r >= 0xE0020 && r <= 0xE007F) // Tag sequences (subdivision flags)
+}
+
+// splitsFlag reports whether cutting runes at i separates the two regional
+// indicators of a flag
+func splitsFlag(runes []rune, i int) bool {
+	isIndicator := func(r rune) bool { return r >= 0x1F1E6 && r <= 0x1F1FF }
+	if !isIndicator(runes[i]) {
+		return false
+	}
+	before := 0
+	for j := i - 1; j >= 0 && isIndicator(runes[j]); j-- {
+		before++
+	}
+	return before%2 == 1
+}
+
+// GetDefaultOutputPath generates a default output path based on the audio
+// source. The video is named after title if set, such as the title of
+// generated speech, whose file has a provider name, else after the audio
+// file. It goes in outputDir if set, else next to a local audio file, else
+// (for downloaded or generated audio, which lives in the temp folder) in the
+// current directory.
+func GetDefaultOutputPath(audioPath, title, outputDir string) string {
+	if title != "" {
+		return filepath.Join(outputDir, fmt.Sprintf("%s_mmmeld.mp4", SanitizeFilename(title)))
+	}
 	if audioPath == "" || audioPath == "generate" {
-		return "mmmeld_output.mp4"
+		return filepath.Join(outputDir, "mmmeld_output.mp4")
 	}
 
 	// Extract base name without extension
@@ -157,7 +324,47 @@ func GetDefaultOutputPath(audioPath string) string {
 	// Sanitize the name
 	name = SanitizeFilename(name)
 
-	return fmt.Sprintf("%s_mmmeld.mp4", name)
+	dir := outputDir
+	if dir == "" && !InTempFolder(audioPath) {
+		dir = filepath.Dir(audioPath)
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s_mmmeld.mp4", name))
+}
+
+// InTempFolder reports whether path is inside the temp assets folder
+func InTempFolder(path string) bool {
+	folder, err := filepath.Abs(config.TempAssetsFolder)
+	if err != nil {
+		return false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(folder, abs)
+	return err == nil && rel != "." && !strings.HasPrefix(rel, "..")
+}
+
+// ResolveOutputPath applies the overwrite policy to a planned output file. A
+// path that does not exist yet is used as is. An existing one is replaced
+// with overwrite, or with autoNumber becomes the first free name_2.ext,
+// name_3.ext, and so on; otherwise it is an error.
+func ResolveOutputPath(path string, overwrite, autoNumber bool) (string, error) {
+	if overwrite || !FileExists(path) {
+		return path, nil
+	}
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s_%d%s", base, n, ext)
+		if FileExists(candidate) {
+			continue
+		}
+		if !autoNumber {
+			return "", fmt.Errorf("output %s already exists; use --overwrite (-y) to replace it or --auto-number to write %s", path, candidate)
+		}
+		return candidate, nil
+	}
 }
 
 // IsYouTubeURL checks if a URL is a YouTube URL
@@ -166,66 +373,584 @@ func IsYouTubeURL(url string) bool {
 	return youtubeRegex.MatchString(url)
 }
 
-// DownloadYouTubeAudio downloads audio from a YouTube URL using yt-dlp
-func DownloadYouTubeAudio(url string, cleanup *CleanupManager) (string, error) {
-	if err := EnsureTempFolder(); err != nil {
-		return "", fmt.Errorf("failed to create temp folder: %w", err)
+// ytdlpHosts lists sites whose links are handed to yt-dlp rather than
+// downloaded directly. Subdomains match too (e.g. artist.bandcamp.com).
+var ytdlpHosts = []string{
+	"youtube.com",
+	"youtu.be",
+	"youtube-nocookie.com",
+	"soundcloud.com",
+	"vimeo.com",
+	"bandcamp.com",
+	"mixcloud.com",
+	"dailymotion.com",
+	"twitch.tv",
+	"tiktok.com",
+	"archive.org",
+}
+
+// IsYtDlpURL reports whether a URL should be downloaded with yt-dlp: YouTube
+// links, links to other known yt-dlp-supported sites, or, when forceYtDlp is
+// set, any http(s) URL.
+func IsYtDlpURL(rawURL string, forceYtDlp bool) bool {
+	if IsYouTubeURL(rawURL) {
+		return true
+	}
+	if !httpx.IsHTTPURL(rawURL) {
+		return false
+	}
+	if forceYtDlp {
+		return true
 	}
 
-	runPrefix := tempAssetRunNonce
-	outputTemplate := filepath.Join(config.TempAssetsFolder, fmt.Sprintf("%s_%%(title)s.%%(ext)s", runPrefix))
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, known := range ytdlpHosts {
+		if host == known || strings.HasSuffix(host, "."+known) {
+			return true
+		}
+	}
+	return false
+}
+
+// YtDlpOptions passes login cookies and extra arguments to yt-dlp, for
+// age-restricted and members-only videos
+type YtDlpOptions struct {
+	Cookies            string   // Netscape cookies file (--cookies)
+	CookiesFromBrowser string   // Browser to load cookies from (--cookies-from-browser)
+	ExtraArgs          []string // Passed as given, after mmmeld's own arguments; may hold credentials
+	PlaylistItems      string   // Entries of a playlist to download (--playlist-items)
+	Binary             string   // yt-dlp to run, a path or a name on PATH (empty = see YtDlpNames)
+	Proxy              string   // Proxy URL (--proxy; empty = yt-dlp reads HTTP_PROXY and HTTPS_PROXY)
+
+	NoCache  bool          // Always download instead of reusing the download cache
+	CacheDir string        // Download cache location (empty = DefaultDownloadCacheDir)
+	CacheTTL time.Duration // Cached downloads unused this long are pruned (0 = kept)
+}
 
-	cmd := exec.Command("yt-dlp",
+// YtDlpOptionsFromConfig returns the yt-dlp options set on the command line
+func YtDlpOptionsFromConfig(cfg *config.Config) YtDlpOptions {
+	return YtDlpOptions{
+		Cookies:            cfg.YtDlpCookies,
+		CookiesFromBrowser: cfg.YtDlpCookiesFromBrowser,
+		ExtraArgs:          cfg.YtDlpArgs,
+		PlaylistItems:      cfg.PlaylistItems,
+		Binary:             cfg.YtDlpPath,
+		Proxy:              cfg.Proxy,
+		NoCache:            cfg.NoDownloadCache,
+		CacheTTL:           time.Duration(cfg.DownloadCacheTTLDays) * 24 * time.Hour,
+	}
+}
+
+// YtDlpNames lists the binaries to look for, in order: the configured one
+// alone, or else yt-dlp and, failing that, youtube-dl
+func YtDlpNames(configured string) []string {
+	if configured != "" {
+		return []string{configured}
+	}
+	return []string{"yt-dlp", "youtube-dl"}
+}
+
+// YtDlpNotFound is the error for a missing yt-dlp, with install instructions
+func YtDlpNotFound(configured string) error {
+	if configured != "" {
+		return fmt.Errorf("yt-dlp not found at %s (from --ytdlp-path or MMMELD_YTDLP)", configured)
+	}
+	return errors.New("yt-dlp not found on PATH; install it (brew install yt-dlp, pip install yt-dlp, or see https://github.com/yt-dlp/yt-dlp#installation) or point --ytdlp-path or MMMELD_YTDLP at it")
+}
+
+// IsYoutubeDL reports whether binary is youtube-dl, which lacks several of
+// yt-dlp's options
+func IsYoutubeDL(binary string) bool {
+	name := strings.TrimSuffix(strings.ToLower(filepath.Base(binary)), ".exe")
+	return name == "youtube-dl"
+}
+
+// ytDlpCommand builds the yt-dlp command for args, finding the binary per
+// opts. youtube-dl gets the options it understands: the --print lines and
+// progress options are dropped, so the downloaded file is found from its
+// output or the temp folder, and --embed-metadata becomes --add-metadata.
+func ytDlpCommand(opts YtDlpOptions, args []string) (*exec.Cmd, error) {
+	var binary string
+	for _, name := range YtDlpNames(opts.Binary) {
+		if path, err := exec.LookPath(name); err == nil {
+			binary = path
+			break
+		}
+	}
+	if binary == "" {
+		return nil, YtDlpNotFound(opts.Binary)
+	}
+	if IsYoutubeDL(binary) {
+		logx.Debugf("yt-dlp not found; using %s", binary)
+		args = youtubeDLArgs(args)
+	}
+	return exec.Command(binary, args...), nil
+}
+
+// youtubeDLArgs adapts yt-dlp arguments to youtube-dl
+func youtubeDLArgs(args []string) []string {
+	var adapted []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--print":
+			i++ // And its template
+		case "--no-simulate", "--progress":
+		case "--embed-metadata":
+			adapted = append(adapted, "--add-metadata")
+		default:
+			adapted = append(adapted, args[i])
+		}
+	}
+	return adapted
+}
+
+// args returns the yt-dlp arguments for o. PlaylistItems is left to the
+// playlist downloads.
+func (o YtDlpOptions) args() []string {
+	var args []string
+	if o.Proxy != "" {
+		args = append(args, "--proxy", o.Proxy)
+	}
+	if o.Cookies != "" {
+		args = append(args, "--cookies", o.Cookies)
+	}
+	if o.CookiesFromBrowser != "" {
+		args = append(args, "--cookies-from-browser", o.CookiesFromBrowser)
+	}
+	return append(args, o.ExtraArgs...)
+}
+
+// ytDlpSecretFlags are yt-dlp options whose values are not logged
+var ytDlpSecretFlags = map[string]bool{
+	"--cookies":                     true,
+	"--cookies-from-browser":        true,
+	"-u":                            true,
+	"--username":                    true,
+	"-p":                            true,
+	"--password":                    true,
+	"-2":                            true,
+	"--twofactor":                   true,
+	"--video-password":              true,
+	"--ap-username":                 true,
+	"--ap-password":                 true,
+	"--client-certificate-password": true,
+	"--add-header":                  true,
+	"--add-headers":                 true,
+}
+
+// redactYtDlpArgs renders a yt-dlp command line for logging, with the values
+// of ytDlpSecretFlags and URL credentials replaced
+func redactYtDlpArgs(args []string) string {
+	redacted := make([]string, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if name, _, ok := strings.Cut(arg, "="); ok && ytDlpSecretFlags[name] {
+			redacted[i] = name + "=xxxxx"
+			continue
+		}
+		redacted[i] = RedactURL(arg)
+		if ytDlpSecretFlags[arg] && i+1 < len(args) {
+			i++
+			redacted[i] = "xxxxx"
+		}
+	}
+	return strings.Join(redacted, " ")
+}
+
+// ytDlpAudioFormat and ytDlpVideoFormat pick what yt-dlp downloads: an mp3
+// with the video's metadata as tags, or an mp4 where one is offered
+var (
+	ytDlpAudioFormat = []string{
 		"--format", "bestaudio/best",
 		"--extract-audio",
 		"--audio-format", "mp3",
 		"--audio-quality", "192K",
+		"--embed-metadata",
+	}
+	ytDlpVideoFormat = []string{"--format", "best[ext=mp4]/best"}
+)
+
+// ytDlpVideoExts are the containers a video download may end up in
+var ytDlpVideoExts = []string{".mp4", ".webm", ".mkv"}
+
+// IsPlaylistURL reports whether a yt-dlp URL names a playlist: a YouTube
+// /playlist link, a SoundCloud set, or a Bandcamp album. A YouTube watch link
+// that carries a list parameter is the one video it plays.
+func IsPlaylistURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	isHost := func(known string) bool { return host == known || strings.HasSuffix(host, "."+known) }
+	switch {
+	case isHost("youtube.com"):
+		return u.Path == "/playlist" && u.Query().Get("list") != ""
+	case isHost("soundcloud.com"):
+		return strings.Contains(u.Path, "/sets/")
+	case isHost("bandcamp.com"):
+		return strings.HasPrefix(u.Path, "/album/")
+	}
+	return false
+}
+
+// Playlist is a downloaded playlist
+type Playlist struct {
+	Title string   // As yt-dlp reports it; empty if unknown
+	Files []string // One per downloaded entry, in playlist order
+}
+
+// playlistDownloads numbers the playlists downloaded in this process, so each
+// gets its own file prefix
+var playlistDownloads atomic.Int32
+
+// DownloadPlaylistAudio downloads the entries of a playlist (those in
+// opts.PlaylistItems, if set) as mp3s
+func DownloadPlaylistAudio(url string, opts YtDlpOptions, cleanup *CleanupManager) (*Playlist, error) {
+	return downloadPlaylist(url, ytDlpAudioFormat, []string{".mp3"}, opts, cleanup)
+}
+
+// DownloadPlaylistVideo downloads the entries of a playlist (those in
+// opts.PlaylistItems, if set) as videos
+func DownloadPlaylistVideo(url string, opts YtDlpOptions, cleanup *CleanupManager) (*Playlist, error) {
+	return downloadPlaylist(url, ytDlpVideoFormat, ytDlpVideoExts, opts, cleanup)
+}
+
+func downloadPlaylist(url string, format, exts []string, opts YtDlpOptions, cleanup *CleanupManager) (*Playlist, error) {
+	if err := EnsureTempFolder(); err != nil {
+		return nil, fmt.Errorf("failed to create temp folder: %w", err)
+	}
+
+	// Zero-padded entry numbers keep the fallback glob in playlist order
+	prefix := fmt.Sprintf("%s-playlist%d", tempAssetRunNonce, playlistDownloads.Add(1))
+	outputTemplate := filepath.Join(config.TempAssetsFolder, prefix+"_%(playlist_index)04d_%(title)s.%(ext)s")
+
+	args := append([]string{}, format...)
+	args = append(args,
+		"--yes-playlist",
+		"--newline",
+		"--progress",
+		"--print", ytDlpPrintFilepath,
+		"--print", ytDlpPrintPlaylistTitle,
+		"--no-simulate",
 		"--output", outputTemplate,
-		url,
 	)
+	if opts.PlaylistItems != "" {
+		args = append(args, "--playlist-items", opts.PlaylistItems)
+	}
+	args = append(append(args, opts.args()...), url)
+	cmd, err := ytDlpCommand(opts, args)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := runYtDlp(cmd, url)
+	if err != nil {
+		return nil, fmt.Errorf("yt-dlp failed for playlist: %w\nOutput: %s", err, output)
+	}
+
+	playlist := &Playlist{Files: parseYtDlpFilepaths(output, exts...)}
+	if len(playlist.Files) == 0 {
+		playlist.Files = globRunDownloads(prefix, exts...)
+	}
+	if len(playlist.Files) == 0 {
+		return nil, fmt.Errorf("playlist %s has no entries to download", RedactURL(url))
+	}
+	if titles := ytDlpPrinted(output, ytDlpPlaylistMarker); len(titles) > 0 && titles[0] != "NA" {
+		playlist.Title = titles[0]
+	}
 
-	output, err := cmd.CombinedOutput()
+	for _, file := range playlist.Files {
+		cleanup.Add(file)
+	}
+	logx.Infof("Downloaded %d playlist entries", len(playlist.Files))
+	return playlist, nil
+}
+
+// DownloadRemoteAudio downloads audio from YouTube or any other yt-dlp-supported
+// site, reusing the download cache when the video was fetched before
+func DownloadRemoteAudio(url string, opts YtDlpOptions, cleanup *CleanupManager) (string, error) {
+	return cachedDownload(url, "audio", []string{".mp3"}, opts, cleanup, func() (string, error) {
+		return downloadRemoteAudio(url, opts, cleanup)
+	})
+}
+
+func downloadRemoteAudio(url string, opts YtDlpOptions, cleanup *CleanupManager) (string, error) {
+	if err := EnsureTempFolder(); err != nil {
+		return "", fmt.Errorf("failed to create temp folder: %w", err)
+	}
+
+	runPrefix := tempAssetRunNonce
+	outputTemplate := filepath.Join(config.TempAssetsFolder, fmt.Sprintf("%s_%%(title)s.%%(ext)s", runPrefix))
+
+	args := append([]string{}, ytDlpAudioFormat...)
+	args = append(args,
+		"--no-playlist",
+		"--newline",
+		"--progress",
+		"--print", ytDlpPrintFilepath,
+		"--no-simulate",
+		"--output", outputTemplate,
+	)
+	args = append(append(args, opts.args()...), url)
+	cmd, err := ytDlpCommand(opts, args)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := runYtDlp(cmd, url)
 	if err != nil {
 		return "", fmt.Errorf("yt-dlp failed: %w\nOutput: %s", err, output)
 	}
 
-	// Find the downloaded file
-	// yt-dlp outputs the filename, we need to parse it
-	lines := strings.Split(string(output), "\n")
-	var downloadedFile string
-
-	for _, line := range lines {
-		if strings.Contains(line, "has already been downloaded") ||
-			strings.Contains(line, "Destination:") ||
-			strings.Contains(line, "[download]") && strings.Contains(line, ".mp3") {
-			// Extract filename from line
-			parts := strings.Fields(line)
-			for _, part := range parts {
-				if strings.HasSuffix(part, ".mp3") && strings.Contains(part, config.TempAssetsFolder) {
-					downloadedFile = part
-					break
-				}
+	downloadedFile := parseYtDlpFilepath(output, ".mp3")
+	if downloadedFile == "" {
+		downloadedFile = globRunDownload(runPrefix, ".mp3")
+	}
+	if downloadedFile == "" {
+		return "", fmt.Errorf("could not find downloaded audio file")
+	}
+
+	cleanup.Add(downloadedFile)
+	logx.Infof("Downloaded remote audio: %s", downloadedFile)
+
+	return downloadedFile, nil
+}
+
+// ytDlpFileMarker starts the line yt-dlp prints with the final path of each
+// download, after post-processing and moving. --print implies --quiet, so
+// --progress keeps the progress lines download_progress events come from.
+const ytDlpFileMarker = "mmmeld-file:"
+
+// ytDlpPrintFilepath is the --print template that writes that line
+const ytDlpPrintFilepath = "after_move:" + ytDlpFileMarker + "%(filepath)s"
+
+// ytDlpPlaylistMarker starts the lines that carry a playlist's title, printed
+// with each entry; yt-dlp prints NA when the site has none
+const ytDlpPlaylistMarker = "mmmeld-playlist:"
+
+// ytDlpPrintPlaylistTitle is the --print template that writes those lines
+const ytDlpPrintPlaylistTitle = "after_move:" + ytDlpPlaylistMarker + "%(playlist_title)s"
+
+// ytDlpLegacyPath matches the lines older yt-dlp versions, or runs where
+// --print output is lost, report files on, capturing the path whole so titles
+// with spaces survive
+var ytDlpLegacyPath = regexp.MustCompile(`^\[\w+\] (?:Destination: (.+)|(.+) has already been downloaded|Merging formats into "(.+)")$`)
+
+// parseYtDlpFilepath returns the downloaded file yt-dlp's output names, or ""
+// if none with one of exts is found. The marked --print line wins; otherwise
+// the last legacy Destination/already-downloaded/merge line is used.
+func parseYtDlpFilepath(output []byte, exts ...string) string {
+	if printed := parseYtDlpFilepaths(output, exts...); len(printed) > 0 {
+		return printed[len(printed)-1]
+	}
+
+	var legacy string
+	for _, line := range strings.Split(string(output), "\n") {
+		if m := ytDlpLegacyPath.FindStringSubmatch(strings.TrimRight(line, "\r")); m != nil {
+			path := m[1] + m[2] + m[3]
+			if hasExt(path, exts) {
+				legacy = path
 			}
 		}
 	}
+	return legacy
+}
 
-	if downloadedFile == "" {
-		// Fallback: look for any .mp3 file in temp folder
-		files, err := filepath.Glob(filepath.Join(config.TempAssetsFolder, fmt.Sprintf("%s_*.mp3", runPrefix)))
-		if err != nil || len(files) == 0 {
-			return "", fmt.Errorf("could not find downloaded audio file")
+// parseYtDlpFilepaths returns every file with one of exts the marked --print
+// lines name, in the order yt-dlp finished them
+func parseYtDlpFilepaths(output []byte, exts ...string) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, path := range ytDlpPrinted(output, ytDlpFileMarker) {
+		if hasExt(path, exts) && !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
 		}
-		downloadedFile = files[len(files)-1] // Get the most recent
 	}
+	return paths
+}
 
-	cleanup.Add(downloadedFile)
-	log.Printf("Downloaded YouTube audio: %s", downloadedFile)
+// ytDlpPrinted returns the rest of each output line that starts with marker
+func ytDlpPrinted(output []byte, marker string) []string {
+	var values []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimRight(line, "\r"), marker); ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
 
-	return downloadedFile, nil
+// hasExt reports whether path ends in one of exts, ignoring case
+func hasExt(path string, exts []string) bool {
+	for _, ext := range exts {
+		if strings.EqualFold(filepath.Ext(path), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// globRunDownload is the last resort when yt-dlp's output names no file: the
+// last file of this run in the temp folder, by name, with one of exts
+func globRunDownload(runPrefix string, exts ...string) string {
+	for _, ext := range exts {
+		files, err := filepath.Glob(filepath.Join(config.TempAssetsFolder, runPrefix+"_*"+ext))
+		if err == nil && len(files) > 0 {
+			return files[len(files)-1]
+		}
+	}
+	return ""
+}
+
+// globRunDownloads is globRunDownload for playlists: every file with prefix
+// and one of exts, sorted by name and so by entry number
+func globRunDownloads(prefix string, exts ...string) []string {
+	var files []string
+	for _, ext := range exts {
+		matches, _ := filepath.Glob(filepath.Join(config.TempAssetsFolder, prefix+"_*"+ext))
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// downloadLogStep is how far, in percent, a download advances between
+// progress lines at the default log level, as for ffmpeg encodes
+const downloadLogStep = 25
+
+// Downloads of unknown size log every unknownSizeLogStep bytes; known sizes
+// under minLoggedDownload finish too quickly to be worth progress lines
+const (
+	unknownSizeLogStep = 10 << 20
+	minLoggedDownload  = 8 << 20
+)
+
+// progressLog logs a download's progress every downloadLogStep percent, so
+// long downloads are not silent outside --json
+type progressLog struct {
+	label   string
+	last    int
+	nextLog int
+}
+
+func newProgressLog(label string) *progressLog {
+	return &progressLog{label: label, last: -1, nextLog: downloadLogStep}
+}
+
+// update records percent, reporting whether it advanced. A lower percent
+// than the last starts over: yt-dlp moved on to another file, such as the
+// next playlist entry.
+func (p *progressLog) update(percent int) bool {
+	if percent < p.last {
+		p.last, p.nextLog = -1, downloadLogStep
+	}
+	if percent <= p.last {
+		return false
+	}
+	p.last = percent
+	if percent >= p.nextLog {
+		logx.Infof("%s: %d%%", p.label, percent)
+		for p.nextLog <= percent {
+			p.nextLog += downloadLogStep
+		}
+	}
+	return true
+}
+
+// progressReader logs an HTTP download's progress as it is read
+type progressReader struct {
+	r         io.Reader
+	total     int64
+	read      int64
+	log       *progressLog
+	nextBytes int64 // Unknown size: when to log next
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+	switch {
+	case p.total > 0:
+		p.log.update(int(min(100, p.read*100/p.total)))
+	case p.read >= p.nextBytes:
+		logx.Infof("%s: %d MiB", p.log.label, p.read>>20)
+		p.nextBytes += unknownSizeLogStep
+	}
+	return n, err
 }
 
-// DownloadYouTubeVideo downloads video from a YouTube URL using yt-dlp
-func DownloadYouTubeVideo(url string, cleanup *CleanupManager) (string, error) {
+// downloadProgress wraps an HTTP response body so reading it reports progress:
+// download_progress events for --json, and log lines for large or unknown-size
+// downloads
+func downloadProgress(body io.Reader, total int64, safeURL string) io.Reader {
+	body = events.Progress(body, total, "download_progress", events.Fields{"url": safeURL})
+	if total > 0 && total < minLoggedDownload {
+		return body
+	}
+	return &progressReader{r: body, total: total, log: newProgressLog("Downloading " + safeURL), nextBytes: unknownSizeLogStep}
+}
+
+// ytDlpPercent matches yt-dlp's "[download]  42.0% of ..." progress lines
+var ytDlpPercent = regexp.MustCompile(`^\[download\]\s+(\d+(?:\.\d+)?)%`)
+
+// ytDlpItem matches the line yt-dlp starts each playlist entry with
+var ytDlpItem = regexp.MustCompile(`^\[download\] Downloading (?:item|video) (\d+) of (\d+)`)
+
+// ytDlpOutput collects yt-dlp's combined output as it streams in, turning its
+// progress lines into download_progress events and log lines
+type ytDlpOutput struct {
+	buf     bytes.Buffer
+	partial []byte
+	url     string
+	log     *progressLog
+}
+
+func (o *ytDlpOutput) Write(p []byte) (int, error) {
+	o.buf.Write(p)
+	o.partial = append(o.partial, p...)
+	for {
+		i := bytes.IndexAny(o.partial, "\r\n")
+		if i < 0 {
+			break
+		}
+		if m := ytDlpPercent.FindSubmatch(o.partial[:i]); m != nil {
+			percent, _ := strconv.ParseFloat(string(m[1]), 64)
+			if o.log.update(int(percent)) {
+				events.Emit("download_progress", events.Fields{"url": RedactURL(o.url), "percent": percent})
+			}
+		} else if m := ytDlpItem.FindSubmatch(o.partial[:i]); m != nil {
+			logx.Infof("Playlist entry %s of %s", m[1], m[2])
+		}
+		o.partial = o.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// runYtDlp runs cmd like CombinedOutput, reporting download progress as it goes
+func runYtDlp(cmd *exec.Cmd, url string) ([]byte, error) {
+	logx.Debugf("Running yt-dlp: %s", redactYtDlpArgs(cmd.Args))
+	out := &ytDlpOutput{url: url, log: newProgressLog("Downloading " + RedactURL(url))}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	err := cmd.Run()
+	return out.buf.Bytes(), err
+}
+
+// DownloadRemoteVideo downloads video from YouTube or any other yt-dlp-supported
+// site, reusing the download cache when the video was fetched before
+func DownloadRemoteVideo(url string, opts YtDlpOptions, cleanup *CleanupManager) (string, error) {
+	return cachedDownload(url, "video", ytDlpVideoExts, opts, cleanup, func() (string, error) {
+		return downloadRemoteVideo(url, opts, cleanup)
+	})
+}
+
+func downloadRemoteVideo(url string, opts YtDlpOptions, cleanup *CleanupManager) (string, error) {
 	if err := EnsureTempFolder(); err != nil {
 		return "", fmt.Errorf("failed to create temp folder: %w", err)
 	}
@@ -233,113 +958,703 @@ func DownloadYouTubeVideo(url string, cleanup *CleanupManager) (string, error) {
 	runPrefix := tempAssetRunNonce
 	outputTemplate := filepath.Join(config.TempAssetsFolder, fmt.Sprintf("%s_%%(title)s.%%(ext)s", runPrefix))
 
-	cmd := exec.Command("yt-dlp",
-		"--format", "best[ext=mp4]/best",
+	args := append([]string{}, ytDlpVideoFormat...)
+	args = append(args,
+		"--no-playlist",
+		"--newline",
+		"--progress",
+		"--print", ytDlpPrintFilepath,
+		"--no-simulate",
 		"--output", outputTemplate,
-		url,
 	)
+	args = append(append(args, opts.args()...), url)
+	cmd, err := ytDlpCommand(opts, args)
+	if err != nil {
+		return "", err
+	}
 
-	output, err := cmd.CombinedOutput()
+	output, err := runYtDlp(cmd, url)
 	if err != nil {
 		return "", fmt.Errorf("yt-dlp failed for video: %w\nOutput: %s", err, output)
 	}
 
-	// Find the downloaded file
-	lines := strings.Split(string(output), "\n")
-	var downloadedFile string
-
-	for _, line := range lines {
-		if strings.Contains(line, "has already been downloaded") ||
-			strings.Contains(line, "Destination:") ||
-			strings.Contains(line, "[download]") && (strings.Contains(line, ".mp4") || strings.Contains(line, ".webm")) {
-			parts := strings.Fields(line)
-			for _, part := range parts {
-				if (strings.HasSuffix(part, ".mp4") || strings.HasSuffix(part, ".webm")) &&
-					strings.Contains(part, config.TempAssetsFolder) {
-					downloadedFile = part
-					break
+	downloadedFile := parseYtDlpFilepath(output, ytDlpVideoExts...)
+	if downloadedFile == "" {
+		downloadedFile = globRunDownload(runPrefix, ytDlpVideoExts...)
+	}
+	if downloadedFile == "" {
+		return "", fmt.Errorf("could not find downloaded video file")
+	}
+
+	cleanup.Add(downloadedFile)
+	logx.Infof("Downloaded remote video: %s", downloadedFile)
+
+	return downloadedFile, nil
+}
+
+// downloadCache keeps yt-dlp downloads between runs, one directory per video
+// and kind holding the file under its downloaded name, so iterating on a
+// render does not fetch the same music again. A nil cache is valid and never
+// hits.
+type downloadCache struct {
+	dir string
+}
+
+// DefaultDownloadCacheDir returns the default download cache location (~/.cache/mmmeld/yt on Linux)
+func DefaultDownloadCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "mmmeld", "yt"), nil
+}
+
+// openDownloadCache opens the cache opts select, pruning entries unused for
+// opts.CacheTTL; with opts.NoCache it returns nil
+func openDownloadCache(opts YtDlpOptions) (*downloadCache, error) {
+	if opts.NoCache {
+		return nil, nil
+	}
+	dir := opts.CacheDir
+	if dir == "" {
+		var err error
+		dir, err = DefaultDownloadCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate cache directory: %w", err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if opts.CacheTTL > 0 {
+		if removed, freed, err := PruneDownloadCache(dir, opts.CacheTTL); err == nil && removed > 0 {
+			logx.Debugf("Pruned %d stale cached download(s), %.1f MB", removed, float64(freed)/(1<<20))
+		}
+	}
+	return &downloadCache{dir: dir}, nil
+}
+
+// youtubeVideoID matches the ID in watch, youtu.be, shorts, embed, and live links
+var youtubeVideoID = regexp.MustCompile(`(?:[?&]v=|youtu\.be/|/(?:shorts|embed|live|v)/)([A-Za-z0-9_-]{11})(?:[^A-Za-z0-9_-]|$)`)
+
+// unsafeKeyChars are replaced in cache keys, which become directory names
+var unsafeKeyChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// ytDlpIDMarker starts the line videoKey has yt-dlp print
+const ytDlpIDMarker = "mmmeld-id:"
+
+// videoKey identifies url's video as extractor-id, e.g. youtube-dQw4w9WgXcQ.
+// YouTube IDs are read from the link; other sites cost a yt-dlp metadata
+// request, which downloads nothing.
+func videoKey(url string, opts YtDlpOptions) (string, error) {
+	if IsYouTubeURL(url) {
+		if m := youtubeVideoID.FindStringSubmatch(url); m != nil {
+			return "youtube-" + m[1], nil
+		}
+	}
+
+	args := []string{"--no-playlist", "--skip-download", "--print", ytDlpIDMarker + "%(extractor_key)s %(id)s"}
+	args = append(append(args, opts.args()...), url)
+	cmd, err := ytDlpCommand(opts, args)
+	if err != nil {
+		return "", err
+	}
+	logx.Debugf("Running yt-dlp: %s", redactYtDlpArgs(cmd.Args))
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("yt-dlp could not identify the video: %w", err)
+	}
+	printed := ytDlpPrinted(output, ytDlpIDMarker)
+	if len(printed) == 0 {
+		return "", errors.New("yt-dlp printed no video ID")
+	}
+	extractor, id, _ := strings.Cut(printed[0], " ")
+	if id == "" || id == "NA" {
+		return "", errors.New("yt-dlp printed no video ID")
+	}
+	return strings.ToLower(unsafeKeyChars.ReplaceAllString(extractor, "_")) + "-" + unsafeKeyChars.ReplaceAllString(id, "_"), nil
+}
+
+// entry returns the directory for key's kind ("audio" or "video"). Extra
+// yt-dlp arguments can change what is downloaded, so they get entries of
+// their own.
+func (c *downloadCache) entry(key, kind string, extraArgs []string) string {
+	name := key + "-" + kind
+	if len(extraArgs) > 0 {
+		sum := sha256.Sum256([]byte(strings.Join(extraArgs, "\x00")))
+		name += "-" + hex.EncodeToString(sum[:])[:8]
+	}
+	return filepath.Join(c.dir, name)
+}
+
+// lookup returns the cached file in entry with one of exts, refreshing the
+// entry's modification time so the TTL counts from its last use
+func (c *downloadCache) lookup(entry string, exts []string) (string, bool) {
+	files, err := os.ReadDir(entry)
+	if err != nil {
+		return "", false
+	}
+	for _, f := range files {
+		if !f.IsDir() && hasExt(f.Name(), exts) {
+			now := time.Now()
+			os.Chtimes(entry, now, now)
+			return filepath.Join(entry, f.Name()), true
+		}
+	}
+	return "", false
+}
+
+// store files a fresh download under entry, dropping the run prefix from its
+// name. The entry is assembled aside and renamed into place, so a parallel
+// run never sees it half written.
+func (c *downloadCache) store(entry, path string) error {
+	tmp := entry + ".tmp-" + tempAssetRunNonce
+	os.RemoveAll(tmp)
+	if err := os.MkdirAll(tmp, 0755); err != nil {
+		return err
+	}
+	name := strings.TrimPrefix(filepath.Base(path), tempAssetRunNonce+"_")
+	if err := linkOrCopy(path, filepath.Join(tmp, name)); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	os.RemoveAll(entry)
+	if err := os.Rename(tmp, entry); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	return nil
+}
+
+// linkOrCopy hard-links src to dst, copying when the two are on different
+// file systems or links are unsupported. An existing dst is replaced rather
+// than written through, since it may be a link to a cached file.
+func linkOrCopy(src, dst string) error {
+	os.Remove(dst)
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	return CopyFile(src, dst)
+}
+
+// cachedDownload returns url's cached kind download, placed in the temp
+// folder under this run's prefix, or runs download and caches its result.
+// Cache failures only cost the reuse; the download itself still happens.
+func cachedDownload(url, kind string, exts []string, opts YtDlpOptions, cleanup *CleanupManager, download func() (string, error)) (string, error) {
+	cache, err := openDownloadCache(opts)
+	if err != nil {
+		logx.Warnf("Download cache unavailable: %v", err)
+	}
+	if cache == nil {
+		return download()
+	}
+	key, err := videoKey(url, opts)
+	if err != nil {
+		logx.Debugf("Not caching %s: %v", RedactURL(url), err)
+		return download()
+	}
+
+	entry := cache.entry(key, kind, opts.ExtraArgs)
+	if cached, ok := cache.lookup(entry, exts); ok {
+		if err := EnsureTempFolder(); err != nil {
+			return "", fmt.Errorf("failed to create temp folder: %w", err)
+		}
+		dest := filepath.Join(config.TempAssetsFolder, tempAssetRunNonce+"_"+filepath.Base(cached))
+		if err := linkOrCopy(cached, dest); err == nil {
+			cleanup.Add(dest)
+			logx.Infof("Reusing cached download of %s: %s", RedactURL(url), dest)
+			return dest, nil
+		}
+		logx.Warnf("Failed to reuse cached download %s: %v", cached, err)
+	}
+
+	path, err := download()
+	if err != nil {
+		return "", err
+	}
+	if err := cache.store(entry, path); err != nil {
+		logx.Warnf("Failed to cache download %s: %v", path, err)
+	}
+	return path, nil
+}
+
+// PruneDownloadCache deletes cached downloads unused for longer than maxAge.
+// An empty dir uses DefaultDownloadCacheDir. It returns the entries and bytes
+// removed.
+func PruneDownloadCache(dir string, maxAge time.Duration) (int, int64, error) {
+	if dir == "" {
+		var err error
+		dir, err = DefaultDownloadCacheDir()
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to locate cache directory: %w", err)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+
+	removed := 0
+	var freed int64
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || time.Since(info.ModTime()) <= maxAge {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		size := info.Size()
+		if e.IsDir() {
+			size = 0
+			filepath.WalkDir(path, func(_ string, d os.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					if fi, err := d.Info(); err == nil {
+						size += fi.Size()
+					}
 				}
-			}
+				return nil
+			})
+		}
+		if err := os.RemoveAll(path); err != nil {
+			continue
 		}
+		removed++
+		freed += size
+		logx.Debugf("Evicted cached download: %s", path)
+	}
+	return removed, freed, nil
+}
+
+// ErrInvalidImage marks downloaded data that is empty, truncated, or not an
+// image (e.g. an HTML error page served with status 200).
+var ErrInvalidImage = errors.New("invalid image data")
+
+// SniffImageFormat identifies PNG, JPEG, WebP, and GIF data by its magic
+// number, returning "" for anything else
+func SniffImageFormat(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return "png"
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8, 0xFF}):
+		return "jpeg"
+	case len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WEBP":
+		return "webp"
+	case bytes.HasPrefix(head, []byte("GIF87a")), bytes.HasPrefix(head, []byte("GIF89a")):
+		return "gif"
 	}
+	return ""
+}
 
-	if downloadedFile == "" {
-		// Fallback: look for video files in temp folder
-		patterns := []string{
-			fmt.Sprintf("%s_*.mp4", runPrefix),
-			fmt.Sprintf("%s_*.webm", runPrefix),
-			fmt.Sprintf("%s_*.mkv", runPrefix),
-		}
-		for _, pattern := range patterns {
-			files, err := filepath.Glob(filepath.Join(config.TempAssetsFolder, pattern))
-			if err == nil && len(files) > 0 {
-				downloadedFile = files[len(files)-1]
-				break
-			}
+// SniffAudioFormat identifies WAV, MP3, Ogg, FLAC, and MP4 (m4a) audio by its
+// magic number, returning the usual file extension without the dot, or "" for
+// anything else
+func SniffAudioFormat(head []byte) string {
+	switch {
+	case len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WAVE":
+		return "wav"
+	case bytes.HasPrefix(head, []byte("ID3")), len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0:
+		return "mp3"
+	case bytes.HasPrefix(head, []byte("OggS")):
+		return "ogg"
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return "flac"
+	case len(head) >= 8 && string(head[4:8]) == "ftyp":
+		return "m4a"
+	}
+	return ""
+}
+
+// peekImage checks the start of r for an image magic number and returns a
+// reader that still yields the peeked bytes, along with the detected format
+func peekImage(r io.Reader) (io.Reader, string, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(12)
+	if len(head) == 0 {
+		if err != nil && err != io.EOF {
+			return nil, "", fmt.Errorf("failed to read image: %w", err)
 		}
+		return nil, "", fmt.Errorf("%w: empty response", ErrInvalidImage)
 	}
+	format := SniffImageFormat(head)
+	if format == "" {
+		snippet, _ := br.Peek(80)
+		return nil, "", fmt.Errorf("%w: unrecognized data starting with %q", ErrInvalidImage, snippet)
+	}
+	return br, format, nil
+}
 
-	if downloadedFile == "" {
-		return "", fmt.Errorf("could not find downloaded video file")
+// SaveImage writes image data from r to path after checking its magic number.
+// When size is non-negative, the number of bytes written must match it. On
+// failure the partial file is removed and the error wraps ErrInvalidImage if
+// the data itself was bad.
+func SaveImage(r io.Reader, size int64, path string) error {
+	r, _, err := peekImage(r)
+	if err != nil {
+		return err
 	}
 
-	cleanup.Add(downloadedFile)
-	log.Printf("Downloaded YouTube video: %s", downloadedFile)
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create image file: %w", err)
+	}
+	written, err := io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && size >= 0 && written != size {
+		err = fmt.Errorf("%w: got %d of %d bytes", ErrInvalidImage, written, size)
+	}
+	if err != nil {
+		os.Remove(path)
+		if errors.Is(err, ErrInvalidImage) {
+			return err
+		}
+		return fmt.Errorf("failed to save image: %w", err)
+	}
+	return nil
+}
 
-	return downloadedFile, nil
+// RedactURL hides any password embedded in a URL so it can be logged
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return rawURL
+	}
+	return u.Redacted()
 }
 
-// DownloadImage downloads an image from a URL
-func DownloadImage(url string, cleanup *CleanupManager) (string, error) {
-	if err := EnsureTempFolder(); err != nil {
-		return "", fmt.Errorf("failed to create temp folder: %w", err)
+// HTTP download limits. Bodies have no overall deadline, since a large audio
+// file can take minutes; a download that receives nothing, headers or body,
+// for downloadStallTimeout is cancelled instead.
+const (
+	downloadStallTimeout = 60 * time.Second
+	maxDownloadRedirects = 10
+	downloadRetries      = 3
+	downloadRetryDelay   = 2 * time.Second
+)
+
+// HTTPClient returns the client for image and audio downloads: the shared
+// httpx transport, with its proxy and connection timeouts, and at most
+// maxDownloadRedirects redirects. Download adds retries, the stall timeout,
+// and the size cap.
+func HTTPClient() *http.Client {
+	return &http.Client{Transport: httpx.Transport(), CheckRedirect: limitRedirects}
+}
+
+func limitRedirects(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxDownloadRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxDownloadRedirects)
 	}
+	return nil
+}
+
+// StatusError is a download answered with a status other than 200 OK
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("HTTP %d", e.StatusCode)
+}
+
+// ErrTooLarge marks a download over config.MaxDownloadSize
+var ErrTooLarge = errors.New("download too large")
+
+// errStalled marks a body that sent nothing for downloadStallTimeout
+var errStalled = fmt.Errorf("download stalled: no data for %s", downloadStallTimeout)
+
+// sleep is a variable so tests can skip the retry backoff
+var sleep = time.Sleep
+
+// Download GETs req with client (HTTPClient() if nil) and hands save the
+// response and its body. Connection failures, stalls, cut-off bodies, and 5xx
+// and 429 responses are retried up to downloadRetries times with backoff, so
+// save must start over on each call. A body over
+// config.MaxDownloadSize fails with ErrTooLarge.
+func Download(req *http.Request, client *http.Client, save func(resp *http.Response, body io.Reader) error) error {
+	if client == nil {
+		client = HTTPClient()
+	}
+	safeURL := RedactURL(req.URL.String())
+	delay := downloadRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := downloadOnce(req, client, safeURL, save)
+		if err == nil || attempt > downloadRetries || !isTransient(err) {
+			return err
+		}
+		logx.Warnf("Download of %s failed (%v); retrying in %s (%d/%d)", safeURL, err, delay, attempt, downloadRetries)
+		sleep(delay)
+		delay *= 2
+	}
+}
+
+func downloadOnce(req *http.Request, client *http.Client, safeURL string, save func(*http.Response, io.Reader) error) error {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+	body := &watchedBody{}
+	body.timer = time.AfterFunc(downloadStallTimeout, func() {
+		body.stalled.Store(true)
+		cancel()
+	})
+	defer body.timer.Stop()
 
-	resp, err := http.Get(url)
+	resp, err := client.Do(req.WithContext(ctx))
 	if err != nil {
-		return "", fmt.Errorf("failed to download image: %w", err)
+		if body.stalled.Load() {
+			return errStalled
+		}
+		return err // net/http already strips passwords from URLs in its errors
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to download image: HTTP %d", resp.StatusCode)
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+	if resp.ContentLength > config.MaxDownloadSize {
+		return errTooLarge()
+	}
+
+	body.r = resp.Body
+	return save(resp, downloadProgress(body, resp.ContentLength, safeURL))
+}
+
+func errTooLarge() error {
+	return fmt.Errorf("%w: over the %d MB limit (--max-download-size)", ErrTooLarge, config.MaxDownloadSize>>20)
+}
+
+// watchedBody restarts the stall timer on every read and stops the body at
+// config.MaxDownloadSize
+type watchedBody struct {
+	r       io.Reader
+	read    int64
+	timer   *time.Timer
+	stalled atomic.Bool
+}
+
+func (b *watchedBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if b.stalled.Load() {
+		return n, errStalled
+	}
+	b.timer.Reset(downloadStallTimeout)
+	b.read += int64(n)
+	if b.read > config.MaxDownloadSize {
+		return n, errTooLarge()
 	}
+	return n, err
+}
 
-	// Determine file extension from content type or URL
-	ext := ".jpg" // default
-	contentType := resp.Header.Get("Content-Type")
-	switch contentType {
-	case "image/png":
-		ext = ".png"
-	case "image/gif":
-		ext = ".gif"
-	case "image/webp":
-		ext = ".webp"
-	default:
-		// Try to get extension from URL
-		if urlExt := filepath.Ext(url); urlExt != "" {
-			ext = urlExt
+// isTransient reports whether a failed download may succeed if retried
+func isTransient(err error) bool {
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.StatusCode >= 500 || status.StatusCode == http.StatusTooManyRequests
+	}
+	var netErr net.Error
+	return errors.Is(err, errStalled) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// DownloadImage fetches an image into the temp folder. headers (which may be
+// nil) and basic auth embedded in the URL are sent with the request and kept
+// across same-host redirects only; neither is ever logged.
+func DownloadImage(rawURL string, headers http.Header, cleanup *CleanupManager) (string, error) {
+	if err := EnsureTempFolder(); err != nil {
+		return "", fmt.Errorf("failed to create temp folder: %w", err)
+	}
+
+	safeURL := RedactURL(rawURL)
+	req, err := newImageRequest(rawURL, headers)
+	if err != nil {
+		return "", fmt.Errorf("failed to download image from %s: invalid URL", safeURL)
+	}
+
+	client := HTTPClient()
+	client.CheckRedirect = sameHostAuthRedirect(req.URL.Host, headers)
+	var imagePath string
+	err = Download(req, client, func(resp *http.Response, body io.Reader) error {
+		// The extension follows the actual bytes, not the Content-Type or URL
+		body, format, err := peekImage(body)
+		if err != nil {
+			return err
 		}
+		ext := "." + format
+		if format == "jpeg" {
+			ext = ".jpg"
+		}
+		imagePath = filepath.Join(config.TempAssetsFolder, fmt.Sprintf("downloaded_image_%d%s", time.Now().UnixNano(), ext))
+		return SaveImage(body, resp.ContentLength, imagePath)
+	})
+	var status *StatusError
+	switch {
+	case errors.As(err, &status) && (status.StatusCode == http.StatusUnauthorized || status.StatusCode == http.StatusForbidden):
+		return "", fmt.Errorf("failed to download image from %s: %w (check the URL credentials or --image-header)", safeURL, err)
+	case err != nil:
+		return "", fmt.Errorf("failed to download image from %s: %w", safeURL, err)
 	}
 
-	filename := fmt.Sprintf("downloaded_image_%d%s", time.Now().UnixNano(), ext)
-	filepath := filepath.Join(config.TempAssetsFolder, filename)
+	cleanup.Add(imagePath)
+	logx.Infof("Downloaded image: %s", imagePath)
 
-	file, err := os.Create(filepath)
+	return imagePath, nil
+}
+
+// newImageRequest builds a GET for rawURL, moving any user:password in the
+// URL into a basic auth header so it is not carried in the request URL
+func newImageRequest(rawURL string, headers http.Header) (*http.Request, error) {
+	u, err := url.Parse(rawURL)
 	if err != nil {
-		return "", fmt.Errorf("failed to create image file: %w", err)
+		return nil, err
 	}
-	defer file.Close()
+	user := u.User
+	u.User = nil
 
-	_, err = io.Copy(file, resp.Body)
+	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to save image: %w", err)
+		return nil, err
+	}
+	for name, values := range headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
 	}
+	if user != nil {
+		password, _ := user.Password()
+		req.SetBasicAuth(user.Username(), password)
+	}
+	return req, nil
+}
+
+// sameHostAuthRedirect follows redirects but strips Authorization and the
+// custom headers once a redirect leaves the original host
+func sameHostAuthRedirect(host string, headers http.Header) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if err := limitRedirects(req, via); err != nil {
+			return err
+		}
+		if req.URL.Host != host {
+			req.Header.Del("Authorization")
+			for name := range headers {
+				req.Header.Del(name)
+			}
+		}
+		return nil
+	}
+}
 
-	cleanup.Add(filepath)
-	log.Printf("Downloaded image: %s", filepath)
+// ReadInputList reads a media list file: one path, URL, or "generate" entry
+// per line, with blank lines and "#" comment lines ignored. Relative paths
+// resolve against the list file's directory.
+func ReadInputList(listPath string) ([]string, error) {
+	data, err := os.ReadFile(listPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input list: %w", err)
+	}
 
-	return filepath, nil
+	dir := filepath.Dir(listPath)
+	var entries []string
+	for _, line := range strings.Split(string(data), "\n") {
+		entry := strings.TrimSpace(line)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		if !strings.EqualFold(entry, "generate") && !strings.Contains(entry, "://") && !filepath.IsAbs(entry) {
+			entry = filepath.Join(dir, entry)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("input list %s has no entries", listPath)
+	}
+	return entries, nil
+}
+
+// DownloadAudio downloads an audio file from a direct HTTP(S) URL into the temp folder
+func DownloadAudio(rawURL string, cleanup *CleanupManager) (string, error) {
+	if err := EnsureTempFolder(); err != nil {
+		return "", fmt.Errorf("failed to create temp folder: %w", err)
+	}
+
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to download audio: %w", err)
+	}
+
+	var audioPath string
+	err = Download(req, nil, func(resp *http.Response, body io.Reader) error {
+		ext := audioExtension(resp.Header.Get("Content-Type"), rawURL)
+		filename := fmt.Sprintf("%s_%s%s", tempAssetRunNonce, audioBaseName(rawURL), ext)
+		audioPath = filepath.Join(config.TempAssetsFolder, filename)
+
+		file, err := os.Create(audioPath)
+		if err != nil {
+			return fmt.Errorf("failed to create audio file: %w", err)
+		}
+		_, err = io.Copy(file, body)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(audioPath)
+			return fmt.Errorf("failed to save audio: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to download audio: %w", err)
+	}
+
+	cleanup.Add(audioPath)
+	logx.Infof("Downloaded audio: %s", audioPath)
+
+	return audioPath, nil
+}
+
+// audioExtension picks a file extension from the response content type,
+// falling back to the URL path and finally to .mp3
+func audioExtension(contentType, rawURL string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		switch mediaType {
+		case "audio/mpeg", "audio/mp3":
+			return ".mp3"
+		case "audio/wav", "audio/x-wav", "audio/wave":
+			return ".wav"
+		case "audio/ogg":
+			return ".ogg"
+		case "audio/flac", "audio/x-flac":
+			return ".flac"
+		case "audio/aac":
+			return ".aac"
+		case "audio/mp4", "audio/x-m4a":
+			return ".m4a"
+		case "audio/webm":
+			return ".webm"
+		}
+	}
+
+	if u, err := url.Parse(rawURL); err == nil {
+		if ext := strings.ToLower(filepath.Ext(u.Path)); ext != "" {
+			return ext
+		}
+	}
+
+	return ".mp3"
+}
+
+// audioBaseName derives a safe base filename from the last URL path segment
+func audioBaseName(rawURL string) string {
+	name := "downloaded_audio"
+	if u, err := url.Parse(rawURL); err == nil {
+		base := filepath.Base(u.Path)
+		base = strings.TrimSuffix(base, filepath.Ext(base))
+		if base != "" && base != "." && base != "/" {
+			name = base
+		}
+	}
+	return SanitizeFilename(name)
 }
 
 // GetMultilineInput reads multiline input from stdin (for interactive mode)
@@ -364,3 +1679,77 @@ func FileExists(filename string) bool {
 	_, err := os.Stat(filename)
 	return err == nil
 }
+
+// CopyFile copies src to dst, creating or truncating dst
+func CopyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy %s to %s: %w", src, dst, err)
+	}
+	return out.Close()
+}
+
+// PruneCache deletes files in a cache directory older than maxAge (0 = no age
+// limit), then the least recently used ones until the directory fits maxSize
+// bytes. Temp files younger than an hour are another run's store in progress
+// and are left alone; older ones are a crashed run's leftovers. kind names
+// the entries in debug output. It returns the files and bytes removed.
+func PruneCache(dir string, maxSize int64, maxAge time.Duration, kind string) (int, int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+
+	type cached struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []cached
+	var total int64
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		if strings.HasSuffix(e.Name(), ".tmp") && time.Since(info.ModTime()) < time.Hour {
+			continue
+		}
+		files = append(files, cached{filepath.Join(dir, e.Name()), info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	removed := 0
+	var freed int64
+	for _, f := range files {
+		expired := maxAge > 0 && time.Since(f.modTime) > maxAge
+		if !expired && total <= maxSize {
+			break
+		}
+		if err := os.Remove(f.path); err != nil {
+			continue
+		}
+		total -= f.size
+		freed += f.size
+		removed++
+		logx.Debugf("Evicted cached %s: %s", kind, f.path)
+	}
+	return removed, freed, nil
+}
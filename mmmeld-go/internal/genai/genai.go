@@ -5,16 +5,29 @@ package genai
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
+
+	"mmmeld/internal/ffmpeg"
+	"mmmeld/internal/fileutil"
+	"mmmeld/internal/httpx"
+	"mmmeld/internal/logx"
+	"mmmeld/internal/probe"
+	"mmmeld/internal/retry"
 
 	"google.golang.org/genai"
 )
@@ -23,19 +36,173 @@ const (
 	// DefaultModel is the default Gemini model for audio analysis
 	// Use gemini-3-pro-preview for best quality analysis
 	DefaultModel = "models/gemini-3-pro-preview"
+
+	// DefaultValidationModel is the faster model that checks generated images;
+	// a failed call is retried once with DefaultModel
+	DefaultValidationModel = "models/gemini-2.5-flash"
+
+	// TitleModel and OpenAITitleModel are fast models for short text tasks like titles
+	TitleModel       = "models/gemini-2.5-flash"
+	OpenAITitleModel = "gpt-5-mini"
+
+	// DefaultReviewModel is the OpenAI model for the second-opinion prompt
+	// review and the OpenAI validation fallbacks; override with --review-model
+	// or MMMELD_REVIEW_MODEL
+	DefaultReviewModel = "gpt-5.2-pro"
+
+	// DefaultAnthropicReviewModel is the Claude model used by --reviewer anthropic
+	DefaultAnthropicReviewModel = "claude-sonnet-4-5"
+
+	// defaultAnthropicBaseURL is used unless ANTHROPIC_BASE_URL points
+	// elsewhere (e.g. a proxy)
+	defaultAnthropicBaseURL = "https://api.anthropic.com/v1"
+	anthropicMessagesPath   = "/messages"
+	anthropicVersion        = "2023-06-01"
+	anthropicMaxTokens      = 4096
+
+	// defaultOpenAIBaseURL is used unless OPENAI_BASE_URL points elsewhere
+	// (e.g. Azure OpenAI or a proxy)
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+	openAIResponsesPath  = "/responses"
+
+	// openAIVisionMaxTokens caps the reply to an OpenAI request with an image
+	openAIVisionMaxTokens = 1000
+
+	// reviewerTimeout bounds each OpenAI or Anthropic reviewer request;
+	// ollamaTimeout is longer, since local models on modest hardware can
+	// take minutes per reply
+	reviewerTimeout = 120 * time.Second
+	ollamaTimeout   = 10 * time.Minute
+
+	// titleTimeout bounds each title request
+	titleTimeout = 60 * time.Second
+
+	// DefaultOllamaModel is the local model used by --reviewer ollama and
+	// --llm ollama unless OLLAMA_MODEL or --review-model/--llm-model is set
+	DefaultOllamaModel = "llama3.1"
+
+	// defaultOllamaHost is used unless OLLAMA_HOST points elsewhere
+	defaultOllamaHost = "http://localhost:11434"
+
+	// ImagenModel is the Imagen model used by the imagen image provider
+	ImagenModel = "imagen-4.0-generate-001"
+
+	// maxTitleInput bounds how much text is sent when generating a title
+	maxTitleInput = 4000
+
+	// DefaultBriefTemperature and DefaultPromptTemperature are the sampling
+	// temperatures of pass 1 (audio brief) and pass 2 (image prompt)
+	DefaultBriefTemperature  = 0.7
+	DefaultPromptTemperature = 0.8
+
+	// DefaultQuotaRetries is how many times a Gemini quota error is retried
+	// before falling back to the reviewer
+	DefaultQuotaRetries = 3
+
+	// DefaultValidationConcurrency bounds ValidateImages when it is given no
+	// concurrency
+	DefaultValidationConcurrency = 4
+
+	// quotaRetryBaseDelay doubles on each retry unless Gemini's RetryInfo
+	// asks for a specific delay; no wait exceeds quotaRetryMaxDelay
+	quotaRetryBaseDelay = 5 * time.Second
+	quotaRetryMaxDelay  = 2 * time.Minute
+
+	// LargeAudioBytes and LongAudioSeconds are the size and duration above
+	// which audio is transcoded to mono 64 kbps AAC before upload
+	LargeAudioBytes  = 100 << 20
+	LongAudioSeconds = 20 * 60
+
+	// probeAudioBytes is the size above which the duration is probed; smaller
+	// files cannot be long enough to matter
+	probeAudioBytes = 20 << 20
+
+	// processingTimeout grows from processingBaseTimeout by
+	// processingTimeoutStep per processingTimeoutBytes uploaded, up to
+	// processingMaxTimeout
+	processingBaseTimeout  = 2 * time.Minute
+	processingTimeoutStep  = time.Minute
+	processingTimeoutBytes = 50 << 20
+	processingMaxTimeout   = 15 * time.Minute
 )
 
 // ANSI color codes for terminal output
 const (
-	colorReset  = "\033[0m"
-	colorYellow = "\033[33m"
-	colorRed    = "\033[31m"
+	colorReset = "\033[0m"
+	colorRed   = "\033[31m"
+)
+
+// Logger receives the package's log output, for embedding genai in a service
+// with its own structured logging
+type Logger interface {
+	Infof(format string, v ...interface{}) // Progress and debug output
+	Warnf(format string, v ...interface{}) // Recoverable problems and fallbacks
+}
+
+// Progress stages reported to a Progress callback
+const (
+	StageUpload   = "upload"
+	StageLyrics   = "lyrics"
+	StageBrief    = "brief"
+	StagePrompt   = "prompt"
+	StageReview   = "review"
+	StageValidate = "validate"
 )
 
-// logWarning logs a warning message in yellow
-func logWarning(format string, v ...interface{}) {
+// stdLogger is the default Logger: logx, so --quiet and --log-level apply.
+// Quiet drops info output.
+type stdLogger struct {
+	quiet bool
+}
+
+func (l stdLogger) Infof(format string, v ...interface{}) {
+	if !l.quiet {
+		logx.Infof(format, v...)
+	}
+}
+
+func (l stdLogger) Warnf(format string, v ...interface{}) {
+	logx.Warnf(format, v...)
+}
+
+// NopLogger discards all output, warnings included
+type NopLogger struct{}
+
+func (NopLogger) Infof(format string, v ...interface{}) {}
+func (NopLogger) Warnf(format string, v ...interface{}) {}
+
+// quietLogger drops a custom Logger's info output for Quiet runs
+type quietLogger struct {
+	Logger
+}
+
+func (quietLogger) Infof(format string, v ...interface{}) {}
+
+// defaultLogger serves calls that take no options
+var defaultLogger Logger = stdLogger{}
+
+// resolveLogger returns l, or the default Logger when l is nil, with info
+// output dropped when quiet. Warnings still get through; use NopLogger to
+// silence those too.
+func resolveLogger(l Logger, quiet bool) Logger {
+	if l == nil {
+		return stdLogger{quiet: quiet}
+	}
+	if quiet {
+		return quietLogger{l}
+	}
+	return l
+}
+
+// reportProgress passes a stage message to progress, or logs it as info when
+// no callback is set
+func reportProgress(l Logger, progress func(stage, msg string), stage, format string, v ...interface{}) {
 	msg := fmt.Sprintf(format, v...)
-	log.Printf("%sWarning: %s%s", colorYellow, msg, colorReset)
+	if progress != nil {
+		progress(stage, msg)
+		return
+	}
+	l.Infof("%s", msg)
 }
 
 // StylePreference represents the preferred visual style for generated prompts
@@ -49,6 +216,92 @@ const (
 	StyleCinematic      StylePreference = "cinematic"
 )
 
+// CasingPolicy controls how strictly validation judges the capitalization of
+// rendered caption text
+type CasingPolicy string
+
+const (
+	// CasingStrict accepts the expected casing, ALL CAPS, or all lowercase
+	CasingStrict CasingPolicy = "strict"
+	// CasingAny accepts any capitalization of correctly spelled text
+	CasingAny CasingPolicy = "any"
+	// CasingExact accepts only the expected casing, character for character
+	CasingExact CasingPolicy = "exact"
+)
+
+// ValidationOptions tunes image validation
+type ValidationOptions struct {
+	Casing      CasingPolicy  // How strictly rendered caption casing is judged
+	Reviewer    string        // Reviewer for the validation fallback: openai (default), anthropic, or none
+	ReviewModel string        // Reviewer model for the validation fallback (empty = MMMELD_REVIEW_MODEL or the reviewer's default)
+	Retries     int           // Gemini quota-error retries before falling back (0 = none)
+	NoFallback  bool          // Fail instead of falling back to the reviewer once retries are exhausted
+	Language    string        // Language of the caption text, e.g. pt-BR (empty = English)
+	Client      ClientOptions // Gemini client used by the package-level validation helpers
+	Model       string        // Gemini validation model (empty = DefaultValidationModel)
+
+	Logger   Logger                  // Receives log output (nil = the Client's, else the standard log)
+	Progress func(stage, msg string) // Receives stage announcements instead of Logger (nil = log them)
+}
+
+func (o ValidationOptions) model() string {
+	if o.Model == "" {
+		return DefaultValidationModel
+	}
+	return o.Model
+}
+
+func (o ValidationOptions) logger() Logger {
+	return resolveLogger(o.Logger, false)
+}
+
+func (o ValidationOptions) progress(stage, format string, v ...interface{}) {
+	reportProgress(o.logger(), o.Progress, stage, format, v...)
+}
+
+// reviewModel resolves the review model: an explicit choice, then
+// MMMELD_REVIEW_MODEL, then the reviewer's default
+func reviewModel(model, defaultModel string) string {
+	if model != "" {
+		return model
+	}
+	if env := os.Getenv("MMMELD_REVIEW_MODEL"); env != "" {
+		return env
+	}
+	return defaultModel
+}
+
+// openAIURL returns the URL of an OpenAI API path under OPENAI_BASE_URL,
+// defaulting to the public API
+func openAIURL(path string) string {
+	base := os.Getenv("OPENAI_BASE_URL")
+	if base == "" {
+		base = defaultOpenAIBaseURL
+	}
+	return strings.TrimRight(base, "/") + path
+}
+
+// anthropicURL returns the URL of an Anthropic API path under
+// ANTHROPIC_BASE_URL, defaulting to the public API
+func anthropicURL(path string) string {
+	base := os.Getenv("ANTHROPIC_BASE_URL")
+	if base == "" {
+		base = defaultAnthropicBaseURL
+	}
+	return strings.TrimRight(base, "/") + path
+}
+
+// setOpenAIHeaders sets authentication and content type for an OpenAI
+// request. Azure OpenAI reads the key from api-key, so it is sent there too
+// when OPENAI_BASE_URL is set.
+func setOpenAIHeaders(req *http.Request, apiKey string) {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	if os.Getenv("OPENAI_BASE_URL") != "" {
+		req.Header.Set("api-key", apiKey)
+	}
+}
+
 // PromptOptions contains options for generating an image prompt from audio
 type PromptOptions struct {
 	Title           string
@@ -58,46 +311,527 @@ type PromptOptions struct {
 	StylePreference StylePreference
 	Model           string
 	Quiet           bool
-	Debug           bool // Enable verbose debug output
+	Debug           bool               // Enable verbose debug output
+	Scenes          int                // Number of distinct scene prompts to derive from one brief (default 1)
+	CasingPolicy    CasingPolicy       // How strictly rendered caption casing is validated (default strict)
+	Reviewer        string             // Second-opinion reviewer: openai (default), anthropic, or none
+	ReviewModel     string             // Reviewer model (empty = MMMELD_REVIEW_MODEL or the reviewer's default)
+	Retries         int                // Gemini quota-error retries before falling back (0 = none)
+	NoFallback      bool               // Fail instead of falling back to the reviewer once retries are exhausted
+	Transcribe      bool               // Also transcribe the lyrics, which then inform the brief's lyric themes
+	Avoid           []string           // Extra terms merged into the brief's avoid list
+	Language        string             // Language of the caption text, e.g. pt-BR (empty = English); scenes stay English
+	SkipAudio       bool               // Write the prompt with Gemini from the title and notes alone, skipping the upload and pass 1
+	AnalyzeWindow   *probe.AudioWindow // Analyze only this part of the audio (nil = the whole file)
+
+	// Cleanup registers temporary audio transcodes for deletion, e.g. a
+	// *fileutil.CleanupManager (nil = deleted when the call returns)
+	Cleanup interface{ Add(path string) }
+	// TempPath is where an audio transcode is written, e.g. a
+	// fileutil.TempAssetPath (empty = a new file in the OS temp dir)
+	TempPath string
+
+	NoCache  bool   // Neither read nor write the prompt cache
+	Refresh  bool   // Skip cached results but store the new one
+	CacheDir string // Prompt cache location (empty = DefaultPromptCacheDir)
+
+	Logger   Logger                  // Receives log output (nil = the Client's, else the standard log); Quiet drops info output
+	Progress func(stage, msg string) // Receives stage announcements (Stage*) instead of Logger, even when Quiet
+
+	BriefTemperature  *float32 // Pass 1 sampling temperature, 0-2 (nil = DefaultBriefTemperature)
+	PromptTemperature *float32 // Pass 2 sampling temperature, 0-2 (nil = DefaultPromptTemperature)
+
+	scene    string // Per-scene focus instruction added to pass 2
+	lyrics   string // Transcribed lyrics added to pass 1
+	revision string // RevisePrompt's previous prompt and instruction added to pass 2
+}
+
+func (o PromptOptions) logger() Logger {
+	return resolveLogger(o.Logger, o.Quiet)
+}
+
+func (o PromptOptions) progress(stage, format string, v ...interface{}) {
+	reportProgress(o.logger(), o.Progress, stage, format, v...)
 }
 
 // PromptResult contains the result of prompt generation
 type PromptResult struct {
 	Prompt        string
+	Prompts       []string // One prompt per scene when Scenes > 1 (Prompts[0] == Prompt)
 	Title         string
 	AudioFile     string
 	Style         StylePreference
 	Timestamp     time.Time
-	AudioAnalysis string // Raw audio analysis (when debug mode)
+	AudioAnalysis string      // Raw audio analysis (when debug mode)
+	Brief         *AudioBrief // Pass 1 creative brief (nil when audio analysis was skipped)
+	Lyrics        string      // Transcribed lyrics when Transcribe is set
+	Fallback      bool        // Gemini was unavailable, so the prompt was written without audio analysis
+	AudioSkipped  bool        // SkipAudio was set, so Gemini wrote the prompt without audio analysis
+	Cached        bool        // Returned from the prompt cache; Usage is then empty
+	Usage         *Usage      // Token usage of every model call in the run
+}
+
+// CallUsage is the token usage reported for one model call
+type CallUsage struct {
+	Call         string `json:"call"` // Pipeline step, e.g. "brief", "prompt", "review"
+	Model        string `json:"model"`
+	InputTokens  int    `json:"input_tokens"`  // Audio, text and image input
+	OutputTokens int    `json:"output_tokens"` // Includes thinking tokens
+}
+
+// Usage collects the CallUsage of a prompt run
+type Usage struct {
+	mu    sync.Mutex
+	Calls []CallUsage
+}
+
+// localModelPrefix marks usage of local (Ollama) models, which cost nothing
+const localModelPrefix = "ollama/"
+
+// modelPrices are approximate list prices in USD per million input and output
+// tokens, used only for the cost estimate. Models missing here are counted
+// but not priced.
+var modelPrices = map[string][2]float64{
+	"gemini-3-pro-preview": {2.00, 12.00},
+	"gemini-2.5-pro":       {1.25, 10.00},
+	"gemini-2.5-flash":     {0.30, 2.50},
+	"gpt-5.2-pro":          {21.00, 168.00},
+	"gpt-5.2":              {1.75, 14.00},
+	"gpt-5-mini":           {0.25, 2.00},
+	"claude-sonnet-4-5":    {3.00, 15.00},
 }
 
 // Client wraps the Google GenAI client
 type Client struct {
 	client *genai.Client
-	ctx    context.Context
+	ctx    context.Context // Construction context; Close uses it, minus cancellation, for cleanup
+
+	// Logger and Progress are the defaults for calls whose options leave
+	// them unset
+	Logger   Logger
+	Progress func(stage, msg string)
+
+	mu      sync.Mutex
+	uploads map[string]*genai.File // Uploaded audio keyed by file SHA-256
+	created []string               // Remote files this client uploaded, deleted by Close
 }
 
-// NewClient creates a new Gemini API client
+// Backends accepted by ClientOptions
+const (
+	BackendGeminiAPI = "gemini"
+	BackendVertexAI  = "vertex"
+)
+
+// ClientOptions configures NewClientWithConfig. The zero value matches
+// NewClient: the Gemini API with GEMINI_API_KEY.
+type ClientOptions struct {
+	APIKey     string       // Gemini API key (empty = GEMINI_API_KEY); optional for Vertex AI
+	Backend    string       // BackendGeminiAPI (default) or BackendVertexAI
+	Project    string       // Vertex AI project (empty = GOOGLE_CLOUD_PROJECT)
+	Location   string       // Vertex AI location (empty = GOOGLE_CLOUD_LOCATION)
+	HTTPClient *http.Client // Client for every request, e.g. in tests (nil = httpx.Client, with --proxy and --ca-cert)
+}
+
+// hasGeminiAccess reports whether these options can reach Gemini without a
+// request: an API key, or Vertex AI with its own credentials
+func (o ClientOptions) hasGeminiAccess() bool {
+	return o.APIKey != "" || o.Backend == BackendVertexAI || os.Getenv("GEMINI_API_KEY") != ""
+}
+
+// NewClient creates a new Gemini API client from GEMINI_API_KEY
 func NewClient(ctx context.Context) (*Client, error) {
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("GEMINI_API_KEY environment variable not set")
-	}
+	return NewClientWithConfig(ctx, ClientOptions{})
+}
 
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  apiKey,
-		Backend: genai.BackendGeminiAPI,
-	})
+// NewClientWithConfig creates a Gemini client with an explicit key, backend
+// and HTTP client
+func NewClientWithConfig(ctx context.Context, copts ClientOptions) (*Client, error) {
+	client, err := NewSDKClient(ctx, copts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
+		return nil, err
 	}
 
 	return &Client{
-		client: client,
-		ctx:    ctx,
+		client:  client,
+		ctx:     ctx,
+		uploads: make(map[string]*genai.File),
 	}, nil
 }
 
+// Close deletes the remote files this client uploaded. Uploads are reused
+// across calls until then.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	created := c.created
+	c.created = nil
+	c.uploads = make(map[string]*genai.File)
+	c.mu.Unlock()
+
+	// Clean up even when the caller's context was cancelled mid-operation
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(c.ctx), 30*time.Second)
+	defer cancel()
+
+	var errs []error
+	for _, name := range created {
+		if _, err := c.client.Files.Delete(ctx, name, nil); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete remote file %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// uploadAudio uploads audioPath and waits until Gemini has processed it. A
+// file with the same content already uploaded by this client is reused.
+func (c *Client) uploadAudio(ctx context.Context, audioPath, mimeType string, opts PromptOptions) (*genai.File, error) {
+	hash, err := fileSHA256(audioPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash audio file: %w", err)
+	}
+
+	c.mu.Lock()
+	cached := c.uploads[hash]
+	c.mu.Unlock()
+	if cached != nil {
+		if info, err := c.client.Files.Get(ctx, cached.Name, nil); err == nil && info.State == genai.FileStateActive {
+			opts.progress(StageUpload, "Reusing uploaded audio for %s", audioPath)
+			return cached, nil
+		}
+	}
+
+	opts.progress(StageUpload, "Uploading %s...", audioPath)
+
+	uploadResult, err := c.client.Files.UploadFromPath(ctx, audioPath, &genai.UploadFileConfig{
+		MIMEType: mimeType,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload audio file: %w", err)
+	}
+
+	c.mu.Lock()
+	c.created = append(c.created, uploadResult.Name)
+	c.mu.Unlock()
+
+	// Poll for file to be ready with timeout; plain terminal output gets
+	// progress dots
+	dots := opts.Logger == nil && opts.Progress == nil && !opts.Quiet && logx.Enabled(logx.LevelInfo)
+	opts.progress(StageUpload, "Processing audio...")
+
+	var size int64
+	if info, err := os.Stat(audioPath); err == nil {
+		size = info.Size()
+	}
+	timeout := processingTimeout(size)
+	pollCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		select {
+		case <-pollCtx.Done():
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("audio processing interrupted: %w", ctx.Err())
+			}
+			return nil, fmt.Errorf("timeout waiting for file processing after %s", timeout)
+		default:
+		}
+
+		fileInfo, err := c.client.Files.Get(pollCtx, uploadResult.Name, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get file status: %w", err)
+		}
+
+		if fileInfo.State == genai.FileStateActive {
+			if dots {
+				fmt.Fprintln(os.Stderr, " ready.")
+			} else {
+				opts.progress(StageUpload, "Audio ready")
+			}
+			break
+		} else if fileInfo.State == genai.FileStateFailed {
+			return nil, fmt.Errorf("file processing failed")
+		}
+
+		if dots {
+			// Alongside the log lines on stderr, keeping stdout for results
+			fmt.Fprint(os.Stderr, ".")
+		}
+		select {
+		case <-pollCtx.Done():
+		case <-time.After(2 * time.Second):
+		}
+	}
+
+	c.mu.Lock()
+	c.uploads[hash] = uploadResult
+	c.mu.Unlock()
+	return uploadResult, nil
+}
+
+// promptCacheVersion changes whenever cached results would no longer match
+// what the pipeline produces
+const promptCacheVersion = "1"
+
+// promptCache stores prompt results as JSON, keyed by the audio content and
+// every option that shapes the prompt. A nil cache is valid and never hits.
+type promptCache struct {
+	dir     string
+	maxSize int64
+}
+
+// DefaultPromptCacheMaxSize is how many bytes of prompt results the cache
+// keeps before evicting the least recently used
+const DefaultPromptCacheMaxSize = 64 << 20
+
+// DefaultPromptCacheDir returns the default prompt cache location (~/.cache/mmmeld/prompts on Linux)
+func DefaultPromptCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "mmmeld", "prompts"), nil
+}
+
+func openPromptCache(dir string) (*promptCache, error) {
+	if dir == "" {
+		var err error
+		dir, err = DefaultPromptCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate cache directory: %w", err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &promptCache{dir: dir, maxSize: DefaultPromptCacheMaxSize}, nil
+}
+
+// promptCacheKey hashes the audio content (unless SkipAudio leaves it unused)
+// with a canonical serialization of the options that influence the result.
+// Logging, retry and cache settings do not change the prompt and are left out.
+func promptCacheKey(audioPath string, opts PromptOptions) (string, error) {
+	var audioHash string
+	if !opts.SkipAudio {
+		var err error
+		if audioHash, err = fileSHA256(audioPath); err != nil {
+			return "", fmt.Errorf("failed to hash audio file: %w", err)
+		}
+	}
+	canonical, err := json.Marshal(struct {
+		Version           string
+		Audio             string
+		Title             string
+		Notes             string
+		Caption           string
+		Subcaption        string
+		Style             StylePreference
+		Model             string
+		Scenes            int
+		CasingPolicy      CasingPolicy
+		Reviewer          string
+		ReviewModel       string
+		Transcribe        bool
+		Avoid             []string
+		Language          string
+		SkipAudio         bool
+		AnalyzeWindow     *probe.AudioWindow
+		BriefTemperature  *float32
+		PromptTemperature *float32
+	}{
+		promptCacheVersion, audioHash, opts.Title, opts.Notes, opts.Caption, opts.Subcaption,
+		opts.StylePreference, opts.Model, max(opts.Scenes, 1), opts.CasingPolicy, opts.Reviewer,
+		reviewModel(opts.ReviewModel, ""), opts.Transcribe, opts.Avoid, opts.Language, opts.SkipAudio,
+		opts.AnalyzeWindow, opts.BriefTemperature, opts.PromptTemperature,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// lookup returns the cached result for key, marked Cached and with empty
+// usage since no model was called
+func (c *promptCache) lookup(key string) (*PromptResult, bool) {
+	if c == nil {
+		return nil, false
+	}
+	path := filepath.Join(c.dir, key+".json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var result PromptResult
+	if err := json.Unmarshal(data, &result); err != nil || result.Prompt == "" {
+		return nil, false
+	}
+	// Refresh the modification time so eviction removes the least recently
+	// used entries first
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	result.Cached = true
+	result.Usage = &Usage{}
+	return &result, true
+}
+
+// store writes result to the cache, replacing any earlier entry for key, and
+// evicts old entries. Each write goes to a temp file of its own that is
+// renamed into place, so parallel runs storing the same key never interleave.
+func (c *promptCache) store(key string, result *PromptResult) error {
+	if c == nil {
+		return nil
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.dir, key+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(c.dir, key+".json"))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	_, _, err = fileutil.PruneCache(c.dir, c.maxSize, 0, "prompt")
+	return err
+}
+
+// processingTimeout is how long to wait for Gemini to process an upload of
+// size bytes
+func processingTimeout(size int64) time.Duration {
+	timeout := processingBaseTimeout + time.Duration(size/processingTimeoutBytes)*processingTimeoutStep
+	if timeout > processingMaxTimeout {
+		return processingMaxTimeout
+	}
+	return timeout
+}
+
+// audioDuration and transcodeAudio are variables so tests can substitute
+// fakes for ffprobe and ffmpeg
+var (
+	audioDuration  = probe.Duration
+	transcodeAudio = ffmpeg.ExtractAudio
+)
+
+// prepareAudio returns the file to upload for audioPath: the file itself, or
+// a mono 64 kbps AAC transcode in the system temp folder when the file is a
+// video, is large or long, or only opts.AnalyzeWindow is analyzed. The
+// transcode is registered with opts.Cleanup, or deleted by the returned
+// remove function.
+func prepareAudio(ctx context.Context, audioPath string, opts PromptOptions) (path, mimeType string, remove func(), err error) {
+	remove = func() {}
+	video := ffmpeg.IsVideoInput(audioPath)
+	var reason string
+	if opts.AnalyzeWindow != nil {
+		reason = "analyzing " + opts.AnalyzeWindow.String() + " only"
+	} else if video {
+		reason = "audio track of a video"
+	} else if info, err := os.Stat(audioPath); err == nil {
+		if info.Size() > LargeAudioBytes {
+			reason = fmt.Sprintf("%d MB", info.Size()>>20)
+		} else if info.Size() > probeAudioBytes {
+			if seconds, err := audioDuration(audioPath); err == nil && seconds > LongAudioSeconds {
+				reason = fmt.Sprintf("%.0f minutes", seconds/60)
+			}
+		}
+	}
+	if reason == "" {
+		return audioPath, getMimeType(audioPath), remove, nil
+	}
+
+	transcoded := opts.TempPath
+	if transcoded == "" {
+		f, err := os.CreateTemp("", "mmmeld-audio-*.m4a")
+		if err != nil {
+			return "", "", remove, fmt.Errorf("failed to create temp audio file: %w", err)
+		}
+		f.Close()
+		transcoded = f.Name()
+	}
+	if opts.Cleanup != nil {
+		opts.Cleanup.Add(transcoded)
+	} else {
+		remove = func() { os.Remove(transcoded) }
+	}
+
+	extract := ffmpeg.ExtractOptions{Mono: true, Bitrate: "64k"}
+	if w := opts.AnalyzeWindow; w != nil {
+		extract.Start, extract.Duration = w.Start, w.End-w.Start
+	}
+
+	opts.progress(StageUpload, "Compressing %s for upload (%s)...", audioPath, reason)
+	if err := transcodeAudio(ctx, audioPath, transcoded, extract); err != nil {
+		remove()
+		if opts.AnalyzeWindow != nil {
+			return "", "", func() {}, fmt.Errorf("failed to extract analysis window: %w", err)
+		}
+		if video {
+			return "", "", func() {}, fmt.Errorf("failed to extract the audio track of %s: %w", audioPath, err)
+		}
+		// The original may still be within Gemini's limits
+		opts.logger().Warnf("Audio compression failed, uploading the original: %v", err)
+		return audioPath, getMimeType(audioPath), func() {}, nil
+	}
+	return transcoded, getMimeType(transcoded), remove, nil
+}
+
+// fileSHA256 returns the hex SHA-256 of a file's contents
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// NewSDKClient creates a google.golang.org/genai client from copts, for
+// callers that need SDK features such as Imagen
+func NewSDKClient(ctx context.Context, copts ClientOptions) (*genai.Client, error) {
+	cc := &genai.ClientConfig{
+		APIKey:     copts.APIKey,
+		Project:    copts.Project,
+		Location:   copts.Location,
+		HTTPClient: copts.HTTPClient,
+	}
+	if cc.HTTPClient == nil {
+		cc.HTTPClient = httpx.Client(0)
+	}
+	switch copts.Backend {
+	case "", BackendGeminiAPI:
+		cc.Backend = genai.BackendGeminiAPI
+		if cc.APIKey == "" {
+			cc.APIKey = os.Getenv("GEMINI_API_KEY")
+		}
+		if cc.APIKey == "" {
+			return nil, fmt.Errorf("GEMINI_API_KEY environment variable not set")
+		}
+	case BackendVertexAI:
+		// Project, location, and credentials otherwise come from the
+		// GOOGLE_CLOUD_* environment and application default credentials
+		cc.Backend = genai.BackendVertexAI
+	default:
+		return nil, fmt.Errorf("unknown Gemini backend %q (must be %s or %s)", copts.Backend, BackendGeminiAPI, BackendVertexAI)
+	}
+
+	client, err := genai.NewClient(ctx, cc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
+	}
+	return client, nil
+}
+
 // AudioBrief contains structured analysis of audio for image prompt generation
 type AudioBrief struct {
 	Genre                string   `json:"genre"`
@@ -114,7 +848,7 @@ type AudioBrief struct {
 }
 
 // GenerateImagePrompt analyzes an audio file and generates an image prompt using 2-pass pipeline
-func (c *Client) GenerateImagePrompt(audioPath string, opts PromptOptions) (*PromptResult, error) {
+func (c *Client) GenerateImagePrompt(ctx context.Context, audioPath string, opts PromptOptions) (*PromptResult, error) {
 	// Set defaults
 	if opts.Model == "" {
 		opts.Model = DefaultModel
@@ -125,121 +859,317 @@ func (c *Client) GenerateImagePrompt(audioPath string, opts PromptOptions) (*Pro
 	if opts.Title == "" {
 		opts.Title = strings.TrimSuffix(filepath.Base(audioPath), filepath.Ext(audioPath))
 	}
+	if opts.BriefTemperature == nil {
+		opts.BriefTemperature = ptr(float32(DefaultBriefTemperature))
+	}
+	if opts.PromptTemperature == nil {
+		opts.PromptTemperature = ptr(float32(DefaultPromptTemperature))
+	}
+	if opts.Logger == nil {
+		opts.Logger = c.Logger
+	}
+	if opts.Progress == nil {
+		opts.Progress = c.Progress
+	}
+	logger := opts.logger()
+	if opts.Debug {
+		logger.Infof("DEBUG: temperatures - brief %.2f, prompt %.2f", *opts.BriefTemperature, *opts.PromptTemperature)
+	}
+	// An identical earlier request returns its prompt without model calls
+	var cache *promptCache
+	var cacheKey string
+	if !opts.NoCache {
+		var err error
+		if cache, err = openPromptCache(opts.CacheDir); err != nil {
+			logger.Warnf("Prompt cache disabled: %v", err)
+		} else if cacheKey, err = promptCacheKey(audioPath, opts); err != nil {
+			logger.Warnf("Prompt cache disabled: %v", err)
+			cache = nil
+		}
+	}
+	if !opts.Refresh {
+		if result, hit := cache.lookup(cacheKey); hit {
+			logger.Infof("Using cached prompt for %s (--refresh to regenerate)", audioPath)
+			result.AudioFile = audioPath
+			return result, nil
+		}
+	}
 
-	// Upload the audio file
-	if !opts.Quiet {
-		log.Printf("Uploading %s...", audioPath)
+	result, err := c.generateImagePrompt(ctx, audioPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	// A fallback prompt lacks audio analysis, so a later run should retry
+	if !result.Fallback {
+		if err := cache.store(cacheKey, result); err != nil {
+			logger.Warnf("Failed to cache prompt: %v", err)
+		}
 	}
+	return result, nil
+}
 
-	mimeType := getMimeType(audioPath)
-	uploadResult, err := c.client.Files.UploadFromPath(c.ctx, audioPath, &genai.UploadFileConfig{
-		MIMEType: mimeType,
-	})
+// generateImagePrompt runs the prompt pipeline for GenerateImagePrompt once
+// its options are defaulted
+func (c *Client) generateImagePrompt(ctx context.Context, audioPath string, opts PromptOptions) (*PromptResult, error) {
+	logger := opts.logger()
+	usage := &Usage{}
+	ctx = withUsage(ctx, usage)
+
+	if opts.SkipAudio {
+		return c.generateMetadataOnlyPrompt(ctx, audioPath, opts, usage)
+	}
+
+	// Compress long or large audio, or cut the analysis window, then upload
+	// it (or reuse this client's earlier upload of it)
+	uploadPath, mimeType, removeUpload, err := prepareAudio(ctx, audioPath, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to upload audio file: %w", err)
+		return nil, err
+	}
+	defer removeUpload()
+	uploadResult, err := c.uploadAudio(ctx, uploadPath, mimeType, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	// Ensure cleanup of the uploaded file
-	defer func() {
-		if _, err := c.client.Files.Delete(c.ctx, uploadResult.Name, nil); err != nil {
-			logWarning("Failed to delete remote file: %v", err)
+	// === Optional: Lyrics transcription, fed into pass 1 ===
+	if opts.Transcribe {
+		opts.progress(StageLyrics, "Transcribing lyrics...")
+		err = withQuotaRetry(ctx, logger, opts.Retries, "lyrics transcription", func() error {
+			var err error
+			opts.lyrics, err = c.transcribeLyrics(ctx, uploadResult.URI, mimeType, opts)
+			return err
+		})
+		if err != nil {
+			// Non-fatal - the brief can still be built from the audio alone
+			logger.Warnf("Lyrics transcription failed: %v", err)
 		}
-	}()
+	}
 
-	// Poll for file to be ready with timeout
-	if !opts.Quiet {
-		log.Print("Processing audio...")
+	// === PASS 1: Audio → Creative Brief (structured JSON) ===
+	opts.progress(StageBrief, "Pass 1: Analyzing audio for creative brief...")
+
+	var brief *AudioBrief
+	var briefJSON string
+	err = withQuotaRetry(ctx, logger, opts.Retries, "audio analysis", func() error {
+		var err error
+		brief, briefJSON, err = c.generateAudioBrief(ctx, uploadResult.URI, mimeType, opts)
+		return err
+	})
+	if err != nil {
+		// Retries are exhausted on a quota error - fall back to the reviewer
+		if isQuotaError(err) {
+			return promptQuotaFallback(ctx, err, audioPath, opts, usage)
+		}
+		return nil, fmt.Errorf("failed to generate audio brief: %w", err)
 	}
 
-	pollCtx, cancel := context.WithTimeout(c.ctx, 2*time.Minute)
-	defer cancel()
+	brief.Avoid = mergeAvoidTerms(brief.Avoid, opts.Avoid)
 
-	for {
-		select {
-		case <-pollCtx.Done():
-			return nil, fmt.Errorf("timeout waiting for file processing")
-		default:
+	if opts.Debug {
+		logger.Infof("DEBUG: avoid list: %s", strings.Join(brief.Avoid, ", "))
+		logger.Infof("\n============================================================")
+		logger.Infof("DEBUG: CREATIVE BRIEF (JSON)")
+		logger.Infof("============================================================")
+		logger.Infof("%s", briefJSON)
+		logger.Infof("============================================================\n")
+	}
+
+	reviewer, err := NewReviewer(opts.Reviewer, opts.ReviewModel)
+	if err != nil {
+		logger.Warnf("%v, skipping second-opinion review", err)
+	}
+
+	scenes := opts.Scenes
+	if scenes < 1 {
+		scenes = 1
+	}
+	prompts := make([]string, 0, scenes)
+	for i := 0; i < scenes; i++ {
+		sceneOpts := opts
+		sceneLabel := ""
+		if scenes > 1 {
+			sceneOpts.scene = sceneFocus(brief, i, scenes)
+			sceneLabel = fmt.Sprintf(" (scene %d/%d)", i+1, scenes)
 		}
 
-		fileInfo, err := c.client.Files.Get(c.ctx, uploadResult.Name, nil)
+		// === PASS 2: Brief → Ideogram Prompt ===
+		opts.progress(StagePrompt, "Pass 2: Generating Ideogram prompt from brief%s...", sceneLabel)
+
+		promptText, err := c.generatePromptFromBrief(ctx, brief, sceneOpts)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get file status: %w", err)
+			return nil, fmt.Errorf("failed to generate prompt%s: %w", sceneLabel, err)
 		}
 
-		if fileInfo.State == genai.FileStateActive {
-			if !opts.Quiet {
-				log.Println(" ready.")
+		// Clean up the prompt (remove quotes, newlines, preambles)
+		promptText = cleanPromptOutput(promptText)
+
+		// === PASS 3: Second Opinion Review ===
+		if reviewer != nil {
+			opts.progress(StageReview, "Pass 3: Getting second opinion from %s%s...", reviewer.Name(), sceneLabel)
+
+			promptText, err = reviewPrompt(ctx, reviewer, promptText, brief, opts)
+			if err != nil {
+				// Non-fatal - if second opinion fails, we still have the original prompt
+				logger.Warnf("Second opinion review failed, using original prompt: %v", err)
 			}
-			break
-		} else if fileInfo.State == genai.FileStateFailed {
-			return nil, fmt.Errorf("file processing failed")
 		}
+		prompts = append(prompts, promptText)
+	}
 
-		if !opts.Quiet {
-			fmt.Print(".")
-		}
-		time.Sleep(2 * time.Second)
+	return &PromptResult{
+		Prompt:        prompts[0],
+		Prompts:       prompts,
+		Title:         opts.Title,
+		AudioFile:     audioPath,
+		Style:         opts.StylePreference,
+		Timestamp:     time.Now(),
+		AudioAnalysis: briefJSON,
+		Brief:         brief,
+		Lyrics:        opts.lyrics,
+		Usage:         usage,
+	}, nil
+}
+
+// RevisePrompt reruns pass 2 and the review on an existing brief, so a prompt
+// can be regenerated or adjusted without uploading or analyzing the audio
+// again. An empty instruction writes a fresh prompt from the brief; otherwise
+// previous is revised as instructed (e.g. "same idea but at night") while
+// keeping the brief's palette, avoid list, and text overlay.
+func (c *Client) RevisePrompt(ctx context.Context, brief *AudioBrief, previous, instruction string, opts PromptOptions) (string, *Usage, error) {
+	if brief == nil {
+		return "", nil, errors.New("no creative brief to revise from (the audio was not analyzed)")
+	}
+	if opts.Model == "" {
+		opts.Model = DefaultModel
 	}
+	logger := opts.logger()
+	usage := &Usage{}
+	ctx = withUsage(ctx, usage)
+	opts.revision = revisionInstruction(previous, instruction)
 
-	// === PASS 1: Audio → Creative Brief (structured JSON) ===
-	if !opts.Quiet {
-		log.Println("Pass 1: Analyzing audio for creative brief...")
+	opts.progress(StagePrompt, "Pass 2: Revising Ideogram prompt from brief...")
+	var promptText string
+	err := withQuotaRetry(ctx, logger, opts.Retries, "prompt revision", func() error {
+		var err error
+		promptText, err = c.generatePromptFromBrief(ctx, brief, opts)
+		return err
+	})
+	if err != nil {
+		return "", usage, fmt.Errorf("failed to revise prompt: %w", err)
 	}
+	promptText = cleanPromptOutput(promptText)
 
-	brief, briefJSON, err := c.generateAudioBrief(uploadResult.URI, mimeType, opts)
+	reviewer, err := NewReviewer(opts.Reviewer, opts.ReviewModel)
 	if err != nil {
-		// Check if this is a quota error - if so, fall back to OpenAI
-		if strings.Contains(err.Error(), "429") || strings.Contains(err.Error(), "quota") || strings.Contains(err.Error(), "RESOURCE_EXHAUSTED") {
-			logWarning("Gemini quota exceeded, falling back to OpenAI for prompt generation")
-			return generatePromptWithOpenAIFallback(audioPath, opts)
+		logger.Warnf("%v, skipping second-opinion review", err)
+	}
+	if reviewer != nil {
+		opts.progress(StageReview, "Pass 3: Getting second opinion from %s...", reviewer.Name())
+		promptText, err = reviewPrompt(ctx, reviewer, promptText, brief, opts)
+		if err != nil {
+			logger.Warnf("Second opinion review failed, using revised prompt: %v", err)
 		}
-		return nil, fmt.Errorf("failed to generate audio brief: %w", err)
 	}
+	return promptText, usage, nil
+}
 
-	if opts.Debug {
-		log.Printf("\n============================================================")
-		log.Printf("DEBUG: CREATIVE BRIEF (JSON)")
-		log.Printf("============================================================")
-		log.Printf("%s", briefJSON)
-		log.Printf("============================================================\n")
+// revisionInstruction is the pass 2 addition for RevisePrompt
+func revisionInstruction(previous, instruction string) string {
+	instruction = strings.TrimSpace(instruction)
+	if instruction == "" {
+		if previous == "" {
+			return ""
+		}
+		return fmt.Sprintf("PREVIOUS PROMPT (write a fresh alternative, not a copy):\n%s", previous)
 	}
+	return fmt.Sprintf("REVISION REQUEST:\nRevise this previous prompt as the user asks, changing only what the request implies and keeping the text overlay, palette, and constraints above.\nPrevious prompt: %s\nRequest: %s", previous, instruction)
+}
 
-	// === PASS 2: Brief → Ideogram Prompt ===
-	if !opts.Quiet {
-		log.Println("Pass 2: Generating Ideogram prompt from brief...")
+// promptQuotaFallback writes the prompt with the reviewer once Gemini stays
+// over quota, unless the fallback is disabled or has no key
+func promptQuotaFallback(ctx context.Context, quotaErr error, audioPath string, opts PromptOptions, usage *Usage) (*PromptResult, error) {
+	r, err := fallbackReviewer(opts.Reviewer, opts.ReviewModel, opts.NoFallback)
+	if err != nil {
+		return nil, fmt.Errorf("Gemini quota exceeded and cannot fall back (%v): %w", err, quotaErr)
 	}
-
-	promptText, err := c.generatePromptFromBrief(brief, opts)
+	opts.logger().Warnf("Gemini quota exceeded, falling back to %s for prompt generation", r.Name())
+	result, err := generatePromptWithReviewer(ctx, r, audioPath, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate prompt: %w", err)
+		return nil, err
 	}
+	result.Usage = usage
+	return result, nil
+}
 
-	// Clean up the prompt (remove quotes, newlines, preambles)
-	promptText = cleanPromptOutput(promptText)
+// generateMetadataOnlyPrompt writes the prompt with Gemini from the title,
+// notes and caption alone (SkipAudio), then gets the usual second opinion
+func (c *Client) generateMetadataOnlyPrompt(ctx context.Context, audioPath string, opts PromptOptions, usage *Usage) (*PromptResult, error) {
+	logger := opts.logger()
+	if opts.Transcribe {
+		logger.Warnf("Lyrics transcription needs audio analysis and is skipped")
+	}
+	opts.progress(StagePrompt, "Generating image prompt from title and notes (no audio analysis)...")
+
+	systemPrompt, userPrompt := buildMetadataPrompt(opts)
+	contents := []*genai.Content{
+		{
+			Role: "user",
+			Parts: []*genai.Part{
+				{Text: userPrompt},
+			},
+		},
+	}
+	config := &genai.GenerateContentConfig{
+		SystemInstruction: &genai.Content{Parts: []*genai.Part{{Text: systemPrompt}}},
+		Temperature:       opts.PromptTemperature,
+	}
+
+	var resp *genai.GenerateContentResponse
+	err := withQuotaRetry(ctx, logger, opts.Retries, "prompt generation", func() error {
+		var err error
+		resp, err = c.client.Models.GenerateContent(ctx, opts.Model, contents, config)
+		return err
+	})
+	if err != nil {
+		if isQuotaError(err) {
+			return promptQuotaFallback(ctx, err, audioPath, opts, usage)
+		}
+		return nil, fmt.Errorf("prompt generation failed: %w", err)
+	}
+	recordGeminiUsage(ctx, "prompt", opts.Model, resp)
 
-	// === PASS 3: Second Opinion Review (OpenAI) ===
-	if !opts.Quiet {
-		log.Println("Pass 3: Getting second opinion from OpenAI...")
+	promptText := cleanPromptOutput(extractResponseText(resp))
+	if promptText == "" {
+		return nil, fmt.Errorf("prompt generation failed: Gemini returned an empty prompt")
 	}
 
-	promptText, err = reviewPromptWithOpenAI(promptText, brief, opts)
+	reviewer, err := NewReviewer(opts.Reviewer, opts.ReviewModel)
 	if err != nil {
-		// Non-fatal - if second opinion fails, we still have the original prompt
-		logWarning("Second opinion review failed: %v", err)
+		logger.Warnf("%v, skipping second-opinion review", err)
+	}
+	if reviewer != nil {
+		opts.progress(StageReview, "Getting second opinion from %s...", reviewer.Name())
+		promptText, err = reviewPrompt(ctx, reviewer, promptText, nil, opts)
+		if err != nil {
+			// Non-fatal - if second opinion fails, we still have the original prompt
+			logger.Warnf("Second opinion review failed, using original prompt: %v", err)
+		}
 	}
 
 	return &PromptResult{
-		Prompt:        promptText,
-		Title:         opts.Title,
-		AudioFile:     audioPath,
-		Style:         opts.StylePreference,
-		Timestamp:     time.Now(),
-		AudioAnalysis: briefJSON,
+		Prompt:       promptText,
+		Prompts:      []string{promptText},
+		Title:        opts.Title,
+		AudioFile:    audioPath,
+		Style:        opts.StylePreference,
+		Timestamp:    time.Now(),
+		AudioSkipped: true,
+		Usage:        usage,
 	}, nil
 }
 
 // generateAudioBrief produces a structured creative brief from audio analysis
-func (c *Client) generateAudioBrief(fileURI, mimeType string, opts PromptOptions) (*AudioBrief, string, error) {
+func (c *Client) generateAudioBrief(ctx context.Context, fileURI, mimeType string, opts PromptOptions) (*AudioBrief, string, error) {
 	systemInstruction := &genai.Content{
 		Parts: []*genai.Part{
 			{Text: `You are an audio analyst creating a creative brief for an image generator.
@@ -279,6 +1209,12 @@ User notes: %s
 Style preference: %s
 
 Listen carefully and output ONLY the JSON brief.`, opts.Title, opts.Notes, opts.StylePreference)
+	if opts.lyrics != "" {
+		userPrompt += "\n\nTranscribed lyrics (base lyric_themes on these):\n" + opts.lyrics
+	}
+	if opts.Language != "" {
+		userPrompt += fmt.Sprintf("\n\nThe lyrics and caption are in %s; write every brief field in English.", opts.Language)
+	}
 
 	contents := []*genai.Content{
 		{
@@ -295,28 +1231,175 @@ Listen carefully and output ONLY the JSON brief.`, opts.Title, opts.Notes, opts.
 
 	config := &genai.GenerateContentConfig{
 		SystemInstruction: systemInstruction,
-		Temperature:       ptr(float32(0.7)),
+		Temperature:       opts.BriefTemperature,
+		ResponseMIMEType:  "application/json",
+		ResponseSchema:    audioBriefSchema(),
+	}
+
+	resp, err := c.client.Models.GenerateContent(ctx, opts.Model, contents, config)
+	if err != nil {
+		return nil, "", fmt.Errorf("brief generation failed: %w", err)
+	}
+	recordGeminiUsage(ctx, "brief", opts.Model, resp)
+
+	briefJSON := extractResponseText(resp)
+	return parseAudioBriefWithRepair(briefJSON, func(parseErr error) (string, error) {
+		opts.logger().Warnf("Audio brief was not valid JSON (%v), asking Gemini to repair it", parseErr)
+		repairContents := append(contents,
+			&genai.Content{Role: "model", Parts: []*genai.Part{{Text: briefJSON}}},
+			&genai.Content{Role: "user", Parts: []*genai.Part{{Text: fmt.Sprintf(
+				"That reply could not be parsed (%v). Reply again with only the corrected JSON brief.", parseErr)}}},
+		)
+		resp, err := c.client.Models.GenerateContent(ctx, opts.Model, repairContents, config)
+		if err != nil {
+			return "", fmt.Errorf("brief repair failed: %w", err)
+		}
+		recordGeminiUsage(ctx, "brief-repair", opts.Model, resp)
+		return extractResponseText(resp), nil
+	})
+}
+
+// audioBriefSchema is the Gemini response schema matching AudioBrief
+func audioBriefSchema() *genai.Schema {
+	str := &genai.Schema{Type: genai.TypeString}
+	list := &genai.Schema{Type: genai.TypeArray, Items: str}
+	properties := map[string]*genai.Schema{
+		"genre":                 str,
+		"bpm":                   {Type: genai.TypeInteger},
+		"energy":                {Type: genai.TypeInteger, Description: "1-10"},
+		"mood_adjectives":       list,
+		"prominent_instruments": list,
+		"visual_nouns":          list,
+		"textures":              list,
+		"palette_colors":        list,
+		"central_metaphor":      str,
+		"avoid":                 list,
+		"lyric_themes":          str,
+	}
+	order := []string{"genre", "bpm", "energy", "mood_adjectives", "prominent_instruments", "visual_nouns",
+		"textures", "palette_colors", "central_metaphor", "avoid", "lyric_themes"}
+	return &genai.Schema{
+		Type:             genai.TypeObject,
+		Properties:       properties,
+		PropertyOrdering: order,
+		Required:         order,
+	}
+}
+
+// parseAudioBrief decodes a brief produced under audioBriefSchema
+func parseAudioBrief(briefJSON string) (*AudioBrief, error) {
+	var brief AudioBrief
+	if err := json.Unmarshal([]byte(strings.TrimSpace(briefJSON)), &brief); err != nil {
+		return nil, err
+	}
+	return &brief, nil
+}
+
+// parseAudioBriefWithRepair parses briefJSON and, if that fails, parses the
+// reply repair gives for the parse error once. The JSON that was parsed (or
+// the last raw reply, on failure) is returned for debugging.
+func parseAudioBriefWithRepair(briefJSON string, repair func(parseErr error) (string, error)) (*AudioBrief, string, error) {
+	brief, err := parseAudioBrief(briefJSON)
+	if err == nil {
+		return brief, briefJSON, nil
+	}
+
+	repaired, repairErr := repair(err)
+	if repairErr != nil {
+		return nil, briefJSON, fmt.Errorf("failed to parse brief JSON: %w (%v)\nRaw response: %s", err, repairErr, briefJSON)
+	}
+	brief, err = parseAudioBrief(repaired)
+	if err != nil {
+		return nil, repaired, fmt.Errorf("failed to parse brief JSON after repair: %w\nRaw response: %s", err, repaired)
+	}
+	return brief, repaired, nil
+}
+
+// transcribeLyrics asks Gemini for the lyrics of the uploaded audio, with
+// instrumental sections summarized in brackets
+func (c *Client) transcribeLyrics(ctx context.Context, fileURI, mimeType string, opts PromptOptions) (string, error) {
+	prompt := `Transcribe the sung or spoken lyrics of this audio as plain text, one line per sung line, with a blank line between sections.
+Mark instrumental sections with a short bracketed summary, e.g. [Instrumental: piano and strings build]. If the track has no vocals, reply with a single bracketed summary of the whole track.
+Do not add a title, commentary, or chord names.`
+
+	contents := []*genai.Content{
+		{
+			Role: "user",
+			Parts: []*genai.Part{
+				{Text: prompt},
+				{FileData: &genai.FileData{
+					FileURI:  fileURI,
+					MIMEType: mimeType,
+				}},
+			},
+		},
+	}
+
+	config := &genai.GenerateContentConfig{
+		Temperature: ptr(float32(0.2)),
+	}
+
+	resp, err := c.client.Models.GenerateContent(ctx, opts.Model, contents, config)
+	if err != nil {
+		return "", fmt.Errorf("lyrics transcription failed: %w", err)
+	}
+	recordGeminiUsage(ctx, "lyrics", opts.Model, resp)
+	lyrics := extractResponseText(resp)
+	if lyrics == "" {
+		return "", fmt.Errorf("empty lyrics transcription from Gemini")
+	}
+	return lyrics, nil
+}
+
+// captionLanguageNote tells a validator that the expected text is in a
+// language other than English, so accents are not flagged as misspellings
+func captionLanguageNote(language string) string {
+	if language == "" {
+		return ""
 	}
+	return fmt.Sprintf(`
+- LANGUAGE: The expected text is in %s, not English. Judge spelling against the expected text exactly as given: accents and diacritics (e.g. á, ã, ç, é, õ, ñ, ü) are correct spelling, not errors or unusual words. Text rendered with missing or different accents IS misspelled.`, language)
+}
 
-	resp, err := c.client.Models.GenerateContent(c.ctx, opts.Model, contents, config)
-	if err != nil {
-		return nil, "", fmt.Errorf("brief generation failed: %w", err)
+// promptLanguageNote tells a prompt-writing pass to keep the scene in English
+// while treating the caption text as the given language
+func promptLanguageNote(language string) string {
+	if language == "" {
+		return ""
 	}
+	return fmt.Sprintf(`
 
-	briefJSON := extractResponseText(resp)
-	briefJSON = cleanJSONResponse(briefJSON)
+LANGUAGE: Write everything in English except the caption/subcaption text, which is in %s. Reproduce that text exactly as given, with all accents and diacritics; never translate, transliterate, or "correct" it.`, language)
+}
 
-	var brief AudioBrief
-	if err := json.Unmarshal([]byte(briefJSON), &brief); err != nil {
-		// If JSON parsing fails, return raw text for debugging
-		return nil, briefJSON, fmt.Errorf("failed to parse brief JSON: %w\nRaw response: %s", err, briefJSON)
+// ParseAvoidList splits a comma-separated avoid list into trimmed terms
+func ParseAvoidList(s string) []string {
+	var terms []string
+	for _, term := range strings.Split(s, ",") {
+		if term = strings.TrimSpace(term); term != "" {
+			terms = append(terms, term)
+		}
 	}
+	return terms
+}
 
-	return &brief, briefJSON, nil
+// mergeAvoidTerms appends extra to avoid, skipping case-insensitive duplicates
+func mergeAvoidTerms(avoid, extra []string) []string {
+	seen := make(map[string]bool, len(avoid)+len(extra))
+	merged := make([]string, 0, len(avoid)+len(extra))
+	for _, term := range append(append([]string{}, avoid...), extra...) {
+		key := strings.ToLower(strings.TrimSpace(term))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		merged = append(merged, term)
+	}
+	return merged
 }
 
 // generatePromptFromBrief creates the final Ideogram prompt from the structured brief
-func (c *Client) generatePromptFromBrief(brief *AudioBrief, opts PromptOptions) (string, error) {
+func (c *Client) generatePromptFromBrief(ctx context.Context, brief *AudioBrief, opts PromptOptions) (string, error) {
 	styleConstraints := getStyleConstraints(opts.StylePreference)
 
 	systemInstruction := &genai.Content{
@@ -400,6 +1483,14 @@ User notes: %s`,
 		opts.Notes,
 	))
 
+	if opts.scene != "" {
+		userPrompt.WriteString("\n\n" + opts.scene)
+	}
+	if opts.revision != "" {
+		userPrompt.WriteString("\n\n" + opts.revision)
+	}
+	userPrompt.WriteString(promptLanguageNote(opts.Language))
+
 	userPrompt.WriteString("\n\nERA / CULTURAL FIT:\n- Keep props/wardrobe/architecture aligned to the genre's implied era. For modern genres (e.g., CCM live worship), prefer contemporary objects and environments; do not drift into ancient/medieval/biblical props unless explicitly indicated by user notes or prominent lyric themes.\n")
 
 	contents := []*genai.Content{
@@ -413,17 +1504,29 @@ User notes: %s`,
 
 	config := &genai.GenerateContentConfig{
 		SystemInstruction: systemInstruction,
-		Temperature:       ptr(float32(0.8)),
+		Temperature:       opts.PromptTemperature,
 	}
 
-	resp, err := c.client.Models.GenerateContent(c.ctx, opts.Model, contents, config)
+	resp, err := c.client.Models.GenerateContent(ctx, opts.Model, contents, config)
 	if err != nil {
 		return "", fmt.Errorf("prompt generation failed: %w", err)
 	}
+	recordGeminiUsage(ctx, "prompt", opts.Model, resp)
 
 	return extractResponseText(resp), nil
 }
 
+// sceneFocus steers one of several prompts from the same brief toward its own
+// visual noun so multi-slide videos get distinct scenes with a shared look
+func sceneFocus(brief *AudioBrief, index, total int) string {
+	subject := "a different moment or setting from the song"
+	if len(brief.VisualNouns) > 0 {
+		subject = fmt.Sprintf("%q", brief.VisualNouns[index%len(brief.VisualNouns)])
+	}
+	return fmt.Sprintf(`SCENE %d OF %d:
+Build this prompt around %s as the primary subject, with a setting and composition clearly different from the other scenes. Keep the palette, mood, and text overlay consistent so the scenes read as one series.`, index+1, total, subject)
+}
+
 func getStyleConstraints(style StylePreference) string {
 	switch style {
 	case StylePhotorealistic:
@@ -439,28 +1542,448 @@ func getStyleConstraints(style StylePreference) string {
 	}
 }
 
-// SecondOpinionResult contains the result of the OpenAI second-opinion review
+// SecondOpinionResult contains the result of the second-opinion review
 type SecondOpinionResult struct {
 	Approved       bool   `json:"approved"`
 	ImprovedPrompt string `json:"improved_prompt,omitempty"`
 	Reason         string `json:"reason,omitempty"`
 }
 
-// reviewPromptWithOpenAI gets a second opinion from OpenAI on the generated prompt
-// It checks if the prompt makes sense given the audio analysis and original request
-// generatePromptWithOpenAIFallback creates an image prompt using OpenAI when Gemini is unavailable
-// This skips audio analysis and works only with the available metadata (title, notes, caption, subcaption)
-func generatePromptWithOpenAIFallback(audioPath string, opts PromptOptions) (*PromptResult, error) {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("OPENAI_API_KEY not set - cannot fall back to OpenAI")
+// Reviewer names accepted by --reviewer
+const (
+	ReviewerOpenAI    = "openai"
+	ReviewerAnthropic = "anthropic"
+	ReviewerOllama    = "ollama"
+	ReviewerNone      = "none"
+)
+
+// Reviewer is a second model that double-checks Gemini's work: it reviews
+// generated prompts and stands in for Gemini when Gemini is unavailable
+type Reviewer interface {
+	// Name identifies the reviewer and its model in logs
+	Name() string
+	// Review judges an image prompt against the audio brief and request
+	Review(ctx context.Context, prompt string, brief *AudioBrief, opts PromptOptions) (SecondOpinionResult, error)
+	// Ask sends a prompt, with an optional system prompt and image, and
+	// returns the text reply
+	Ask(ctx context.Context, system, prompt string, imageData []byte, mimeType string) (string, error)
+}
+
+// NewReviewer returns the named reviewer (empty = openai) using model, or
+// nil for "none". A missing API key for the reviewer is an error; ollama
+// needs none.
+func NewReviewer(name, model string) (Reviewer, error) {
+	switch name {
+	case "", ReviewerOpenAI:
+		apiKey := os.Getenv("OPENAI_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("OPENAI_API_KEY not set")
+		}
+		return &openAIReviewer{apiKey: apiKey, model: reviewModel(model, DefaultReviewModel), timeout: reviewerTimeout}, nil
+	case ReviewerAnthropic:
+		apiKey := os.Getenv("ANTHROPIC_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("ANTHROPIC_API_KEY not set")
+		}
+		return &anthropicReviewer{apiKey: apiKey, model: reviewModel(model, DefaultAnthropicReviewModel), timeout: reviewerTimeout}, nil
+	case ReviewerOllama:
+		return &ollamaReviewer{model: ollamaModel(model), timeout: ollamaTimeout}, nil
+	case ReviewerNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown reviewer %q (must be openai, anthropic, ollama, or none)", name)
+	}
+}
+
+// fallbackReviewer returns the reviewer that stands in for Gemini, or an
+// error explaining why there is none
+func fallbackReviewer(name, model string, disabled bool) (Reviewer, error) {
+	if disabled {
+		return nil, fmt.Errorf("fallback disabled by --no-openai-fallback")
+	}
+	r, err := NewReviewer(name, model)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, fmt.Errorf("reviewer is %s", ReviewerNone)
+	}
+	return r, nil
+}
+
+// askForSecondOpinion sends the review prompt to r and parses its JSON verdict
+func askForSecondOpinion(ctx context.Context, r Reviewer, prompt string, brief *AudioBrief, opts PromptOptions) (SecondOpinionResult, error) {
+	var result SecondOpinionResult
+	if opts.Debug {
+		opts.logger().Infof("Second-opinion reviewer: %s", r.Name())
+	}
+	responseText, err := r.Ask(withUsageCall(ctx, "review"), "", buildReviewPrompt(prompt, brief, opts), nil, "")
+	if err != nil {
+		return result, err
+	}
+	if err := json.Unmarshal([]byte(cleanJSONResponse(responseText)), &result); err != nil {
+		return result, fmt.Errorf("failed to parse %s review JSON: %w", r.Name(), err)
+	}
+	return result, nil
+}
+
+// openAIReviewer reviews through the OpenAI Responses API
+type openAIReviewer struct {
+	apiKey  string
+	model   string
+	timeout time.Duration
+}
+
+func (r *openAIReviewer) Name() string {
+	return fmt.Sprintf("OpenAI (%s)", r.model)
+}
+
+func (r *openAIReviewer) Review(ctx context.Context, prompt string, brief *AudioBrief, opts PromptOptions) (SecondOpinionResult, error) {
+	return askForSecondOpinion(ctx, r, prompt, brief, opts)
+}
+
+func (r *openAIReviewer) Ask(ctx context.Context, system, prompt string, imageData []byte, mimeType string) (string, error) {
+	var input []map[string]interface{}
+	if system != "" {
+		input = append(input, map[string]interface{}{
+			"role": "system",
+			"content": []map[string]interface{}{
+				{"type": "input_text", "text": system},
+			},
+		})
+	}
+	content := []map[string]interface{}{
+		{"type": "input_text", "text": prompt},
+	}
+	if imageData != nil {
+		imageBase64 := base64.StdEncoding.EncodeToString(imageData)
+		content = append(content, map[string]interface{}{
+			"type":      "input_image",
+			"image_url": fmt.Sprintf("data:%s;base64,%s", mimeType, imageBase64),
+		})
+	}
+	input = append(input, map[string]interface{}{"role": "user", "content": content})
+
+	requestBody := map[string]interface{}{
+		"model": r.model,
+		"input": input,
+	}
+	if imageData != nil {
+		requestBody["max_output_tokens"] = openAIVisionMaxTokens
+	} else {
+		requestBody["text"] = map[string]interface{}{
+			"format": map[string]string{"type": "text"},
+		}
+	}
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal OpenAI request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", openAIURL(openAIResponsesPath), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create OpenAI request: %w", err)
+	}
+	setOpenAIHeaders(req, r.apiKey)
+
+	client := httpx.Client(r.timeout)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("OpenAI request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("OpenAI API error %d: %s", resp.StatusCode, string(body))
+	}
+
+	// Parse the Responses API format
+	var responsesResp struct {
+		Output []struct {
+			Content []struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"output"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&responsesResp); err != nil {
+		return "", fmt.Errorf("failed to decode OpenAI response: %w", err)
+	}
+	recordUsage(ctx, "", r.model, responsesResp.Usage.InputTokens, responsesResp.Usage.OutputTokens)
+
+	for _, output := range responsesResp.Output {
+		for _, content := range output.Content {
+			if (content.Type == "output_text" || content.Type == "text") && content.Text != "" {
+				return content.Text, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no text response from OpenAI")
+}
+
+// anthropicReviewer reviews through the Anthropic Messages API
+type anthropicReviewer struct {
+	apiKey  string
+	model   string
+	timeout time.Duration
+}
+
+func (r *anthropicReviewer) Name() string {
+	return fmt.Sprintf("Anthropic (%s)", r.model)
+}
+
+func (r *anthropicReviewer) Review(ctx context.Context, prompt string, brief *AudioBrief, opts PromptOptions) (SecondOpinionResult, error) {
+	return askForSecondOpinion(ctx, r, prompt, brief, opts)
+}
+
+func (r *anthropicReviewer) Ask(ctx context.Context, system, prompt string, imageData []byte, mimeType string) (string, error) {
+	var content []map[string]interface{}
+	if imageData != nil {
+		content = append(content, map[string]interface{}{
+			"type": "image",
+			"source": map[string]string{
+				"type":       "base64",
+				"media_type": mimeType,
+				"data":       base64.StdEncoding.EncodeToString(imageData),
+			},
+		})
+	}
+	content = append(content, map[string]interface{}{"type": "text", "text": prompt})
+
+	requestBody := map[string]interface{}{
+		"model":      r.model,
+		"max_tokens": anthropicMaxTokens,
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": content},
+		},
+	}
+	if system != "" {
+		requestBody["system"] = system
+	}
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal Anthropic request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", anthropicURL(anthropicMessagesPath), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create Anthropic request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", r.apiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+
+	client := httpx.Client(r.timeout)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Anthropic request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("Anthropic API error %d: %s", resp.StatusCode, string(body))
+	}
+
+	var messagesResp struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&messagesResp); err != nil {
+		return "", fmt.Errorf("failed to decode Anthropic response: %w", err)
+	}
+	recordUsage(ctx, "", r.model, messagesResp.Usage.InputTokens, messagesResp.Usage.OutputTokens)
+
+	for _, content := range messagesResp.Content {
+		if content.Type == "text" && content.Text != "" {
+			return content.Text, nil
+		}
+	}
+	return "", fmt.Errorf("no text response from Anthropic")
+}
+
+// ollamaReviewer reviews, and writes metadata-only prompts, with a local
+// model through Ollama's /api/chat
+type ollamaReviewer struct {
+	model   string
+	timeout time.Duration
+}
+
+func (r *ollamaReviewer) Name() string {
+	return fmt.Sprintf("Ollama (%s)", r.model)
+}
+
+func (r *ollamaReviewer) Review(ctx context.Context, prompt string, brief *AudioBrief, opts PromptOptions) (SecondOpinionResult, error) {
+	return askForSecondOpinion(ctx, r, prompt, brief, opts)
+}
+
+func (r *ollamaReviewer) Ask(ctx context.Context, system, prompt string, imageData []byte, mimeType string) (string, error) {
+	var messages []map[string]interface{}
+	if system != "" {
+		messages = append(messages, map[string]interface{}{"role": "system", "content": system})
+	}
+	message := map[string]interface{}{"role": "user", "content": prompt}
+	if imageData != nil {
+		message["images"] = []string{base64.StdEncoding.EncodeToString(imageData)}
+	}
+	messages = append(messages, message)
+
+	requestBody := map[string]interface{}{
+		"model":    r.model,
+		"messages": messages,
+		"stream":   false,
+	}
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal Ollama request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", ollamaURL("/api/chat"), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create Ollama request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := httpx.Client(r.timeout)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Ollama request failed (is ollama running at %s?): %w", ollamaURL(""), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("Ollama API error %d: %s", resp.StatusCode, string(body))
+	}
+
+	var chatResp struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		PromptEvalCount int `json:"prompt_eval_count"`
+		EvalCount       int `json:"eval_count"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return "", fmt.Errorf("failed to decode Ollama response: %w", err)
+	}
+	recordUsage(ctx, "", localModelPrefix+r.model, chatResp.PromptEvalCount, chatResp.EvalCount)
+
+	if chatResp.Message.Content == "" {
+		return "", fmt.Errorf("no text response from Ollama")
+	}
+	return chatResp.Message.Content, nil
+}
+
+// ollamaModel picks the Ollama model: explicit value, then OLLAMA_MODEL, then
+// the default
+func ollamaModel(model string) string {
+	if model != "" {
+		return model
+	}
+	if env := os.Getenv("OLLAMA_MODEL"); env != "" {
+		return env
+	}
+	return DefaultOllamaModel
+}
+
+// ollamaURL returns the URL of an Ollama API path under OLLAMA_HOST, which
+// (as with the ollama CLI) may omit the scheme
+func ollamaURL(path string) string {
+	host := os.Getenv("OLLAMA_HOST")
+	if host == "" {
+		host = defaultOllamaHost
+	}
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	return strings.TrimSuffix(host, "/") + path
+}
+
+// GenerateMetadataPrompt writes and reviews an image prompt with the
+// opts.Reviewer model from the title, notes and caption alone, without Gemini.
+// It backs offline use (--llm ollama); the audio itself is never analyzed.
+func GenerateMetadataPrompt(ctx context.Context, audioPath string, opts PromptOptions) (*PromptResult, error) {
+	if opts.StylePreference == "" {
+		opts.StylePreference = StyleAuto
+	}
+	if opts.Title == "" {
+		opts.Title = strings.TrimSuffix(filepath.Base(audioPath), filepath.Ext(audioPath))
+	}
+
+	r, err := NewReviewer(opts.Reviewer, opts.ReviewModel)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, fmt.Errorf("metadata-only prompts need a reviewer model, not %s", ReviewerNone)
+	}
+	opts.logger().Warnf("Audio analysis needs Gemini; %s will write the prompt from the title and notes only", r.Name())
+
+	usage := &Usage{}
+	ctx = withUsage(ctx, usage)
+
+	result, err := generatePromptWithReviewer(ctx, r, audioPath, opts)
+	if err != nil {
+		return nil, err
 	}
+	result.Usage = usage
+
+	opts.progress(StageReview, "Getting second opinion from %s...", r.Name())
+	reviewed, err := reviewPrompt(ctx, r, result.Prompt, nil, opts)
+	if err != nil {
+		// Non-fatal - keep the unreviewed prompt
+		opts.logger().Warnf("Second opinion review failed, using original prompt: %v", err)
+	}
+	result.Prompt = reviewed
+	result.Prompts = []string{reviewed}
+	return result, nil
+}
+
+// generatePromptWithReviewer creates an image prompt with the reviewer model when Gemini is unavailable
+// This skips audio analysis and works only with the available metadata (title, notes, caption, subcaption)
+func generatePromptWithReviewer(ctx context.Context, r Reviewer, audioPath string, opts PromptOptions) (*PromptResult, error) {
+	opts.progress(StagePrompt, "Generating image prompt with %s (no audio analysis)...", r.Name())
+
+	systemPrompt, userPrompt := buildMetadataPrompt(opts)
+	combinedPrompt := fmt.Sprintf("%s\n\n---\n\n%s", systemPrompt, userPrompt)
 
-	if !opts.Quiet {
-		log.Printf("Generating image prompt with OpenAI (no audio analysis)...")
+	promptText, err := r.Ask(withUsageCall(ctx, "fallback-prompt"), "", combinedPrompt, nil, "")
+	if err != nil {
+		return nil, err
 	}
 
-	// Build the prompt for OpenAI
+	promptText = cleanPromptOutput(promptText)
+
+	opts.logger().Warnf("Image prompt generated via %s fallback (no audio analysis performed)", r.Name())
+
+	return &PromptResult{
+		Prompt:        promptText,
+		Title:         opts.Title,
+		AudioFile:     audioPath,
+		Style:         opts.StylePreference,
+		Timestamp:     time.Now(),
+		AudioAnalysis: "", // No audio analysis in fallback mode
+		Fallback:      true,
+	}, nil
+}
+
+// buildMetadataPrompt returns the system and user prompts for writing an
+// image prompt from the title, notes and caption, without audio analysis
+func buildMetadataPrompt(opts PromptOptions) (string, string) {
 	systemPrompt := `You are an Ideogram prompt writer creating image prompts for music cover art.
 You do NOT have access to the audio file - work only with the provided metadata.
 
@@ -506,121 +2029,64 @@ Title/caption "%s" is prominently displayed.
 		userPrompt.WriteString(fmt.Sprintf(`TEXT OVERLAY (START PROMPT WITH THIS EXACT FORMAT):
 Text "%s" is prominently displayed.
 
-`, opts.Subcaption))
-	}
-
-	userPrompt.WriteString(fmt.Sprintf(`AVAILABLE CONTEXT:
-- Title: %s
-- User notes/direction: %s
-- Style preference: %s
-
-Based on this context, create a compelling visual that would work as cover art for this music. Infer the mood, genre, and appropriate imagery from the title and notes.`,
-		opts.Title,
-		opts.Notes,
-		opts.StylePreference,
-	))
-
-	combinedPrompt := fmt.Sprintf("%s\n\n---\n\n%s", systemPrompt, userPrompt.String())
-
-	// Make the OpenAI API call
-	requestBody := map[string]interface{}{
-		"model": "gpt-5.2-pro",
-		"input": []map[string]interface{}{
-			{
-				"role": "user",
-				"content": []map[string]string{
-					{"type": "input_text", "text": combinedPrompt},
-				},
-			},
-		},
-		"text": map[string]interface{}{
-			"format": map[string]string{"type": "text"},
-		},
-	}
-
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal OpenAI request: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/responses", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create OpenAI request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 120 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("OpenAI request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("OpenAI API error %d: %s", resp.StatusCode, string(body))
+`, opts.Subcaption))
 	}
 
-	// Parse the responses API format
-	var responsesResp struct {
-		Output []struct {
-			Content []struct {
-				Type string `json:"type"`
-				Text string `json:"text"`
-			} `json:"content"`
-		} `json:"output"`
-	}
+	userPrompt.WriteString(fmt.Sprintf(`AVAILABLE CONTEXT:
+- Title: %s
+- User notes/direction: %s
+- Style preference: %s
 
-	if err := json.NewDecoder(resp.Body).Decode(&responsesResp); err != nil {
-		return nil, fmt.Errorf("failed to decode OpenAI response: %w", err)
+Based on this context, create a compelling visual that would work as cover art for this music. Infer the mood, genre, and appropriate imagery from the title and notes.`,
+		opts.Title,
+		opts.Notes,
+		opts.StylePreference,
+	))
+	if len(opts.Avoid) > 0 {
+		userPrompt.WriteString("\n\nMUST AVOID: " + strings.Join(opts.Avoid, ", "))
 	}
 
-	// Extract text from the response
-	var promptText string
-	for _, output := range responsesResp.Output {
-		for _, content := range output.Content {
-			if content.Type == "output_text" {
-				promptText = content.Text
-				break
-			}
-		}
-		if promptText != "" {
-			break
-		}
-	}
+	return systemPrompt, userPrompt.String()
+}
 
-	if promptText == "" {
-		return nil, fmt.Errorf("no text response from OpenAI")
+// reviewPrompt gets a second opinion from the reviewer on the generated prompt
+// It checks if the prompt makes sense given the audio analysis and original request
+func reviewPrompt(ctx context.Context, r Reviewer, prompt string, brief *AudioBrief, opts PromptOptions) (string, error) {
+	requiredTextOverlayPrefix := buildRequiredTextOverlayPrefix(opts)
+	result, err := r.Review(ctx, prompt, brief, opts)
+	if err != nil {
+		return prompt, err
 	}
 
-	promptText = cleanPromptOutput(promptText)
-
-	logWarning("Image prompt generated via OpenAI fallback (no audio analysis performed)")
-
-	return &PromptResult{
-		Prompt:        promptText,
-		Title:         opts.Title,
-		AudioFile:     audioPath,
-		Style:         opts.StylePreference,
-		Timestamp:     time.Now(),
-		AudioAnalysis: "", // No audio analysis in fallback mode
-	}, nil
-}
+	if result.Approved {
+		opts.logger().Infof("✓ Second opinion: Prompt approved - %s", result.Reason)
+		return prompt, nil
+	}
 
-func reviewPromptWithOpenAI(prompt string, brief *AudioBrief, opts PromptOptions) (string, error) {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		// If no OpenAI key, skip second opinion and return original prompt
-		logWarning("OPENAI_API_KEY not set, skipping second-opinion review")
+	// Prompt was flagged - use the improved version
+	if result.ImprovedPrompt == "" {
+		opts.logger().Warnf("Prompt flagged but no improvement provided, using original")
 		return prompt, nil
 	}
 
+	opts.logger().Infof("⚡ Second opinion: Prompt improved - %s", result.Reason)
+	improved := cleanPromptOutput(result.ImprovedPrompt)
+	if requiredTextOverlayPrefix != "" {
+		improved = enforceRequiredTextOverlayPrefix(improved, requiredTextOverlayPrefix)
+	}
+	return improved, nil
+}
+
+// buildReviewPrompt assembles the second-opinion request: reviewer
+// instructions, the audio brief, the original request, and the prompt
+func buildReviewPrompt(prompt string, brief *AudioBrief, opts PromptOptions) string {
 	requiredTextOverlayPrefix := buildRequiredTextOverlayPrefix(opts)
 
 	// Build the review request
-	briefSummary := fmt.Sprintf(`Audio Analysis:
+	briefSummary := `Audio Analysis:
+- Not available: the prompt was written from the title and notes only, so judge it against those`
+	if brief != nil {
+		briefSummary = fmt.Sprintf(`Audio Analysis:
 - Genre: %s
 - Energy: %d/10
 - Mood: %s
@@ -628,14 +2094,15 @@ func reviewPromptWithOpenAI(prompt string, brief *AudioBrief, opts PromptOptions
 - Lyric themes: %s
 - Central metaphor: %s
 - Visual elements suggested: %s`,
-		brief.Genre,
-		brief.Energy,
-		strings.Join(brief.MoodAdjectives, ", "),
-		strings.Join(brief.ProminentInstruments, ", "),
-		brief.LyricThemes,
-		brief.CentralMetaphor,
-		strings.Join(brief.VisualNouns, ", "),
-	)
+			brief.Genre,
+			brief.Energy,
+			strings.Join(brief.MoodAdjectives, ", "),
+			strings.Join(brief.ProminentInstruments, ", "),
+			brief.LyricThemes,
+			brief.CentralMetaphor,
+			strings.Join(brief.VisualNouns, ", "),
+		)
+	}
 
 	requestContext := fmt.Sprintf(`Original Request:
 - Title: %s
@@ -697,8 +2164,8 @@ Output ONLY valid JSON:
 If approved, improved_prompt should be empty string "".
 If not approved, provide an improved prompt that fixes the issues while preserving the good elements and any required text overlays.`
 
-	// Combine system and user prompt for the responses API
-	combinedPrompt := fmt.Sprintf(`%s
+	// Combine system and user prompt into a single request
+	return fmt.Sprintf(`%s
 
 ---
 
@@ -715,115 +2182,10 @@ Generated Image Prompt:
 Review this prompt. Does it make intuitive sense for this audio/request, or is it weird/disconnected? Output JSON only.`,
 		systemPrompt,
 		briefSummary,
-		requestContext,
+		requestContext+promptLanguageNote(opts.Language),
 		requiredTextOverlayPrefix,
 		prompt,
 	)
-
-	// Make the OpenAI API call using the /v1/responses endpoint for gpt-5.2-pro
-	requestBody := map[string]interface{}{
-		"model": "gpt-5.2-pro",
-		"input": []map[string]interface{}{
-			{
-				"role": "user",
-				"content": []map[string]string{
-					{"type": "input_text", "text": combinedPrompt},
-				},
-			},
-		},
-		"text": map[string]interface{}{
-			"format": map[string]string{"type": "text"},
-		},
-	}
-
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		logWarning("Failed to marshal OpenAI request, using original prompt: %v", err)
-		return prompt, nil
-	}
-
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/responses", bytes.NewBuffer(jsonData))
-	if err != nil {
-		logWarning("Failed to create OpenAI request, using original prompt: %v", err)
-		return prompt, nil
-	}
-
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 120 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		logWarning("OpenAI request failed, using original prompt: %v", err)
-		return prompt, nil
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		logWarning("OpenAI API error %d: %s, using original prompt", resp.StatusCode, string(body))
-		return prompt, nil
-	}
-
-	// Parse the responses API format
-	var responsesResp struct {
-		Output []struct {
-			Content []struct {
-				Type string `json:"type"`
-				Text string `json:"text"`
-			} `json:"content"`
-		} `json:"output"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&responsesResp); err != nil {
-		logWarning("Failed to decode OpenAI response, using original prompt: %v", err)
-		return prompt, nil
-	}
-
-	// Extract text from the response
-	var responseText string
-	for _, output := range responsesResp.Output {
-		for _, content := range output.Content {
-			if content.Type == "output_text" {
-				responseText = content.Text
-				break
-			}
-		}
-		if responseText != "" {
-			break
-		}
-	}
-
-	if responseText == "" {
-		logWarning("No text response from OpenAI, using original prompt")
-		return prompt, nil
-	}
-
-	// Parse the JSON response
-	responseText = cleanJSONResponse(responseText)
-	var result SecondOpinionResult
-	if err := json.Unmarshal([]byte(responseText), &result); err != nil {
-		logWarning("Failed to parse OpenAI review JSON, using original prompt: %v", err)
-		return prompt, nil
-	}
-
-	if result.Approved {
-		log.Printf("✓ Second opinion: Prompt approved - %s", result.Reason)
-		return prompt, nil
-	}
-
-	// Prompt was flagged - use the improved version
-	if result.ImprovedPrompt == "" {
-		logWarning("Prompt flagged but no improvement provided, using original")
-		return prompt, nil
-	}
-
-	log.Printf("⚡ Second opinion: Prompt improved - %s", result.Reason)
-	improved := cleanPromptOutput(result.ImprovedPrompt)
-	if requiredTextOverlayPrefix != "" {
-		improved = enforceRequiredTextOverlayPrefix(improved, requiredTextOverlayPrefix)
-	}
-	return improved, nil
 }
 
 func buildRequiredTextOverlayPrefix(opts PromptOptions) string {
@@ -888,17 +2250,342 @@ func cleanPromptOutput(s string) string {
 	return strings.TrimSpace(s)
 }
 
-func cleanJSONResponse(s string) string {
-	s = strings.TrimSpace(s)
-	// Remove markdown code blocks if present
-	s = strings.TrimPrefix(s, "```json")
-	s = strings.TrimPrefix(s, "```")
-	s = strings.TrimSuffix(s, "```")
-	return strings.TrimSpace(s)
+// GenerateTitle asks an LLM for a short (3-6 word) descriptive title for text.
+// Gemini is used when copts has a key (or GEMINI_API_KEY is set), with OpenAI
+// as the fallback.
+func GenerateTitle(text string, copts ClientOptions) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", fmt.Errorf("no text to title")
+	}
+	if len(text) > maxTitleInput {
+		// Cut on a rune boundary so multi-byte text stays valid UTF-8
+		cut := maxTitleInput
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut]
+	}
+
+	prompt := fmt.Sprintf(`Write a descriptive title of 3 to 6 words for the following spoken text.
+Reply with the title only: no quotes, no trailing punctuation, no preamble.
+
+TEXT:
+%s`, text)
+
+	var geminiErr error
+	if copts.hasGeminiAccess() {
+		ctx, cancel := context.WithTimeout(context.Background(), titleTimeout)
+		defer cancel()
+
+		client, err := NewClientWithConfig(ctx, copts)
+		if err == nil {
+			contents := []*genai.Content{{Role: "user", Parts: []*genai.Part{{Text: prompt}}}}
+			var resp *genai.GenerateContentResponse
+			resp, err = client.client.Models.GenerateContent(ctx, TitleModel, contents, nil)
+			if err == nil {
+				if title := cleanTitle(extractResponseText(resp)); title != "" {
+					return title, nil
+				}
+				err = fmt.Errorf("empty title from Gemini")
+			}
+		}
+		geminiErr = err
+	}
+
+	if os.Getenv("OPENAI_API_KEY") == "" {
+		if geminiErr != nil {
+			return "", fmt.Errorf("failed to generate title with Gemini: %w", geminiErr)
+		}
+		return "", fmt.Errorf("no GEMINI_API_KEY or OPENAI_API_KEY set for title generation")
+	}
+	if geminiErr != nil {
+		defaultLogger.Warnf("Gemini title generation failed (%v), falling back to OpenAI", geminiErr)
+	}
+
+	responseText, err := openAIResponseText(context.Background(), OpenAITitleModel, prompt, titleTimeout)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate title with OpenAI: %w", err)
+	}
+	title := cleanTitle(responseText)
+	if title == "" {
+		return "", fmt.Errorf("empty title from OpenAI")
+	}
+	return title, nil
+}
+
+// DescribeReferenceImage asks Gemini to describe the visual language of a
+// reference image (palette, composition, medium, typography, mood) so it can be
+// appended to a prompt for image providers without a remix endpoint
+func DescribeReferenceImage(imagePath string, copts ClientOptions) (string, error) {
+	imageData, err := os.ReadFile(imagePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read reference image: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+	defer cancel()
+
+	client, err := NewClientWithConfig(ctx, copts)
+	if err != nil {
+		return "", err
+	}
+
+	prompt := `Describe the visual language of this image so another image can share it: color palette, lighting, composition, medium or rendering style, typography treatment, and mood.
+Do not describe the specific subject matter. Reply with one paragraph of at most 80 words and no preamble.`
+
+	contents := []*genai.Content{
+		{
+			Role: "user",
+			Parts: []*genai.Part{
+				{Text: prompt},
+				{InlineData: &genai.Blob{
+					MIMEType: getImageMimeType(imagePath),
+					Data:     imageData,
+				}},
+			},
+		},
+	}
+
+	resp, err := client.client.Models.GenerateContent(ctx, TitleModel, contents, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to describe reference image: %w", err)
+	}
+
+	desc := cleanPromptOutput(extractResponseText(resp))
+	if desc == "" {
+		return "", fmt.Errorf("empty reference image description from Gemini")
+	}
+	return desc, nil
+}
+
+// cleanTitle keeps the first line of a model reply and strips quotes, a
+// "Title:" label, and trailing punctuation
+func cleanTitle(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSpace(s)
+	for _, label := range []string{"Title:", "title:", "TITLE:"} {
+		s = strings.TrimSpace(strings.TrimPrefix(s, label))
+	}
+	s = strings.Trim(s, `"'“”*`)
+	s = strings.TrimRight(s, ".!;:, ")
+	return strings.TrimSpace(s)
+}
+
+// openAIResponseText sends a single text prompt to the OpenAI Responses API
+// and returns the first output text
+func openAIResponseText(ctx context.Context, model, prompt string, timeout time.Duration) (string, error) {
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		return "", fmt.Errorf("OPENAI_API_KEY not set")
+	}
+	r := &openAIReviewer{apiKey: apiKey, model: model, timeout: timeout}
+	return r.Ask(ctx, "", prompt, nil, "")
+}
+
+// isQuotaError reports whether err is a Gemini rate-limit or quota error
+func isQuotaError(err error) bool {
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "429") || strings.Contains(msg, "quota") || strings.Contains(msg, "RESOURCE_EXHAUSTED")
+}
+
+// retryInfoDelay returns the delay Gemini asked for in a RetryInfo error
+// detail, if any
+func retryInfoDelay(err error) (time.Duration, bool) {
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) {
+		return 0, false
+	}
+	for _, detail := range apiErr.Details {
+		if t, _ := detail["@type"].(string); !strings.HasSuffix(t, "google.rpc.RetryInfo") {
+			continue
+		}
+		if v, ok := detail["retryDelay"].(string); ok {
+			if d, err := time.ParseDuration(v); err == nil && d > 0 {
+				return d, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// withQuotaRetry calls fn and retries it up to retries times while it fails
+// with a quota error, backing off exponentially or as RetryInfo directs.
+// The last error is returned once retries are exhausted.
+func withQuotaRetry(ctx context.Context, logger Logger, retries int, what string, fn func() error) error {
+	_, err := retry.Do(ctx, retry.Policy{
+		Attempts:  retries + 1,
+		Retryable: isQuotaError,
+		Delay: func(attempt int, err error) time.Duration {
+			if d, ok := retryInfoDelay(err); ok {
+				return min(d, quotaRetryMaxDelay)
+			}
+			return retry.Backoff(attempt, quotaRetryBaseDelay, quotaRetryMaxDelay, false)
+		},
+		OnRetry: func(attempt int, wait time.Duration, err error) {
+			logger.Warnf("Gemini quota exceeded during %s, retrying in %s (%d/%d)", what, wait, attempt, retries)
+		},
+	}, fn)
+	return err
+}
+
+func cleanJSONResponse(s string) string {
+	s = strings.TrimSpace(s)
+	// Remove markdown code blocks if present
+	s = strings.TrimPrefix(s, "```json")
+	s = strings.TrimPrefix(s, "```")
+	s = strings.TrimSuffix(s, "```")
+	return strings.TrimSpace(s)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// add records one call; a nil Usage ignores it
+func (u *Usage) add(call CallUsage) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.Calls = append(u.Calls, call)
+}
+
+// Merge appends the calls of other, e.g. to total a batch run
+func (u *Usage) Merge(other *Usage) {
+	if u == nil || other == nil {
+		return
+	}
+	other.mu.Lock()
+	calls := append([]CallUsage(nil), other.Calls...)
+	other.mu.Unlock()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.Calls = append(u.Calls, calls...)
+}
+
+// Totals returns the summed input and output tokens of all calls
+func (u *Usage) Totals() (input, output int) {
+	if u == nil {
+		return 0, 0
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, call := range u.Calls {
+		input += call.InputTokens
+		output += call.OutputTokens
+	}
+	return input, output
+}
+
+// EstimatedCost returns the estimated USD cost of all calls, along with the
+// models that have no known price and were left out of the estimate
+func (u *Usage) EstimatedCost() (float64, []string) {
+	if u == nil {
+		return 0, nil
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var cost float64
+	var unpriced []string
+	for _, call := range u.Calls {
+		model := strings.TrimPrefix(call.Model, "models/")
+		if strings.HasPrefix(model, localModelPrefix) {
+			continue
+		}
+		price, ok := modelPrices[model]
+		if !ok {
+			if !slices.Contains(unpriced, model) {
+				unpriced = append(unpriced, model)
+			}
+			continue
+		}
+		cost += (float64(call.InputTokens)*price[0] + float64(call.OutputTokens)*price[1]) / 1e6
+	}
+	return cost, unpriced
+}
+
+// Summary renders the usage as one line, e.g.
+// "tokens: 48k audio+text in / 600 out, est. $0.11"
+func (u *Usage) Summary() string {
+	input, output := u.Totals()
+	cost, unpriced := u.EstimatedCost()
+	summary := fmt.Sprintf("tokens: %s audio+text in / %s out, est. $%.2f", formatTokenCount(input), formatTokenCount(output), cost)
+	if len(unpriced) > 0 {
+		summary += fmt.Sprintf(" (no price for %s)", strings.Join(unpriced, ", "))
+	}
+	return summary
+}
+
+// formatTokenCount abbreviates a token count: 600, 1.2k, 48k, 1.5M
+func formatTokenCount(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	case n >= 10_000:
+		return fmt.Sprintf("%.0fk", float64(n)/1e3)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1e3)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+type usageKey struct{}
+
+// usageScope is the context value calls record their usage into; call names
+// the pipeline step for reviewer calls, which don't know it themselves
+type usageScope struct {
+	usage *Usage
+	call  string
+}
+
+// withUsage makes calls made with the returned context record into u
+func withUsage(ctx context.Context, u *Usage) context.Context {
+	return context.WithValue(ctx, usageKey{}, usageScope{usage: u})
+}
+
+// withUsageCall labels the reviewer calls made with the returned context
+func withUsageCall(ctx context.Context, call string) context.Context {
+	scope, ok := ctx.Value(usageKey{}).(usageScope)
+	if !ok {
+		return ctx
+	}
+	scope.call = call
+	return context.WithValue(ctx, usageKey{}, scope)
 }
 
-func ptr[T any](v T) *T {
-	return &v
+// recordUsage adds a call to the context's Usage, if any. An empty call takes
+// the label set by withUsageCall.
+func recordUsage(ctx context.Context, call, model string, input, output int) {
+	scope, ok := ctx.Value(usageKey{}).(usageScope)
+	if !ok {
+		return
+	}
+	if call == "" {
+		call = scope.call
+	}
+	if call == "" {
+		call = "other"
+	}
+	scope.usage.add(CallUsage{Call: call, Model: model, InputTokens: input, OutputTokens: output})
+}
+
+// recordGeminiUsage records the usage metadata of a Gemini response
+func recordGeminiUsage(ctx context.Context, call, model string, resp *genai.GenerateContentResponse) {
+	if resp == nil || resp.UsageMetadata == nil {
+		return
+	}
+	m := resp.UsageMetadata
+	recordUsage(ctx, call, model, int(m.PromptTokenCount), int(m.CandidatesTokenCount+m.ThoughtsTokenCount))
 }
 
 func extractResponseText(resp *genai.GenerateContentResponse) string {
@@ -951,47 +2638,167 @@ func IsAudioFile(path string) bool {
 
 // ImageValidationResult contains the result of image validation
 type ImageValidationResult struct {
+	Path         string // The validated image
 	IsAcceptable bool
 	Score        float64 // Overall quality score 1.0-10.0
 	Issues       []string
 	Suggestions  []string
 	Caption      string // What caption was found (if any)
 	Subcaption   string // What subcaption was found (if any)
+
+	// The Issues that retry feedback acts on, classified by kind
+	Failures []*ValidationFailure
+}
+
+// Kinds of ValidationFailure
+var (
+	ErrCaptionMismatch         = errors.New("caption mismatch")
+	ErrCaptionMissing          = errors.New("caption missing")
+	ErrSubcaptionMismatch      = errors.New("subcaption mismatch")
+	ErrSubcaptionMissing       = errors.New("subcaption missing")
+	ErrHallucinatedInstruments = errors.New("hallucinated instruments")
+)
+
+// ValidationFailure is an image validation issue of a known kind. Kind is one
+// of the Err values above, so errors.Is identifies it; Message is the issue as
+// listed in Issues, and Seen what the image showed instead (the rendered text,
+// or the instruments).
+type ValidationFailure struct {
+	Kind    error
+	Message string
+	Seen    string
+}
+
+func (f *ValidationFailure) Error() string {
+	return f.Message
+}
+
+func (f *ValidationFailure) Unwrap() error {
+	return f.Kind
+}
+
+// fail rejects the image for a failure of kind, listing message as an issue
+func (r *ImageValidationResult) fail(kind error, seen, message string) {
+	r.IsAcceptable = false
+	r.Issues = append(r.Issues, message)
+	r.Failures = append(r.Failures, &ValidationFailure{Kind: kind, Message: message, Seen: seen})
 }
 
 // PromptValidationResult contains the result of validating an image against its prompt
 type PromptValidationResult struct {
-	PromptMatch       bool     // Does the image match the prompt's intent?
-	TextRendered      bool     // Is the text rendered correctly?
-	CasingCorrect     bool     // Is the text casing as expected?
-	CasingAppropriate bool     // Is the casing stylistically appropriate even if different?
-	Issues            []string // List of issues found
-	Suggestions       []string // Suggestions for improvement
+	PromptMatch       bool              // Does the image match the prompt's intent?
+	TextRendered      bool              // Is the text rendered correctly?
+	CasingCorrect     bool              // Is the text casing as expected?
+	CasingAppropriate bool              // Is the casing stylistically appropriate even if different?
+	Score             float64           // Overall score 1.0-10.0 (0 when only the plain-text fallback parsed)
+	InstrumentsSeen   []string          // Musical instruments visible in the image
+	Issues            []ValidationIssue // Issues found, each with a severity
+	Suggestions       []string          // Suggestions for improvement
+}
+
+// Severity ranks a prompt validation issue
+type Severity string
+
+const (
+	SeverityBlocker Severity = "blocker" // Regenerate: e.g. hallucinated instruments, missing text
+	SeverityWarning Severity = "warning" // Noticeable deviation that may still be acceptable
+	SeverityInfo    Severity = "info"    // Minor deviation, e.g. slightly different lighting
+)
+
+// ValidationIssue is one problem found by prompt validation
+type ValidationIssue struct {
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// IssuesWithSeverity returns the messages of the issues with severity s
+func (r *PromptValidationResult) IssuesWithSeverity(s Severity) []string {
+	var messages []string
+	for _, issue := range r.Issues {
+		if issue.Severity == s {
+			messages = append(messages, issue.Message)
+		}
+	}
+	return messages
+}
+
+// HasBlocker reports whether any issue calls for regenerating the image
+func (r *PromptValidationResult) HasBlocker() bool {
+	return len(r.IssuesWithSeverity(SeverityBlocker)) > 0
 }
 
 // ValidateGeneratedImage is a convenience function that creates a client and validates an image
-func ValidateGeneratedImage(imagePath, expectedCaption, expectedSubcaption string) (*ImageValidationResult, error) {
-	ctx := context.Background()
-	client, err := NewClient(ctx)
+func ValidateGeneratedImage(ctx context.Context, imagePath, expectedCaption, expectedSubcaption string, vopts ValidationOptions) (*ImageValidationResult, error) {
+	client, err := NewClientWithConfig(ctx, vopts.Client)
 	if err != nil {
 		return nil, err
 	}
-	return client.ValidateImage(imagePath, expectedCaption, expectedSubcaption)
+	return client.ValidateImage(ctx, imagePath, expectedCaption, expectedSubcaption, vopts)
 }
 
 // ValidateImageAgainstPrompt validates that a generated image matches the prompt intent
-func ValidateImageAgainstPrompt(imagePath, prompt, expectedCaption, expectedSubcaption string) (*PromptValidationResult, error) {
-	ctx := context.Background()
-	client, err := NewClient(ctx)
+func ValidateImageAgainstPrompt(ctx context.Context, imagePath, prompt, expectedCaption, expectedSubcaption string, vopts ValidationOptions) (*PromptValidationResult, error) {
+	client, err := NewClientWithConfig(ctx, vopts.Client)
 	if err != nil {
 		return nil, err
 	}
-	return client.ValidateImageAgainstPrompt(imagePath, prompt, expectedCaption, expectedSubcaption)
+	return client.ValidateImageAgainstPrompt(ctx, imagePath, prompt, expectedCaption, expectedSubcaption, vopts)
+}
+
+// validationDefaults fills vopts' Logger and Progress from the client
+func (c *Client) validationDefaults(vopts ValidationOptions) ValidationOptions {
+	if vopts.Logger == nil {
+		vopts.Logger = c.Logger
+	}
+	if vopts.Progress == nil {
+		vopts.Progress = c.Progress
+	}
+	return vopts
+}
+
+// sameModel reports whether a and b name the same Gemini model, ignoring a
+// models/ prefix, case, and a -latest or numeric version suffix such as -001
+func sameModel(a, b string) bool {
+	return normalizeModel(a) == normalizeModel(b)
+}
+
+func normalizeModel(name string) string {
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "models/"))
+	name = strings.TrimSuffix(name, "-latest")
+	if i := strings.LastIndexByte(name, '-'); i > 0 && len(name)-i == 4 {
+		if _, err := strconv.Atoi(name[i+1:]); err == nil {
+			name = name[:i]
+		}
+	}
+	return name
+}
+
+// generateValidation runs a validation request on the validation model,
+// retrying once on DefaultModel if the faster model's call fails
+func (c *Client) generateValidation(ctx context.Context, what string, contents []*genai.Content, config *genai.GenerateContentConfig, vopts ValidationOptions) (*genai.GenerateContentResponse, error) {
+	generate := func(model string) (*genai.GenerateContentResponse, error) {
+		var resp *genai.GenerateContentResponse
+		err := withQuotaRetry(ctx, vopts.logger(), vopts.Retries, what, func() error {
+			var err error
+			resp, err = c.client.Models.GenerateContent(ctx, model, contents, config)
+			return err
+		})
+		return resp, err
+	}
+
+	model := vopts.model()
+	resp, err := generate(model)
+	if err == nil || sameModel(model, DefaultModel) || ctx.Err() != nil {
+		return resp, err
+	}
+	vopts.logger().Warnf("%s with %s failed (%v), retrying with %s", what, model, err, DefaultModel)
+	return generate(DefaultModel)
 }
 
 // ValidateImageAgainstPrompt validates that an image matches its generation prompt
-func (c *Client) ValidateImageAgainstPrompt(imagePath, prompt, expectedCaption, expectedSubcaption string) (*PromptValidationResult, error) {
-	log.Printf("Validating image against prompt with Gemini...")
+func (c *Client) ValidateImageAgainstPrompt(ctx context.Context, imagePath, prompt, expectedCaption, expectedSubcaption string, vopts ValidationOptions) (*PromptValidationResult, error) {
+	vopts = c.validationDefaults(vopts)
+	vopts.progress(StageValidate, "Validating image against prompt with Gemini...")
 
 	// Read the image file
 	imageData, err := os.ReadFile(imagePath)
@@ -1003,7 +2810,7 @@ func (c *Client) ValidateImageAgainstPrompt(imagePath, prompt, expectedCaption,
 	mimeType := getImageMimeType(imagePath)
 
 	// Build the comprehensive validation prompt
-	validationPrompt := buildPromptValidationPrompt(prompt, expectedCaption, expectedSubcaption)
+	validationPrompt := buildPromptValidationPrompt(prompt, expectedCaption, expectedSubcaption, vopts.Casing, vopts.Language)
 
 	// Build the content with image
 	contents := []*genai.Content{
@@ -1019,21 +2826,30 @@ func (c *Client) ValidateImageAgainstPrompt(imagePath, prompt, expectedCaption,
 		},
 	}
 
-	resp, err := c.client.Models.GenerateContent(c.ctx, DefaultModel, contents, nil)
+	config := &genai.GenerateContentConfig{
+		Temperature:      ptr(float32(0.1)), // Low temperature for consistent output
+		ResponseMIMEType: "application/json",
+	}
+
+	resp, err := c.generateValidation(ctx, "prompt validation", contents, config, vopts)
 	if err != nil {
-		// Check if this is a quota error - if so, fall back to OpenAI
-		if strings.Contains(err.Error(), "429") || strings.Contains(err.Error(), "quota") || strings.Contains(err.Error(), "RESOURCE_EXHAUSTED") {
-			logWarning("Gemini quota exceeded, falling back to OpenAI for prompt validation")
-			return validateImageAgainstPromptWithOpenAI(imagePath, imageData, mimeType, prompt, expectedCaption, expectedSubcaption)
+		// Retries are exhausted on a quota error - fall back to the reviewer
+		if isQuotaError(err) {
+			r, rerr := fallbackReviewer(vopts.Reviewer, vopts.ReviewModel, vopts.NoFallback)
+			if rerr != nil {
+				return nil, fmt.Errorf("Gemini quota exceeded and cannot fall back for validation (%v): %w", rerr, err)
+			}
+			vopts.logger().Warnf("Gemini quota exceeded, falling back to %s for prompt validation", r.Name())
+			return validateImageAgainstPromptWithReviewer(ctx, r, imageData, mimeType, prompt, expectedCaption, expectedSubcaption, vopts)
 		}
 		return nil, fmt.Errorf("failed to validate image: %w", err)
 	}
 
 	responseText := extractResponseText(resp)
-	return parsePromptValidationResponse(responseText, expectedCaption, expectedSubcaption), nil
+	return parsePromptValidationResponse(vopts.logger(), responseText, expectedCaption, expectedSubcaption, vopts.Casing), nil
 }
 
-func buildPromptValidationPrompt(originalPrompt, expectedCaption, expectedSubcaption string) string {
+func buildPromptValidationPrompt(originalPrompt, expectedCaption, expectedSubcaption string, casing CasingPolicy, language string) string {
 	prompt := fmt.Sprintf(`You are a quality control reviewer for AI-generated images. Analyze this image against its generation prompt and provide a detailed assessment.
 
 ORIGINAL PROMPT:
@@ -1080,7 +2896,21 @@ VALIDATION CRITERIA:
      * Answer: RENDERED or MISSING or DISTORTED`, expectedSubcaption)
 		}
 
-		prompt += `
+		prompt += captionLanguageNote(language)
+
+		switch casing {
+		case CasingAny:
+			// Casing is not judged, so there is no casing section to answer
+		case CasingExact:
+			prompt += `
+
+5. TEXT CASING:
+   - Acceptable casing: exact match only, character for character
+   - ALL CAPS, all lowercase, or any other casing that differs from expected is NOT acceptable
+   - For example: only "Better Than My Breath" is OK for "Better Than My Breath"
+   - Answer: EXACT_MATCH or UNACCEPTABLE`
+		default:
+			prompt += `
 
 5. TEXT CASING:
    - Acceptable casing: exact match, ALL CAPS, or all lowercase
@@ -1088,47 +2918,175 @@ VALIDATION CRITERIA:
    - For example: "BETTER THAN MY BREATH" or "better than my breath" are OK for "Better Than My Breath"
    - But "Better than my Breath" is NOT OK for "Better Than My Breath"
    - Answer: EXACT_MATCH, ALL_CAPS, ALL_LOWER, or UNACCEPTABLE`
+		}
 	}
 
 	prompt += `
 
-RESPOND IN THIS EXACT FORMAT:
-PROMPT_MATCH: MATCH or NO_MATCH
-QUALITY: GOOD or POOR
-INSTRUMENTS_STATUS: INSTRUMENTS_CORRECT or INSTRUMENTS_WRONG
-INSTRUMENTS_SEEN: [list any instruments visible in image, or "none"]`
+Output ONLY this JSON (no markdown, no explanation):
+{
+  "prompt_match": true/false (MATCH = true),
+  "quality": "GOOD" or "POOR",
+  "instruments_wrong": true/false (INSTRUMENTS_WRONG = true),
+  "instruments_seen": ["every instrument visible in the image; empty if none"],`
 
 	if expectedCaption != "" {
 		prompt += `
-CAPTION_STATUS: RENDERED or MISSING or DISTORTED`
+  "caption_status": "RENDERED" or "MISSING" or "DISTORTED",`
 	}
 	if expectedSubcaption != "" {
 		prompt += `
-SUBCAPTION_STATUS: RENDERED or MISSING or DISTORTED`
+  "subcaption_status": "RENDERED" or "MISSING" or "DISTORTED",`
 	}
-	if expectedCaption != "" || expectedSubcaption != "" {
-		prompt += `
-TEXT_CASING: EXACT_MATCH or ALL_CAPS or ALL_LOWER or UNACCEPTABLE`
+	if (expectedCaption != "" || expectedSubcaption != "") && casing != CasingAny {
+		if casing == CasingExact {
+			prompt += `
+  "text_casing": "EXACT_MATCH" or "UNACCEPTABLE",`
+		} else {
+			prompt += `
+  "text_casing": "EXACT_MATCH" or "ALL_CAPS" or "ALL_LOWER" or "UNACCEPTABLE",`
+		}
 	}
 
 	prompt += `
-ISSUES: List any specific issues found (or "None")
-SUGGESTIONS: List any suggestions for improvement (or "None")
+  "score": 1.0-10.0 (overall: 10 = faithful and flawless, below 6 = should be regenerated),
+  "issues": [{"severity": "blocker" or "warning" or "info", "message": "specific issue"}],
+  "suggestions": ["suggestions for improvement"]
+}
+
+ISSUE SEVERITY:
+- blocker: the image must be regenerated (hallucinated or wrong instruments, missing/misspelled/distorted text, wrong subject, AI artifacts, anything offensive)
+- warning: a noticeable deviation from the prompt that may still be acceptable (different palette, composition, or setting details)
+- info: a minor deviation that does not matter (slightly different lighting, small stylistic liberties)
 
 Be constructive but honest. The goal is to identify images that need regeneration.`
 
 	return prompt
 }
 
-func parsePromptValidationResponse(response, expectedCaption, expectedSubcaption string) *PromptValidationResult {
+// PromptValidationJSON is the expected JSON output of prompt validation
+type PromptValidationJSON struct {
+	PromptMatch      bool              `json:"prompt_match"`
+	Quality          string            `json:"quality"`
+	InstrumentsWrong bool              `json:"instruments_wrong"`
+	InstrumentsSeen  []string          `json:"instruments_seen"`
+	CaptionStatus    string            `json:"caption_status"`
+	SubcaptionStatus string            `json:"subcaption_status"`
+	TextCasing       string            `json:"text_casing"`
+	Score            float64           `json:"score"`
+	Issues           []ValidationIssue `json:"issues"`
+	Suggestions      []string          `json:"suggestions"`
+}
+
+// parsePromptValidationResponse decodes a JSON prompt validation, falling
+// back to the plain-text format when the reply is not valid JSON
+func parsePromptValidationResponse(logger Logger, response, expectedCaption, expectedSubcaption string, casing CasingPolicy) *PromptValidationResult {
+	var validation PromptValidationJSON
+	if err := json.Unmarshal([]byte(cleanJSONResponse(response)), &validation); err != nil {
+		logger.Warnf("Failed to parse prompt validation JSON, using fallback: %v", err)
+		return parsePromptValidationText(response, expectedCaption, expectedSubcaption, casing)
+	}
+
+	result := &PromptValidationResult{
+		PromptMatch:       validation.PromptMatch,
+		TextRendered:      true,
+		CasingCorrect:     true,
+		CasingAppropriate: true,
+		Score:             validation.Score,
+		Issues:            []ValidationIssue{},
+		Suggestions:       []string{},
+	}
+	if result.Score > 0 && result.Score < 1.0 {
+		result.Score = 1.0
+	} else if result.Score > 10.0 {
+		result.Score = 10.0
+	}
+
+	for _, instrument := range validation.InstrumentsSeen {
+		if instrument = strings.TrimSpace(instrument); instrument != "" && !strings.EqualFold(instrument, "none") {
+			result.InstrumentsSeen = append(result.InstrumentsSeen, instrument)
+		}
+	}
+	if validation.InstrumentsWrong {
+		result.PromptMatch = false
+		message := "Image contains instruments not specified in prompt"
+		if len(result.InstrumentsSeen) > 0 {
+			message = fmt.Sprintf("Hallucinated instruments in image: %s", strings.Join(result.InstrumentsSeen, ", "))
+		}
+		result.Issues = append(result.Issues, ValidationIssue{Severity: SeverityBlocker, Message: message})
+	}
+
+	for _, text := range []struct{ label, expected, status string }{
+		{"Caption", expectedCaption, validation.CaptionStatus},
+		{"Subcaption", expectedSubcaption, validation.SubcaptionStatus},
+	} {
+		status := strings.ToUpper(text.status)
+		if text.expected != "" && (strings.Contains(status, "MISSING") || strings.Contains(status, "DISTORTED")) {
+			result.TextRendered = false
+			result.Issues = append(result.Issues, ValidationIssue{
+				Severity: SeverityBlocker,
+				Message:  fmt.Sprintf("%s '%s' %s", text.label, text.expected, strings.ToLower(status)),
+			})
+		}
+	}
+	if validation.TextCasing != "" {
+		result.CasingCorrect, result.CasingAppropriate = judgeTextCasing(casing, strings.ToUpper(validation.TextCasing), result.CasingCorrect)
+	}
+
+	for _, issue := range validation.Issues {
+		issue.Message = strings.TrimSpace(issue.Message)
+		if issue.Message == "" || strings.EqualFold(issue.Message, "none") {
+			continue
+		}
+		switch issue.Severity = Severity(strings.ToLower(strings.TrimSpace(string(issue.Severity)))); issue.Severity {
+		case SeverityBlocker, SeverityWarning, SeverityInfo:
+		default:
+			issue.Severity = SeverityWarning
+		}
+		result.Issues = append(result.Issues, issue)
+	}
+	for _, suggestion := range validation.Suggestions {
+		if suggestion = strings.TrimSpace(suggestion); suggestion != "" && !strings.EqualFold(suggestion, "none") {
+			result.Suggestions = append(result.Suggestions, suggestion)
+		}
+	}
+
+	return result
+}
+
+// judgeTextCasing applies a TEXT_CASING answer under the casing policy,
+// returning whether the casing is correct and stylistically appropriate.
+// An unrecognized answer keeps current.
+func judgeTextCasing(casing CasingPolicy, answer string, current bool) (correct, appropriate bool) {
+	switch {
+	case casing == CasingAny:
+		// Casing was not asked for; ignore a stray answer
+		return true, true
+	case casing == CasingExact && (strings.Contains(answer, "ALL_CAPS") || strings.Contains(answer, "ALL_LOWER")):
+		return false, false
+	case strings.Contains(answer, "EXACT_MATCH") || strings.Contains(answer, "ALL_CAPS") || strings.Contains(answer, "ALL_LOWER"):
+		return true, true
+	case strings.Contains(answer, "UNACCEPTABLE"):
+		return false, false
+	}
+	return current, current
+}
+
+// parsePromptValidationText parses the older plain-text validation format;
+// issues it finds have no stated severity, so only hallucinated instruments
+// count as blockers
+func parsePromptValidationText(response, expectedCaption, expectedSubcaption string, casing CasingPolicy) *PromptValidationResult {
 	result := &PromptValidationResult{
 		PromptMatch:       true,
 		TextRendered:      true,
 		CasingCorrect:     true,
 		CasingAppropriate: true,
-		Issues:            []string{},
+		Issues:            []ValidationIssue{},
 		Suggestions:       []string{},
 	}
+	warning := func(message string) ValidationIssue {
+		return ValidationIssue{Severity: SeverityWarning, Message: message}
+	}
 
 	lines := strings.Split(response, "\n")
 	var inIssues, inSuggestions bool
@@ -1144,23 +3102,22 @@ func parsePromptValidationResponse(response, expectedCaption, expectedSubcaption
 		} else if strings.HasPrefix(upperLine, "INSTRUMENTS_STATUS:") {
 			if strings.Contains(upperLine, "WRONG") {
 				result.PromptMatch = false
-				result.Issues = append(result.Issues, "Image contains instruments not specified in prompt")
+				result.Issues = append(result.Issues, ValidationIssue{Severity: SeverityBlocker, Message: "Image contains instruments not specified in prompt"})
 			}
 		} else if strings.HasPrefix(upperLine, "INSTRUMENTS_SEEN:") {
 			instruments := strings.TrimPrefix(line, "INSTRUMENTS_SEEN:")
 			instruments = strings.TrimPrefix(instruments, "Instruments_seen:")
 			instruments = strings.TrimSpace(instruments)
 			if instruments != "" && !strings.EqualFold(instruments, "none") && !strings.EqualFold(instruments, "[none]") {
-				// Check if this was already flagged as wrong
-				for _, issue := range result.Issues {
-					if strings.Contains(issue, "instruments") {
-						// Update the issue with specific instruments
-						for i, iss := range result.Issues {
-							if strings.Contains(iss, "instruments not specified") {
-								result.Issues[i] = fmt.Sprintf("Hallucinated instruments in image: %s", instruments)
-								break
-							}
-						}
+				for _, instrument := range strings.Split(strings.Trim(instruments, "[]"), ",") {
+					if instrument = strings.TrimSpace(instrument); instrument != "" {
+						result.InstrumentsSeen = append(result.InstrumentsSeen, instrument)
+					}
+				}
+				// Name the instruments in an instrument issue already flagged
+				for i, issue := range result.Issues {
+					if strings.Contains(issue.Message, "instruments not specified") {
+						result.Issues[i].Message = fmt.Sprintf("Hallucinated instruments in image: %s", instruments)
 						break
 					}
 				}
@@ -1170,13 +3127,7 @@ func parsePromptValidationResponse(response, expectedCaption, expectedSubcaption
 				result.TextRendered = false
 			}
 		} else if strings.HasPrefix(upperLine, "TEXT_CASING:") {
-			if strings.Contains(upperLine, "EXACT_MATCH") || strings.Contains(upperLine, "ALL_CAPS") || strings.Contains(upperLine, "ALL_LOWER") {
-				result.CasingCorrect = true
-				result.CasingAppropriate = true
-			} else if strings.Contains(upperLine, "UNACCEPTABLE") {
-				result.CasingCorrect = false
-				result.CasingAppropriate = false
-			}
+			result.CasingCorrect, result.CasingAppropriate = judgeTextCasing(casing, upperLine, result.CasingCorrect)
 		} else if strings.HasPrefix(upperLine, "ISSUES:") {
 			inIssues = true
 			inSuggestions = false
@@ -1184,7 +3135,7 @@ func parsePromptValidationResponse(response, expectedCaption, expectedSubcaption
 			issueText = strings.TrimPrefix(issueText, "Issues:")
 			issueText = strings.TrimSpace(issueText)
 			if issueText != "" && !strings.EqualFold(issueText, "None") {
-				result.Issues = append(result.Issues, issueText)
+				result.Issues = append(result.Issues, warning(issueText))
 			}
 		} else if strings.HasPrefix(upperLine, "SUGGESTIONS:") {
 			inSuggestions = true
@@ -1203,7 +3154,7 @@ func parsePromptValidationResponse(response, expectedCaption, expectedSubcaption
 			issue := strings.TrimPrefix(line, "-")
 			issue = strings.TrimSpace(issue)
 			if issue != "" && !strings.EqualFold(issue, "None") {
-				result.Issues = append(result.Issues, issue)
+				result.Issues = append(result.Issues, warning(issue))
 			}
 		} else if inSuggestions && strings.HasPrefix(line, "-") {
 			sugg := strings.TrimPrefix(line, "-")
@@ -1231,12 +3182,73 @@ type TextValidationJSON struct {
 }
 
 // ValidateImage uses Gemini to check if the generated image has the expected text rendered correctly
-func (c *Client) ValidateImage(imagePath string, expectedCaption, expectedSubcaption string) (*ImageValidationResult, error) {
+func (c *Client) ValidateImage(ctx context.Context, imagePath string, expectedCaption, expectedSubcaption string, vopts ValidationOptions) (*ImageValidationResult, error) {
+	result, err := c.validateImage(ctx, imagePath, expectedCaption, expectedSubcaption, vopts)
+	if result != nil {
+		result.Path = imagePath
+	}
+	return result, err
+}
+
+// ValidateImages validates many images with this client, at most concurrency
+// at a time (0 = DefaultValidationConcurrency). Each image retries and falls
+// back to the reviewer on its own, so a quota error costs one image its
+// Gemini verdict, not the batch. Results follow the order of paths; an image
+// that could not be validated has a nil result and its error is joined into
+// the returned error.
+func (c *Client) ValidateImages(ctx context.Context, paths []string, expectedCaption, expectedSubcaption string, concurrency int, vopts ValidationOptions) ([]*ImageValidationResult, error) {
+	if concurrency < 1 {
+		concurrency = DefaultValidationConcurrency
+	}
+
+	results := make([]*ImageValidationResult, len(paths))
+	errs := make([]error, len(paths))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, path := range paths {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result, err := c.ValidateImage(ctx, path, expectedCaption, expectedSubcaption, vopts)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", path, err)
+				return
+			}
+			results[i] = result
+		}(i, path)
+	}
+	wg.Wait()
+
+	failed, quota := 0, 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+			if isQuotaError(err) {
+				quota++
+			}
+		}
+	}
+	if failed > 0 {
+		summary := fmt.Sprintf("%d of %d images could not be validated", failed, len(paths))
+		if quota > 0 {
+			summary += fmt.Sprintf(" (%d over Gemini quota with no fallback)", quota)
+		}
+		return results, fmt.Errorf("%s: %w", summary, errors.Join(errs...))
+	}
+	return results, nil
+}
+
+// validateImage is ValidateImage without the result's Path
+func (c *Client) validateImage(ctx context.Context, imagePath string, expectedCaption, expectedSubcaption string, vopts ValidationOptions) (*ImageValidationResult, error) {
 	if expectedCaption == "" && expectedSubcaption == "" {
 		return &ImageValidationResult{IsAcceptable: true}, nil
 	}
 
-	log.Printf("Validating generated image with Gemini...")
+	vopts = c.validationDefaults(vopts)
+	vopts.progress(StageValidate, "Validating generated image with Gemini...")
 
 	imageData, err := os.ReadFile(imagePath)
 	if err != nil {
@@ -1246,7 +3258,7 @@ func (c *Client) ValidateImage(imagePath string, expectedCaption, expectedSubcap
 	mimeType := getImageMimeType(imagePath)
 
 	// Build JSON-output validation prompt
-	validationPrompt := buildJSONValidationPrompt(expectedCaption, expectedSubcaption)
+	validationPrompt := buildJSONValidationPrompt(expectedCaption, expectedSubcaption, vopts.Casing, vopts.Language)
 
 	systemInstruction := &genai.Content{
 		Parts: []*genai.Part{
@@ -1272,21 +3284,25 @@ func (c *Client) ValidateImage(imagePath string, expectedCaption, expectedSubcap
 		Temperature:       ptr(float32(0.1)), // Low temperature for consistent output
 	}
 
-	resp, err := c.client.Models.GenerateContent(c.ctx, DefaultModel, contents, config)
+	resp, err := c.generateValidation(ctx, "image validation", contents, config, vopts)
 	if err != nil {
-		// Check if this is a quota error - if so, fall back to OpenAI
-		if strings.Contains(err.Error(), "429") || strings.Contains(err.Error(), "quota") || strings.Contains(err.Error(), "RESOURCE_EXHAUSTED") {
-			logWarning("Gemini quota exceeded, falling back to OpenAI for image validation")
-			return validateImageWithOpenAI(imagePath, imageData, mimeType, expectedCaption, expectedSubcaption)
+		// Retries are exhausted on a quota error - fall back to the reviewer
+		if isQuotaError(err) {
+			r, rerr := fallbackReviewer(vopts.Reviewer, vopts.ReviewModel, vopts.NoFallback)
+			if rerr != nil {
+				return nil, fmt.Errorf("Gemini quota exceeded and cannot fall back for validation (%v): %w", rerr, err)
+			}
+			vopts.logger().Warnf("Gemini quota exceeded, falling back to %s for image validation", r.Name())
+			return validateImageWithReviewer(ctx, r, imageData, mimeType, expectedCaption, expectedSubcaption, vopts)
 		}
 		return nil, fmt.Errorf("failed to validate image: %w", err)
 	}
 
 	responseText := extractResponseText(resp)
-	return parseJSONValidationResponse(responseText, expectedCaption, expectedSubcaption)
+	return parseJSONValidationResponse(vopts.logger(), responseText, expectedCaption, expectedSubcaption, vopts.Casing)
 }
 
-func buildJSONValidationPrompt(expectedCaption, expectedSubcaption string) string {
+func buildJSONValidationPrompt(expectedCaption, expectedSubcaption string, casing CasingPolicy, language string) string {
 	prompt := `Examine this image and validate the text rendering.
 
 Expected text to find:`
@@ -1302,6 +3318,7 @@ Expected text to find:`
 		prompt += fmt.Sprintf(`
 - Subcaption: "%s"`, expectedSubcaption)
 	}
+	prompt += captionLanguageNote(language)
 
 	prompt += `
 
@@ -1325,7 +3342,24 @@ Output ONLY this JSON (no markdown, no explanation):
   "reason": "brief explanation if FAIL, or empty if PASS"
 }
 
-SCORING (score field) - evaluate how well the image meets quality standards:
+SCORING (score field) - evaluate how well the image meets quality standards:`
+
+	if casing == CasingAny {
+		prompt += `
+- 10.0: Perfect - text correct in any casing, image looks professional and realistic
+- 8.0-9.9: Excellent - text correct, image high quality with no major artifacts
+- 6.0-7.9: Good - text readable and correctly spelled, minor image quality issues
+- 4.0-5.9: Fair - text has spelling errors OR noticeable visual artifacts/unrealistic elements
+- 1.0-3.9: Poor - text missing/illegible OR major visual problems (AI artifacts)
+
+IMPORTANT SCORING NOTES:
+- Casing is NOT a factor: do not lower the score for capitalization differences
+- Only spelling errors, missing text, or visual artifacts should drop score below 6.0
+
+Primary factors (in order): text spelling accuracy, visual realism.
+`
+	} else {
+		prompt += `
 - 10.0: Perfect - text correct with exact or acceptable casing, image looks professional and realistic
 - 8.0-9.9: Excellent - text correct, image high quality with no major artifacts
 - 6.0-7.9: Good - text readable and correctly spelled but casing differs, OR minor image quality issues
@@ -1338,7 +3372,10 @@ IMPORTANT SCORING NOTES:
 - Only spelling errors, missing text, or visual artifacts should drop score below 6.0
 
 Primary factors (in order): text spelling accuracy, visual realism, text casing.
+`
+	}
 
+	prompt += `
 AI GENERATION ARTIFACTS TO CHECK FOR (FAIL if present):
 - Glass/crystal shatter effects on soft materials (fabric, cloth, curtains, skin)
 - Fabric that looks cracked, shattered, or broken like glass instead of torn naturally
@@ -1370,16 +3407,29 @@ RULES:`
 	}
 
 	prompt += `
-- Minor stylistic differences (tilde vs hyphen) are acceptable
+- Minor stylistic differences (tilde vs hyphen) are acceptable`
+
+	switch casing {
+	case CasingAny:
+		prompt += `
+- Casing does NOT matter: any capitalization of correctly spelled text is acceptable (e.g., "better than MY breath" for "Better Than My Breath")`
+	case CasingExact:
+		prompt += `
+- Casing must match EXACTLY, character for character; ALL CAPS or all lowercase renderings of mixed-case text are NOT acceptable`
+	default:
+		prompt += `
 - Casing must match EXACTLY, OR be ALL CAPS, OR be all lowercase (these are the only acceptable casing variations)
-- Mixed case that differs from the expected input is NOT acceptable (e.g., "Better than my Breath" when expecting "Better Than My Breath")
+- Mixed case that differs from the expected input is NOT acceptable (e.g., "Better than my Breath" when expecting "Better Than My Breath")`
+	}
+
+	prompt += `
 - FAIL if the image contains anything offensive or inappropriate BEYOND what was requested in the prompt (e.g., unintentional shapes resembling body parts, crude imagery, or unfortunate visual double-meanings)
 - verdict: "PASS" if all expected text is rendered correctly with acceptable casing AND image is appropriate, "FAIL" otherwise`
 
 	return prompt
 }
 
-func parseJSONValidationResponse(response, expectedCaption, expectedSubcaption string) (*ImageValidationResult, error) {
+func parseJSONValidationResponse(logger Logger, response, expectedCaption, expectedSubcaption string, casing CasingPolicy) (*ImageValidationResult, error) {
 	result := &ImageValidationResult{
 		IsAcceptable: true,
 		Issues:       []string{},
@@ -1392,7 +3442,7 @@ func parseJSONValidationResponse(response, expectedCaption, expectedSubcaption s
 	var validation TextValidationJSON
 	if err := json.Unmarshal([]byte(response), &validation); err != nil {
 		// Fallback to old parsing method if JSON fails
-		logWarning("Failed to parse validation JSON, using fallback: %v", err)
+		logger.Warnf("Failed to parse validation JSON, using fallback: %v", err)
 		return parseValidationResponseFallback(response, expectedCaption, expectedSubcaption), nil
 	}
 
@@ -1406,33 +3456,39 @@ func parseJSONValidationResponse(response, expectedCaption, expectedSubcaption s
 
 	// Check for hallucinated instruments
 	if validation.InstrumentsWrong && len(validation.InstrumentsSeen) > 0 {
-		result.IsAcceptable = false
-		result.Issues = append(result.Issues, fmt.Sprintf("Hallucinated instruments in image: %s", strings.Join(validation.InstrumentsSeen, ", ")))
+		instruments := strings.Join(validation.InstrumentsSeen, ", ")
+		result.fail(ErrHallucinatedInstruments, instruments, "Hallucinated instruments in image: "+instruments)
 		result.Suggestions = append(result.Suggestions, "Regenerate without musical instruments or specify correct instruments in prompt")
 	}
 
+	// The model's casing judgement is only as good as its prompt; settle it
+	// from the transcribed text where that is unambiguous
+	validation.CaptionOK = applyCasingPolicy(casing, validation.CaptionOK, expectedCaption, validation.CaptionSeen)
+	validation.SubcaptionOK = applyCasingPolicy(casing, validation.SubcaptionOK, expectedSubcaption, validation.SubcaptionSeen)
+
 	// Populate result from JSON
 	if expectedCaption != "" && !validation.CaptionOK {
-		result.IsAcceptable = false
 		if validation.CaptionSeen != "" {
-			result.Issues = append(result.Issues, fmt.Sprintf("Caption mismatch: expected '%s', saw '%s'", expectedCaption, validation.CaptionSeen))
+			result.fail(ErrCaptionMismatch, validation.CaptionSeen, fmt.Sprintf("Caption mismatch: expected '%s', saw '%s'", expectedCaption, validation.CaptionSeen))
 		} else {
-			result.Issues = append(result.Issues, fmt.Sprintf("Caption '%s' not found", expectedCaption))
+			result.fail(ErrCaptionMissing, "", fmt.Sprintf("Caption '%s' not found", expectedCaption))
 		}
 		result.Caption = validation.CaptionSeen
 	}
 
 	if expectedSubcaption != "" && !validation.SubcaptionOK {
-		result.IsAcceptable = false
 		if validation.SubcaptionSeen != "" {
-			result.Issues = append(result.Issues, fmt.Sprintf("Subcaption mismatch: expected '%s', saw '%s'", expectedSubcaption, validation.SubcaptionSeen))
+			result.fail(ErrSubcaptionMismatch, validation.SubcaptionSeen, fmt.Sprintf("Subcaption mismatch: expected '%s', saw '%s'", expectedSubcaption, validation.SubcaptionSeen))
 		} else {
-			result.Issues = append(result.Issues, fmt.Sprintf("Subcaption '%s' not found", expectedSubcaption))
+			result.fail(ErrSubcaptionMissing, "", fmt.Sprintf("Subcaption '%s' not found", expectedSubcaption))
 		}
 		result.Subcaption = validation.SubcaptionSeen
 	}
 
-	if validation.Verdict == "FAIL" {
+	// Under CasingAny a FAIL over casing alone does not fail the image
+	casingOnlyFail := casing == CasingAny && result.IsAcceptable && isCasingReason(validation.Reason)
+
+	if validation.Verdict == "FAIL" && !casingOnlyFail {
 		result.IsAcceptable = false
 		if validation.Reason != "" && !containsIssue(result.Issues, validation.Reason) {
 			result.Issues = append(result.Issues, validation.Reason)
@@ -1447,6 +3503,34 @@ func parseJSONValidationResponse(response, expectedCaption, expectedSubcaption s
 	return result, nil
 }
 
+// applyCasingPolicy corrects a caption_ok verdict when the seen text matches
+// the expected text apart from casing: CasingAny accepts it and CasingExact
+// rejects anything but an exact match. Strict keeps the model's verdict.
+func applyCasingPolicy(casing CasingPolicy, ok bool, expected, seen string) bool {
+	if expected == "" || seen == "" {
+		return ok
+	}
+	expected = strings.Join(strings.Fields(expected), " ")
+	seen = strings.Join(strings.Fields(seen), " ")
+	if !strings.EqualFold(expected, seen) {
+		return ok
+	}
+	switch casing {
+	case CasingAny:
+		return true
+	case CasingExact:
+		return ok && expected == seen
+	default:
+		return ok
+	}
+}
+
+// isCasingReason reports whether a validation failure reason is about casing
+func isCasingReason(reason string) bool {
+	lower := strings.ToLower(reason)
+	return strings.Contains(lower, "casing") || strings.Contains(lower, "capitaliz") || strings.Contains(lower, "uppercase") || strings.Contains(lower, "lowercase")
+}
+
 func containsIssue(issues []string, needle string) bool {
 	for _, issue := range issues {
 		if strings.Contains(issue, needle) {
@@ -1502,191 +3586,33 @@ func parseValidationResponseFallback(response, expectedCaption, expectedSubcapti
 	return result
 }
 
-// validateImageAgainstPromptWithOpenAI validates an image against its prompt using OpenAI when Gemini is unavailable
-func validateImageAgainstPromptWithOpenAI(imagePath string, imageData []byte, mimeType, prompt, expectedCaption, expectedSubcaption string) (*PromptValidationResult, error) {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("OPENAI_API_KEY not set - cannot fall back to OpenAI for validation")
-	}
-
-	log.Printf("Validating image against prompt with OpenAI...")
-
-	validationPrompt := buildPromptValidationPrompt(prompt, expectedCaption, expectedSubcaption)
-
-	// Encode image to base64
-	imageBase64 := base64.StdEncoding.EncodeToString(imageData)
-
-	// Build OpenAI Responses API request with vision content
-	requestBody := map[string]interface{}{
-		"model": "gpt-5.2-pro",
-		"input": []map[string]interface{}{
-			{
-				"role": "user",
-				"content": []map[string]interface{}{
-					{"type": "input_text", "text": validationPrompt},
-					{"type": "input_image", "image_url": fmt.Sprintf("data:%s;base64,%s", mimeType, imageBase64)},
-				},
-			},
-		},
-		"max_output_tokens": 1000,
-	}
-
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal OpenAI request: %w", err)
-	}
+// validateImageAgainstPromptWithReviewer validates an image against its prompt using the reviewer when Gemini is unavailable
+func validateImageAgainstPromptWithReviewer(ctx context.Context, r Reviewer, imageData []byte, mimeType, prompt, expectedCaption, expectedSubcaption string, vopts ValidationOptions) (*PromptValidationResult, error) {
+	vopts.progress(StageValidate, "Validating image against prompt with %s...", r.Name())
 
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/responses", bytes.NewBuffer(jsonData))
+	validationPrompt := buildPromptValidationPrompt(prompt, expectedCaption, expectedSubcaption, vopts.Casing, vopts.Language)
+	responseText, err := r.Ask(ctx, "", validationPrompt, imageData, mimeType)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create OpenAI request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 120 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("OpenAI request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("OpenAI API error %d: %s", resp.StatusCode, string(body))
-	}
-
-	// Parse the Responses API format
-	var responsesResp struct {
-		Output []struct {
-			Content []struct {
-				Type string `json:"type"`
-				Text string `json:"text"`
-			} `json:"content"`
-		} `json:"output"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&responsesResp); err != nil {
-		return nil, fmt.Errorf("failed to decode OpenAI response: %w", err)
-	}
-
-	// Extract first output_text
-	var responseText string
-	for _, output := range responsesResp.Output {
-		for _, content := range output.Content {
-			if content.Type == "output_text" || content.Type == "text" {
-				responseText = content.Text
-				break
-			}
-		}
-		if responseText != "" {
-			break
-		}
-	}
-
-	if responseText == "" {
-		return nil, fmt.Errorf("no text response from OpenAI")
+		return nil, err
 	}
 
-	logWarning("Image validated via OpenAI fallback")
-	return parsePromptValidationResponse(responseText, expectedCaption, expectedSubcaption), nil
+	vopts.logger().Warnf("Image validated via %s fallback", r.Name())
+	return parsePromptValidationResponse(vopts.logger(), responseText, expectedCaption, expectedSubcaption, vopts.Casing), nil
 }
 
-// validateImageWithOpenAI validates image text rendering using OpenAI when Gemini is unavailable
-func validateImageWithOpenAI(imagePath string, imageData []byte, mimeType, expectedCaption, expectedSubcaption string) (*ImageValidationResult, error) {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("OPENAI_API_KEY not set - cannot fall back to OpenAI for validation")
-	}
-
-	log.Printf("Validating image text with OpenAI...")
+// validateImageWithReviewer validates image text rendering using the reviewer when Gemini is unavailable
+func validateImageWithReviewer(ctx context.Context, r Reviewer, imageData []byte, mimeType, expectedCaption, expectedSubcaption string, vopts ValidationOptions) (*ImageValidationResult, error) {
+	vopts.progress(StageValidate, "Validating image text with %s...", r.Name())
 
-	validationPrompt := buildJSONValidationPrompt(expectedCaption, expectedSubcaption)
+	validationPrompt := buildJSONValidationPrompt(expectedCaption, expectedSubcaption, vopts.Casing, vopts.Language)
 	systemPrompt := "You are a strict QA reviewer for AI-generated images. Output ONLY valid JSON, no other text."
-
-	// Encode image to base64
-	imageBase64 := base64.StdEncoding.EncodeToString(imageData)
-
-	// Build OpenAI Responses API request with vision content
-	requestBody := map[string]interface{}{
-		"model": "gpt-5.2-pro",
-		"input": []map[string]interface{}{
-			{
-				"role": "system",
-				"content": []map[string]interface{}{
-					{"type": "input_text", "text": systemPrompt},
-				},
-			},
-			{
-				"role": "user",
-				"content": []map[string]interface{}{
-					{"type": "input_text", "text": validationPrompt},
-					{"type": "input_image", "image_url": fmt.Sprintf("data:%s;base64,%s", mimeType, imageBase64)},
-				},
-			},
-		},
-		"max_output_tokens": 1000,
-	}
-
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal OpenAI request: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/responses", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create OpenAI request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 120 * time.Second}
-	resp, err := client.Do(req)
+	responseText, err := r.Ask(ctx, systemPrompt, validationPrompt, imageData, mimeType)
 	if err != nil {
-		return nil, fmt.Errorf("OpenAI request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("OpenAI API error %d: %s", resp.StatusCode, string(body))
-	}
-
-	// Parse the Responses API format
-	var responsesResp struct {
-		Output []struct {
-			Content []struct {
-				Type string `json:"type"`
-				Text string `json:"text"`
-			} `json:"content"`
-		} `json:"output"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&responsesResp); err != nil {
-		return nil, fmt.Errorf("failed to decode OpenAI response: %w", err)
-	}
-
-	// Extract first output_text
-	var responseText string
-	for _, output := range responsesResp.Output {
-		for _, content := range output.Content {
-			if content.Type == "output_text" || content.Type == "text" {
-				responseText = content.Text
-				break
-			}
-		}
-		if responseText != "" {
-			break
-		}
-	}
-
-	if responseText == "" {
-		return nil, fmt.Errorf("no text response from OpenAI")
+		return nil, err
 	}
 
-	logWarning("Image validated via OpenAI fallback")
-	return parseJSONValidationResponse(responseText, expectedCaption, expectedSubcaption)
+	vopts.logger().Warnf("Image validated via %s fallback", r.Name())
+	return parseJSONValidationResponse(vopts.logger(), responseText, expectedCaption, expectedSubcaption, vopts.Casing)
 }
 
 func getImageMimeType(path string) string {
@@ -0,0 +1,1383 @@
+package genai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"mmmeld/internal/ffmpeg"
+	"mmmeld/internal/logx"
+	"mmmeld/internal/probe"
+	"mmmeld/internal/retry"
+
+	"google.golang.org/genai"
+)
+
+const validBriefJSON = `{
+  "genre": "synthwave",
+  "bpm": 104,
+  "energy": 6,
+  "mood_adjectives": ["nostalgic", "driving", "warm"],
+  "prominent_instruments": ["synth", "drums"],
+  "visual_nouns": ["chrome tail light", "rain-streaked window", "neon sign", "cassette deck", "highway overpass"],
+  "textures": ["wet asphalt", "brushed steel", "vinyl upholstery"],
+  "palette_colors": ["#ff2a6d", "#05d9e8", "#01012b"],
+  "central_metaphor": "A late-night drive as a way of outrunning memory",
+  "avoid": ["palm tree silhouette", "grid horizon", "sunset gradient"],
+  "lyric_themes": ""
+}`
+
+func TestParseAudioBrief(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expectError bool
+		genre       string
+	}{
+		{"valid", validBriefJSON, false, "synthwave"},
+		{"surrounding whitespace", "\n  " + validBriefJSON + "\n", false, "synthwave"},
+		{"markdown fence", "```json\n" + validBriefJSON + "\n```", true, ""},
+		{"truncated", validBriefJSON[:120], true, ""},
+		{"wrong field type", `{"genre": "folk", "energy": "high"}`, true, ""},
+		{"prose", "Here is the brief you asked for.", true, ""},
+		{"empty", "", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			brief, err := parseAudioBrief(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("parseAudioBrief(%q) = %+v, expected error", tt.input, brief)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAudioBrief(%q) unexpected error: %v", tt.input, err)
+			}
+			if brief.Genre != tt.genre {
+				t.Errorf("parseAudioBrief(%q).Genre = %q, expected %q", tt.input, brief.Genre, tt.genre)
+			}
+		})
+	}
+}
+
+func TestParseAudioBriefWithRepair(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		repaired    string
+		repairErr   error
+		expectError bool
+		repairCalls int
+		raw         string
+	}{
+		{"valid needs no repair", validBriefJSON, "", nil, false, 0, validBriefJSON},
+		{"repaired", validBriefJSON[:120], validBriefJSON, nil, false, 1, validBriefJSON},
+		{"repair still malformed", validBriefJSON[:120], "{not json", nil, true, 1, "{not json"},
+		{"repair request fails", validBriefJSON[:120], "", fmt.Errorf("quota"), true, 1, validBriefJSON[:120]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			brief, raw, err := parseAudioBriefWithRepair(tt.input, func(parseErr error) (string, error) {
+				calls++
+				if parseErr == nil {
+					t.Errorf("repair called without a parse error")
+				}
+				return tt.repaired, tt.repairErr
+			})
+			if calls != tt.repairCalls {
+				t.Errorf("repair called %d times, expected %d", calls, tt.repairCalls)
+			}
+			if raw != tt.raw {
+				t.Errorf("raw = %q, expected %q", raw, tt.raw)
+			}
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("parseAudioBriefWithRepair(%q) expected error", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAudioBriefWithRepair(%q) unexpected error: %v", tt.input, err)
+			}
+			if brief.Genre != "synthwave" || len(brief.VisualNouns) != 5 {
+				t.Errorf("parseAudioBriefWithRepair(%q) = %+v, expected the valid brief", tt.input, brief)
+			}
+		})
+	}
+}
+
+func TestParseAvoidList(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"", nil},
+		{"neon city", []string{"neon city"}},
+		{" neon city , vinyl record close-up ", []string{"neon city", "vinyl record close-up"}},
+		{"a,,b,", []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		got := ParseAvoidList(tt.input)
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("ParseAvoidList(%q) = %v, expected %v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestMergeAvoidTerms(t *testing.T) {
+	tests := []struct {
+		name     string
+		avoid    []string
+		extra    []string
+		expected []string
+	}{
+		{"no extra", []string{"sunrise", "dove"}, nil, []string{"sunrise", "dove"}},
+		{"appends", []string{"sunrise"}, []string{"neon city"}, []string{"sunrise", "neon city"}},
+		{"case-insensitive duplicate", []string{"Neon City"}, []string{"neon city", "vinyl"}, []string{"Neon City", "vinyl"}},
+		{"drops blanks", []string{"", "dove"}, []string{" "}, []string{"dove"}},
+	}
+
+	for _, tt := range tests {
+		got := mergeAvoidTerms(tt.avoid, tt.extra)
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: mergeAvoidTerms(%v, %v) = %v, expected %v", tt.name, tt.avoid, tt.extra, got, tt.expected)
+		}
+	}
+}
+
+func TestCleanPromptOutputKeepsAccents(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`Title/caption "Coração Valente" is prominently displayed.`, `Title/caption "Coração Valente" is prominently displayed.`},
+		{"\"Canção da manhã, pão e café\"", "Canção da manhã, pão e café"},
+		{"Prompt: Letreiro \"SÃO JOÃO\"\nsobre azulejos", "Letreiro \"SÃO JOÃO\" sobre azulejos"},
+		{"  Título “Ñandú” — über ação  ", "Título “Ñandú” — über ação"},
+	}
+
+	for _, tt := range tests {
+		got := cleanPromptOutput(tt.input)
+		if got != tt.expected {
+			t.Errorf("cleanPromptOutput(%q) = %q, expected %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestCaptionLanguageNote(t *testing.T) {
+	if note := captionLanguageNote(""); note != "" {
+		t.Errorf("captionLanguageNote(%q) = %q, expected empty", "", note)
+	}
+	prompt := buildJSONValidationPrompt("Coração Valente", "", CasingStrict, "pt-BR")
+	for _, want := range []string{`"Coração Valente"`, "pt-BR", "diacritics"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("buildJSONValidationPrompt(pt-BR) missing %q", want)
+		}
+	}
+}
+
+func TestCasingPolicyPrompts(t *testing.T) {
+	tests := []struct {
+		casing        CasingPolicy
+		textRule      string // Expected in the text validation prompt
+		casingSection string // Expected in the prompt validation prompt ("" = none)
+	}{
+		{CasingStrict, "Casing must match EXACTLY, OR be ALL CAPS, OR be all lowercase", `"EXACT_MATCH" or "ALL_CAPS" or "ALL_LOWER" or "UNACCEPTABLE"`},
+		{CasingAny, "Casing does NOT matter", ""},
+		{CasingExact, "Casing must match EXACTLY, character for character", `"EXACT_MATCH" or "UNACCEPTABLE"`},
+	}
+
+	for _, tt := range tests {
+		prompt := buildJSONValidationPrompt("Better Than My Breath", "", tt.casing, "")
+		if !strings.Contains(prompt, tt.textRule) {
+			t.Errorf("buildJSONValidationPrompt(%s) does not contain %q", tt.casing, tt.textRule)
+		}
+		if tt.casing == CasingAny && strings.Contains(prompt, "text casing.") {
+			t.Errorf("buildJSONValidationPrompt(%s) still scores text casing", tt.casing)
+		}
+
+		prompt = buildPromptValidationPrompt("a lighthouse", "Better Than My Breath", "", tt.casing, "")
+		if tt.casingSection == "" {
+			if strings.Contains(prompt, "TEXT CASING") || strings.Contains(prompt, "text_casing") {
+				t.Errorf("buildPromptValidationPrompt(%s) asks about casing", tt.casing)
+			}
+		} else if !strings.Contains(prompt, "TEXT CASING") || !strings.Contains(prompt, tt.casingSection) {
+			t.Errorf("buildPromptValidationPrompt(%s) does not ask for %s", tt.casing, tt.casingSection)
+		}
+	}
+}
+
+func TestCasingPolicyScoring(t *testing.T) {
+	const (
+		allCapsFail = `{"caption_ok": false, "caption_seen": "BETTER THAN MY BREATH", "score": 6.5, "verdict": "FAIL", "reason": "Caption capitalization differs from the expected text"}`
+		allCapsPass = `{"caption_ok": true, "caption_seen": "BETTER THAN MY BREATH", "score": 8, "verdict": "PASS"}`
+		misspelled  = `{"caption_ok": false, "caption_seen": "Better Than My Breathe", "score": 4, "verdict": "FAIL", "reason": "Caption misspelled"}`
+	)
+	tests := []struct {
+		name       string
+		casing     CasingPolicy
+		response   string
+		acceptable bool
+	}{
+		{"strict keeps a casing failure", CasingStrict, allCapsFail, false},
+		{"any ignores a casing failure", CasingAny, allCapsFail, true},
+		{"exact keeps a casing failure", CasingExact, allCapsFail, false},
+		{"strict accepts all caps", CasingStrict, allCapsPass, true},
+		{"any accepts all caps", CasingAny, allCapsPass, true},
+		{"exact rejects all caps", CasingExact, allCapsPass, false},
+		{"any still fails a misspelling", CasingAny, misspelled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseJSONValidationResponse(&recordingLogger{}, tt.response, "Better Than My Breath", "", tt.casing)
+			if err != nil {
+				t.Fatalf("parseJSONValidationResponse() error: %v", err)
+			}
+			if result.IsAcceptable != tt.acceptable {
+				t.Errorf("IsAcceptable = %v, expected %v (issues: %q)", result.IsAcceptable, tt.acceptable, result.Issues)
+			}
+		})
+	}
+
+	for _, tt := range []struct {
+		casing   CasingPolicy
+		answer   string
+		expected bool
+	}{
+		{CasingStrict, "ALL_CAPS", true},
+		{CasingStrict, "UNACCEPTABLE", false},
+		{CasingAny, "UNACCEPTABLE", true},
+		{CasingExact, "ALL_LOWER", false},
+		{CasingExact, "EXACT_MATCH", true},
+	} {
+		if correct, _ := judgeTextCasing(tt.casing, tt.answer, true); correct != tt.expected {
+			t.Errorf("judgeTextCasing(%s, %q) = %v, expected %v", tt.casing, tt.answer, correct, tt.expected)
+		}
+	}
+}
+
+func TestParseJSONValidationFailures(t *testing.T) {
+	response := `{"caption_ok": false, "caption_seen": "Breathe", "subcaption_ok": false, "instruments_seen": ["trumpet"], "instruments_wrong": true, "score": 4, "verdict": "FAIL", "reason": "Background is blurry"}`
+	result, err := parseJSONValidationResponse(&recordingLogger{}, response, "Breath", "Live", CasingStrict)
+	if err != nil {
+		t.Fatalf("parseJSONValidationResponse() error: %v", err)
+	}
+
+	expected := []struct {
+		kind error
+		seen string
+	}{
+		{ErrHallucinatedInstruments, "trumpet"},
+		{ErrCaptionMismatch, "Breathe"},
+		{ErrSubcaptionMissing, ""},
+	}
+	if len(result.Failures) != len(expected) {
+		t.Fatalf("Failures = %v, expected %d", result.Failures, len(expected))
+	}
+	for i, want := range expected {
+		failure := result.Failures[i]
+		if !errors.Is(failure, want.kind) || failure.Seen != want.seen || failure.Message != result.Issues[i] {
+			t.Errorf("Failures[%d] = %+v, expected kind %v, seen %q, and the message of Issues[%d] %q", i, failure, want.kind, want.seen, i, result.Issues[i])
+		}
+	}
+	if len(result.Issues) != 4 || result.Issues[3] != "Background is blurry" {
+		t.Errorf("Issues = %q, expected the three failures and the verdict reason", result.Issues)
+	}
+}
+
+func TestFormatTokenCount(t *testing.T) {
+	tests := []struct {
+		input    int
+		expected string
+	}{
+		{0, "0"},
+		{600, "600"},
+		{1234, "1.2k"},
+		{48213, "48k"},
+		{1500000, "1.5M"},
+	}
+
+	for _, tt := range tests {
+		got := formatTokenCount(tt.input)
+		if got != tt.expected {
+			t.Errorf("formatTokenCount(%d) = %q, expected %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestUsageSummary(t *testing.T) {
+	tests := []struct {
+		name     string
+		calls    []CallUsage
+		expected string
+	}{
+		{"empty", nil, "tokens: 0 audio+text in / 0 out, est. $0.00"},
+		{"gemini and openai", []CallUsage{
+			{Call: "brief", Model: "models/gemini-3-pro-preview", InputTokens: 40000, OutputTokens: 400},
+			{Call: "prompt", Model: "models/gemini-3-pro-preview", InputTokens: 2000, OutputTokens: 150},
+			{Call: "review", Model: "gpt-5-mini", InputTokens: 6000, OutputTokens: 50},
+		}, "tokens: 48k audio+text in / 600 out, est. $0.09"},
+		{"unpriced model", []CallUsage{
+			{Call: "review", Model: "my-proxy-model", InputTokens: 500, OutputTokens: 20},
+		}, "tokens: 500 audio+text in / 20 out, est. $0.00 (no price for my-proxy-model)"},
+		{"local model is free", []CallUsage{
+			{Call: "fallback-prompt", Model: "ollama/llama3.1", InputTokens: 700, OutputTokens: 90},
+		}, "tokens: 700 audio+text in / 90 out, est. $0.00"},
+	}
+
+	for _, tt := range tests {
+		usage := &Usage{Calls: tt.calls}
+		got := usage.Summary()
+		if got != tt.expected {
+			t.Errorf("%s: Summary() = %q, expected %q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestParsePromptValidationResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		response    string
+		casing      CasingPolicy
+		match       bool
+		rendered    bool
+		score       float64
+		instruments []string
+		issues      []ValidationIssue
+		warns       int
+	}{
+		{
+			name: "json hallucinated instruments",
+			response: `{"prompt_match": true, "quality": "GOOD", "instruments_wrong": true, "instruments_seen": ["violin", "drums"],
+				"caption_status": "RENDERED", "score": 4.5,
+				"issues": [{"severity": "info", "message": "Lighting is warmer than described"}], "suggestions": ["None"]}`,
+			casing:      CasingStrict,
+			match:       false,
+			rendered:    true,
+			score:       4.5,
+			instruments: []string{"violin", "drums"},
+			issues: []ValidationIssue{
+				{SeverityBlocker, "Hallucinated instruments in image: violin, drums"},
+				{SeverityInfo, "Lighting is warmer than described"},
+			},
+		},
+		{
+			name:     "json fenced with missing caption and unknown severity",
+			response: "```json\n" + `{"prompt_match": true, "caption_status": "MISSING", "score": 12, "issues": [{"severity": "Major", "message": "Palette is off"}]}` + "\n```",
+			casing:   CasingStrict,
+			match:    true,
+			rendered: false,
+			score:    10,
+			issues: []ValidationIssue{
+				{SeverityBlocker, "Caption 'Title' missing"},
+				{SeverityWarning, "Palette is off"},
+			},
+		},
+		{
+			name: "plain text fallback",
+			response: `PROMPT_MATCH: MATCH
+INSTRUMENTS_STATUS: INSTRUMENTS_WRONG
+INSTRUMENTS_SEEN: saxophone, piano
+CAPTION_STATUS: RENDERED
+ISSUES:
+- Background is too busy
+SUGGESTIONS: None`,
+			casing:      CasingStrict,
+			match:       false,
+			rendered:    true,
+			instruments: []string{"saxophone", "piano"},
+			issues: []ValidationIssue{
+				{SeverityBlocker, "Hallucinated instruments in image: saxophone, piano"},
+				{SeverityWarning, "Background is too busy"},
+			},
+			warns: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &recordingLogger{}
+			result := parsePromptValidationResponse(logger, tt.response, "Title", "", tt.casing)
+			if result.PromptMatch != tt.match {
+				t.Errorf("PromptMatch = %v, expected %v", result.PromptMatch, tt.match)
+			}
+			if result.TextRendered != tt.rendered {
+				t.Errorf("TextRendered = %v, expected %v", result.TextRendered, tt.rendered)
+			}
+			if result.Score != tt.score {
+				t.Errorf("Score = %v, expected %v", result.Score, tt.score)
+			}
+			if !reflect.DeepEqual(result.InstrumentsSeen, tt.instruments) {
+				t.Errorf("InstrumentsSeen = %q, expected %q", result.InstrumentsSeen, tt.instruments)
+			}
+			if !reflect.DeepEqual(result.Issues, tt.issues) {
+				t.Errorf("Issues = %v, expected %v", result.Issues, tt.issues)
+			}
+			if len(result.Suggestions) != 0 {
+				t.Errorf("Suggestions = %q, expected none", result.Suggestions)
+			}
+			if len(logger.warns) != tt.warns {
+				t.Errorf("got %d warnings, expected %d: %q", len(logger.warns), tt.warns, logger.warns)
+			}
+			if blocker := result.HasBlocker(); blocker != (len(result.IssuesWithSeverity(SeverityBlocker)) > 0) {
+				t.Errorf("HasBlocker() = %v, inconsistent with IssuesWithSeverity", blocker)
+			}
+		})
+	}
+}
+
+// recordingLogger collects log output for assertions
+type recordingLogger struct {
+	infos, warns []string
+}
+
+func (l *recordingLogger) Infof(format string, v ...interface{}) {
+	l.infos = append(l.infos, fmt.Sprintf(format, v...))
+}
+
+func (l *recordingLogger) Warnf(format string, v ...interface{}) {
+	l.warns = append(l.warns, fmt.Sprintf(format, v...))
+}
+
+func TestPromptOptionsLogging(t *testing.T) {
+	tests := []struct {
+		name     string
+		quiet    bool
+		callback bool
+		infos    int
+		warns    int
+		stages   []string
+	}{
+		{"logger only", false, false, 2, 1, nil},
+		{"quiet drops info", true, false, 0, 1, nil},
+		{"progress callback", false, true, 1, 1, []string{StageBrief}},
+		{"progress callback when quiet", true, true, 0, 1, []string{StageBrief}},
+	}
+
+	for _, tt := range tests {
+		logger := &recordingLogger{}
+		var stages []string
+		opts := PromptOptions{Quiet: tt.quiet, Logger: logger}
+		if tt.callback {
+			opts.Progress = func(stage, msg string) { stages = append(stages, stage) }
+		}
+
+		opts.progress(StageBrief, "Pass 1: %s", "brief")
+		opts.logger().Infof("debug detail")
+		opts.logger().Warnf("quota exceeded")
+
+		if len(logger.infos) != tt.infos || len(logger.warns) != tt.warns {
+			t.Errorf("%s: got %d infos, %d warnings, expected %d, %d", tt.name, len(logger.infos), len(logger.warns), tt.infos, tt.warns)
+		}
+		if !reflect.DeepEqual(stages, tt.stages) {
+			t.Errorf("%s: progress stages = %v, expected %v", tt.name, stages, tt.stages)
+		}
+	}
+}
+
+// redirectTransport sends every request to a test server
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestClientUploadReuseAndClose(t *testing.T) {
+	var mu sync.Mutex
+	var uploads, deletes int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if got := r.Header.Get("x-goog-api-key"); got != "explicit-key" {
+			t.Errorf("%s %s: api key = %q, expected %q", r.Method, r.URL.Path, got, "explicit-key")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.Header.Get("X-Goog-Upload-Command") == "start":
+			w.Header().Set("X-Goog-Upload-URL", "https://upload.invalid/upload-session/1")
+		case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/upload-session/"):
+			uploads++
+			w.Header().Set("X-Goog-Upload-Status", "final")
+			fmt.Fprint(w, `{"file": {"name": "files/song", "uri": "https://files.invalid/song", "state": "PROCESSING"}}`)
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/files/song"):
+			fmt.Fprint(w, `{"name": "files/song", "uri": "https://files.invalid/song", "state": "ACTIVE"}`)
+		case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/files/song"):
+			deletes++
+			fmt.Fprint(w, `{}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	t.Setenv("GEMINI_API_KEY", "")
+	ctx := context.Background()
+	client, err := NewClientWithConfig(ctx, ClientOptions{
+		APIKey:     "explicit-key",
+		HTTPClient: &http.Client{Transport: redirectTransport{target}},
+	})
+	if err != nil {
+		t.Fatalf("NewClientWithConfig() unexpected error: %v", err)
+	}
+
+	audioPath := filepath.Join(t.TempDir(), "song.mp3")
+	if err := os.WriteFile(audioPath, []byte("not really audio"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := PromptOptions{Logger: NopLogger{}}
+	for i := 0; i < 2; i++ {
+		file, err := client.uploadAudio(ctx, audioPath, "audio/mpeg", opts)
+		if err != nil {
+			t.Fatalf("uploadAudio() #%d unexpected error: %v", i+1, err)
+		}
+		if file.URI != "https://files.invalid/song" {
+			t.Errorf("uploadAudio() #%d URI = %q, expected %q", i+1, file.URI, "https://files.invalid/song")
+		}
+	}
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+
+	if uploads != 1 || deletes != 1 {
+		t.Errorf("got %d uploads and %d deletes, expected 1 and 1", uploads, deletes)
+	}
+}
+
+func TestNewClientWithConfigErrors(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "")
+	tests := []struct {
+		name  string
+		copts ClientOptions
+	}{
+		{"no key", ClientOptions{}},
+		{"unknown backend", ClientOptions{APIKey: "key", Backend: "azure"}},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewClientWithConfig(context.Background(), tt.copts); err == nil {
+			t.Errorf("%s: NewClientWithConfig(%+v) expected error", tt.name, tt.copts)
+		}
+	}
+}
+
+func TestValidateImages(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "")
+	client, err := NewClientWithConfig(context.Background(), ClientOptions{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("NewClientWithConfig() unexpected error: %v", err)
+	}
+	paths := []string{"a.png", "b.png", "c.png", "d.png", "e.png"}
+	vopts := ValidationOptions{Logger: NopLogger{}}
+
+	// Without expected text every image is accepted without a request
+	results, err := client.ValidateImages(context.Background(), paths, "", "", 2, vopts)
+	if err != nil {
+		t.Fatalf("ValidateImages() unexpected error: %v", err)
+	}
+	for i, result := range results {
+		if result == nil || result.Path != paths[i] || !result.IsAcceptable {
+			t.Errorf("ValidateImages() result %d = %+v, expected an acceptable result for %s", i, result, paths[i])
+		}
+	}
+
+	// Unreadable images fail individually, in order, before any request
+	missing := []string{filepath.Join(t.TempDir(), "missing1.png"), filepath.Join(t.TempDir(), "missing2.png")}
+	results, err = client.ValidateImages(context.Background(), missing, "Caption", "", 0, vopts)
+	if err == nil {
+		t.Fatalf("ValidateImages(missing) expected error")
+	}
+	if len(results) != len(missing) || results[0] != nil || results[1] != nil {
+		t.Errorf("ValidateImages(missing) = %v, expected two nil results", results)
+	}
+	for _, path := range missing {
+		if !strings.Contains(err.Error(), path) {
+			t.Errorf("ValidateImages(missing) error %q does not name %s", err, path)
+		}
+	}
+}
+
+func TestValidationModelFallback(t *testing.T) {
+	var mu sync.Mutex
+	var models []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		model := strings.TrimSuffix(r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:], ":generateContent")
+		models = append(models, model)
+		w.Header().Set("Content-Type", "application/json")
+		if model == "flaky-flash" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error": {"code": 404, "message": "model not found", "status": "NOT_FOUND"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"candidates": [{"content": {"role": "model", "parts": [{"text": "{\"prompt_match\": true, \"score\": 8.5, \"issues\": []}"}]}}]}`)
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	t.Setenv("GEMINI_API_KEY", "")
+	client, err := NewClientWithConfig(context.Background(), ClientOptions{
+		APIKey:     "test-key",
+		HTTPClient: &http.Client{Transport: redirectTransport{target}},
+	})
+	if err != nil {
+		t.Fatalf("NewClientWithConfig() unexpected error: %v", err)
+	}
+	imagePath := filepath.Join(t.TempDir(), "image.png")
+	if err := os.WriteFile(imagePath, []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fallback := strings.TrimPrefix(DefaultModel, "models/")
+	tests := []struct {
+		name     string
+		model    string
+		expected []string
+		warns    int
+	}{
+		{"default model", "", []string{strings.TrimPrefix(DefaultValidationModel, "models/")}, 0},
+		{"falls back on error", "flaky-flash", []string{"flaky-flash", fallback}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models = nil
+			logger := &recordingLogger{}
+			result, err := client.ValidateImageAgainstPrompt(context.Background(), imagePath, "a prompt", "", "", ValidationOptions{Model: tt.model, Logger: logger})
+			if err != nil {
+				t.Fatalf("ValidateImageAgainstPrompt() unexpected error: %v", err)
+			}
+			if result.Score != 8.5 {
+				t.Errorf("Score = %v, expected 8.5", result.Score)
+			}
+			if !reflect.DeepEqual(models, tt.expected) {
+				t.Errorf("models requested = %q, expected %q", models, tt.expected)
+			}
+			if len(logger.warns) != tt.warns {
+				t.Errorf("got %d warnings, expected %d: %q", len(logger.warns), tt.warns, logger.warns)
+			}
+		})
+	}
+}
+
+func TestWithQuotaRetry(t *testing.T) {
+	var waits []time.Duration
+	origSleep := retry.Sleep
+	retry.Sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return ctx.Err()
+	}
+	t.Cleanup(func() { retry.Sleep = origSleep })
+
+	quota := genai.APIError{Code: http.StatusTooManyRequests, Message: "quota exceeded"}
+	tests := []struct {
+		name        string
+		retries     int
+		failures    []error
+		expectCalls int
+		expectWaits []time.Duration
+		expectError bool
+	}{
+		{"quota then success", 3, []error{quota, quota}, 3, []time.Duration{quotaRetryBaseDelay, 2 * quotaRetryBaseDelay}, false},
+		{"retries exhausted", 1, []error{quota, quota, quota}, 2, []time.Duration{quotaRetryBaseDelay}, true},
+		{"other errors are not retried", 3, []error{errors.New("bad request")}, 1, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waits = nil
+			logger := &recordingLogger{}
+			calls := 0
+			err := withQuotaRetry(context.Background(), logger, tt.retries, "analysis", func() error {
+				calls++
+				if calls <= len(tt.failures) {
+					return tt.failures[calls-1]
+				}
+				return nil
+			})
+			if (err != nil) != tt.expectError || calls != tt.expectCalls {
+				t.Errorf("withQuotaRetry() = %v after %d calls, expected error %v after %d", err, calls, tt.expectError, tt.expectCalls)
+			}
+			if !reflect.DeepEqual(waits, tt.expectWaits) {
+				t.Errorf("waits = %v, expected %v", waits, tt.expectWaits)
+			}
+			if len(logger.warns) != len(tt.expectWaits) {
+				t.Errorf("got %d warnings, expected one per retry: %q", len(logger.warns), logger.warns)
+			}
+		})
+	}
+}
+
+func TestSameModel(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"models/gemini-3-pro-preview", DefaultModel, true},
+		{"gemini-3-pro-preview", DefaultModel, true},
+		{"Gemini-3-Pro-Preview-001", DefaultModel, true},
+		{"models/gemini-3-pro-preview-latest", DefaultModel, true},
+		{DefaultValidationModel, DefaultModel, false},
+		{"gemini-2.5-flash-001", DefaultValidationModel, true},
+		{"gemini-2.5-flash-lite", DefaultValidationModel, false},
+	}
+
+	for _, test := range tests {
+		if got := sameModel(test.a, test.b); got != test.expected {
+			t.Errorf("sameModel(%q, %q) = %v, expected %v", test.a, test.b, got, test.expected)
+		}
+	}
+}
+
+// BenchmarkValidationModel runs ten validations, as a run with ten image
+// retries does, against a server that answers the default model in 20ms and
+// the flash model in 5ms
+func BenchmarkValidationModel(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, strings.TrimPrefix(DefaultModel, "models/")) {
+			time.Sleep(20 * time.Millisecond)
+		} else {
+			time.Sleep(5 * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"candidates": [{"content": {"role": "model", "parts": [{"text": "{\"prompt_match\": true, \"score\": 8.5, \"issues\": []}"}]}}]}`)
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	b.Setenv("GEMINI_API_KEY", "")
+	client, err := NewClientWithConfig(context.Background(), ClientOptions{
+		APIKey:     "test-key",
+		HTTPClient: &http.Client{Transport: redirectTransport{target}},
+	})
+	if err != nil {
+		b.Fatalf("NewClientWithConfig() unexpected error: %v", err)
+	}
+	imagePath := filepath.Join(b.TempDir(), "image.png")
+	if err := os.WriteFile(imagePath, []byte("png"), 0644); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, model := range []string{DefaultModel, DefaultValidationModel} {
+		b.Run(strings.TrimPrefix(model, "models/"), func(b *testing.B) {
+			vopts := ValidationOptions{Model: model, Logger: &recordingLogger{}}
+			for i := 0; i < b.N; i++ {
+				for retry := 0; retry < 10; retry++ {
+					if _, err := client.ValidateImageAgainstPrompt(context.Background(), imagePath, "a prompt", "", "", vopts); err != nil {
+						b.Fatalf("ValidateImageAgainstPrompt() unexpected error: %v", err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// capturedRequest is what a fake reviewer API received
+type capturedRequest struct {
+	path   string
+	header http.Header
+	body   map[string]any
+}
+
+// reviewerServer answers every request with status and reply, recording the
+// last request
+func reviewerServer(t *testing.T, status int, reply string) (*httptest.Server, *capturedRequest) {
+	t.Helper()
+	captured := &capturedRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured.path, captured.header = r.URL.Path, r.Header.Clone()
+		json.NewDecoder(r.Body).Decode(&captured.body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, reply)
+	}))
+	t.Cleanup(server.Close)
+	return server, captured
+}
+
+func TestOpenAIReviewerAsk(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		reply       string
+		image       []byte
+		expected    string
+		expectError string
+	}{
+		{
+			name:     "text reply",
+			status:   http.StatusOK,
+			reply:    `{"output": [{"content": [{"type": "output_text", "text": "looks good"}]}], "usage": {"input_tokens": 12, "output_tokens": 3}}`,
+			expected: "looks good",
+		},
+		{
+			name:     "image request",
+			status:   http.StatusOK,
+			reply:    `{"output": [{"content": [{"type": "output_text", "text": "a lighthouse"}]}]}`,
+			image:    []byte("png"),
+			expected: "a lighthouse",
+		},
+		{
+			name:        "API error",
+			status:      http.StatusUnauthorized,
+			reply:       `{"error": "bad key"}`,
+			expectError: "OpenAI API error 401",
+		},
+		{
+			name:        "no text",
+			status:      http.StatusOK,
+			reply:       `{"output": []}`,
+			expectError: "no text response from OpenAI",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, captured := reviewerServer(t, tt.status, tt.reply)
+			t.Setenv("OPENAI_BASE_URL", server.URL+"/v1")
+			r := &openAIReviewer{apiKey: "sk-test", model: DefaultReviewModel, timeout: reviewerTimeout}
+			usage := &Usage{}
+
+			text, err := r.Ask(withUsage(context.Background(), usage), "be brief", "review this", tt.image, "image/png")
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Fatalf("Ask() error = %v, expected %q", err, tt.expectError)
+				}
+				return
+			}
+			if err != nil || text != tt.expected {
+				t.Fatalf("Ask() = %q, %v, expected %q", text, err, tt.expected)
+			}
+			if captured.path != "/v1"+openAIResponsesPath || captured.header.Get("Authorization") != "Bearer sk-test" {
+				t.Errorf("request to %s with Authorization %q, expected %s with the key", captured.path, captured.header.Get("Authorization"), openAIResponsesPath)
+			}
+			if captured.body["model"] != DefaultReviewModel {
+				t.Errorf("model = %v, expected %s", captured.body["model"], DefaultReviewModel)
+			}
+			if _, capped := captured.body["max_output_tokens"]; capped != (tt.image != nil) {
+				t.Errorf("max_output_tokens set = %v, expected it only with an image", capped)
+			}
+			if in, out := usage.Totals(); tt.name == "text reply" && (in != 12 || out != 3) {
+				t.Errorf("usage = %d in, %d out, expected 12 and 3", in, out)
+			}
+		})
+	}
+}
+
+func TestAnthropicReviewerAsk(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		reply       string
+		image       []byte
+		expected    string
+		expectError string
+	}{
+		{
+			name:     "text reply",
+			status:   http.StatusOK,
+			reply:    `{"content": [{"type": "text", "text": "looks good"}], "usage": {"input_tokens": 20, "output_tokens": 4}}`,
+			expected: "looks good",
+		},
+		{
+			name:     "image request",
+			status:   http.StatusOK,
+			reply:    `{"content": [{"type": "text", "text": "a lighthouse"}]}`,
+			image:    []byte("png"),
+			expected: "a lighthouse",
+		},
+		{
+			name:        "API error",
+			status:      http.StatusTooManyRequests,
+			reply:       `{"type": "error"}`,
+			expectError: "Anthropic API error 429",
+		},
+		{
+			name:        "no text",
+			status:      http.StatusOK,
+			reply:       `{"content": []}`,
+			expectError: "no text response from Anthropic",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, captured := reviewerServer(t, tt.status, tt.reply)
+			t.Setenv("ANTHROPIC_BASE_URL", server.URL+"/v1")
+			r := &anthropicReviewer{apiKey: "ak-test", model: DefaultAnthropicReviewModel, timeout: reviewerTimeout}
+			usage := &Usage{}
+
+			text, err := r.Ask(withUsage(context.Background(), usage), "be brief", "review this", tt.image, "image/png")
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Fatalf("Ask() error = %v, expected %q", err, tt.expectError)
+				}
+				return
+			}
+			if err != nil || text != tt.expected {
+				t.Fatalf("Ask() = %q, %v, expected %q", text, err, tt.expected)
+			}
+			if captured.path != "/v1"+anthropicMessagesPath || captured.header.Get("x-api-key") != "ak-test" || captured.header.Get("anthropic-version") != anthropicVersion {
+				t.Errorf("request to %s with headers %v, expected %s with the key and version", captured.path, captured.header, anthropicMessagesPath)
+			}
+			if captured.body["model"] != DefaultAnthropicReviewModel || captured.body["system"] != "be brief" {
+				t.Errorf("model = %v, system = %v, expected %s and the system prompt", captured.body["model"], captured.body["system"], DefaultAnthropicReviewModel)
+			}
+			messages, _ := captured.body["messages"].([]any)
+			var parts int
+			if len(messages) == 1 {
+				content, _ := messages[0].(map[string]any)["content"].([]any)
+				parts = len(content)
+			}
+			expectedParts := 1
+			if tt.image != nil {
+				expectedParts = 2 // The image, then the prompt
+			}
+			if parts != expectedParts {
+				t.Errorf("sent %d content parts, expected %d", parts, expectedParts)
+			}
+			if in, out := usage.Totals(); tt.name == "text reply" && (in != 20 || out != 4) {
+				t.Errorf("usage = %d in, %d out, expected 20 and 4", in, out)
+			}
+		})
+	}
+}
+
+func TestOllamaReviewerAsk(t *testing.T) {
+	server, captured := reviewerServer(t, http.StatusOK, `{"message": {"role": "assistant", "content": "looks good"}, "prompt_eval_count": 30, "eval_count": 5}`)
+	// OLLAMA_HOST may omit the scheme, as with the ollama CLI
+	t.Setenv("OLLAMA_HOST", strings.TrimPrefix(server.URL, "http://"))
+	r := &ollamaReviewer{model: DefaultOllamaModel, timeout: ollamaTimeout}
+	usage := &Usage{}
+
+	text, err := r.Ask(withUsage(context.Background(), usage), "be brief", "review this", []byte("png"), "image/png")
+	if err != nil || text != "looks good" {
+		t.Fatalf("Ask() = %q, %v, expected the reply", text, err)
+	}
+	if captured.path != "/api/chat" || captured.body["model"] != DefaultOllamaModel || captured.body["stream"] != false {
+		t.Errorf("request to %s with model %v, stream %v, expected a non-streaming /api/chat for %s", captured.path, captured.body["model"], captured.body["stream"], DefaultOllamaModel)
+	}
+	messages, _ := captured.body["messages"].([]any)
+	if len(messages) != 2 {
+		t.Fatalf("sent %d messages, expected the system prompt and the user prompt", len(messages))
+	}
+	if images, _ := messages[1].(map[string]any)["images"].([]any); len(images) != 1 {
+		t.Errorf("user message images = %v, expected the image", images)
+	}
+	if in, out := usage.Totals(); in != 30 || out != 5 {
+		t.Errorf("usage = %d in, %d out, expected 30 and 5", in, out)
+	}
+}
+
+func TestOllamaReviewerNotRunning(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	host := server.URL
+	server.Close() // Nothing listens here now
+	t.Setenv("OLLAMA_HOST", host)
+	r := &ollamaReviewer{model: DefaultOllamaModel, timeout: ollamaTimeout}
+
+	_, err := r.Ask(context.Background(), "", "review this", nil, "")
+	if err == nil || !strings.Contains(err.Error(), "is ollama running at "+host+"?") {
+		t.Errorf("Ask() error = %v, expected a hint naming %s", err, host)
+	}
+}
+
+func TestGenerateTitleTruncatesOnRuneBoundary(t *testing.T) {
+	server, captured := reviewerServer(t, http.StatusOK, `{"output": [{"content": [{"type": "output_text", "text": "\"Kaffee am Morgen.\""}]}]}`)
+	t.Setenv("GEMINI_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	t.Setenv("OPENAI_BASE_URL", server.URL)
+
+	// "ü" is two bytes, so the cut at maxTitleInput falls inside one
+	text := "a" + strings.Repeat("ü", maxTitleInput)
+	title, err := GenerateTitle(text, ClientOptions{})
+	if err != nil || title != "Kaffee am Morgen" {
+		t.Fatalf("GenerateTitle() = %q, %v, expected the cleaned title", title, err)
+	}
+
+	data, _ := json.Marshal(captured.body)
+	if strings.ContainsRune(string(data), utf8.RuneError) || !strings.Contains(string(data), "üü") {
+		t.Errorf("title prompt has a split rune: %.200s...", data)
+	}
+}
+
+func TestNewReviewerTimeouts(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	t.Setenv("ANTHROPIC_API_KEY", "ak-test")
+
+	tests := []struct {
+		name     string
+		expected time.Duration
+	}{
+		{ReviewerOpenAI, reviewerTimeout},
+		{ReviewerAnthropic, reviewerTimeout},
+		{ReviewerOllama, ollamaTimeout},
+	}
+
+	for _, tt := range tests {
+		r, err := NewReviewer(tt.name, "")
+		if err != nil {
+			t.Fatalf("NewReviewer(%q) error: %v", tt.name, err)
+		}
+		var timeout time.Duration
+		switch r := r.(type) {
+		case *openAIReviewer:
+			timeout = r.timeout
+		case *anthropicReviewer:
+			timeout = r.timeout
+		case *ollamaReviewer:
+			timeout = r.timeout
+		}
+		if timeout != tt.expected {
+			t.Errorf("NewReviewer(%q) timeout = %v, expected %v", tt.name, timeout, tt.expected)
+		}
+	}
+}
+
+func TestGenerateImagePromptSkipAudio(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"candidates": [{"content": {"role": "model", "parts": [{"text": "\"Title/caption \"Dawn\" is prominently displayed. A kettle on a stove at sunrise.\""}]}}],
+			"usageMetadata": {"promptTokenCount": 900, "candidatesTokenCount": 60}}`)
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	t.Setenv("GEMINI_API_KEY", "")
+	client, err := NewClientWithConfig(context.Background(), ClientOptions{
+		APIKey:     "test-key",
+		HTTPClient: &http.Client{Transport: redirectTransport{target}},
+	})
+	if err != nil {
+		t.Fatalf("NewClientWithConfig() unexpected error: %v", err)
+	}
+
+	// The audio file is never read, so it need not exist
+	opts := PromptOptions{
+		Caption:   "Dawn",
+		Reviewer:  ReviewerNone,
+		SkipAudio: true,
+		Logger:    NopLogger{},
+		CacheDir:  t.TempDir(),
+	}
+	result, err := client.GenerateImagePrompt(context.Background(), "/nonexistent/dawn.wav", opts)
+	if err != nil {
+		t.Fatalf("GenerateImagePrompt() unexpected error: %v", err)
+	}
+
+	expected := []string{"POST /v1beta/" + DefaultModel + ":generateContent"}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("requests = %q, expected %q", requests, expected)
+	}
+	if !result.AudioSkipped || result.Fallback || result.Brief != nil {
+		t.Errorf("result AudioSkipped = %v, Fallback = %v, Brief = %v, expected a skipped run without a brief", result.AudioSkipped, result.Fallback, result.Brief)
+	}
+	if !strings.HasPrefix(result.Prompt, `Title/caption "Dawn"`) {
+		t.Errorf("Prompt = %q, expected it to start with the caption overlay", result.Prompt)
+	}
+	if result.Title != "dawn" {
+		t.Errorf("Title = %q, expected %q", result.Title, "dawn")
+	}
+	if in, out := result.Usage.Totals(); in != 900 || out != 60 {
+		t.Errorf("Usage.Totals() = %d, %d, expected 900, 60", in, out)
+	}
+
+	// The same request is answered from the cache unless refreshed
+	tests := []struct {
+		name     string
+		refresh  bool
+		cached   bool
+		requests int
+	}{
+		{"cache hit", false, true, 1},
+		{"refresh", true, false, 2},
+		{"hit after refresh", false, true, 2},
+	}
+	for _, tt := range tests {
+		opts.Refresh = tt.refresh
+		again, err := client.GenerateImagePrompt(context.Background(), "/nonexistent/dawn.wav", opts)
+		if err != nil {
+			t.Fatalf("%s: GenerateImagePrompt() unexpected error: %v", tt.name, err)
+		}
+		if again.Cached != tt.cached || again.Prompt != result.Prompt || !again.AudioSkipped {
+			t.Errorf("%s: result Cached = %v, Prompt = %q, expected Cached = %v and the same prompt", tt.name, again.Cached, again.Prompt, tt.cached)
+		}
+		if len(requests) != tt.requests {
+			t.Errorf("%s: %d requests, expected %d", tt.name, len(requests), tt.requests)
+		}
+	}
+}
+
+func TestProcessingTimeout(t *testing.T) {
+	tests := []struct {
+		size     int64
+		expected time.Duration
+	}{
+		{0, 2 * time.Minute},
+		{49 << 20, 2 * time.Minute},
+		{120 << 20, 4 * time.Minute},
+		{950 << 20, 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if result := processingTimeout(tt.size); result != tt.expected {
+			t.Errorf("processingTimeout(%d) = %v, expected %v", tt.size, result, tt.expected)
+		}
+	}
+}
+
+type recordingCleanup struct {
+	paths []string
+}
+
+func (c *recordingCleanup) Add(path string) {
+	c.paths = append(c.paths, path)
+}
+
+func TestPrepareAudio(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small.mp3")
+	if err := os.WriteFile(small, []byte("audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	long := filepath.Join(dir, "long.mp3")
+	if err := os.WriteFile(long, make([]byte, probeAudioBytes+1), 0644); err != nil {
+		t.Fatal(err)
+	}
+	video := filepath.Join(dir, "live.mp4")
+	if err := os.WriteFile(video, []byte("video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	originalDuration, originalTranscode := audioDuration, transcodeAudio
+	t.Cleanup(func() { audioDuration, transcodeAudio = originalDuration, originalTranscode })
+	audioDuration = func(path string) (float64, error) { return 90 * 60, nil }
+	var transcodes []ffmpeg.ExtractOptions
+	transcodeAudio = func(ctx context.Context, inputPath, outputPath string, opts ffmpeg.ExtractOptions) error {
+		transcodes = append(transcodes, opts)
+		return os.WriteFile(outputPath, []byte("m4a"), 0644)
+	}
+
+	compact := ffmpeg.ExtractOptions{Mono: true, Bitrate: "64k"}
+	windowed := ffmpeg.ExtractOptions{Start: time.Minute, Duration: 3 * time.Minute, Mono: true, Bitrate: "64k"}
+	tests := []struct {
+		name       string
+		path       string
+		window     *probe.AudioWindow
+		transcoded bool
+		extract    ffmpeg.ExtractOptions
+	}{
+		{"small file is uploaded as is", small, nil, false, ffmpeg.ExtractOptions{}},
+		{"long file is compressed", long, nil, true, compact},
+		{"video audio track is extracted", video, nil, true, compact},
+		{"window is cut", small, &probe.AudioWindow{Start: time.Minute, End: 4 * time.Minute}, true, windowed},
+	}
+
+	for _, tt := range tests {
+		for _, withCleanup := range []bool{false, true} {
+			transcodes = nil
+			cleanup := &recordingCleanup{}
+			opts := PromptOptions{AnalyzeWindow: tt.window, Logger: NopLogger{}}
+			if withCleanup {
+				opts.Cleanup = cleanup
+			}
+			path, mimeType, remove, err := prepareAudio(context.Background(), tt.path, opts)
+			if err != nil {
+				t.Fatalf("%s: prepareAudio() unexpected error: %v", tt.name, err)
+			}
+			if !tt.transcoded {
+				if path != tt.path || len(transcodes) != 0 || len(cleanup.paths) != 0 {
+					t.Errorf("%s: prepareAudio() = %s after %d transcodes, expected the original", tt.name, path, len(transcodes))
+				}
+				remove()
+				continue
+			}
+			if path == tt.path || mimeType != "audio/mp4" {
+				t.Errorf("%s: prepareAudio() = %s (%s), expected an m4a transcode", tt.name, path, mimeType)
+			}
+			if len(transcodes) != 1 || transcodes[0] != tt.extract {
+				t.Errorf("%s: extractions = %+v, expected one with %+v", tt.name, transcodes, tt.extract)
+			}
+			remove()
+			_, statErr := os.Stat(path)
+			if withCleanup {
+				if !reflect.DeepEqual(cleanup.paths, []string{path}) || statErr != nil {
+					t.Errorf("%s: cleanup = %q, stat error %v, expected %s registered and kept", tt.name, cleanup.paths, statErr, path)
+				}
+				os.Remove(path)
+			} else if !os.IsNotExist(statErr) {
+				t.Errorf("%s: transcode %s not removed (stat error %v)", tt.name, path, statErr)
+			}
+		}
+	}
+}
+
+func TestPromptCacheKey(t *testing.T) {
+	dir := t.TempDir()
+	song, copied, other := filepath.Join(dir, "song.mp3"), filepath.Join(dir, "copy.mp3"), filepath.Join(dir, "other.mp3")
+	for path, content := range map[string]string{song: "audio", copied: "audio", other: "different audio"} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	base := PromptOptions{Title: "Song", Notes: "late night", Model: DefaultModel}
+	baseKey, err := promptCacheKey(song, base)
+	if err != nil {
+		t.Fatalf("promptCacheKey() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		opts  func(*PromptOptions)
+		equal bool
+	}{
+		{"same content at another path", copied, func(o *PromptOptions) {}, true},
+		{"logging and retries", song, func(o *PromptOptions) { o.Quiet, o.Debug, o.Retries, o.Logger = true, true, 5, NopLogger{} }, true},
+		{"one scene is the default", song, func(o *PromptOptions) { o.Scenes = 1 }, true},
+		{"different audio", other, func(o *PromptOptions) {}, false},
+		{"notes", song, func(o *PromptOptions) { o.Notes = "early morning" }, false},
+		{"caption", song, func(o *PromptOptions) { o.Caption = "Song" }, false},
+		{"temperature", song, func(o *PromptOptions) { o.PromptTemperature = ptr(float32(1.2)) }, false},
+		{"window", song, func(o *PromptOptions) { o.AnalyzeWindow = &probe.AudioWindow{End: time.Minute} }, false},
+	}
+
+	for _, tt := range tests {
+		opts := base
+		tt.opts(&opts)
+		key, err := promptCacheKey(tt.path, opts)
+		if err != nil {
+			t.Fatalf("%s: promptCacheKey() unexpected error: %v", tt.name, err)
+		}
+		if (key == baseKey) != tt.equal {
+			t.Errorf("%s: key equal = %v, expected %v", tt.name, key == baseKey, tt.equal)
+		}
+	}
+
+	if _, err := promptCacheKey(filepath.Join(dir, "missing.mp3"), base); err == nil {
+		t.Errorf("promptCacheKey(missing) expected error")
+	}
+}
+
+func TestRevisePrompt(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"candidates": [{"content": {"role": "model", "parts": [{"text": "A rain-slick rooftop at night, neon reflections."}]}}]}`)
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	t.Setenv("GEMINI_API_KEY", "")
+	client, err := NewClientWithConfig(context.Background(), ClientOptions{
+		APIKey:     "test-key",
+		HTTPClient: &http.Client{Transport: redirectTransport{target}},
+	})
+	if err != nil {
+		t.Fatalf("NewClientWithConfig() unexpected error: %v", err)
+	}
+	brief := &AudioBrief{Genre: "synthwave", PaletteColors: []string{"#ff00aa"}}
+	opts := PromptOptions{Reviewer: ReviewerNone, Quiet: true}
+
+	prompt, usage, err := client.RevisePrompt(context.Background(), brief, "A rooftop at dusk.", "same idea but at night", opts)
+	if err != nil {
+		t.Fatalf("RevisePrompt() unexpected error: %v", err)
+	}
+	if prompt != "A rain-slick rooftop at night, neon reflections." {
+		t.Errorf("RevisePrompt() = %q, expected the model's prompt", prompt)
+	}
+	if usage == nil {
+		t.Error("RevisePrompt() usage = nil, expected the pass 2 call")
+	}
+	if len(bodies) != 1 {
+		t.Fatalf("RevisePrompt() made %d requests, expected 1 (no upload or brief)", len(bodies))
+	}
+	for _, want := range []string{"A rooftop at dusk.", "same idea but at night", "synthwave"} {
+		if !strings.Contains(bodies[0], want) {
+			t.Errorf("RevisePrompt() request missing %q", want)
+		}
+	}
+
+	if _, _, err := client.RevisePrompt(context.Background(), nil, "A rooftop at dusk.", "", opts); err == nil {
+		t.Error("RevisePrompt(nil brief) expected error")
+	}
+}
+
+func TestDefaultLoggerFollowsLogLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr); logx.SetLevel(logx.LevelInfo) })
+
+	logx.SetLevel(logx.LevelWarn)
+	logger := resolveLogger(nil, false)
+	logger.Infof("uploading")
+	logger.Warnf("over quota")
+	if out := buf.String(); strings.Contains(out, "uploading") || !strings.Contains(out, "Warning: over quota") {
+		t.Errorf("at warn level, default logger wrote %q; want only the warning", out)
+	}
+}
+
+func TestPromptCacheStoreEvicts(t *testing.T) {
+	cache, err := openPromptCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.store("old", &PromptResult{Prompt: "A rooftop at dusk."}); err != nil {
+		t.Fatalf("store(old) unexpected error: %v", err)
+	}
+	past := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(cache.dir, "old.json"), past, past)
+	info, _ := os.Stat(filepath.Join(cache.dir, "old.json"))
+	cache.maxSize = info.Size() + 1
+
+	if err := cache.store("new", &PromptResult{Prompt: "A rooftop at night."}); err != nil {
+		t.Fatalf("store(new) unexpected error: %v", err)
+	}
+	if _, hit := cache.lookup("old"); hit {
+		t.Error("least recently used entry kept past maxSize")
+	}
+	if result, hit := cache.lookup("new"); !hit || result.Prompt != "A rooftop at night." || !result.Cached {
+		t.Errorf("lookup(new) = %+v, %v, expected the cached prompt", result, hit)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(cache.dir, "*.tmp")); len(leftovers) != 0 {
+		t.Errorf("store left temp files: %v", leftovers)
+	}
+}
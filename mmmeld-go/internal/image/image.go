@@ -3,27 +3,67 @@ package image
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	goimage "image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
 	"io"
-	"log"
+	"math"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"mmmeld/internal/config"
+	"mmmeld/internal/events"
+	"mmmeld/internal/ffmpeg"
 	"mmmeld/internal/fileutil"
 	"mmmeld/internal/genai"
+	"mmmeld/internal/httpx"
+	"mmmeld/internal/logx"
+	"mmmeld/internal/probe"
+	"mmmeld/internal/retry"
+
+	googlegenai "google.golang.org/genai"
 )
 
 type MediaInput struct {
 	Path        string
 	IsVideo     bool
 	IsGenerated bool
+	AspectCheck string // Result of the generated image aspect ratio check, if run
+	TextOverlay bool   // Caption text was composited with ffmpeg rather than generated
+
+	// Provenance, for the run summary
+	Source   string            // SourceLocal, SourceDownloaded, or SourceGenerated
+	Entry    string            // The --image entry it came from, with URL credentials redacted
+	Prompt   string            // Prompt a generated image was made from
+	Brief    *genai.AudioBrief // Audio brief behind Prompt, when it came from audio analysis
+	Attempts *AttemptsReport   // Generation attempts and validation scores of a generated image
 }
 
+// MediaInput sources
+const (
+	SourceLocal      = "local"
+	SourceDownloaded = "downloaded"
+	SourceGenerated  = "generated"
+)
+
 // ImageGenOptions contains options for image generation including validation
 type ImageGenOptions struct {
 	Description  string
@@ -37,8 +77,105 @@ type ImageGenOptions struct {
 	AttemptNum   int                // Current attempt number for file naming (1-based)
 	StyleType    string             // Ideogram style type (AUTO, GENERAL, REALISTIC, DESIGN, FICTION)
 	StylePreset  string             // Ideogram style preset (e.g., CINEMATIC, OIL_PAINTING, etc.)
+	ForceYtDlp   bool               // Treat any http(s) input as a yt-dlp source
+
+	NegativePrompt string // Ideogram, Stability, local-sd: what the image should not contain
+	Seed           int64  // Stability and Replicate: generation seed (0 = random)
+	Model          string // Replicate model slug ("owner/name" or "owner/name:version")
+	Quality        string // gpt-image-1 quality: low, medium, high (empty = auto)
+
+	SDEndpoint string        // Local Stable Diffusion (A1111 API) base URL
+	SDSteps    int           // Local SD sampling steps
+	SDSampler  string        // Local SD sampler name (empty = server default)
+	SDTimeout  time.Duration // How long to wait for a local SD image
+
+	Upscale bool // Upscale the accepted image with Ideogram
+
+	ReferenceImage string // Local image whose visual language the result should follow
+	ImageWeight    int    // Ideogram remix weight of the reference image (1-100)
+
+	OutputPath string // Planned video output; namespaces temp image filenames
+	Scene      int    // 1-based scene number of a "generate" slot (0 = a single image)
+
+	Fit   config.FitMode // Adjust user-supplied stills to AspectRatio
+	Focus config.Focus   // Crop focus point for config.FitCrop
+
+	StrictAspect bool // Fail attempts whose image does not match AspectRatio instead of cropping
+
+	CaptionFallback string // "drawtext" overlays caption text when validation keeps failing
+	CaptionFont     string // Font file for the drawtext fallback (empty = ffmpeg default)
+	CaptionFontSize int    // Caption font size in pixels (0 = 1/12 of image height)
+	CaptionColor    string // Caption text color for the drawtext fallback
+
+	CasingPolicy    genai.CasingPolicy // How strictly validation judges caption casing (default strict)
+	Reviewer        string             // Model that stands in for Gemini validation: openai, anthropic, or none
+	ReviewModel     string             // Reviewer model for the validation fallback (empty = default)
+	ValidationModel string             // Gemini validation model (empty = genai.DefaultValidationModel)
+	ValidatePrompt  bool               // Also check each image against Description; a mismatch or blocker rejects it
+	Retries         int                // Gemini quota-error retries before falling back to the reviewer
+	NoFallback      bool               // Fail validation instead of falling back to the reviewer
+	Language        string             // Language of the caption text (empty = English)
+	GeminiKey       string             // Gemini key for validation, Imagen, and reference descriptions (empty = GEMINI_API_KEY)
+
+	DownloadHeaders http.Header           // Extra headers for http(s) image inputs; never logged
+	YtDlp           fileutil.YtDlpOptions // Cookies and extra arguments for yt-dlp video inputs
+
+	AdaptiveRetry bool // Append corrective instructions from failed validation to the next attempt's prompt
+	Debug         bool // Log extra diagnostics such as adapted retry prompts
+
+	NoCache  bool   // Skip the on-disk generated image cache
+	CacheDir string // Image cache location; empty uses DefaultImageCacheDir
+
+	Context context.Context // Cancels requests and rate-limit waits; nil uses context.Background()
 }
 
+// context returns opts.Context, defaulting to context.Background()
+func (opts ImageGenOptions) context() context.Context {
+	if opts.Context == nil {
+		return context.Background()
+	}
+	return opts.Context
+}
+
+// Replicate API types
+type replicatePrediction struct {
+	ID     string          `json:"id"`
+	Status string          `json:"status"` // starting, processing, succeeded, failed, canceled
+	Output json.RawMessage `json:"output"`
+	Error  interface{}     `json:"error"`
+	URLs   struct {
+		Get string `json:"get"`
+	} `json:"urls"`
+}
+
+// maxConcurrentImageGenerations bounds parallel provider calls when several
+// "generate" slots are requested
+const maxConcurrentImageGenerations = 3
+
+// Rate-limit handling for Ideogram and OpenAI: 429/503 responses are retried
+// after Retry-After (or backoff) without consuming a generation attempt
+const (
+	rateLimitMaxWaits  = 6
+	rateLimitBaseDelay = 2 * time.Second
+	rateLimitMaxDelay  = 2 * time.Minute
+)
+
+const (
+	replicatePollStart   = 1 * time.Second
+	replicatePollMax     = 10 * time.Second
+	replicatePollTimeout = 5 * time.Minute
+)
+
+// Provider API endpoints are variables so tests can point them at a local
+// server
+var (
+	ideogramRemixURL   = "https://api.ideogram.ai/v1/ideogram-v3/remix"
+	ideogramUpscaleURL = "https://api.ideogram.ai/upscale"
+	openAIImagesURL    = "https://api.openai.com/v1/images/generations"
+	replicateAPIURL    = "https://api.replicate.com/v1"
+	stabilityURL       = "https://api.stability.ai/v2beta/stable-image/generate/sd3"
+)
+
 type OpenAIImageRequest struct {
 	Model   string `json:"model"`
 	Prompt  string `json:"prompt"`
@@ -49,7 +186,8 @@ type OpenAIImageRequest struct {
 
 type OpenAIImageResponse struct {
 	Data []struct {
-		URL string `json:"url"`
+		URL     string `json:"url"`
+		B64JSON string `json:"b64_json"` // gpt-image-1 always returns base64
 	} `json:"data"`
 }
 
@@ -72,6 +210,7 @@ type OpenAIChatResponse struct {
 // Ideogram API types
 type IdeogramRequest struct {
 	Prompt         string `json:"prompt"`
+	NegativePrompt string `json:"negative_prompt,omitempty"`
 	AspectRatio    string `json:"aspect_ratio,omitempty"`
 	RenderingSpeed string `json:"rendering_speed,omitempty"`
 	StyleType      string `json:"style_type,omitempty"`
@@ -85,107 +224,363 @@ type IdeogramResponse struct {
 }
 
 // GetImageInputs processes image/video inputs from configuration
-func GetImageInputs(cfg *config.Config, title, description string, cleanup *fileutil.CleanupManager) ([]MediaInput, error) {
-	return GetImageInputsWithAudio(cfg, title, description, "", cleanup)
+func GetImageInputs(ctx context.Context, cfg *config.Config, title, description string, cleanup *fileutil.CleanupManager) ([]MediaInput, error) {
+	return GetImageInputsWithAudio(ctx, cfg, title, description, "", cleanup)
+}
+
+// Entries splits --image into entries. "@file" reads them from a list
+// file, one per line, which avoids the comma splitting of the flag form.
+func Entries(image string) ([]string, error) {
+	image = strings.TrimSpace(image)
+	if image == "" {
+		return nil, nil
+	}
+	if listPath, ok := strings.CutPrefix(image, "@"); ok {
+		entries, err := fileutil.ReadInputList(listPath)
+		if err != nil {
+			return nil, err
+		}
+		logx.Debugf("Read %d image entries from %s", len(entries), listPath)
+		return entries, nil
+	}
+
+	var entries []string
+	for _, entry := range strings.Split(image, ",") {
+		entries = append(entries, strings.TrimSpace(entry))
+	}
+	return entries, nil
+}
+
+// analyzeAudio and processImageInput are variables so tests can run
+// GetImageInputsWithAudio without Gemini or an image provider
+var (
+	analyzeAudio      = analyzeAudioForPrompt
+	processImageInput = processImageInputWithOpts
+)
+
+// AudioAnalysis is the --analyze-audio result for one audio file. It runs
+// Gemini at most once, on first use, and hands its scene prompts to every
+// generated image that asks, so several requests in a run (such as the
+// interactive image loop) share one upload.
+type AudioAnalysis struct {
+	audioPath string
+
+	once    sync.Once
+	prompts []string
+	brief   *genai.AudioBrief
+
+	mu   sync.Mutex
+	used int // Prompts handed out so far
+}
+
+// NewAudioAnalysis returns an analysis of audioPath that has not run yet
+func NewAudioAnalysis(audioPath string) *AudioAnalysis {
+	return &AudioAnalysis{audioPath: audioPath}
+}
+
+// run analyzes the audio the first time it is called, asking for scenes
+// prompts; later calls reuse that result whatever they ask for. Problems are
+// logged once and leave no prompts, so callers fall back to their defaults.
+func (a *AudioAnalysis) run(cfg *config.Config, title, notes string, scenes int, cleanup *fileutil.CleanupManager) {
+	a.once.Do(func() {
+		switch {
+		case a.audioPath == "":
+			logx.Warnf("--analyze-audio was given but there is no audio to analyze; image prompts come from --image-description or the title")
+			return
+		case !genai.IsAudioFile(a.audioPath):
+			logx.Warnf("--analyze-audio skipped: %s is not a supported audio file", a.audioPath)
+			return
+		}
+		logx.Infof("Analyzing audio with Gemini to generate image prompt...")
+		prompts, brief, err := analyzeAudio(cfg, a.audioPath, title, notes, scenes, cleanup)
+		if err != nil {
+			logx.Warnf("Audio analysis failed, falling back to default: %v", err)
+			return
+		}
+		a.prompts, a.brief = prompts, brief
+		for i, prompt := range prompts {
+			logx.Debugf("Generated prompt from audio (scene %d/%d):\n%s", i+1, len(prompts), prompt)
+		}
+	})
+}
+
+// next hands out the next scene prompt, or "" when the analysis produced
+// none. Once every scene has been used the prompts repeat with a variation
+// note, so a run does not ask for the same picture twice.
+func (a *AudioAnalysis) next() (string, *genai.AudioBrief) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.prompts) == 0 {
+		return "", nil
+	}
+	n := a.used
+	a.used++
+	prompt := a.prompts[n%len(a.prompts)]
+	if round := n / len(a.prompts); round > 0 {
+		prompt += fmt.Sprintf("\n\nVariation %d: keep the concept but choose a different composition, viewpoint, and framing.", round+1)
+	}
+	return prompt, a.brief
 }
 
 // GetImageInputsWithAudio processes image/video inputs from configuration,
 // optionally analyzing an audio file to generate an image prompt using Gemini.
-func GetImageInputsWithAudio(cfg *config.Config, title, description, audioPath string, cleanup *fileutil.CleanupManager) ([]MediaInput, error) {
+// Several "generate" entries get distinct scene prompts from one analysis and
+// are generated concurrently, keeping their slot order.
+func GetImageInputsWithAudio(ctx context.Context, cfg *config.Config, title, description, audioPath string, cleanup *fileutil.CleanupManager) ([]MediaInput, error) {
+	return GetImageInputsWithAnalysis(ctx, cfg, title, description, NewAudioAnalysis(audioPath), cleanup)
+}
+
+// GetImageInputsWithAnalysis is GetImageInputsWithAudio with a shared
+// analysis, for callers that request images several times per run.
+// Cancelling ctx stops image generation and validation.
+func GetImageInputsWithAnalysis(ctx context.Context, cfg *config.Config, title, description string, analysis *AudioAnalysis, cleanup *fileutil.CleanupManager) ([]MediaInput, error) {
 	var inputs []MediaInput
 
-	// If analyze-audio is enabled and we have an audio file, generate prompt from audio
-	audioGeneratedPrompt := ""
-	if cfg.AnalyzeAudio && audioPath != "" && genai.IsAudioFile(audioPath) {
-		log.Println("Analyzing audio with Gemini to generate image prompt...")
+	inputPaths, err := Entries(cfg.Image)
+	if err != nil {
+		return nil, err
+	}
+	generateSlots := 0
+	for _, inputPath := range inputPaths {
+		if strings.ToLower(inputPath) == "generate" {
+			generateSlots++
+		}
+	}
+
+	// If analyze-audio is enabled, generate prompts from the audio
+	useAnalysis := false
+	switch {
+	case !cfg.AnalyzeAudio:
+	case generateSlots == 0 && !(len(inputPaths) == 0 && cfg.AutoFill):
+		logx.Debugf("--analyze-audio skipped: no image is generated")
+	default:
 		// Use AudioNotes if provided, otherwise fall back to description
 		notes := cfg.AudioNotes
 		if notes == "" {
 			notes = description
 		}
-		prompt, err := analyzeAudioForPrompt(audioPath, title, notes, cfg.ImageCaption, cfg.ImageSubcaption, cfg.ImageStyle)
-		if err != nil {
-			log.Printf("Warning: Audio analysis failed, falling back to default: %v", err)
-		} else {
-			audioGeneratedPrompt = prompt
-			log.Printf("Generated prompt from audio:\n%s", prompt)
+		scenes := 1
+		if cfg.ImageDescription == "" && generateSlots > 1 {
+			scenes = generateSlots
 		}
+		analysis.run(cfg, title, notes, scenes, cleanup)
+		useAnalysis = cfg.ImageDescription == ""
 	}
 
-	if cfg.Image != "" {
-		log.Printf("Processing image inputs: %s", cfg.Image)
+	if len(inputPaths) > 0 {
+		logged := make([]string, len(inputPaths))
+		for i, inputPath := range inputPaths {
+			logged[i] = fileutil.RedactURL(inputPath)
+		}
+		logx.Infof("Processing image inputs: %s", strings.Join(logged, ","))
 
-		inputPaths := strings.Split(cfg.Image, ",")
-		for _, inputPath := range inputPaths {
-			inputPath = strings.TrimSpace(inputPath)
+		inputPaths, entries, err := expandPlaylists(inputPaths, cfg, cleanup)
+		if err != nil {
+			return nil, err
+		}
 
+		type slot struct {
+			path  string
+			opts  ImageGenOptions
+			scene int               // 1-based scene number for generate slots
+			brief *genai.AudioBrief // Set when the prompt came from audio analysis
+		}
+		slots := make([]slot, len(inputPaths))
+		scene := 0
+		for i, inputPath := range inputPaths {
 			// Use audio-generated prompt if available and this is a "generate" request
 			effectiveDesc := cfg.ImageDescription
-			if audioGeneratedPrompt != "" && strings.ToLower(inputPath) == "generate" && effectiveDesc == "" {
-				effectiveDesc = audioGeneratedPrompt
+			if strings.ToLower(inputPath) == "generate" {
+				if useAnalysis {
+					if prompt, brief := analysis.next(); prompt != "" {
+						effectiveDesc, slots[i].brief = prompt, brief
+					}
+				}
+				scene++
+				slots[i].scene = scene
 			}
+			slots[i].path = inputPath
+			slots[i].opts = imageGenOptionsFromConfig(cfg, title, effectiveDesc)
+			slots[i].opts.Scene = slots[i].scene
+			slots[i].opts.Context = ctx
+		}
 
-			// Build options with caption/subcaption for validation
-			opts := ImageGenOptions{
-				Description:  effectiveDesc,
-				Title:        title,
-				Provider:     cfg.ImageProvider,
-				Caption:      cfg.ImageCaption,
-				Subcaption:   cfg.ImageSubcaption,
-				AspectRatio:  cfg.AspectRatio,
-				ValidateText: cfg.ImageCaption != "" || cfg.ImageSubcaption != "",
-				MaxRetries:   10,
-				StyleType:    cfg.StyleType,
-				StylePreset:  cfg.StylePreset,
-			}
+		workers := 1
+		if generateSlots > 1 {
+			workers = maxConcurrentImageGenerations
+		}
+		results := make([]*MediaInput, len(slots))
+		errs := make([]error, len(slots))
+		jobs := make(chan int)
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range jobs {
+					results[i], errs[i] = processImageInput(slots[i].path, slots[i].opts, description, cleanup)
+				}
+			}()
+		}
+		for i := range slots {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
 
-			input, err := processImageInputWithOpts(inputPath, opts, description, cleanup)
-			if err != nil {
-				return nil, fmt.Errorf("failed to process image input %s: %w", inputPath, err)
+		for i, sl := range slots {
+			if errs[i] != nil {
+				return nil, fmt.Errorf("failed to process image input %s: %w", fileutil.RedactURL(sl.path), errs[i])
 			}
-
-			inputs = append(inputs, *input)
+			scene := sl.scene
+			if scene > 0 && generateSlots > 1 {
+				logx.Debugf("Image slot %d (scene %d/%d): %s\n  Prompt: %s", i+1, scene, generateSlots, results[i].Path, sl.opts.Description)
+			} else {
+				scene = 0 // A lone generated image has no scene to name
+			}
+			if scene > 0 || results[i].AspectCheck != "" {
+				if err := writePromptSidecar(results[i], scene, generateSlots, results[i].Prompt); err != nil {
+					logx.Warnf("Failed to write prompt sidecar: %v", err)
+				}
+			}
+			results[i].Entry = fileutil.RedactURL(entries[i])
+			if entries[i] != sl.path {
+				results[i].Source = SourceDownloaded // A playlist entry
+			}
+			if sl.brief != nil {
+				results[i].Brief = sl.brief
+			}
+			inputs = append(inputs, *results[i])
 		}
 	} else if cfg.AutoFill {
-		log.Println("Auto-generating default image")
+		logx.Infof("Auto-generating default image")
 
 		imageDesc := cfg.ImageDescription
+		var brief *genai.AudioBrief
 		if imageDesc == "" {
 			// Prefer audio-generated prompt, then title-based fallback
-			if audioGeneratedPrompt != "" {
-				imageDesc = audioGeneratedPrompt
-			} else if title != "" {
+			if useAnalysis {
+				imageDesc, brief = analysis.next()
+			}
+			if imageDesc == "" && title != "" {
 				imageDesc = fmt.Sprintf("A visual representation of audio titled %s", title)
-			} else {
+			} else if imageDesc == "" {
 				imageDesc = "A visually engaging background image"
 			}
 		}
 
-		opts := ImageGenOptions{
-			Description:  imageDesc,
-			Title:        title,
-			Provider:     cfg.ImageProvider,
-			Caption:      cfg.ImageCaption,
-			Subcaption:   cfg.ImageSubcaption,
-			AspectRatio:  cfg.AspectRatio,
-			ValidateText: cfg.ImageCaption != "" || cfg.ImageSubcaption != "",
-			MaxRetries:   10,
-			StyleType:    cfg.StyleType,
-			StylePreset:  cfg.StylePreset,
-		}
-
-		input, err := generateImageWithValidation(opts, cleanup)
+		opts := imageGenOptionsFromConfig(cfg, title, imageDesc)
+		opts.Context = ctx
+		input, report, err := generateImageWithValidation(opts, cleanup)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate default image: %w", err)
 		}
+		input.Source, input.Entry, input.Prompt, input.Brief, input.Attempts = SourceGenerated, "generate", imageDesc, brief, report
+		if input.AspectCheck != "" {
+			if err := writePromptSidecar(input, 0, 1, imageDesc); err != nil {
+				logx.Warnf("Failed to write prompt sidecar: %v", err)
+			}
+		}
 
 		inputs = append(inputs, *input)
 	}
 
-	log.Printf("Processed %d media inputs", len(inputs))
+	logx.Debugf("Processed %d media inputs", len(inputs))
 	return inputs, nil
 }
 
+// expandPlaylists downloads the playlist URLs among inputPaths and puts their
+// videos in the playlist's place, in playlist order. entries holds the input
+// each path came from.
+func expandPlaylists(inputPaths []string, cfg *config.Config, cleanup *fileutil.CleanupManager) (paths, entries []string, err error) {
+	for _, inputPath := range inputPaths {
+		if !IsVideoURL(inputPath, cfg.ForceYtDlp) || !fileutil.IsPlaylistURL(inputPath) {
+			paths, entries = append(paths, inputPath), append(entries, inputPath)
+			continue
+		}
+		logx.Infof("Downloading playlist videos with yt-dlp: %s", fileutil.RedactURL(inputPath))
+		playlist, err := fileutil.DownloadPlaylistVideo(inputPath, fileutil.YtDlpOptionsFromConfig(cfg), cleanup)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to process image input %s: %w", fileutil.RedactURL(inputPath), err)
+		}
+		for _, file := range playlist.Files {
+			paths, entries = append(paths, file), append(entries, inputPath)
+		}
+	}
+	return paths, entries, nil
+}
+
+// imageGenOptionsFromConfig builds generation options, including caption
+// validation, from the command-line configuration
+func imageGenOptionsFromConfig(cfg *config.Config, title, description string) ImageGenOptions {
+	return ImageGenOptions{
+		Description:  description,
+		Title:        title,
+		Provider:     cfg.ImageProvider,
+		Caption:      cfg.ImageCaption,
+		Subcaption:   cfg.ImageSubcaption,
+		AspectRatio:  cfg.AspectRatio,
+		ValidateText: cfg.ImageCaption != "" || cfg.ImageSubcaption != "",
+		MaxRetries:   10,
+		StyleType:    cfg.StyleType,
+		StylePreset:  cfg.StylePreset,
+		ForceYtDlp:   cfg.ForceYtDlp,
+		OutputPath:   cfg.Output,
+		Fit:          cfg.Fit,
+		Focus:        cfg.Focus,
+
+		StrictAspect: cfg.StrictAspect,
+
+		CaptionFallback: cfg.CaptionFallback,
+		CaptionFont:     cfg.CaptionFont,
+		CaptionFontSize: cfg.CaptionFontSize,
+		CaptionColor:    cfg.CaptionColor,
+
+		CasingPolicy:    genai.CasingPolicy(cfg.CasingPolicy),
+		Reviewer:        cfg.Reviewer,
+		ReviewModel:     cfg.ReviewModel,
+		Retries:         cfg.GeminiRetries,
+		ValidationModel: cfg.ValidationModel,
+		NoFallback:      cfg.NoOpenAIFallback,
+		Language:        cfg.Language,
+		GeminiKey:       cfg.GeminiKey,
+
+		DownloadHeaders: cfg.ImageHeaders,
+		YtDlp:           fileutil.YtDlpOptionsFromConfig(cfg),
+
+		AdaptiveRetry: cfg.AdaptiveRetry,
+		Debug:         cfg.Debug,
+
+		NegativePrompt: negativePrompt(cfg.NegativePrompt, genai.ParseAvoidList(cfg.ImageAvoid)),
+		Seed:           cfg.Seed,
+		Model:          cfg.ReplicateModel,
+		Quality:        cfg.ImageQuality,
+		SDEndpoint:     cfg.SDEndpoint,
+		SDSteps:        cfg.SDSteps,
+		SDSampler:      cfg.SDSampler,
+		SDTimeout:      time.Duration(cfg.SDTimeout) * time.Second,
+		Upscale:        cfg.Upscale,
+		ReferenceImage: cfg.ReferenceImage,
+		ImageWeight:    cfg.ImageWeight,
+		NoCache:        cfg.NoImageCache,
+	}
+}
+
+// writePromptSidecar records which scene (if scene > 0), aspect check, and
+// prompt produced an image in a .txt file next to it
+func writePromptSidecar(input *MediaInput, scene, scenes int, prompt string) error {
+	sidecar := strings.TrimSuffix(input.Path, filepath.Ext(input.Path)) + ".txt"
+	var content string
+	if scene > 0 {
+		content = fmt.Sprintf("Scene %d/%d\n", scene, scenes)
+	}
+	if input.AspectCheck != "" {
+		content += fmt.Sprintf("Aspect: %s\n", input.AspectCheck)
+	}
+	content += "\n" + prompt + "\n"
+	return os.WriteFile(sidecar, []byte(content), 0644)
+}
+
 func processImageInputWithOpts(inputPath string, opts ImageGenOptions, fallbackDesc string, cleanup *fileutil.CleanupManager) (*MediaInput, error) {
 	switch {
 	case strings.ToLower(inputPath) == "generate":
@@ -200,36 +595,58 @@ func processImageInputWithOpts(inputPath string, opts ImageGenOptions, fallbackD
 			}
 			opts.Description = desc
 		}
-		log.Printf("Generating image with %s: %s", opts.Provider, desc)
-		return generateImageWithValidation(opts, cleanup)
+		logx.Infof("Generating image with %s: %s", opts.Provider, desc)
+		input, report, err := generateImageWithValidation(opts, cleanup)
+		if err != nil {
+			return nil, err
+		}
+		input.Source, input.Prompt, input.Attempts = SourceGenerated, desc, report
+		return input, nil
 
-	case fileutil.IsYouTubeURL(inputPath):
-		log.Printf("Downloading YouTube video: %s", inputPath)
-		videoPath, err := fileutil.DownloadYouTubeVideo(inputPath, cleanup)
+	case IsVideoURL(inputPath, opts.ForceYtDlp):
+		logx.Infof("Downloading video with yt-dlp: %s", fileutil.RedactURL(inputPath))
+		videoPath, err := fileutil.DownloadRemoteVideo(inputPath, opts.YtDlp, cleanup)
 		if err != nil {
 			return nil, err
 		}
 		return &MediaInput{
 			Path:    videoPath,
 			IsVideo: true,
+			Source:  SourceDownloaded,
 		}, nil
 
 	case strings.HasPrefix(inputPath, "http"):
-		log.Printf("Downloading image from URL: %s", inputPath)
-		imagePath, err := fileutil.DownloadImage(inputPath, cleanup)
+		logx.Infof("Downloading image from URL: %s", fileutil.RedactURL(inputPath))
+		imagePath, err := fileutil.DownloadImage(inputPath, opts.DownloadHeaders, cleanup)
+		if err != nil {
+			return nil, err
+		}
+		imagePath, err = FitImage(imagePath, opts, cleanup)
 		if err != nil {
 			return nil, err
 		}
 		return &MediaInput{
-			Path: imagePath,
+			Path:   imagePath,
+			Source: SourceDownloaded,
 		}, nil
 
 	case fileutil.FileExists(inputPath):
-		log.Printf("Using local file: %s", inputPath)
+		logx.Infof("Using local file: %s", inputPath)
 		isVideo := IsVideoFile(inputPath)
+		path := inputPath
+		if !isVideo {
+			var err error
+			if path, err = ConvertImage(inputPath, opts, cleanup); err != nil {
+				return nil, err
+			}
+			if path, err = FitImage(path, opts, cleanup); err != nil {
+				return nil, err
+			}
+		}
 		return &MediaInput{
-			Path:    inputPath,
+			Path:    path,
 			IsVideo: isVideo,
+			Source:  SourceLocal,
 		}, nil
 
 	default:
@@ -237,6 +654,335 @@ func processImageInputWithOpts(inputPath string, opts ImageGenOptions, fallbackD
 	}
 }
 
+// overlayCaption composites opts.Caption and opts.Subcaption onto an image
+// with ffmpeg drawtext. Following the prompts' "reserve negative space"
+// convention, the text sits centered in the upper band over a translucent box
+// so it stays legible on busy backgrounds. Text is passed via textfile to
+// avoid drawtext escaping.
+func overlayCaption(input *MediaInput, opts ImageGenOptions, cleanup *fileutil.CleanupManager) (*MediaInput, error) {
+	name := strings.TrimSuffix(filepath.Base(input.Path), filepath.Ext(input.Path))
+	captioned := fileutil.TempAssetPath(config.TempAssetsFolder, opts.OutputPath, fmt.Sprintf("captioned_%s.png", name))
+
+	size := "h/12"
+	subSize := "h/24"
+	if opts.CaptionFontSize > 0 {
+		size = strconv.Itoa(opts.CaptionFontSize)
+		subSize = strconv.Itoa(max(opts.CaptionFontSize/2, 1))
+	}
+	color := opts.CaptionColor
+	if color == "" {
+		color = config.DefaultCaptionColor
+	}
+
+	var filters []string
+	drawtext := func(text, fontSize, y, suffix string) error {
+		textFile := fileutil.TempAssetPath(config.TempAssetsFolder, opts.OutputPath, fmt.Sprintf("%s_%s.txt", name, suffix))
+		if err := os.WriteFile(textFile, []byte(text), 0644); err != nil {
+			return fmt.Errorf("failed to write caption text: %w", err)
+		}
+		cleanup.Add(textFile)
+
+		args := []string{
+			"textfile=" + escapeFilterValue(textFile),
+			"fontsize=" + fontSize,
+			"fontcolor=" + escapeFilterValue(color),
+			"x=(w-text_w)/2",
+			"y=" + y,
+			"box=1",
+			"boxcolor=black@0.35",
+			"boxborderw=" + "(" + fontSize + ")/3",
+		}
+		if opts.CaptionFont != "" {
+			args = append(args, "fontfile="+escapeFilterValue(opts.CaptionFont))
+		}
+		filters = append(filters, "drawtext="+strings.Join(args, ":"))
+		return nil
+	}
+
+	captionY := "h*0.10"
+	if opts.Caption != "" {
+		if err := drawtext(opts.Caption, size, captionY, "caption"); err != nil {
+			return nil, err
+		}
+		captionY = fmt.Sprintf("h*0.10+(%s)*1.6", size)
+	}
+	if opts.Subcaption != "" {
+		if err := drawtext(opts.Subcaption, subSize, captionY, "subcaption"); err != nil {
+			return nil, err
+		}
+	}
+	if len(filters) == 0 {
+		return input, nil
+	}
+
+	cmd := []string{"ffmpeg", "-y", "-i", input.Path, "-vf", strings.Join(filters, ","), "-frames:v", "1", captioned}
+	if output, err := ffmpeg.RunCommandWithOutput(cmd); err != nil {
+		return nil, fmt.Errorf("ffmpeg drawtext failed: %w\n%s", err, truncateString(string(output), 500))
+	}
+	cleanup.Add(captioned)
+
+	return &MediaInput{
+		Path:        captioned,
+		IsGenerated: input.IsGenerated,
+		AspectCheck: input.AspectCheck,
+		TextOverlay: true,
+	}, nil
+}
+
+// escapeFilterValue quotes a value for use inside an ffmpeg filter option
+func escapeFilterValue(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// aspectTolerance is the relative ratio difference accepted from providers
+const aspectTolerance = 0.02
+
+// fitImage is a variable so tests can run checkAspectRatio's crop without
+// ffmpeg
+var fitImage = FitImage
+
+// checkAspectRatio compares a generated image with opts.AspectRatio. A
+// mismatch beyond aspectTolerance fails the attempt with StrictAspect, and is
+// otherwise center-cropped to the requested ratio. The outcome is recorded in
+// AspectCheck. Images that cannot be probed are accepted as they are.
+func checkAspectRatio(input *MediaInput, opts ImageGenOptions, cleanup *fileutil.CleanupManager) (*MediaInput, error) {
+	ratio := opts.AspectRatio
+	if ratio == "" {
+		ratio = config.AspectRatio16x9
+	}
+
+	width, height, err := imageSize(input.Path)
+	if err != nil {
+		input.AspectCheck = fmt.Sprintf("not checked (%v)", err)
+		return input, nil
+	}
+
+	rw, rh := ratio.Ratio()
+	target := float64(rw) / float64(rh)
+	actual := float64(width) / float64(height)
+	if math.Abs(actual-target)/target <= aspectTolerance {
+		input.AspectCheck = fmt.Sprintf("ok (%dx%d matches %s)", width, height, ratio)
+		return input, nil
+	}
+
+	mismatch := fmt.Sprintf("%dx%d does not match %s", width, height, ratio)
+	if opts.StrictAspect {
+		os.Remove(input.Path)
+		return nil, fmt.Errorf("generated image %s (--strict-aspect)", mismatch)
+	}
+
+	cropOpts := opts
+	cropOpts.AspectRatio = ratio
+	cropOpts.Fit = config.FitCrop
+	cropOpts.Focus = config.Focus{X: 0.5, Y: 0.5}
+	cropped, err := fitImage(input.Path, cropOpts, cleanup)
+	if err != nil {
+		return nil, fmt.Errorf("generated image %s and cropping failed: %w", mismatch, err)
+	}
+	os.Remove(input.Path)
+	return &MediaInput{
+		Path:        cropped,
+		IsGenerated: input.IsGenerated,
+		AspectCheck: fmt.Sprintf("cropped (%s)", mismatch),
+	}, nil
+}
+
+// canvasLongEdge is the long edge, in pixels, SVGs are rasterized to
+const canvasLongEdge = 1920
+
+// ConvertImage rasterizes SVG and decodes HEIC/HEIF stills, which ffmpeg
+// cannot consume reliably, into a PNG in the temp folder. Other files are
+// returned unchanged.
+func ConvertImage(path string, opts ImageGenOptions, cleanup *fileutil.CleanupManager) (string, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".svg" && ext != ".heic" && ext != ".heif" {
+		return path, nil
+	}
+
+	converted := convertedImagePath(path, opts)
+
+	var candidates [][]string
+	var install string
+	if ext == ".svg" {
+		width, height := canvasSize(opts.AspectRatio)
+		size := fmt.Sprintf("%dx%d", width, height)
+		candidates = [][]string{
+			{"rsvg-convert", "--keep-aspect-ratio", "-w", strconv.Itoa(width), "-h", strconv.Itoa(height), "-o", converted, path},
+			{"magick", "-background", "none", "-density", "300", path, "-resize", size, converted},
+			{"convert", "-background", "none", "-density", "300", path, "-resize", size, converted},
+		}
+		install = "librsvg (rsvg-convert) or ImageMagick"
+	} else {
+		candidates = [][]string{
+			{"magick", path, converted},
+			{"heif-convert", path, converted},
+			{"ffmpeg", "-y", "-i", path, "-frames:v", "1", converted},
+		}
+		install = "ImageMagick with HEIC support (magick), libheif (heif-convert), or ffmpeg 7.1+"
+	}
+
+	var failures []string
+	for _, cmd := range candidates {
+		if _, err := exec.LookPath(cmd[0]); err != nil {
+			continue
+		}
+		logx.Debugf("Converting %s to PNG with %s", path, cmd[0])
+		output, err := ffmpeg.RunCommandWithOutput(cmd)
+		if err == nil && fileutil.FileExists(converted) {
+			cleanup.Add(converted)
+			return converted, nil
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v\n%s", cmd[0], err, truncateString(string(output), 300)))
+	}
+
+	if len(failures) == 0 {
+		return "", fmt.Errorf("cannot convert %s: no converter found; install %s", path, install)
+	}
+	return "", fmt.Errorf("failed to convert %s (install %s if these tools lack support):\n%s", path, install, strings.Join(failures, "\n"))
+}
+
+// convertedImagePath names ConvertImage's PNG of path, tagged with its
+// source like fittedImagePath
+func convertedImagePath(path string, opts ImageGenOptions) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return fileutil.TempAssetPath(config.TempAssetsFolder, opts.OutputPath, fmt.Sprintf("converted_%s_%s.png", name, sourceTag(path)))
+}
+
+// canvasSize returns the pixel size SVGs are rasterized to: the aspect ratio
+// scaled to a canvasLongEdge long edge, with even dimensions for encoders
+func canvasSize(ar config.AspectRatio) (int, int) {
+	w, h := ar.Ratio()
+	if w >= h {
+		return canvasLongEdge, canvasLongEdge * h / w &^ 1
+	}
+	return canvasLongEdge * w / h &^ 1, canvasLongEdge
+}
+
+// FitImage writes a copy of a still adjusted to opts.AspectRatio according to
+// opts.Fit into the temp folder and returns its path. The original is never
+// modified; it is returned unchanged when Fit is unset or already matches.
+func FitImage(path string, opts ImageGenOptions, cleanup *fileutil.CleanupManager) (string, error) {
+	if opts.Fit == config.FitNone {
+		return path, nil
+	}
+
+	width, height, err := imageSize(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read size of %s: %w", path, err)
+	}
+	filter := fitFilter(width, height, opts.AspectRatio, opts.Fit, opts.Focus)
+	if filter == "" {
+		return path, nil
+	}
+
+	fitted := fittedImagePath(path, opts)
+	logx.Debugf("Fitting %s (%dx%d) to %s with %s: %s", path, width, height, opts.AspectRatio, opts.Fit, filter)
+
+	cmd := []string{"ffmpeg", "-y", "-i", path, "-vf", filter, "-frames:v", "1", fitted}
+	if output, err := ffmpeg.RunCommandWithOutput(cmd); err != nil {
+		return "", fmt.Errorf("failed to fit %s: %w\n%s", path, err, truncateString(string(output), 500))
+	}
+	cleanup.Add(fitted)
+	return fitted, nil
+}
+
+// fittedImagePath names FitImage's copy of path. The source tag keeps
+// same-named stills from different folders apart.
+func fittedImagePath(path string, opts ImageGenOptions) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return fileutil.TempAssetPath(config.TempAssetsFolder, opts.OutputPath, fmt.Sprintf("fit_%s_%s_%s.png", opts.Fit, name, sourceTag(path)))
+}
+
+// sourceTag is a short hash of path's absolute form, for temp file names
+// derived from user files that may share a base name
+func sourceTag(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	sum := sha256.Sum256([]byte(path))
+	return hex.EncodeToString(sum[:4])
+}
+
+// imageSize returns a still's pixel dimensions, read from the header of a
+// PNG, JPEG or GIF and via ffprobe for other formats
+func imageSize(path string) (int, int, error) {
+	if f, err := os.Open(path); err == nil {
+		cfg, _, err := goimage.DecodeConfig(f)
+		f.Close()
+		if err == nil && cfg.Width > 0 && cfg.Height > 0 {
+			return cfg.Width, cfg.Height, nil
+		}
+	}
+
+	output, err := ffmpeg.RunCommandWithOutput([]string{"ffprobe", "-v", "error", "-select_streams", "v:0",
+		"-show_entries", "stream=width,height", "-of", "csv=p=0:s=x", path})
+	if err != nil {
+		return 0, 0, fmt.Errorf("ffprobe failed: %w", err)
+	}
+	var width, height int
+	if _, err := fmt.Sscanf(strings.TrimSpace(string(output)), "%dx%d", &width, &height); err != nil || width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("unexpected ffprobe output %q", strings.TrimSpace(string(output)))
+	}
+	return width, height, nil
+}
+
+// fitFilter returns the ffmpeg filter that brings a width x height still to
+// the aspect ratio, or "" when it is already within 1%. Crop keeps the focus
+// point as close to center as the frame allows; pad centers the image on
+// black; stretch keeps the width and rescales the height. Output dimensions
+// are even so the result can be encoded as yuv420p video.
+func fitFilter(width, height int, ratio config.AspectRatio, mode config.FitMode, focus config.Focus) string {
+	rw, rh := ratio.Ratio()
+	target := float64(rw) / float64(rh)
+	current := float64(width) / float64(height)
+	if math.Abs(current-target)/target < 0.01 {
+		return ""
+	}
+
+	even := func(v float64) int {
+		n := int(math.Round(v/2)) * 2
+		if n < 2 {
+			n = 2
+		}
+		return n
+	}
+
+	switch mode {
+	case config.FitCrop:
+		cw, ch := width&^1, height&^1
+		if current > target {
+			cw = min(even(float64(height)*target), cw)
+		} else {
+			ch = min(even(float64(width)/target), ch)
+		}
+		x := clampInt(int(math.Round(focus.X*float64(width)-float64(cw)/2)), 0, width-cw)
+		y := clampInt(int(math.Round(focus.Y*float64(height)-float64(ch)/2)), 0, height-ch)
+		return fmt.Sprintf("crop=%d:%d:%d:%d", cw, ch, x, y)
+	case config.FitPad:
+		pw, ph := even(float64(width)), even(float64(height))
+		if current > target {
+			ph = max(even(float64(width)/target), ph)
+		} else {
+			pw = max(even(float64(height)*target), pw)
+		}
+		return fmt.Sprintf("pad=%d:%d:(ow-iw)/2:(oh-ih)/2:color=black", pw, ph)
+	case config.FitStretch:
+		w := even(float64(width))
+		return fmt.Sprintf("scale=%d:%d,setsar=1", w, even(float64(w)/target))
+	}
+	return ""
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
 func generateImage(description, title string, provider config.ImageProvider, cleanup *fileutil.CleanupManager) (*MediaInput, error) {
 	if err := fileutil.EnsureTempFolder(); err != nil {
 		return nil, fmt.Errorf("failed to create temp folder: %w", err)
@@ -245,7 +991,17 @@ func generateImage(description, title string, provider config.ImageProvider, cle
 	// Route to appropriate provider
 	switch provider {
 	case config.ImageProviderDALLE:
-		return generateDALLEImage3(description, title, 1, cleanup)
+		return generateDALLEImage3(ImageGenOptions{Description: description, Title: title, AttemptNum: 1}, cleanup)
+	case config.ImageProviderStability:
+		return generateStabilityImage(ImageGenOptions{Description: description, Title: title, AttemptNum: 1}, cleanup)
+	case config.ImageProviderReplicate:
+		return generateReplicateImage(ImageGenOptions{Description: description, Title: title, AttemptNum: 1}, cleanup)
+	case config.ImageProviderGPTImage:
+		return generateGPTImage(ImageGenOptions{Description: description, Title: title, AttemptNum: 1}, cleanup)
+	case config.ImageProviderImagen:
+		return generateImagenImage(ImageGenOptions{Description: description, Title: title, AttemptNum: 1}, cleanup)
+	case config.ImageProviderLocalSD:
+		return generateLocalSDImage(ImageGenOptions{Description: description, Title: title, AttemptNum: 1}, cleanup)
 	case config.ImageProviderIdeogram:
 		fallthrough
 	default:
@@ -253,19 +1009,93 @@ func generateImage(description, title string, provider config.ImageProvider, cle
 	}
 }
 
+// AttemptRecord describes one generation attempt in an AttemptsReport
+type AttemptRecord struct {
+	Attempt  int      `json:"attempt"`
+	Provider string   `json:"provider"`
+	Path     string   `json:"path,omitempty"`
+	Score    float64  `json:"score,omitempty"`
+	Verdict  string   `json:"verdict"` // accepted, rejected, unvalidated, text-overlay, cached, or error
+	Issues   []string `json:"issues,omitempty"`
+}
+
+// AttemptsReport records every attempt made by one generateImageWithValidation
+// call, so retries can be reviewed without digging through logs
+type AttemptsReport struct {
+	Caption    string          `json:"caption,omitempty"`
+	Subcaption string          `json:"subcaption,omitempty"`
+	Outcome    string          `json:"outcome"`            // accepted, best-effort, text-overlay, unvalidated, cached, failed, or interrupted
+	Selected   int             `json:"selected,omitempty"` // Attempt number of the image used, if any
+	Attempts   []AttemptRecord `json:"attempts"`
+	Path       string          `json:"-"` // Where the report was written
+
+	// With ValidatePrompt, the prompt check of the selected image (nil if it
+	// was not checked)
+	PromptValidation *genai.PromptValidationResult `json:"prompt_validation,omitempty"`
+}
+
+// reportSeq numbers attempts reports written by this process
+var reportSeq atomic.Int64
+
+// write saves the report as JSON in the temp folder. Reports of failed runs
+// are kept alongside the retained images; others are cleaned up with the run.
+func (r *AttemptsReport) write(outputPath string, cleanup *fileutil.CleanupManager) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		logx.Warnf("Failed to encode attempts report: %v", err)
+		return
+	}
+	path := fileutil.TempAssetPath(config.TempAssetsFolder, outputPath, fmt.Sprintf("attempts_%03d.json", reportSeq.Add(1)))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		logx.Warnf("Failed to write attempts report: %v", err)
+		return
+	}
+	if r.Outcome != "failed" && cleanup != nil {
+		cleanup.Add(path)
+	}
+	r.Path = path
+}
+
+// PrintAttemptsReport writes the report as a table
+func PrintAttemptsReport(w io.Writer, report *AttemptsReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ATTEMPT\tPROVIDER\tSCORE\tVERDICT\tISSUES\tFILE")
+	for _, a := range report.Attempts {
+		score := "-"
+		if a.Score > 0 {
+			score = fmt.Sprintf("%.1f", a.Score)
+		}
+		mark := ""
+		if a.Attempt == report.Selected {
+			mark = " *"
+		}
+		fmt.Fprintf(tw, "%d%s\t%s\t%s\t%s\t%s\t%s\n", a.Attempt, mark, a.Provider, score, a.Verdict, strings.Join(a.Issues, "; "), a.Path)
+	}
+	return tw.Flush()
+}
+
 // GenerateAndValidateImage is a public wrapper for generateImageWithValidation
-func GenerateAndValidateImage(opts ImageGenOptions, cleanup *fileutil.CleanupManager) (*MediaInput, error) {
+func GenerateAndValidateImage(opts ImageGenOptions, cleanup *fileutil.CleanupManager) (*MediaInput, *AttemptsReport, error) {
 	return generateImageWithValidation(opts, cleanup)
 }
 
-// generateImageWithValidation generates an image and validates text rendering using Gemini
-func generateImageWithValidation(opts ImageGenOptions, cleanup *fileutil.CleanupManager) (*MediaInput, error) {
+// validateImageText and overlayText are variables so tests can run
+// generateImageWithValidation without Gemini or ffmpeg
+var (
+	validateImageText = genai.ValidateGeneratedImage
+	overlayText       = overlayCaption
+)
+
+// generateImageWithValidation generates an image and validates text rendering
+// using Gemini. When a cached image is reused, the report's outcome is cached
+// and its one attempt is the copy.
+func generateImageWithValidation(opts ImageGenOptions, cleanup *fileutil.CleanupManager) (*MediaInput, *AttemptsReport, error) {
 	if err := fileutil.EnsureTempFolder(); err != nil {
-		return nil, fmt.Errorf("failed to create temp folder: %w", err)
+		return nil, nil, fmt.Errorf("failed to create temp folder: %w", err)
 	}
 
 	if !opts.ValidateText || (opts.Caption == "" && opts.Subcaption == "") {
-		log.Printf("Note: Image text validation is disabled (no image-caption/image-subcaption provided). Generated images may not contain any rendered text.")
+		logx.Infof("Note: Image text validation is disabled (no image-caption/image-subcaption provided). Generated images may not contain any rendered text.")
 	}
 
 	maxRetries := opts.MaxRetries
@@ -273,17 +1103,124 @@ func generateImageWithValidation(opts ImageGenOptions, cleanup *fileutil.Cleanup
 		maxRetries = 10
 	}
 
+	provider := string(opts.Provider)
+	if provider == "" {
+		provider = string(config.ImageProviderIdeogram)
+	}
+
+	// Identical requests reuse a previously accepted image
+	var cache *imageCache
+	cacheKey := imageCacheKey(opts)
+	if !opts.NoCache {
+		var err error
+		cache, err = openImageCache(opts.CacheDir, config.DefaultImageCacheMaxSize)
+		if err != nil {
+			logx.Warnf("Image cache disabled: %v", err)
+		}
+	}
+	if cachedPath, hit := cache.lookup(cacheKey); hit {
+		logx.Infof("Using cached image: %s", cachedPath)
+		input, err := copyCachedImage(cachedPath, opts.OutputPath, cleanup)
+		if err == nil {
+			input, err = checkAspectRatio(input, opts, cleanup)
+		}
+		if err == nil {
+			if cleanup != nil {
+				cleanup.Remove(input.Path)
+			}
+			report := &AttemptsReport{
+				Caption:    opts.Caption,
+				Subcaption: opts.Subcaption,
+				Outcome:    "cached",
+				Selected:   1,
+				Attempts:   []AttemptRecord{{Attempt: 1, Provider: provider, Path: input.Path, Verdict: "cached"}},
+			}
+			report.write(opts.OutputPath, cleanup)
+			return input, report, nil
+		}
+		logx.Warnf("Failed to reuse cached image, generating a new one: %v", err)
+	}
+	accept := func(input *MediaInput, attempt int) *MediaInput {
+		input = finalizeImage(input, opts, attempt, cleanup)
+		if err := cache.store(cacheKey, input.Path); err != nil {
+			logx.Warnf("Failed to cache image: %v", err)
+		}
+		return input
+	}
+
+	// Ideogram remixes the reference directly; other providers get it described
+	isIdeogram := opts.Provider == config.ImageProviderIdeogram || opts.Provider == ""
+	if opts.ReferenceImage != "" && !isIdeogram {
+		opts = applyReferenceDescription(opts)
+		opts.ReferenceImage = ""
+	}
+
 	var lastErr error
 	var bestInput *MediaInput
 	var bestScore float64 = 0
+	var bestAttempt int
+	var bestPrompt *genai.PromptValidationResult
 
 	// Track all generated images to clean up non-best at the end
 	type attemptResult struct {
-		input *MediaInput
-		score float64
+		input  *MediaInput
+		score  float64
+		prompt *genai.PromptValidationResult
 	}
 	var allAttempts []attemptResult
 
+	// Corrective instructions from the last failed validation, if any
+	var feedback string
+
+	report := &AttemptsReport{Caption: opts.Caption, Subcaption: opts.Subcaption}
+	record := func(attempt int, path string, score float64, verdict string, issues ...string) {
+		report.Attempts = append(report.Attempts, AttemptRecord{
+			Attempt:  attempt,
+			Provider: provider,
+			Path:     path,
+			Score:    score,
+			Verdict:  verdict,
+			Issues:   issues,
+		})
+		events.Emit("image_attempt", events.Fields{
+			"attempt":  attempt,
+			"provider": provider,
+			"path":     path,
+			"score":    score,
+			"verdict":  verdict,
+			"issues":   issues,
+		})
+	}
+	finish := func(outcome string, selected int) *AttemptsReport {
+		report.Outcome = outcome
+		report.Selected = selected
+		if selected > 0 && selected <= len(report.Attempts) {
+			for _, prev := range allAttempts {
+				if prev.input != nil && prev.input.Path == report.Attempts[selected-1].Path {
+					report.PromptValidation = prev.prompt
+				}
+			}
+		}
+		report.write(opts.OutputPath, cleanup)
+		if outcome == "failed" && report.Path != "" {
+			logx.Infof("Attempts report with scores for the retained images: %s", report.Path)
+		}
+		return report
+	}
+
+	vopts := genai.ValidationOptions{
+		Casing:      opts.CasingPolicy,
+		Reviewer:    opts.Reviewer,
+		ReviewModel: opts.ReviewModel,
+		Retries:     opts.Retries,
+		Model:       opts.ValidationModel,
+		NoFallback:  opts.NoFallback,
+		Language:    opts.Language,
+		Client:      genai.ClientOptions{APIKey: opts.GeminiKey},
+	}
+
+	validateText := opts.ValidateText && (opts.Caption != "" || opts.Subcaption != "")
+
 	for attempt := 1; attempt <= maxRetries; attempt++ {
 		// Generate the image - pass attempt number for file naming
 		var input *MediaInput
@@ -292,10 +1229,26 @@ func generateImageWithValidation(opts ImageGenOptions, cleanup *fileutil.Cleanup
 		// Set attempt number for file naming
 		attemptOpts := opts
 		attemptOpts.AttemptNum = attempt
+		if feedback != "" {
+			attemptOpts.Description = opts.Description + "\n\n" + feedback
+			if opts.Debug {
+				logx.Debugf("Adapted prompt for attempt %d/%d:\n%s", attempt, maxRetries, attemptOpts.Description)
+			}
+		}
 
 		switch opts.Provider {
 		case config.ImageProviderDALLE:
-			input, err = generateDALLEImage3(opts.Description, opts.Title, attempt, cleanup)
+			input, err = generateDALLEImage3(attemptOpts, cleanup)
+		case config.ImageProviderStability:
+			input, err = generateStabilityImage(attemptOpts, cleanup)
+		case config.ImageProviderReplicate:
+			input, err = generateReplicateImage(attemptOpts, cleanup)
+		case config.ImageProviderGPTImage:
+			input, err = generateGPTImage(attemptOpts, cleanup)
+		case config.ImageProviderImagen:
+			input, err = generateImagenImage(attemptOpts, cleanup)
+		case config.ImageProviderLocalSD:
+			input, err = generateLocalSDImage(attemptOpts, cleanup)
 		case config.ImageProviderIdeogram:
 			fallthrough
 		default:
@@ -303,161 +1256,823 @@ func generateImageWithValidation(opts ImageGenOptions, cleanup *fileutil.Cleanup
 		}
 
 		if err != nil {
+			record(attempt, "", 0, "error", err.Error())
+			if errors.Is(err, context.Canceled) {
+				return nil, finish("interrupted", 0), fmt.Errorf("image generation interrupted: %w", err)
+			}
 			lastErr = err
-			log.Printf("Image generation failed on attempt %d/%d: %v", attempt, maxRetries, err)
+			logx.Infof("Image generation failed on attempt %d/%d: %v", attempt, maxRetries, err)
 			continue
 		}
 
-		// If validation not needed, return immediately (clean up any previous attempts)
-		if !opts.ValidateText || (opts.Caption == "" && opts.Subcaption == "") {
-			// Clean up any previous attempts
+		input, err = checkAspectRatio(input, attemptOpts, cleanup)
+		if err != nil {
+			record(attempt, "", 0, "rejected", err.Error())
+			lastErr = err
+			logx.Infof("Image rejected on attempt %d/%d: %v", attempt, maxRetries, err)
+			continue
+		}
+		logx.Debugf("Aspect check (attempt %d/%d): %s", attempt, maxRetries, input.AspectCheck)
+
+		// If validation not needed, return immediately (clean up any previous attempts)
+		if !validateText && !opts.ValidatePrompt {
+			// Clean up any previous attempts
 			for _, prev := range allAttempts {
 				if prev.input != nil && cleanup != nil && strings.Contains(prev.input.Path, "temp_assets") {
 					os.Remove(prev.input.Path)
 				}
 			}
-			// Preserve the selected image from cleanup
-			if cleanup != nil {
-				cleanup.Remove(input.Path)
-			}
-			return input, nil
+			record(attempt, input.Path, 0, "unvalidated")
+			return accept(input, attempt), finish("unvalidated", attempt), nil
 		}
 
-		// Validate text rendering with Gemini
-		log.Printf("Validating image text rendering (attempt %d/%d)...", attempt, maxRetries)
-		result, err := genai.ValidateGeneratedImage(input.Path, opts.Caption, opts.Subcaption)
+		// Validate text rendering with Gemini; prompt-only checks start from a pass
+		result := &genai.ImageValidationResult{Path: input.Path, IsAcceptable: true}
+		if validateText {
+			logx.Debugf("Validating image text rendering (attempt %d/%d)...", attempt, maxRetries)
+			result, err = validateImageText(opts.context(), input.Path, opts.Caption, opts.Subcaption, vopts)
+		}
+		var promptResult *genai.PromptValidationResult
+		if err == nil && result.IsAcceptable && opts.ValidatePrompt {
+			logx.Debugf("Validating image against the prompt (attempt %d/%d)...", attempt, maxRetries)
+			promptResult, err = genai.ValidateImageAgainstPrompt(opts.context(), input.Path, opts.Description, opts.Caption, opts.Subcaption, vopts)
+			if err == nil {
+				result = mergePromptValidation(result, promptResult, validateText)
+			}
+		}
+		if err != nil && opts.context().Err() != nil {
+			record(attempt, input.Path, 0, "error", err.Error())
+			return nil, finish("interrupted", 0), fmt.Errorf("image validation interrupted: %w", opts.context().Err())
+		}
 		if err != nil {
-			log.Printf("Warning: Image validation failed, accepting image: %v", err)
+			logx.Warnf("Image validation failed, accepting image: %v", err)
 			// Clean up any previous attempts
 			for _, prev := range allAttempts {
 				if prev.input != nil && cleanup != nil && strings.Contains(prev.input.Path, "temp_assets") {
 					os.Remove(prev.input.Path)
 				}
 			}
-			// Preserve the selected image from cleanup
-			if cleanup != nil {
-				cleanup.Remove(input.Path)
-			}
-			return input, nil
+			record(attempt, input.Path, 0, "unvalidated", fmt.Sprintf("validation error: %v", err))
+			return finalizeImage(input, opts, attempt, cleanup), finish("unvalidated", attempt), nil
 		}
 
 		// Track this attempt (keep all images until we know which is best)
-		allAttempts = append(allAttempts, attemptResult{input: input, score: result.Score})
+		allAttempts = append(allAttempts, attemptResult{input: input, score: result.Score, prompt: promptResult})
 
 		// Track best scoring image
 		if result.Score > bestScore {
 			bestInput = input
 			bestScore = result.Score
+			bestAttempt = attempt
+			bestPrompt = promptResult
 		}
 
 		if result.IsAcceptable {
-			log.Printf("✓ Image text validation passed (score: %.1f)", result.Score)
+			logx.Infof("✓ Image validation passed (score: %.1f)", result.Score)
 			// Clean up non-selected images
 			for _, prev := range allAttempts {
 				if prev.input != nil && prev.input.Path != input.Path && cleanup != nil && strings.Contains(prev.input.Path, "temp_assets") {
 					os.Remove(prev.input.Path)
 				}
 			}
-			// Preserve the selected image from cleanup
-			if cleanup != nil {
-				cleanup.Remove(input.Path)
-			}
-			return input, nil
+			record(attempt, input.Path, result.Score, "accepted")
+			return accept(input, attempt), finish("accepted", attempt), nil
 		}
+		record(attempt, input.Path, result.Score, "rejected", result.Issues...)
 
 		// Validation failed - log issues and retry
-		log.Printf("✗ Image text validation failed (attempt %d/%d, score: %.1f):", attempt, maxRetries, result.Score)
+		logx.Infof("✗ Image validation failed (attempt %d/%d, score: %.1f):", attempt, maxRetries, result.Score)
 		for _, issue := range result.Issues {
-			log.Printf("  - %s", issue)
+			logx.Infof("  - %s", issue)
 		}
 		if len(result.Suggestions) > 0 {
-			log.Printf("  Suggestions:")
+			logx.Infof("  Suggestions:")
 			for _, suggestion := range result.Suggestions {
-				log.Printf("    • %s", suggestion)
+				logx.Infof("    • %s", suggestion)
 			}
 		}
 
 		if attempt < maxRetries {
-			log.Printf("Retrying image generation... (best score so far: %.1f)", bestScore)
+			if opts.AdaptiveRetry {
+				feedback = retryFeedback(result, opts.Caption, opts.Subcaption)
+				if feedback != "" {
+					logx.Debugf("Adding validation feedback to the next prompt (%d chars)", len(feedback))
+				}
+			}
+			logx.Infof("Retrying image generation... (best score so far: %.1f)", bestScore)
+		}
+	}
+
+	// Overlay the text ourselves rather than accept or reject misspelled renders
+	if bestInput != nil && opts.CaptionFallback == config.CaptionFallbackDrawtext {
+		logx.Infof("Text validation failed after %d attempts; overlaying caption with ffmpeg drawtext on best image (score: %.1f)", maxRetries, bestScore)
+		overlaid, err := overlayText(bestInput, opts, cleanup)
+		if err != nil {
+			logx.Warnf("Drawtext caption fallback failed: %v", err)
+		} else {
+			for _, prev := range allAttempts {
+				if prev.input != nil && cleanup != nil && strings.Contains(prev.input.Path, "temp_assets") {
+					os.Remove(prev.input.Path)
+				}
+			}
+
+			// Score the captioned image, not the render it was drawn on
+			score, issues := bestScore, []string(nil)
+			if validateText {
+				result, err := validateImageText(opts.context(), overlaid.Path, opts.Caption, opts.Subcaption, vopts)
+				if err != nil {
+					score, issues = 0, []string{fmt.Sprintf("validation error: %v", err)}
+				} else {
+					score, issues = result.Score, result.Issues
+					logx.Infof("Captioned image score: %.1f", score)
+				}
+			}
+			selected := len(report.Attempts) + 1
+			allAttempts = append(allAttempts, attemptResult{input: overlaid, score: score, prompt: bestPrompt})
+			record(selected, overlaid.Path, score, "text-overlay", issues...)
+			return finalizeImage(overlaid, opts, bestAttempt, cleanup), finish("text-overlay", selected), nil
 		}
 	}
 
 	// If best score meets minimum threshold (>=6.0), use it with a warning
 	if bestInput != nil && bestScore >= 6.0 {
-		log.Printf("Warning: Text validation failed after %d attempts, using best image (score: %.1f)", maxRetries, bestScore)
+		logx.Warnf("Text validation failed after %d attempts, using best image (score: %.1f)", maxRetries, bestScore)
 		// Clean up non-best images
 		for _, prev := range allAttempts {
 			if prev.input != nil && prev.input.Path != bestInput.Path && cleanup != nil && strings.Contains(prev.input.Path, "temp_assets") {
 				os.Remove(prev.input.Path)
 			}
 		}
-		// Preserve the selected image from cleanup
-		if cleanup != nil {
-			cleanup.Remove(bestInput.Path)
-		}
-		return bestInput, nil
+		return finalizeImage(bestInput, opts, bestAttempt, cleanup), finish("best-effort", bestAttempt), nil
 	}
 
 	// Score too low (<6.0) - fail and retain all images for inspection
 	if bestInput != nil {
-		log.Printf("ERROR: Best score %.1f is below minimum threshold (6.0) after %d attempts", bestScore, maxRetries)
-		log.Printf("Retaining all %d generated images in temp_assets for inspection", len(allAttempts))
+		logx.Errorf("Best score %.1f is below minimum threshold (6.0) after %d attempts", bestScore, maxRetries)
+		logx.Infof("Retaining all %d generated images in temp_assets for inspection", len(allAttempts))
 		// Preserve all images from cleanup so user can inspect them
 		for _, prev := range allAttempts {
 			if prev.input != nil && cleanup != nil {
 				cleanup.Remove(prev.input.Path)
 			}
 		}
-		return nil, fmt.Errorf("image validation failed: best score %.1f is below minimum threshold (6.0) after %d attempts", bestScore, maxRetries)
+		return nil, finish("failed", 0), fmt.Errorf("image validation failed: best score %.1f is below minimum threshold (6.0) after %d attempts", bestScore, maxRetries)
+	}
+
+	return nil, finish("failed", 0), fmt.Errorf("failed to generate image after %d attempts: %w", maxRetries, lastErr)
+}
+
+// mergePromptValidation folds a prompt check into an attempt's validation: a
+// mismatch or blocker rejects it, blockers become issues for retry feedback,
+// and the prompt score lowers the attempt's score (or is it, without a text
+// check)
+func mergePromptValidation(result *genai.ImageValidationResult, prompt *genai.PromptValidationResult, textChecked bool) *genai.ImageValidationResult {
+	merged := *result
+	merged.Issues = append([]string(nil), result.Issues...)
+	merged.Suggestions = append(append([]string(nil), result.Suggestions...), prompt.Suggestions...)
+	if !prompt.PromptMatch {
+		merged.IsAcceptable = false
+		merged.Issues = append(merged.Issues, "Image does not match the prompt intent")
+	}
+	if prompt.HasBlocker() {
+		merged.IsAcceptable = false
+		merged.Issues = append(merged.Issues, prompt.IssuesWithSeverity(genai.SeverityBlocker)...)
+	}
+	if !textChecked || (prompt.Score > 0 && prompt.Score < merged.Score) {
+		merged.Score = prompt.Score
+	}
+	return &merged
+}
+
+// maxRetryFeedback caps the corrective text appended to a retry prompt so it
+// cannot crowd out the original description
+const maxRetryFeedback = 600
+
+// retryFeedback turns a failed validation into corrective instructions for
+// the next attempt: exact caption text to render and instruments to leave out
+// for the failures validation classified, then the remaining issues to avoid,
+// capped at maxRetryFeedback characters
+func retryFeedback(result *genai.ImageValidationResult, caption, subcaption string) string {
+	var lines []string
+	classified := make(map[string]bool)
+	for _, failure := range result.Failures {
+		classified[failure.Message] = true
+		switch {
+		case errors.Is(failure, genai.ErrCaptionMismatch):
+			lines = append(lines, fmt.Sprintf("The previous image rendered the caption as %q; render exactly %q.", failure.Seen, caption))
+		case errors.Is(failure, genai.ErrCaptionMissing):
+			lines = append(lines, fmt.Sprintf("The previous image was missing the caption; render %q clearly and legibly.", caption))
+		case errors.Is(failure, genai.ErrSubcaptionMismatch):
+			lines = append(lines, fmt.Sprintf("The previous image rendered the subcaption as %q; render exactly %q.", failure.Seen, subcaption))
+		case errors.Is(failure, genai.ErrSubcaptionMissing):
+			lines = append(lines, fmt.Sprintf("The previous image was missing the subcaption; render %q clearly and legibly.", subcaption))
+		case errors.Is(failure, genai.ErrHallucinatedInstruments):
+			lines = append(lines, fmt.Sprintf("Do not include any musical instruments such as %s.", failure.Seen))
+		default:
+			classified[failure.Message] = false
+		}
+	}
+	for _, issue := range result.Issues {
+		if !classified[issue] {
+			lines = append(lines, fmt.Sprintf("Avoid this problem from the previous image: %s.", strings.TrimRight(issue, ".")))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+
+	feedback := "Corrections from the previous attempt:"
+	for _, line := range lines {
+		if len(feedback)+1+len(line) > maxRetryFeedback {
+			break
+		}
+		feedback += "\n" + line
+	}
+	if !strings.Contains(feedback, "\n") {
+		return ""
+	}
+	return feedback
+}
+
+// imageCache stores accepted generated images on disk, keyed by everything
+// that shapes the request, so iterating on video settings does not pay for
+// the same image twice. A nil cache is valid and never hits.
+type imageCache struct {
+	dir     string
+	maxSize int64
+}
+
+// DefaultImageCacheDir returns the default image cache location (~/.cache/mmmeld/images on Linux)
+func DefaultImageCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "mmmeld", "images"), nil
+}
+
+func openImageCache(dir string, maxSize int64) (*imageCache, error) {
+	if dir == "" {
+		var err error
+		dir, err = DefaultImageCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate cache directory: %w", err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &imageCache{dir: dir, maxSize: maxSize}, nil
+}
+
+// imageCacheKey hashes everything that influences a generated image
+func imageCacheKey(opts ImageGenOptions) string {
+	provider := opts.Provider
+	if provider == "" {
+		provider = config.ImageProviderIdeogram
+	}
+
+	h := sha256.New()
+	for _, part := range []string{
+		string(provider),
+		opts.Description,
+		string(opts.AspectRatio),
+		opts.StyleType,
+		opts.StylePreset,
+		opts.Caption,
+		opts.Subcaption,
+		opts.NegativePrompt,
+		strconv.FormatInt(opts.Seed, 10),
+		opts.Model,
+		opts.Quality,
+		referenceDigest(opts.ReferenceImage),
+		strconv.Itoa(opts.ImageWeight),
+		strconv.FormatBool(opts.Upscale),
+		strconv.Itoa(opts.Scene),
+		string(opts.CasingPolicy),
+		strconv.FormatBool(opts.ValidateText),
+		strconv.FormatBool(opts.ValidatePrompt),
+		opts.Language,
+		opts.CaptionFallback,
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// referenceDigest hashes a reference image's contents, so editing the file in
+// place changes the cache key. An unreadable file falls back to its path.
+func referenceDigest(path string) string {
+	if path == "" {
+		return ""
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return path
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return path
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// lookup returns the cached image for key, refreshing its modification time
+// so eviction removes the least recently used entries first
+func (c *imageCache) lookup(key string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	matches, _ := filepath.Glob(filepath.Join(c.dir, key+".*"))
+	for _, path := range matches {
+		if strings.HasSuffix(path, ".tmp") {
+			continue
+		}
+		now := time.Now()
+		os.Chtimes(path, now, now)
+		return path, true
+	}
+	return "", false
+}
+
+// store copies an accepted image into the cache and evicts old entries. The
+// copy is written to a temp file of its own and renamed into place, so
+// workers storing the same key at once never interleave their writes.
+func (c *imageCache) store(key, path string) error {
+	if c == nil {
+		return nil
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(c.dir, key+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(c.dir, key+imageFileExt(path)))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	_, _, err = PruneImageCache(c.dir, c.maxSize, 0)
+	return err
+}
+
+// imageFileExt returns the extension matching path's contents, which can be
+// JPEG or WebP even when the file is named .png
+func imageFileExt(path string) string {
+	head := make([]byte, 12)
+	if f, err := os.Open(path); err == nil {
+		n, _ := io.ReadFull(f, head)
+		head = head[:n]
+		f.Close()
+	}
+	switch format := fileutil.SniffImageFormat(head); format {
+	case "jpeg":
+		return ".jpg"
+	case "":
+		return ".png"
+	default:
+		return "." + format
+	}
+}
+
+// copyCachedImage copies a cache hit into temp_assets so later steps can
+// treat it like a freshly generated image without touching the cache
+func copyCachedImage(cachedPath, outputPath string, cleanup *fileutil.CleanupManager) (*MediaInput, error) {
+	f, err := os.Open(cachedPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cached image: %w", err)
+	}
+	defer f.Close()
+
+	imagePath, err := saveGeneratedImage(f, -1, "cached", outputPath, 1, cleanup)
+	if err != nil {
+		return nil, err
+	}
+	// Preserve the selected image from cleanup
+	if cleanup != nil {
+		cleanup.Remove(imagePath)
+	}
+	return &MediaInput{Path: imagePath, IsGenerated: true}, nil
+}
+
+// PruneImageCache deletes cached images older than maxAge (0 = no age limit),
+// then the least recently used ones until the cache fits maxSize bytes. An
+// empty dir uses DefaultImageCacheDir. It returns the files and bytes removed.
+func PruneImageCache(dir string, maxSize int64, maxAge time.Duration) (int, int64, error) {
+	if dir == "" {
+		var err error
+		dir, err = DefaultImageCacheDir()
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to locate cache directory: %w", err)
+		}
+	}
+	return fileutil.PruneCache(dir, maxSize, maxAge, "image")
+}
+
+// finalizeImage optionally upscales the accepted image and preserves the
+// chosen file from cleanup. A failed upscale falls back to the original.
+func finalizeImage(input *MediaInput, opts ImageGenOptions, attemptNum int, cleanup *fileutil.CleanupManager) *MediaInput {
+	if opts.Upscale {
+		upscaledPath, err := upscaleIdeogramImage(opts.context(), input.Path, opts.OutputPath, attemptNum, cleanup)
+		if err != nil {
+			logx.Warnf("Image upscale failed, using original image: %v", err)
+		} else {
+			input = &MediaInput{Path: upscaledPath, IsVideo: input.IsVideo, IsGenerated: input.IsGenerated}
+		}
+	}
+
+	// Preserve the selected image from cleanup
+	if cleanup != nil {
+		cleanup.Remove(input.Path)
+	}
+	return input
+}
+
+// upscaleIdeogramImage sends an image to Ideogram's upscale endpoint and
+// downloads the result into temp_assets
+func upscaleIdeogramImage(ctx context.Context, imagePath, outputPath string, attemptNum int, cleanup *fileutil.CleanupManager) (string, error) {
+	apiKey := os.Getenv("IDEOGRAM_API_KEY")
+	if apiKey == "" {
+		return "", fmt.Errorf("IDEOGRAM_API_KEY not found in environment")
+	}
+
+	imageFile, err := os.Open(imagePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open image for upscale: %w", err)
+	}
+	defer imageFile.Close()
+
+	logx.Infof("Upscaling image with Ideogram: %s", imagePath)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if err := form.WriteField("image_request", "{}"); err != nil {
+		return "", fmt.Errorf("failed to build Ideogram upscale request: %w", err)
+	}
+	part, err := form.CreateFormFile("image_file", filepath.Base(imagePath))
+	if err != nil {
+		return "", fmt.Errorf("failed to build Ideogram upscale request: %w", err)
+	}
+	if _, err := io.Copy(part, imageFile); err != nil {
+		return "", fmt.Errorf("failed to build Ideogram upscale request: %w", err)
+	}
+	if err := form.Close(); err != nil {
+		return "", fmt.Errorf("failed to build Ideogram upscale request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", ideogramUpscaleURL, &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create Ideogram upscale request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Api-Key", apiKey)
+
+	client := httpx.Client(180 * time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Ideogram upscale request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read Ideogram upscale response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Ideogram upscale error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+
+	var ideogramResp IdeogramResponse
+	if err := json.Unmarshal(respBody, &ideogramResp); err != nil {
+		return "", fmt.Errorf("failed to parse Ideogram upscale response: %w", err)
+	}
+	if len(ideogramResp.Data) == 0 || ideogramResp.Data[0].URL == "" {
+		return "", fmt.Errorf("no image URL in Ideogram upscale response")
+	}
+
+	upscaledPath, err := downloadImage(ideogramResp.Data[0].URL, "upscaled", outputPath, attemptNum, cleanup)
+	if err != nil {
+		return "", fmt.Errorf("failed to download upscaled image: %w", err)
+	}
+	logx.Debugf("Image upscaled successfully")
+
+	return upscaledPath, nil
+}
+
+// generateDALLEImage3 generates an image using DALL-E 3 with retry logic
+func generateDALLEImage3(opts ImageGenOptions, cleanup *fileutil.CleanupManager) (*MediaInput, error) {
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_PERSONAL_API_KEY")
+	}
+	if apiKey == "" {
+		return nil, fmt.Errorf("OpenAI API key not found in environment")
+	}
+
+	ctx := opts.context()
+	attemptNum := opts.AttemptNum
+	if attemptNum <= 0 {
+		attemptNum = 1
+	}
+
+	maxRetries := 5
+	prompt := opts.Description
+	var lastErr error
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		// Enhance the prompt each attempt; pass isRetry=true on subsequent attempts
+		enhancedPrompt, err := enhanceImagePrompt(ctx, prompt, apiKey, attempt > 0)
+		if errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		if err != nil {
+			logx.Warnf("Failed to enhance prompt (attempt %d), using original: %v", attempt+1, err)
+			enhancedPrompt = prompt
+		}
+
+		imageURL, err := generateDALLEImage(ctx, enhancedPrompt, apiKey)
+		if err == nil {
+			// Download the generated image with attempt number for naming
+			imagePath, dlErr := downloadImage(imageURL, "dalle", opts.OutputPath, attemptNum, cleanup)
+			if dlErr != nil {
+				return nil, fmt.Errorf("failed to download generated image: %w", dlErr)
+			}
+			return &MediaInput{Path: imagePath, IsGenerated: true}, nil
+		}
+
+		lastErr = err
+		if strings.Contains(err.Error(), "content_policy_violation") {
+			logx.Infof("DALL-E content policy violation on attempt %d/%d. Retrying with a safer prompt...", attempt+1, maxRetries)
+			// On retry, modify the prompt slightly to encourage safer content
+			prompt = prompt + " (safe, descriptive, no sensitive content)"
+			continue
+		}
+
+		// Non-policy errors: do not retry
+		break
+	}
+
+	return nil, fmt.Errorf("failed to generate image after %d attempts: %w", maxRetries, lastErr)
+}
+
+// generateGPTImage generates an image with OpenAI's gpt-image-1 at the size
+// closest to the aspect ratio, retrying with a safer prompt on moderation blocks
+func generateGPTImage(opts ImageGenOptions, cleanup *fileutil.CleanupManager) (*MediaInput, error) {
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_PERSONAL_API_KEY")
+	}
+	if apiKey == "" {
+		return nil, fmt.Errorf("OpenAI API key not found in environment")
+	}
+
+	size := opts.AspectRatio.OpenAIImageSize()
+	quality := opts.Quality
+	if quality == "" {
+		quality = "auto"
+	}
+	logx.Infof("Generating image with gpt-image-1 (size: %s, quality: %s)...", size, quality)
+
+	attemptNum := opts.AttemptNum
+	if attemptNum <= 0 {
+		attemptNum = 1
+	}
+
+	maxRetries := 5
+	prompt := opts.Description
+	var lastErr error
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		imageData, err := requestGPTImage(opts.context(), prompt, size, quality, apiKey)
+		if err == nil {
+			imagePath, saveErr := saveGeneratedImage(bytes.NewReader(imageData), -1, "gpt-image", opts.OutputPath, attemptNum, cleanup)
+			if saveErr != nil {
+				return nil, fmt.Errorf("failed to save generated image: %w", saveErr)
+			}
+			return &MediaInput{Path: imagePath, IsGenerated: true}, nil
+		}
+
+		lastErr = err
+		if strings.Contains(err.Error(), "moderation_blocked") || strings.Contains(err.Error(), "content_policy_violation") {
+			logx.Infof("gpt-image-1 content policy violation on attempt %d/%d. Retrying with a safer prompt...", attempt+1, maxRetries)
+			prompt = prompt + " (safe, descriptive, no sensitive content)"
+			continue
+		}
+
+		// Non-policy errors: do not retry
+		break
+	}
+
+	return nil, fmt.Errorf("failed to generate image after %d attempts: %w", maxRetries, lastErr)
+}
+
+// requestGPTImage calls the images endpoint with gpt-image-1 and returns the decoded image
+func requestGPTImage(ctx context.Context, prompt, size, quality, apiKey string) ([]byte, error) {
+	request := OpenAIImageRequest{
+		Model:   "gpt-image-1",
+		Prompt:  prompt,
+		N:       1,
+		Size:    size,
+		Quality: quality,
+	}
+
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal image request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", openAIImagesURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create image request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := httpx.Client(180 * time.Second) // High quality can take minutes
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make image request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("gpt-image-1 API error %d: %s", resp.StatusCode, string(body))
+	}
+
+	var imageResp OpenAIImageResponse
+	if err := json.NewDecoder(resp.Body).Decode(&imageResp); err != nil {
+		return nil, fmt.Errorf("failed to decode image response: %w", err)
+	}
+
+	if len(imageResp.Data) == 0 || imageResp.Data[0].B64JSON == "" {
+		return nil, fmt.Errorf("no image data received")
+	}
+
+	imageData, err := base64.StdEncoding.DecodeString(imageResp.Data[0].B64JSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image data: %w", err)
+	}
+	return imageData, nil
+}
+
+// generateImagenImage generates an image with Google Imagen using the Gemini API key
+func generateImagenImage(opts ImageGenOptions, cleanup *fileutil.CleanupManager) (*MediaInput, error) {
+	ctx, cancel := context.WithTimeout(opts.context(), 120*time.Second)
+	defer cancel()
+
+	client, err := genai.NewSDKClient(ctx, genai.ClientOptions{APIKey: opts.GeminiKey})
+	if err != nil {
+		return nil, err
+	}
+
+	aspectRatioStr := opts.AspectRatio.ImagenAspectRatio()
+	logx.Infof("Generating image with %s (aspect ratio: %s)...", genai.ImagenModel, aspectRatioStr)
+
+	resp, err := client.Models.GenerateImages(ctx, genai.ImagenModel, opts.Description, &googlegenai.GenerateImagesConfig{
+		NumberOfImages: 1,
+		AspectRatio:    aspectRatioStr,
+		OutputMIMEType: "image/png",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Imagen request failed: %w", err)
+	}
+
+	if len(resp.GeneratedImages) == 0 {
+		return nil, fmt.Errorf("no image in Imagen response")
+	}
+	generated := resp.GeneratedImages[0]
+	if generated.Image == nil || len(generated.Image.ImageBytes) == 0 {
+		if generated.RAIFilteredReason != "" {
+			return nil, fmt.Errorf("Imagen filtered the image: %s", generated.RAIFilteredReason)
+		}
+		return nil, fmt.Errorf("no image data in Imagen response")
+	}
+	logx.Debugf("Imagen image generated successfully")
+
+	attemptNum := opts.AttemptNum
+	if attemptNum <= 0 {
+		attemptNum = 1
+	}
+	imagePath, err := saveGeneratedImage(bytes.NewReader(generated.Image.ImageBytes), -1, "imagen", opts.OutputPath, attemptNum, cleanup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save Imagen image: %w", err)
+	}
+
+	return &MediaInput{Path: imagePath, IsGenerated: true}, nil
+}
+
+// generateLocalSDImage generates an image with a local Stable Diffusion server
+// through the AUTOMATIC1111 txt2img API, which returns base64-encoded PNGs
+func generateLocalSDImage(opts ImageGenOptions, cleanup *fileutil.CleanupManager) (*MediaInput, error) {
+	endpoint := opts.SDEndpoint
+	if endpoint == "" {
+		endpoint = config.DefaultSDEndpoint
+	}
+	steps := opts.SDSteps
+	if steps <= 0 {
+		steps = config.DefaultSDSteps
+	}
+	timeout := opts.SDTimeout
+	if timeout <= 0 {
+		timeout = config.DefaultSDTimeout * time.Second
 	}
 
-	return nil, fmt.Errorf("failed to generate image after %d attempts: %w", maxRetries, lastErr)
-}
+	width, height := opts.AspectRatio.SDDimensions()
+	logx.Infof("Generating image with local Stable Diffusion at %s (%dx%d, %d steps)...", endpoint, width, height, steps)
 
-// generateDALLEImage3 generates an image using DALL-E 3 with retry logic
-func generateDALLEImage3(description, title string, attemptNum int, cleanup *fileutil.CleanupManager) (*MediaInput, error) {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		apiKey = os.Getenv("OPENAI_PERSONAL_API_KEY")
+	seed := opts.Seed
+	if seed == 0 {
+		seed = -1 // A1111 picks a random seed
 	}
-	if apiKey == "" {
-		return nil, fmt.Errorf("OpenAI API key not found in environment")
+	reqBody := map[string]interface{}{
+		"prompt":          opts.Description,
+		"negative_prompt": opts.NegativePrompt,
+		"width":           width,
+		"height":          height,
+		"steps":           steps,
+		"seed":            seed,
+		"batch_size":      1,
+		"n_iter":          1,
+	}
+	if opts.SDSampler != "" {
+		reqBody["sampler_name"] = opts.SDSampler
 	}
 
-	maxRetries := 5
-	prompt := description
-	var lastErr error
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		// Enhance the prompt each attempt; pass isRetry=true on subsequent attempts
-		enhancedPrompt, err := enhanceImagePrompt(prompt, apiKey, attempt > 0)
-		if err != nil {
-			log.Printf("Failed to enhance prompt (attempt %d), using original: %v", attempt+1, err)
-			enhancedPrompt = prompt
-		}
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal local SD request: %w", err)
+	}
 
-		imageURL, err := generateDALLEImage(enhancedPrompt, apiKey)
-		if err == nil {
-			// Download the generated image with attempt number for naming
-			imagePath, dlErr := downloadGeneratedImage(imageURL, title, description, attemptNum, cleanup)
-			if dlErr != nil {
-				return nil, fmt.Errorf("failed to download generated image: %w", dlErr)
-			}
-			return &MediaInput{Path: imagePath, IsGenerated: true}, nil
+	url := strings.TrimRight(endpoint, "/") + "/sdapi/v1/txt2img"
+	req, err := http.NewRequestWithContext(opts.context(), "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create local SD request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := httpx.Client(timeout)
+	resp, err := client.Do(req)
+	if err != nil {
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			return nil, fmt.Errorf("could not connect to %s: is your local SD server running (with --api)?", endpoint)
 		}
+		return nil, fmt.Errorf("local SD request failed (timeout %s): %w", timeout, err)
+	}
+	defer resp.Body.Close()
 
-		lastErr = err
-		if strings.Contains(err.Error(), "content_policy_violation") {
-			log.Printf("DALL-E content policy violation on attempt %d/%d. Retrying with a safer prompt...", attempt+1, maxRetries)
-			// On retry, modify the prompt slightly to encourage safer content
-			prompt = prompt + " (safe, descriptive, no sensitive content)"
-			continue
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("local SD server at %s has no txt2img API (start it with --api)", endpoint)
 		}
+		return nil, fmt.Errorf("local SD error (status %d): %s", resp.StatusCode, string(errBody))
+	}
 
-		// Non-policy errors: do not retry
-		break
+	var result struct {
+		Images []string `json:"images"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode local SD response: %w", err)
+	}
+	if len(result.Images) == 0 {
+		return nil, fmt.Errorf("no image in local SD response")
 	}
 
-	return nil, fmt.Errorf("failed to generate image after %d attempts: %w", maxRetries, lastErr)
+	// Some servers prefix the payload with a data URI header
+	encoded := result.Images[0]
+	if i := strings.Index(encoded, ","); i >= 0 && strings.HasPrefix(encoded, "data:") {
+		encoded = encoded[i+1:]
+	}
+	imageData, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode local SD image: %w", err)
+	}
+	logx.Debugf("Local SD image generated successfully")
+
+	attemptNum := opts.AttemptNum
+	if attemptNum <= 0 {
+		attemptNum = 1
+	}
+	imagePath, err := saveGeneratedImage(bytes.NewReader(imageData), -1, "localsd", opts.OutputPath, attemptNum, cleanup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save local SD image: %w", err)
+	}
+
+	return &MediaInput{Path: imagePath, IsGenerated: true}, nil
 }
 
 // generateIdeogramImage generates an image using Ideogram v3 API (legacy wrapper)
@@ -488,7 +2103,7 @@ func generateIdeogramImageWithOpts(opts ImageGenOptions, cleanup *fileutil.Clean
 	}
 	if opts.StylePreset != "" && styleType != "" && styleType != "AUTO" && styleType != "GENERAL" {
 		if userProvidedStyleType {
-			log.Printf("Note: style_preset requires AUTO or GENERAL style_type, overriding %s -> GENERAL", styleType)
+			logx.Infof("Note: style_preset requires AUTO or GENERAL style_type, overriding %s -> GENERAL", styleType)
 		}
 		styleType = "GENERAL"
 	}
@@ -501,32 +2116,48 @@ func generateIdeogramImageWithOpts(opts ImageGenOptions, cleanup *fileutil.Clean
 	if opts.StylePreset != "" {
 		styleInfo += fmt.Sprintf(", style_preset: %s", opts.StylePreset)
 	}
-	log.Printf("Generating image with Ideogram v3 (aspect ratio: %s%s)...", aspectRatioStr, styleInfo)
 
 	// Create the request
 	reqBody := IdeogramRequest{
 		Prompt:         opts.Description,
+		NegativePrompt: opts.NegativePrompt,
 		AspectRatio:    aspectRatioStr,
 		RenderingSpeed: "TURBO",
 		StyleType:      styleType,
 		StylePreset:    opts.StylePreset,
 	}
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal Ideogram request: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", "https://api.ideogram.ai/v1/ideogram-v3/generate", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Ideogram request: %w", err)
+	// Requests are rebuilt for each rate-limit retry since bodies are consumed
+	var newRequest func() (*http.Request, error)
+	if opts.ReferenceImage != "" {
+		logx.Infof("Remixing reference image with Ideogram v3 (image weight: %d, aspect ratio: %s%s)...", opts.imageWeight(), aspectRatioStr, styleInfo)
+		newRequest = func() (*http.Request, error) {
+			req, err := newIdeogramRemixRequest(reqBody, opts.ReferenceImage, opts.imageWeight())
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Api-Key", apiKey)
+			return req, nil
+		}
+	} else {
+		logx.Infof("Generating image with Ideogram v3 (aspect ratio: %s%s)...", aspectRatioStr, styleInfo)
+		jsonData, err := json.Marshal(reqBody)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal Ideogram request: %w", err)
+		}
+		newRequest = func() (*http.Request, error) {
+			req, err := http.NewRequest("POST", "https://api.ideogram.ai/v1/ideogram-v3/generate", bytes.NewReader(jsonData))
+			if err != nil {
+				return nil, fmt.Errorf("failed to create Ideogram request: %w", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Api-Key", apiKey)
+			return req, nil
+		}
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Api-Key", apiKey)
-
-	client := &http.Client{Timeout: 120 * time.Second}
-	resp, err := client.Do(req)
+	client := httpx.Client(120 * time.Second)
+	resp, err := doWithRateLimit(opts.context(), client, "Ideogram", newRequest)
 	if err != nil {
 		return nil, fmt.Errorf("Ideogram API request failed: %w", err)
 	}
@@ -551,14 +2182,14 @@ func generateIdeogramImageWithOpts(opts ImageGenOptions, cleanup *fileutil.Clean
 	}
 
 	imageURL := ideogramResp.Data[0].URL
-	log.Printf("Ideogram image generated successfully")
+	logx.Debugf("Ideogram image generated successfully")
 
 	// Download the generated image with attempt number for naming
 	attemptNum := opts.AttemptNum
 	if attemptNum <= 0 {
 		attemptNum = 1
 	}
-	imagePath, err := downloadGeneratedImage(imageURL, opts.Title, opts.Description, attemptNum, cleanup)
+	imagePath, err := downloadImage(imageURL, "ideogram", opts.OutputPath, attemptNum, cleanup)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download Ideogram image: %w", err)
 	}
@@ -566,7 +2197,348 @@ func generateIdeogramImageWithOpts(opts ImageGenOptions, cleanup *fileutil.Clean
 	return &MediaInput{Path: imagePath, IsGenerated: true}, nil
 }
 
-func enhanceImagePrompt(description, apiKey string, isRetry bool) (string, error) {
+// doWithRateLimit sends the request built by newRequest, waiting and resending
+// while the server answers 429 or 503. The wait honors Retry-After (seconds
+// or HTTP date) and otherwise backs off exponentially with jitter. Cancelling
+// ctx aborts the wait with context.Canceled. After rateLimitMaxWaits the last
+// response is returned for the caller to report.
+func doWithRateLimit(ctx context.Context, client *http.Client, label string, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	var resp *http.Response
+	_, err := retry.Do(ctx, retry.Policy{
+		Attempts: rateLimitMaxWaits + 1,
+		Retryable: func(err error) bool {
+			return errors.Is(err, errRateLimited)
+		},
+		Delay: func(wait int, err error) time.Duration {
+			return rateLimitDelay(wait, resp.Header.Get("Retry-After"), time.Now())
+		},
+		OnRetry: func(wait int, delay time.Duration, err error) {
+			resp.Body.Close()
+			logx.Infof("%s rate limited (HTTP %d), waiting %s before retrying (%d/%d)...", label, resp.StatusCode, delay.Round(time.Millisecond), wait, rateLimitMaxWaits)
+		},
+	}, func() error {
+		req, err := newRequest()
+		if err != nil {
+			return err
+		}
+		resp, err = client.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			return errRateLimited
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errRateLimited):
+		return resp, nil // Out of waits; the caller reports the response
+	case err != nil && ctx.Err() != nil:
+		return nil, context.Canceled
+	case err != nil:
+		return nil, err
+	}
+	return resp, nil
+}
+
+// errRateLimited marks a 429 or 503 response for doWithRateLimit to retry
+var errRateLimited = errors.New("rate limited")
+
+// rateLimitDelay returns the wait before resending a rate-limited request.
+// Retry-After wins when present; otherwise exponential backoff with jitter.
+func rateLimitDelay(wait int, retryAfter string, now time.Time) time.Duration {
+	retryAfter = strings.TrimSpace(retryAfter)
+	if secs, err := strconv.Atoi(retryAfter); err == nil && secs > 0 {
+		return min(time.Duration(secs)*time.Second, rateLimitMaxDelay)
+	}
+	if t, err := http.ParseTime(retryAfter); err == nil && t.After(now) {
+		return min(t.Sub(now), rateLimitMaxDelay)
+	}
+	return retry.Backoff(wait, rateLimitBaseDelay, rateLimitMaxDelay, true)
+}
+
+// newIdeogramRemixRequest builds a multipart request for Ideogram's v3 remix
+// endpoint, uploading the reference image from disk alongside the prompt
+func newIdeogramRemixRequest(reqBody IdeogramRequest, referencePath string, imageWeight int) (*http.Request, error) {
+	imageFile, err := os.Open(referencePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open reference image: %w", err)
+	}
+	defer imageFile.Close()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	fields := [][2]string{
+		{"prompt", reqBody.Prompt},
+		{"image_weight", strconv.Itoa(imageWeight)},
+		{"aspect_ratio", reqBody.AspectRatio},
+		{"rendering_speed", reqBody.RenderingSpeed},
+	}
+	if reqBody.StyleType != "" {
+		fields = append(fields, [2]string{"style_type", reqBody.StyleType})
+	}
+	if reqBody.StylePreset != "" {
+		fields = append(fields, [2]string{"style_preset", reqBody.StylePreset})
+	}
+	if reqBody.NegativePrompt != "" {
+		fields = append(fields, [2]string{"negative_prompt", reqBody.NegativePrompt})
+	}
+	for _, field := range fields {
+		if err := form.WriteField(field[0], field[1]); err != nil {
+			return nil, fmt.Errorf("failed to build Ideogram remix request: %w", err)
+		}
+	}
+	part, err := form.CreateFormFile("image", filepath.Base(referencePath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build Ideogram remix request: %w", err)
+	}
+	if _, err := io.Copy(part, imageFile); err != nil {
+		return nil, fmt.Errorf("failed to build Ideogram remix request: %w", err)
+	}
+	if err := form.Close(); err != nil {
+		return nil, fmt.Errorf("failed to build Ideogram remix request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", ideogramRemixURL, &body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Ideogram remix request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req, nil
+}
+
+// imageWeight returns the Ideogram remix image weight, defaulting when unset
+func (opts ImageGenOptions) imageWeight() int {
+	if opts.ImageWeight <= 0 {
+		return config.DefaultImageWeight
+	}
+	return opts.ImageWeight
+}
+
+// describeReferenceImage is a variable so tests can run
+// applyReferenceDescription without Gemini
+var describeReferenceImage = genai.DescribeReferenceImage
+
+// applyReferenceDescription appends a Gemini description of the reference
+// image to the prompt for providers without a remix endpoint. A failed
+// description is logged and the prompt is left unchanged.
+func applyReferenceDescription(opts ImageGenOptions) ImageGenOptions {
+	logx.Infof("Provider %s has no remix support; describing reference image with Gemini: %s", opts.Provider, opts.ReferenceImage)
+	desc, err := describeReferenceImage(opts.ReferenceImage, genai.ClientOptions{APIKey: opts.GeminiKey})
+	if err != nil {
+		logx.Warnf("Could not describe reference image, generating without it: %v", err)
+		return opts
+	}
+	opts.Description = fmt.Sprintf("%s\n\nMatch the visual language of this reference image: %s", opts.Description, desc)
+	return opts
+}
+
+// generateStabilityImage generates an image with Stability AI's Stable Diffusion 3
+// endpoint, which returns the PNG directly instead of a URL
+func generateStabilityImage(opts ImageGenOptions, cleanup *fileutil.CleanupManager) (*MediaInput, error) {
+	apiKey := os.Getenv("STABILITY_API_KEY")
+	if apiKey == "" {
+		return nil, fmt.Errorf("STABILITY_API_KEY not found in environment")
+	}
+
+	aspectRatioStr := opts.AspectRatio.StabilityAspectRatio()
+	logx.Infof("Generating image with Stability SD3 (aspect ratio: %s)...", aspectRatioStr)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	fields := [][2]string{
+		{"prompt", opts.Description},
+		{"aspect_ratio", aspectRatioStr},
+		{"output_format", "png"},
+	}
+	if opts.NegativePrompt != "" {
+		fields = append(fields, [2]string{"negative_prompt", opts.NegativePrompt})
+	}
+	if opts.Seed != 0 {
+		fields = append(fields, [2]string{"seed", strconv.FormatInt(opts.Seed, 10)})
+	}
+	for _, field := range fields {
+		if err := form.WriteField(field[0], field[1]); err != nil {
+			return nil, fmt.Errorf("failed to build Stability request: %w", err)
+		}
+	}
+	if err := form.Close(); err != nil {
+		return nil, fmt.Errorf("failed to build Stability request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(opts.context(), "POST", stabilityURL, &body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Stability request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	req.Header.Set("Accept", "image/*")
+
+	client := httpx.Client(120 * time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Stability API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Stability API error (status %d): %s", resp.StatusCode, string(errBody))
+	}
+	logx.Debugf("Stability image generated successfully")
+
+	attemptNum := opts.AttemptNum
+	if attemptNum <= 0 {
+		attemptNum = 1
+	}
+	imagePath, err := saveGeneratedImage(resp.Body, resp.ContentLength, "stability", opts.OutputPath, attemptNum, cleanup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save Stability image: %w", err)
+	}
+
+	return &MediaInput{Path: imagePath, IsGenerated: true}, nil
+}
+
+// generateReplicateImage runs a text-to-image model on Replicate (Flux by
+// default), polls the prediction until it finishes, and downloads the first
+// output image
+func generateReplicateImage(opts ImageGenOptions, cleanup *fileutil.CleanupManager) (*MediaInput, error) {
+	token := os.Getenv("REPLICATE_API_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("REPLICATE_API_TOKEN not found in environment")
+	}
+
+	model := opts.Model
+	if model == "" {
+		model = config.DefaultReplicateModel
+	}
+
+	input := map[string]interface{}{
+		"prompt":        opts.Description,
+		"aspect_ratio":  string(opts.AspectRatio),
+		"output_format": "png",
+	}
+	if opts.AspectRatio == "" {
+		input["aspect_ratio"] = string(config.AspectRatio16x9)
+	}
+	if opts.Seed != 0 {
+		input["seed"] = opts.Seed
+	}
+	logx.Infof("Generating image with Replicate %s (aspect ratio: %s)...", model, input["aspect_ratio"])
+
+	// "owner/name:version" pins a version; "owner/name" runs the latest one
+	url := fmt.Sprintf("%s/models/%s/predictions", replicateAPIURL, model)
+	reqBody := map[string]interface{}{"input": input}
+	if _, version, ok := strings.Cut(model, ":"); ok {
+		url = replicateAPIURL + "/predictions"
+		reqBody["version"] = version
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Replicate request: %w", err)
+	}
+
+	ctx := opts.context()
+	prediction, err := replicateRequest(ctx, "POST", url, token, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+
+	delay := replicatePollStart
+	deadline := time.Now().Add(replicatePollTimeout)
+	for prediction.Status != "succeeded" {
+		switch prediction.Status {
+		case "failed", "canceled":
+			return nil, fmt.Errorf("Replicate prediction %s %s: %v", prediction.ID, prediction.Status, prediction.Error)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Replicate prediction %s still %s after %s", prediction.ID, prediction.Status, replicatePollTimeout)
+		}
+		if prediction.URLs.Get == "" {
+			return nil, fmt.Errorf("Replicate prediction %s has no status URL", prediction.ID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, replicatePollMax)
+
+		prediction, err = replicateRequest(ctx, "GET", prediction.URLs.Get, token, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	imageURL, err := replicateOutputURL(prediction.Output)
+	if err != nil {
+		return nil, fmt.Errorf("Replicate prediction %s: %w", prediction.ID, err)
+	}
+	logx.Debugf("Replicate image generated successfully")
+
+	attemptNum := opts.AttemptNum
+	if attemptNum <= 0 {
+		attemptNum = 1
+	}
+	imagePath, err := downloadImage(imageURL, "replicate", opts.OutputPath, attemptNum, cleanup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download Replicate image: %w", err)
+	}
+
+	return &MediaInput{Path: imagePath, IsGenerated: true}, nil
+}
+
+// replicateRequest sends an authenticated Replicate API request and decodes the prediction
+func replicateRequest(ctx context.Context, method, url, token string, body io.Reader) (*replicatePrediction, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Replicate request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := httpx.Client(60 * time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Replicate API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Replicate response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("Replicate API error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+
+	var prediction replicatePrediction
+	if err := json.Unmarshal(respBody, &prediction); err != nil {
+		return nil, fmt.Errorf("failed to parse Replicate response: %w", err)
+	}
+	return &prediction, nil
+}
+
+// replicateOutputURL returns the first image URL of a prediction's output,
+// which models return either as a list of URLs or a single URL
+func replicateOutputURL(output json.RawMessage) (string, error) {
+	var urls []string
+	if err := json.Unmarshal(output, &urls); err == nil && len(urls) > 0 && urls[0] != "" {
+		return urls[0], nil
+	}
+	var url string
+	if err := json.Unmarshal(output, &url); err == nil && url != "" {
+		return url, nil
+	}
+	return "", fmt.Errorf("no image URL in output: %s", string(output))
+}
+
+func enhanceImagePrompt(ctx context.Context, description, apiKey string, isRetry bool) (string, error) {
 	systemContent := "You are a helpful assistant that creates high-quality, safe image prompts for DALL-E based on user descriptions."
 	if len(description) < 15 {
 		systemContent += " Always include visual elements that represent music or audio in your prompts, even if not explicitly mentioned in the description."
@@ -593,16 +2565,18 @@ func enhanceImagePrompt(description, apiKey string, isRetry bool) (string, error
 		return "", fmt.Errorf("failed to marshal chat request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("failed to create chat request: %w", err)
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create chat request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
 	}
 
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	client := httpx.Client(30 * time.Second)
+	resp, err := doWithRateLimit(ctx, client, "OpenAI chat", newRequest)
 	if err != nil {
 		return "", fmt.Errorf("failed to make chat request: %w", err)
 	}
@@ -625,7 +2599,7 @@ func enhanceImagePrompt(description, apiKey string, isRetry bool) (string, error
 	return chatResp.Choices[0].Message.Content, nil
 }
 
-func generateDALLEImage(prompt, apiKey string) (string, error) {
+func generateDALLEImage(ctx context.Context, prompt, apiKey string) (string, error) {
 	request := OpenAIImageRequest{
 		Model:   "dall-e-3",
 		Prompt:  prompt,
@@ -639,16 +2613,18 @@ func generateDALLEImage(prompt, apiKey string) (string, error) {
 		return "", fmt.Errorf("failed to marshal image request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/images/generations", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("failed to create image request: %w", err)
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequest("POST", openAIImagesURL, bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create image request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
 	}
 
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 60 * time.Second} // DALL-E can take longer
-	resp, err := client.Do(req)
+	client := httpx.Client(60 * time.Second) // DALL-E can take longer
+	resp, err := doWithRateLimit(ctx, client, "DALL-E", newRequest)
 	if err != nil {
 		return "", fmt.Errorf("failed to make image request: %w", err)
 	}
@@ -671,58 +2647,72 @@ func generateDALLEImage(prompt, apiKey string) (string, error) {
 	return imageResp.Data[0].URL, nil
 }
 
-func downloadGeneratedImage(imageURL, title, description string, attemptNum int, cleanup *fileutil.CleanupManager) (string, error) {
-	resp, err := http.Get(imageURL)
+// downloadImage fetches an image URL into temp_assets (see saveGeneratedImage)
+func downloadImage(imageURL, prefix, outputPath string, attemptNum int, cleanup *fileutil.CleanupManager) (string, error) {
+	req, err := http.NewRequest("GET", imageURL, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to download generated image: %w", err)
+		return "", fmt.Errorf("failed to download image: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to download image: HTTP %d", resp.StatusCode)
+	var imagePath string
+	err = fileutil.Download(req, nil, func(resp *http.Response, body io.Reader) error {
+		path, err := saveGeneratedImage(body, resp.ContentLength, prefix, outputPath, attemptNum, cleanup)
+		imagePath = path
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to download image: %w", err)
 	}
+	return imagePath, nil
+}
 
-	// Create filename with epoch timestamp to avoid collisions across parallel runs
-	// Format: ideogram_<epoch>_0001.png, ideogram_<epoch>_0002.png, etc.
-	epoch := time.Now().UnixMilli()
-	filename := fmt.Sprintf("ideogram_%d_%04d.png", epoch, attemptNum)
-	imagePath := filepath.Join(config.TempAssetsFolder, filename)
+// imageSeq numbers images saved by this process so concurrent slots with the
+// same provider and attempt get distinct filenames
+var imageSeq atomic.Int64
 
-	file, err := os.Create(imagePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to create image file: %w", err)
-	}
-	defer file.Close()
+// saveGeneratedImage writes image data to temp_assets and registers it for
+// cleanup. Names are scoped to the planned output and this run via
+// fileutil.TempAssetPath, so parallel mmmeld runs in one directory never share
+// files: <output-hash>_<run-nonce>_<provider>_<seq>_<attempt>.png. size is the
+// expected byte count (-1 if unknown); payloads that are empty, truncated, or
+// not an image are rejected with fileutil.ErrInvalidImage.
+func saveGeneratedImage(r io.Reader, size int64, prefix, outputPath string, attemptNum int, cleanup *fileutil.CleanupManager) (string, error) {
+	filename := fmt.Sprintf("%s_%03d_%04d.png", prefix, imageSeq.Add(1), attemptNum)
+	imagePath := fileutil.TempAssetPath(config.TempAssetsFolder, outputPath, filename)
 
-	_, err = io.Copy(file, resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to save image: %w", err)
+	if err := fileutil.SaveImage(r, size, imagePath); err != nil {
+		return "", err
 	}
 
 	cleanup.Add(imagePath)
-	log.Printf("Downloaded generated image: %s", imagePath)
+	logx.Debugf("Downloaded generated image: %s", imagePath)
 
 	return imagePath, nil
 }
 
 // IsVideoFile checks if a file is a video based on its extension
 func IsVideoFile(filePath string) bool {
-	ext := strings.ToLower(filepath.Ext(filePath))
-	videoExts := []string{".mp4", ".avi", ".mov", ".mkv", ".webm", ".wmv", ".flv", ".m4v"}
+	return ffmpeg.IsVideoFile(filePath)
+}
 
-	for _, videoExt := range videoExts {
-		if ext == videoExt {
-			return true
-		}
+// IsVideoURL reports whether an --image entry is a video to download with
+// yt-dlp. --force-ytdlp widens that to any http(s) URL except one whose path
+// names an image file, which is still downloaded as an image.
+func IsVideoURL(entry string, forceYtDlp bool) bool {
+	if !fileutil.IsYtDlpURL(entry, forceYtDlp) {
+		return false
 	}
-
-	return false
+	if fileutil.IsYtDlpURL(entry, false) {
+		return true
+	}
+	u, err := url.Parse(entry)
+	return err != nil || !IsImageFile(u.Path)
 }
 
 // IsImageFile checks if a file is an image based on its extension
 func IsImageFile(filePath string) bool {
 	ext := strings.ToLower(filepath.Ext(filePath))
-	imageExts := []string{".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp", ".tiff"}
+	imageExts := []string{".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp", ".tiff", ".svg", ".heic", ".heif"}
 
 	for _, imageExt := range imageExts {
 		if ext == imageExt {
@@ -744,26 +2734,44 @@ func GetMediaType(filePath string) string {
 	return "unknown"
 }
 
-// analyzeAudioForPrompt uses Gemini to analyze an audio file and generate an image prompt
-func analyzeAudioForPrompt(audioPath, title, notes, caption, subcaption, style string) (string, error) {
+// analyzeAudioForPrompt uses Gemini to analyze an audio file and generate
+// image prompts: one per scene, all derived from a single audio brief. Fewer
+// prompts than scenes may be returned if the fallback path cannot vary them.
+// The brief is nil when Gemini wrote the prompts without audio analysis.
+func analyzeAudioForPrompt(cfg *config.Config, audioPath, title, notes string, scenes int, cleanup *fileutil.CleanupManager) ([]string, *genai.AudioBrief, error) {
 	ctx := context.Background()
+	caption, subcaption, style := cfg.ImageCaption, cfg.ImageSubcaption, cfg.ImageStyle
+
+	var window *probe.AudioWindow
+	if cfg.AnalyzeWindow != "" {
+		var err error
+		if window, err = probe.ParseAudioWindow(cfg.AnalyzeWindow); err != nil {
+			return nil, nil, err
+		}
+		logx.Debugf("Gemini analysis - Window: %s", window)
+	}
 
-	log.Printf("Gemini analysis - Title: %q", title)
-	log.Printf("Gemini analysis - Notes: %q", notes)
+	logx.Debugf("Gemini analysis - Title: %q", title)
+	logx.Debugf("Gemini analysis - Notes: %q", notes)
 	if caption != "" {
-		log.Printf("Gemini analysis - Caption: %q", caption)
+		logx.Debugf("Gemini analysis - Caption: %q", caption)
 	}
 	if subcaption != "" {
-		log.Printf("Gemini analysis - Subcaption: %q", subcaption)
+		logx.Debugf("Gemini analysis - Subcaption: %q", subcaption)
 	}
 	if style != "" && style != "auto" {
-		log.Printf("Gemini analysis - Style: %q", style)
+		logx.Debugf("Gemini analysis - Style: %q", style)
 	}
 
-	client, err := genai.NewClient(ctx)
+	client, err := genai.NewClientWithConfig(ctx, genai.ClientOptions{APIKey: cfg.GeminiKey})
 	if err != nil {
-		return "", fmt.Errorf("failed to create Gemini client: %w", err)
+		return nil, nil, fmt.Errorf("failed to create Gemini client: %w", err)
 	}
+	defer func() {
+		if err := client.Close(); err != nil {
+			logx.Warnf("%v", err)
+		}
+	}()
 
 	// Convert style string to StylePreference
 	stylePref := genai.StyleAuto
@@ -784,15 +2792,52 @@ func analyzeAudioForPrompt(audioPath, title, notes, caption, subcaption, style s
 		Caption:         caption,
 		Subcaption:      subcaption,
 		StylePreference: stylePref,
-		Quiet:           false,
+		Scenes:          scenes,
+		Reviewer:        cfg.Reviewer,
+		ReviewModel:     cfg.ReviewModel,
+		Retries:         cfg.GeminiRetries,
+		NoFallback:      cfg.NoOpenAIFallback,
+		Avoid:           genai.ParseAvoidList(cfg.ImageAvoid),
+		Language:        cfg.Language,
+		AnalyzeWindow:   window,
+		Cleanup:         cleanup,
+		TempPath:        fileutil.TempAssetPath(config.TempAssetsFolder, cfg.Output, "analysis_audio.m4a"),
+		NoCache:         cfg.NoPromptCache,
+	}
+	briefTemp, promptTemp := float32(cfg.BriefTemperature), float32(cfg.PromptTemperature)
+	opts.BriefTemperature, opts.PromptTemperature = &briefTemp, &promptTemp
+
+	result, err := client.GenerateImagePrompt(ctx, audioPath, opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate prompt from audio: %w", err)
+	}
+	if result.Fallback {
+		logx.Warnf("Image prompt was written without audio analysis (Gemini unavailable); expect a less faithful image")
+	}
+	if result.Brief != nil {
+		logx.Debugf("Audio brief - Palette: %s", strings.Join(result.Brief.PaletteColors, ", "))
+		logx.Debugf("Audio brief - Metaphor: %s", result.Brief.CentralMetaphor)
+	}
+	if result.Usage != nil {
+		logx.Debugf("Prompt generation %s", result.Usage.Summary())
 	}
 
-	result, err := client.GenerateImagePrompt(audioPath, opts)
-	if err != nil {
-		return "", fmt.Errorf("failed to generate prompt from audio: %w", err)
+	if len(result.Prompts) > 0 {
+		return result.Prompts, result.Brief, nil
 	}
+	return []string{result.Prompt}, result.Brief, nil
+}
 
-	return result.Prompt, nil
+// negativePrompt appends avoid terms to a user negative prompt
+func negativePrompt(base string, avoid []string) string {
+	if len(avoid) == 0 {
+		return base
+	}
+	terms := strings.Join(avoid, ", ")
+	if base == "" {
+		return terms
+	}
+	return base + ", " + terms
 }
 
 // truncateString truncates a string to the specified length, adding "..." if truncated
@@ -0,0 +1,103 @@
+// Package logx is a small leveled front end for the standard logger. The
+// default level shows stage progress and warnings; command lines, raw ffmpeg
+// output, and other diagnostics are debug messages shown with --verbose.
+package logx
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// Level orders messages by importance; a message is shown when its level is
+// at or below the current one
+type Level int32
+
+const (
+	LevelError Level = iota
+	LevelWarn
+	LevelInfo
+	LevelDebug
+)
+
+// Levels lists the accepted --log-level names, least verbose first
+var Levels = []string{"error", "warn", "info", "debug"}
+
+func (l Level) String() string {
+	if l >= LevelError && int(l) < len(Levels) {
+		return Levels[l]
+	}
+	return fmt.Sprintf("Level(%d)", int32(l))
+}
+
+// ParseLevel parses a --log-level name. "warning" and "verbose" are accepted
+// as aliases of warn and debug.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "error":
+		return LevelError, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "info", "":
+		return LevelInfo, nil
+	case "debug", "verbose":
+		return LevelDebug, nil
+	}
+	return LevelInfo, fmt.Errorf("invalid log level %q (valid: %s)", s, strings.Join(Levels, ", "))
+}
+
+var level atomic.Int32
+
+func init() {
+	level.Store(int32(LevelInfo))
+}
+
+// SetLevel changes which messages are shown. At LevelDebug the standard
+// logger also prefixes messages with their file and line.
+func SetLevel(l Level) {
+	level.Store(int32(l))
+	flags := log.LstdFlags
+	if l >= LevelDebug {
+		flags |= log.Lshortfile
+	}
+	log.SetFlags(flags)
+}
+
+// CurrentLevel returns the level set by SetLevel
+func CurrentLevel() Level {
+	return Level(level.Load())
+}
+
+// Enabled reports whether messages at l are shown
+func Enabled(l Level) bool {
+	return l <= CurrentLevel()
+}
+
+// output logs at l, attributing the message to the caller of the exported
+// function
+func output(l Level, format string, args ...any) {
+	if Enabled(l) {
+		log.Output(3, fmt.Sprintf(format, args...))
+	}
+}
+
+// Debugf logs diagnostics such as command lines; shown with --verbose
+func Debugf(format string, args ...any) {
+	output(LevelDebug, format, args...)
+}
+
+// Infof logs stage progress
+func Infof(format string, args ...any) {
+	output(LevelInfo, format, args...)
+}
+
+// Warnf logs a problem the run recovers from, prefixed with "Warning: "
+func Warnf(format string, args ...any) {
+	output(LevelWarn, "Warning: "+format, args...)
+}
+
+// Errorf logs a failure that is shown even with --quiet
+func Errorf(format string, args ...any) {
+	output(LevelError, "Error: "+format, args...)
+}
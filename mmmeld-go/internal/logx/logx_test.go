@@ -0,0 +1,76 @@
+package logx
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Level
+		wantErr  bool
+	}{
+		{"error", LevelError, false},
+		{"warn", LevelWarn, false},
+		{"Warning", LevelWarn, false},
+		{"info", LevelInfo, false},
+		{"", LevelInfo, false},
+		{"debug", LevelDebug, false},
+		{"verbose", LevelDebug, false},
+		{"loud", LevelInfo, true},
+	}
+
+	for _, test := range tests {
+		result, err := ParseLevel(test.input)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseLevel(%q) error = %v, expected error: %v", test.input, err, test.wantErr)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("ParseLevel(%q) = %v, expected %v", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestLevelFiltering(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+		SetLevel(LevelInfo)
+	})
+
+	tests := []struct {
+		level    Level
+		expected []string
+	}{
+		{LevelError, []string{"Error: e"}},
+		{LevelWarn, []string{"Warning: w", "Error: e"}},
+		{LevelInfo, []string{"i", "Warning: w", "Error: e"}},
+		{LevelDebug, []string{"d", "i", "Warning: w", "Error: e"}},
+	}
+
+	for _, test := range tests {
+		buf.Reset()
+		SetLevel(test.level)
+		Debugf("d")
+		Infof("i")
+		Warnf("w")
+		Errorf("e")
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != len(test.expected) {
+			t.Errorf("At %v logged %q, expected %d lines", test.level, lines, len(test.expected))
+			continue
+		}
+		for i, line := range lines {
+			if !strings.HasSuffix(line, " "+test.expected[i]) {
+				t.Errorf("At %v line %d = %q, expected it to end with %q", test.level, i, line, test.expected[i])
+			}
+		}
+	}
+}
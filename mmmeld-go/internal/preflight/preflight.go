@@ -0,0 +1,279 @@
+// Package preflight checks, before a run does any work, that the API keys
+// and external tools its configuration will need are available, so a missing
+// key is reported up front instead of after TTS and downloads have been paid
+// for.
+package preflight
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"mmmeld/internal/config"
+	"mmmeld/internal/fileutil"
+	"mmmeld/internal/image"
+)
+
+// minFFmpegMajor is the oldest ffmpeg/ffprobe release mmmeld's filter graphs
+// are used with
+const minFFmpegMajor = 4
+
+// ytDlpMaxAge is how old a yt-dlp release can be before it is flagged; site
+// changes routinely break older extractors
+const ytDlpMaxAge = 365 * 24 * time.Hour
+
+// Stubbed in tests
+var (
+	lookPath    = exec.LookPath
+	toolVersion = func(path string, arg string) (string, error) {
+		out, err := exec.Command(path, arg).Output()
+		return string(out), err
+	}
+	now = time.Now
+)
+
+// Options tunes Check
+type Options struct {
+	// Completed lists stages ("audio", "images", "bg_music") a previous run
+	// finished, which --resume may reuse; their keys and tools are not
+	// required. A stage that turns out to need redoing fails as usual.
+	Completed map[string]bool
+}
+
+// Report is the outcome of Check
+type Report struct {
+	Problems []string // Each one would stop the run
+	Warnings []string // Worth knowing, but not fatal
+}
+
+// Err returns nil when nothing would stop the run, or an error listing every
+// problem
+func (r *Report) Err() error {
+	if len(r.Problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("preflight found %d problem(s):\n  - %s\nfix them or pass --skip-preflight", len(r.Problems), strings.Join(r.Problems, "\n  - "))
+}
+
+// Check reports every API key and tool cfg's run will need but cannot find
+func Check(cfg *config.Config, opts Options) *Report {
+	r := &Report{}
+	for _, need := range RequiredKeys(cfg, opts) {
+		switch {
+		case need.Value != "":
+		case need.Flag != "":
+			r.Problems = append(r.Problems, fmt.Sprintf("%s is not set (needed for %s); set it or pass %s", need.Env, need.Reason, need.Flag))
+		default:
+			r.Problems = append(r.Problems, fmt.Sprintf("%s is not set (needed for %s)", need.Env, need.Reason))
+		}
+	}
+
+	r.checkFFmpeg("ffmpeg")
+	r.checkFFmpeg("ffprobe")
+	if needsYtDlp(cfg, opts) {
+		r.checkYtDlp(cfg.YtDlpPath)
+	}
+
+	if cfg.Audio == "generate" && cfg.TTSProvider == config.ProviderLocal && !opts.Completed["audio"] {
+		if fields := strings.Fields(cfg.TTSCommand); len(fields) == 0 {
+			r.Problems = append(r.Problems, "--tts-command (or MMMELD_TTS_COMMAND) is not set (needed for --tts-provider local)")
+		} else if _, err := lookPath(fields[0]); err != nil {
+			r.Problems = append(r.Problems, fmt.Sprintf("%s from --tts-command not found on PATH", fields[0]))
+		}
+	}
+	return r
+}
+
+// KeyNeed is an API key a run will use
+type KeyNeed struct {
+	Env    string // Environment variable
+	Flag   string // Flag that sets it instead, if any
+	Reason string // What needs it
+	Value  string // The key as configured (empty = missing)
+}
+
+// RequiredKeys lists the API keys cfg's run will use, with their configured
+// values
+func RequiredKeys(cfg *config.Config, opts Options) []KeyNeed {
+	var needs []KeyNeed
+
+	if cfg.Audio == "generate" && !opts.Completed["audio"] {
+		reason := "--tts-provider " + string(cfg.TTSProvider)
+		switch cfg.TTSProvider {
+		case config.ProviderElevenLabs:
+			needs = append(needs, KeyNeed{"ELEVENLABS_API_KEY", "--elevenlabs-key", reason, cfg.ElevenLabsKey})
+		case config.ProviderOpenAI:
+			needs = append(needs, KeyNeed{"OPENAI_API_KEY", "--openai-key", reason, cfg.OpenAIKey})
+		case config.ProviderDeepgram:
+			needs = append(needs, KeyNeed{"DEEPGRAM_API_KEY", "--deepgram-key", reason, cfg.DeepgramKey})
+		case config.ProviderAzure:
+			needs = append(needs,
+				KeyNeed{"AZURE_SPEECH_KEY", "--azure-speech-key", reason, cfg.AzureKey},
+				KeyNeed{"AZURE_SPEECH_REGION", "--azure-speech-region", reason, cfg.AzureRegion})
+		}
+	}
+
+	if !opts.Completed["images"] {
+		if cfg.AnalyzeAudio && cfg.Audio != "" {
+			needs = append(needs, KeyNeed{"GEMINI_API_KEY", "--gemini-key", "--analyze-audio", cfg.GeminiKey})
+		}
+		if generatesImages(cfg) {
+			reason := "--image-provider " + string(cfg.ImageProvider)
+			switch cfg.ImageProvider.KeyEnv() {
+			case "IDEOGRAM_API_KEY":
+				needs = append(needs, KeyNeed{"IDEOGRAM_API_KEY", "--ideogram-key", reason, cfg.IdeogramKey})
+			case "OPENAI_API_KEY":
+				needs = append(needs, KeyNeed{"OPENAI_API_KEY", "--openai-key", reason, cfg.OpenAIKey})
+			case "GEMINI_API_KEY":
+				needs = append(needs, KeyNeed{"GEMINI_API_KEY", "--gemini-key", reason, cfg.GeminiKey})
+			case "STABILITY_API_KEY":
+				needs = append(needs, KeyNeed{"STABILITY_API_KEY", "", reason, cfg.StabilityKey})
+			case "REPLICATE_API_TOKEN":
+				needs = append(needs, KeyNeed{"REPLICATE_API_TOKEN", "", reason, cfg.ReplicateKey})
+			}
+			if cfg.Upscale && cfg.ImageProvider != config.ImageProviderIdeogram {
+				needs = append(needs, KeyNeed{"IDEOGRAM_API_KEY", "--ideogram-key", "--upscale", cfg.IdeogramKey})
+			}
+		}
+	}
+	return mergeNeeds(needs)
+}
+
+// mergeNeeds folds needs for the same environment variable into the first,
+// joining their reasons, so a missing key is reported once
+func mergeNeeds(needs []KeyNeed) []KeyNeed {
+	var merged []KeyNeed
+	index := make(map[string]int)
+	for _, need := range needs {
+		if i, ok := index[need.Env]; ok {
+			merged[i].Reason += " and " + need.Reason
+			continue
+		}
+		index[need.Env] = len(merged)
+		merged = append(merged, need)
+	}
+	return merged
+}
+
+// generatesImages reports whether the run will call the image provider: a
+// "generate" entry, or --autofill without --image
+func generatesImages(cfg *config.Config) bool {
+	if cfg.Image == "" {
+		return cfg.AutoFill
+	}
+	entries, err := image.Entries(cfg.Image)
+	if err != nil {
+		return false // The images stage reports the unreadable list
+	}
+	for _, entry := range entries {
+		if strings.EqualFold(entry, "generate") {
+			return true
+		}
+	}
+	return false
+}
+
+// needsYtDlp reports whether any input still to be fetched is a yt-dlp URL
+func needsYtDlp(cfg *config.Config, opts Options) bool {
+	var inputs []string
+	if !opts.Completed["audio"] {
+		inputs = append(inputs, cfg.Audio)
+	}
+	if !opts.Completed["bg_music"] {
+		inputs = append(inputs, cfg.BGMusic)
+	}
+	for _, input := range inputs {
+		if input != "" && fileutil.IsYtDlpURL(input, cfg.ForceYtDlp) {
+			return true
+		}
+	}
+	if !opts.Completed["images"] {
+		entries, _ := image.Entries(cfg.Image)
+		for _, entry := range entries {
+			if image.IsVideoURL(entry, cfg.ForceYtDlp) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ffmpegVersion matches "ffmpeg version 6.1.1-3ubuntu5" and "ffmpeg version n7.0"
+var ffmpegVersion = regexp.MustCompile(`version n?(\d+)\.(\d+)`)
+
+// checkFFmpeg requires ffmpeg or ffprobe on PATH at minFFmpegMajor or newer.
+// Git builds report no release number and are accepted.
+func (r *Report) checkFFmpeg(name string) {
+	path, err := lookPath(name)
+	if err != nil {
+		r.Problems = append(r.Problems, fmt.Sprintf("%s not found on PATH", name))
+		return
+	}
+	out, err := toolVersion(path, "-version")
+	if err != nil {
+		r.Problems = append(r.Problems, fmt.Sprintf("%s -version failed: %v", name, err))
+		return
+	}
+	if major, ok := parseFFmpegMajor(out); ok && major < minFFmpegMajor {
+		first, _, _ := strings.Cut(out, "\n")
+		r.Problems = append(r.Problems, fmt.Sprintf("%s is too old (%s); %d.0 or newer is needed", name, strings.TrimSpace(first), minFFmpegMajor))
+	}
+}
+
+// parseFFmpegMajor returns the major version from ffmpeg -version output
+func parseFFmpegMajor(out string) (int, bool) {
+	m := ffmpegVersion.FindStringSubmatch(out)
+	if m == nil {
+		return 0, false
+	}
+	major, err := strconv.Atoi(m[1])
+	return major, err == nil
+}
+
+// checkYtDlp requires yt-dlp, found the way downloads find it, and warns
+// when the release is old or only youtube-dl is installed
+func (r *Report) checkYtDlp(configured string) {
+	var path string
+	for _, name := range fileutil.YtDlpNames(configured) {
+		if found, err := lookPath(name); err == nil {
+			path = found
+			break
+		}
+	}
+	if path == "" {
+		r.Problems = append(r.Problems, fileutil.YtDlpNotFound(configured).Error())
+		return
+	}
+	if fileutil.IsYoutubeDL(path) {
+		r.Warnings = append(r.Warnings, "yt-dlp not found, using youtube-dl; it is no longer maintained and fails on many sites, so install yt-dlp if downloads fail")
+		return
+	}
+	out, err := toolVersion(path, "--version")
+	if err != nil {
+		r.Problems = append(r.Problems, fmt.Sprintf("yt-dlp --version failed: %v", err))
+		return
+	}
+	released, err := parseYtDlpVersion(out)
+	if err != nil {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("could not read the yt-dlp version: %v", err))
+		return
+	}
+	if age := now().Sub(released); age > ytDlpMaxAge {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("yt-dlp %s is %d days old; if downloads fail, update it with yt-dlp -U", strings.TrimSpace(out), int(age.Hours()/24)))
+	}
+}
+
+// parseYtDlpVersion reads the release date from a yt-dlp version such as
+// 2024.08.06 or the nightly 2024.08.06.232541
+func parseYtDlpVersion(out string) (time.Time, error) {
+	v := strings.TrimSpace(out)
+	parts := strings.SplitN(v, ".", 4)
+	if len(parts) < 3 {
+		return time.Time{}, errors.New("unexpected version " + strconv.Quote(v))
+	}
+	return time.Parse("2006.01.02", strings.Join(parts[:3], "."))
+}
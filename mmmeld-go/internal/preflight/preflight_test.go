@@ -0,0 +1,192 @@
+package preflight
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"mmmeld/internal/config"
+)
+
+// stubTools makes lookPath find the given tools, each reporting its version
+func stubTools(t *testing.T, versions map[string]string) {
+	t.Helper()
+	origLookPath, origToolVersion := lookPath, toolVersion
+	lookPath = func(name string) (string, error) {
+		if _, ok := versions[name]; ok {
+			return "/usr/bin/" + name, nil
+		}
+		return "", errors.New("not found")
+	}
+	toolVersion = func(path, arg string) (string, error) {
+		return versions[strings.TrimPrefix(path, "/usr/bin/")], nil
+	}
+	now = func() time.Time { return time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() {
+		lookPath, toolVersion, now = origLookPath, origToolVersion, time.Now
+	})
+}
+
+func TestRequiredKeys(t *testing.T) {
+	tests := []struct {
+		name      string
+		setup     func(*config.Config)
+		completed map[string]bool
+		expected  []string
+	}{
+		{"local files", func(c *config.Config) { c.Audio, c.Image = "song.mp3", "cover.png" }, nil, nil},
+		{"elevenlabs speech", func(c *config.Config) { c.Audio, c.Image = "generate", "cover.png" }, nil, []string{"ELEVENLABS_API_KEY"}},
+		{"azure speech", func(c *config.Config) {
+			c.Audio, c.Image, c.TTSProvider = "generate", "cover.png", config.ProviderAzure
+		}, nil, []string{"AZURE_SPEECH_KEY", "AZURE_SPEECH_REGION"}},
+		{"generated image", func(c *config.Config) { c.Audio, c.Image = "song.mp3", "cover.png,Generate" }, nil, []string{"IDEOGRAM_API_KEY"}},
+		{"autofill image", func(c *config.Config) {
+			c.Audio, c.AutoFill, c.ImageProvider = "song.mp3", true, config.ImageProviderDALLE
+		}, nil, []string{"OPENAI_API_KEY"}},
+		{"analyze and upscale", func(c *config.Config) {
+			c.Audio, c.Image, c.AnalyzeAudio = "song.mp3", "generate", true
+			c.ImageProvider, c.Upscale = config.ImageProviderImagen, true
+		}, nil, []string{"GEMINI_API_KEY", "IDEOGRAM_API_KEY"}},
+		{"openai speech and images", func(c *config.Config) {
+			c.Audio, c.Image, c.TTSProvider, c.ImageProvider = "generate", "generate", config.ProviderOpenAI, config.ImageProviderDALLE
+		}, nil, []string{"OPENAI_API_KEY"}},
+		{"resumed speech", func(c *config.Config) { c.Audio, c.Image = "generate", "generate" }, map[string]bool{"audio": true}, []string{"IDEOGRAM_API_KEY"}},
+	}
+
+	for _, tt := range tests {
+		cfg := config.New()
+		tt.setup(cfg)
+		var envs []string
+		for _, need := range RequiredKeys(cfg, Options{Completed: tt.completed}) {
+			envs = append(envs, need.Env)
+		}
+		if strings.Join(envs, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("RequiredKeys(%s) = %q, expected %q", tt.name, envs, tt.expected)
+		}
+	}
+}
+
+func TestRequiredKeysMergesReasons(t *testing.T) {
+	cfg := config.New()
+	cfg.Audio, cfg.Image, cfg.AnalyzeAudio, cfg.ImageProvider = "song.mp3", "generate", true, config.ImageProviderImagen
+
+	needs := RequiredKeys(cfg, Options{})
+	expected := "--analyze-audio and --image-provider " + string(config.ImageProviderImagen)
+	if len(needs) != 1 || needs[0].Reason != expected {
+		t.Errorf("RequiredKeys() = %+v, expected one GEMINI_API_KEY need for %q", needs, expected)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	stubTools(t, map[string]string{
+		"ffmpeg": "ffmpeg version 3.4.8-0ubuntu0.2 Copyright (c) 2000-2020\n",
+		"yt-dlp": "2023.03.04\n",
+	})
+
+	cfg := config.New()
+	cfg.Audio = "https://www.youtube.com/watch?v=abc"
+	cfg.Image = "generate"
+	cfg.IdeogramKey = "key"
+
+	r := Check(cfg, Options{})
+	expected := []string{"ffmpeg is too old", "ffprobe not found on PATH"}
+	if len(r.Problems) != len(expected) {
+		t.Fatalf("Check() problems = %q, expected %d", r.Problems, len(expected))
+	}
+	for i, problem := range r.Problems {
+		if !strings.Contains(problem, expected[i]) {
+			t.Errorf("Check() problem %d = %q, expected it to contain %q", i, problem, expected[i])
+		}
+	}
+	if len(r.Warnings) != 1 || !strings.Contains(r.Warnings[0], "yt-dlp 2023.03.04") {
+		t.Errorf("Check() warnings = %q, expected an old yt-dlp warning", r.Warnings)
+	}
+	if err := r.Err(); err == nil || !strings.Contains(err.Error(), "2 problem(s)") {
+		t.Errorf("Err() = %v, expected both problems", err)
+	}
+}
+
+func TestCheckPasses(t *testing.T) {
+	stubTools(t, map[string]string{
+		"ffmpeg":  "ffmpeg version N-113548-g5b5b5b5 Copyright (c) 2000-2024\n",
+		"ffprobe": "ffprobe version n7.0 Copyright (c) 2007-2024\n",
+	})
+
+	cfg := config.New()
+	cfg.Audio, cfg.Image = "song.mp3", "cover.png"
+	if r := Check(cfg, Options{}); r.Err() != nil || len(r.Warnings) > 0 {
+		t.Errorf("Check() = %v, %q, expected no problems or warnings", r.Err(), r.Warnings)
+	}
+}
+
+func TestCheckYtDlp(t *testing.T) {
+	tests := []struct {
+		name       string
+		installed  map[string]string
+		configured string
+		problem    string
+		warning    string
+	}{
+		{"missing", map[string]string{}, "", "install it (brew install yt-dlp", ""},
+		{"youtube-dl fallback", map[string]string{"youtube-dl": "2021.12.17\n"}, "", "", "using youtube-dl"},
+		{"configured path", map[string]string{"yt-dlp-nightly": "2025.05.30\n"}, "yt-dlp-nightly", "", ""},
+		{"configured path missing", map[string]string{"yt-dlp": "2025.05.30\n"}, "/opt/yt-dlp", "not found at /opt/yt-dlp", ""},
+	}
+
+	for _, tt := range tests {
+		stubTools(t, tt.installed)
+		r := &Report{}
+		r.checkYtDlp(tt.configured)
+		if got := strings.Join(r.Problems, "\n"); (tt.problem == "") != (got == "") || !strings.Contains(got, tt.problem) {
+			t.Errorf("checkYtDlp(%s) problems = %q, expected %q", tt.name, r.Problems, tt.problem)
+		}
+		if got := strings.Join(r.Warnings, "\n"); (tt.warning == "") != (got == "") || !strings.Contains(got, tt.warning) {
+			t.Errorf("checkYtDlp(%s) warnings = %q, expected %q", tt.name, r.Warnings, tt.warning)
+		}
+	}
+}
+
+func TestParseFFmpegMajor(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+		ok       bool
+	}{
+		{"ffmpeg version 6.1.1-3ubuntu5 Copyright", 6, true},
+		{"ffprobe version n7.0 Copyright", 7, true},
+		{"ffmpeg version 4.4.2-0ubuntu0.22.04.1", 4, true},
+		{"ffmpeg version N-113548-g5b5b5b5", 0, false},
+		{"ffmpeg version 2023-08-17-git-b62e4e6-full_build", 0, false},
+	}
+
+	for _, test := range tests {
+		major, ok := parseFFmpegMajor(test.input)
+		if major != test.expected || ok != test.ok {
+			t.Errorf("parseFFmpegMajor(%q) = %d, %v, expected %d, %v", test.input, major, ok, test.expected, test.ok)
+		}
+	}
+}
+
+func TestParseYtDlpVersion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{"2024.08.06\n", "2024-08-06", false},
+		{"2024.08.06.232541", "2024-08-06", false},
+		{"unknown", "", true},
+	}
+
+	for _, test := range tests {
+		result, err := parseYtDlpVersion(test.input)
+		if (err != nil) != test.wantErr {
+			t.Errorf("parseYtDlpVersion(%q) error = %v, expected error: %v", test.input, err, test.wantErr)
+			continue
+		}
+		if err == nil && result.Format("2006-01-02") != test.expected {
+			t.Errorf("parseYtDlpVersion(%q) = %s, expected %s", test.input, result.Format("2006-01-02"), test.expected)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Package probe wraps ffprobe lookups. Durations are kept in a process-wide
+// cache so each media file is only probed once per run.
+package probe
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mmmeld/internal/logx"
+)
+
+type durationEntry struct {
+	modTime  time.Time
+	size     int64
+	duration float64
+}
+
+var (
+	mu        sync.Mutex
+	durations = make(map[string]durationEntry)
+)
+
+// probeDuration runs ffprobe for a single file. It is a variable so tests can
+// substitute a fake and count invocations.
+var probeDuration = func(path string) (float64, error) {
+	cmd := exec.Command("ffprobe", "-v", "error", "-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1", path)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get media duration for %s: %w", path, err)
+	}
+
+	durationStr := strings.TrimSpace(string(output))
+	if durationStr == "" {
+		return 0, fmt.Errorf("ffprobe returned empty duration for %s", path)
+	}
+
+	duration, err := strconv.ParseFloat(durationStr, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse duration '%s': %w", durationStr, err)
+	}
+
+	logx.Debugf("Media duration for %s: %.3f seconds", path, duration)
+	return duration, nil
+}
+
+// Duration returns the duration of a media file in seconds. Results are cached
+// by absolute path and are reused as long as the file's size and modification
+// time are unchanged.
+func Duration(path string) (float64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		// Let ffprobe produce the error so callers see a consistent message
+		return probeDuration(path)
+	}
+
+	key := cacheKey(path)
+
+	mu.Lock()
+	entry, ok := durations[key]
+	mu.Unlock()
+	if ok && entry.size == info.Size() && entry.modTime.Equal(info.ModTime()) {
+		return entry.duration, nil
+	}
+
+	duration, err := probeDuration(path)
+	if err != nil {
+		return 0, err
+	}
+
+	mu.Lock()
+	durations[key] = durationEntry{
+		modTime:  info.ModTime(),
+		size:     info.Size(),
+		duration: duration,
+	}
+	mu.Unlock()
+
+	return duration, nil
+}
+
+// Invalidate drops any cached data for path. Call it after rewriting a file
+// whose size and modification time might not change (e.g. same-second rewrites).
+func Invalidate(path string) {
+	mu.Lock()
+	delete(durations, cacheKey(path))
+	mu.Unlock()
+}
+
+// Reset clears the whole cache.
+func Reset() {
+	mu.Lock()
+	durations = make(map[string]durationEntry)
+	mu.Unlock()
+}
+
+// Tags returns the container-level metadata tags (ID3, Vorbis comments, MP4
+// atoms, ...) of a media file. Keys are lowercased so callers can look up
+// "title", "artist", "album" regardless of how the container spells them.
+func Tags(path string) (map[string]string, error) {
+	cmd := exec.Command("ffprobe", "-v", "error", "-show_entries", "format_tags",
+		"-of", "json", path)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tags for %s: %w", path, err)
+	}
+
+	return parseTags(output)
+}
+
+func parseTags(output []byte) (map[string]string, error) {
+	var data struct {
+		Format struct {
+			Tags map[string]string `json:"tags"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(output, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe tags: %w", err)
+	}
+
+	tags := make(map[string]string, len(data.Format.Tags))
+	for k, v := range data.Format.Tags {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			tags[strings.ToLower(k)] = v
+		}
+	}
+	return tags, nil
+}
+
+func cacheKey(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
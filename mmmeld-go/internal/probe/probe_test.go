@@ -0,0 +1,182 @@
+package probe
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func withFakeProbe(t testing.TB, duration float64) *int {
+	t.Helper()
+	calls := 0
+	original := probeDuration
+	probeDuration = func(path string) (float64, error) {
+		calls++
+		return duration, nil
+	}
+	Reset()
+	t.Cleanup(func() {
+		probeDuration = original
+		Reset()
+	})
+	return &calls
+}
+
+func TestDurationCachesByPath(t *testing.T) {
+	calls := withFakeProbe(t, 12.5)
+
+	path := filepath.Join(t.TempDir(), "clip.mp3")
+	if err := os.WriteFile(path, []byte("audio"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		d, err := Duration(path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if d != 12.5 {
+			t.Errorf("Expected duration 12.5, got %f", d)
+		}
+	}
+
+	if *calls != 1 {
+		t.Errorf("Expected 1 probe, got %d", *calls)
+	}
+}
+
+func TestDurationReprobesWhenFileChanges(t *testing.T) {
+	calls := withFakeProbe(t, 3.0)
+
+	path := filepath.Join(t.TempDir(), "clip.mp3")
+	if err := os.WriteFile(path, []byte("audio"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if _, err := Duration(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Grow the file and bump its mtime
+	if err := os.WriteFile(path, []byte("longer audio"), 0644); err != nil {
+		t.Fatalf("Failed to rewrite test file: %v", err)
+	}
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(path, future, future)
+
+	if _, err := Duration(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if *calls != 2 {
+		t.Errorf("Expected 2 probes after file change, got %d", *calls)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	calls := withFakeProbe(t, 1.0)
+
+	path := filepath.Join(t.TempDir(), "clip.wav")
+	if err := os.WriteFile(path, []byte("audio"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	Duration(path)
+	Invalidate(path)
+	Duration(path)
+
+	if *calls != 2 {
+		t.Errorf("Expected 2 probes after Invalidate, got %d", *calls)
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	output := []byte(`{"format": {"tags": {"TITLE": "Midnight Drive", "Artist": "The Band", "album": "  ", "encoder": "Lavf60"}}}`)
+
+	tags, err := parseTags(output)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tags["title"] != "Midnight Drive" {
+		t.Errorf("Expected title 'Midnight Drive', got %q", tags["title"])
+	}
+	if tags["artist"] != "The Band" {
+		t.Errorf("Expected artist 'The Band', got %q", tags["artist"])
+	}
+	if _, ok := tags["album"]; ok {
+		t.Error("Blank tags should be dropped")
+	}
+
+	if _, err := parseTags([]byte("not json")); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+
+	empty, err := parseTags([]byte(`{"format": {}}`))
+	if err != nil || len(empty) != 0 {
+		t.Errorf("Expected no tags and no error, got %v, %v", empty, err)
+	}
+}
+
+// benchmarkMediaPath returns a real media file from the repo's test_media
+// folder, skipping when ffprobe or the file is unavailable.
+func TestParseAudioWindow(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected *AudioWindow
+		wantErr  bool
+	}{
+		{"0:60-4:00", &AudioWindow{60 * time.Second, 4 * time.Minute}, false},
+		{"90-240", &AudioWindow{90 * time.Second, 240 * time.Second}, false},
+		{"1:02:03-1:05:00.5", &AudioWindow{time.Hour + 2*time.Minute + 3*time.Second, time.Hour + 5*time.Minute + 500*time.Millisecond}, false},
+		{" 0:30 - 1:00 ", &AudioWindow{30 * time.Second, time.Minute}, false},
+		{"4:00-1:00", nil, true},
+		{"60", nil, true},
+		{"a:00-1:00", nil, true},
+		{"1.5:00-2:00", nil, true},
+		{"1:2:3:4-5", nil, true},
+	}
+
+	for _, tt := range tests {
+		result, err := ParseAudioWindow(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAudioWindow(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("ParseAudioWindow(%q) = %v, expected %v", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func benchmarkMediaPath(b *testing.B) string {
+	b.Helper()
+	if _, err := exec.LookPath("ffprobe"); err != nil {
+		b.Skip("ffprobe not available")
+	}
+	path := filepath.Join("..", "..", "..", "test_media", "10_seconds.wav")
+	if _, err := os.Stat(path); err != nil {
+		b.Skip("test media not available")
+	}
+	return path
+}
+
+func BenchmarkDurationUncached(b *testing.B) {
+	path := benchmarkMediaPath(b)
+	for i := 0; i < b.N; i++ {
+		Invalidate(path)
+		if _, err := Duration(path); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDurationCached(b *testing.B) {
+	path := benchmarkMediaPath(b)
+	Reset()
+	for i := 0; i < b.N; i++ {
+		if _, err := Duration(path); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
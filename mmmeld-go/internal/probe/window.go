@@ -0,0 +1,64 @@
+package probe
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AudioWindow is the part of a track analyzed for an image prompt
+type AudioWindow struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// String renders the window as m:ss-m:ss
+func (w AudioWindow) String() string {
+	format := func(d time.Duration) string {
+		seconds := int(d.Round(time.Second) / time.Second)
+		return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+	}
+	return format(w.Start) + "-" + format(w.End)
+}
+
+// ParseAudioWindow parses an analysis window such as "0:60-4:00" or "90-240".
+// Each bound is seconds, m:ss or h:mm:ss; a bound's seconds may exceed 59.
+func ParseAudioWindow(s string) (*AudioWindow, error) {
+	start, end, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid analysis window %q (expected start-end, e.g. 0:60-4:00)", s)
+	}
+	var w AudioWindow
+	for _, bound := range []struct {
+		text string
+		dst  *time.Duration
+	}{{start, &w.Start}, {end, &w.End}} {
+		d, err := parseTimestamp(bound.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid analysis window %q: %w", s, err)
+		}
+		*bound.dst = d
+	}
+	if w.End <= w.Start {
+		return nil, fmt.Errorf("invalid analysis window %q: end must be after start", s)
+	}
+	return &w, nil
+}
+
+// parseTimestamp parses seconds, m:ss or h:mm:ss
+func parseTimestamp(s string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+	var total float64
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil || v < 0 || (i < len(parts)-1 && v != float64(int(v))) {
+			return 0, fmt.Errorf("invalid timestamp %q", s)
+		}
+		total = total*60 + v
+	}
+	return time.Duration(total * float64(time.Second)), nil
+}
@@ -0,0 +1,241 @@
+// Package resume records which stages of an mmmeld run have finished so a
+// failed run can be restarted without redoing expensive work. The manifest
+// lives next to the temp assets it describes and stores, per stage, the
+// settings it was produced with, the SHA-256 of every file it produced, and
+// its result.
+package resume
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ManifestName is the manifest's file name inside the temp assets folder
+const ManifestName = "run_manifest.json"
+
+const manifestVersion = 1
+
+// Stage is one completed step of a run
+type Stage struct {
+	Inputs    map[string]string `json:"inputs"` // Setting name -> JSON value the stage was run with
+	Files     map[string]string `json:"files"`  // Produced file -> SHA-256
+	Result    json.RawMessage   `json:"result"`
+	Completed time.Time         `json:"completed"`
+}
+
+// Manifest is the persisted record of completed stages
+type Manifest struct {
+	Version int               `json:"version"`
+	Created time.Time         `json:"created"`
+	Stages  map[string]*Stage `json:"stages"`
+
+	path string
+}
+
+// New returns an empty manifest that will be saved in dir
+func New(dir string) *Manifest {
+	return &Manifest{
+		Version: manifestVersion,
+		Created: time.Now(),
+		Stages:  make(map[string]*Stage),
+		path:    filepath.Join(dir, ManifestName),
+	}
+}
+
+// Load reads the manifest saved in dir. A missing manifest is not an error;
+// it loads as empty, so every stage runs.
+func Load(dir string) (*Manifest, error) {
+	m := New(dir)
+	data, err := os.ReadFile(m.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse run manifest %s: %w", m.path, err)
+	}
+	if m.Version != manifestVersion {
+		return nil, fmt.Errorf("run manifest %s has version %d, expected %d", m.path, m.Version, manifestVersion)
+	}
+	if m.Stages == nil {
+		m.Stages = make(map[string]*Stage)
+	}
+	return m, nil
+}
+
+// Path returns where the manifest is saved
+func (m *Manifest) Path() string {
+	return m.path
+}
+
+// Check reports whether stage name completed with the same inputs and its
+// files are unchanged. When it did, its result is decoded into result. When it
+// did not, reason explains why the stage has to run again.
+func (m *Manifest) Check(name string, inputs map[string]string, result any) (ok bool, reason string, err error) {
+	stage := m.Stages[name]
+	if stage == nil {
+		return false, "not completed by the previous run", nil
+	}
+
+	if changes := diffInputs(stage.Inputs, inputs); len(changes) > 0 {
+		return false, strings.Join(changes, "; "), nil
+	}
+
+	for _, path := range sortedKeys(stage.Files) {
+		sum, err := hashFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return false, fmt.Sprintf("%s is missing", path), nil
+		}
+		if err != nil {
+			return false, "", err
+		}
+		if sum != stage.Files[path] {
+			return false, fmt.Sprintf("%s was modified", path), nil
+		}
+	}
+
+	if err := json.Unmarshal(stage.Result, result); err != nil {
+		return false, "", fmt.Errorf("failed to decode %s result from run manifest: %w", name, err)
+	}
+	return true, "", nil
+}
+
+// Complete records stage name as finished and saves the manifest. Later
+// stages that depend on it should include its Digest in their inputs.
+func (m *Manifest) Complete(name string, inputs map[string]string, files []string, result any) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s result: %w", name, err)
+	}
+
+	stage := &Stage{
+		Inputs:    inputs,
+		Files:     make(map[string]string, len(files)),
+		Result:    data,
+		Completed: time.Now(),
+	}
+	for _, path := range files {
+		if path == "" {
+			continue
+		}
+		sum, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		stage.Files[path] = sum
+	}
+
+	m.Stages[name] = stage
+	return m.save()
+}
+
+// Digest identifies the output of stage name, or is empty if the stage has
+// not completed. It changes whenever the stage's files or result change.
+func (m *Manifest) Digest(name string) string {
+	stage := m.Stages[name]
+	if stage == nil {
+		return ""
+	}
+	h := sha256.New()
+	for _, path := range sortedKeys(stage.Files) {
+		fmt.Fprintf(h, "%s=%s\n", path, stage.Files[path])
+	}
+	// The saved manifest is indented, so compact the result to hash the same
+	// bytes before and after a reload
+	var result bytes.Buffer
+	if err := json.Compact(&result, stage.Result); err != nil {
+		result.Write(stage.Result)
+	}
+	h.Write(result.Bytes())
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// Remove deletes the saved manifest once the run has succeeded
+func (m *Manifest) Remove() error {
+	if err := os.Remove(m.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove run manifest: %w", err)
+	}
+	return nil
+}
+
+// save writes the manifest atomically so an interrupted run never leaves a
+// truncated file behind
+func (m *Manifest) save() error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode run manifest: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return fmt.Errorf("failed to create run manifest folder: %w", err)
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write run manifest: %w", err)
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		return fmt.Errorf("failed to write run manifest: %w", err)
+	}
+	return nil
+}
+
+// diffInputs describes each setting whose value differs between the previous
+// run and this one
+func diffInputs(previous, current map[string]string) []string {
+	keys := make(map[string]bool)
+	for k := range previous {
+		keys[k] = true
+	}
+	for k := range current {
+		keys[k] = true
+	}
+
+	var changes []string
+	for _, k := range sortedKeys(keys) {
+		was, had := previous[k]
+		now, has := current[k]
+		switch {
+		case !had:
+			changes = append(changes, fmt.Sprintf("%s is new (now %s)", k, now))
+		case !has:
+			changes = append(changes, fmt.Sprintf("%s is no longer set (was %s)", k, was))
+		case was != now:
+			changes = append(changes, fmt.Sprintf("%s changed from %s to %s", k, was, now))
+		}
+	}
+	return changes
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,152 @@
+package resume
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type audioResult struct {
+	Path  string
+	Title string
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	asset := filepath.Join(dir, "speech.mp3")
+	if err := os.WriteFile(asset, []byte("audio"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	inputs := map[string]string{"audio": `"generate"`, "voice_id": `"alloy"`}
+	m := New(dir)
+	if err := m.Complete("audio", inputs, []string{asset}, audioResult{Path: asset, Title: "Hello"}); err != nil {
+		t.Fatalf("Complete() error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		stage    string
+		inputs   map[string]string
+		modify   bool
+		expected bool
+		reason   string
+	}{
+		{"unchanged", "audio", inputs, false, true, ""},
+		{"unknown stage", "images", inputs, false, false, "not completed"},
+		{"changed flag", "audio", map[string]string{"audio": `"generate"`, "voice_id": `"nova"`}, false, false, `voice_id changed from "alloy" to "nova"`},
+		{"new flag", "audio", map[string]string{"audio": `"generate"`, "voice_id": `"alloy"`, "title": `"x"`}, false, false, "title is new"},
+		{"modified file", "audio", inputs, true, false, "was modified"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.modify {
+				if err := os.WriteFile(asset, []byte("other audio"), 0644); err != nil {
+					t.Fatalf("Failed to modify test file: %v", err)
+				}
+			}
+
+			loaded, err := Load(dir)
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			var result audioResult
+			ok, reason, err := loaded.Check(tt.stage, tt.inputs, &result)
+			if err != nil {
+				t.Fatalf("Check(%q) error: %v", tt.stage, err)
+			}
+			if ok != tt.expected {
+				t.Errorf("Check(%q) = %v (%s), expected %v", tt.stage, ok, reason, tt.expected)
+			}
+			if !strings.Contains(reason, tt.reason) {
+				t.Errorf("Check(%q) reason = %q, expected it to contain %q", tt.stage, reason, tt.reason)
+			}
+			if ok && result.Title != "Hello" {
+				t.Errorf("Check(%q) result title = %q, expected %q", tt.stage, result.Title, "Hello")
+			}
+		})
+	}
+}
+
+func TestCheckMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	asset := filepath.Join(dir, "image.png")
+	if err := os.WriteFile(asset, []byte("png"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	m := New(dir)
+	if err := m.Complete("images", nil, []string{asset}, []string{asset}); err != nil {
+		t.Fatalf("Complete() error: %v", err)
+	}
+	if err := os.Remove(asset); err != nil {
+		t.Fatalf("Failed to remove test file: %v", err)
+	}
+
+	var result []string
+	ok, reason, err := m.Check("images", nil, &result)
+	if err != nil {
+		t.Fatalf("Check() error: %v", err)
+	}
+	if ok || !strings.Contains(reason, "is missing") {
+		t.Errorf("Check() = %v (%s), expected a missing file", ok, reason)
+	}
+}
+
+func TestDigestChangesWithFiles(t *testing.T) {
+	dir := t.TempDir()
+	asset := filepath.Join(dir, "speech.mp3")
+	if err := os.WriteFile(asset, []byte("one"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	m := New(dir)
+	if got := m.Digest("audio"); got != "" {
+		t.Errorf("Digest() before Complete = %q, expected empty", got)
+	}
+	if err := m.Complete("audio", nil, []string{asset}, asset); err != nil {
+		t.Fatalf("Complete() error: %v", err)
+	}
+	first := m.Digest("audio")
+
+	if err := os.WriteFile(asset, []byte("two"), 0644); err != nil {
+		t.Fatalf("Failed to modify test file: %v", err)
+	}
+	if err := m.Complete("audio", nil, []string{asset}, asset); err != nil {
+		t.Fatalf("Complete() error: %v", err)
+	}
+	if second := m.Digest("audio"); second == first {
+		t.Errorf("Digest() = %q after the file changed, expected a new digest", second)
+	}
+}
+
+func TestLoadMissingManifest(t *testing.T) {
+	m, err := Load(t.TempDir())
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if len(m.Stages) != 0 {
+		t.Errorf("Load() stages = %d, expected 0", len(m.Stages))
+	}
+	if err := m.Remove(); err != nil {
+		t.Errorf("Remove() on a missing manifest returned %v", err)
+	}
+}
+
+func TestDigestSurvivesReload(t *testing.T) {
+	dir := t.TempDir()
+	m := New(dir)
+	if err := m.Complete("audio", nil, nil, audioResult{Path: "a.mp3", Title: "A"}); err != nil {
+		t.Fatalf("Complete() error: %v", err)
+	}
+
+	loaded, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if got, expected := loaded.Digest("audio"), m.Digest("audio"); got != expected {
+		t.Errorf("Digest() after reload = %q, expected %q", got, expected)
+	}
+}
@@ -0,0 +1,72 @@
+// Package retry runs calls that may fail transiently, waiting between
+// attempts. Gemini quota errors, rate-limited image providers, and TTS
+// requests share its loop and backoff; each caller decides which failures
+// are worth retrying and how long they ask to wait.
+package retry
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Policy tunes Do
+type Policy struct {
+	Attempts  int                                              // Calls in all, the first included (< 1 = 1)
+	Retryable func(err error) bool                             // Whether a failure is worth retrying (nil = every failure)
+	Delay     func(attempt int, err error) time.Duration       // Wait after the attempt-th failure, e.g. Backoff or a Retry-After
+	OnRetry   func(attempt int, wait time.Duration, err error) // Called before each wait, e.g. to log it
+}
+
+// Sleep waits for d unless ctx is done first, returning ctx's error then. It
+// is a variable so tests can skip real waits.
+var Sleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Do calls fn until it succeeds, fails with an error p does not retry, or
+// p.Attempts calls have been made, and returns the number of calls and the
+// last error. Cancelling ctx stops a wait, returning ctx's error wrapped
+// around the failure that was being retried.
+func Do(ctx context.Context, p Policy, fn func() error) (int, error) {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.Attempts || (p.Retryable != nil && !p.Retryable(err)) {
+			return attempt, err
+		}
+
+		var wait time.Duration
+		if p.Delay != nil {
+			wait = p.Delay(attempt, err)
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, wait, err)
+		}
+		if serr := Sleep(ctx, wait); serr != nil {
+			return attempt, fmt.Errorf("%w (while retrying after: %v)", serr, err)
+		}
+	}
+}
+
+// Backoff returns the wait after the attempt-th failure: base doubled for
+// each earlier failure, at most limit. With jitter the wait is a random point
+// in the upper half of that, which keeps concurrent runs from retrying in
+// lockstep.
+func Backoff(attempt int, base, limit time.Duration, jitter bool) time.Duration {
+	delay := base << (max(attempt, 1) - 1)
+	if delay <= 0 || delay > limit {
+		delay = limit
+	}
+	if !jitter {
+		return delay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -0,0 +1,143 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	errTransient = errors.New("503 Service Unavailable")
+	errPermanent = errors.New("401 Unauthorized")
+)
+
+// fakeSleep records the waits Do asks for instead of sleeping
+func fakeSleep(t *testing.T) *[]time.Duration {
+	t.Helper()
+	orig := Sleep
+	t.Cleanup(func() { Sleep = orig })
+	var waits []time.Duration
+	Sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return ctx.Err()
+	}
+	return &waits
+}
+
+func TestDo(t *testing.T) {
+	tests := []struct {
+		name          string
+		attempts      int
+		failures      []error // Returned by the first calls; later calls succeed
+		expectCalls   int
+		expectError   error
+		expectWaits   []time.Duration
+		expectRetries []int
+	}{
+		{
+			name:          "succeeds after transient failures",
+			attempts:      4,
+			failures:      []error{errTransient, errTransient},
+			expectCalls:   3,
+			expectWaits:   []time.Duration{time.Second, 2 * time.Second},
+			expectRetries: []int{1, 2},
+		},
+		{
+			name:          "gives up after the last attempt",
+			attempts:      3,
+			failures:      []error{errTransient, errTransient, errTransient, errTransient},
+			expectCalls:   3,
+			expectError:   errTransient,
+			expectWaits:   []time.Duration{time.Second, 2 * time.Second},
+			expectRetries: []int{1, 2},
+		},
+		{
+			name:        "does not retry a permanent failure",
+			attempts:    3,
+			failures:    []error{errPermanent},
+			expectCalls: 1,
+			expectError: errPermanent,
+		},
+		{
+			name:        "a single attempt never waits",
+			attempts:    0,
+			failures:    []error{errTransient},
+			expectCalls: 1,
+			expectError: errTransient,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			waits := fakeSleep(t)
+			var retries []int
+			policy := Policy{
+				Attempts:  test.attempts,
+				Retryable: func(err error) bool { return err == errTransient },
+				Delay: func(attempt int, err error) time.Duration {
+					return Backoff(attempt, time.Second, time.Minute, false)
+				},
+				OnRetry: func(attempt int, wait time.Duration, err error) { retries = append(retries, attempt) },
+			}
+
+			calls := 0
+			n, err := Do(context.Background(), policy, func() error {
+				calls++
+				if calls <= len(test.failures) {
+					return test.failures[calls-1]
+				}
+				return nil
+			})
+			if n != test.expectCalls || calls != test.expectCalls || err != test.expectError {
+				t.Errorf("Do() = %d, %v after %d calls, expected %d, %v", n, err, calls, test.expectCalls, test.expectError)
+			}
+			if !reflect.DeepEqual(*waits, test.expectWaits) {
+				t.Errorf("waits = %v, expected %v", *waits, test.expectWaits)
+			}
+			if !reflect.DeepEqual(retries, test.expectRetries) {
+				t.Errorf("OnRetry attempts = %v, expected %v", retries, test.expectRetries)
+			}
+		})
+	}
+}
+
+func TestDoCancelled(t *testing.T) {
+	fakeSleep(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	n, err := Do(ctx, Policy{Attempts: 5}, func() error {
+		calls++
+		return errTransient
+	})
+	if n != 1 || calls != 1 || !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), errTransient.Error()) {
+		t.Errorf("Do() = %d, %v after %d calls, expected to stop at the first wait with context.Canceled and the failure", n, err, calls)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{0, time.Second},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{7, 60 * time.Second},
+		{100, 60 * time.Second},
+	}
+
+	for _, test := range tests {
+		if d := Backoff(test.attempt, time.Second, time.Minute, false); d != test.expected {
+			t.Errorf("Backoff(%d) = %v, expected %v", test.attempt, d, test.expected)
+		}
+		if d := Backoff(test.attempt, time.Second, time.Minute, true); d < test.expected/2 || d > test.expected {
+			t.Errorf("Backoff(%d) with jitter = %v, expected between %v and %v", test.attempt, d, test.expected/2, test.expected)
+		}
+	}
+}
@@ -2,30 +2,81 @@ package tts
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"text/tabwriter"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"mmmeld/internal/config"
 	"mmmeld/internal/fileutil"
+	"mmmeld/internal/genai"
+	"mmmeld/internal/httpx"
+	"mmmeld/internal/logx"
+	"mmmeld/internal/probe"
+	"mmmeld/internal/retry"
 )
 
 const (
-	MaxChunkSize = 4096
+	MaxChunkSize = config.DefaultTTSChunkSize
+
+	retryBaseDelay = 1 * time.Second
+	retryMaxDelay  = 60 * time.Second
+	errorBodyLimit = 500 // Bytes of a failed response body kept in errors
 )
 
 type TTSResult struct {
 	AudioPath   string
 	Title       string
 	Description string
+	Segments    []Segment // Sentence timings; only filled when SpeechOptions.Timestamps is set
+
+	Provider config.TTSProvider
+	VoiceID  string      // For dialogue scripts, the SPEAKER=voice pairs used
+	Duration float64     // Total seconds; 0 when a chunk could not be probed
+	Chunks   []ChunkInfo // Requests in playback order
+}
+
+// ChunkInfo describes one provider request and the audio it produced. Path
+// may be a temp file that does not outlive the run.
+type ChunkInfo struct {
+	Index    int     `json:"index"`
+	Text     string  `json:"text"`
+	Path     string  `json:"path"`
+	Duration float64 `json:"duration"`
+}
+
+// Segment is a sentence and the time range in which it is spoken, in seconds
+type Segment struct {
+	Text  string  `json:"text"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// charAlignment holds per-character timings returned by ElevenLabs
+type charAlignment struct {
+	Characters []string  `json:"characters"`
+	Starts     []float64 `json:"character_start_times_seconds"`
+	Ends       []float64 `json:"character_end_times_seconds"`
 }
 
 type ElevenLabsRequest struct {
@@ -36,115 +87,878 @@ type ElevenLabsRequest struct {
 }
 
 type OpenAITTSRequest struct {
-	Model string `json:"model"`
-	Input string `json:"input"`
-	Voice string `json:"voice"`
+	Model          string  `json:"model"`
+	Input          string  `json:"input"`
+	Voice          string  `json:"voice"`
+	ResponseFormat string  `json:"response_format,omitempty"`
+	Speed          float64 `json:"speed,omitempty"`
 }
 
 type DeepgramTTSRequest struct {
 	Text string `json:"text"`
 }
 
-// SplitTextIntoChunks breaks text into chunks suitable for TTS processing
+// APIError is a non-200 response from a TTS provider
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Body       string        // Response body, truncated to errorBodyLimit bytes
+	RetryAfter time.Duration // Parsed Retry-After header, zero when absent
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API error %d: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// newAPIError reads a snippet of a failed response into an APIError
+func newAPIError(provider string, resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, errorBodyLimit))
+	return &APIError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Body:       strings.TrimSpace(string(body)),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// parseRetryAfter accepts both forms of Retry-After: delay seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// isRetryable reports whether err is a transient failure worth retrying:
+// 408, 429, 5xx responses and network errors.
+func isRetryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusRequestTimeout ||
+			apiErr.StatusCode == http.StatusTooManyRequests ||
+			apiErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryDelay returns the wait before the next attempt. Retry-After wins when
+// the server sends it; otherwise exponential backoff with jitter is used.
+func retryDelay(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return min(apiErr.RetryAfter, retryMaxDelay)
+	}
+	return retry.Backoff(attempt, retryBaseDelay, retryMaxDelay, true)
+}
+
+// withRetry calls fn until it succeeds, fails with a non-retryable error, or
+// maxAttempts is reached. It returns the number of attempts made.
+func withRetry(maxAttempts int, label string, fn func() (string, error)) (string, int, error) {
+	var result string
+	attempts, err := retry.Do(context.Background(), retry.Policy{
+		Attempts:  maxAttempts,
+		Retryable: isRetryable,
+		Delay:     retryDelay,
+		OnRetry: func(attempt int, wait time.Duration, err error) {
+			logx.Warnf("Attempt %d/%d for %s failed (%v), retrying in %s", attempt, maxAttempts, label, err, wait.Round(time.Millisecond))
+		},
+	}, func() error {
+		var err error
+		result, err = fn()
+		return err
+	})
+	if err != nil {
+		return "", attempts, err
+	}
+	return result, attempts, nil
+}
+
+// SplitTextIntoChunks breaks text into chunks of at most maxSize bytes. Lines
+// are kept together where possible; longer lines are split at sentence
+// boundaries (terminators stay attached), then at spaces, and a single word
+// longer than maxSize is cut into pieces as a last resort.
 func SplitTextIntoChunks(text string, maxSize int) []string {
 	if maxSize <= 0 {
 		maxSize = MaxChunkSize
 	}
 
 	var chunks []string
-	var currentChunk strings.Builder
-
-	lines := strings.Split(text, "\n")
-
-	for _, line := range lines {
-		if len(line) > maxSize {
-			// Split long lines by sentences
-			sentences := regexp.MustCompile(`(?:[.!?]+\s+)`).Split(line, -1)
-			for _, sentence := range sentences {
-				if len(sentence) > maxSize {
-					// Split by words as last resort
-					words := strings.Fields(sentence)
-					for _, word := range words {
-						if currentChunk.Len()+len(word)+1 > maxSize {
-							if currentChunk.Len() > 0 {
-								chunks = append(chunks, currentChunk.String())
-								currentChunk.Reset()
-							}
-						}
-						if currentChunk.Len() > 0 {
-							currentChunk.WriteString(" ")
-						}
-						currentChunk.WriteString(word)
-					}
-				} else {
-					if currentChunk.Len()+len(sentence)+1 > maxSize {
-						if currentChunk.Len() > 0 {
-							chunks = append(chunks, currentChunk.String())
-							currentChunk.Reset()
-						}
-					}
-					if currentChunk.Len() > 0 {
-						currentChunk.WriteString(" ")
-					}
-					currentChunk.WriteString(sentence)
+	var current strings.Builder
+
+	// add appends piece after sep, starting a new chunk when it would not fit
+	add := func(piece, sep string) {
+		if current.Len() > 0 && current.Len()+len(sep)+len(piece) > maxSize {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+		if current.Len() > 0 {
+			current.WriteString(sep)
+		}
+		current.WriteString(piece)
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		if len(line) <= maxSize {
+			add(line, "\n")
+			continue
+		}
+
+		sep := "\n"
+		for _, sentence := range splitSentences(line) {
+			if len(sentence) <= maxSize {
+				add(sentence, sep)
+				sep = " "
+				continue
+			}
+			for _, word := range strings.Fields(sentence) {
+				for _, piece := range splitLongWord(word, maxSize) {
+					add(piece, sep)
+					sep = " "
 				}
 			}
-		} else {
-			if currentChunk.Len()+len(line)+1 > maxSize {
-				if currentChunk.Len() > 0 {
-					chunks = append(chunks, currentChunk.String())
-					currentChunk.Reset()
+		}
+	}
+
+	if current.Len() > 0 {
+		chunks = append(chunks, current.String())
+	}
+
+	return chunks
+}
+
+// abbreviations never end a sentence even when followed by a space
+var abbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "sr": true,
+	"jr": true, "mt": true, "vs": true, "etc": true, "e.g": true, "i.e": true,
+	"inc": true, "ltd": true, "corp": true, "approx": true, "dept": true,
+	"gov": true, "lt": true, "sgt": true, "capt": true, "a.m": true, "p.m": true,
+	"u.s": true,
+}
+
+// numberAbbreviations are also ordinary words, so they only count as
+// abbreviations before a number, as in "No. 5" or "Fig. 3"
+var numberAbbreviations = map[string]bool{
+	"no": true, "fig": true, "est": true, "col": true, "rev": true,
+}
+
+// nameAbbreviations only count as abbreviations before a capitalized word,
+// as in "St. Louis" or "Smith & Co. Ltd"
+var nameAbbreviations = map[string]bool{
+	"st": true, "co": true,
+}
+
+// isAbbreviation reports whether the word ending at the period at index dot
+// of runes is a known abbreviation or a single-letter initial
+func isAbbreviation(runes []rune, dot int) bool {
+	start := dot
+	for start > 0 && !unicode.IsSpace(runes[start-1]) && runes[start-1] != '(' && runes[start-1] != '"' && runes[start-1] != '“' {
+		start--
+	}
+	word := string(runes[start:dot])
+	if utf8.RuneCountInString(word) == 1 && unicode.IsUpper(runes[start]) {
+		return true // Initial, as in "J. R. R. Tolkien"
+	}
+	word = strings.ToLower(word)
+	if numberAbbreviations[word] {
+		return nextWordIsNumber(runes, dot+1)
+	}
+	if nameAbbreviations[word] {
+		return nextWordIsUpper(runes, dot+1)
+	}
+	return abbreviations[word]
+}
+
+// sentenceEnds returns the rune offsets just past each sentence in text.
+// Sentences end after a run of .!? (plus any closing quotes or brackets)
+// followed by whitespace, or at a newline. Terminators inside quoted speech,
+// after abbreviations, or followed by a lowercase word do not end a sentence.
+func sentenceEnds(text string) []int {
+	runes := []rune(text)
+	var ends []int
+	inQuote := false
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\n':
+			ends = append(ends, i)
+			inQuote = false
+		case r == '"':
+			inQuote = !inQuote
+		case r == '“':
+			inQuote = true
+		case r == '”':
+			inQuote = false
+		case r == '.' || r == '!' || r == '?':
+			j := i
+			for j < len(runes) && (runes[j] == '.' || runes[j] == '!' || runes[j] == '?') {
+				j++
+			}
+			quoted := inQuote
+			for j < len(runes) && strings.ContainsRune(`"”’')]`, runes[j]) {
+				if runes[j] == '"' || runes[j] == '”' {
+					quoted = false
 				}
+				j++
+			}
+			atBoundary := j == len(runes) || unicode.IsSpace(runes[j])
+			if atBoundary && nextWordIsLower(runes, j) {
+				atBoundary = false // "“Why?” and waited" continues the sentence
+			}
+			if atBoundary && !quoted && !(r == '.' && j == i+1 && isAbbreviation(runes, i)) {
+				ends = append(ends, j)
+				inQuote = false
+			} else {
+				inQuote = quoted
 			}
-			if currentChunk.Len() > 0 {
-				currentChunk.WriteString("\n")
+			i = j - 1
+		}
+	}
+	if len(ends) == 0 || ends[len(ends)-1] != len(runes) {
+		ends = append(ends, len(runes))
+	}
+	return ends
+}
+
+// nextWordIsNumber reports whether the first non-space rune from i on the
+// same line is a digit
+func nextWordIsNumber(runes []rune, i int) bool {
+	for i < len(runes) && runes[i] != '\n' && unicode.IsSpace(runes[i]) {
+		i++
+	}
+	return i < len(runes) && unicode.IsDigit(runes[i])
+}
+
+// nextWordIsUpper reports whether the first non-space rune from i on the same
+// line is an uppercase letter
+func nextWordIsUpper(runes []rune, i int) bool {
+	for i < len(runes) && runes[i] != '\n' && unicode.IsSpace(runes[i]) {
+		i++
+	}
+	return i < len(runes) && unicode.IsUpper(runes[i])
+}
+
+// nextWordIsLower reports whether the first non-space rune from i on the same
+// line is a lowercase letter
+func nextWordIsLower(runes []rune, i int) bool {
+	for i < len(runes) && runes[i] != '\n' && unicode.IsSpace(runes[i]) {
+		i++
+	}
+	return i < len(runes) && unicode.IsLower(runes[i])
+}
+
+// splitSentences splits text into trimmed sentences with terminators attached
+func splitSentences(text string) []string {
+	var sentences []string
+	for _, span := range splitSentenceSpans(text) {
+		sentences = append(sentences, span.text)
+	}
+	return sentences
+}
+
+// splitLongWord cuts word into pieces of at most maxSize bytes without
+// splitting a UTF-8 sequence
+func splitLongWord(word string, maxSize int) []string {
+	var pieces []string
+	for len(word) > maxSize {
+		cut := maxSize
+		for cut > 0 && !utf8.RuneStart(word[cut]) {
+			cut--
+		}
+		if cut == 0 {
+			_, cut = utf8.DecodeRuneInString(word)
+		}
+		pieces = append(pieces, word[:cut])
+		word = word[cut:]
+	}
+	return append(pieces, word)
+}
+
+// SpeechOptions contains provider settings for speech generation
+type SpeechOptions struct {
+	Provider    config.TTSProvider
+	VoiceID     string
+	Command     string  // Command template for the local provider
+	ChunkSize   int     // Max characters per request; 0 disables chunking for the local provider
+	Model       string  // Provider model (OpenAI: tts-1, tts-1-hd, gpt-4o-mini-tts); empty uses the default
+	Speed       float64 // OpenAI speaking speed (0.25-4.0); 0 uses the provider default
+	Rate        float64 // Provider-independent speaking rate; 0 or 1 leaves the rate unchanged
+	Format      string  // Output format (mp3, wav, ogg, flac); empty keeps the provider default
+	MaxAttempts int     // Attempts per chunk for transient API failures; 0 uses config.DefaultTTSMaxAttempts
+	NoCache     bool    // Skip the on-disk chunk cache
+	CacheDir    string  // Chunk cache location; empty uses DefaultCacheDir
+
+	Voices        map[string]string // Speaker tag -> voice ID; enables dialogue scripts ("ALICE: Hi")
+	DialoguePause float64           // Seconds of silence between dialogue turns
+
+	// DeepgramEncoding and DeepgramSampleRate pick Deepgram's output encoding
+	// (see DeepgramEncodings) instead of deriving it from Format
+	DeepgramEncoding   string
+	DeepgramSampleRate int
+
+	// Lexicon maps source strings to replacement spellings, or to
+	// "ipa:<phonemes>" hints that SSML providers (Azure) speak via <phoneme>
+	Lexicon map[string]string
+
+	ElevenLabs *ElevenLabsSettings // Voice settings; nil uses DefaultElevenLabsSettings
+	NoLLMTitle bool                // Use the first-sentence heuristic instead of asking an LLM for a title
+	GeminiKey  string              // Gemini API key for LLM titles (empty = GEMINI_API_KEY)
+
+	// Timestamps fills TTSResult.Segments. ElevenLabs timings come from its
+	// with-timestamps endpoint; other providers (and cached chunks) get
+	// sentence timings spread over each chunk's probed duration.
+	Timestamps bool
+}
+
+// ElevenLabsSettings are the model and voice_settings sent with ElevenLabs requests
+type ElevenLabsSettings struct {
+	ModelID         string // Empty uses config.ElevenLabsModelID
+	Stability       float64
+	SimilarityBoost float64
+	Style           float64
+	SpeakerBoost    bool
+}
+
+// DefaultElevenLabsSettings returns the settings used when none are given
+func DefaultElevenLabsSettings() ElevenLabsSettings {
+	return ElevenLabsSettings{
+		ModelID:         config.ElevenLabsModelID,
+		Stability:       config.DefaultElevenLabsStability,
+		SimilarityBoost: config.DefaultElevenLabsSimilarity,
+		Style:           config.DefaultElevenLabsStyle,
+		SpeakerBoost:    true,
+	}
+}
+
+// elevenLabsSettings returns opts.ElevenLabs or the defaults
+func (opts SpeechOptions) elevenLabsSettings() ElevenLabsSettings {
+	if opts.ElevenLabs == nil {
+		return DefaultElevenLabsSettings()
+	}
+	settings := *opts.ElevenLabs
+	if settings.ModelID == "" {
+		settings.ModelID = config.ElevenLabsModelID
+	}
+	return settings
+}
+
+// model returns the provider model that will be requested, or "" when the
+// provider has no model choice
+func (opts SpeechOptions) model() string {
+	switch opts.Provider {
+	case config.ProviderElevenLabs:
+		return opts.elevenLabsSettings().ModelID
+	case config.ProviderOpenAI:
+		if opts.Model == "" {
+			return config.OpenAITTSModel
+		}
+	}
+	return opts.Model
+}
+
+// DialogueTurn is one speaker's contiguous lines in a dialogue script
+type DialogueTurn struct {
+	Speaker string // Empty for text before the first recognized speaker tag
+	Text    string
+}
+
+var speakerTagRegex = regexp.MustCompile(`^\s*([^:\s][^:]*?)\s*:\s*(.*)$`)
+
+// ParseDialogue splits a script into turns. A line starting with "NAME:" starts
+// a turn when NAME (case-insensitive) is a key of voices; any other line
+// continues the current turn.
+func ParseDialogue(text string, voices map[string]string) []DialogueTurn {
+	known := make(map[string]string, len(voices))
+	for speaker := range voices {
+		known[strings.ToUpper(speaker)] = speaker
+	}
+
+	var turns []DialogueTurn
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if m := speakerTagRegex.FindStringSubmatch(line); m != nil {
+			if speaker, ok := known[strings.ToUpper(m[1])]; ok {
+				turns = append(turns, DialogueTurn{Speaker: speaker, Text: strings.TrimSpace(m[2])})
+				continue
 			}
-			currentChunk.WriteString(line)
 		}
+		if len(turns) == 0 {
+			turns = append(turns, DialogueTurn{})
+		}
+		last := &turns[len(turns)-1]
+		if last.Text != "" {
+			last.Text += "\n"
+		}
+		last.Text += strings.TrimSpace(line)
 	}
 
-	if currentChunk.Len() > 0 {
-		chunks = append(chunks, currentChunk.String())
+	// Drop turns that are only a speaker tag
+	result := turns[:0]
+	for _, turn := range turns {
+		if turn.Text != "" {
+			result = append(result, turn)
+		}
 	}
+	return result
+}
 
-	return chunks
+// SupportedFormats lists the output formats GenerateSpeech can produce
+var SupportedFormats = []string{"mp3", "wav", "ogg", "flac"}
+
+// formatCodecs are the ffmpeg encoders used when converting or re-encoding
+var formatCodecs = map[string]string{
+	"mp3":  "libmp3lame",
+	"wav":  "pcm_s16le",
+	"ogg":  "libopus",
+	"flac": "flac",
+}
+
+// nativeFormats maps output formats to each provider's own format parameter.
+// Formats missing here are produced as mp3 and converted with ffmpeg.
+var nativeFormats = map[config.TTSProvider]map[string]string{
+	config.ProviderElevenLabs: {
+		"mp3": "mp3_44100_192",
+		"wav": "pcm_44100", // Raw PCM, wrapped in a WAV header on save
+	},
+	config.ProviderOpenAI: {
+		"mp3":  "mp3",
+		"wav":  "wav",
+		"flac": "flac",
+		"ogg":  "opus", // Ogg-encapsulated Opus
+	},
+	config.ProviderDeepgram: {
+		"mp3":  "encoding=mp3&sample_rate=44100",
+		"wav":  "encoding=linear16&container=wav&sample_rate=48000",
+		"flac": "encoding=flac&sample_rate=48000",
+		"ogg":  "encoding=opus&container=ogg",
+	},
+	config.ProviderAzure: {
+		"mp3": "audio-48khz-192kbitrate-mono-mp3",
+		"wav": "riff-48khz-16bit-mono-pcm",
+		"ogg": "ogg-48khz-16bit-mono-opus",
+	},
+}
+
+// deepgramEncoding describes one of Deepgram Aura's output encodings
+type deepgramEncoding struct {
+	format      string // Output format (file extension) of the chunk
+	container   string // Deepgram container parameter, empty for none
+	codec       string // ffmpeg encoder used when chunks are re-encoded; empty copies
+	sampleRates []int  // Accepted sample_rate values; nil when Deepgram fixes the rate
+}
+
+var deepgramEncodings = map[string]deepgramEncoding{
+	"linear16": {"wav", "wav", "pcm_s16le", []int{8000, 16000, 24000, 32000, 48000}},
+	"mulaw":    {"wav", "wav", "pcm_mulaw", []int{8000, 16000}},
+	"alaw":     {"wav", "wav", "pcm_alaw", []int{8000, 16000}},
+	"flac":     {"flac", "", "flac", []int{8000, 16000, 22050, 32000, 48000}},
+	"opus":     {"ogg", "ogg", "libopus", nil},
+	"mp3":      {"mp3", "", "", nil},
+}
+
+// DeepgramEncodings lists the encodings accepted by --encoding
+var DeepgramEncodings = []string{"linear16", "mulaw", "alaw", "flac", "opus", "mp3"}
+
+// DeepgramEncodingFormat returns the output format an encoding is saved as,
+// or "" for an unknown encoding
+func DeepgramEncodingFormat(encoding string) string {
+	return deepgramEncodings[encoding].format
+}
+
+// ValidateDeepgramEncoding checks an encoding, sample rate (0 = Deepgram's
+// default), and requested output format (empty = any) against each other
+func ValidateDeepgramEncoding(encoding string, sampleRate int, format string) error {
+	if encoding == "" {
+		if sampleRate != 0 {
+			return fmt.Errorf("sample-rate requires --encoding")
+		}
+		return nil
+	}
+
+	enc, ok := deepgramEncodings[encoding]
+	if !ok {
+		return fmt.Errorf("unsupported Deepgram encoding: %s (must be one of %s)", encoding, strings.Join(DeepgramEncodings, ", "))
+	}
+	if format != "" && format != enc.format {
+		return fmt.Errorf("Deepgram encoding %s produces %s, not %s", encoding, enc.format, format)
+	}
+	if sampleRate == 0 {
+		return nil
+	}
+	if enc.sampleRates == nil {
+		return fmt.Errorf("Deepgram encoding %s has a fixed sample rate; drop --sample-rate", encoding)
+	}
+	for _, rate := range enc.sampleRates {
+		if sampleRate == rate {
+			return nil
+		}
+	}
+	rates := make([]string, len(enc.sampleRates))
+	for i, rate := range enc.sampleRates {
+		rates[i] = strconv.Itoa(rate)
+	}
+	return fmt.Errorf("unsupported sample rate %d for Deepgram %s (must be one of %s)", sampleRate, encoding, strings.Join(rates, ", "))
+}
+
+// deepgramQuery builds the encoding parameters of a Deepgram speak request
+func deepgramQuery(encoding string, sampleRate int) string {
+	enc := deepgramEncodings[encoding]
+	query := "encoding=" + encoding
+	if enc.container != "" {
+		query += "&container=" + enc.container
+	}
+	if sampleRate != 0 {
+		query += fmt.Sprintf("&sample_rate=%d", sampleRate)
+	}
+	return query
+}
+
+// chunkCodec returns the ffmpeg encoder for re-encoding this request's chunks,
+// or "" to pick one from the file extension
+func (opts SpeechOptions) chunkCodec() string {
+	if opts.Provider == config.ProviderDeepgram && opts.DeepgramEncoding != "" {
+		return deepgramEncodings[opts.DeepgramEncoding].codec
+	}
+	return ""
+}
+
+// ValidateFormat checks that format is empty or one of SupportedFormats
+func ValidateFormat(format string) error {
+	if format == "" {
+		return nil
+	}
+	for _, f := range SupportedFormats {
+		if format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported output format: %s (must be one of %s)", format, strings.Join(SupportedFormats, ", "))
+}
+
+// Native speaking-rate ranges. Rates outside a provider's range, and providers
+// without rate control, fall back to ffmpeg's atempo filter.
+var nativeRateRanges = map[config.TTSProvider][2]float64{
+	config.ProviderElevenLabs: {0.7, 1.2}, // voice_settings.speed
+	config.ProviderOpenAI:     {0.25, 4.0},
+	config.ProviderAzure:      {0.5, 2.0}, // SSML prosody rate
+}
+
+// supportsNativeRate reports whether provider can apply rate itself
+func supportsNativeRate(provider config.TTSProvider, rate float64) bool {
+	r, ok := nativeRateRanges[provider]
+	return ok && rate >= r[0] && rate <= r[1]
+}
+
+// splitChunks splits text into the requests that will be sent to the provider
+func (opts SpeechOptions) splitChunks(text string) []string {
+	switch {
+	case opts.ChunkSize <= 0 && opts.Provider == config.ProviderLocal:
+		// Local engines have no request size limit
+		return []string{text}
+	case opts.ChunkSize <= 0:
+		return SplitTextIntoChunks(text, MaxChunkSize)
+	default:
+		return SplitTextIntoChunks(text, opts.ChunkSize)
+	}
+}
+
+// PricePer1KChars holds approximate list prices in USD per 1,000 characters,
+// keyed by provider or "provider/model" for models priced differently
+var PricePer1KChars = map[string]float64{
+	"elevenlabs":                   0.30,
+	"elevenlabs/eleven_flash_v2_5": 0.15,
+	"elevenlabs/eleven_turbo_v2_5": 0.15,
+	"openai":                       0.015,
+	"openai/tts-1-hd":              0.030,
+	"deepgram":                     0.030,
+	"azure":                        0.016,
+	"local":                        0,
+}
+
+// Estimate is what synthesizing a text would take, computed without API calls
+type Estimate struct {
+	Provider   config.TTSProvider
+	Model      string
+	Chunks     int
+	Characters int
+	PricePer1K float64 // USD per 1,000 characters
+	Cost       float64 // USD
+}
+
+// EstimateSpeech runs the same lexicon, dialogue, and chunking steps as
+// GenerateSpeechWithOptions and prices the resulting characters. A positive
+// pricePer1K overrides PricePer1KChars.
+func EstimateSpeech(text string, opts SpeechOptions, pricePer1K float64) Estimate {
+	var parts []string
+	if len(opts.Voices) > 0 {
+		for _, turn := range ParseDialogue(text, opts.Voices) {
+			parts = append(parts, turn.Text)
+		}
+	} else {
+		parts = []string{text}
+	}
+
+	e := Estimate{Provider: opts.Provider, Model: opts.model()}
+	for _, part := range parts {
+		part, _ = applyLexicon(part, opts.Lexicon)
+		for _, chunk := range opts.splitChunks(part) {
+			e.Chunks++
+			e.Characters += utf8.RuneCountInString(chunk)
+		}
+	}
+
+	e.PricePer1K = pricePer1K
+	if e.PricePer1K <= 0 {
+		price, ok := PricePer1KChars[string(opts.Provider)+"/"+e.Model]
+		if !ok {
+			price = PricePer1KChars[string(opts.Provider)]
+		}
+		e.PricePer1K = price
+	}
+	e.Cost = float64(e.Characters) / 1000 * e.PricePer1K
+	return e
+}
+
+// PrintEstimate writes an estimate as aligned label/value lines
+func PrintEstimate(w io.Writer, e Estimate) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	provider := string(e.Provider)
+	if e.Model != "" {
+		provider += " (" + e.Model + ")"
+	}
+	fmt.Fprintf(tw, "Provider:\t%s\n", provider)
+	fmt.Fprintf(tw, "Chunks:\t%d\n", e.Chunks)
+	fmt.Fprintf(tw, "Characters:\t%d\n", e.Characters)
+	fmt.Fprintf(tw, "Price:\t$%.4f per 1,000 characters\n", e.PricePer1K)
+	fmt.Fprintf(tw, "Estimated cost:\t$%.2f\n", e.Cost)
+	return tw.Flush()
 }
 
 // GenerateSpeech generates speech from text using the specified provider
 func GenerateSpeech(text, voiceID string, provider config.TTSProvider, cleanup *fileutil.CleanupManager, outputFilename string) (*TTSResult, error) {
+	opts := SpeechOptions{
+		Provider:    provider,
+		VoiceID:     voiceID,
+		Command:     os.Getenv("MMMELD_TTS_COMMAND"),
+		ChunkSize:   MaxChunkSize,
+		MaxAttempts: config.DefaultTTSMaxAttempts,
+	}
+	return GenerateSpeechWithOptions(text, opts, cleanup, outputFilename)
+}
+
+// GenerateSpeechWithOptions generates speech from text using the provider and settings in opts
+func GenerateSpeechWithOptions(text string, opts SpeechOptions, cleanup *fileutil.CleanupManager, outputFilename string) (*TTSResult, error) {
+	if err := config.ValidateSpeedAndRate(opts.Speed, opts.Rate); err != nil {
+		return nil, err
+	}
 	if err := fileutil.EnsureTempFolder(); err != nil {
 		return nil, fmt.Errorf("failed to create temp folder: %w", err)
 	}
 
-	chunks := SplitTextIntoChunks(text, MaxChunkSize)
+	if len(opts.Voices) > 0 {
+		return generateDialogue(text, opts, cleanup, outputFilename)
+	}
+
+	provider := opts.Provider
+	voiceID := opts.VoiceID
+
+	// Resolve the speaking rate once: either the provider handles it in the
+	// request, or each chunk is sped up with atempo before concatenation.
+	useAtempo := false
+	if opts.Rate > 0 && opts.Rate != 1 {
+		if supportsNativeRate(provider, opts.Rate) {
+			logx.Infof("Speech rate %.2f: applied natively by %s", opts.Rate, provider)
+		} else {
+			useAtempo = true
+			logx.Infof("Speech rate %.2f: %s has no native support for this rate, using ffmpeg atempo", opts.Rate, provider)
+		}
+	}
+	nativeRate := 0.0
+	if !useAtempo && opts.Rate != 1 {
+		nativeRate = opts.Rate
+	}
+
+	if err := ValidateFormat(opts.Format); err != nil {
+		return nil, err
+	}
+
+	// Ask the provider for the target format when it can produce it; otherwise
+	// request mp3 and convert each chunk with ffmpeg.
+	requestFormat := "mp3"
+	convertTo := ""
+	switch {
+	case provider == config.ProviderLocal:
+		if opts.Format != "" && opts.Format != "wav" {
+			convertTo = opts.Format
+		}
+	case opts.Format == "":
+	case nativeFormats[provider][opts.Format] != "":
+		requestFormat = opts.Format
+	default:
+		convertTo = opts.Format
+	}
+	deepgramParams := nativeFormats[config.ProviderDeepgram][requestFormat]
+	if provider == config.ProviderDeepgram && opts.DeepgramEncoding != "" {
+		if err := ValidateDeepgramEncoding(opts.DeepgramEncoding, opts.DeepgramSampleRate, opts.Format); err != nil {
+			return nil, err
+		}
+		requestFormat = deepgramEncodings[opts.DeepgramEncoding].format
+		convertTo = ""
+		deepgramParams = deepgramQuery(opts.DeepgramEncoding, opts.DeepgramSampleRate)
+	}
+	if convertTo != "" {
+		logx.Debugf("%s has no native %s output, converting with ffmpeg", provider, convertTo)
+	}
+
+	description := text
+	phonemes := lexiconPhonemes(opts.Lexicon)
+	if len(opts.Lexicon) > 0 {
+		var count int
+		text, count = applyLexicon(text, opts.Lexicon)
+		logx.Infof("Lexicon: applied %d substitution(s)", count)
+		if len(phonemes) > 0 && provider != config.ProviderAzure {
+			logx.Warnf("Lexicon: %s does not accept SSML, ignoring its %d IPA entries", provider, len(phonemes))
+			phonemes = nil
+		}
+	}
+
+	chunks := opts.splitChunks(text)
 	var audioFiles []string
 	var title string
 
-	log.Printf("Generating speech using %s with %d chunks", provider, len(chunks))
-
-	for i, chunk := range chunks {
-		log.Printf("Processing chunk %d/%d", i+1, len(chunks))
+	if model := opts.model(); model != "" {
+		logx.Infof("Generating speech using %s (model %s) with %d chunks", provider, model, len(chunks))
+	} else {
+		logx.Infof("Generating speech using %s with %d chunks", provider, len(chunks))
+	}
 
-		var audioFile string
-		var err error
+	// Set by ElevenLabs when timestamps are requested, reset for each chunk
+	var alignment *charAlignment
 
+	generateChunk := func(chunk string) (string, error) {
 		switch provider {
 		case config.ProviderElevenLabs:
-			audioFile, err = generateElevenLabsSpeech(chunk, voiceID, cleanup)
+			if opts.Timestamps {
+				alignment = &charAlignment{}
+				return generateElevenLabsSpeech(chunk, voiceID, nativeRate, requestFormat, opts.elevenLabsSettings(), alignment, cleanup)
+			}
+			return generateElevenLabsSpeech(chunk, voiceID, nativeRate, requestFormat, opts.elevenLabsSettings(), nil, cleanup)
 		case config.ProviderOpenAI:
-			audioFile, err = generateOpenAISpeech(chunk, voiceID, cleanup)
+			openAIOpts := opts
+			if openAIOpts.Speed == 0 {
+				openAIOpts.Speed = nativeRate
+			}
+			openAIOpts.Format = requestFormat
+			return generateOpenAISpeech(chunk, openAIOpts, cleanup)
 		case config.ProviderDeepgram:
-			audioFile, err = generateDeepgramSpeech(chunk, voiceID, cleanup)
+			return generateDeepgramSpeech(chunk, voiceID, deepgramParams, requestFormat, cleanup)
+		case config.ProviderAzure:
+			return generateAzureSpeech(chunk, voiceID, nativeRate, requestFormat, phonemes, cleanup)
+		case config.ProviderLocal:
+			return generateLocalSpeech(chunk, voiceID, opts.Command, cleanup)
 		default:
-			return nil, fmt.Errorf("unsupported TTS provider: %s", provider)
+			return "", fmt.Errorf("unsupported TTS provider: %s", provider)
 		}
+	}
+
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = config.DefaultTTSMaxAttempts
+	}
 
+	var cache *chunkCache
+	if !opts.NoCache {
+		var err error
+		cache, err = openChunkCache(opts.CacheDir, config.DefaultTTSCacheMaxSize)
 		if err != nil {
-			return nil, fmt.Errorf("failed to generate speech for chunk %d: %w", i+1, err)
+			logx.Warnf("TTS cache disabled: %v", err)
+		}
+	}
+	var segments []Segment
+	var chunkInfos []ChunkInfo
+	offset := 0.0
+	measured := true
+
+	for i, chunk := range chunks {
+		logx.Infof("Processing chunk %d/%d", i+1, len(chunks))
+		alignment = nil
+
+		key := chunkCacheKey(opts, requestFormat, nativeRate, chunk)
+		var audioFile string
+		cachedFile, hit := cache.lookup(key)
+		if hit {
+			logx.Infof("Using cached speech for chunk %d: %s", i+1, cachedFile)
+			var err error
+			if audioFile, err = copyCachedChunk(cachedFile, cleanup); err != nil {
+				logx.Warnf("Failed to reuse cached chunk %d: %v", i+1, err)
+				hit = false
+			}
+		}
+		if !hit {
+			var attempts int
+			var err error
+			audioFile, attempts, err = withRetry(maxAttempts, fmt.Sprintf("chunk %d/%d", i+1, len(chunks)), func() (string, error) {
+				return generateChunk(chunk)
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate speech for chunk %d after %d attempt(s): %w", i+1, attempts, err)
+			}
+			if err := cache.store(key, audioFile); err != nil {
+				logx.Warnf("Failed to cache chunk %d: %v", i+1, err)
+			}
+		}
+
+		var err error
+
+		if useAtempo {
+			audioFile, err = applyAtempo(audioFile, opts.Rate, cleanup)
+			if err != nil {
+				return nil, fmt.Errorf("failed to adjust speech rate for chunk %d: %w", i+1, err)
+			}
+		}
+
+		if convertTo != "" {
+			audioFile, err = convertAudio(audioFile, convertTo, cleanup)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert chunk %d to %s: %w", i+1, convertTo, err)
+			}
 		}
 
 		audioFiles = append(audioFiles, audioFile)
 
+		duration, err := probe.Duration(audioFile)
+		if err != nil {
+			if opts.Timestamps {
+				return nil, fmt.Errorf("failed to measure chunk %d for timestamps: %w", i+1, err)
+			}
+			logx.Warnf("Could not measure chunk %d: %v", i+1, err)
+			measured = false
+		}
+		chunkInfos = append(chunkInfos, ChunkInfo{Index: i, Text: chunk, Path: audioFile, Duration: duration})
+
+		if opts.Timestamps {
+			scale := 1.0
+			if useAtempo {
+				scale = 1 / opts.Rate
+			}
+			chunkSegments, ok := alignedSegments(chunk, offset, alignment, scale)
+			if !ok {
+				chunkSegments = proportionalSegments(chunk, offset, duration)
+			}
+			segments = append(segments, chunkSegments...)
+		}
+		offset += duration
+
 		if title == "" {
 			title = generateTitleFromText(chunk)
 		}
@@ -153,7 +967,7 @@ func GenerateSpeech(text, voiceID string, provider config.TTSProvider, cleanup *
 	var finalAudioPath string
 	if len(audioFiles) > 1 {
 		var err error
-		finalAudioPath, err = concatenateAudioFiles(audioFiles, cleanup)
+		finalAudioPath, err = concatenateAudioFiles(audioFiles, opts.chunkCodec(), cleanup)
 		if err != nil {
 			return nil, fmt.Errorf("failed to concatenate audio files: %w", err)
 		}
@@ -171,15 +985,29 @@ func GenerateSpeech(text, voiceID string, provider config.TTSProvider, cleanup *
 		}
 		finalAudioPath = customPath
 	}
+	if len(chunkInfos) == 1 {
+		chunkInfos[0].Path = finalAudioPath
+	}
+	if !measured {
+		offset = 0
+	}
 
 	return &TTSResult{
 		AudioPath:   finalAudioPath,
-		Title:       title,
-		Description: text,
+		Title:       resolveTitle(description, title, opts),
+		Description: description,
+		Segments:    segments,
+		Provider:    provider,
+		VoiceID:     voiceID,
+		Duration:    offset,
+		Chunks:      chunkInfos,
 	}, nil
 }
 
-func generateElevenLabsSpeech(text, voiceID string, cleanup *fileutil.CleanupManager) (string, error) {
+// generateElevenLabsSpeech streams audio for text. When alignment is non-nil the
+// with-timestamps endpoint is used instead and alignment receives the
+// per-character timings.
+func generateElevenLabsSpeech(text, voiceID string, rate float64, format string, settings ElevenLabsSettings, alignment *charAlignment, cleanup *fileutil.CleanupManager) (string, error) {
 	apiKey := os.Getenv("ELEVENLABS_API_KEY")
 	if apiKey == "" {
 		apiKey = os.Getenv("XI_API_KEY")
@@ -189,18 +1017,24 @@ func generateElevenLabsSpeech(text, voiceID string, cleanup *fileutil.CleanupMan
 	}
 
 	url := fmt.Sprintf("https://api.elevenlabs.io/v1/text-to-speech/%s/stream", voiceID)
+	if alignment != nil {
+		url = fmt.Sprintf("https://api.elevenlabs.io/v1/text-to-speech/%s/with-timestamps", voiceID)
+	}
 
 	requestBody := ElevenLabsRequest{
 		Text:         text,
-		ModelID:      config.ElevenLabsModelID,
-		OutputFormat: "mp3_44100_192",
+		ModelID:      settings.ModelID,
+		OutputFormat: nativeFormats[config.ProviderElevenLabs][format],
 		VoiceSettings: map[string]interface{}{
-			"stability":         0.5,
-			"similarity_boost":  0.8,
-			"style":             0.0,
-			"use_speaker_boost": true,
+			"stability":         settings.Stability,
+			"similarity_boost":  settings.SimilarityBoost,
+			"style":             settings.Style,
+			"use_speaker_boost": settings.SpeakerBoost,
 		},
 	}
+	if rate > 0 {
+		requestBody.VoiceSettings["speed"] = rate
+	}
 
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
@@ -212,11 +1046,13 @@ func generateElevenLabsSpeech(text, voiceID string, cleanup *fileutil.CleanupMan
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Accept", "audio/mpeg")
+	if format == "mp3" && alignment == nil {
+		req.Header.Set("Accept", "audio/mpeg")
+	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("xi-api-key", apiKey)
 
-	client := &http.Client{Timeout: 300 * time.Second}
+	client := httpx.Client(300 * time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to make request: %w", err)
@@ -224,11 +1060,27 @@ func generateElevenLabsSpeech(text, voiceID string, cleanup *fileutil.CleanupMan
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("ElevenLabs API error %d: %s", resp.StatusCode, string(body))
+		return "", newAPIError("ElevenLabs", resp)
+	}
+
+	var audio io.Reader = resp.Body
+	if alignment != nil {
+		var data struct {
+			AudioBase64 string        `json:"audio_base64"`
+			Alignment   charAlignment `json:"alignment"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+			return "", fmt.Errorf("failed to decode timestamped response: %w", err)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(data.AudioBase64)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode audio: %w", err)
+		}
+		*alignment = data.Alignment
+		audio = bytes.NewReader(decoded)
 	}
 
-	filename := fmt.Sprintf("elevenlabs_%d.mp3", time.Now().UnixNano())
+	filename := fmt.Sprintf("elevenlabs_%d.%s", time.Now().UnixNano(), format)
 	filepath := filepath.Join(config.TempAssetsFolder, filename)
 
 	file, err := os.Create(filepath)
@@ -237,18 +1089,26 @@ func generateElevenLabsSpeech(text, voiceID string, cleanup *fileutil.CleanupMan
 	}
 	defer file.Close()
 
-	_, err = io.Copy(file, resp.Body)
+	if format == "wav" {
+		pcm, err := io.ReadAll(audio)
+		if err != nil {
+			return "", fmt.Errorf("failed to read audio: %w", err)
+		}
+		err = writeWAV(file, pcm, 44100, 1)
+	} else {
+		_, err = io.Copy(file, audio)
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to save audio: %w", err)
 	}
 
 	cleanup.Add(filepath)
-	log.Printf("Generated ElevenLabs audio: %s", filepath)
+	logx.Debugf("Generated ElevenLabs audio: %s", filepath)
 
 	return filepath, nil
 }
 
-func generateOpenAISpeech(text, voiceID string, cleanup *fileutil.CleanupManager) (string, error) {
+func generateOpenAISpeech(text string, opts SpeechOptions, cleanup *fileutil.CleanupManager) (string, error) {
 	apiKey := os.Getenv("OPENAI_API_KEY")
 	if apiKey == "" {
 		return "", fmt.Errorf("OpenAI API key not found in environment")
@@ -256,10 +1116,21 @@ func generateOpenAISpeech(text, voiceID string, cleanup *fileutil.CleanupManager
 
 	url := "https://api.openai.com/v1/audio/speech"
 
+	model := opts.Model
+	if model == "" {
+		model = config.OpenAITTSModel
+	}
+	format := opts.Format
+	if format == "" {
+		format = "mp3"
+	}
+
 	requestBody := OpenAITTSRequest{
-		Model: "tts-1",
-		Input: text,
-		Voice: voiceID,
+		Model:          model,
+		Input:          text,
+		Voice:          opts.VoiceID,
+		ResponseFormat: nativeFormats[config.ProviderOpenAI][format],
+		Speed:          opts.Speed,
 	}
 
 	jsonData, err := json.Marshal(requestBody)
@@ -275,7 +1146,7 @@ func generateOpenAISpeech(text, voiceID string, cleanup *fileutil.CleanupManager
 	req.Header.Set("Authorization", "Bearer "+apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 300 * time.Second}
+	client := httpx.Client(300 * time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to make request: %w", err)
@@ -283,11 +1154,10 @@ func generateOpenAISpeech(text, voiceID string, cleanup *fileutil.CleanupManager
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("OpenAI API error %d: %s", resp.StatusCode, string(body))
+		return "", newAPIError("OpenAI", resp)
 	}
 
-	filename := fmt.Sprintf("openai_%d.mp3", time.Now().UnixNano())
+	filename := fmt.Sprintf("openai_%d.%s", time.Now().UnixNano(), format)
 	filepath := filepath.Join(config.TempAssetsFolder, filename)
 
 	file, err := os.Create(filepath)
@@ -302,18 +1172,21 @@ func generateOpenAISpeech(text, voiceID string, cleanup *fileutil.CleanupManager
 	}
 
 	cleanup.Add(filepath)
-	log.Printf("Generated OpenAI audio: %s", filepath)
+	logx.Debugf("Generated OpenAI audio: %s", filepath)
 
 	return filepath, nil
 }
 
-func generateDeepgramSpeech(text, voiceID string, cleanup *fileutil.CleanupManager) (string, error) {
+// generateDeepgramSpeech requests speech with the given encoding parameters
+// (e.g. "encoding=linear16&container=wav&sample_rate=8000") and saves it with
+// the format as its extension
+func generateDeepgramSpeech(text, voiceID, params, format string, cleanup *fileutil.CleanupManager) (string, error) {
 	apiKey := os.Getenv("DEEPGRAM_API_KEY")
 	if apiKey == "" {
 		return "", fmt.Errorf("Deepgram API key not found in environment")
 	}
 
-	url := fmt.Sprintf("https://api.deepgram.com/v1/speak?model=%s&encoding=mp3&sample_rate=44100", voiceID)
+	url := fmt.Sprintf("https://api.deepgram.com/v1/speak?model=%s&%s", voiceID, params)
 
 	requestBody := DeepgramTTSRequest{
 		Text: text,
@@ -332,7 +1205,7 @@ func generateDeepgramSpeech(text, voiceID string, cleanup *fileutil.CleanupManag
 	req.Header.Set("Authorization", "Token "+apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 300 * time.Second}
+	client := httpx.Client(300 * time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to make request: %w", err)
@@ -340,11 +1213,10 @@ func generateDeepgramSpeech(text, voiceID string, cleanup *fileutil.CleanupManag
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("Deepgram API error %d: %s", resp.StatusCode, string(body))
+		return "", newAPIError("Deepgram", resp)
 	}
 
-	filename := fmt.Sprintf("deepgram_%d.mp3", time.Now().UnixNano())
+	filename := fmt.Sprintf("deepgram_%d.%s", time.Now().UnixNano(), format)
 	filepath := filepath.Join(config.TempAssetsFolder, filename)
 
 	file, err := os.Create(filepath)
@@ -359,21 +1231,468 @@ func generateDeepgramSpeech(text, voiceID string, cleanup *fileutil.CleanupManag
 	}
 
 	cleanup.Add(filepath)
-	log.Printf("Generated Deepgram audio: %s", filepath)
+	logx.Debugf("Generated Deepgram audio: %s", filepath)
 
 	return filepath, nil
 }
 
-func concatenateAudioFiles(audioFiles []string, cleanup *fileutil.CleanupManager) (string, error) {
-	if len(audioFiles) == 0 {
-		return "", fmt.Errorf("no audio files to concatenate")
+func generateAzureSpeech(text, voiceID string, rate float64, format string, phonemes map[string]string, cleanup *fileutil.CleanupManager) (string, error) {
+	apiKey := os.Getenv("AZURE_SPEECH_KEY")
+	if apiKey == "" {
+		return "", fmt.Errorf("Azure Speech key not found in environment (AZURE_SPEECH_KEY)")
+	}
+	region := os.Getenv("AZURE_SPEECH_REGION")
+	if region == "" {
+		return "", fmt.Errorf("Azure Speech region not found in environment (AZURE_SPEECH_REGION)")
+	}
+
+	url := fmt.Sprintf("https://%s.tts.speech.microsoft.com/cognitiveservices/v1", region)
+
+	ssml, err := buildAzureSSML(text, voiceID, rate, phonemes)
+	if err != nil {
+		return "", fmt.Errorf("failed to build SSML: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", url, strings.NewReader(ssml))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Ocp-Apim-Subscription-Key", apiKey)
+	req.Header.Set("Content-Type", "application/ssml+xml")
+	req.Header.Set("X-Microsoft-OutputFormat", nativeFormats[config.ProviderAzure][format])
+	req.Header.Set("User-Agent", "mmmeld")
+
+	client := httpx.Client(300 * time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", newAPIError("Azure Speech", resp)
+	}
+
+	filename := fmt.Sprintf("azure_%d.%s", time.Now().UnixNano(), format)
+	filepath := filepath.Join(config.TempAssetsFolder, filename)
+
+	file, err := os.Create(filepath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create audio file: %w", err)
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to save audio: %w", err)
+	}
+
+	cleanup.Add(filepath)
+	logx.Debugf("Generated Azure audio: %s", filepath)
+
+	return filepath, nil
+}
+
+// buildAzureSSML wraps text in the SSML document the Azure TTS endpoint expects.
+// The xml:lang is taken from the voice name (en-US-JennyNeural -> en-US). A
+// non-zero rate other than 1 is expressed as a relative prosody rate, and
+// words in phonemes are wrapped in IPA <phoneme> tags.
+func buildAzureSSML(text, voiceName string, rate float64, phonemes map[string]string) (string, error) {
+	lang := "en-US"
+	if parts := strings.SplitN(voiceName, "-", 3); len(parts) == 3 {
+		lang = parts[0] + "-" + parts[1]
+	}
+
+	var escapedText, escapedVoice bytes.Buffer
+	last := 0
+	for _, m := range lexiconMatches(text, phonemes) {
+		if err := xml.EscapeText(&escapedText, []byte(text[last:m.start])); err != nil {
+			return "", err
+		}
+		word := text[m.start:m.end]
+		escapedText.WriteString(`<phoneme alphabet="ipa" ph="`)
+		if err := xml.EscapeText(&escapedText, []byte(phonemes[word])); err != nil {
+			return "", err
+		}
+		escapedText.WriteString(`">`)
+		if err := xml.EscapeText(&escapedText, []byte(word)); err != nil {
+			return "", err
+		}
+		escapedText.WriteString(`</phoneme>`)
+		last = m.end
+	}
+	if err := xml.EscapeText(&escapedText, []byte(text[last:])); err != nil {
+		return "", err
+	}
+	if err := xml.EscapeText(&escapedVoice, []byte(voiceName)); err != nil {
+		return "", err
+	}
+
+	body := escapedText.String()
+	if rate > 0 && rate != 1 {
+		body = fmt.Sprintf(`<prosody rate="%+.0f%%">%s</prosody>`, (rate-1)*100, body)
+	}
+
+	return fmt.Sprintf(`<speak version="1.0" xmlns="http://www.w3.org/2001/10/synthesis" xml:lang="%s"><voice name="%s">%s</voice></speak>`,
+		lang, escapedVoice.String(), body), nil
+}
+
+// lexiconMatch is a whole-word occurrence of a lexicon entry in text
+type lexiconMatch struct {
+	start, end int
+}
+
+// lexiconMatches finds case-sensitive, whole-word occurrences of the lexicon's
+// keys, preferring the longest key at each position. A key edge that is a
+// letter or digit must not touch another letter or digit, so "Go" does not
+// match inside "Google".
+func lexiconMatches(text string, lexicon map[string]string) []lexiconMatch {
+	if len(lexicon) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(lexicon))
+	for key := range lexicon {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+
+	isWordRune := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' }
+
+	var matches []lexiconMatch
+	for i := 0; i < len(text); {
+		matched := false
+		for _, key := range keys {
+			if !strings.HasPrefix(text[i:], key) {
+				continue
+			}
+			end := i + len(key)
+			first, _ := utf8.DecodeRuneInString(key)
+			lastRune, _ := utf8.DecodeLastRuneInString(key)
+			before, _ := utf8.DecodeLastRuneInString(text[:i])
+			after, _ := utf8.DecodeRuneInString(text[end:])
+			if i > 0 && isWordRune(first) && isWordRune(before) {
+				continue
+			}
+			if end < len(text) && isWordRune(lastRune) && isWordRune(after) {
+				continue
+			}
+			matches = append(matches, lexiconMatch{start: i, end: end})
+			i = end
+			matched = true
+			break
+		}
+		if !matched {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			i += size
+		}
+	}
+	return matches
+}
+
+// applyLexicon replaces whole-word occurrences of plain (non-IPA) lexicon
+// entries and returns the new text with the number of substitutions
+func applyLexicon(text string, lexicon map[string]string) (string, int) {
+	plain := make(map[string]string, len(lexicon))
+	for word, replacement := range lexicon {
+		if !strings.HasPrefix(replacement, "ipa:") {
+			plain[word] = replacement
+		}
+	}
+
+	matches := lexiconMatches(text, plain)
+	if len(matches) == 0 {
+		return text, 0
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(text[last:m.start])
+		b.WriteString(plain[text[m.start:m.end]])
+		last = m.end
+	}
+	b.WriteString(text[last:])
+	return b.String(), len(matches)
+}
+
+// lexiconPhonemes returns the lexicon's "ipa:" entries with the prefix removed
+func lexiconPhonemes(lexicon map[string]string) map[string]string {
+	var phonemes map[string]string
+	for word, replacement := range lexicon {
+		if ipa, ok := strings.CutPrefix(replacement, "ipa:"); ok {
+			if phonemes == nil {
+				phonemes = make(map[string]string)
+			}
+			phonemes[word] = strings.TrimSpace(ipa)
+		}
+	}
+	return phonemes
+}
+
+// deepgramKey identifies an explicit Deepgram encoding for cache keys
+func deepgramKey(opts SpeechOptions) string {
+	if opts.Provider != config.ProviderDeepgram || opts.DeepgramEncoding == "" {
+		return ""
+	}
+	return deepgramQuery(opts.DeepgramEncoding, opts.DeepgramSampleRate)
+}
+
+// phonemeKey serializes the IPA entries that change Azure's audio for cache keys
+func phonemeKey(opts SpeechOptions) string {
+	if opts.Provider != config.ProviderAzure {
+		return ""
+	}
+	phonemes := lexiconPhonemes(opts.Lexicon)
+	words := make([]string, 0, len(phonemes))
+	for word := range phonemes {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	var b strings.Builder
+	for _, word := range words {
+		fmt.Fprintf(&b, "%s=%s;", word, phonemes[word])
+	}
+	return b.String()
+}
+
+// generateLocalSpeech runs a user-supplied TTS command with the text on stdin.
+// The template may reference {out} (the output file) and {voice} (the voice ID);
+// without {out}, the command's stdout is taken as the audio.
+func generateLocalSpeech(text, voiceID, commandTemplate string, cleanup *fileutil.CleanupManager) (string, error) {
+	if strings.TrimSpace(commandTemplate) == "" {
+		return "", fmt.Errorf("local TTS requires a command template (--tts-command or MMMELD_TTS_COMMAND)")
+	}
+
+	filename := fmt.Sprintf("local_%03d.wav", localSpeechSeq.Add(1))
+	outputPath := fileutil.TempAssetPath(config.TempAssetsFolder, "", filename)
+
+	args, writesToFile, err := buildLocalCommand(commandTemplate, outputPath, voiceID)
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	var stdoutFile *os.File
+	if !writesToFile {
+		stdoutFile, err = os.Create(outputPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to create audio file: %w", err)
+		}
+		cmd.Stdout = stdoutFile
+	}
+
+	cleanup.Add(outputPath)
+	logx.Debugf("Running local TTS: %s", strings.Join(args, " "))
+	err = cmd.Run()
+	if stdoutFile != nil {
+		stdoutFile.Close()
+	}
+	if err != nil {
+		return "", fmt.Errorf("local TTS command failed: %w\nStderr: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	if info, err := os.Stat(outputPath); err != nil || info.Size() == 0 {
+		return "", fmt.Errorf("local TTS command produced no audio at %s\nStderr: %s", outputPath, strings.TrimSpace(stderr.String()))
+	}
+
+	// Commands are free to write MP3, Ogg or FLAC; name the file after what
+	// they actually produced so later steps pick the right demuxer
+	if renamed := sniffedAudioPath(outputPath); renamed != outputPath {
+		if err := os.Rename(outputPath, renamed); err != nil {
+			return "", fmt.Errorf("failed to rename local audio: %w", err)
+		}
+		cleanup.Remove(outputPath)
+		cleanup.Add(renamed)
+		outputPath = renamed
+	}
+
+	logx.Debugf("Generated local audio: %s", outputPath)
+	return outputPath, nil
+}
+
+// localSpeechSeq numbers the local TTS outputs written by this process
+var localSpeechSeq atomic.Int64
+
+// sniffedAudioPath returns path with the extension matching its contents, or
+// path unchanged when the format is unrecognized
+func sniffedAudioPath(path string) string {
+	head := make([]byte, 12)
+	if f, err := os.Open(path); err == nil {
+		n, _ := io.ReadFull(f, head)
+		head = head[:n]
+		f.Close()
+	}
+	format := fileutil.SniffAudioFormat(head)
+	if format == "" || strings.EqualFold(filepath.Ext(path), "."+format) {
+		return path
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path)) + "." + format
+}
+
+// buildLocalCommand splits a command template into arguments and substitutes
+// placeholders. It reports whether the template names an output file.
+func buildLocalCommand(template, outputPath, voiceID string) ([]string, bool, error) {
+	args, err := splitCommandLine(template)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(args) == 0 {
+		return nil, false, fmt.Errorf("empty TTS command template")
+	}
+
+	writesToFile := false
+	for i, arg := range args {
+		if strings.Contains(arg, "{out}") {
+			writesToFile = true
+		}
+		arg = strings.ReplaceAll(arg, "{out}", outputPath)
+		arg = strings.ReplaceAll(arg, "{voice}", voiceID)
+		args[i] = arg
+	}
+	return args, writesToFile, nil
+}
+
+// splitCommandLine splits a command line on whitespace, honoring single and double quotes
+func splitCommandLine(s string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	inArg := false
+
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in TTS command template")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// applyAtempo re-encodes an audio file at the given speaking rate, keeping pitch
+func applyAtempo(inputPath string, rate float64, cleanup *fileutil.CleanupManager) (string, error) {
+	ext := filepath.Ext(inputPath)
+	outputPath := filepath.Join(config.TempAssetsFolder, fmt.Sprintf("atempo_%d%s", time.Now().UnixNano(), ext))
+
+	cmd := exec.Command("ffmpeg", "-v", "error", "-i", inputPath, "-filter:a", atempoFilter(rate), "-y", outputPath)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("ffmpeg atempo failed: %w\nOutput: %s", err, output)
+	}
+
+	cleanup.Add(outputPath)
+	return outputPath, nil
+}
+
+// atempoFilter builds an atempo filter chain for rate. Each stage is kept
+// within 0.5-2.0, which every ffmpeg version accepts.
+func atempoFilter(rate float64) string {
+	var stages []string
+	for rate > 2.0 {
+		stages = append(stages, "atempo=2.0")
+		rate /= 2.0
+	}
+	for rate < 0.5 {
+		stages = append(stages, "atempo=0.5")
+		rate /= 0.5
+	}
+	stages = append(stages, fmt.Sprintf("atempo=%.4f", rate))
+	return strings.Join(stages, ",")
+}
+
+// convertAudio re-encodes an audio file into format, returning the new path
+func convertAudio(inputPath, format string, cleanup *fileutil.CleanupManager) (string, error) {
+	outputPath := filepath.Join(config.TempAssetsFolder, fmt.Sprintf("converted_%d.%s", time.Now().UnixNano(), format))
+
+	cmd := exec.Command("ffmpeg", "-v", "error", "-i", inputPath, "-c:a", formatCodecs[format], "-y", outputPath)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("ffmpeg conversion failed: %w\nOutput: %s", err, output)
+	}
+
+	cleanup.Add(outputPath)
+	return outputPath, nil
+}
+
+// writeWAV writes 16-bit little-endian PCM samples with a RIFF/WAVE header
+func writeWAV(w io.Writer, pcm []byte, sampleRate, channels int) error {
+	const bitsPerSample = 16
+	blockAlign := channels * bitsPerSample / 8
+
+	header := []interface{}{
+		[4]byte{'R', 'I', 'F', 'F'},
+		uint32(36 + len(pcm)),
+		[4]byte{'W', 'A', 'V', 'E'},
+		[4]byte{'f', 'm', 't', ' '},
+		uint32(16), // fmt chunk size
+		uint16(1),  // PCM
+		uint16(channels),
+		uint32(sampleRate),
+		uint32(sampleRate * blockAlign),
+		uint16(blockAlign),
+		uint16(bitsPerSample),
+		[4]byte{'d', 'a', 't', 'a'},
+		uint32(len(pcm)),
+	}
+	for _, field := range header {
+		if err := binary.Write(w, binary.LittleEndian, field); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(pcm)
+	return err
+}
+
+func concatenateAudioFiles(audioFiles []string, codec string, cleanup *fileutil.CleanupManager) (string, error) {
+	if len(audioFiles) == 0 {
+		return "", fmt.Errorf("no audio files to concatenate")
 	}
 
 	if len(audioFiles) == 1 {
 		return audioFiles[0], nil
 	}
 
-	outputPath := filepath.Join(config.TempAssetsFolder, fmt.Sprintf("concatenated_%d.mp3", time.Now().UnixNano()))
+	// Keep the chunks' container so stream copy works (e.g. WAV from local engines)
+	ext := filepath.Ext(audioFiles[0])
+	if ext == "" {
+		ext = ".mp3"
+	}
+	outputPath := filepath.Join(config.TempAssetsFolder, fmt.Sprintf("concatenated_%d%s", time.Now().UnixNano(), ext))
 
 	// Create a temporary file list for ffmpeg concat
 	listFile := filepath.Join(config.TempAssetsFolder, fmt.Sprintf("concat_list_%d.txt", time.Now().UnixNano()))
@@ -393,14 +1712,27 @@ func concatenateAudioFiles(audioFiles []string, cleanup *fileutil.CleanupManager
 	}
 	defer os.Remove(listFile)
 
-	cmd := exec.Command("ffmpeg", "-f", "concat", "-safe", "0", "-i", listFile, "-c", "copy", outputPath)
+	// mp3 frames can be stream-copied; PCM and other containers carry per-file
+	// headers (and chunks may differ after atempo/conversion), so re-encode.
+	args := []string{"-f", "concat", "-safe", "0", "-i", listFile}
+	if codec == "" && ext != ".mp3" {
+		codec = formatCodecs[strings.TrimPrefix(ext, ".")]
+	}
+	if codec != "" {
+		args = append(args, "-c:a", codec)
+	} else {
+		args = append(args, "-c", "copy")
+	}
+	args = append(args, outputPath)
+
+	cmd := exec.Command("ffmpeg", args...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return "", fmt.Errorf("ffmpeg concat failed: %w\nOutput: %s", err, output)
 	}
 
 	cleanup.Add(outputPath)
-	log.Printf("Concatenated %d audio files to: %s", len(audioFiles), outputPath)
+	logx.Debugf("Concatenated %d audio files to: %s", len(audioFiles), outputPath)
 
 	return outputPath, nil
 }
@@ -423,6 +1755,560 @@ func generateTitleFromText(text string) string {
 	return text
 }
 
+// sentenceSpan is a sentence and its rune offsets within the source text
+type sentenceSpan struct {
+	text       string
+	start, end int // Rune offsets, end exclusive
+}
+
+// splitSentenceSpans finds the sentences in text along with their rune offsets
+func splitSentenceSpans(text string) []sentenceSpan {
+	runes := []rune(text)
+	var spans []sentenceSpan
+	prev := 0
+	for _, end := range sentenceEnds(text) {
+		start := prev
+		prev = end
+		for start < end && unicode.IsSpace(runes[start]) {
+			start++
+		}
+		stop := end
+		for stop > start && unicode.IsSpace(runes[stop-1]) {
+			stop--
+		}
+		if start == stop {
+			continue
+		}
+		spans = append(spans, sentenceSpan{text: string(runes[start:stop]), start: start, end: stop})
+	}
+	return spans
+}
+
+// proportionalSegments spreads duration over the sentences of text by length
+func proportionalSegments(text string, offset, duration float64) []Segment {
+	spans := splitSentenceSpans(text)
+	total := 0
+	for _, span := range spans {
+		total += span.end - span.start
+	}
+	if total == 0 {
+		return nil
+	}
+
+	segments := make([]Segment, 0, len(spans))
+	t := offset
+	for _, span := range spans {
+		length := duration * float64(span.end-span.start) / float64(total)
+		segments = append(segments, Segment{Text: span.text, Start: t, End: t + length})
+		t += length
+	}
+	return segments
+}
+
+// alignedSegments builds sentence timings from per-character timings. It
+// returns false when the alignment is missing or does not match the text.
+func alignedSegments(text string, offset float64, a *charAlignment, scale float64) ([]Segment, bool) {
+	if a == nil {
+		return nil, false
+	}
+	runes := len([]rune(text))
+	if len(a.Characters) != runes || len(a.Starts) != runes || len(a.Ends) != runes {
+		return nil, false
+	}
+
+	spans := splitSentenceSpans(text)
+	segments := make([]Segment, 0, len(spans))
+	for _, span := range spans {
+		segments = append(segments, Segment{
+			Text:  span.text,
+			Start: offset + a.Starts[span.start]*scale,
+			End:   offset + a.Ends[span.end-1]*scale,
+		})
+	}
+	return segments, true
+}
+
+// chunkCache stores generated chunks on disk so identical requests are not
+// sent to the provider again. A nil cache is valid and never hits.
+type chunkCache struct {
+	dir     string
+	maxSize int64
+}
+
+// DefaultCacheDir returns the default chunk cache location (~/.cache/mmmeld/tts on Linux)
+func DefaultCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "mmmeld", "tts"), nil
+}
+
+func openChunkCache(dir string, maxSize int64) (*chunkCache, error) {
+	if dir == "" {
+		var err error
+		dir, err = DefaultCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate cache directory: %w", err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &chunkCache{dir: dir, maxSize: maxSize}, nil
+}
+
+// chunkCacheKey hashes everything that influences a chunk's audio
+func chunkCacheKey(opts SpeechOptions, requestFormat string, nativeRate float64, chunk string) string {
+	model := opts.model()
+	if opts.Provider == config.ProviderElevenLabs {
+		settings := opts.elevenLabsSettings()
+		model = fmt.Sprintf("%s|%g|%g|%g|%t", settings.ModelID,
+			settings.Stability, settings.SimilarityBoost, settings.Style, settings.SpeakerBoost)
+	}
+
+	h := sha256.New()
+	for _, part := range []string{
+		string(opts.Provider),
+		opts.VoiceID,
+		model,
+		requestFormat,
+		strconv.FormatFloat(opts.Speed, 'f', -1, 64),
+		strconv.FormatFloat(nativeRate, 'f', -1, 64),
+		deepgramKey(opts),
+		opts.Command,
+		phonemeKey(opts),
+		chunk,
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// lookup returns the cached file for key, refreshing its modification time so
+// eviction removes the least recently used entries first.
+func (c *chunkCache) lookup(key string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	matches, _ := filepath.Glob(filepath.Join(c.dir, key+".*"))
+	for _, path := range matches {
+		if strings.HasSuffix(path, ".tmp") {
+			continue
+		}
+		now := time.Now()
+		os.Chtimes(path, now, now)
+		return path, true
+	}
+	return "", false
+}
+
+// cachedChunkSeq numbers the cached chunks copied by this process
+var cachedChunkSeq atomic.Int64
+
+// copyCachedChunk links or copies a cached chunk into the temp folder, so
+// another run's eviction cannot delete it while this run still needs it
+func copyCachedChunk(cachedPath string, cleanup *fileutil.CleanupManager) (string, error) {
+	name := fmt.Sprintf("cached_%03d%s", cachedChunkSeq.Add(1), filepath.Ext(cachedPath))
+	outputPath := fileutil.TempAssetPath(config.TempAssetsFolder, "", name)
+	if err := os.Link(cachedPath, outputPath); err != nil {
+		if err := fileutil.CopyFile(cachedPath, outputPath); err != nil {
+			os.Remove(outputPath)
+			return "", err
+		}
+	}
+	cleanup.Add(outputPath)
+	return outputPath, nil
+}
+
+// store copies a generated chunk into the cache and evicts old entries. The
+// copy is written to a temp file of its own and renamed into place, so runs
+// storing the same key at once never interleave their writes.
+func (c *chunkCache) store(key, path string) error {
+	if c == nil {
+		return nil
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(c.dir, key+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(c.dir, key+filepath.Ext(path)))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	_, _, err = fileutil.PruneCache(c.dir, c.maxSize, 0, "speech")
+	return err
+}
+
+// generateDialogue synthesizes each turn of a dialogue script with its
+// speaker's voice and joins the turns in order, padding all but the last
+// with opts.DialoguePause seconds of silence.
+func generateDialogue(text string, opts SpeechOptions, cleanup *fileutil.CleanupManager, outputFilename string) (*TTSResult, error) {
+	turns := ParseDialogue(text, opts.Voices)
+	if len(turns) == 0 {
+		return nil, fmt.Errorf("dialogue script contains no text")
+	}
+	logx.Infof("Generating dialogue with %d turns", len(turns))
+
+	turnOpts := opts
+	turnOpts.Voices = nil
+	turnOpts.NoLLMTitle = true // Titled once below from the whole script
+
+	if len(turns) == 1 {
+		if turns[0].Speaker != "" {
+			turnOpts.VoiceID = opts.Voices[turns[0].Speaker]
+		}
+		result, err := GenerateSpeechWithOptions(turns[0].Text, turnOpts, cleanup, outputFilename)
+		if err != nil {
+			return nil, err
+		}
+		result.Description = text
+		result.Title = resolveTitle(turns[0].Text, result.Title, opts)
+		if voices := dialogueVoices(turns, opts.Voices); voices != "" {
+			result.VoiceID = voices
+		}
+		return result, nil
+	}
+
+	var turnFiles []string
+	var segments []Segment
+	var chunkInfos []ChunkInfo
+	offset := 0.0
+	measured := true
+	for i, turn := range turns {
+		turnOpts.VoiceID = opts.VoiceID
+		if turn.Speaker != "" {
+			turnOpts.VoiceID = opts.Voices[turn.Speaker]
+		}
+		logx.Infof("Dialogue turn %d/%d (%s)", i+1, len(turns), turn.Speaker)
+
+		result, err := GenerateSpeechWithOptions(turn.Text, turnOpts, cleanup, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate dialogue turn %d (%s): %w", i+1, turn.Speaker, err)
+		}
+
+		path := result.AudioPath
+		if opts.DialoguePause > 0 && i < len(turns)-1 {
+			path, err = padAudio(path, opts.DialoguePause, opts.chunkCodec(), cleanup)
+			if err != nil {
+				return nil, fmt.Errorf("failed to add pause after dialogue turn %d: %w", i+1, err)
+			}
+		}
+		turnFiles = append(turnFiles, path)
+
+		for _, chunk := range result.Chunks {
+			chunk.Index = len(chunkInfos)
+			chunkInfos = append(chunkInfos, chunk)
+		}
+		for _, seg := range result.Segments {
+			segments = append(segments, Segment{Text: seg.Text, Start: seg.Start + offset, End: seg.End + offset})
+		}
+
+		// Includes the pause, so later turns' timings line up
+		duration, err := probe.Duration(path)
+		if err != nil {
+			if opts.Timestamps {
+				return nil, fmt.Errorf("failed to measure dialogue turn %d for timestamps: %w", i+1, err)
+			}
+			logx.Warnf("Could not measure dialogue turn %d: %v", i+1, err)
+			measured = false
+		}
+		offset += duration
+	}
+	if !measured {
+		offset = 0
+	}
+
+	finalAudioPath, err := concatenateAudioFiles(turnFiles, opts.chunkCodec(), cleanup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to concatenate dialogue turns: %w", err)
+	}
+
+	if outputFilename != "" {
+		customPath := strings.TrimSuffix(outputFilename, filepath.Ext(outputFilename)) + filepath.Ext(finalAudioPath)
+		if err := os.Rename(finalAudioPath, customPath); err != nil {
+			return nil, fmt.Errorf("failed to rename output file: %w", err)
+		}
+		finalAudioPath = customPath
+	}
+
+	return &TTSResult{
+		AudioPath:   finalAudioPath,
+		Title:       resolveTitle(dialogueText(turns), generateTitleFromText(turns[0].Text), opts),
+		Description: text,
+		Segments:    segments,
+		Provider:    opts.Provider,
+		VoiceID:     dialogueVoices(turns, opts.Voices),
+		Duration:    offset,
+		Chunks:      chunkInfos,
+	}, nil
+}
+
+// dialogueVoices lists the SPEAKER=voice pairs of the speakers in turns, in
+// order of first appearance
+func dialogueVoices(turns []DialogueTurn, voices map[string]string) string {
+	seen := make(map[string]bool)
+	var pairs []string
+	for _, turn := range turns {
+		if turn.Speaker == "" || seen[turn.Speaker] {
+			continue
+		}
+		seen[turn.Speaker] = true
+		pairs = append(pairs, turn.Speaker+"="+voices[turn.Speaker])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// dialogueText joins the turns' text without speaker tags
+func dialogueText(turns []DialogueTurn) string {
+	lines := make([]string, len(turns))
+	for i, turn := range turns {
+		lines[i] = turn.Text
+	}
+	return strings.Join(lines, "\n")
+}
+
+// generateLLMTitle is a variable so tests can avoid network calls
+var generateLLMTitle = genai.GenerateTitle
+
+// resolveTitle asks an LLM for a title unless opts.NoLLMTitle is set, keeping
+// the heuristic title when no key is configured or the request fails
+func resolveTitle(text, heuristic string, opts SpeechOptions) string {
+	if opts.NoLLMTitle {
+		return heuristic
+	}
+	if opts.GeminiKey == "" && os.Getenv("GEMINI_API_KEY") == "" && os.Getenv("OPENAI_API_KEY") == "" {
+		return heuristic
+	}
+
+	title, err := generateLLMTitle(text, genai.ClientOptions{APIKey: opts.GeminiKey})
+	if err != nil {
+		logx.Warnf("LLM title generation failed, using %q: %v", heuristic, err)
+		return heuristic
+	}
+	logx.Infof("Generated title: %s", title)
+	return title
+}
+
+// padAudio appends seconds of silence to an audio file, keeping its format
+func padAudio(inputPath string, seconds float64, codec string, cleanup *fileutil.CleanupManager) (string, error) {
+	ext := filepath.Ext(inputPath)
+	outputPath := filepath.Join(config.TempAssetsFolder, fmt.Sprintf("padded_%d%s", time.Now().UnixNano(), ext))
+
+	args := []string{"-v", "error", "-i", inputPath, "-af", fmt.Sprintf("apad=pad_dur=%.3f", seconds)}
+	if codec == "" {
+		codec = formatCodecs[strings.TrimPrefix(ext, ".")]
+	}
+	if codec != "" {
+		args = append(args, "-c:a", codec)
+	}
+	args = append(args, "-y", outputPath)
+
+	cmd := exec.Command("ffmpeg", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("ffmpeg apad failed: %w\nOutput: %s", err, output)
+	}
+
+	cleanup.Add(outputPath)
+	return outputPath, nil
+}
+
+// Voice describes a voice offered by a TTS provider
+type Voice struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Language string `json:"language,omitempty"`
+	Gender   string `json:"gender,omitempty"`
+}
+
+// openAIVoices is OpenAI's fixed voice list; the API has no voices endpoint
+var openAIVoices = []Voice{
+	{ID: "alloy", Name: "Alloy", Language: "multilingual", Gender: "neutral"},
+	{ID: "ash", Name: "Ash", Language: "multilingual", Gender: "male"},
+	{ID: "ballad", Name: "Ballad", Language: "multilingual", Gender: "male"},
+	{ID: "coral", Name: "Coral", Language: "multilingual", Gender: "female"},
+	{ID: "echo", Name: "Echo", Language: "multilingual", Gender: "male"},
+	{ID: "fable", Name: "Fable", Language: "multilingual", Gender: "male"},
+	{ID: "nova", Name: "Nova", Language: "multilingual", Gender: "female"},
+	{ID: "onyx", Name: "Onyx", Language: "multilingual", Gender: "male"},
+	{ID: "sage", Name: "Sage", Language: "multilingual", Gender: "female"},
+	{ID: "shimmer", Name: "Shimmer", Language: "multilingual", Gender: "female"},
+}
+
+// ListVoices returns the voices available from a TTS provider, sorted by ID
+func ListVoices(provider config.TTSProvider) ([]Voice, error) {
+	var voices []Voice
+	var err error
+
+	switch provider {
+	case config.ProviderElevenLabs:
+		voices, err = listElevenLabsVoices()
+	case config.ProviderOpenAI:
+		voices = append([]Voice(nil), openAIVoices...)
+	case config.ProviderDeepgram:
+		voices, err = listDeepgramVoices()
+	case config.ProviderAzure:
+		voices, err = listAzureVoices()
+	case config.ProviderLocal:
+		return nil, fmt.Errorf("the local provider has no voice list; voices are configured in --tts-command")
+	default:
+		return nil, fmt.Errorf("unsupported TTS provider: %s", provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(voices, func(i, j int) bool { return voices[i].ID < voices[j].ID })
+	return voices, nil
+}
+
+// PrintVoices writes voices as an aligned table
+func PrintVoices(w io.Writer, voices []Voice) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tLANGUAGE\tGENDER")
+	for _, v := range voices {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", v.ID, v.Name, v.Language, v.Gender)
+	}
+	return tw.Flush()
+}
+
+// getVoicesJSON performs an authenticated GET and decodes the JSON response into out
+func getVoicesJSON(url string, headers map[string]string, providerName string, out interface{}) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	client := httpx.Client(60 * time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(providerName, resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s voices: %w", providerName, err)
+	}
+	return nil
+}
+
+func listElevenLabsVoices() ([]Voice, error) {
+	apiKey := os.Getenv("ELEVENLABS_API_KEY")
+	if apiKey == "" {
+		apiKey = os.Getenv("XI_API_KEY")
+	}
+	if apiKey == "" {
+		return nil, fmt.Errorf("ElevenLabs API key not found in environment")
+	}
+
+	var data struct {
+		Voices []struct {
+			VoiceID string            `json:"voice_id"`
+			Name    string            `json:"name"`
+			Labels  map[string]string `json:"labels"`
+		} `json:"voices"`
+	}
+	if err := getVoicesJSON("https://api.elevenlabs.io/v1/voices", map[string]string{"xi-api-key": apiKey}, "ElevenLabs", &data); err != nil {
+		return nil, err
+	}
+
+	voices := make([]Voice, 0, len(data.Voices))
+	for _, v := range data.Voices {
+		language := v.Labels["language"]
+		if language == "" {
+			language = v.Labels["accent"]
+		}
+		voices = append(voices, Voice{ID: v.VoiceID, Name: v.Name, Language: language, Gender: v.Labels["gender"]})
+	}
+	return voices, nil
+}
+
+func listDeepgramVoices() ([]Voice, error) {
+	apiKey := os.Getenv("DEEPGRAM_API_KEY")
+	if apiKey == "" {
+		return nil, fmt.Errorf("Deepgram API key not found in environment")
+	}
+
+	var data struct {
+		TTS []struct {
+			Name          string   `json:"name"`
+			CanonicalName string   `json:"canonical_name"`
+			Languages     []string `json:"languages"`
+			Metadata      struct {
+				Tags []string `json:"tags"`
+			} `json:"metadata"`
+		} `json:"tts"`
+	}
+	if err := getVoicesJSON("https://api.deepgram.com/v1/models", map[string]string{"Authorization": "Token " + apiKey}, "Deepgram", &data); err != nil {
+		return nil, err
+	}
+
+	voices := make([]Voice, 0, len(data.TTS))
+	for _, m := range data.TTS {
+		voice := Voice{ID: m.CanonicalName, Name: m.Name, Language: strings.Join(m.Languages, ", ")}
+		for _, tag := range m.Metadata.Tags {
+			switch strings.ToLower(tag) {
+			case "masculine", "male":
+				voice.Gender = "male"
+			case "feminine", "female":
+				voice.Gender = "female"
+			}
+		}
+		voices = append(voices, voice)
+	}
+	return voices, nil
+}
+
+func listAzureVoices() ([]Voice, error) {
+	apiKey := os.Getenv("AZURE_SPEECH_KEY")
+	if apiKey == "" {
+		return nil, fmt.Errorf("Azure Speech key not found in environment (AZURE_SPEECH_KEY)")
+	}
+	region := os.Getenv("AZURE_SPEECH_REGION")
+	if region == "" {
+		return nil, fmt.Errorf("Azure Speech region not found in environment (AZURE_SPEECH_REGION)")
+	}
+
+	var data []struct {
+		ShortName   string `json:"ShortName"`
+		DisplayName string `json:"DisplayName"`
+		Locale      string `json:"Locale"`
+		Gender      string `json:"Gender"`
+	}
+	url := fmt.Sprintf("https://%s.tts.speech.microsoft.com/cognitiveservices/voices/list", region)
+	if err := getVoicesJSON(url, map[string]string{"Ocp-Apim-Subscription-Key": apiKey}, "Azure Speech", &data); err != nil {
+		return nil, err
+	}
+
+	voices := make([]Voice, 0, len(data))
+	for _, v := range data {
+		voices = append(voices, Voice{ID: v.ShortName, Name: v.DisplayName, Language: v.Locale, Gender: strings.ToLower(v.Gender)})
+	}
+	return voices, nil
+}
+
 // IsValidAudioFile checks if a file is valid audio using ffmpeg
 func IsValidAudioFile(filepath string) bool {
 	cmd := exec.Command("ffmpeg", "-v", "error", "-i", filepath, "-f", "null", "-")
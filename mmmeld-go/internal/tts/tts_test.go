@@ -0,0 +1,904 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"mmmeld/internal/config"
+	"mmmeld/internal/fileutil"
+	"mmmeld/internal/genai"
+	"mmmeld/internal/retry"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    []string
+		expectError bool
+	}{
+		{"piper --model en_US-amy --output_file {out}", []string{"piper", "--model", "en_US-amy", "--output_file", "{out}"}, false},
+		{`say -v "Samantha Enhanced" -o {out}`, []string{"say", "-v", "Samantha Enhanced", "-o", "{out}"}, false},
+		{`tool --arg '' next`, []string{"tool", "--arg", "", "next"}, false},
+		{"  spaced   out  ", []string{"spaced", "out"}, false},
+		{`broken "quote`, nil, true},
+	}
+
+	for _, test := range tests {
+		result, err := splitCommandLine(test.input)
+		if test.expectError {
+			if err == nil {
+				t.Errorf("splitCommandLine(%q) expected error", test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("splitCommandLine(%q) unexpected error: %v", test.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("splitCommandLine(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestBuildLocalCommand(t *testing.T) {
+	args, writesToFile, err := buildLocalCommand("piper --model {voice} --output_file {out}", "temp_assets/x.wav", "en_US-amy")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"piper", "--model", "en_US-amy", "--output_file", "temp_assets/x.wav"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected %q, got %q", expected, args)
+	}
+	if !writesToFile {
+		t.Error("Expected writesToFile for a template containing {out}")
+	}
+
+	_, writesToFile, err = buildLocalCommand("espeak --stdout", "temp_assets/x.wav", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if writesToFile {
+		t.Error("Expected stdout mode for a template without {out}")
+	}
+
+	if _, _, err := buildLocalCommand("   ", "out.wav", ""); err == nil {
+		t.Error("Expected error for empty template")
+	}
+}
+
+func TestGenerateLocalSpeechFromStdout(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat not available")
+	}
+	if err := fileutil.EnsureTempFolder(); err != nil {
+		t.Fatalf("Failed to create temp folder: %v", err)
+	}
+
+	cleanup := fileutil.NewCleanupManager()
+	defer cleanup.Cleanup()
+
+	path, err := generateLocalSpeech("hello from stdin", "", "cat", cleanup)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if string(data) != "hello from stdin" {
+		t.Errorf("Expected stdin to be echoed into the output, got %q", data)
+	}
+}
+
+func TestGenerateLocalSpeechNamesSniffedFormat(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat not available")
+	}
+	if err := fileutil.EnsureTempFolder(); err != nil {
+		t.Fatalf("Failed to create temp folder: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		audio     string
+		expectExt string
+	}{
+		{"wav", "RIFF\x24\x00\x00\x00WAVEfmt ", ".wav"},
+		{"mp3", "ID3\x04\x00\x00\x00\x00\x00\x00", ".mp3"},
+		{"ogg", "OggS\x00\x02\x00\x00\x00\x00\x00\x00", ".ogg"},
+		{"unrecognized", "raw pcm samples", ".wav"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cleanup := fileutil.NewCleanupManager()
+			defer cleanup.Cleanup()
+
+			path, err := generateLocalSpeech(test.audio, "", "cat", cleanup)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if filepath.Ext(path) != test.expectExt {
+				t.Errorf("Expected a %s file, got %s", test.expectExt, path)
+			}
+			if filepath.Dir(path) != config.TempAssetsFolder || !strings.Contains(filepath.Base(path), "_local_") {
+				t.Errorf("Expected a run-scoped temp asset name, got %s", path)
+			}
+			if data, err := os.ReadFile(path); err != nil || string(data) != test.audio {
+				t.Errorf("Expected the command output at %s, got %q, %v", path, data, err)
+			}
+		})
+	}
+}
+
+func TestGenerateLocalSpeechReportsStderr(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	if err := fileutil.EnsureTempFolder(); err != nil {
+		t.Fatalf("Failed to create temp folder: %v", err)
+	}
+
+	cleanup := fileutil.NewCleanupManager()
+	defer cleanup.Cleanup()
+
+	_, err := generateLocalSpeech("text", "", `sh -c "echo model missing >&2; exit 3"`, cleanup)
+	if err == nil {
+		t.Fatal("Expected error from failing command")
+	}
+	if !strings.Contains(err.Error(), "model missing") {
+		t.Errorf("Expected stderr in error, got: %v", err)
+	}
+}
+
+func TestBuildAzureSSML(t *testing.T) {
+	ssml, err := buildAzureSSML("Fish & <chips>", "de-DE-KatjaNeural", 0, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(ssml, `xml:lang="de-DE"`) {
+		t.Errorf("Expected language from voice name, got: %s", ssml)
+	}
+	if !strings.Contains(ssml, `<voice name="de-DE-KatjaNeural">`) {
+		t.Errorf("Expected voice element, got: %s", ssml)
+	}
+	if !strings.Contains(ssml, "Fish &amp; &lt;chips&gt;") {
+		t.Errorf("Expected escaped text, got: %s", ssml)
+	}
+	if strings.Contains(ssml, "prosody") {
+		t.Errorf("Expected no prosody element without a rate, got: %s", ssml)
+	}
+
+	ssml, err = buildAzureSSML("Hello", "en-US-JennyNeural", 1.15, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(ssml, `<prosody rate="+15%">Hello</prosody>`) {
+		t.Errorf("Expected +15%% prosody rate, got: %s", ssml)
+	}
+}
+
+func TestBuildAzureSSMLPhonemes(t *testing.T) {
+	ssml, err := buildAzureSSML("Shalom & welcome, Shaloms", "en-US-JennyNeural", 0, map[string]string{"Shalom": "ʃaˈlom"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `<phoneme alphabet="ipa" ph="ʃaˈlom">Shalom</phoneme> &amp; welcome, Shaloms`
+	if !strings.Contains(ssml, expected) {
+		t.Errorf("Expected %s in SSML, got: %s", expected, ssml)
+	}
+}
+
+func TestApplyLexicon(t *testing.T) {
+	lexicon := map[string]string{
+		"Go":       "Gopher",
+		"mmmeld":   "em em meld",
+		"C++":      "C plus plus",
+		"Shalom":   "ipa:ʃaˈlom",
+		"New York": "Noo York",
+	}
+
+	tests := []struct {
+		text      string
+		expected  string
+		wantCount int
+	}{
+		{"I use Go daily.", "I use Gopher daily.", 1},
+		{"Google and go are untouched.", "Google and go are untouched.", 0},
+		{"mmmeld, mmmeld!", "em em meld, em em meld!", 2},
+		{"Learn C++ today.", "Learn C plus plus today.", 1},
+		{"Shalom from New York", "Shalom from Noo York", 1},
+		{"", "", 0},
+	}
+
+	for _, test := range tests {
+		result, count := applyLexicon(test.text, lexicon)
+		if result != test.expected || count != test.wantCount {
+			t.Errorf("applyLexicon(%q) = %q, %d, expected %q, %d", test.text, result, count, test.expected, test.wantCount)
+		}
+	}
+}
+
+func TestAtempoFilter(t *testing.T) {
+	tests := []struct {
+		rate     float64
+		expected string
+	}{
+		{1.15, "atempo=1.1500"},
+		{0.5, "atempo=0.5000"},
+		{3.0, "atempo=2.0,atempo=1.5000"},
+		{0.25, "atempo=0.5,atempo=0.5000"},
+	}
+
+	for _, test := range tests {
+		result := atempoFilter(test.rate)
+		if result != test.expected {
+			t.Errorf("atempoFilter(%v) = %q, expected %q", test.rate, result, test.expected)
+		}
+	}
+}
+
+func TestSupportsNativeRate(t *testing.T) {
+	tests := []struct {
+		provider config.TTSProvider
+		rate     float64
+		expected bool
+	}{
+		{config.ProviderElevenLabs, 1.15, true},
+		{config.ProviderElevenLabs, 1.5, false},
+		{config.ProviderOpenAI, 3.0, true},
+		{config.ProviderAzure, 0.8, true},
+		{config.ProviderDeepgram, 1.15, false},
+		{config.ProviderLocal, 1.15, false},
+	}
+
+	for _, test := range tests {
+		result := supportsNativeRate(test.provider, test.rate)
+		if result != test.expected {
+			t.Errorf("supportsNativeRate(%s, %v) = %v, expected %v", test.provider, test.rate, result, test.expected)
+		}
+	}
+}
+
+func TestListVoicesOpenAI(t *testing.T) {
+	voices, err := ListVoices(config.ProviderOpenAI)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	found := false
+	for i, v := range voices {
+		if v.ID == config.OpenAIVoiceID {
+			found = true
+		}
+		if i > 0 && voices[i-1].ID > v.ID {
+			t.Errorf("Voices not sorted: %q before %q", voices[i-1].ID, v.ID)
+		}
+	}
+	if !found {
+		t.Errorf("Expected default voice %q in list", config.OpenAIVoiceID)
+	}
+
+	if _, err := ListVoices(config.ProviderLocal); err == nil {
+		t.Error("Expected error listing voices for the local provider")
+	}
+}
+
+func TestPrintVoices(t *testing.T) {
+	var buf strings.Builder
+	if err := PrintVoices(&buf, []Voice{{ID: "onyx", Name: "Onyx", Language: "multilingual", Gender: "male"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected header and one row, got %q", buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "ID") || !strings.Contains(lines[1], "onyx") {
+		t.Errorf("Unexpected table output: %q", buf.String())
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	tests := []struct {
+		format      string
+		expectError bool
+	}{
+		{"", false},
+		{"mp3", false},
+		{"wav", false},
+		{"ogg", false},
+		{"flac", false},
+		{"aiff", true},
+		{"MP3", true},
+	}
+
+	for _, test := range tests {
+		err := ValidateFormat(test.format)
+		if (err != nil) != test.expectError {
+			t.Errorf("ValidateFormat(%q) error = %v, expected error %v", test.format, err, test.expectError)
+		}
+	}
+}
+
+func TestWriteWAV(t *testing.T) {
+	pcm := []byte{1, 2, 3, 4}
+	var buf bytes.Buffer
+	if err := writeWAV(&buf, pcm, 44100, 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data := buf.Bytes()
+	if len(data) != 44+len(pcm) {
+		t.Fatalf("Expected %d bytes, got %d", 44+len(pcm), len(data))
+	}
+	if string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" || string(data[36:40]) != "data" {
+		t.Errorf("Unexpected header: %q", data[:44])
+	}
+	if rate := binary.LittleEndian.Uint32(data[24:28]); rate != 44100 {
+		t.Errorf("Expected sample rate 44100, got %d", rate)
+	}
+	if size := binary.LittleEndian.Uint32(data[40:44]); size != uint32(len(pcm)) {
+		t.Errorf("Expected data size %d, got %d", len(pcm), size)
+	}
+	if !bytes.Equal(data[44:], pcm) {
+		t.Errorf("Expected samples after header, got %v", data[44:])
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"rate limited", &APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{"request timeout", &APIError{StatusCode: http.StatusRequestTimeout}, true},
+		{"bad gateway", fmt.Errorf("wrapped: %w", &APIError{StatusCode: http.StatusBadGateway}), true},
+		{"unauthorized", &APIError{StatusCode: http.StatusUnauthorized}, false},
+		{"bad request", &APIError{StatusCode: http.StatusBadRequest}, false},
+		{"missing key", errors.New("OpenAI API key not found in environment"), false},
+	}
+
+	for _, test := range tests {
+		if result := isRetryable(test.err); result != test.expected {
+			t.Errorf("isRetryable(%s) = %v, expected %v", test.name, result, test.expected)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"7", 7 * time.Second},
+		{"Wed, 01 Jan 2025 12:00:30 GMT", 30 * time.Second},
+		{"Wed, 01 Jan 2025 11:00:00 GMT", 0},
+		{"soon", 0},
+	}
+
+	for _, test := range tests {
+		if result := parseRetryAfter(test.value, now); result != test.expected {
+			t.Errorf("parseRetryAfter(%q) = %v, expected %v", test.value, result, test.expected)
+		}
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	var waits []time.Duration
+	original := retry.Sleep
+	retry.Sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	defer func() { retry.Sleep = original }()
+
+	calls := 0
+	result, attempts, err := withRetry(4, "chunk 1/1", func() (string, error) {
+		calls++
+		if calls < 3 {
+			return "", &APIError{Provider: "ElevenLabs", StatusCode: 429, RetryAfter: 2 * time.Second}
+		}
+		return "ok.mp3", nil
+	})
+	if err != nil || result != "ok.mp3" || attempts != 3 {
+		t.Fatalf("withRetry = %q, %d, %v; expected ok.mp3 after 3 attempts", result, attempts, err)
+	}
+	if len(waits) != 2 || waits[0] != 2*time.Second {
+		t.Errorf("Expected two Retry-After waits of 2s, got %v", waits)
+	}
+
+	calls = 0
+	_, attempts, err = withRetry(3, "chunk 1/1", func() (string, error) {
+		calls++
+		return "", &APIError{Provider: "OpenAI", StatusCode: 502, Body: "upstream"}
+	})
+	if err == nil || attempts != 3 || calls != 3 {
+		t.Errorf("Expected failure after 3 attempts, got attempts=%d calls=%d err=%v", attempts, calls, err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "upstream") {
+		t.Errorf("Expected response body in error, got: %v", err)
+	}
+
+	calls = 0
+	_, attempts, _ = withRetry(3, "chunk 1/1", func() (string, error) {
+		calls++
+		return "", &APIError{Provider: "OpenAI", StatusCode: 401}
+	})
+	if attempts != 1 || calls != 1 {
+		t.Errorf("Expected no retries for 401, got %d attempts", attempts)
+	}
+}
+
+func TestRetryDelayBackoff(t *testing.T) {
+	err := &APIError{StatusCode: 503}
+	for attempt := 1; attempt <= 10; attempt++ {
+		d := retryDelay(attempt, err)
+		upper := retryBaseDelay << (attempt - 1)
+		if upper > retryMaxDelay {
+			upper = retryMaxDelay
+		}
+		if d < upper/2 || d > upper {
+			t.Errorf("retryDelay(%d) = %v, expected between %v and %v", attempt, d, upper/2, upper)
+		}
+	}
+}
+
+func TestChunkCacheKey(t *testing.T) {
+	opts := SpeechOptions{Provider: config.ProviderOpenAI, VoiceID: "onyx"}
+	base := chunkCacheKey(opts, "mp3", 0, "Hello")
+
+	if chunkCacheKey(opts, "mp3", 0, "Hello") != base {
+		t.Error("Expected identical settings to produce the same key")
+	}
+
+	withModel := opts
+	withModel.Model = config.OpenAITTSModel
+	if chunkCacheKey(withModel, "mp3", 0, "Hello") != base {
+		t.Error("Expected the default model to hash the same as an empty model")
+	}
+
+	variants := map[string]string{
+		"text":   chunkCacheKey(opts, "mp3", 0, "Hello!"),
+		"format": chunkCacheKey(opts, "wav", 0, "Hello"),
+		"rate":   chunkCacheKey(opts, "mp3", 1.1, "Hello"),
+	}
+	other := opts
+	other.VoiceID = "nova"
+	variants["voice"] = chunkCacheKey(other, "mp3", 0, "Hello")
+	other = opts
+	other.Model = "tts-1-hd"
+	variants["model"] = chunkCacheKey(other, "mp3", 0, "Hello")
+
+	for name, key := range variants {
+		if key == base {
+			t.Errorf("Expected a different key when %s changes", name)
+		}
+	}
+}
+
+func TestChunkCacheKeyElevenLabsSettings(t *testing.T) {
+	opts := SpeechOptions{Provider: config.ProviderElevenLabs, VoiceID: config.ElevenLabsVoiceID}
+	defaults := DefaultElevenLabsSettings()
+	explicit := opts
+	explicit.ElevenLabs = &defaults
+	if chunkCacheKey(opts, "mp3", 0, "Hello") != chunkCacheKey(explicit, "mp3", 0, "Hello") {
+		t.Error("Expected nil settings to hash the same as the defaults")
+	}
+
+	stable := defaults
+	stable.Stability = 0.9
+	explicit.ElevenLabs = &stable
+	if chunkCacheKey(opts, "mp3", 0, "Hello") == chunkCacheKey(explicit, "mp3", 0, "Hello") {
+		t.Error("Expected a different key when voice settings change")
+	}
+}
+
+func TestChunkCacheStoreAndLookup(t *testing.T) {
+	cache, err := openChunkCache(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	src := filepath.Join(t.TempDir(), "chunk.mp3")
+	if err := os.WriteFile(src, []byte("audio"), 0644); err != nil {
+		t.Fatalf("Failed to create chunk: %v", err)
+	}
+
+	if _, hit := cache.lookup("abc"); hit {
+		t.Fatal("Expected miss on empty cache")
+	}
+	if err := cache.store("abc", src); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	path, hit := cache.lookup("abc")
+	if !hit {
+		t.Fatal("Expected hit after store")
+	}
+	if filepath.Ext(path) != ".mp3" {
+		t.Errorf("Expected cached file to keep its extension, got %s", path)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "audio" {
+		t.Errorf("Expected cached contents, got %q", data)
+	}
+
+	var nilCache *chunkCache
+	if _, hit := nilCache.lookup("abc"); hit {
+		t.Error("Expected nil cache to miss")
+	}
+	if err := nilCache.store("abc", src); err != nil {
+		t.Errorf("Expected nil cache store to be a no-op, got %v", err)
+	}
+}
+
+func TestChunkCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := openChunkCache(dir, 10)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	src := filepath.Join(t.TempDir(), "chunk.mp3")
+	if err := os.WriteFile(src, []byte("123456"), 0644); err != nil {
+		t.Fatalf("Failed to create chunk: %v", err)
+	}
+
+	if err := cache.store("old", src); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	past := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(dir, "old.mp3"), past, past)
+
+	if err := cache.store("new", src); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, hit := cache.lookup("old"); hit {
+		t.Error("Expected least recently used entry to be evicted")
+	}
+	if _, hit := cache.lookup("new"); !hit {
+		t.Error("Expected newest entry to survive eviction")
+	}
+}
+
+func TestCopyCachedChunk(t *testing.T) {
+	saved := config.TempAssetsFolder
+	config.TempAssetsFolder = t.TempDir()
+	t.Cleanup(func() { config.TempAssetsFolder = saved })
+
+	cached := filepath.Join(t.TempDir(), "abc.mp3")
+	if err := os.WriteFile(cached, []byte("audio"), 0644); err != nil {
+		t.Fatalf("Failed to create cached chunk: %v", err)
+	}
+
+	cleanup := fileutil.NewCleanupManager()
+	path, err := copyCachedChunk(cached, cleanup)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if filepath.Dir(path) != config.TempAssetsFolder || filepath.Ext(path) != ".mp3" || !strings.Contains(filepath.Base(path), "_cached_") {
+		t.Errorf("Expected a cached_ .mp3 in the temp folder, got %s", path)
+	}
+	again, err := copyCachedChunk(cached, cleanup)
+	if err != nil || again == path {
+		t.Errorf("Expected a second copy at a new path, got %s, %v", again, err)
+	}
+
+	// Another run evicting the entry must not take this run's copy with it
+	os.Remove(cached)
+	if data, err := os.ReadFile(path); err != nil || string(data) != "audio" {
+		t.Errorf("Expected the copy to outlive eviction, got %q, %v", data, err)
+	}
+
+	cleanup.Cleanup()
+	if fileutil.FileExists(path) {
+		t.Error("Expected the copy to be registered for cleanup")
+	}
+}
+
+func TestParseDialogue(t *testing.T) {
+	voices := map[string]string{"ALICE": "nova", "Bob": "onyx"}
+	script := `Narrator intro line.
+ALICE: Hi Bob.
+How are you?
+
+bob: Fine. Note: it's raining.
+NOTE: not a speaker
+ALICE:
+BOB: Bye.`
+
+	turns := ParseDialogue(script, voices)
+	expected := []DialogueTurn{
+		{Speaker: "", Text: "Narrator intro line."},
+		{Speaker: "ALICE", Text: "Hi Bob.\nHow are you?"},
+		{Speaker: "Bob", Text: "Fine. Note: it's raining.\nNOTE: not a speaker"},
+		{Speaker: "Bob", Text: "Bye."},
+	}
+	if !reflect.DeepEqual(turns, expected) {
+		t.Errorf("ParseDialogue = %#v, expected %#v", turns, expected)
+	}
+
+	if turns := ParseDialogue("  \n\n", voices); len(turns) != 0 {
+		t.Errorf("Expected no turns for blank script, got %#v", turns)
+	}
+}
+
+func TestProportionalSegments(t *testing.T) {
+	segments := proportionalSegments("Hi there. Bye!", 10, 4)
+	expected := []Segment{
+		{Text: "Hi there.", Start: 10, End: 10 + 4*9.0/13},
+		{Text: "Bye!", Start: 10 + 4*9.0/13, End: 14},
+	}
+	if len(segments) != len(expected) {
+		t.Fatalf("Expected %d segments, got %#v", len(expected), segments)
+	}
+	for i := range expected {
+		got, want := segments[i], expected[i]
+		if got.Text != want.Text || !nearlyEqual(got.Start, want.Start) || !nearlyEqual(got.End, want.End) {
+			t.Errorf("Segment %d = %+v, expected %+v", i, got, want)
+		}
+	}
+
+	if segments := proportionalSegments("   ", 0, 3); segments != nil {
+		t.Errorf("Expected no segments for blank text, got %#v", segments)
+	}
+}
+
+func TestAlignedSegments(t *testing.T) {
+	text := "Hi. Yo!"
+	a := &charAlignment{}
+	for i, r := range text {
+		a.Characters = append(a.Characters, string(r))
+		a.Starts = append(a.Starts, float64(i))
+		a.Ends = append(a.Ends, float64(i)+0.5)
+	}
+
+	segments, ok := alignedSegments(text, 100, a, 1)
+	if !ok {
+		t.Fatal("Expected alignment to be used")
+	}
+	expected := []Segment{
+		{Text: "Hi.", Start: 100, End: 102.5},
+		{Text: "Yo!", Start: 104, End: 106.5},
+	}
+	if !reflect.DeepEqual(segments, expected) {
+		t.Errorf("alignedSegments = %+v, expected %+v", segments, expected)
+	}
+
+	// atempo at 2x halves every timing
+	segments, _ = alignedSegments(text, 0, a, 0.5)
+	if segments[1].Start != 2 || segments[1].End != 3.25 {
+		t.Errorf("Expected scaled timings, got %+v", segments[1])
+	}
+
+	a.Characters = a.Characters[:3]
+	if _, ok := alignedSegments(text, 0, a, 1); ok {
+		t.Error("Expected mismatched alignment to be rejected")
+	}
+	if _, ok := alignedSegments(text, 0, nil, 1); ok {
+		t.Error("Expected nil alignment to be rejected")
+	}
+}
+
+func nearlyEqual(a, b float64) bool {
+	d := a - b
+	return d < 1e-9 && d > -1e-9
+}
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"One. Two! Three?", []string{"One.", "Two!", "Three?"}},
+		{"Wait... What?! Okay.", []string{"Wait...", "What?!", "Okay."}},
+		{"Mr. Smith met Dr. Jones. They talked.", []string{"Mr. Smith met Dr. Jones.", "They talked."}},
+		{"Bring fruit, e.g. apples. Thanks.", []string{"Bring fruit, e.g. apples.", "Thanks."}},
+		{"J. R. R. Tolkien wrote it. Yes.", []string{"J. R. R. Tolkien wrote it.", "Yes."}},
+		{`He said "Stop. Now." Then he left.`, []string{`He said "Stop. Now."`, "Then he left."}},
+		{"She asked “Why? How?” and waited. Done.", []string{"She asked “Why? How?” and waited.", "Done."}},
+		{"(See above.) Next.", []string{"(See above.)", "Next."}},
+		{"No terminator", []string{"No terminator"}},
+		{"Version 1.5 shipped. Ok.", []string{"Version 1.5 shipped.", "Ok."}},
+		{"The answer was no. We left.", []string{"The answer was no.", "We left."}},
+		{"See Fig. 3 and No. 5. Done.", []string{"See Fig. 3 and No. 5.", "Done."}},
+		{"We flew to St. Louis. It rained.", []string{"We flew to St. Louis.", "It rained."}},
+		{"Smith & Co. Ltd sells tea. Buy some.", []string{"Smith & Co. Ltd sells tea.", "Buy some."}},
+	}
+
+	for _, test := range tests {
+		result := splitSentences(test.input)
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("splitSentences(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestSplitTextIntoChunks(t *testing.T) {
+	long := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 20)
+	tests := []struct {
+		name    string
+		text    string
+		maxSize int
+	}{
+		{"short text", "Hello world.", 100},
+		{"multiple lines", "Line one.\nLine two.\n\nLine four.", 12},
+		{"long line of sentences", long, 100},
+		{"abbreviations and quotes", `Mr. Smith said "Wait. Stop. Listen." Then Dr. Jones answered, e.g. with facts. ` + long, 80},
+		{"sentence longer than max", "Supercalifragilistic expialidocious words keep on going without any stop at all", 20},
+		{"single word longer than max", "Pneumonoultramicroscopicsilicovolcanoconiosis", 10},
+		{"multibyte word longer than max", "ééééééééééééééé", 5},
+	}
+
+	for _, test := range tests {
+		chunks := SplitTextIntoChunks(test.text, test.maxSize)
+
+		for _, chunk := range chunks {
+			if len(chunk) > test.maxSize {
+				t.Errorf("%s: chunk %q exceeds max size %d", test.name, chunk, test.maxSize)
+			}
+			if !utf8.ValidString(chunk) {
+				t.Errorf("%s: chunk %q is not valid UTF-8", test.name, chunk)
+			}
+		}
+
+		// Chunks must round-trip to the original text, modulo whitespace and
+		// the cuts made inside overlong words
+		joined := strings.Join(strings.Fields(strings.Join(chunks, " ")), " ")
+		original := strings.Join(strings.Fields(test.text), " ")
+		if strings.ReplaceAll(joined, " ", "") != strings.ReplaceAll(original, " ", "") {
+			t.Errorf("%s: chunks do not round-trip:\n got: %q\nwant: %q", test.name, joined, original)
+		}
+		if !strings.Contains(test.name, "longer than max") && joined != original {
+			t.Errorf("%s: chunks do not round-trip:\n got: %q\nwant: %q", test.name, joined, original)
+		}
+	}
+}
+
+func TestSplitTextIntoChunksKeepsTerminators(t *testing.T) {
+	chunks := SplitTextIntoChunks("First sentence here. Second sentence here! Third one?", 25)
+	expected := []string{"First sentence here.", "Second sentence here!", "Third one?"}
+	if !reflect.DeepEqual(chunks, expected) {
+		t.Errorf("SplitTextIntoChunks = %q, expected %q", chunks, expected)
+	}
+}
+
+func TestResolveTitle(t *testing.T) {
+	orig := generateLLMTitle
+	defer func() { generateLLMTitle = orig }()
+	t.Setenv("GEMINI_API_KEY", "test")
+	t.Setenv("OPENAI_API_KEY", "")
+
+	tests := []struct {
+		name     string
+		opts     SpeechOptions
+		llm      func(string, genai.ClientOptions) (string, error)
+		expected string
+	}{
+		{"llm title", SpeechOptions{}, func(string, genai.ClientOptions) (string, error) { return "Quiet Mornings By The Sea", nil }, "Quiet Mornings By The Sea"},
+		{"llm error falls back", SpeechOptions{}, func(string, genai.ClientOptions) (string, error) { return "", errors.New("boom") }, "Heuristic"},
+		{"disabled", SpeechOptions{NoLLMTitle: true}, func(string, genai.ClientOptions) (string, error) { return "Unused", nil }, "Heuristic"},
+	}
+
+	for _, test := range tests {
+		generateLLMTitle = test.llm
+		if got := resolveTitle("some text", "Heuristic", test.opts); got != test.expected {
+			t.Errorf("%s: resolveTitle = %q, expected %q", test.name, got, test.expected)
+		}
+	}
+}
+
+func TestValidateDeepgramEncoding(t *testing.T) {
+	tests := []struct {
+		encoding   string
+		sampleRate int
+		format     string
+		wantErr    bool
+	}{
+		{"", 0, "", false},
+		{"", 8000, "", true},
+		{"linear16", 0, "", false},
+		{"linear16", 8000, "wav", false},
+		{"linear16", 44100, "", true},
+		{"mulaw", 8000, "", false},
+		{"mulaw", 48000, "", true},
+		{"alaw", 16000, "", false},
+		{"opus", 0, "ogg", false},
+		{"opus", 16000, "", true},
+		{"mp3", 0, "", false},
+		{"mp3", 0, "wav", true},
+		{"linear16", 0, "mp3", true},
+		{"pcm", 0, "", true},
+	}
+
+	for _, test := range tests {
+		err := ValidateDeepgramEncoding(test.encoding, test.sampleRate, test.format)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ValidateDeepgramEncoding(%q, %d, %q) error = %v, wantErr %v", test.encoding, test.sampleRate, test.format, err, test.wantErr)
+		}
+	}
+}
+
+func TestDeepgramQuery(t *testing.T) {
+	tests := []struct {
+		encoding   string
+		sampleRate int
+		expected   string
+	}{
+		{"linear16", 8000, "encoding=linear16&container=wav&sample_rate=8000"},
+		{"mulaw", 0, "encoding=mulaw&container=wav"},
+		{"opus", 0, "encoding=opus&container=ogg"},
+		{"flac", 16000, "encoding=flac&sample_rate=16000"},
+		{"mp3", 0, "encoding=mp3"},
+	}
+
+	for _, test := range tests {
+		if got := deepgramQuery(test.encoding, test.sampleRate); got != test.expected {
+			t.Errorf("deepgramQuery(%q, %d) = %q, expected %q", test.encoding, test.sampleRate, got, test.expected)
+		}
+	}
+}
+
+func TestEstimateSpeech(t *testing.T) {
+	text := strings.Repeat("This sentence is exactly forty chars ok. ", 50)
+	opts := SpeechOptions{Provider: config.ProviderOpenAI, ChunkSize: 500}
+
+	e := EstimateSpeech(text, opts, 0)
+	if e.Model != config.OpenAITTSModel {
+		t.Errorf("Model = %q, expected %q", e.Model, config.OpenAITTSModel)
+	}
+	if e.Chunks != len(SplitTextIntoChunks(text, 500)) {
+		t.Errorf("Chunks = %d, expected %d", e.Chunks, len(SplitTextIntoChunks(text, 500)))
+	}
+	if e.PricePer1K != PricePer1KChars["openai"] {
+		t.Errorf("PricePer1K = %v, expected %v", e.PricePer1K, PricePer1KChars["openai"])
+	}
+	if want := float64(e.Characters) / 1000 * e.PricePer1K; e.Cost != want {
+		t.Errorf("Cost = %v, expected %v", e.Cost, want)
+	}
+
+	opts.Model = "tts-1-hd"
+	if e := EstimateSpeech(text, opts, 0); e.PricePer1K != PricePer1KChars["openai/tts-1-hd"] {
+		t.Errorf("tts-1-hd PricePer1K = %v, expected %v", e.PricePer1K, PricePer1KChars["openai/tts-1-hd"])
+	}
+	if e := EstimateSpeech(text, opts, 1); e.PricePer1K != 1 {
+		t.Errorf("override PricePer1K = %v, expected 1", e.PricePer1K)
+	}
+
+	dialogue := EstimateSpeech("ALICE: Hello there.\nBOB: Hi.", SpeechOptions{Provider: config.ProviderOpenAI, Voices: map[string]string{"ALICE": "nova", "BOB": "onyx"}}, 0)
+	if dialogue.Chunks != 2 || dialogue.Characters != len("Hello there.")+len("Hi.") {
+		t.Errorf("dialogue estimate = %d chunks, %d characters, expected 2 chunks, %d characters", dialogue.Chunks, dialogue.Characters, len("Hello there.")+len("Hi."))
+	}
+}
+
+func TestDialogueVoices(t *testing.T) {
+	voices := map[string]string{"ALICE": "nova", "BOB": "onyx"}
+	turns := []DialogueTurn{{Text: "intro"}, {Speaker: "BOB", Text: "Hi."}, {Speaker: "ALICE", Text: "Hello."}, {Speaker: "BOB", Text: "Bye."}}
+
+	if got := dialogueVoices(turns, voices); got != "BOB=onyx,ALICE=nova" {
+		t.Errorf("dialogueVoices = %q, expected %q", got, "BOB=onyx,ALICE=nova")
+	}
+}
+
+func TestGenerateSpeechSpeedAndRate(t *testing.T) {
+	// For OpenAI both set the request's speed, so one would be dropped
+	opts := SpeechOptions{Provider: config.ProviderOpenAI, VoiceID: "alloy", Speed: 1.1, Rate: 1.3}
+	_, err := GenerateSpeechWithOptions("Hello.", opts, fileutil.NewCleanupManager(), "")
+	if err == nil || !strings.Contains(err.Error(), "pass only one") {
+		t.Errorf("GenerateSpeechWithOptions(speed 1.1, rate 1.3) error = %v, expected the combination to be rejected", err)
+	}
+}
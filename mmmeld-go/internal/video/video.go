@@ -3,16 +3,23 @@ package video
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	goimage "image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 
 	"mmmeld/internal/config"
+	"mmmeld/internal/ffmpeg"
 	"mmmeld/internal/fileutil"
 	"mmmeld/internal/image"
+	"mmmeld/internal/logx"
+	"mmmeld/internal/probe"
 )
 
 // Instructions (from Python original):
@@ -54,36 +61,56 @@ type VideoGenParams struct {
 	AudioMargins     config.AudioMargins
 	TempFolder       string
 	TargetDimensions *Dimensions
+
+	// Background fills the canvas around inputs of another aspect ratio:
+	// "black" (or empty), "blur", "auto" (each still's edge color), or
+	// "color:#RRGGBB"
+	Background string
+
+	// MaxSourceDimension caps the long edge of still inputs; larger stills are
+	// downscaled copies. 0 uses 2x the long edge of the reference canvas.
+	MaxSourceDimension int
+}
+
+// Final encode settings
+const (
+	outputFPS          = 30
+	outputVideoCodec   = "libx264"
+	outputPreset       = "slow"
+	outputCRF          = 18
+	outputAudioCodec   = "aac"
+	outputAudioBitrate = "192k"
+)
+
+// RenderSettings describes how GenerateVideo encoded a video
+type RenderSettings struct {
+	Width         int                 `json:"width"`
+	Height        int                 `json:"height"`
+	FPS           int                 `json:"fps"`
+	VideoCodec    string              `json:"video_codec"`
+	Preset        string              `json:"preset"`
+	CRF           int                 `json:"crf"`
+	AudioCodec    string              `json:"audio_codec"`
+	AudioBitrate  string              `json:"audio_bitrate"`
+	Background    string              `json:"background"`
+	Duration      float64             `json:"duration_seconds"`
+	AudioMargins  config.AudioMargins `json:"audio_margins"`
+	BGMusicVolume float64             `json:"bg_music_volume,omitempty"`
 }
 
+// defaultCanvas is the canvas used when no input size is known
+var defaultCanvas = Dimensions{Width: 1920, Height: 1080}
+
 // GetMediaDuration returns the duration of a media file in seconds
-// For images, returns 5.0 seconds (standard duration)
+// For images, returns 5.0 seconds (standard duration). Probe results are cached
+// for the rest of the run.
 func GetMediaDuration(filepath string) (float64, error) {
 	if image.IsImageFile(filepath) {
-		log.Printf("Using standard 5-second duration for image: %s", filepath)
+		logx.Debugf("Using standard 5-second duration for image: %s", filepath)
 		return 5.0, nil
 	}
 
-	cmd := exec.Command("ffprobe", "-v", "error", "-show_entries", "format=duration",
-		"-of", "default=noprint_wrappers=1:nokey=1", filepath)
-
-	output, err := cmd.Output()
-	if err != nil {
-		return 0, fmt.Errorf("failed to get media duration for %s: %w", filepath, err)
-	}
-
-	durationStr := strings.TrimSpace(string(output))
-	if durationStr == "" {
-		return 0, fmt.Errorf("ffprobe returned empty duration for %s", filepath)
-	}
-
-	duration, err := strconv.ParseFloat(durationStr, 64)
-	if err != nil {
-		return 0, fmt.Errorf("failed to parse duration '%s': %w", durationStr, err)
-	}
-
-	log.Printf("Media duration for %s: %.3f seconds", filepath, duration)
-	return duration, nil
+	return probe.Duration(filepath)
 }
 
 // CalculateTotalDuration determines the total output video duration
@@ -95,7 +122,7 @@ func CalculateTotalDuration(audioPath string, mediaInputs []image.MediaInput, ma
 			return 0, fmt.Errorf("failed to get audio duration: %w", err)
 		}
 		total := audioDuration + margins.Start + margins.End
-		log.Printf("Total duration (with audio): %.3f = %.3f + %.3f + %.3f",
+		logx.Debugf("Total duration (with audio): %.3f = %.3f + %.3f + %.3f",
 			total, audioDuration, margins.Start, margins.End)
 		return total, nil
 	}
@@ -115,50 +142,59 @@ func CalculateTotalDuration(audioPath string, mediaInputs []image.MediaInput, ma
 		totalDuration = 5.0
 	}
 
-	log.Printf("Total duration (without audio): %.3f seconds", totalDuration)
+	logx.Debugf("Total duration (without audio): %.3f seconds", totalDuration)
 	return totalDuration, nil
 }
 
-// CalculateMaxDimensions finds the maximum width and height from all inputs
-func CalculateMaxDimensions(mediaInputs []image.MediaInput) (Dimensions, error) {
-	var maxWidth, maxHeight int
+// probeDimensions returns a media file's display width and height via ffprobe,
+// accounting for rotation metadata
+func probeDimensions(path string) (int, int, error) {
+	cmd := exec.Command("ffprobe", "-v", "error", "-select_streams", "v:0",
+		"-show_entries", "stream=width,height,rotation", "-of", "json", path)
 
-	for _, input := range mediaInputs {
-		cmd := exec.Command("ffprobe", "-v", "error", "-select_streams", "v:0",
-			"-show_entries", "stream=width,height,rotation", "-of", "json", input.Path)
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get dimensions: %w", err)
+	}
 
-		output, err := cmd.Output()
-		if err != nil {
-			log.Printf("Warning: Failed to get dimensions for %s: %v", input.Path, err)
-			continue
-		}
+	var data struct {
+		Streams []struct {
+			Width  int `json:"width"`
+			Height int `json:"height"`
+			Tags   struct {
+				Rotate string `json:"rotate"`
+			} `json:"tags"`
+		} `json:"streams"`
+	}
 
-		var data struct {
-			Streams []struct {
-				Width  int `json:"width"`
-				Height int `json:"height"`
-				Tags   struct {
-					Rotate string `json:"rotate"`
-				} `json:"tags"`
-			} `json:"streams"`
-		}
+	if err := json.Unmarshal(output, &data); err != nil {
+		return 0, 0, fmt.Errorf("failed to parse dimensions: %w", err)
+	}
 
-		if err := json.Unmarshal(output, &data); err != nil {
-			log.Printf("Warning: Failed to parse dimensions for %s: %v", input.Path, err)
-			continue
-		}
+	if len(data.Streams) == 0 {
+		return 0, 0, nil
+	}
 
-		if len(data.Streams) == 0 {
-			continue
-		}
+	stream := data.Streams[0]
+	width, height := stream.Width, stream.Height
 
-		stream := data.Streams[0]
-		width, height := stream.Width, stream.Height
+	// Handle rotation
+	if stream.Tags.Rotate == "90" || stream.Tags.Rotate == "270" {
+		logx.Debugf("Detected %s degree rotation for %s", stream.Tags.Rotate, path)
+		width, height = height, width
+	}
+	return width, height, nil
+}
 
-		// Handle rotation
-		if stream.Tags.Rotate == "90" || stream.Tags.Rotate == "270" {
-			log.Printf("Detected %s degree rotation for %s", stream.Tags.Rotate, input.Path)
-			width, height = height, width
+// CalculateMaxDimensions finds the maximum width and height from all inputs
+func CalculateMaxDimensions(mediaInputs []image.MediaInput) (Dimensions, error) {
+	var maxWidth, maxHeight int
+
+	for _, input := range mediaInputs {
+		width, height, err := probeDimensions(input.Path)
+		if err != nil {
+			logx.Warnf("Failed to get dimensions for %s: %v", input.Path, err)
+			continue
 		}
 
 		if width > maxWidth {
@@ -171,15 +207,192 @@ func CalculateMaxDimensions(mediaInputs []image.MediaInput) (Dimensions, error)
 
 	// Default dimensions if no valid inputs found
 	if maxWidth == 0 || maxHeight == 0 {
-		maxWidth, maxHeight = 1920, 1080
+		maxWidth, maxHeight = defaultCanvas.Width, defaultCanvas.Height
 	}
 
-	log.Printf("Calculated max dimensions: %dx%d", maxWidth, maxHeight)
+	logx.Debugf("Calculated max dimensions: %dx%d", maxWidth, maxHeight)
 	return Dimensions{Width: maxWidth, Height: maxHeight}, nil
 }
 
+// canvasFilter builds input i's video chain: the timing filters in timing,
+// then a fit to the canvas, labelled [v<i>]. Space the input does not cover
+// is padded with black or a "color:#RRGGBB" color, or for "blur" filled with
+// a blurred copy of the input scaled and cropped to cover the canvas.
+func canvasFilter(i int, timing string, dims Dimensions, background string) string {
+	w, h := dims.Width, dims.Height
+	if background == "blur" {
+		return fmt.Sprintf(
+			"[%d:v]%s,split[fg%d][bg%d];"+
+				"[bg%d]scale=%d:%d:force_original_aspect_ratio=increase,crop=%d:%d,boxblur=20:2[bgb%d];"+
+				"[fg%d]scale=%d:%d:force_original_aspect_ratio=decrease[fgs%d];"+
+				"[bgb%d][fgs%d]overlay=(W-w)/2:(H-h)/2,setsar=1,setpts=PTS-STARTPTS[v%d];",
+			i, timing, i, i,
+			i, w, h, w, h, i,
+			i, w, h, i,
+			i, i, i)
+	}
+
+	color := "black"
+	if hex, ok := strings.CutPrefix(background, "color:"); ok {
+		color = "0x" + strings.TrimPrefix(hex, "#")
+	}
+	return fmt.Sprintf("[%d:v]%s,scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2:color=%s,setpts=PTS-STARTPTS[v%d];",
+		i, timing, w, h, w, h, color, i)
+}
+
+// edgeColors caches autoBackground results by path
+var edgeColors sync.Map
+
+// autoBackground returns a "color:#RRGGBB" background matching the average
+// color along a still's edges, so padding blends with the artwork. Videos
+// and stills that cannot be decoded (e.g. WebP) get black.
+func autoBackground(input image.MediaInput) string {
+	if input.IsVideo || !image.IsImageFile(input.Path) {
+		return "black"
+	}
+	if cached, ok := edgeColors.Load(input.Path); ok {
+		return cached.(string)
+	}
+
+	background := "black"
+	if r, g, b, err := edgeColor(input.Path); err != nil {
+		logx.Warnf("Could not sample edge color of %s, using black: %v", input.Path, err)
+	} else {
+		background = fmt.Sprintf("color:#%02x%02x%02x", r, g, b)
+	}
+	edgeColors.Store(input.Path, background)
+	return background
+}
+
+// edgeColor averages the pixels in a band along the image border, about 2% of
+// the short side deep, sampling sparsely on large images
+func edgeColor(path string) (uint8, uint8, uint8, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer f.Close()
+
+	img, _, err := goimage.Decode(f)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return 0, 0, 0, fmt.Errorf("image is empty")
+	}
+	shortSide := w
+	if h < shortSide {
+		shortSide = h
+	}
+	band := max(1, shortSide/50)
+	step := max(1, max(w, h)/400)
+
+	var sumR, sumG, sumB, n uint64
+	for y := 0; y < h; y += step {
+		for x := 0; x < w; x += step {
+			if x >= band && x < w-band && y >= band && y < h-band {
+				// Skip the interior; jump to the right-hand band
+				x = max(x, w-band-step)
+				continue
+			}
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			sumR += uint64(r >> 8)
+			sumG += uint64(g >> 8)
+			sumB += uint64(b >> 8)
+			n++
+		}
+	}
+	return uint8(sumR / n), uint8(sumG / n), uint8(sumB / n), nil
+}
+
+// maxSourceDimension returns the long-edge cap for stills: the override when
+// set, otherwise twice the long edge of the reference canvas (the target
+// dimensions, or the largest video input, but at least the default canvas)
+func maxSourceDimension(params VideoGenParams) int {
+	if params.MaxSourceDimension > 0 {
+		return params.MaxSourceDimension
+	}
+
+	canvas := defaultCanvas
+	if params.TargetDimensions != nil {
+		canvas = *params.TargetDimensions
+	} else {
+		for _, input := range params.MediaInputs {
+			if !input.IsVideo {
+				continue
+			}
+			if width, height, err := probeDimensions(input.Path); err == nil {
+				canvas.Width = max(canvas.Width, width)
+				canvas.Height = max(canvas.Height, height)
+			}
+		}
+	}
+	return 2 * max(canvas.Width, canvas.Height)
+}
+
+// fitWithin scales width x height down, preserving aspect, so neither side
+// exceeds maxDim. It reports false when the size already fits.
+func fitWithin(width, height, maxDim int) (int, int, bool) {
+	if maxDim <= 0 || (width <= maxDim && height <= maxDim) {
+		return width, height, false
+	}
+	if width >= height {
+		return maxDim, max(height*maxDim/width&^1, 2), true
+	}
+	return max(width*maxDim/height&^1, 2), maxDim, true
+}
+
+// downscaleStills replaces stills larger than maxDim with resized copies in
+// the temp folder, so the lossless sequence and its scale filter do not work
+// at full sensor resolution. Originals are untouched; the returned temp files
+// are for the caller to remove.
+func downscaleStills(mediaInputs []image.MediaInput, maxDim int, tempFolder, plannedOutputPath string) ([]image.MediaInput, []string, error) {
+	result := make([]image.MediaInput, len(mediaInputs))
+	copy(result, mediaInputs)
+
+	var tempFiles []string
+	for i, input := range result {
+		if input.IsVideo || !image.IsImageFile(input.Path) {
+			continue
+		}
+		width, height, err := probeDimensions(input.Path)
+		if err != nil {
+			logx.Warnf("Failed to get dimensions for %s: %v", input.Path, err)
+			continue
+		}
+		newWidth, newHeight, resize := fitWithin(width, height, maxDim)
+		if !resize {
+			continue
+		}
+
+		ext := strings.ToLower(filepath.Ext(input.Path))
+		quality := []string{}
+		if ext == ".jpg" || ext == ".jpeg" {
+			quality = []string{"-q:v", "2"}
+		} else {
+			ext = ".png"
+		}
+		name := strings.TrimSuffix(filepath.Base(input.Path), filepath.Ext(input.Path))
+		resized := fileutil.TempAssetPath(tempFolder, plannedOutputPath, fmt.Sprintf("downscaled_%03d_%s%s", i, name, ext))
+
+		logx.Infof("Downscaling %s from %dx%d to %dx%d", input.Path, width, height, newWidth, newHeight)
+		cmd := []string{"ffmpeg", "-y", "-i", input.Path, "-vf", fmt.Sprintf("scale=%d:%d", newWidth, newHeight), "-frames:v", "1"}
+		cmd = append(cmd, quality...)
+		cmd = append(cmd, resized)
+		if err := runFFmpegCommand(cmd); err != nil {
+			return nil, tempFiles, fmt.Errorf("failed to downscale %s: %w", input.Path, err)
+		}
+		tempFiles = append(tempFiles, resized)
+		result[i].Path = resized
+	}
+	return result, tempFiles, nil
+}
+
 // CreateVisualSequence creates video and audio sequences from media inputs
-func CreateVisualSequence(mediaInputs []image.MediaInput, totalDuration float64, tempFolder string, hasMainAudio bool, dimensions Dimensions, plannedOutputPath string) (string, string, error) {
+func CreateVisualSequence(mediaInputs []image.MediaInput, totalDuration float64, tempFolder string, hasMainAudio bool, dimensions Dimensions, background, plannedOutputPath string) (string, string, error) {
 	tempVideoSeq := fileutil.TempAssetPath(tempFolder, plannedOutputPath, "temp_video_sequence.mkv")
 	tempAudioSeq := fileutil.TempAssetPath(tempFolder, plannedOutputPath, "temp_audio_sequence.wav")
 
@@ -188,6 +401,11 @@ func CreateVisualSequence(mediaInputs []image.MediaInput, totalDuration float64,
 	var tempAudioEnsuredFiles []string // Track intermediate files for cleanup
 
 	for i, input := range mediaInputs {
+		inputBackground := background
+		if background == "auto" {
+			inputBackground = autoBackground(input)
+		}
+
 		// Ensure video has audio track
 		inputWithAudio, err := ensureVideoHasAudio(input.Path, tempFolder, plannedOutputPath)
 		if err != nil {
@@ -241,26 +459,23 @@ func CreateVisualSequence(mediaInputs []image.MediaInput, totalDuration float64,
 		}
 
 		if image.IsImageFile(input.Path) {
-			videoFilters = append(videoFilters, fmt.Sprintf(
-				"[%d:v]loop=loop=-1:size=1:start=0,trim=duration=%.3f,scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setpts=PTS-STARTPTS[v%d];",
-				i, targetDuration, dimensions.Width, dimensions.Height, dimensions.Width, dimensions.Height, i))
+			videoFilters = append(videoFilters, canvasFilter(i,
+				fmt.Sprintf("loop=loop=-1:size=1:start=0,trim=duration=%.3f", targetDuration), dimensions, inputBackground))
 			audioFilters = append(audioFilters, fmt.Sprintf("aevalsrc=0:duration=%.3f[a%d];", targetDuration, i))
 		} else {
 			// For videos, handle looping if needed
 			if hasMainAudio && duration < targetDuration {
 				// Video needs to loop
 				loopCount := int(targetDuration/duration) + 1
-				videoFilters = append(videoFilters, fmt.Sprintf(
-					"[%d:v]loop=loop=%d:size=%d:start=0,trim=duration=%.3f,scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setpts=PTS-STARTPTS[v%d];",
-					i, loopCount, int(duration*30), targetDuration, dimensions.Width, dimensions.Height, dimensions.Width, dimensions.Height, i))
+				videoFilters = append(videoFilters, canvasFilter(i,
+					fmt.Sprintf("loop=loop=%d:size=%d:start=0,trim=duration=%.3f", loopCount, int(duration*30), targetDuration), dimensions, inputBackground))
 				audioFilters = append(audioFilters, fmt.Sprintf(
 					"[%d:a]aloop=loop=%d:size=%d,atrim=duration=%.3f,asetpts=PTS-STARTPTS[a%d];",
 					i, loopCount, int(duration*44100), targetDuration, i))
 			} else {
 				// Video is longer or same length, just trim
-				videoFilters = append(videoFilters, fmt.Sprintf(
-					"[%d:v]trim=duration=%.3f,scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setpts=PTS-STARTPTS[v%d];",
-					i, targetDuration, dimensions.Width, dimensions.Height, dimensions.Width, dimensions.Height, i))
+				videoFilters = append(videoFilters, canvasFilter(i,
+					fmt.Sprintf("trim=duration=%.3f", targetDuration), dimensions, inputBackground))
 				audioFilters = append(audioFilters, fmt.Sprintf("[%d:a]atrim=duration=%.3f,asetpts=PTS-STARTPTS[a%d];", i, targetDuration, i))
 			}
 		}
@@ -286,8 +501,8 @@ func CreateVisualSequence(mediaInputs []image.MediaInput, totalDuration float64,
 	videoCmd = append(videoCmd, "-filter_complex", strings.Join(videoFilters, ""),
 		"-map", "[outv]", "-c:v", "libx264", "-preset", "ultrafast", "-crf", "0", tempVideoSeq)
 
-	log.Printf("Creating video sequence: %s", strings.Join(videoCmd, " "))
-	if err := runFFmpegCommand(videoCmd); err != nil {
+	logx.Infof("Creating video sequence from %d input(s)", len(mediaInputs))
+	if err := runFFmpegWithProgress(videoCmd, "visual_sequence", totalDuration); err != nil {
 		return "", "", fmt.Errorf("failed to create video sequence: %w", err)
 	}
 
@@ -297,7 +512,7 @@ func CreateVisualSequence(mediaInputs []image.MediaInput, totalDuration float64,
 	audioCmd = append(audioCmd, "-filter_complex", strings.Join(audioFilters, ""),
 		"-map", "[outa]", "-c:a", "pcm_s16le", tempAudioSeq)
 
-	log.Printf("Creating audio sequence: %s", strings.Join(audioCmd, " "))
+	logx.Debugf("Creating audio sequence")
 	if err := runFFmpegCommand(audioCmd); err != nil {
 		return "", "", fmt.Errorf("failed to create audio sequence: %w", err)
 	}
@@ -305,19 +520,31 @@ func CreateVisualSequence(mediaInputs []image.MediaInput, totalDuration float64,
 	// Clean up intermediate audio_ensured_* files
 	for _, tempFile := range tempAudioEnsuredFiles {
 		if err := os.Remove(tempFile); err != nil {
-			log.Printf("Warning: failed to clean up temp file %s: %v", tempFile, err)
+			logx.Warnf("Failed to clean up temp file %s: %v", tempFile, err)
 		}
 	}
 
 	return tempVideoSeq, tempAudioSeq, nil
 }
 
-// GenerateVideo creates the final video with all effects and audio
-func GenerateVideo(params VideoGenParams) error {
+// GenerateVideo creates the final video with all effects and audio and
+// returns the settings it was encoded with
+func GenerateVideo(params VideoGenParams) (*RenderSettings, error) {
 	if err := fileutil.EnsureTempFolder(); err != nil {
-		return fmt.Errorf("failed to create temp folder: %w", err)
+		return nil, fmt.Errorf("failed to create temp folder: %w", err)
 	}
 
+	// Oversized stills are resized once up front rather than on every frame
+	maxDim := maxSourceDimension(params)
+	mediaInputs, downscaled, err := downscaleStills(params.MediaInputs, maxDim, params.TempFolder, params.OutputPath)
+	for _, tempFile := range downscaled {
+		defer os.Remove(tempFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	params.MediaInputs = mediaInputs
+
 	// Determine dimensions
 	var dimensions Dimensions
 	if params.TargetDimensions != nil {
@@ -326,20 +553,20 @@ func GenerateVideo(params VideoGenParams) error {
 		var err error
 		dimensions, err = CalculateMaxDimensions(params.MediaInputs)
 		if err != nil {
-			return fmt.Errorf("failed to calculate dimensions: %w", err)
+			return nil, fmt.Errorf("failed to calculate dimensions: %w", err)
 		}
 	}
 
 	// Calculate total duration
 	totalDuration, err := CalculateTotalDuration(params.AudioPath, params.MediaInputs, params.AudioMargins)
 	if err != nil {
-		return fmt.Errorf("failed to calculate total duration: %w", err)
+		return nil, fmt.Errorf("failed to calculate total duration: %w", err)
 	}
 
 	// Create visual sequence
-	visualSeq, audioSeq, err := CreateVisualSequence(params.MediaInputs, totalDuration, params.TempFolder, params.AudioPath != "", dimensions, params.OutputPath)
+	visualSeq, audioSeq, err := CreateVisualSequence(params.MediaInputs, totalDuration, params.TempFolder, params.AudioPath != "", dimensions, params.Background, params.OutputPath)
 	if err != nil {
-		return fmt.Errorf("failed to create visual sequence: %w", err)
+		return nil, fmt.Errorf("failed to create visual sequence: %w", err)
 	}
 	defer os.Remove(visualSeq)
 	defer os.Remove(audioSeq)
@@ -366,7 +593,7 @@ func GenerateVideo(params VideoGenParams) error {
 	}
 
 	// Apply video effects
-	filterComplex = append(filterComplex, "[trimmed_video]fps=30,format=yuv420p")
+	filterComplex = append(filterComplex, fmt.Sprintf("[trimmed_video]fps=%d,format=yuv420p", outputFPS))
 	if params.AudioPath != "" {
 		filterComplex = append(filterComplex, fmt.Sprintf(",fade=t=out:st=%.3f:d=%.3f", totalDuration-params.AudioMargins.End, params.AudioMargins.End))
 	}
@@ -391,14 +618,37 @@ func GenerateVideo(params VideoGenParams) error {
 	cmd = append(cmd, inputs...)
 	cmd = append(cmd, "-filter_complex", strings.Join(filterComplex, ""),
 		"-map", "[faded_video]", "-map", "[faded_audio]",
-		"-c:v", "libx264", "-preset", "slow", "-crf", "18",
-		"-c:a", "aac", "-b:a", "192k",
+		"-c:v", outputVideoCodec, "-preset", outputPreset, "-crf", strconv.Itoa(outputCRF),
+		"-c:a", outputAudioCodec, "-b:a", outputAudioBitrate,
 		"-movflags", "+faststart",
 		"-t", fmt.Sprintf("%.3f", totalDuration),
 		params.OutputPath)
 
-	log.Printf("Generating final video: %s", strings.Join(cmd, " "))
-	return runFFmpegCommand(cmd)
+	logx.Infof("Encoding final video: %s", params.OutputPath)
+	if err := runFFmpegWithProgress(cmd, "final_render", totalDuration); err != nil {
+		return nil, err
+	}
+
+	// The output may have been probed on a previous run with the same path
+	probe.Invalidate(params.OutputPath)
+
+	settings := &RenderSettings{
+		Width:        dimensions.Width,
+		Height:       dimensions.Height,
+		FPS:          outputFPS,
+		VideoCodec:   outputVideoCodec,
+		Preset:       outputPreset,
+		CRF:          outputCRF,
+		AudioCodec:   outputAudioCodec,
+		AudioBitrate: outputAudioBitrate,
+		Background:   params.Background,
+		Duration:     totalDuration,
+		AudioMargins: params.AudioMargins,
+	}
+	if params.BGMusicPath != "" {
+		settings.BGMusicVolume = params.BGMusicVolume
+	}
+	return settings, nil
 }
 
 // ensureVideoHasAudio adds silent audio track to videos that don't have audio
@@ -422,7 +672,7 @@ func ensureVideoHasAudio(inputPath, tempFolder, plannedOutputPath string) (strin
 		"-f", "lavfi", "-i", "anullsrc=channel_layout=stereo:sample_rate=44100",
 		"-c:v", "copy", "-c:a", "aac", "-shortest", outputPath}
 
-	log.Printf("Adding silent audio to video: %s", strings.Join(addAudioCmd, " "))
+	logx.Debugf("Adding silent audio to video: %s", inputPath)
 	if err := runFFmpegCommand(addAudioCmd); err != nil {
 		return "", err
 	}
@@ -432,16 +682,13 @@ func ensureVideoHasAudio(inputPath, tempFolder, plannedOutputPath string) (strin
 
 // runFFmpegCommand executes ffmpeg with proper error handling
 func runFFmpegCommand(cmd []string) error {
-	log.Printf("Running ffmpeg: %s", strings.Join(cmd, " "))
-
-	execCmd := exec.Command(cmd[0], cmd[1:]...)
-	output, err := execCmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("ffmpeg failed: %w\nOutput: %s", err, string(output))
-	}
+	return ffmpeg.RunCommand(cmd)
+}
 
-	log.Println("ffmpeg command completed successfully")
-	return nil
+// runFFmpegWithProgress runs cmd, reporting how far ffmpeg has encoded
+// towards totalDuration seconds as progress for step
+func runFFmpegWithProgress(cmd []string, step string, totalDuration float64) error {
+	return ffmpeg.Run(cmd, ffmpeg.RunOptions{Step: step, Duration: totalDuration})
 }
 
 // ValidateVideo checks if the generated video meets expectations
@@ -472,7 +719,7 @@ func ValidateVideo(outputPath string, expectedDuration float64, shouldHaveAudio
 		}
 	}
 
-	log.Printf("Video validation passed: %s", outputPath)
+	logx.Infof("Video validation passed: %s", outputPath)
 	return nil
 }
 
@@ -1,6 +1,15 @@
 package video
 
 import (
+	"fmt"
+	goimage "image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"mmmeld/internal/config"
@@ -162,4 +171,191 @@ func TestDimensions(t *testing.T) {
 	if dims.Height != 1080 {
 		t.Error("Height not set correctly")
 	}
-}
\ No newline at end of file
+}
+func TestFitWithin(t *testing.T) {
+	tests := []struct {
+		width, height, maxDim int
+		expectedW, expectedH  int
+		expectedResize        bool
+	}{
+		{8256, 5504, 3840, 3840, 2560, true},
+		{4000, 6000, 3840, 2560, 3840, true},
+		{1920, 1080, 3840, 1920, 1080, false},
+		{3840, 3840, 3840, 3840, 3840, false},
+		{6000, 4000, 0, 6000, 4000, false},
+	}
+
+	for _, test := range tests {
+		w, h, resize := fitWithin(test.width, test.height, test.maxDim)
+		if w != test.expectedW || h != test.expectedH || resize != test.expectedResize {
+			t.Errorf("fitWithin(%d, %d, %d) = %d, %d, %v, expected %d, %d, %v",
+				test.width, test.height, test.maxDim, w, h, resize, test.expectedW, test.expectedH, test.expectedResize)
+		}
+	}
+}
+
+func TestMaxSourceDimension(t *testing.T) {
+	tests := []struct {
+		params   VideoGenParams
+		expected int
+	}{
+		{VideoGenParams{MaxSourceDimension: 2500}, 2500},
+		{VideoGenParams{}, 3840},
+		{VideoGenParams{TargetDimensions: &Dimensions{Width: 1080, Height: 1920}}, 3840},
+		{VideoGenParams{TargetDimensions: &Dimensions{Width: 1280, Height: 720}}, 2560},
+	}
+
+	for _, test := range tests {
+		if result := maxSourceDimension(test.params); result != test.expected {
+			t.Errorf("maxSourceDimension(%+v) = %d, expected %d", test.params, result, test.expected)
+		}
+	}
+}
+
+func TestCanvasFilter(t *testing.T) {
+	dims := Dimensions{Width: 1920, Height: 1080}
+	tests := []struct {
+		background string
+		contains   []string
+	}{
+		{"", []string{"[0:v]trim=duration=5.000,scale=1920:1080:force_original_aspect_ratio=decrease", "pad=1920:1080:(ow-iw)/2:(oh-ih)/2:color=black", "[v0];"}},
+		{"black", []string{":color=black,"}},
+		{"color:#112233", []string{":color=0x112233,"}},
+		{"blur", []string{"split[fg0][bg0]", "force_original_aspect_ratio=increase,crop=1920:1080,boxblur", "[bgb0][fgs0]overlay=(W-w)/2:(H-h)/2", "[v0];"}},
+	}
+
+	for _, test := range tests {
+		result := canvasFilter(0, "trim=duration=5.000", dims, test.background)
+		for _, want := range test.contains {
+			if !strings.Contains(result, want) {
+				t.Errorf("canvasFilter(%q) = %q, expected it to contain %q", test.background, result, want)
+			}
+		}
+	}
+}
+
+func TestAutoBackground(t *testing.T) {
+	// A red frame around a white interior: the edge color is red
+	img := goimage.NewRGBA(goimage.Rect(0, 0, 200, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 200; x++ {
+			c := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+			if x < 10 || x >= 190 || y < 10 || y >= 90 {
+				c = color.RGBA{R: 200, G: 16, B: 32, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "framed.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create image: %v", err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatalf("Failed to encode image: %v", err)
+	}
+	f.Close()
+
+	tests := []struct {
+		input    image.MediaInput
+		expected string
+	}{
+		{image.MediaInput{Path: path}, "color:#c81020"},
+		{image.MediaInput{Path: "missing.png"}, "black"},
+		{image.MediaInput{Path: "clip.mp4", IsVideo: true}, "black"},
+	}
+
+	for _, test := range tests {
+		if result := autoBackground(test.input); result != test.expected {
+			t.Errorf("autoBackground(%q) = %q, expected %q", test.input.Path, result, test.expected)
+		}
+	}
+}
+
+// benchmarkPhotos returns stills from the folder named by MMMELD_BENCH_PHOTOS
+// (e.g. a folder of DSLR JPEGs), or else four synthetic 24-megapixel JPEGs,
+// skipping when ffmpeg is unavailable. Compare the two benchmarks below to
+// see the downscale gain:
+//
+//	go test ./internal/video -bench VisualSequence -benchtime 3x
+func benchmarkPhotos(b *testing.B) []image.MediaInput {
+	b.Helper()
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		b.Skip("ffmpeg not available")
+	}
+	dir := os.Getenv("MMMELD_BENCH_PHOTOS")
+	if dir == "" {
+		return syntheticPhotos(b, 4, 6000, 4000)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		b.Skipf("cannot read %s: %v", dir, err)
+	}
+	var inputs []image.MediaInput
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if !entry.IsDir() && image.IsImageFile(path) && !strings.HasSuffix(strings.ToLower(path), ".svg") {
+			inputs = append(inputs, image.MediaInput{Path: path})
+		}
+	}
+	if len(inputs) == 0 {
+		b.Skipf("no photos in %s", dir)
+	}
+	return inputs
+}
+
+// syntheticPhotos writes count width x height JPEGs with a gradient and
+// grain, so they compress about as well as photos do
+func syntheticPhotos(b *testing.B, count, width, height int) []image.MediaInput {
+	b.Helper()
+	dir := b.TempDir()
+	img := goimage.NewRGBA(goimage.Rect(0, 0, width, height))
+	var inputs []image.MediaInput
+	for n := 0; n < count; n++ {
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				grain := uint8((x*7 + y*13 + n*31) % 23)
+				img.SetRGBA(x, y, color.RGBA{R: uint8(x*255/width) + grain, G: uint8(y*255/height) + grain, B: uint8(n * 60), A: 255})
+			}
+		}
+		path := filepath.Join(dir, fmt.Sprintf("photo_%d.jpg", n+1))
+		f, err := os.Create(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := jpeg.Encode(f, img, &jpeg.Options{Quality: 90}); err != nil {
+			b.Fatal(err)
+		}
+		f.Close()
+		inputs = append(inputs, image.MediaInput{Path: path})
+	}
+	return inputs
+}
+
+func benchmarkVisualSequence(b *testing.B, maxDim int) {
+	inputs := benchmarkPhotos(b)
+	tempFolder := b.TempDir()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		seqInputs, tempFiles, err := downscaleStills(inputs, maxDim, tempFolder, "bench.mp4")
+		if err != nil {
+			b.Fatal(err)
+		}
+		dimensions, _ := CalculateMaxDimensions(seqInputs)
+		videoSeq, audioSeq, err := CreateVisualSequence(seqInputs, 5.0*float64(len(seqInputs)), tempFolder, false, dimensions, "", "bench.mp4")
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, f := range append(tempFiles, videoSeq, audioSeq) {
+			os.Remove(f)
+		}
+	}
+}
+
+func BenchmarkVisualSequenceOriginal(b *testing.B) {
+	benchmarkVisualSequence(b, 0)
+}
+
+func BenchmarkVisualSequenceDownscaled(b *testing.B) {
+	benchmarkVisualSequence(b, 2*defaultCanvas.Width)
+}